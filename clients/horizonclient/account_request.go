@@ -1,10 +1,13 @@
 package horizonclient
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 
+	hProtocol "github.com/stellar/go/protocols/horizon"
 	"github.com/stellar/go/support/errors"
 )
 
@@ -57,3 +60,55 @@ func (ar AccountRequest) HTTPRequest(horizonURL string) (*http.Request, error) {
 
 	return http.NewRequest("GET", horizonURL+endpoint, nil)
 }
+
+// AccountHandler is a function that is called when an account is received
+type AccountHandler func(hProtocol.Account)
+
+// AccountDataHandler is a function that is called when an account data entry is received
+type AccountDataHandler func(hProtocol.AccountData)
+
+// StreamAccount streams the details of the account. Use context.WithCancel to stop streaming or
+// context.Background() if you want to stream indefinitely. AccountHandler is a user-supplied
+// function that is executed for each streamed account received.
+func (ar AccountRequest) StreamAccount(ctx context.Context, client *Client, handler AccountHandler) error {
+	ar.DataKey = ""
+	endpoint, err := ar.BuildURL()
+	if err != nil {
+		return errors.Wrap(err, "unable to build endpoint")
+	}
+
+	url := fmt.Sprintf("%s%s", client.fixHorizonURL(), endpoint)
+	return client.stream(ctx, url, func(data []byte) error {
+		var account hProtocol.Account
+		err := json.Unmarshal(data, &account)
+		if err != nil {
+			return errors.Wrap(err, "error unmarshaling data")
+		}
+		handler(account)
+		return nil
+	})
+}
+
+// StreamAccountData streams the data entry of the account with the given key. Use context.WithCancel
+// to stop streaming or context.Background() if you want to stream indefinitely. AccountDataHandler is
+// a user-supplied function that is executed for each streamed data entry received.
+func (ar AccountRequest) StreamAccountData(ctx context.Context, client *Client, handler AccountDataHandler) error {
+	if ar.DataKey == "" {
+		return errors.New("invalid request: no data key")
+	}
+	endpoint, err := ar.BuildURL()
+	if err != nil {
+		return errors.Wrap(err, "unable to build endpoint")
+	}
+
+	url := fmt.Sprintf("%s%s", client.fixHorizonURL(), endpoint)
+	return client.stream(ctx, url, func(data []byte) error {
+		var accountData hProtocol.AccountData
+		err := json.Unmarshal(data, &accountData)
+		if err != nil {
+			return errors.Wrap(err, "error unmarshaling data")
+		}
+		handler(accountData)
+		return nil
+	})
+}
@@ -1,10 +1,17 @@
 package horizonclient
 
 import (
+	"context"
+	"net/http"
 	"testing"
 
+	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/http/httptest"
 )
 
 func TestAccountRequestBuildUrl(t *testing.T) {
@@ -40,3 +47,133 @@ func TestAccountRequestBuildUrl(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "accounts/GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU/data/test", endpoint)
 }
+
+func TestAccountRequestStreamAccount(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+	accountID := "GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU"
+
+	hmock.On(
+		"GET",
+		"https://localhost/accounts/"+accountID+"?cursor=now",
+	).ReturnString(200, `data: {"id": "`+accountID+`", "sequence": "9865509814140929"}`+"\n\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var account hProtocol.Account
+	err := client.StreamAccount(ctx, AccountRequest{AccountID: accountID}, func(a hProtocol.Account) {
+		account = a
+		cancel()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "9865509814140929", account.Sequence)
+
+	hmock.On(
+		"GET",
+		"https://localhost/accounts/"+accountID+"/data/config.memo_required?cursor=now",
+	).ReturnString(200, `data: {"value": "MQ=="}`+"\n\n")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	var data hProtocol.AccountData
+	err = client.StreamAccountData(ctx, AccountRequest{AccountID: accountID, DataKey: "config.memo_required"}, func(d hProtocol.AccountData) {
+		data = d
+		cancel()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "MQ==", data.Value)
+
+	err = client.StreamAccountData(context.Background(), AccountRequest{AccountID: accountID}, func(hProtocol.AccountData) {})
+	assert.EqualError(t, err, "too few parameters")
+}
+
+func TestStreamErrorHandler(t *testing.T) {
+	hmock := httptest.NewClient()
+	var streamErrors []string
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+		StreamErrorHandler: func(err error) error {
+			streamErrors = append(streamErrors, err.Error())
+			if len(streamErrors) == 2 {
+				return errors.New("giving up")
+			}
+			return nil
+		},
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/liquidity_pools/cafebabe/transactions?cursor=now",
+	).Return(func(*http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(200, "id: 1\ndata: {\"id\": \"a\"}\n\nid: 2\ndata: {\"id\": \"b\"}\n\n"), nil
+	})
+	// The stream is resumed from the last received event after the
+	// connection is closed and after errors.
+	hmock.On(
+		"GET",
+		"https://localhost/liquidity_pools/cafebabe/transactions?cursor=2",
+	).Return(func(*http.Request) (*http.Response, error) {
+		if len(streamErrors) == 0 {
+			return httpmock.NewStringResponse(503, ""), nil
+		}
+		return httpmock.NewStringResponse(200, "id: 3\ndata: invalid\n\n"), nil
+	})
+
+	var ids []string
+	err := client.StreamTransactions(context.Background(), TransactionRequest{ForLiquidityPool: "cafebabe"}, func(tr hProtocol.Transaction) {
+		ids = append(ids, tr.ID)
+	})
+	assert.EqualError(t, err, "giving up")
+	assert.Equal(t, []string{"a", "b"}, ids)
+	require.Len(t, streamErrors, 2)
+	assert.Equal(t, "got bad HTTP status code 503", streamErrors[0])
+	assert.Contains(t, streamErrors[1], "error unmarshaling data")
+}
+
+func TestStreamSkipsUndecodableEvents(t *testing.T) {
+	hmock := httptest.NewClient()
+	var streamErrors []string
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+		StreamErrorHandler: func(err error) error {
+			streamErrors = append(streamErrors, err.Error())
+			return nil
+		},
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/transactions?cursor=1",
+	).Return(func(*http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(200, "id: 2\ndata: {\"id\": \"a\"}\n\nid: 3\ndata: invalid\n\nid: 4\ndata: {\"id\": \"b\"}\n\n"), nil
+	})
+	// The event which cannot be decoded is not requested again.
+	hmock.On(
+		"GET",
+		"https://localhost/transactions?cursor=3",
+	).Return(func(*http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(200, "id: 4\ndata: {\"id\": \"b\"}\n\n"), nil
+	})
+	// An event without ID is received again on every attempt.
+	hmock.On(
+		"GET",
+		"https://localhost/transactions?cursor=4",
+	).Return(func(*http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(200, "data: invalid\n\n"), nil
+	})
+
+	var ids []string
+	err := client.StreamTransactions(context.Background(), TransactionRequest{Cursor: "1"}, func(tr hProtocol.Transaction) {
+		ids = append(ids, tr.ID)
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "event after cursor 4 failed 3 times")
+	assert.Equal(t, []string{"a", "b"}, ids)
+	require.Len(t, streamErrors, 3)
+	for _, streamErr := range streamErrors {
+		assert.Contains(t, streamErr, "error unmarshaling data")
+	}
+}
@@ -25,18 +25,18 @@ import (
 )
 
 // sendRequest builds the URL for the given horizon request and sends the url to a horizon server
-func (c *Client) sendRequest(hr HorizonRequest, resp interface{}) (err error) {
+func (c *Client) sendRequest(ctx context.Context, hr HorizonRequest, resp interface{}) (err error) {
 	req, err := hr.HTTPRequest(c.fixHorizonURL())
 	if err != nil {
 		return err
 	}
 
-	return c.sendHTTPRequest(req, resp)
+	return c.sendHTTPRequest(ctx, req, resp)
 }
 
 // checkMemoRequired implements a memo required check as defined in
 // https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0029.md
-func (c *Client) checkMemoRequired(transaction *txnbuild.Transaction) error {
+func (c *Client) checkMemoRequired(ctx context.Context, transaction *txnbuild.Transaction) error {
 	destinations := map[string]bool{}
 
 	for i, op := range transaction.Operations() {
@@ -77,7 +77,7 @@ func (c *Client) checkMemoRequired(transaction *txnbuild.Transaction) error {
 			DataKey:   "config.memo_required",
 		}
 
-		data, err := c.AccountData(request)
+		data, err := c.AccountDataWithContext(ctx, request)
 		if err != nil {
 			horizonError := GetError(err)
 
@@ -101,22 +101,25 @@ func (c *Client) checkMemoRequired(transaction *txnbuild.Transaction) error {
 
 // sendGetRequest sends a HTTP GET request to a horizon server.
 // It can be used for requests that do not implement the HorizonRequest interface.
-func (c *Client) sendGetRequest(requestURL string, a interface{}) error {
+func (c *Client) sendGetRequest(ctx context.Context, requestURL string, a interface{}) error {
 	req, err := http.NewRequest("GET", requestURL, nil)
 	if err != nil {
 		return errors.Wrap(err, "error creating HTTP request")
 	}
-	return c.sendHTTPRequest(req, a)
+	return c.sendHTTPRequest(ctx, req, a)
 }
 
-func (c *Client) sendHTTPRequest(req *http.Request, a interface{}) error {
+// sendHTTPRequest sends the request using the given context. The request is
+// cancelled when the context is done or after the horizon timeout, whichever
+// happens first.
+func (c *Client) sendHTTPRequest(ctx context.Context, req *http.Request, a interface{}) error {
 	c.setClientAppHeaders(req)
 	c.setDefaultClient()
 
 	if c.horizonTimeout == 0 {
 		c.horizonTimeout = HorizonTimeout
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), c.horizonTimeout)
+	ctx, cancel := context.WithTimeout(ctx, c.horizonTimeout)
 	defer cancel()
 
 	if resp, err := c.HTTP.Do(req.WithContext(ctx)); err != nil {
@@ -126,7 +129,58 @@ func (c *Client) sendHTTPRequest(req *http.Request, a interface{}) error {
 	}
 }
 
-// stream handles connections to endpoints that support streaming on a horizon server
+// sendAsyncSubmitRequest sends an asynchronous transaction submission request.
+// Horizon responds with an error status code when stellar-core does not
+// accept the transaction, with the submission response in the body: it is
+// decoded into resp instead of returning an error.
+func (c *Client) sendAsyncSubmitRequest(ctx context.Context, req *http.Request, resp *hProtocol.AsyncTransactionSubmissionResponse) error {
+	c.setClientAppHeaders(req)
+	c.setDefaultClient()
+
+	if c.horizonTimeout == 0 {
+		c.horizonTimeout = HorizonTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.horizonTimeout)
+	defer cancel()
+
+	httpResp, err := c.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if err != nil {
+		return errors.Wrap(err, "error reading response")
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		var submission hProtocol.AsyncTransactionSubmissionResponse
+		if json.Unmarshal(data, &submission) == nil && submission.TxStatus != "" {
+			*resp = submission
+			return nil
+		}
+	}
+	httpResp.Body = io.NopCloser(bytes.NewReader(data))
+	return decodeResponse(httpResp, resp, c)
+}
+
+// maxEventFailures is the number of times an event without ID which cannot be
+// decoded is received again before the stream is stopped.
+const maxEventFailures = 3
+
+// eventError is returned by streamEvents when an event cannot be decoded.
+type eventError struct {
+	error
+	// skipped is true when the cursor was moved past the event.
+	skipped bool
+}
+
+// stream handles connections to endpoints that support streaming on a horizon server.
+// The stream is resumed from the last received event ID whenever the server closes the
+// connection. Errors end the stream unless the client's StreamErrorHandler returns nil
+// for them, in which case the stream is resumed as well. Events which cannot be decoded
+// are skipped when they have an ID, otherwise the stream is stopped once the same
+// event failed maxEventFailures times.
 func (c *Client) stream(
 	ctx context.Context,
 	streamURL string,
@@ -142,112 +196,151 @@ func (c *Client) stream(
 		query.Set("cursor", "now")
 	}
 
+	failedCursor, failures := "", 0
 	for {
 		// updates the url with new cursor
 		su.RawQuery = query.Encode()
-		req, err := http.NewRequest("GET", su.String(), nil)
-		if err != nil {
-			return errors.Wrap(err, "error creating HTTP request")
+		err = c.streamEvents(ctx, su.String(), query, handler)
+		if ctx.Err() != nil {
+			return nil
 		}
-		req.Header.Set("Accept", "text/event-stream")
-		c.setDefaultClient()
-		c.setClientAppHeaders(req)
-
-		// We can use c.HTTP here because we set Timeout per request not on the client. See sendRequest()
-		resp, err := c.HTTP.Do(req)
-		if err != nil {
-			return errors.Wrap(err, "error sending HTTP request")
+		if err == nil {
+			continue
 		}
-
-		// Expected statusCode are 200-299
-		if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-			return fmt.Errorf("got bad HTTP status code %d", resp.StatusCode)
+		if eventErr, ok := err.(eventError); ok && !eventErr.skipped {
+			if cursor := query.Get("cursor"); cursor != failedCursor {
+				failedCursor, failures = cursor, 0
+			}
+			failures++
+			if failures >= maxEventFailures {
+				return errors.Wrapf(eventErr.error, "event after cursor %s failed %d times", failedCursor, failures)
+			}
+		}
+		if c.StreamErrorHandler == nil {
+			return err
 		}
-		defer resp.Body.Close()
+		if err = c.StreamErrorHandler(err); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
 
-		reader := bufio.NewReader(resp.Body)
+// streamEvents opens a single connection to the stream url and passes its
+// events to the handler until the connection is closed. The cursor in query
+// is updated with the ID of every received event.
+func (c *Client) streamEvents(
+	ctx context.Context,
+	streamURL string,
+	query url.Values,
+	handler func(data []byte) error,
+) error {
+	req, err := http.NewRequest("GET", streamURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "error creating HTTP request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.setDefaultClient()
+	c.setClientAppHeaders(req)
 
-		// Read events one by one. Break this loop when there is no more data to be
-		// read from resp.Body (io.EOF).
-	Events:
-		for {
-			// Read until empty line = event delimiter. The perfect solution would be to read
-			// as many bytes as possible and forward them to sse.Decode. However this
-			// requires much more complicated code.
-			// We could also write our own `sse` package that works fine with streams directly
-			// (github.com/manucorporat/sse is just using io/ioutils.ReadAll).
-			var buffer bytes.Buffer
-			nonEmptylinesRead := 0
-			for {
-				// Check if ctx is not cancelled
-				select {
-				case <-ctx.Done():
-					return nil
-				default:
-					// Continue
-				}
+	// We can use c.HTTP here because we set Timeout per request not on the client. See sendRequest()
+	resp, err := c.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "error sending HTTP request")
+	}
+	defer resp.Body.Close()
 
-				line, err := reader.ReadString('\n')
-				if err != nil {
-					if err == io.EOF || err == io.ErrUnexpectedEOF {
-						// We catch EOF errors to handle two possible situations:
-						// - The last line before closing the stream was not empty. This should never
-						//   happen in Horizon as it always sends an empty line after each event.
-						// - The stream was closed by the server/proxy because the connection was idle.
-						//
-						// In the former case, that (again) should never happen in Horizon, we need to
-						// check if there are any events we need to decode. We do this in the `if`
-						// statement below just in case if Horizon behaviour changes in a future.
-						//
-						// From spec:
-						// > Once the end of the file is reached, the user agent must dispatch the
-						// > event one final time, as defined below.
-						if nonEmptylinesRead == 0 {
-							break Events
-						}
-					} else {
-						return errors.Wrap(err, "error reading line")
-					}
-				}
-				buffer.WriteString(line)
+	// Expected statusCode are 200-299
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return fmt.Errorf("got bad HTTP status code %d", resp.StatusCode)
+	}
 
-				if strings.TrimRight(line, "\n\r") == "" {
-					break
-				}
+	reader := bufio.NewReader(resp.Body)
 
-				nonEmptylinesRead++
+	// Read events one by one. Return when there is no more data to be
+	// read from resp.Body (io.EOF).
+	for {
+		// Read until empty line = event delimiter. The perfect solution would be to read
+		// as many bytes as possible and forward them to sse.Decode. However this
+		// requires much more complicated code.
+		// We could also write our own `sse` package that works fine with streams directly
+		// (github.com/manucorporat/sse is just using io/ioutils.ReadAll).
+		var buffer bytes.Buffer
+		nonEmptylinesRead := 0
+		for {
+			// Check if ctx is not cancelled
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				// Continue
 			}
 
-			events, err := sse.Decode(strings.NewReader(buffer.String()))
+			line, err := reader.ReadString('\n')
 			if err != nil {
-				return errors.Wrap(err, "error decoding event")
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					// We catch EOF errors to handle two possible situations:
+					// - The last line before closing the stream was not empty. This should never
+					//   happen in Horizon as it always sends an empty line after each event.
+					// - The stream was closed by the server/proxy because the connection was idle.
+					//
+					// In the former case, that (again) should never happen in Horizon, we need to
+					// check if there are any events we need to decode. We do this in the `if`
+					// statement below just in case if Horizon behaviour changes in a future.
+					//
+					// From spec:
+					// > Once the end of the file is reached, the user agent must dispatch the
+					// > event one final time, as defined below.
+					if nonEmptylinesRead == 0 {
+						return nil
+					}
+				} else if ctx.Err() != nil {
+					return nil
+				} else {
+					return errors.Wrap(err, "error reading line")
+				}
 			}
+			buffer.WriteString(line)
 
-			// Right now len(events) should always be 1. This loop will be helpful after writing
-			// new SSE decoder that can handle io.Reader without using ioutils.ReadAll().
-			for _, event := range events {
-				if event.Event != "message" {
-					continue
-				}
+			if strings.TrimRight(line, "\n\r") == "" {
+				break
+			}
 
-				// Update cursor with event ID
-				if event.Id != "" {
-					query.Set("cursor", event.Id)
-				}
+			nonEmptylinesRead++
+		}
 
-				switch data := event.Data.(type) {
-				case string:
-					err = handler([]byte(data))
-					err = errors.Wrap(err, "handler error")
-				case []byte:
-					err = handler(data)
-					err = errors.Wrap(err, "handler error")
-				default:
-					err = errors.New("invalid event.Data type")
-				}
-				if err != nil {
-					return err
-				}
+		events, err := sse.Decode(strings.NewReader(buffer.String()))
+		if err != nil {
+			return eventError{error: errors.Wrap(err, "error decoding event")}
+		}
+
+		// Right now len(events) should always be 1. This loop will be helpful after writing
+		// new SSE decoder that can handle io.Reader without using ioutils.ReadAll().
+		for _, event := range events {
+			if event.Event != "message" {
+				continue
+			}
+
+			switch data := event.Data.(type) {
+			case string:
+				err = handler([]byte(data))
+				err = errors.Wrap(err, "handler error")
+			case []byte:
+				err = handler(data)
+				err = errors.Wrap(err, "handler error")
+			default:
+				err = errors.New("invalid event.Data type")
+			}
+			// Update cursor with event ID once the event is handled so
+			// that a resumed stream does not skip it. An event which
+			// cannot be decoded is skipped, it would fail again.
+			if event.Id != "" {
+				query.Set("cursor", event.Id)
+			}
+			if err != nil {
+				return eventError{error: err, skipped: event.Id != ""}
 			}
 		}
 	}
@@ -291,13 +384,23 @@ func (c *Client) HorizonTimeout() time.Duration {
 // have a trustline to an asset.
 // See https://developers.stellar.org/api/resources/accounts/
 func (c *Client) Accounts(request AccountsRequest) (accounts hProtocol.AccountsPage, err error) {
-	err = c.sendRequest(request, &accounts)
+	return c.AccountsWithContext(context.Background(), request)
+}
+
+// AccountsWithContext is the same as Accounts but uses the given context for requests to Horizon.
+func (c *Client) AccountsWithContext(ctx context.Context, request AccountsRequest) (accounts hProtocol.AccountsPage, err error) {
+	err = c.sendRequest(ctx, request, &accounts)
 	return
 }
 
 // AccountDetail returns information for a single account.
 // See https://developers.stellar.org/api/resources/accounts/single/
 func (c *Client) AccountDetail(request AccountRequest) (account hProtocol.Account, err error) {
+	return c.AccountDetailWithContext(context.Background(), request)
+}
+
+// AccountDetailWithContext is the same as AccountDetail but uses the given context for requests to Horizon.
+func (c *Client) AccountDetailWithContext(ctx context.Context, request AccountRequest) (account hProtocol.Account, err error) {
 	if request.AccountID == "" {
 		err = errors.New("no account ID provided")
 	}
@@ -306,13 +409,18 @@ func (c *Client) AccountDetail(request AccountRequest) (account hProtocol.Accoun
 		return
 	}
 
-	err = c.sendRequest(request, &account)
+	err = c.sendRequest(ctx, request, &account)
 	return
 }
 
 // AccountData returns a single data associated with a given account
 // See https://developers.stellar.org/api/resources/accounts/data/
 func (c *Client) AccountData(request AccountRequest) (accountData hProtocol.AccountData, err error) {
+	return c.AccountDataWithContext(context.Background(), request)
+}
+
+// AccountDataWithContext is the same as AccountData but uses the given context for requests to Horizon.
+func (c *Client) AccountDataWithContext(ctx context.Context, request AccountRequest) (accountData hProtocol.AccountData, err error) {
 	if request.AccountID == "" || request.DataKey == "" {
 		err = errors.New("too few parameters")
 	}
@@ -321,34 +429,54 @@ func (c *Client) AccountData(request AccountRequest) (accountData hProtocol.Acco
 		return
 	}
 
-	err = c.sendRequest(request, &accountData)
+	err = c.sendRequest(ctx, request, &accountData)
 	return
 }
 
 // Effects returns effects (https://developers.stellar.org/api/resources/effects/)
 // It can be used to return effects for an account, a ledger, an operation, a transaction and all effects on the network.
 func (c *Client) Effects(request EffectRequest) (effects effects.EffectsPage, err error) {
-	err = c.sendRequest(request, &effects)
+	return c.EffectsWithContext(context.Background(), request)
+}
+
+// EffectsWithContext is the same as Effects but uses the given context for requests to Horizon.
+func (c *Client) EffectsWithContext(ctx context.Context, request EffectRequest) (effects effects.EffectsPage, err error) {
+	err = c.sendRequest(ctx, request, &effects)
 	return
 }
 
 // Assets returns asset information.
 // See https://developers.stellar.org/api/resources/assets/list/
 func (c *Client) Assets(request AssetRequest) (assets hProtocol.AssetsPage, err error) {
-	err = c.sendRequest(request, &assets)
+	return c.AssetsWithContext(context.Background(), request)
+}
+
+// AssetsWithContext is the same as Assets but uses the given context for requests to Horizon.
+func (c *Client) AssetsWithContext(ctx context.Context, request AssetRequest) (assets hProtocol.AssetsPage, err error) {
+	err = c.sendRequest(ctx, request, &assets)
 	return
 }
 
 // Ledgers returns information about all ledgers.
 // See https://developers.stellar.org/api/resources/ledgers/list/
 func (c *Client) Ledgers(request LedgerRequest) (ledgers hProtocol.LedgersPage, err error) {
-	err = c.sendRequest(request, &ledgers)
+	return c.LedgersWithContext(context.Background(), request)
+}
+
+// LedgersWithContext is the same as Ledgers but uses the given context for requests to Horizon.
+func (c *Client) LedgersWithContext(ctx context.Context, request LedgerRequest) (ledgers hProtocol.LedgersPage, err error) {
+	err = c.sendRequest(ctx, request, &ledgers)
 	return
 }
 
 // LedgerDetail returns information about a particular ledger for a given sequence number
 // See https://developers.stellar.org/api/resources/ledgers/single/
 func (c *Client) LedgerDetail(sequence uint32) (ledger hProtocol.Ledger, err error) {
+	return c.LedgerDetailWithContext(context.Background(), sequence)
+}
+
+// LedgerDetailWithContext is the same as LedgerDetail but uses the given context for requests to Horizon.
+func (c *Client) LedgerDetailWithContext(ctx context.Context, sequence uint32) (ledger hProtocol.Ledger, err error) {
 	if sequence == 0 {
 		err = errors.New("invalid sequence number provided")
 	}
@@ -358,28 +486,43 @@ func (c *Client) LedgerDetail(sequence uint32) (ledger hProtocol.Ledger, err err
 	}
 
 	request := LedgerRequest{forSequence: sequence}
-	err = c.sendRequest(request, &ledger)
+	err = c.sendRequest(ctx, request, &ledger)
 	return
 }
 
 // FeeStats returns information about fees in the last 5 ledgers.
 // See https://developers.stellar.org/api/aggregations/fee-stats/
 func (c *Client) FeeStats() (feestats hProtocol.FeeStats, err error) {
+	return c.FeeStatsWithContext(context.Background())
+}
+
+// FeeStatsWithContext is the same as FeeStats but uses the given context for requests to Horizon.
+func (c *Client) FeeStatsWithContext(ctx context.Context) (feestats hProtocol.FeeStats, err error) {
 	request := feeStatsRequest{endpoint: "fee_stats"}
-	err = c.sendRequest(request, &feestats)
+	err = c.sendRequest(ctx, request, &feestats)
 	return
 }
 
 // Offers returns information about offers made on the SDEX.
 // See https://developers.stellar.org/api/resources/offers/list/
 func (c *Client) Offers(request OfferRequest) (offers hProtocol.OffersPage, err error) {
-	err = c.sendRequest(request, &offers)
+	return c.OffersWithContext(context.Background(), request)
+}
+
+// OffersWithContext is the same as Offers but uses the given context for requests to Horizon.
+func (c *Client) OffersWithContext(ctx context.Context, request OfferRequest) (offers hProtocol.OffersPage, err error) {
+	err = c.sendRequest(ctx, request, &offers)
 	return
 }
 
 // OfferDetails returns information for a single offer.
 // See https://developers.stellar.org/api/resources/offers/single/
 func (c *Client) OfferDetails(offerID string) (offer hProtocol.Offer, err error) {
+	return c.OfferDetailsWithContext(context.Background(), offerID)
+}
+
+// OfferDetailsWithContext is the same as OfferDetails but uses the given context for requests to Horizon.
+func (c *Client) OfferDetailsWithContext(ctx context.Context, offerID string) (offer hProtocol.Offer, err error) {
 	if len(offerID) == 0 {
 		err = errors.New("no offer ID provided")
 		return
@@ -390,20 +533,30 @@ func (c *Client) OfferDetails(offerID string) (offer hProtocol.Offer, err error)
 		return
 	}
 
-	err = c.sendRequest(OfferRequest{OfferID: offerID}, &offer)
+	err = c.sendRequest(ctx, OfferRequest{OfferID: offerID}, &offer)
 	return
 }
 
 // Operations returns stellar operations (https://developers.stellar.org/api/resources/operations/list/)
 // It can be used to return operations for an account, a ledger, a transaction and all operations on the network.
 func (c *Client) Operations(request OperationRequest) (ops operations.OperationsPage, err error) {
-	err = c.sendRequest(request.SetOperationsEndpoint(), &ops)
+	return c.OperationsWithContext(context.Background(), request)
+}
+
+// OperationsWithContext is the same as Operations but uses the given context for requests to Horizon.
+func (c *Client) OperationsWithContext(ctx context.Context, request OperationRequest) (ops operations.OperationsPage, err error) {
+	err = c.sendRequest(ctx, request.SetOperationsEndpoint(), &ops)
 	return
 }
 
 // OperationDetail returns a single stellar operation for a given operation id
 // See https://developers.stellar.org/api/resources/operations/single/
 func (c *Client) OperationDetail(id string) (ops operations.Operation, err error) {
+	return c.OperationDetailWithContext(context.Background(), id)
+}
+
+// OperationDetailWithContext is the same as OperationDetail but uses the given context for requests to Horizon.
+func (c *Client) OperationDetailWithContext(ctx context.Context, id string) (ops operations.Operation, err error) {
 	if id == "" {
 		return ops, errors.New("invalid operation id provided")
 	}
@@ -412,7 +565,7 @@ func (c *Client) OperationDetail(id string) (ops operations.Operation, err error
 
 	var record interface{}
 
-	err = c.sendRequest(request, &record)
+	err = c.sendRequest(ctx, request, &record)
 	if err != nil {
 		return ops, errors.Wrap(err, "sending request to horizon")
 	}
@@ -436,9 +589,15 @@ func (c *Client) OperationDetail(id string) (ops operations.Operation, err error
 // SubmitTransactionXDR submits a transaction represented as a base64 XDR string to the network. err can be either error object or horizon.Error object.
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitTransactionXDR(transactionXdr string) (tx hProtocol.Transaction,
+	err error) {
+	return c.SubmitTransactionXDRWithContext(context.Background(), transactionXdr)
+}
+
+// SubmitTransactionXDRWithContext is the same as SubmitTransactionXDR but uses the given context for requests to Horizon.
+func (c *Client) SubmitTransactionXDRWithContext(ctx context.Context, transactionXdr string) (tx hProtocol.Transaction,
 	err error) {
 	request := submitRequest{endpoint: "transactions", transactionXdr: transactionXdr}
-	err = c.sendRequest(request, &tx)
+	err = c.sendRequest(ctx, request, &tx)
 	return
 }
 
@@ -452,7 +611,12 @@ func (c *Client) SubmitTransactionXDR(transactionXdr string) (tx hProtocol.Trans
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitFeeBumpTransaction(transaction *txnbuild.FeeBumpTransaction) (tx hProtocol.Transaction, err error) {
-	return c.SubmitFeeBumpTransactionWithOptions(transaction, SubmitTxOpts{})
+	return c.SubmitFeeBumpTransactionWithContext(context.Background(), transaction)
+}
+
+// SubmitFeeBumpTransactionWithContext is the same as SubmitFeeBumpTransaction but uses the given context for requests to Horizon.
+func (c *Client) SubmitFeeBumpTransactionWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction) (tx hProtocol.Transaction, err error) {
+	return c.SubmitFeeBumpTransactionWithOptionsWithContext(ctx, transaction, SubmitTxOpts{})
 }
 
 // SubmitFeeBumpTransactionWithOptions submits a fee bump transaction to the network, allowing
@@ -460,22 +624,35 @@ func (c *Client) SubmitFeeBumpTransaction(transaction *txnbuild.FeeBumpTransacti
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	return c.SubmitFeeBumpTransactionWithOptionsWithContext(context.Background(), transaction, opts)
+}
+
+// SubmitFeeBumpTransactionWithOptionsWithContext is the same as SubmitFeeBumpTransactionWithOptions but uses the given context for requests to Horizon.
+func (c *Client) SubmitFeeBumpTransactionWithOptionsWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	txeBase64, err := c.feeBumpTransactionXDR(ctx, transaction, opts)
+	if err != nil {
+		return
+	}
+
+	return c.SubmitTransactionXDRWithContext(ctx, txeBase64)
+}
+
+// feeBumpTransactionXDR returns the base64 XDR of the fee bump transaction to
+// submit, after checking if the destination accounts require a memo.
+func (c *Client) feeBumpTransactionXDR(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (string, error) {
 	// only check if memo is required if skip is false and the inner transaction
 	// doesn't have a memo.
 	if inner := transaction.InnerTransaction(); !opts.SkipMemoRequiredCheck && inner.Memo() == nil {
-		err = c.checkMemoRequired(inner)
-		if err != nil {
-			return
+		if err := c.checkMemoRequired(ctx, inner); err != nil {
+			return "", err
 		}
 	}
 
 	txeBase64, err := transaction.Base64()
 	if err != nil {
-		err = errors.Wrap(err, "Unable to convert transaction object to base64 string")
-		return
+		return "", errors.Wrap(err, "Unable to convert transaction object to base64 string")
 	}
-
-	return c.SubmitTransactionXDR(txeBase64)
+	return txeBase64, nil
 }
 
 // SubmitTransaction submits a transaction to the network. err can be either an
@@ -488,7 +665,12 @@ func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBu
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitTransaction(transaction *txnbuild.Transaction) (tx hProtocol.Transaction, err error) {
-	return c.SubmitTransactionWithOptions(transaction, SubmitTxOpts{})
+	return c.SubmitTransactionWithContext(context.Background(), transaction)
+}
+
+// SubmitTransactionWithContext is the same as SubmitTransaction but uses the given context for requests to Horizon.
+func (c *Client) SubmitTransactionWithContext(ctx context.Context, transaction *txnbuild.Transaction) (tx hProtocol.Transaction, err error) {
+	return c.SubmitTransactionWithOptionsWithContext(ctx, transaction, SubmitTxOpts{})
 }
 
 // SubmitTransactionWithOptions submits a transaction to the network, allowing
@@ -496,87 +678,249 @@ func (c *Client) SubmitTransaction(transaction *txnbuild.Transaction) (tx hProto
 //
 // See https://developers.stellar.org/api/resources/transactions/post/
 func (c *Client) SubmitTransactionWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	return c.SubmitTransactionWithOptionsWithContext(context.Background(), transaction, opts)
+}
+
+// SubmitTransactionWithOptionsWithContext is the same as SubmitTransactionWithOptions but uses the given context for requests to Horizon.
+func (c *Client) SubmitTransactionWithOptionsWithContext(ctx context.Context, transaction *txnbuild.Transaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
+	txeBase64, err := c.transactionXDR(ctx, transaction, opts)
+	if err != nil {
+		return
+	}
+
+	return c.SubmitTransactionXDRWithContext(ctx, txeBase64)
+}
+
+// transactionXDR returns the base64 XDR of the transaction to submit, after
+// checking if the destination accounts require a memo.
+func (c *Client) transactionXDR(ctx context.Context, transaction *txnbuild.Transaction, opts SubmitTxOpts) (string, error) {
 	// only check if memo is required if skip is false and the transaction
 	// doesn't have a memo.
 	if !opts.SkipMemoRequiredCheck && transaction.Memo() == nil {
-		err = c.checkMemoRequired(transaction)
-		if err != nil {
-			return
+		if err := c.checkMemoRequired(ctx, transaction); err != nil {
+			return "", err
 		}
 	}
 
 	txeBase64, err := transaction.Base64()
 	if err != nil {
-		err = errors.Wrap(err, "Unable to convert transaction object to base64 string")
+		return "", errors.Wrap(err, "Unable to convert transaction object to base64 string")
+	}
+	return txeBase64, nil
+}
+
+// SubmitTransactionXDRAsync submits a transaction represented as a base64 XDR string to the
+// network without waiting for it to be included in a ledger. The response holds the status
+// returned by stellar-core, which is "PENDING" if the transaction was accepted. err can be
+// either an error object or a horizon.Error object, it is not set if stellar-core rejected
+// the transaction.
+//
+// Use TransactionStatus or StreamTransactionStatus to know when the transaction is included
+// in a ledger.
+func (c *Client) SubmitTransactionXDRAsync(transactionXdr string) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitTransactionXDRAsyncWithContext(context.Background(), transactionXdr)
+}
+
+// SubmitTransactionXDRAsyncWithContext is the same as SubmitTransactionXDRAsync but uses the given context for requests to Horizon.
+func (c *Client) SubmitTransactionXDRAsyncWithContext(ctx context.Context, transactionXdr string) (resp hProtocol.AsyncTransactionSubmissionResponse, err error) {
+	request := submitRequest{endpoint: "transactions_async", transactionXdr: transactionXdr}
+	req, err := request.HTTPRequest(c.fixHorizonURL())
+	if err != nil {
+		return
+	}
+	err = c.sendAsyncSubmitRequest(ctx, req, &resp)
+	return
+}
+
+// SubmitTransactionAsync submits a transaction to the network without waiting for it to be
+// included in a ledger, see SubmitTransactionXDRAsync.
+//
+// This function will always check if the destination account requires a memo in the transaction as
+// defined in SEP0029: https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0029.md
+//
+// If you want to skip this check, use SubmitTransactionAsyncWithOptions.
+func (c *Client) SubmitTransactionAsync(transaction *txnbuild.Transaction) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitTransactionAsyncWithOptionsWithContext(context.Background(), transaction, SubmitTxOpts{})
+}
+
+// SubmitTransactionAsyncWithContext is the same as SubmitTransactionAsync but uses the given context for requests to Horizon.
+func (c *Client) SubmitTransactionAsyncWithContext(ctx context.Context, transaction *txnbuild.Transaction) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitTransactionAsyncWithOptionsWithContext(ctx, transaction, SubmitTxOpts{})
+}
+
+// SubmitTransactionAsyncWithOptions submits a transaction to the network without waiting for it
+// to be included in a ledger, allowing you to pass SubmitTxOpts.
+func (c *Client) SubmitTransactionAsyncWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitTransactionAsyncWithOptionsWithContext(context.Background(), transaction, opts)
+}
+
+// SubmitTransactionAsyncWithOptionsWithContext is the same as SubmitTransactionAsyncWithOptions but uses the given context for requests to Horizon.
+func (c *Client) SubmitTransactionAsyncWithOptionsWithContext(ctx context.Context, transaction *txnbuild.Transaction, opts SubmitTxOpts) (resp hProtocol.AsyncTransactionSubmissionResponse, err error) {
+	txeBase64, err := c.transactionXDR(ctx, transaction, opts)
+	if err != nil {
+		return
+	}
+
+	return c.SubmitTransactionXDRAsyncWithContext(ctx, txeBase64)
+}
+
+// SubmitFeeBumpTransactionAsync submits a fee bump transaction to the network without waiting
+// for it to be included in a ledger, see SubmitTransactionXDRAsync.
+//
+// This function will always check if the destination account requires a memo in the transaction as
+// defined in SEP0029: https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0029.md
+//
+// If you want to skip this check, use SubmitFeeBumpTransactionAsyncWithOptions.
+func (c *Client) SubmitFeeBumpTransactionAsync(transaction *txnbuild.FeeBumpTransaction) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitFeeBumpTransactionAsyncWithOptionsWithContext(context.Background(), transaction, SubmitTxOpts{})
+}
+
+// SubmitFeeBumpTransactionAsyncWithContext is the same as SubmitFeeBumpTransactionAsync but uses the given context for requests to Horizon.
+func (c *Client) SubmitFeeBumpTransactionAsyncWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitFeeBumpTransactionAsyncWithOptionsWithContext(ctx, transaction, SubmitTxOpts{})
+}
+
+// SubmitFeeBumpTransactionAsyncWithOptions submits a fee bump transaction to the network without
+// waiting for it to be included in a ledger, allowing you to pass SubmitTxOpts.
+func (c *Client) SubmitFeeBumpTransactionAsyncWithOptions(transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (hProtocol.AsyncTransactionSubmissionResponse, error) {
+	return c.SubmitFeeBumpTransactionAsyncWithOptionsWithContext(context.Background(), transaction, opts)
+}
+
+// SubmitFeeBumpTransactionAsyncWithOptionsWithContext is the same as SubmitFeeBumpTransactionAsyncWithOptions but uses the given context for requests to Horizon.
+func (c *Client) SubmitFeeBumpTransactionAsyncWithOptionsWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (resp hProtocol.AsyncTransactionSubmissionResponse, err error) {
+	txeBase64, err := c.feeBumpTransactionXDR(ctx, transaction, opts)
+	if err != nil {
 		return
 	}
 
-	return c.SubmitTransactionXDR(txeBase64)
+	return c.SubmitTransactionXDRAsyncWithContext(ctx, txeBase64)
+}
+
+// TransactionStatus returns whether the transaction with the given hash was included in a
+// ledger. The status is "NOT_FOUND" until the transaction is included in a ledger ingested
+// by Horizon.
+func (c *Client) TransactionStatus(txHash string) (hProtocol.AsyncTransactionStatus, error) {
+	return c.TransactionStatusWithContext(context.Background(), txHash)
+}
+
+// TransactionStatusWithContext is the same as TransactionStatus but uses the given context for requests to Horizon.
+func (c *Client) TransactionStatusWithContext(ctx context.Context, txHash string) (status hProtocol.AsyncTransactionStatus, err error) {
+	if txHash == "" {
+		return status, errors.New("no transaction hash provided")
+	}
+
+	err = c.sendRequest(ctx, transactionStatusRequest{hash: txHash}, &status)
+	return
 }
 
 // Transactions returns stellar transactions (https://developers.stellar.org/api/resources/transactions/list/)
 // It can be used to return transactions for an account, a ledger,and all transactions on the network.
 func (c *Client) Transactions(request TransactionRequest) (txs hProtocol.TransactionsPage, err error) {
-	err = c.sendRequest(request, &txs)
+	return c.TransactionsWithContext(context.Background(), request)
+}
+
+// TransactionsWithContext is the same as Transactions but uses the given context for requests to Horizon.
+func (c *Client) TransactionsWithContext(ctx context.Context, request TransactionRequest) (txs hProtocol.TransactionsPage, err error) {
+	err = c.sendRequest(ctx, request, &txs)
 	return
 }
 
 // TransactionDetail returns information about a particular transaction for a given transaction hash
 // See https://developers.stellar.org/api/resources/transactions/single/
 func (c *Client) TransactionDetail(txHash string) (tx hProtocol.Transaction, err error) {
+	return c.TransactionDetailWithContext(context.Background(), txHash)
+}
+
+// TransactionDetailWithContext is the same as TransactionDetail but uses the given context for requests to Horizon.
+func (c *Client) TransactionDetailWithContext(ctx context.Context, txHash string) (tx hProtocol.Transaction, err error) {
 	if txHash == "" {
 		return tx, errors.New("no transaction hash provided")
 	}
 
 	request := TransactionRequest{forTransactionHash: txHash}
-	err = c.sendRequest(request, &tx)
+	err = c.sendRequest(ctx, request, &tx)
 	return
 }
 
 // OrderBook returns the orderbook for an asset pair (https://developers.stellar.org/api/aggregations/order-books/single/)
 func (c *Client) OrderBook(request OrderBookRequest) (obs hProtocol.OrderBookSummary, err error) {
-	err = c.sendRequest(request, &obs)
+	return c.OrderBookWithContext(context.Background(), request)
+}
+
+// OrderBookWithContext is the same as OrderBook but uses the given context for requests to Horizon.
+func (c *Client) OrderBookWithContext(ctx context.Context, request OrderBookRequest) (obs hProtocol.OrderBookSummary, err error) {
+	err = c.sendRequest(ctx, request, &obs)
 	return
 }
 
 // Paths returns the available paths to make a strict receive path payment. See https://developers.stellar.org/api/aggregations/paths/strict-receive/
 // This function is an alias for `client.StrictReceivePaths` and will be deprecated, use `client.StrictReceivePaths` instead.
 func (c *Client) Paths(request PathsRequest) (paths hProtocol.PathsPage, err error) {
-	paths, err = c.StrictReceivePaths(request)
+	return c.PathsWithContext(context.Background(), request)
+}
+
+// PathsWithContext is the same as Paths but uses the given context for requests to Horizon.
+func (c *Client) PathsWithContext(ctx context.Context, request PathsRequest) (paths hProtocol.PathsPage, err error) {
+	paths, err = c.StrictReceivePathsWithContext(ctx, request)
 	return
 }
 
 // StrictReceivePaths returns the available paths to make a strict receive path payment. See https://developers.stellar.org/api/aggregations/paths/strict-receive/
 func (c *Client) StrictReceivePaths(request PathsRequest) (paths hProtocol.PathsPage, err error) {
-	err = c.sendRequest(request, &paths)
+	return c.StrictReceivePathsWithContext(context.Background(), request)
+}
+
+// StrictReceivePathsWithContext is the same as StrictReceivePaths but uses the given context for requests to Horizon.
+func (c *Client) StrictReceivePathsWithContext(ctx context.Context, request PathsRequest) (paths hProtocol.PathsPage, err error) {
+	err = c.sendRequest(ctx, request, &paths)
 	return
 }
 
 // StrictSendPaths returns the available paths to make a strict send path payment. See https://developers.stellar.org/api/aggregations/paths/strict-send/
 func (c *Client) StrictSendPaths(request StrictSendPathsRequest) (paths hProtocol.PathsPage, err error) {
-	err = c.sendRequest(request, &paths)
+	return c.StrictSendPathsWithContext(context.Background(), request)
+}
+
+// StrictSendPathsWithContext is the same as StrictSendPaths but uses the given context for requests to Horizon.
+func (c *Client) StrictSendPathsWithContext(ctx context.Context, request StrictSendPathsRequest) (paths hProtocol.PathsPage, err error) {
+	err = c.sendRequest(ctx, request, &paths)
 	return
 }
 
 // Payments returns stellar account_merge, create_account, path payment and payment operations.
 // It can be used to return payments for an account, a ledger, a transaction and all payments on the network.
 func (c *Client) Payments(request OperationRequest) (ops operations.OperationsPage, err error) {
-	err = c.sendRequest(request.SetPaymentsEndpoint(), &ops)
+	return c.PaymentsWithContext(context.Background(), request)
+}
+
+// PaymentsWithContext is the same as Payments but uses the given context for requests to Horizon.
+func (c *Client) PaymentsWithContext(ctx context.Context, request OperationRequest) (ops operations.OperationsPage, err error) {
+	err = c.sendRequest(ctx, request.SetPaymentsEndpoint(), &ops)
 	return
 }
 
 // Trades returns stellar trades (https://developers.stellar.org/api/resources/trades/list/)
 // It can be used to return trades for an account, an offer and all trades on the network.
 func (c *Client) Trades(request TradeRequest) (tds hProtocol.TradesPage, err error) {
-	err = c.sendRequest(request, &tds)
+	return c.TradesWithContext(context.Background(), request)
+}
+
+// TradesWithContext is the same as Trades but uses the given context for requests to Horizon.
+func (c *Client) TradesWithContext(ctx context.Context, request TradeRequest) (tds hProtocol.TradesPage, err error) {
+	err = c.sendRequest(ctx, request, &tds)
 	return
 }
 
 // Fund creates a new account funded from friendbot. It only works on test networks. See
 // https://developers.stellar.org/docs/tutorials/create-account/ for more information.
 func (c *Client) Fund(addr string) (tx hProtocol.Transaction, err error) {
+	return c.FundWithContext(context.Background(), addr)
+}
+
+// FundWithContext is the same as Fund but uses the given context for requests to Horizon.
+func (c *Client) FundWithContext(ctx context.Context, addr string) (tx hProtocol.Transaction, err error) {
 	friendbotURL := fmt.Sprintf("%sfriendbot?addr=%s", c.fixHorizonURL(), addr)
-	err = c.sendGetRequest(friendbotURL, &tx)
+	err = c.sendGetRequest(ctx, friendbotURL, &tx)
 	if IsNotFoundError(err) {
 		return tx, errors.Wrap(err, "funding is only available on test networks and may not be supported by "+c.fixHorizonURL())
 	}
@@ -593,7 +937,12 @@ func (c *Client) StreamTrades(ctx context.Context, request TradeRequest, handler
 
 // TradeAggregations returns stellar trade aggregations (https://developers.stellar.org/api/aggregations/trade-aggregations/list/)
 func (c *Client) TradeAggregations(request TradeAggregationRequest) (tds hProtocol.TradeAggregationsPage, err error) {
-	err = c.sendRequest(request, &tds)
+	return c.TradeAggregationsWithContext(context.Background(), request)
+}
+
+// TradeAggregationsWithContext is the same as TradeAggregations but uses the given context for requests to Horizon.
+func (c *Client) TradeAggregationsWithContext(ctx context.Context, request TradeAggregationRequest) (tds hProtocol.TradeAggregationsPage, err error) {
+	err = c.sendRequest(ctx, request, &tds)
 	return
 }
 
@@ -649,6 +998,39 @@ func (c *Client) StreamOrderBooks(ctx context.Context, request OrderBookRequest,
 	return request.StreamOrderBooks(ctx, c, handler)
 }
 
+// StreamAccount streams the details of an account. An event is received whenever the account
+// changes. Use context.WithCancel to stop streaming or context.Background() if you want to stream
+// indefinitely. AccountHandler is a user-supplied function that is executed for each streamed account received.
+func (c *Client) StreamAccount(ctx context.Context, request AccountRequest, handler AccountHandler) error {
+	if request.AccountID == "" {
+		return errors.New("no account ID provided")
+	}
+	return request.StreamAccount(ctx, c, handler)
+}
+
+// StreamAccountData streams a single data entry of an account. An event is received whenever the
+// entry changes. Use context.WithCancel to stop streaming or context.Background() if you want to
+// stream indefinitely. AccountDataHandler is a user-supplied function that is executed for each
+// streamed data entry received.
+func (c *Client) StreamAccountData(ctx context.Context, request AccountRequest, handler AccountDataHandler) error {
+	if request.AccountID == "" || request.DataKey == "" {
+		return errors.New("too few parameters")
+	}
+	return request.StreamAccountData(ctx, c, handler)
+}
+
+// StreamTransactionStatus streams the status of a transaction submitted asynchronously. An event
+// is received whenever the status changes, which is once the transaction is included in a ledger.
+// Use context.WithCancel to stop streaming or context.Background() if you want to stream
+// indefinitely. TransactionStatusHandler is a user-supplied function that is executed for each
+// streamed status received.
+func (c *Client) StreamTransactionStatus(ctx context.Context, txHash string, handler TransactionStatusHandler) error {
+	if txHash == "" {
+		return errors.New("no transaction hash provided")
+	}
+	return transactionStatusRequest{hash: txHash}.StreamTransactionStatus(ctx, c, handler)
+}
+
 // FetchTimebounds provides timebounds for N seconds from now using the server time of the horizon instance.
 // It defaults to localtime when the server time is not available.
 // Note that this will generate your timebounds when you init the transaction, not when you build or submit
@@ -670,7 +1052,12 @@ func (c *Client) FetchTimebounds(seconds int64) (txnbuild.Timebounds, error) {
 
 // Root loads the root endpoint of horizon
 func (c *Client) Root() (root hProtocol.Root, err error) {
-	err = c.sendGetRequest(c.fixHorizonURL(), &root)
+	return c.RootWithContext(context.Background())
+}
+
+// RootWithContext is the same as Root but uses the given context for requests to Horizon.
+func (c *Client) RootWithContext(ctx context.Context) (root hProtocol.Root, err error) {
+	err = c.sendGetRequest(ctx, c.fixHorizonURL(), &root)
 	return
 }
 
@@ -681,111 +1068,201 @@ func (c *Client) Version() string {
 
 // NextAccountsPage returns the next page of accounts.
 func (c *Client) NextAccountsPage(page hProtocol.AccountsPage) (accounts hProtocol.AccountsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &accounts)
+	return c.NextAccountsPageWithContext(context.Background(), page)
+}
+
+// NextAccountsPageWithContext is the same as NextAccountsPage but uses the given context for requests to Horizon.
+func (c *Client) NextAccountsPageWithContext(ctx context.Context, page hProtocol.AccountsPage) (accounts hProtocol.AccountsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &accounts)
 	return
 }
 
 // NextAssetsPage returns the next page of assets.
 func (c *Client) NextAssetsPage(page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &assets)
+	return c.NextAssetsPageWithContext(context.Background(), page)
+}
+
+// NextAssetsPageWithContext is the same as NextAssetsPage but uses the given context for requests to Horizon.
+func (c *Client) NextAssetsPageWithContext(ctx context.Context, page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &assets)
 	return
 }
 
 // PrevAssetsPage returns the previous page of assets.
 func (c *Client) PrevAssetsPage(page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &assets)
+	return c.PrevAssetsPageWithContext(context.Background(), page)
+}
+
+// PrevAssetsPageWithContext is the same as PrevAssetsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevAssetsPageWithContext(ctx context.Context, page hProtocol.AssetsPage) (assets hProtocol.AssetsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &assets)
 	return
 }
 
 // NextLedgersPage returns the next page of ledgers.
 func (c *Client) NextLedgersPage(page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &ledgers)
+	return c.NextLedgersPageWithContext(context.Background(), page)
+}
+
+// NextLedgersPageWithContext is the same as NextLedgersPage but uses the given context for requests to Horizon.
+func (c *Client) NextLedgersPageWithContext(ctx context.Context, page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &ledgers)
 	return
 }
 
 // PrevLedgersPage returns the previous page of ledgers.
 func (c *Client) PrevLedgersPage(page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &ledgers)
+	return c.PrevLedgersPageWithContext(context.Background(), page)
+}
+
+// PrevLedgersPageWithContext is the same as PrevLedgersPage but uses the given context for requests to Horizon.
+func (c *Client) PrevLedgersPageWithContext(ctx context.Context, page hProtocol.LedgersPage) (ledgers hProtocol.LedgersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &ledgers)
 	return
 }
 
 // NextEffectsPage returns the next page of effects.
 func (c *Client) NextEffectsPage(page effects.EffectsPage) (efp effects.EffectsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &efp)
+	return c.NextEffectsPageWithContext(context.Background(), page)
+}
+
+// NextEffectsPageWithContext is the same as NextEffectsPage but uses the given context for requests to Horizon.
+func (c *Client) NextEffectsPageWithContext(ctx context.Context, page effects.EffectsPage) (efp effects.EffectsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &efp)
 	return
 }
 
 // PrevEffectsPage returns the previous page of effects.
 func (c *Client) PrevEffectsPage(page effects.EffectsPage) (efp effects.EffectsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &efp)
+	return c.PrevEffectsPageWithContext(context.Background(), page)
+}
+
+// PrevEffectsPageWithContext is the same as PrevEffectsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevEffectsPageWithContext(ctx context.Context, page effects.EffectsPage) (efp effects.EffectsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &efp)
 	return
 }
 
 // NextTransactionsPage returns the next page of transactions.
 func (c *Client) NextTransactionsPage(page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &transactions)
+	return c.NextTransactionsPageWithContext(context.Background(), page)
+}
+
+// NextTransactionsPageWithContext is the same as NextTransactionsPage but uses the given context for requests to Horizon.
+func (c *Client) NextTransactionsPageWithContext(ctx context.Context, page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &transactions)
 	return
 }
 
 // PrevTransactionsPage returns the previous page of transactions.
 func (c *Client) PrevTransactionsPage(page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &transactions)
+	return c.PrevTransactionsPageWithContext(context.Background(), page)
+}
+
+// PrevTransactionsPageWithContext is the same as PrevTransactionsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevTransactionsPageWithContext(ctx context.Context, page hProtocol.TransactionsPage) (transactions hProtocol.TransactionsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &transactions)
 	return
 }
 
 // NextOperationsPage returns the next page of operations.
 func (c *Client) NextOperationsPage(page operations.OperationsPage) (operations operations.OperationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &operations)
+	return c.NextOperationsPageWithContext(context.Background(), page)
+}
+
+// NextOperationsPageWithContext is the same as NextOperationsPage but uses the given context for requests to Horizon.
+func (c *Client) NextOperationsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations operations.OperationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &operations)
 	return
 }
 
 // PrevOperationsPage returns the previous page of operations.
 func (c *Client) PrevOperationsPage(page operations.OperationsPage) (operations operations.OperationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &operations)
+	return c.PrevOperationsPageWithContext(context.Background(), page)
+}
+
+// PrevOperationsPageWithContext is the same as PrevOperationsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevOperationsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations operations.OperationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &operations)
 	return
 }
 
 // NextPaymentsPage returns the next page of payments.
 func (c *Client) NextPaymentsPage(page operations.OperationsPage) (operations.OperationsPage, error) {
-	return c.NextOperationsPage(page)
+	return c.NextPaymentsPageWithContext(context.Background(), page)
+}
+
+// NextPaymentsPageWithContext is the same as NextPaymentsPage but uses the given context for requests to Horizon.
+func (c *Client) NextPaymentsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error) {
+	return c.NextOperationsPageWithContext(ctx, page)
 }
 
 // PrevPaymentsPage returns the previous page of payments.
 func (c *Client) PrevPaymentsPage(page operations.OperationsPage) (operations.OperationsPage, error) {
-	return c.PrevOperationsPage(page)
+	return c.PrevPaymentsPageWithContext(context.Background(), page)
+}
+
+// PrevPaymentsPageWithContext is the same as PrevPaymentsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevPaymentsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error) {
+	return c.PrevOperationsPageWithContext(ctx, page)
 }
 
 // NextOffersPage returns the next page of offers.
 func (c *Client) NextOffersPage(page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &offers)
+	return c.NextOffersPageWithContext(context.Background(), page)
+}
+
+// NextOffersPageWithContext is the same as NextOffersPage but uses the given context for requests to Horizon.
+func (c *Client) NextOffersPageWithContext(ctx context.Context, page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &offers)
 	return
 }
 
 // PrevOffersPage returns the previous page of offers.
 func (c *Client) PrevOffersPage(page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &offers)
+	return c.PrevOffersPageWithContext(context.Background(), page)
+}
+
+// PrevOffersPageWithContext is the same as PrevOffersPage but uses the given context for requests to Horizon.
+func (c *Client) PrevOffersPageWithContext(ctx context.Context, page hProtocol.OffersPage) (offers hProtocol.OffersPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &offers)
 	return
 }
 
 // NextTradesPage returns the next page of trades.
 func (c *Client) NextTradesPage(page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &trades)
+	return c.NextTradesPageWithContext(context.Background(), page)
+}
+
+// NextTradesPageWithContext is the same as NextTradesPage but uses the given context for requests to Horizon.
+func (c *Client) NextTradesPageWithContext(ctx context.Context, page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &trades)
 	return
 }
 
 // PrevTradesPage returns the previous page of trades.
 func (c *Client) PrevTradesPage(page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &trades)
+	return c.PrevTradesPageWithContext(context.Background(), page)
+}
+
+// PrevTradesPageWithContext is the same as PrevTradesPage but uses the given context for requests to Horizon.
+func (c *Client) PrevTradesPageWithContext(ctx context.Context, page hProtocol.TradesPage) (trades hProtocol.TradesPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &trades)
 	return
 }
 
 // HomeDomainForAccount returns the home domain for a single account.
 func (c *Client) HomeDomainForAccount(aid string) (string, error) {
+	return c.HomeDomainForAccountWithContext(context.Background(), aid)
+}
+
+// HomeDomainForAccountWithContext is the same as HomeDomainForAccount but uses the given context for requests to Horizon.
+func (c *Client) HomeDomainForAccountWithContext(ctx context.Context, aid string) (string, error) {
 	if aid == "" {
 		return "", errors.New("no account ID provided")
 	}
 
-	accountDetail, err := c.AccountDetail(AccountRequest{AccountID: aid})
+	accountDetail, err := c.AccountDetailWithContext(ctx, AccountRequest{AccountID: aid})
 	if err != nil {
 		return "", errors.Wrap(err, "get account detail failed")
 	}
@@ -796,48 +1273,110 @@ func (c *Client) HomeDomainForAccount(aid string) (string, error) {
 // NextTradeAggregationsPage returns the next page of trade aggregations from the current
 // trade aggregations response.
 func (c *Client) NextTradeAggregationsPage(page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &ta)
+	return c.NextTradeAggregationsPageWithContext(context.Background(), page)
+}
+
+// NextTradeAggregationsPageWithContext is the same as NextTradeAggregationsPage but uses the given context for requests to Horizon.
+func (c *Client) NextTradeAggregationsPageWithContext(ctx context.Context, page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &ta)
 	return
 }
 
 // PrevTradeAggregationsPage returns the previous page of trade aggregations from the current
 // trade aggregations response.
 func (c *Client) PrevTradeAggregationsPage(page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &ta)
+	return c.PrevTradeAggregationsPageWithContext(context.Background(), page)
+}
+
+// PrevTradeAggregationsPageWithContext is the same as PrevTradeAggregationsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevTradeAggregationsPageWithContext(ctx context.Context, page hProtocol.TradeAggregationsPage) (ta hProtocol.TradeAggregationsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &ta)
 	return
 }
 
 // ClaimableBalances returns details about available claimable balances,
 // possibly filtered to a specific sponsor or other parameters.
 func (c *Client) ClaimableBalances(cbr ClaimableBalanceRequest) (cb hProtocol.ClaimableBalances, err error) {
-	err = c.sendRequest(cbr, &cb)
+	return c.ClaimableBalancesWithContext(context.Background(), cbr)
+}
+
+// ClaimableBalancesWithContext is the same as ClaimableBalances but uses the given context for requests to Horizon.
+func (c *Client) ClaimableBalancesWithContext(ctx context.Context, cbr ClaimableBalanceRequest) (cb hProtocol.ClaimableBalances, err error) {
+	err = c.sendRequest(ctx, cbr, &cb)
 	return
 }
 
 // ClaimableBalance returns details about a *specific*, unique claimable balance.
 func (c *Client) ClaimableBalance(id string) (cb hProtocol.ClaimableBalance, err error) {
+	return c.ClaimableBalanceWithContext(context.Background(), id)
+}
+
+// ClaimableBalanceWithContext is the same as ClaimableBalance but uses the given context for requests to Horizon.
+func (c *Client) ClaimableBalanceWithContext(ctx context.Context, id string) (cb hProtocol.ClaimableBalance, err error) {
 	cbr := ClaimableBalanceRequest{ID: id}
-	err = c.sendRequest(cbr, &cb)
+	err = c.sendRequest(ctx, cbr, &cb)
+	return
+}
+
+// NextClaimableBalancesPage returns the next page of claimable balances.
+func (c *Client) NextClaimableBalancesPage(page hProtocol.ClaimableBalances) (cb hProtocol.ClaimableBalances, err error) {
+	return c.NextClaimableBalancesPageWithContext(context.Background(), page)
+}
+
+// NextClaimableBalancesPageWithContext is the same as NextClaimableBalancesPage but uses the given context for requests to Horizon.
+func (c *Client) NextClaimableBalancesPageWithContext(ctx context.Context, page hProtocol.ClaimableBalances) (cb hProtocol.ClaimableBalances, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &cb)
+	return
+}
+
+// PrevClaimableBalancesPage returns the previous page of claimable balances.
+func (c *Client) PrevClaimableBalancesPage(page hProtocol.ClaimableBalances) (cb hProtocol.ClaimableBalances, err error) {
+	return c.PrevClaimableBalancesPageWithContext(context.Background(), page)
+}
+
+// PrevClaimableBalancesPageWithContext is the same as PrevClaimableBalancesPage but uses the given context for requests to Horizon.
+func (c *Client) PrevClaimableBalancesPageWithContext(ctx context.Context, page hProtocol.ClaimableBalances) (cb hProtocol.ClaimableBalances, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &cb)
 	return
 }
 
 func (c *Client) LiquidityPoolDetail(request LiquidityPoolRequest) (lp hProtocol.LiquidityPool, err error) {
-	err = c.sendRequest(request, &lp)
+	return c.LiquidityPoolDetailWithContext(context.Background(), request)
+}
+
+// LiquidityPoolDetailWithContext is the same as LiquidityPoolDetail but uses the given context for requests to Horizon.
+func (c *Client) LiquidityPoolDetailWithContext(ctx context.Context, request LiquidityPoolRequest) (lp hProtocol.LiquidityPool, err error) {
+	err = c.sendRequest(ctx, request, &lp)
 	return
 }
 
 func (c *Client) LiquidityPools(request LiquidityPoolsRequest) (lp hProtocol.LiquidityPoolsPage, err error) {
-	err = c.sendRequest(request, &lp)
+	return c.LiquidityPoolsWithContext(context.Background(), request)
+}
+
+// LiquidityPoolsWithContext is the same as LiquidityPools but uses the given context for requests to Horizon.
+func (c *Client) LiquidityPoolsWithContext(ctx context.Context, request LiquidityPoolsRequest) (lp hProtocol.LiquidityPoolsPage, err error) {
+	err = c.sendRequest(ctx, request, &lp)
 	return
 }
 
 func (c *Client) NextLiquidityPoolsPage(page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
-	err = c.sendGetRequest(page.Links.Next.Href, &lp)
+	return c.NextLiquidityPoolsPageWithContext(context.Background(), page)
+}
+
+// NextLiquidityPoolsPageWithContext is the same as NextLiquidityPoolsPage but uses the given context for requests to Horizon.
+func (c *Client) NextLiquidityPoolsPageWithContext(ctx context.Context, page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Next.Href, &lp)
 	return
 }
 
 func (c *Client) PrevLiquidityPoolsPage(page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
-	err = c.sendGetRequest(page.Links.Prev.Href, &lp)
+	return c.PrevLiquidityPoolsPageWithContext(context.Background(), page)
+}
+
+// PrevLiquidityPoolsPageWithContext is the same as PrevLiquidityPoolsPage but uses the given context for requests to Horizon.
+func (c *Client) PrevLiquidityPoolsPageWithContext(ctx context.Context, page hProtocol.LiquidityPoolsPage) (lp hProtocol.LiquidityPoolsPage, err error) {
+	err = c.sendGetRequest(ctx, page.Links.Prev.Href, &lp)
 	return
 }
 
@@ -0,0 +1,495 @@
+package horizonclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/network"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	// DefaultMaxLedgerLag is the default number of ledgers a Horizon endpoint
+	// can lag behind before it is considered unhealthy.
+	DefaultMaxLedgerLag = 10
+	// DefaultMaxRetries is the default number of times a failed request is
+	// retried.
+	DefaultMaxRetries = 3
+	// DefaultInitialBackoff is the default delay before the first retry of a
+	// failed request.
+	DefaultInitialBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff is the default maximum delay between retries.
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+// FailoverOptions configures a FailoverHTTP.
+type FailoverOptions struct {
+	// HTTP is the client used to send requests to the Horizon endpoints.
+	// Defaults to http.DefaultClient.
+	HTTP HTTP
+	// NetworkPassphrase is the passphrase of the network of the endpoints. It
+	// is used to compute the hashes of submitted transactions. When empty,
+	// submissions which fail with a 5xx status code or a transport error are
+	// not retried because their outcome is unknown.
+	NetworkPassphrase string
+	// MaxLedgerLag is the number of ledgers an endpoint can lag behind, either
+	// its own Stellar Core or the most up to date endpoint, before it is
+	// considered unhealthy. Defaults to DefaultMaxLedgerLag.
+	MaxLedgerLag int32
+	// MaxRetries is the number of times a failed request is retried.
+	// Defaults to DefaultMaxRetries, a negative value disables retries.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, it doubles on every
+	// subsequent retry. Defaults to DefaultInitialBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between retries, unless the endpoint
+	// asks for a longer delay with a Retry-After header. Defaults to
+	// DefaultMaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// failoverEndpoint is the state of a Horizon endpoint of a FailoverHTTP.
+type failoverEndpoint struct {
+	url string
+	// healthy is false when the last health check or request to the endpoint
+	// failed.
+	healthy bool
+	// latency is the duration of the last health check.
+	latency time.Duration
+	// limitedUntil is the time until which the endpoint rate limits requests.
+	limitedUntil time.Time
+}
+
+// FailoverHTTP is an HTTP implementation which spreads the requests of a
+// Client over several Horizon endpoints. Requests are sent to the healthiest
+// and fastest endpoint as measured by CheckHealth. Failed idempotent requests
+// (GET and HEAD) are retried with an exponential backoff on another endpoint
+// when they fail with a transport error, a timeout, a 5xx status code or
+// a 429 status code. Endpoints which rate limit requests, by responding with
+// 429 or with an exhausted `X-Ratelimit-Remaining` header, are avoided until
+// their limit is reset.
+//
+// Transaction submissions are only retried when it is safe to do so: a 429
+// response means the transaction was not submitted and it is resent. When the
+// outcome of a submission is unknown (transport error, timeout or 5xx) the
+// endpoints are first searched for the transaction by its hash, which
+// requires FailoverOptions.NetworkPassphrase, and the transaction is resent
+// only if it is not found. Resending the same transaction envelope can never
+// apply it twice.
+//
+// Requests to URLs which do not belong to any of the endpoints, ex. friendbot,
+// are sent as is.
+type FailoverHTTP struct {
+	options   FailoverOptions
+	mu        sync.Mutex
+	endpoints []*failoverEndpoint
+}
+
+// NewFailoverHTTP returns a FailoverHTTP for the given Horizon URLs. All
+// endpoints are considered healthy, in the given order, until CheckHealth is
+// called.
+func NewFailoverHTTP(horizonURLs []string, options FailoverOptions) (*FailoverHTTP, error) {
+	if len(horizonURLs) == 0 {
+		return nil, errors.New("at least one horizon url is required")
+	}
+	if options.HTTP == nil {
+		options.HTTP = http.DefaultClient
+	}
+	if options.MaxLedgerLag == 0 {
+		options.MaxLedgerLag = DefaultMaxLedgerLag
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DefaultMaxRetries
+	}
+	if options.InitialBackoff == 0 {
+		options.InitialBackoff = DefaultInitialBackoff
+	}
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+
+	f := &FailoverHTTP{options: options}
+	for _, horizonURL := range horizonURLs {
+		u, err := url.Parse(horizonURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, errors.Errorf("invalid horizon url %q", horizonURL)
+		}
+		f.endpoints = append(f.endpoints, &failoverEndpoint{
+			url:     strings.TrimRight(horizonURL, "/") + "/",
+			healthy: true,
+		})
+	}
+	return f, nil
+}
+
+// Client returns a Client which sends its requests through f.
+func (f *FailoverHTTP) Client() *Client {
+	return &Client{
+		HorizonURL: f.endpoints[0].url,
+		HTTP:       f,
+	}
+}
+
+// CheckHealth fetches the root resource of every endpoint and updates their
+// health and latency. An endpoint is unhealthy when the request fails or when
+// its ingestion lags more than MaxLedgerLag ledgers behind its Stellar Core
+// or the Stellar Core of the most up to date endpoint.
+func (f *FailoverHTTP) CheckHealth(ctx context.Context) {
+	type result struct {
+		root    hProtocol.Root
+		err     error
+		latency time.Duration
+	}
+	results := make([]result, len(f.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range f.endpoints {
+		wg.Add(1)
+		go func(i int, endpointURL string) {
+			defer wg.Done()
+			client := &Client{HorizonURL: endpointURL, HTTP: f.options.HTTP}
+			start := time.Now()
+			root, err := client.RootWithContext(ctx)
+			results[i] = result{root: root, err: err, latency: time.Since(start)}
+		}(i, endpoint.url)
+	}
+	wg.Wait()
+
+	var latestLedger int32
+	for _, r := range results {
+		if r.err == nil && r.root.CoreSequence > latestLedger {
+			latestLedger = r.root.CoreSequence
+		}
+	}
+
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range results {
+		endpoint := f.endpoints[i]
+		endpoint.latency = r.latency
+		if r.err != nil {
+			endpoint.healthy = false
+			if horizonError := GetError(r.err); horizonError != nil {
+				f.updateRateLimit(endpoint, horizonError.Response, now)
+			}
+			continue
+		}
+		lag := r.root.CoreSequence - r.root.HorizonSequence
+		if behind := latestLedger - r.root.CoreSequence; behind > lag {
+			lag = behind
+		}
+		endpoint.healthy = lag <= f.options.MaxLedgerLag
+	}
+}
+
+// Run calls CheckHealth at the given interval until the context is done.
+func (f *FailoverHTTP) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get sends a GET request to the given URL.
+func (f *FailoverHTTP) Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.Do(req)
+}
+
+// PostForm sends a POST request with the given form to the given URL.
+func (f *FailoverHTTP) PostForm(url string, data url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return f.Do(req)
+}
+
+// Do sends the request to the best endpoint, retrying it as described in the
+// documentation of FailoverHTTP.
+func (f *FailoverHTTP) Do(req *http.Request) (*http.Response, error) {
+	path, ok := f.relativePath(req.URL.String())
+	if !ok {
+		return f.options.HTTP.Do(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading request body")
+		}
+	}
+
+	switch {
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		return f.send(req, path, body, f.options.MaxRetries, isRetryable)
+	case req.Method == http.MethodPost && strings.HasPrefix(path, "transactions_async"):
+		// Asynchronous submissions can be resent: stellar-core reports the
+		// transactions it already received as duplicates.
+		return f.send(req, path, body, f.options.MaxRetries, isRetryable)
+	case req.Method == http.MethodPost && strings.HasPrefix(path, "transactions"):
+		return f.submit(req, path, body)
+	default:
+		return f.send(req, path, body, 0, isRetryable)
+	}
+}
+
+// relativePath returns the path of the URL relative to the endpoint it
+// belongs to. URLs of paging links may refer to any of the endpoints.
+func (f *FailoverHTTP) relativePath(requestURL string) (string, bool) {
+	for _, endpoint := range f.endpoints {
+		if strings.HasPrefix(requestURL, endpoint.url) {
+			return strings.TrimPrefix(requestURL, endpoint.url), true
+		}
+	}
+	return "", false
+}
+
+// send sends the request, retrying it at most retries times while retryable
+// returns true for its outcome.
+func (f *FailoverHTTP) send(
+	req *http.Request,
+	path string,
+	body []byte,
+	retries int,
+	retryable func(*http.Response, error) bool,
+) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := f.attempt(req, req.Method, attempt, path, body)
+		if attempt >= retries || req.Context().Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		discard(resp)
+	}
+}
+
+// submit sends a transaction submission request, retrying it only when the
+// transaction cannot have been applied.
+func (f *FailoverHTTP) submit(req *http.Request, path string, body []byte) (*http.Response, error) {
+	hash, hashErr := transactionHash(body, f.options.NetworkPassphrase)
+	for attempt := 0; ; attempt++ {
+		resp, err := f.attempt(req, req.Method, attempt, path, body)
+		if attempt >= f.options.MaxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			// The transaction was rejected before being submitted.
+			discard(resp)
+			continue
+		}
+		if !isRetryable(resp, err) || hashErr != nil {
+			return resp, err
+		}
+
+		// The transaction may have been submitted, look it up before
+		// resending it.
+		discard(resp)
+		found, err := f.attempt(req, http.MethodGet, 0, "transactions/"+hash, nil)
+		if err == nil && found.StatusCode == http.StatusOK {
+			return found, nil
+		}
+		discard(found)
+	}
+}
+
+// attempt sends the request to the best endpoint, after waiting for the
+// backoff delay of the attempt and for the rate limit of the endpoint to be
+// reset.
+func (f *FailoverHTTP) attempt(
+	req *http.Request,
+	method string,
+	attempt int,
+	path string,
+	body []byte,
+) (*http.Response, error) {
+	endpoint, wait := f.best(attempt)
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	u, err := url.Parse(endpoint + path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing request url")
+	}
+	r := req.Clone(req.Context())
+	r.URL = u
+	r.Host = ""
+	if method != req.Method {
+		r.Method = method
+		r.Header.Del("Content-Type")
+	}
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	} else {
+		r.Body = nil
+		r.ContentLength = 0
+	}
+
+	resp, err := f.options.HTTP.Do(r)
+
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.endpoints {
+		if e.url != endpoint {
+			continue
+		}
+		if (err != nil && req.Context().Err() == nil) || (err == nil && resp.StatusCode >= 500) {
+			e.healthy = false
+		}
+		if err == nil {
+			f.updateRateLimit(e, resp, now)
+		}
+	}
+	return resp, err
+}
+
+// best returns the URL of the endpoint the given attempt should be sent to
+// and how long to wait before sending it.
+func (f *FailoverHTTP) best(attempt int) (string, time.Duration) {
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	endpoints := make([]*failoverEndpoint, len(f.endpoints))
+	copy(endpoints, f.endpoints)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		aLimited, bLimited := a.limitedUntil.After(now), b.limitedUntil.After(now)
+		switch {
+		case aLimited && bLimited:
+			return a.limitedUntil.Before(b.limitedUntil)
+		case aLimited != bLimited:
+			return bLimited
+		case a.healthy != b.healthy:
+			return a.healthy
+		default:
+			return a.latency < b.latency
+		}
+	})
+	endpoint := endpoints[0]
+
+	var wait time.Duration
+	if attempt > 0 {
+		wait = f.options.MaxBackoff
+		if shift := uint(attempt - 1); shift < 32 && f.options.InitialBackoff<<shift < wait {
+			wait = f.options.InitialBackoff << shift
+		}
+	}
+	if limit := endpoint.limitedUntil.Sub(now); limit > wait {
+		wait = limit
+	}
+	return endpoint.url, wait
+}
+
+// updateRateLimit records the rate limit of the endpoint advertised by the
+// response. f.mu must be held.
+func (f *FailoverHTTP) updateRateLimit(endpoint *failoverEndpoint, resp *http.Response, now time.Time) {
+	if resp == nil {
+		return
+	}
+	var reset time.Duration
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		reset = retryAfter(resp, now)
+		if reset <= 0 {
+			reset = f.options.InitialBackoff
+		}
+	case resp.Header.Get("X-Ratelimit-Remaining") == "0":
+		reset = retryAfter(resp, now)
+	}
+	if reset > 0 {
+		endpoint.limitedUntil = now.Add(reset)
+	}
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response or, when absent, by its X-Ratelimit-Reset header.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return date.Sub(now)
+		}
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Reset")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+// isRetryable returns true if the request failed in a way that another
+// attempt may succeed.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// discard closes the body of the response so the connection can be reused.
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// transactionHash returns the hex encoded hash of the transaction in the body
+// of a submission request.
+func transactionHash(body []byte, networkPassphrase string) (string, error) {
+	if networkPassphrase == "" {
+		return "", errors.New("network passphrase is not set")
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing submission form")
+	}
+	var envelope xdr.TransactionEnvelope
+	if err = xdr.SafeUnmarshalBase64(form.Get("tx"), &envelope); err != nil {
+		return "", errors.Wrap(err, "error decoding transaction envelope")
+	}
+	hash, err := network.HashTransactionInEnvelope(envelope, networkPassphrase)
+	if err != nil {
+		return "", errors.Wrap(err, "error hashing transaction")
+	}
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// ensure that the FailoverHTTP implements HTTP
+var _ HTTP = &FailoverHTTP{}
@@ -0,0 +1,215 @@
+package horizonclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/support/http/httptest"
+)
+
+func newTestFailover(t *testing.T, hmock *httptest.Client, passphrase string) *FailoverHTTP {
+	f, err := NewFailoverHTTP(
+		[]string{"https://horizon-a/", "https://horizon-b"},
+		FailoverOptions{
+			HTTP:              hmock,
+			NetworkPassphrase: passphrase,
+			InitialBackoff:    time.Millisecond,
+		},
+	)
+	require.NoError(t, err)
+	return f
+}
+
+func respond(status int, body string, calls *int) httpmock.Responder {
+	return func(*http.Request) (*http.Response, error) {
+		*calls++
+		return httpmock.NewStringResponse(status, body), nil
+	}
+}
+
+func TestFailoverRetriesGetRequests(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := newTestFailover(t, hmock, "").Client()
+	accountURL := "accounts/GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU"
+
+	var callsA, callsB int
+	hmock.On("GET", "https://horizon-a/"+accountURL).Return(respond(500, internalServerError, &callsA))
+	hmock.On("GET", "https://horizon-b/"+accountURL).Return(respond(200, accountResponse, &callsB))
+
+	request := AccountRequest{AccountID: "GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU"}
+	account, err := client.AccountDetail(request)
+	require.NoError(t, err)
+	assert.Equal(t, "GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU", account.AccountID)
+	assert.Equal(t, 1, callsA)
+	assert.Equal(t, 1, callsB)
+
+	// The failing endpoint is avoided until the next health check.
+	_, err = client.AccountDetail(request)
+	require.NoError(t, err)
+	assert.Equal(t, 1, callsA)
+	assert.Equal(t, 2, callsB)
+
+	// Requests to other urls are sent as is.
+	hmock.On("GET", "https://friendbot/?addr=G").ReturnString(200, "{}")
+	resp, err := client.HTTP.Get("https://friendbot/?addr=G")
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestFailoverRateLimit(t *testing.T) {
+	hmock := httptest.NewClient()
+	f := newTestFailover(t, hmock, "")
+	client := f.Client()
+
+	var callsA, callsB int
+	hmock.On("GET", "https://horizon-a/fee_stats").Return(func(*http.Request) (*http.Response, error) {
+		callsA++
+		resp := httpmock.NewStringResponse(429, `{"status": 429}`)
+		resp.Header.Set("Retry-After", "60")
+		return resp, nil
+	})
+	hmock.On("GET", "https://horizon-b/fee_stats").Return(func(*http.Request) (*http.Response, error) {
+		callsB++
+		resp := httpmock.NewStringResponse(200, "{}")
+		resp.Header.Set("X-Ratelimit-Remaining", "0")
+		resp.Header.Set("X-Ratelimit-Reset", "1")
+		return resp, nil
+	})
+
+	_, err := client.FeeStats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, callsA)
+	assert.Equal(t, 1, callsB)
+
+	// Both endpoints are rate limited, the request waits for the endpoint
+	// whose limit is reset first.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.FeeStatsWithContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, callsA)
+	assert.Equal(t, 1, callsB)
+
+	endpoint, wait := f.best(0)
+	assert.Equal(t, "https://horizon-b/", endpoint)
+	assert.True(t, wait > 900*time.Millisecond && wait <= time.Second, wait)
+}
+
+func TestFailoverCheckHealth(t *testing.T) {
+	hmock := httptest.NewClient()
+	f := newTestFailover(t, hmock, "")
+	root := `{"core_latest_ledger": %d, "history_latest_ledger": %d}`
+	var callsA, callsB int
+
+	// horizon-a lags behind its Stellar Core.
+	hmock.On("GET", "https://horizon-a/").Return(respond(200, fmt.Sprintf(root, 100, 80), &callsA))
+	hmock.On("GET", "https://horizon-b/").Return(respond(200, fmt.Sprintf(root, 100, 99), &callsB))
+	f.CheckHealth(context.Background())
+	endpoint, _ := f.best(0)
+	assert.Equal(t, "https://horizon-b/", endpoint)
+
+	// horizon-b lags behind the network.
+	hmock.On("GET", "https://horizon-a/").Return(respond(200, fmt.Sprintf(root, 120, 120), &callsA))
+	hmock.On("GET", "https://horizon-b/").Return(respond(200, fmt.Sprintf(root, 100, 100), &callsB))
+	f.CheckHealth(context.Background())
+	endpoint, _ = f.best(0)
+	assert.Equal(t, "https://horizon-a/", endpoint)
+
+	// horizon-a is down.
+	hmock.On("GET", "https://horizon-a/").ReturnError("connection refused")
+	f.CheckHealth(context.Background())
+	endpoint, _ = f.best(0)
+	assert.Equal(t, "https://horizon-b/", endpoint)
+	assert.Equal(t, 2, callsA)
+	assert.Equal(t, 3, callsB)
+}
+
+func TestFailoverSubmitTransaction(t *testing.T) {
+	txXdr := `AAAAABB90WssODNIgi6BHveqzxTRmIpvAFRyVNM+Hm2GVuCcAAAAZAAABD0AAuV/AAAAAAAAAAAAAAABAAAAAAAAAAAAAAAAyTBGxOgfSApppsTnb/YRr6gOR8WT0LZNrhLh4y3FCgoAAAAXSHboAAAAAAAAAAABhlbgnAAAAEAivKe977CQCxMOKTuj+cWTFqc2OOJU8qGr9afrgu2zDmQaX5Q0cNshc3PiBwe0qw/+D/qJk5QqM5dYeSUGeDQP`
+	hash, err := transactionHash([]byte(url.Values{"tx": {txXdr}}.Encode()), network.TestNetworkPassphrase)
+	require.NoError(t, err)
+
+	// The outcome of the submission is unknown without the network
+	// passphrase.
+	hmock := httptest.NewClient()
+	client := newTestFailover(t, hmock, "").Client()
+	var submissionsA, submissionsB, lookups int
+	hmock.On("POST", "https://horizon-a/transactions").Return(respond(504, timeoutResponse, &submissionsA))
+	_, err = client.SubmitTransactionXDR(txXdr)
+	assert.Equal(t, 504, GetError(err).Response.StatusCode)
+	assert.Equal(t, 1, submissionsA)
+
+	// The transaction was applied, it is found by its hash.
+	hmock = httptest.NewClient()
+	client = newTestFailover(t, hmock, network.TestNetworkPassphrase).Client()
+	submissionsA = 0
+	hmock.On("POST", "https://horizon-a/transactions").Return(respond(504, timeoutResponse, &submissionsA))
+	hmock.On("POST", "https://horizon-b/transactions").Return(respond(200, txSuccess, &submissionsB))
+	hmock.On("GET", "https://horizon-b/transactions/"+hash).Return(respond(200, txSuccess, &lookups))
+	tx, err := client.SubmitTransactionXDR(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, "bcc7a97264dca0a51a63f7ea971b5e7458e334489673078bb2a34eb0cce910ca", tx.Hash)
+	assert.Equal(t, 1, submissionsA)
+	assert.Equal(t, 0, submissionsB)
+	assert.Equal(t, 1, lookups)
+
+	// The transaction was not applied, it is resubmitted.
+	hmock = httptest.NewClient()
+	client = newTestFailover(t, hmock, network.TestNetworkPassphrase).Client()
+	submissionsA, submissionsB, lookups = 0, 0, 0
+	hmock.On("POST", "https://horizon-a/transactions").Return(respond(502, internalServerError, &submissionsA))
+	hmock.On("GET", "https://horizon-b/transactions/"+hash).Return(respond(404, notFoundResponse, &lookups))
+	hmock.On("POST", "https://horizon-b/transactions").Return(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, txXdr, req.FormValue("tx"))
+		return respond(200, txSuccess, &submissionsB)(req)
+	})
+	_, err = client.SubmitTransactionXDR(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, 1, submissionsA)
+	assert.Equal(t, 1, lookups)
+	assert.Equal(t, 1, submissionsB)
+
+	// Failed transactions are not retried.
+	hmock.On("POST", "https://horizon-b/transactions").Return(respond(400, transactionFailure, &submissionsB))
+	_, err = client.SubmitTransactionXDR(txXdr)
+	assert.Equal(t, 400, GetError(err).Response.StatusCode)
+	assert.Equal(t, 2, submissionsB)
+}
+
+func TestFailoverSubmitTransactionAsync(t *testing.T) {
+	txXdr := `AAAAABB90WssODNIgi6BHveqzxTRmIpvAFRyVNM+Hm2GVuCcAAAAZAAABD0AAuV/AAAAAAAAAAAAAAABAAAAAAAAAAAAAAAAyTBGxOgfSApppsTnb/YRr6gOR8WT0LZNrhLh4y3FCgoAAAAXSHboAAAAAAAAAAABhlbgnAAAAEAivKe977CQCxMOKTuj+cWTFqc2OOJU8qGr9afrgu2zDmQaX5Q0cNshc3PiBwe0qw/+D/qJk5QqM5dYeSUGeDQP`
+
+	// Asynchronous submissions are resent without looking the transaction
+	// up.
+	hmock := httptest.NewClient()
+	client := newTestFailover(t, hmock, network.TestNetworkPassphrase).Client()
+	var submissionsA, submissionsB int
+	hmock.On("POST", "https://horizon-a/transactions_async").Return(respond(502, internalServerError, &submissionsA))
+	hmock.On("POST", "https://horizon-b/transactions_async").Return(respond(409, `{"tx_status": "DUPLICATE", "hash": "bcc7a97264dca0a51a63f7ea971b5e7458e334489673078bb2a34eb0cce910ca"}`, &submissionsB))
+	resp, err := client.SubmitTransactionXDRAsync(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, "DUPLICATE", resp.TxStatus)
+	assert.Equal(t, 1, submissionsA)
+	assert.Equal(t, 1, submissionsB)
+}
+
+var internalServerError = `{
+  "type": "https://stellar.org/horizon-errors/server_error",
+  "title": "Internal Server Error",
+  "status": 500
+}`
+
+var timeoutResponse = `{
+  "type": "https://stellar.org/horizon-errors/timeout",
+  "title": "Timeout",
+  "status": 504
+}`
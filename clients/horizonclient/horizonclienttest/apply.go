@@ -0,0 +1,157 @@
+package horizonclienttest
+
+import (
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/stellar/go/exp/txsimulator"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/render/problem"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+)
+
+// appliedTransaction is a transaction applied to the ledger.
+type appliedTransaction struct {
+	envelope    xdr.TransactionEnvelope
+	envelopeXDR string
+	hash        [32]byte
+	innerHash   [32]byte
+	operations  []xdr.Operation
+	successful  bool
+	feeCharged  int64
+	resultXDR   string
+	resultCodes hProtocol.TransactionResultCodes
+}
+
+// submit applies the transaction envelope to the ledger with the simulator.
+// Rejected transactions do not close a ledger, the others close a ledger
+// whether their operations succeed or not. It returns the transaction
+// resource, or a problem for rejected and failed transactions.
+func (s *Server) submit(envelopeXDR string) (hProtocol.Transaction, *problem.P) {
+	tx := &appliedTransaction{envelopeXDR: envelopeXDR}
+	generic, err := txnbuild.TransactionFromXDR(envelopeXDR)
+	if err != nil {
+		return hProtocol.Transaction{}, malformedProblem(envelopeXDR)
+	}
+	if err = xdr.SafeUnmarshalBase64(envelopeXDR, &tx.envelope); err != nil {
+		return hProtocol.Transaction{}, malformedProblem(envelopeXDR)
+	}
+	feeBump, isFeeBump := generic.FeeBump()
+	transaction, _ := generic.Transaction()
+	if isFeeBump {
+		transaction = feeBump.InnerTransaction()
+		tx.hash, err = feeBump.Hash(s.networkPassphrase)
+		if err == nil {
+			tx.innerHash, err = transaction.Hash(s.networkPassphrase)
+		}
+	} else {
+		tx.hash, err = transaction.Hash(s.networkPassphrase)
+		tx.innerHash = tx.hash
+	}
+	if err != nil {
+		return hProtocol.Transaction{}, malformedProblem(envelopeXDR)
+	}
+	tx.operations = innerEnvelope(tx.envelope).Operations()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like Horizon, the result of the first submission of a transaction is
+	// returned when it is submitted again.
+	if record, ok := s.findTransaction(hex.EncodeToString(tx.hash[:])); ok {
+		if !record.resource.Successful {
+			return record.resource, failedProblem(envelopeXDR, record.resource.ResultXdr, record.resultCodes)
+		}
+		return record.resource, nil
+	}
+
+	s.sim.SetLedger(s.history.latest().sequence+1, time.Now())
+	var result txsimulator.Result
+	if isFeeBump {
+		result, err = s.sim.ApplyFeeBump(feeBump)
+	} else {
+		result, err = s.sim.Apply(transaction)
+	}
+	if errors.Cause(err) == txsimulator.ErrUnsupportedOperation {
+		return hProtocol.Transaction{}, problem.MakeInvalidFieldProblem("tx", err)
+	}
+	if err != nil {
+		return hProtocol.Transaction{}, &problem.ServerError
+	}
+	tx.resultXDR, err = xdr.MarshalBase64(result.XDR)
+	if err != nil {
+		return hProtocol.Transaction{}, &problem.ServerError
+	}
+	tx.resultCodes = hProtocol.TransactionResultCodes{
+		TransactionCode: result.TransactionCode,
+		OperationCodes:  result.OperationCodes,
+	}
+	tx.successful = result.Successful
+	tx.feeCharged = int64(result.XDR.FeeCharged)
+	if !result.Included {
+		return hProtocol.Transaction{}, failedProblem(envelopeXDR, tx.resultXDR, tx.resultCodes)
+	}
+
+	s.closeLedger(tx)
+	resource := s.history.transactions[len(s.history.transactions)-1].resource
+	if !tx.successful {
+		return resource, failedProblem(envelopeXDR, tx.resultXDR, tx.resultCodes)
+	}
+	return resource, nil
+}
+
+// innerEnvelope returns the inner transaction of fee bump transactions and
+// the transaction itself otherwise.
+func innerEnvelope(envelope xdr.TransactionEnvelope) xdr.TransactionEnvelope {
+	if !envelope.IsFeeBump() {
+		return envelope
+	}
+	return xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1:   envelope.FeeBump.Tx.InnerTx.V1,
+	}
+}
+
+// findTransaction returns the transaction with the given hash. It must be
+// called with s.mu held.
+func (s *Server) findTransaction(hash string) (transactionRecord, bool) {
+	for _, record := range s.history.transactions {
+		if record.resource.Hash == hash {
+			return record, true
+		}
+	}
+	return transactionRecord{}, false
+}
+
+func malformedProblem(envelopeXDR string) *problem.P {
+	return &problem.P{
+		Type:   problem.DefaultServiceHost + "transaction_malformed",
+		Title:  "Transaction Malformed",
+		Status: http.StatusBadRequest,
+		Detail: "Horizon could not decode the transaction envelope in this " +
+			"request. A transaction should be an XDR TransactionEnvelope struct " +
+			"encoded using base64.",
+		Extras: map[string]interface{}{
+			"envelope_xdr": envelopeXDR,
+		},
+	}
+}
+
+func failedProblem(envelopeXDR, resultXDR string, resultCodes hProtocol.TransactionResultCodes) *problem.P {
+	return &problem.P{
+		Type:   problem.DefaultServiceHost + "transaction_failed",
+		Title:  "Transaction Failed",
+		Status: http.StatusBadRequest,
+		Detail: "The transaction failed when submitted to the stellar network. " +
+			"The `extras.result_codes` field on this response contains further " +
+			"details.",
+		Extras: map[string]interface{}{
+			"envelope_xdr": envelopeXDR,
+			"result_xdr":   resultXDR,
+			"result_codes": resultCodes,
+		},
+	}
+}
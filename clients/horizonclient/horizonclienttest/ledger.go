@@ -0,0 +1,150 @@
+package horizonclienttest
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/xdr"
+)
+
+func accountKey(id xdr.AccountId) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:    xdr.LedgerEntryTypeAccount,
+		Account: &xdr.LedgerKeyAccount{AccountId: id},
+	}
+}
+
+func trustlineKey(id xdr.AccountId, asset xdr.Asset) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:      xdr.LedgerEntryTypeTrustline,
+		TrustLine: &xdr.LedgerKeyTrustLine{AccountId: id, Asset: asset.ToTrustLineAsset()},
+	}
+}
+
+func dataKey(id xdr.AccountId, name string) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeData,
+		Data: &xdr.LedgerKeyData{AccountId: id, DataName: xdr.String64(name)},
+	}
+}
+
+// addLiabilities adds the liabilities to the account or trustline entry.
+func addLiabilities(entry *xdr.LedgerEntry, buying, selling xdr.Int64) {
+	var liabilities *xdr.Liabilities
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeAccount:
+		account := entry.Data.Account
+		if account.Ext.V1 == nil {
+			account.Ext = xdr.AccountEntryExt{V: 1, V1: &xdr.AccountEntryExtensionV1{}}
+		}
+		liabilities = &account.Ext.V1.Liabilities
+	case xdr.LedgerEntryTypeTrustline:
+		trustline := entry.Data.TrustLine
+		if trustline.Ext.V1 == nil {
+			trustline.Ext = xdr.TrustLineEntryExt{V: 1, V1: &xdr.TrustLineEntryV1{}}
+		}
+		liabilities = &trustline.Ext.V1.Liabilities
+	}
+	liabilities.Buying += buying
+	liabilities.Selling += selling
+}
+
+// ledgerState holds the entries of the ledger by type, ordered like Horizon
+// orders them. Resources are built from a snapshot of the entries of the
+// simulator.
+type ledgerState struct {
+	accounts []xdr.LedgerEntry
+	offers   []xdr.LedgerEntry
+	// trustlines and data map the addresses of the accounts to their
+	// entries.
+	trustlines map[string][]xdr.LedgerEntry
+	data       map[string][]xdr.LedgerEntry
+}
+
+// state returns a snapshot of the ledger. It must be called with s.mu held.
+func (s *Server) state() (ledgerState, error) {
+	entries, err := s.sim.Entries()
+	if err != nil {
+		return ledgerState{}, err
+	}
+	st := ledgerState{
+		trustlines: map[string][]xdr.LedgerEntry{},
+		data:       map[string][]xdr.LedgerEntry{},
+	}
+	for _, entry := range entries {
+		switch entry.Data.Type {
+		case xdr.LedgerEntryTypeAccount:
+			st.accounts = append(st.accounts, entry)
+		case xdr.LedgerEntryTypeOffer:
+			st.offers = append(st.offers, entry)
+		case xdr.LedgerEntryTypeTrustline:
+			address := entry.Data.TrustLine.AccountId.Address()
+			st.trustlines[address] = append(st.trustlines[address], entry)
+		case xdr.LedgerEntryTypeData:
+			address := entry.Data.Data.AccountId.Address()
+			st.data[address] = append(st.data[address], entry)
+		}
+	}
+	sort.Slice(st.accounts, func(i, j int) bool {
+		return st.accounts[i].Data.Account.AccountId.Address() < st.accounts[j].Data.Account.AccountId.Address()
+	})
+	sort.Slice(st.offers, func(i, j int) bool {
+		return st.offers[i].Data.Offer.OfferId < st.offers[j].Data.Offer.OfferId
+	})
+	return st, nil
+}
+
+// ledgerRecord is a closed ledger.
+type ledgerRecord struct {
+	sequence   uint32
+	hash       string
+	prevHash   string
+	closedAt   time.Time
+	successful int32
+	failed     int32
+	operations int32
+}
+
+// transactionRecord is a transaction included in a ledger.
+type transactionRecord struct {
+	id          int64
+	ledger      uint32
+	accounts    map[string]bool
+	resource    hProtocol.Transaction
+	resultCodes hProtocol.TransactionResultCodes
+}
+
+// operationRecord is an operation of a transaction included in a ledger.
+type operationRecord struct {
+	id       int64
+	payment  bool
+	accounts map[string]bool
+	resource operations.Operation
+}
+
+// history holds the records of the closed ledgers, in ascending order.
+type history struct {
+	ledgers      []ledgerRecord
+	transactions []transactionRecord
+	operations   []operationRecord
+}
+
+// latest returns the last closed ledger.
+func (h *history) latest() ledgerRecord {
+	return h.ledgers[len(h.ledgers)-1]
+}
+
+// toid returns the ID of the operation of the transaction in the ledger, as
+// used by Horizon for paging tokens. Transactions have an operation index of
+// zero, ledgers a transaction index of zero.
+func toid(ledger uint32, transaction, operation int32) int64 {
+	return int64(ledger)<<32 | int64(transaction)<<12 | int64(operation)
+}
+
+// formatID formats a paging token.
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
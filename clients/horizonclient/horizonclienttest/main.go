@@ -0,0 +1,329 @@
+// Package horizonclienttest provides a fake Horizon server running in the
+// test process, to test code built on horizonclient end to end without
+// running stellar-core and Horizon.
+//
+// The server speaks the Horizon REST and SSE protocols and is backed by an
+// in-memory ledger which can be seeded with accounts, trustlines, data
+// entries and offers. Submitted transactions are applied to the ledger by
+// exp/txsimulator, each of them closing a new ledger, so they succeed and
+// fail with the result codes of the network. Transactions with operations
+// the simulator does not support are rejected with a bad request problem.
+package horizonclienttest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/exp/txsimulator"
+	pricepkg "github.com/stellar/go/price"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	// BaseFee is the minimum fee per operation, in stroops, of the ledger.
+	BaseFee = 100
+	// BaseReserve is the base reserve, in stroops, of the ledger.
+	BaseReserve = 5000000
+)
+
+// Server is a fake Horizon server. Use NewServer to create one and Close to
+// stop it.
+type Server struct {
+	// URL is the base URL of the server, of the form http://ipaddr:port.
+	URL string
+
+	networkPassphrase string
+	server            *httptest.Server
+	done              chan struct{}
+
+	mu      sync.Mutex
+	sim     *txsimulator.Simulator
+	history history
+	// changed is closed, and replaced, whenever the ledger changes to wake
+	// up the open streams.
+	changed chan struct{}
+}
+
+// NewServer starts a fake Horizon server for the given network. Its ledger
+// is empty, seed it with AddAccount.
+func NewServer(networkPassphrase string) *Server {
+	s := &Server{
+		networkPassphrase: networkPassphrase,
+		done:              make(chan struct{}),
+		sim: txsimulator.New(txsimulator.Config{
+			BaseFee:           BaseFee,
+			BaseReserve:       BaseReserve,
+			NetworkPassphrase: networkPassphrase,
+		}),
+		changed: make(chan struct{}),
+	}
+	s.server = httptest.NewServer(s.router())
+	s.URL = s.server.URL
+	s.closeLedger(nil)
+	return s
+}
+
+// Client returns a horizonclient.Client connected to the server.
+func (s *Server) Client() *horizonclient.Client {
+	return &horizonclient.Client{
+		HorizonURL: s.URL,
+		HTTP:       s.server.Client(),
+	}
+}
+
+// Close ends the open streams and shuts down the server.
+func (s *Server) Close() {
+	close(s.done)
+	s.server.Close()
+}
+
+// NetworkPassphrase returns the passphrase of the server's network.
+func (s *Server) NetworkPassphrase() string {
+	return s.networkPassphrase
+}
+
+// AddAccount creates an account with the given native balance. Its sequence
+// number starts at the current ledger, like accounts created by
+// transactions.
+func (s *Server) AddAccount(address, balance string) error {
+	id, err := xdr.AddressToAccountId(address)
+	if err != nil {
+		return errors.Wrap(err, "invalid address")
+	}
+	stroops, err := amount.ParseInt64(balance)
+	if err != nil {
+		return errors.Wrap(err, "invalid balance")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists, err := s.sim.Entry(accountKey(id))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.Errorf("account %s already exists", address)
+	}
+	ledger := s.history.latest().sequence
+	return s.add(xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId:  id,
+				Balance:    xdr.Int64(stroops),
+				SeqNum:     xdr.SequenceNumber(int64(ledger) << 32),
+				Thresholds: xdr.Thresholds{1, 0, 0, 0},
+			},
+		},
+	})
+}
+
+// AddTrustline adds an authorized trustline to the asset with the given
+// balance to the account. The trustline has the maximum limit.
+func (s *Server) AddTrustline(address string, asset txnbuild.Asset, balance string) error {
+	xdrAsset, err := asset.ToXDR()
+	if err != nil {
+		return errors.Wrap(err, "invalid asset")
+	}
+	if xdrAsset.Type == xdr.AssetTypeAssetTypeNative {
+		return errors.New("trustlines cannot be added to the native asset")
+	}
+	stroops, err := amount.ParseInt64(balance)
+	if err != nil {
+		return errors.Wrap(err, "invalid balance")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.accountEntry(address)
+	if err != nil {
+		return err
+	}
+	account.Data.Account.NumSubEntries++
+	return s.add(*account, xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: account.Data.Account.AccountId,
+				Asset:     xdrAsset.ToTrustLineAsset(),
+				Balance:   xdr.Int64(stroops),
+				Limit:     math.MaxInt64,
+				Flags:     xdr.Uint32(xdr.TrustLineFlagsAuthorizedFlag),
+			},
+		},
+	})
+}
+
+// AddData adds a data entry to the account.
+func (s *Server) AddData(address, name string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.accountEntry(address)
+	if err != nil {
+		return err
+	}
+	id := account.Data.Account.AccountId
+	_, exists, err := s.sim.Entry(dataKey(id, name))
+	if err != nil {
+		return err
+	}
+	entries := []xdr.LedgerEntry{{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeData,
+			Data: &xdr.DataEntry{AccountId: id, DataName: xdr.String64(name), DataValue: value},
+		},
+	}}
+	if !exists {
+		account.Data.Account.NumSubEntries++
+		entries = append(entries, *account)
+	}
+	return s.add(entries...)
+}
+
+// AddOffer adds an offer of the seller and returns its ID. The price is the
+// price of one unit of selling in terms of buying. The liabilities of the
+// offer are added to the seller, they are not checked against its balances.
+func (s *Server) AddOffer(seller string, selling, buying txnbuild.Asset, offerAmount, price string) (int64, error) {
+	sellingXDR, err := selling.ToXDR()
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid selling asset")
+	}
+	buyingXDR, err := buying.ToXDR()
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid buying asset")
+	}
+	stroops, err := amount.ParseInt64(offerAmount)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid amount")
+	}
+	xdrPrice, err := pricepkg.Parse(price)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid price")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.accountEntry(seller)
+	if err != nil {
+		return 0, err
+	}
+	account.Data.Account.NumSubEntries++
+	offer := xdr.OfferEntry{
+		SellerId: account.Data.Account.AccountId,
+		OfferId:  xdr.Int64(s.sim.LastOfferID() + 1),
+		Selling:  sellingXDR,
+		Buying:   buyingXDR,
+		Amount:   xdr.Int64(stroops),
+		Price:    xdrPrice,
+	}
+	entries := []xdr.LedgerEntry{{
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeOffer, Offer: &offer},
+	}}
+
+	// Like stellar-core, the liabilities are added to the seller. Issuers
+	// have no liabilities in their assets.
+	liabilities := txsimulator.OfferLiabilities(offer)
+	for _, l := range []struct {
+		asset           xdr.Asset
+		buying, selling xdr.Int64
+	}{
+		{sellingXDR, 0, liabilities.Selling},
+		{buyingXDR, liabilities.Buying, 0},
+	} {
+		if l.asset.Type == xdr.AssetTypeAssetTypeNative {
+			addLiabilities(account, l.buying, l.selling)
+			continue
+		}
+		if l.asset.GetIssuer() == seller {
+			continue
+		}
+		trustline, ok, err := s.sim.Entry(trustlineKey(offer.SellerId, l.asset))
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			addLiabilities(&trustline, l.buying, l.selling)
+			entries = append(entries, trustline)
+		}
+	}
+	entries = append(entries, *account)
+
+	if err := s.add(entries...); err != nil {
+		return 0, err
+	}
+	return int64(offer.OfferId), nil
+}
+
+// accountEntry returns the account entry with the given address. It must be
+// called with s.mu held.
+func (s *Server) accountEntry(address string) (*xdr.LedgerEntry, error) {
+	id, err := xdr.AddressToAccountId(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid address")
+	}
+	entry, ok, err := s.sim.Entry(accountKey(id))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("account %s does not exist", address)
+	}
+	return &entry, nil
+}
+
+// add adds the entries to the ledger, last modified in the latest ledger,
+// and wakes up the open streams. It must be called with s.mu held.
+func (s *Server) add(entries ...xdr.LedgerEntry) error {
+	for i := range entries {
+		entries[i].LastModifiedLedgerSeq = xdr.Uint32(s.history.latest().sequence)
+	}
+	if err := s.sim.Add(entries...); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// notify wakes up the open streams. It must be called with s.mu held.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// closeLedger closes a ledger including the given transaction, if any, and
+// returns its sequence. It must be called with s.mu held, except from
+// NewServer.
+func (s *Server) closeLedger(tx *appliedTransaction) uint32 {
+	record := ledgerRecord{
+		sequence: 1,
+		closedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if len(s.history.ledgers) > 0 {
+		latest := s.history.latest()
+		record.sequence = latest.sequence + 1
+		record.prevHash = latest.hash
+	}
+	hash := sha256.Sum256([]byte(record.prevHash + formatID(int64(record.sequence))))
+	record.hash = hex.EncodeToString(hash[:])
+
+	if tx != nil {
+		s.recordTransaction(record, tx)
+		if tx.successful {
+			record.successful = 1
+		} else {
+			record.failed = 1
+		}
+		record.operations = int32(len(tx.operations))
+	}
+	s.history.ledgers = append(s.history.ledgers, record)
+	s.notify()
+	return record.sequence
+}
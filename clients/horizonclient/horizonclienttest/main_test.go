@@ -0,0 +1,278 @@
+package horizonclienttest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/clients/horizonclient/horizonclienttest"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/txnbuild"
+)
+
+func buildTx(t *testing.T, client *horizonclient.Client, source *keypair.Full, ops ...txnbuild.Operation) *txnbuild.Transaction {
+	account, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: source.Address()})
+	require.NoError(t, err)
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &account,
+		IncrementSequenceNum: true,
+		Operations:           ops,
+		BaseFee:              txnbuild.MinBaseFee,
+		Timebounds:           txnbuild.NewInfiniteTimeout(),
+	})
+	require.NoError(t, err)
+	tx, err = tx.Sign(network.TestNetworkPassphrase, source)
+	require.NoError(t, err)
+	return tx
+}
+
+func nativeBalance(t *testing.T, client *horizonclient.Client, address string) string {
+	account, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: address})
+	require.NoError(t, err)
+	balance, err := account.GetNativeBalance()
+	require.NoError(t, err)
+	return balance
+}
+
+func TestSubmitTransaction(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	source, destination := keypair.MustRandom(), keypair.MustRandom()
+	require.NoError(t, server.AddAccount(source.Address(), "100"))
+
+	tx := buildTx(t, client, source,
+		&txnbuild.CreateAccount{Destination: destination.Address(), Amount: "10"},
+		&txnbuild.Payment{Destination: destination.Address(), Amount: "5", Asset: txnbuild.NativeAsset{}},
+	)
+	resource, err := client.SubmitTransaction(tx)
+	require.NoError(t, err)
+	assert.True(t, resource.Successful)
+	assert.Equal(t, int32(2), resource.Ledger)
+	assert.Equal(t, int64(200), resource.FeeCharged)
+
+	assert.Equal(t, "84.9999800", nativeBalance(t, client, source.Address()))
+	assert.Equal(t, "15.0000000", nativeBalance(t, client, destination.Address()))
+
+	account, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: source.Address()})
+	require.NoError(t, err)
+	assert.Equal(t, "4294967297", account.Sequence)
+
+	found, err := client.TransactionDetail(resource.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, resource.EnvelopeXdr, found.EnvelopeXdr)
+
+	payments, err := client.Payments(horizonclient.OperationRequest{ForAccount: destination.Address()})
+	require.NoError(t, err)
+	require.Len(t, payments.Embedded.Records, 2)
+	assert.IsType(t, operations.CreateAccount{}, payments.Embedded.Records[0])
+	payment := payments.Embedded.Records[1].(operations.Payment)
+	assert.Equal(t, source.Address(), payment.From)
+	assert.Equal(t, "5.0000000", payment.Amount)
+}
+
+func TestSubmitFailedTransaction(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	source, destination := keypair.MustRandom(), keypair.MustRandom()
+	require.NoError(t, server.AddAccount(source.Address(), "10"))
+	require.NoError(t, server.AddAccount(destination.Address(), "10"))
+
+	tx := buildTx(t, client, source,
+		&txnbuild.Payment{Destination: destination.Address(), Amount: "100", Asset: txnbuild.NativeAsset{}},
+	)
+	_, err := client.SubmitTransaction(tx)
+	require.Error(t, err)
+	resultCodes, err := horizonclient.GetError(err).ResultCodes()
+	require.NoError(t, err)
+	assert.Equal(t, "tx_failed", resultCodes.TransactionCode)
+	assert.Equal(t, []string{"op_underfunded"}, resultCodes.OperationCodes)
+
+	// The fee is charged and the sequence number consumed.
+	assert.Equal(t, "9.9999900", nativeBalance(t, client, source.Address()))
+	_, err = client.SubmitTransaction(buildTx(t, client, source,
+		&txnbuild.BumpSequence{BumpTo: 0},
+	))
+	require.NoError(t, err)
+
+	// Resubmitting the applied transaction returns its result, a new
+	// transaction with its sequence number is rejected.
+	_, err = client.SubmitTransaction(tx)
+	resultCodes, err = horizonclient.GetError(err).ResultCodes()
+	require.NoError(t, err)
+	assert.Equal(t, "tx_failed", resultCodes.TransactionCode)
+
+	tx, err = txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &txnbuild.SimpleAccount{AccountID: source.Address(), Sequence: 1},
+		Operations:    []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 0}},
+		BaseFee:       txnbuild.MinBaseFee,
+		Timebounds:    txnbuild.NewInfiniteTimeout(),
+	})
+	require.NoError(t, err)
+	tx, err = tx.Sign(network.TestNetworkPassphrase, source)
+	require.NoError(t, err)
+	_, err = client.SubmitTransaction(tx)
+	resultCodes, err = horizonclient.GetError(err).ResultCodes()
+	require.NoError(t, err)
+	assert.Equal(t, "tx_bad_seq", resultCodes.TransactionCode)
+	assert.Empty(t, resultCodes.OperationCodes)
+}
+
+func TestTrustlinesAndOffers(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	issuer, holder := keypair.MustRandom(), keypair.MustRandom()
+	usd := txnbuild.CreditAsset{Code: "USD", Issuer: issuer.Address()}
+	require.NoError(t, server.AddAccount(issuer.Address(), "100"))
+	require.NoError(t, server.AddAccount(holder.Address(), "100"))
+
+	_, err := client.SubmitTransaction(buildTx(t, client, holder,
+		&txnbuild.ChangeTrust{Line: usd.MustToChangeTrustAsset()},
+	))
+	require.NoError(t, err)
+	_, err = client.SubmitTransaction(buildTx(t, client, issuer,
+		&txnbuild.Payment{Destination: holder.Address(), Amount: "50", Asset: usd},
+	))
+	require.NoError(t, err)
+	_, err = client.SubmitTransaction(buildTx(t, client, holder,
+		&txnbuild.ManageSellOffer{Selling: usd, Buying: txnbuild.NativeAsset{}, Amount: "20", Price: "2"},
+	))
+	require.NoError(t, err)
+
+	account, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: holder.Address()})
+	require.NoError(t, err)
+	assert.Equal(t, "50.0000000", account.GetCreditBalance("USD", issuer.Address()))
+	assert.Equal(t, int32(2), account.SubentryCount)
+
+	offers, err := client.Offers(horizonclient.OfferRequest{ForAccount: holder.Address()})
+	require.NoError(t, err)
+	require.Len(t, offers.Embedded.Records, 1)
+	assert.Equal(t, "20.0000000", offers.Embedded.Records[0].Amount)
+	assert.Equal(t, "2.0000000", offers.Embedded.Records[0].Price)
+
+	assets, err := client.Assets(horizonclient.AssetRequest{ForAssetCode: "USD"})
+	require.NoError(t, err)
+	require.Len(t, assets.Embedded.Records, 1)
+	assert.Equal(t, "50.0000000", assets.Embedded.Records[0].Amount)
+}
+
+func TestNotFound(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	_, err := client.AccountDetail(horizonclient.AccountRequest{AccountID: keypair.MustRandom().Address()})
+	assert.True(t, horizonclient.IsNotFoundError(err))
+}
+
+func TestPaging(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	source := keypair.MustRandom()
+	require.NoError(t, server.AddAccount(source.Address(), "100"))
+	for i := 0; i < 5; i++ {
+		_, err := client.SubmitTransaction(buildTx(t, client, source, &txnbuild.BumpSequence{BumpTo: 0}))
+		require.NoError(t, err)
+	}
+
+	page, err := client.Transactions(horizonclient.TransactionRequest{Limit: 2, Order: horizonclient.OrderDesc})
+	require.NoError(t, err)
+	require.Len(t, page.Embedded.Records, 2)
+	assert.Equal(t, int32(6), page.Embedded.Records[0].Ledger)
+	page, err = client.NextTransactionsPage(page)
+	require.NoError(t, err)
+	require.Len(t, page.Embedded.Records, 2)
+	assert.Equal(t, int32(4), page.Embedded.Records[0].Ledger)
+
+	it := client.TransactionsIterator(context.Background(), horizonclient.TransactionRequest{Limit: 2}, horizonclient.IteratorOptions{})
+	defer it.Close()
+	var ledgers []int32
+	for it.Next() {
+		ledgers = append(ledgers, it.Transaction().Ledger)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []int32{2, 3, 4, 5, 6}, ledgers)
+}
+
+func TestStreamPayments(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	source, destination := keypair.MustRandom(), keypair.MustRandom()
+	require.NoError(t, server.AddAccount(source.Address(), "100"))
+	require.NoError(t, server.AddAccount(destination.Address(), "100"))
+	_, err := client.SubmitTransaction(buildTx(t, client, source,
+		&txnbuild.Payment{Destination: destination.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}},
+	))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	received := make(chan operations.Payment)
+	done := make(chan error)
+	go func() {
+		done <- client.StreamPayments(ctx, horizonclient.OperationRequest{ForAccount: destination.Address()}, func(op operations.Operation) {
+			received <- op.(operations.Payment)
+		})
+	}()
+
+	// The stream starts at the current ledger, the payment above is not
+	// received.
+	time.Sleep(100 * time.Millisecond)
+	_, err = client.SubmitTransaction(buildTx(t, client, source,
+		&txnbuild.Payment{Destination: destination.Address(), Amount: "2", Asset: txnbuild.NativeAsset{}},
+	))
+	require.NoError(t, err)
+	select {
+	case payment := <-received:
+		assert.Equal(t, "2.0000000", payment.Amount)
+	case <-ctx.Done():
+		t.Fatal("payment not streamed")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestStreamAccount(t *testing.T) {
+	server := horizonclienttest.NewServer(network.TestNetworkPassphrase)
+	defer server.Close()
+	client := server.Client()
+
+	account := keypair.MustRandom()
+	require.NoError(t, server.AddAccount(account.Address(), "100"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	received := make(chan hProtocol.Account)
+	done := make(chan error)
+	go func() {
+		done <- client.StreamAccount(ctx, horizonclient.AccountRequest{AccountID: account.Address()}, func(a hProtocol.Account) {
+			received <- a
+		})
+	}()
+
+	a := <-received
+	assert.Empty(t, a.Data)
+	require.NoError(t, server.AddData(account.Address(), "name", []byte("value")))
+	a = <-received
+	assert.Equal(t, "dmFsdWU=", a.Data["name"])
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,387 @@
+package horizonclienttest
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/stellar/go/amount"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/base"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/support/render/hal"
+	"github.com/stellar/go/xdr"
+)
+
+// link returns a link to the path on the server.
+func (s *Server) link(format string, args ...interface{}) hal.Link {
+	return hal.NewLink(s.URL + fmt.Sprintf(format, args...))
+}
+
+// recordTransaction adds the transaction and its operations to the history
+// of the ledger. It must be called with s.mu held.
+func (s *Server) recordTransaction(ledger ledgerRecord, tx *appliedTransaction) {
+	inner := innerEnvelope(tx.envelope)
+	source := inner.SourceAccount().ToAccountId().Address()
+	hash := hex.EncodeToString(tx.hash[:])
+	id := toid(ledger.sequence, 1, 0)
+
+	resource := hProtocol.Transaction{
+		ID:              hash,
+		PT:              formatID(id),
+		Successful:      tx.successful,
+		Hash:            hash,
+		Ledger:          int32(ledger.sequence),
+		LedgerCloseTime: ledger.closedAt,
+		Account:         source,
+		AccountSequence: strconv.FormatInt(inner.SeqNum(), 10),
+		FeeAccount:      source,
+		FeeCharged:      tx.feeCharged,
+		MaxFee:          int64(inner.Fee()),
+		OperationCount:  int32(len(tx.operations)),
+		EnvelopeXdr:     tx.envelopeXDR,
+		ResultXdr:       tx.resultXDR,
+		Signatures:      signatures(inner.Signatures()),
+	}
+	resource.Links.Self = s.link("/transactions/%s", hash)
+	resource.Links.Account = s.link("/accounts/%s", source)
+	resource.Links.Ledger = s.link("/ledgers/%d", ledger.sequence)
+	resource.Links.Operations = s.link("/transactions/%s/operations{?cursor,limit,order}", hash)
+	resource.Links.Transaction = resource.Links.Self
+	if tx.envelope.IsFeeBump() {
+		resource.FeeAccount = tx.envelope.FeeBumpAccount().ToAccountId().Address()
+		resource.MaxFee = tx.envelope.FeeBumpFee()
+		resource.Signatures = signatures(tx.envelope.FeeBumpSignatures())
+		resource.FeeBumpTransaction = &hProtocol.FeeBumpTransaction{
+			Hash:       hash,
+			Signatures: resource.Signatures,
+		}
+		resource.InnerTransaction = &hProtocol.InnerTransaction{
+			Hash:       hex.EncodeToString(tx.innerHash[:]),
+			Signatures: signatures(inner.Signatures()),
+			MaxFee:     int64(inner.Fee()),
+		}
+	}
+	setMemo(&resource, inner.Memo())
+	if tb := inner.TimeBounds(); tb != nil {
+		resource.ValidAfter = time.Unix(int64(tb.MinTime), 0).UTC().Format(time.RFC3339)
+		if tb.MaxTime != 0 {
+			resource.ValidBefore = time.Unix(int64(tb.MaxTime), 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	accounts := map[string]bool{source: true, resource.FeeAccount: true}
+	for i, op := range tx.operations {
+		opSource := source
+		if op.SourceAccount != nil {
+			opSource = op.SourceAccount.ToAccountId().Address()
+		}
+		opID := toid(ledger.sequence, 1, int32(i+1))
+		record := operationRecord{
+			id:       opID,
+			accounts: map[string]bool{opSource: true},
+		}
+
+		b := operations.Base{
+			ID:                    formatID(opID),
+			PT:                    formatID(opID),
+			TransactionSuccessful: tx.successful,
+			SourceAccount:         opSource,
+			Type:                  operations.TypeNames[op.Body.Type],
+			TypeI:                 int32(op.Body.Type),
+			LedgerCloseTime:       ledger.closedAt,
+			TransactionHash:       hash,
+		}
+		b.Links.Self = s.link("/operations/%d", opID)
+		b.Links.Transaction = resource.Links.Self
+		record.resource, record.payment = operationResource(b, op, record.accounts)
+
+		for account := range record.accounts {
+			accounts[account] = true
+		}
+		s.history.operations = append(s.history.operations, record)
+	}
+
+	s.history.transactions = append(s.history.transactions, transactionRecord{
+		id:          id,
+		ledger:      ledger.sequence,
+		accounts:    accounts,
+		resource:    resource,
+		resultCodes: tx.resultCodes,
+	})
+}
+
+// operationResource returns the resource of the operation and whether it is
+// a payment. The participants of the operation are added to accounts.
+func operationResource(b operations.Base, op xdr.Operation, accounts map[string]bool) (operations.Operation, bool) {
+	switch op.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		body := op.Body.MustCreateAccountOp()
+		accounts[body.Destination.Address()] = true
+		return operations.CreateAccount{
+			Base:            b,
+			StartingBalance: amount.String(body.StartingBalance),
+			Funder:          b.SourceAccount,
+			Account:         body.Destination.Address(),
+		}, true
+	case xdr.OperationTypePayment:
+		body := op.Body.MustPaymentOp()
+		to := body.Destination.ToAccountId().Address()
+		accounts[to] = true
+		return operations.Payment{
+			Base:   b,
+			Asset:  assetResource(body.Asset),
+			From:   b.SourceAccount,
+			To:     to,
+			Amount: amount.String(body.Amount),
+		}, true
+	case xdr.OperationTypeChangeTrust:
+		body := op.Body.MustChangeTrustOp()
+		resource := operations.ChangeTrust{
+			Base:    b,
+			Limit:   amount.String(body.Limit),
+			Trustor: b.SourceAccount,
+		}
+		if body.Line.Type != xdr.AssetTypeAssetTypePoolShare {
+			resource.Asset = assetResource(body.Line.ToAsset())
+			resource.Trustee = resource.Asset.Issuer
+		}
+		return resource, false
+	case xdr.OperationTypeManageData:
+		body := op.Body.MustManageDataOp()
+		resource := operations.ManageData{Base: b, Name: string(body.DataName)}
+		if body.DataValue != nil {
+			resource.Value = base64.StdEncoding.EncodeToString(*body.DataValue)
+		}
+		return resource, false
+	case xdr.OperationTypeBumpSequence:
+		body := op.Body.MustBumpSequenceOp()
+		return operations.BumpSequence{
+			Base:   b,
+			BumpTo: strconv.FormatInt(int64(body.BumpTo), 10),
+		}, false
+	case xdr.OperationTypeManageSellOffer:
+		body := op.Body.MustManageSellOfferOp()
+		selling, buying := assetResource(body.Selling), assetResource(body.Buying)
+		return operations.ManageSellOffer{
+			Offer: operations.Offer{
+				Base:               b,
+				Amount:             amount.String(body.Amount),
+				Price:              body.Price.String(),
+				PriceR:             base.Price{N: int32(body.Price.N), D: int32(body.Price.D)},
+				BuyingAssetType:    buying.Type,
+				BuyingAssetCode:    buying.Code,
+				BuyingAssetIssuer:  buying.Issuer,
+				SellingAssetType:   selling.Type,
+				SellingAssetCode:   selling.Code,
+				SellingAssetIssuer: selling.Issuer,
+			},
+			OfferID: int64(body.OfferId),
+		}, false
+	}
+	return b, false
+}
+
+// accountResource returns the resource of the account entry, with the
+// trustlines and data entries of the account in the ledger.
+func (s *Server) accountResource(entry xdr.LedgerEntry, st ledgerState) hProtocol.Account {
+	a := entry.Data.Account
+	address := a.AccountId.Address()
+	resource := hProtocol.Account{
+		ID:                 address,
+		AccountID:          address,
+		Sequence:           strconv.FormatInt(int64(a.SeqNum), 10),
+		SubentryCount:      int32(a.NumSubEntries),
+		HomeDomain:         string(a.HomeDomain),
+		LastModifiedLedger: uint32(entry.LastModifiedLedgerSeq),
+		Thresholds: hProtocol.AccountThresholds{
+			LowThreshold:  a.ThresholdLow(),
+			MedThreshold:  a.ThresholdMedium(),
+			HighThreshold: a.ThresholdHigh(),
+		},
+		Flags: hProtocol.AccountFlags{
+			AuthRequired:        xdr.AccountFlags(a.Flags).IsAuthRequired(),
+			AuthRevocable:       xdr.AccountFlags(a.Flags).IsAuthRevocable(),
+			AuthImmutable:       xdr.AccountFlags(a.Flags).IsAuthImmutable(),
+			AuthClawbackEnabled: xdr.AccountFlags(a.Flags).IsAuthClawbackEnabled(),
+		},
+		Data:          map[string]string{},
+		NumSponsoring: uint32(a.NumSponsoring()),
+		NumSponsored:  uint32(a.NumSponsored()),
+		Sponsor:       sponsor(entry),
+		PT:            address,
+	}
+	if a.InflationDest != nil {
+		resource.InflationDestination = a.InflationDest.Address()
+	}
+	resource.Links.Self = s.link("/accounts/%s", address)
+	resource.Links.Transactions = s.link("/accounts/%s/transactions{?cursor,limit,order}", address)
+	resource.Links.Operations = s.link("/accounts/%s/operations{?cursor,limit,order}", address)
+	resource.Links.Payments = s.link("/accounts/%s/payments{?cursor,limit,order}", address)
+	resource.Links.Offers = s.link("/accounts/%s/offers{?cursor,limit,order}", address)
+	resource.Links.Data = s.link("/accounts/%s/data/{key}", address)
+
+	sponsors := a.SignerSponsoringIDs()
+	for i, signer := range a.Signers {
+		key := signer.Key.Address()
+		signerResource := hProtocol.Signer{
+			Weight: int32(signer.Weight),
+			Key:    key,
+			Type:   hProtocol.MustKeyTypeFromAddress(key),
+		}
+		if sponsors[i] != nil {
+			signerResource.Sponsor = (*sponsors[i]).Address()
+		}
+		resource.Signers = append(resource.Signers, signerResource)
+	}
+	if a.MasterKeyWeight() > 0 {
+		resource.Signers = append(resource.Signers, hProtocol.Signer{
+			Weight: int32(a.MasterKeyWeight()),
+			Key:    address,
+			Type:   hProtocol.MustKeyTypeFromAddress(address),
+		})
+	}
+
+	for _, trustline := range st.trustlines[address] {
+		tl := trustline.Data.TrustLine
+		liabilities := tl.Liabilities()
+		flags := xdr.TrustLineFlags(tl.Flags)
+		isAuthorized := flags.IsAuthorized()
+		isAuthorizedToMaintainLiabilities := flags.IsAuthorizedToMaintainLiabilitiesFlag()
+		isClawbackEnabled := flags.IsClawbackEnabledFlag()
+		balance := hProtocol.Balance{
+			Balance:                           amount.String(tl.Balance),
+			Limit:                             amount.String(tl.Limit),
+			Sponsor:                           sponsor(trustline),
+			LastModifiedLedger:                uint32(trustline.LastModifiedLedgerSeq),
+			IsAuthorized:                      &isAuthorized,
+			IsAuthorizedToMaintainLiabilities: &isAuthorizedToMaintainLiabilities,
+			IsClawbackEnabled:                 &isClawbackEnabled,
+		}
+		if tl.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			balance.LiquidityPoolId = hex.EncodeToString(tl.Asset.LiquidityPoolId[:])
+			balance.Asset.Type = "liquidity_pool_shares"
+		} else {
+			balance.BuyingLiabilities = amount.String(liabilities.Buying)
+			balance.SellingLiabilities = amount.String(liabilities.Selling)
+			balance.Asset = assetResource(tl.Asset.ToAsset())
+		}
+		resource.Balances = append(resource.Balances, balance)
+	}
+	liabilities := a.Liabilities()
+	resource.Balances = append(resource.Balances, hProtocol.Balance{
+		Balance:            amount.String(a.Balance),
+		BuyingLiabilities:  amount.String(liabilities.Buying),
+		SellingLiabilities: amount.String(liabilities.Selling),
+		Asset:              base.Asset{Type: "native"},
+	})
+
+	for _, data := range st.data[address] {
+		d := data.Data.Data
+		resource.Data[string(d.DataName)] = base64.StdEncoding.EncodeToString(d.DataValue)
+	}
+	return resource
+}
+
+// offerResource returns the resource of the offer entry.
+func (s *Server) offerResource(entry xdr.LedgerEntry) hProtocol.Offer {
+	o := entry.Data.Offer
+	seller := o.SellerId.Address()
+	resource := hProtocol.Offer{
+		ID:                 int64(o.OfferId),
+		PT:                 formatID(int64(o.OfferId)),
+		Seller:             seller,
+		Selling:            hProtocol.Asset(assetResource(o.Selling)),
+		Buying:             hProtocol.Asset(assetResource(o.Buying)),
+		Amount:             amount.String(o.Amount),
+		PriceR:             hProtocol.Price{N: int32(o.Price.N), D: int32(o.Price.D)},
+		Price:              o.Price.String(),
+		LastModifiedLedger: int32(entry.LastModifiedLedgerSeq),
+		Sponsor:            sponsor(entry),
+	}
+	resource.Links.Self = s.link("/offers/%d", o.OfferId)
+	resource.Links.OfferMaker = s.link("/accounts/%s", seller)
+	return resource
+}
+
+// sponsor returns the address of the sponsor of the entry, if any.
+func sponsor(entry xdr.LedgerEntry) string {
+	if id := entry.SponsoringID(); id != nil {
+		return (*id).Address()
+	}
+	return ""
+}
+
+// ledgerResource returns the resource of the ledger.
+func (s *Server) ledgerResource(l ledgerRecord) hProtocol.Ledger {
+	failed, txSetOperations := l.failed, l.operations
+	resource := hProtocol.Ledger{
+		ID:                         l.hash,
+		PT:                         formatID(toid(l.sequence, 0, 0)),
+		Hash:                       l.hash,
+		PrevHash:                   l.prevHash,
+		Sequence:                   int32(l.sequence),
+		SuccessfulTransactionCount: l.successful,
+		FailedTransactionCount:     &failed,
+		OperationCount:             l.operations,
+		TxSetOperationCount:        &txSetOperations,
+		ClosedAt:                   l.closedAt,
+		TotalCoins:                 "100000000000.0000000",
+		FeePool:                    "0.0000000",
+		BaseFee:                    BaseFee,
+		BaseReserve:                BaseReserve,
+		MaxTxSetSize:               1,
+		ProtocolVersion:            protocolVersion,
+	}
+	resource.Links.Self = s.link("/ledgers/%d", l.sequence)
+	resource.Links.Transactions = s.link("/ledgers/%d/transactions{?cursor,limit,order}", l.sequence)
+	resource.Links.Operations = s.link("/ledgers/%d/operations{?cursor,limit,order}", l.sequence)
+	resource.Links.Payments = s.link("/ledgers/%d/payments{?cursor,limit,order}", l.sequence)
+	return resource
+}
+
+// protocolVersion is the protocol version of the ledgers.
+const protocolVersion = 18
+
+// assetResource returns the Horizon representation of the asset.
+func assetResource(asset xdr.Asset) base.Asset {
+	var resource base.Asset
+	if err := asset.Extract(&resource.Type, &resource.Code, &resource.Issuer); err != nil {
+		panic(err)
+	}
+	return resource
+}
+
+// signatures returns the base64 encoded signatures.
+func signatures(decorated []xdr.DecoratedSignature) []string {
+	encoded := make([]string, len(decorated))
+	for i, signature := range decorated {
+		encoded[i] = base64.StdEncoding.EncodeToString(signature.Signature)
+	}
+	return encoded
+}
+
+// setMemo sets the memo of the transaction resource.
+func setMemo(resource *hProtocol.Transaction, memo xdr.Memo) {
+	switch memo.Type {
+	case xdr.MemoTypeMemoText:
+		resource.MemoType = "text"
+		resource.Memo = memo.MustText()
+		resource.MemoBytes = base64.StdEncoding.EncodeToString([]byte(memo.MustText()))
+	case xdr.MemoTypeMemoId:
+		resource.MemoType = "id"
+		resource.Memo = strconv.FormatUint(uint64(memo.MustId()), 10)
+	case xdr.MemoTypeMemoHash:
+		hash := memo.MustHash()
+		resource.MemoType = "hash"
+		resource.Memo = base64.StdEncoding.EncodeToString(hash[:])
+	case xdr.MemoTypeMemoReturn:
+		hash := memo.MustRetHash()
+		resource.MemoType = "return"
+		resource.Memo = base64.StdEncoding.EncodeToString(hash[:])
+	default:
+		resource.MemoType = "none"
+	}
+}
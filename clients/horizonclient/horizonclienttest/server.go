@@ -0,0 +1,640 @@
+package horizonclienttest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/keypair"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/render/hal"
+	"github.com/stellar/go/support/render/httpjson"
+	"github.com/stellar/go/support/render/problem"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 200
+)
+
+// objectFunc returns the resource requested by r. It is called with s.mu
+// held.
+type objectFunc func(r *http.Request) (interface{}, *problem.P)
+
+// collectionFunc returns the records of the collection requested by r in
+// ascending order. It is called with s.mu held.
+type collectionFunc func(r *http.Request) ([]hal.Pageable, *problem.P)
+
+func (s *Server) router() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", s.getRoot)
+	r.Get("/fee_stats", s.getFeeStats)
+
+	r.Get("/accounts/{account_id}", s.object(s.account))
+	r.Get("/accounts/{account_id}/data/{key}", s.object(s.accountData))
+	r.Get("/accounts/{account_id}/transactions", s.collection(s.transactions))
+	r.Get("/accounts/{account_id}/operations", s.collection(s.operations(false)))
+	r.Get("/accounts/{account_id}/payments", s.collection(s.operations(true)))
+	r.Get("/accounts/{account_id}/offers", s.collection(s.offers))
+
+	r.Get("/ledgers", s.collection(s.ledgers))
+	r.Get("/ledgers/{ledger_id}", s.object(s.ledger))
+	r.Get("/ledgers/{ledger_id}/transactions", s.collection(s.transactions))
+	r.Get("/ledgers/{ledger_id}/operations", s.collection(s.operations(false)))
+	r.Get("/ledgers/{ledger_id}/payments", s.collection(s.operations(true)))
+
+	r.Get("/transactions", s.collection(s.transactions))
+	r.Post("/transactions", s.postTransaction)
+	r.Get("/transactions/{tx_id}", s.object(s.transaction))
+	r.Get("/transactions/{tx_id}/operations", s.collection(s.operations(false)))
+	r.Get("/transactions/{tx_id}/payments", s.collection(s.operations(true)))
+
+	r.Get("/operations", s.collection(s.operations(false)))
+	r.Get("/operations/{op_id}", s.object(s.operation))
+	r.Get("/payments", s.collection(s.operations(true)))
+
+	r.Get("/offers", s.collection(s.offers))
+	r.Get("/offers/{offer_id}", s.object(s.offer))
+	r.Get("/assets", s.collection(s.assets))
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		renderProblem(w, problem.NotFound)
+	})
+	return r
+}
+
+func (s *Server) getRoot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latest := s.history.latest()
+	s.mu.Unlock()
+
+	var root hProtocol.Root
+	root.Links.Self = s.link("/")
+	root.Links.Account = s.link("/accounts/{account_id}")
+	root.Links.AccountTransactions = s.link("/accounts/{account_id}/transactions{?cursor,limit,order}")
+	root.Links.Assets = s.link("/assets{?asset_code,asset_issuer,cursor,limit,order}")
+	root.Links.FeeStats = s.link("/fee_stats")
+	root.Links.Ledger = s.link("/ledgers/{sequence}")
+	root.Links.Ledgers = s.link("/ledgers{?cursor,limit,order}")
+	root.Links.Operation = s.link("/operations/{id}")
+	root.Links.Operations = s.link("/operations{?cursor,limit,order,include_failed}")
+	root.Links.Payments = s.link("/payments{?cursor,limit,order,include_failed}")
+	root.Links.Transaction = s.link("/transactions/{hash}")
+	root.Links.Transactions = s.link("/transactions{?cursor,limit,order}")
+	offer, offers := s.link("/offers/{offer_id}"), s.link("/offers{?selling,buying,seller,cursor,limit,order}")
+	root.Links.Offer, root.Links.Offers = &offer, &offers
+
+	root.HorizonVersion = "horizonclienttest"
+	root.StellarCoreVersion = "horizonclienttest"
+	root.IngestSequence = latest.sequence
+	root.HorizonSequence = int32(latest.sequence)
+	root.HorizonLatestClosedAt = latest.closedAt
+	root.HistoryElderSequence = 1
+	root.CoreSequence = int32(latest.sequence)
+	root.NetworkPassphrase = s.networkPassphrase
+	root.CurrentProtocolVersion = protocolVersion
+	root.CoreSupportedProtocolVersion = protocolVersion
+	httpjson.Render(w, root, httpjson.HALJSON)
+}
+
+func (s *Server) getFeeStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latest := s.history.latest()
+	s.mu.Unlock()
+
+	// The ledgers are never full, the minimum fee is always enough.
+	distribution := hProtocol.FeeDistribution{
+		Max: BaseFee, Min: BaseFee, Mode: BaseFee,
+		P10: BaseFee, P20: BaseFee, P30: BaseFee, P40: BaseFee, P50: BaseFee,
+		P60: BaseFee, P70: BaseFee, P80: BaseFee, P90: BaseFee, P95: BaseFee, P99: BaseFee,
+	}
+	httpjson.Render(w, hProtocol.FeeStats{
+		LastLedger:        latest.sequence,
+		LastLedgerBaseFee: BaseFee,
+		FeeCharged:        distribution,
+		MaxFee:            distribution,
+	}, httpjson.JSON)
+}
+
+func (s *Server) postTransaction(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderProblem(w, problem.BadRequest)
+		return
+	}
+	envelopeXDR := r.PostForm.Get("tx")
+	if envelopeXDR == "" {
+		renderProblem(w, *problem.MakeInvalidFieldProblem("tx", errors.New("Transaction envelope is missing")))
+		return
+	}
+	resource, p := s.submit(envelopeXDR)
+	if p != nil {
+		renderProblem(w, *p)
+		return
+	}
+	httpjson.Render(w, resource, httpjson.HALJSON)
+}
+
+func (s *Server) account(r *http.Request) (interface{}, *problem.P) {
+	st, err := s.state()
+	if err != nil {
+		return nil, &problem.ServerError
+	}
+	address := chi.URLParam(r, "account_id")
+	for _, entry := range st.accounts {
+		if entry.Data.Account.AccountId.Address() == address {
+			return s.accountResource(entry, st), nil
+		}
+	}
+	return nil, &problem.NotFound
+}
+
+func (s *Server) accountData(r *http.Request) (interface{}, *problem.P) {
+	id, err := xdr.AddressToAccountId(chi.URLParam(r, "account_id"))
+	if err != nil {
+		return nil, &problem.NotFound
+	}
+	entry, ok, err := s.sim.Entry(dataKey(id, chi.URLParam(r, "key")))
+	if err != nil {
+		return nil, &problem.ServerError
+	}
+	if !ok {
+		return nil, &problem.NotFound
+	}
+	return hProtocol.AccountData{Value: base64.StdEncoding.EncodeToString(entry.Data.Data.DataValue)}, nil
+}
+
+func (s *Server) ledger(r *http.Request) (interface{}, *problem.P) {
+	sequence, err := strconv.ParseUint(chi.URLParam(r, "ledger_id"), 10, 32)
+	if err != nil {
+		return nil, problem.MakeInvalidFieldProblem("ledger_id", err)
+	}
+	if sequence == 0 || sequence > uint64(len(s.history.ledgers)) {
+		return nil, &problem.NotFound
+	}
+	return s.ledgerResource(s.history.ledgers[sequence-1]), nil
+}
+
+func (s *Server) transaction(r *http.Request) (interface{}, *problem.P) {
+	record, ok := s.findTransaction(chi.URLParam(r, "tx_id"))
+	if !ok {
+		return nil, &problem.NotFound
+	}
+	return record.resource, nil
+}
+
+func (s *Server) operation(r *http.Request) (interface{}, *problem.P) {
+	id := chi.URLParam(r, "op_id")
+	for _, record := range s.history.operations {
+		if formatID(record.id) == id {
+			return record.resource, nil
+		}
+	}
+	return nil, &problem.NotFound
+}
+
+func (s *Server) offer(r *http.Request) (interface{}, *problem.P) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "offer_id"), 10, 64)
+	if err != nil {
+		return nil, problem.MakeInvalidFieldProblem("offer_id", err)
+	}
+	st, err := s.state()
+	if err != nil {
+		return nil, &problem.ServerError
+	}
+	for _, entry := range st.offers {
+		if int64(entry.Data.Offer.OfferId) == id {
+			return s.offerResource(entry), nil
+		}
+	}
+	return nil, &problem.NotFound
+}
+
+func (s *Server) ledgers(r *http.Request) ([]hal.Pageable, *problem.P) {
+	records := make([]hal.Pageable, len(s.history.ledgers))
+	for i, l := range s.history.ledgers {
+		records[i] = s.ledgerResource(l)
+	}
+	return records, nil
+}
+
+func (s *Server) transactions(r *http.Request) ([]hal.Pageable, *problem.P) {
+	account, ledger, p := historyFilters(r)
+	if p != nil {
+		return nil, p
+	}
+	includeFailed := r.URL.Query().Get("include_failed") == "true"
+
+	var records []hal.Pageable
+	for _, record := range s.history.transactions {
+		switch {
+		case account != "" && !record.accounts[account]:
+		case ledger != 0 && record.ledger != ledger:
+		case !includeFailed && !record.resource.Successful:
+		default:
+			records = append(records, record.resource)
+		}
+	}
+	return records, nil
+}
+
+// operations returns the collectionFunc of the operations, or of the
+// payments only.
+func (s *Server) operations(payments bool) collectionFunc {
+	return func(r *http.Request) ([]hal.Pageable, *problem.P) {
+		account, ledger, p := historyFilters(r)
+		if p != nil {
+			return nil, p
+		}
+		hash := chi.URLParam(r, "tx_id")
+		includeFailed := r.URL.Query().Get("include_failed") == "true" || hash != ""
+
+		var records []hal.Pageable
+		for _, record := range s.history.operations {
+			switch {
+			case payments && !record.payment:
+			case account != "" && !record.accounts[account]:
+			case ledger != 0 && uint32(record.id>>32) != ledger:
+			case hash != "" && record.resource.GetTransactionHash() != hash:
+			case !includeFailed && !record.resource.IsTransactionSuccessful():
+			default:
+				records = append(records, record.resource)
+			}
+		}
+		return records, nil
+	}
+}
+
+func (s *Server) offers(r *http.Request) ([]hal.Pageable, *problem.P) {
+	query := r.URL.Query()
+	seller := chi.URLParam(r, "account_id")
+	if seller == "" {
+		seller = query.Get("seller")
+	}
+	if seller != "" {
+		if _, err := keypair.ParseAddress(seller); err != nil {
+			return nil, problem.MakeInvalidFieldProblem("seller", err)
+		}
+	}
+	selling, buying := query.Get("selling"), query.Get("buying")
+	st, err := s.state()
+	if err != nil {
+		return nil, &problem.ServerError
+	}
+
+	var records []hal.Pageable
+	for _, entry := range st.offers {
+		o := entry.Data.Offer
+		switch {
+		case seller != "" && o.SellerId.Address() != seller:
+		case selling != "" && o.Selling.StringCanonical() != selling:
+		case buying != "" && o.Buying.StringCanonical() != buying:
+		default:
+			records = append(records, s.offerResource(entry))
+		}
+	}
+	return records, nil
+}
+
+func (s *Server) assets(r *http.Request) ([]hal.Pageable, *problem.P) {
+	query := r.URL.Query()
+	code, issuer := query.Get("asset_code"), query.Get("asset_issuer")
+
+	st, err := s.state()
+	if err != nil {
+		return nil, &problem.ServerError
+	}
+
+	stats := map[string]*hProtocol.AssetStat{}
+	amounts := map[string]int64{}
+	for _, entry := range st.accounts {
+		for _, trustline := range st.trustlines[entry.Data.Account.AccountId.Address()] {
+			tl := trustline.Data.TrustLine
+			if tl.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+				continue
+			}
+			asset := tl.Asset.ToAsset()
+			key := asset.StringCanonical()
+			stat, ok := stats[key]
+			if !ok {
+				stat = &hProtocol.AssetStat{Asset: assetResource(asset)}
+				stat.PT = fmt.Sprintf("%s_%s_%s", stat.Code, stat.Issuer, stat.Type)
+				stats[key] = stat
+			}
+			stat.NumAccounts++
+			if xdr.TrustLineFlags(tl.Flags).IsAuthorized() {
+				stat.Accounts.Authorized++
+			} else {
+				stat.Accounts.Unauthorized++
+			}
+			amounts[key] += int64(tl.Balance)
+		}
+	}
+
+	var records []hal.Pageable
+	for _, key := range sortedStats(stats) {
+		stat := stats[key]
+		if (code != "" && stat.Code != code) || (issuer != "" && stat.Issuer != issuer) {
+			continue
+		}
+		stat.Amount = amount.StringFromInt64(amounts[key])
+		stat.Balances.Authorized = stat.Amount
+		stat.Balances.AuthorizedToMaintainLiabilities = amount.StringFromInt64(0)
+		stat.Balances.Unauthorized = amount.StringFromInt64(0)
+		stat.ClaimableBalancesAmount = amount.StringFromInt64(0)
+		stat.LiquidityPoolsAmount = amount.StringFromInt64(0)
+		records = append(records, *stat)
+	}
+	return records, nil
+}
+
+// sortedStats returns the keys of the asset stats ordered by paging token.
+func sortedStats(stats map[string]*hProtocol.AssetStat) []string {
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return stats[keys[i]].PT < stats[keys[j]].PT
+	})
+	return keys
+}
+
+// historyFilters returns the account and ledger in the path of history
+// requests, if any.
+func historyFilters(r *http.Request) (string, uint32, *problem.P) {
+	account := chi.URLParam(r, "account_id")
+	if account != "" {
+		if _, err := keypair.ParseAddress(account); err != nil {
+			return "", 0, problem.MakeInvalidFieldProblem("account_id", err)
+		}
+	}
+	var ledger uint32
+	if param := chi.URLParam(r, "ledger_id"); param != "" {
+		sequence, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return "", 0, problem.MakeInvalidFieldProblem("ledger_id", err)
+		}
+		ledger = uint32(sequence)
+	}
+	return account, ledger, nil
+}
+
+// object returns the handler of a resource, which streams the resource
+// whenever it changes when requested with the text/event-stream media type.
+func (s *Server) object(fn objectFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		resource, p := fn(r)
+		changed := s.changed
+		s.mu.Unlock()
+		if p != nil {
+			renderProblem(w, *p)
+			return
+		}
+		if !streaming(r) {
+			httpjson.Render(w, resource, httpjson.HALJSON)
+			return
+		}
+
+		if !writePreamble(w) {
+			return
+		}
+		var last []byte
+		for {
+			data, err := json.Marshal(resource)
+			if err != nil {
+				return
+			}
+			if string(data) != string(last) {
+				writeEvent(w, "", data)
+				last = data
+			}
+			if !s.wait(r, changed) {
+				return
+			}
+
+			s.mu.Lock()
+			resource, p = fn(r)
+			changed = s.changed
+			s.mu.Unlock()
+			if p != nil {
+				return
+			}
+		}
+	}
+}
+
+// collection returns the handler of a collection. Pages of the collection
+// are rendered like Horizon does. When requested with the text/event-stream
+// media type, the records after the cursor are streamed in ascending order
+// as they are added.
+func (s *Server) collection(fn collectionFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pq, p := pageQuery(r)
+		if p != nil {
+			renderProblem(w, *p)
+			return
+		}
+		s.mu.Lock()
+		records, p := fn(r)
+		changed := s.changed
+		s.mu.Unlock()
+		if p != nil {
+			renderProblem(w, *p)
+			return
+		}
+		if !streaming(r) {
+			fullURL, err := url.Parse(s.URL + r.URL.RequestURI())
+			if err != nil {
+				renderProblem(w, problem.ServerError)
+				return
+			}
+			page := hal.Page{
+				Order:  pq.order,
+				Limit:  pq.limit,
+				Cursor: pq.cursor,
+			}
+			page.FullURL = fullURL
+			for _, record := range pq.page(records) {
+				page.Add(record)
+			}
+			page.PopulateLinks()
+			httpjson.Render(w, page, httpjson.HALJSON)
+			return
+		}
+
+		if !writePreamble(w) {
+			return
+		}
+		cursor := pq.cursor
+		if cursor == "now" {
+			cursor = ""
+			if len(records) > 0 {
+				cursor = records[len(records)-1].PagingToken()
+			}
+		}
+		for {
+			for _, record := range records {
+				if !after(record.PagingToken(), cursor) {
+					continue
+				}
+				data, err := json.Marshal(record)
+				if err != nil {
+					return
+				}
+				cursor = record.PagingToken()
+				writeEvent(w, cursor, data)
+			}
+			if !s.wait(r, changed) {
+				return
+			}
+
+			s.mu.Lock()
+			records, p = fn(r)
+			changed = s.changed
+			s.mu.Unlock()
+			if p != nil {
+				return
+			}
+		}
+	}
+}
+
+// wait blocks until the ledger changes and returns false when the stream
+// has to be closed instead.
+func (s *Server) wait(r *http.Request, changed <-chan struct{}) bool {
+	select {
+	case <-changed:
+		return true
+	case <-r.Context().Done():
+		return false
+	case <-s.done:
+		return false
+	}
+}
+
+// pageParams holds the paging parameters of a collection request.
+type pageParams struct {
+	cursor string
+	order  string
+	limit  uint64
+}
+
+func pageQuery(r *http.Request) (pageParams, *problem.P) {
+	query := r.URL.Query()
+	pq := pageParams{
+		cursor: query.Get("cursor"),
+		order:  query.Get("order"),
+		limit:  defaultLimit,
+	}
+	switch pq.order {
+	case "":
+		pq.order = "asc"
+	case "asc", "desc":
+	default:
+		return pq, problem.MakeInvalidFieldProblem("order", errors.New("order: invalid value"))
+	}
+	if param := query.Get("limit"); param != "" {
+		limit, err := strconv.ParseUint(param, 10, 64)
+		if err != nil || limit == 0 || limit > maxLimit {
+			return pq, problem.MakeInvalidFieldProblem(
+				"limit",
+				errors.Errorf("limit must be between 1 and %d", maxLimit),
+			)
+		}
+		pq.limit = limit
+	}
+	return pq, nil
+}
+
+// page returns the records of the page out of the records of the collection,
+// which are in ascending order.
+func (pq pageParams) page(records []hal.Pageable) []hal.Pageable {
+	var page []hal.Pageable
+	if pq.order == "asc" {
+		for _, record := range records {
+			if uint64(len(page)) == pq.limit {
+				break
+			}
+			if after(record.PagingToken(), pq.cursor) {
+				page = append(page, record)
+			}
+		}
+		return page
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if uint64(len(page)) == pq.limit {
+			break
+		}
+		if pq.cursor == "" || after(pq.cursor, records[i].PagingToken()) {
+			page = append(page, records[i])
+		}
+	}
+	return page
+}
+
+// after returns whether the paging token comes after the cursor. Numeric
+// paging tokens are compared as numbers.
+func after(token, cursor string) bool {
+	if cursor == "" {
+		return true
+	}
+	t, err1 := strconv.ParseInt(token, 10, 64)
+	c, err2 := strconv.ParseInt(cursor, 10, 64)
+	if err1 == nil && err2 == nil {
+		return t > c
+	}
+	return token > cursor
+}
+
+func streaming(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// writePreamble starts an SSE response the way Horizon does.
+func writePreamble(w http.ResponseWriter) bool {
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "Streaming Not Supported", http.StatusBadRequest)
+		return false
+	}
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 1000\nevent: open\ndata: \"hello\"\n\n")
+	w.(http.Flusher).Flush()
+	return true
+}
+
+func writeEvent(w http.ResponseWriter, id string, data []byte) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+// renderProblem renders the problem like Horizon does.
+func renderProblem(w http.ResponseWriter, p problem.P) {
+	if !strings.HasPrefix(p.Type, problem.DefaultServiceHost) {
+		p.Type = problem.DefaultServiceHost + p.Type
+	}
+	js, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		http.Error(w, "error rendering problem", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	w.WriteHeader(p.Status)
+	w.Write(js)
+}
@@ -0,0 +1,528 @@
+package horizonclient
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/effects"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/render/hal"
+)
+
+// IteratorOptions configures the iterators over the records of collection
+// endpoints.
+type IteratorOptions struct {
+	// Prefetch fetches the next page concurrently while the records of the
+	// current page are consumed.
+	Prefetch bool
+	// StopLedger ends the iteration at the first record of a ledger after
+	// StopLedger, or before StopLedger in descending order. It only applies
+	// to the iterators of endpoints ordered by ledger: transactions,
+	// operations, payments, effects and trades.
+	StopLedger uint32
+	// StopTime ends the iteration at the first record closed after StopTime,
+	// or before StopTime in descending order. It applies to the same
+	// iterators as StopLedger.
+	StopTime time.Time
+	// Stream switches to streaming the new records once the last page is
+	// reached, instead of ending the iteration. It applies to the iterators
+	// of endpoints supporting streaming (transactions, operations, payments,
+	// effects, trades and offers) in ascending order. The iteration then
+	// continues until the iterator is closed or a stop condition is met.
+	Stream bool
+}
+
+// rawPage is a page of any collection endpoint.
+type rawPage struct {
+	Links    hal.Links `json:"_links"`
+	Embedded struct {
+		Records []json.RawMessage `json:"records"`
+	} `json:"_embedded"`
+}
+
+type pageResult struct {
+	page rawPage
+	err  error
+}
+
+type streamResult struct {
+	record json.RawMessage
+	err    error
+	end    bool
+}
+
+// recordPosition contains the fields locating a record of an endpoint ordered
+// by ledger.
+type recordPosition struct {
+	PT              string    `json:"paging_token"`
+	CreatedAt       time.Time `json:"created_at"`
+	LedgerCloseTime time.Time `json:"ledger_close_time"`
+}
+
+// iterator implements the iteration over the records of a collection
+// endpoint, the typed iterators decode its records.
+type iterator struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	client  *Client
+	options IteratorOptions
+	// ledgerOrdered is true when the records are ordered by ledger and the
+	// stop conditions apply.
+	ledgerOrdered bool
+	// streamable is true when the endpoint supports streaming.
+	streamable bool
+	descending bool
+
+	nextURL  string
+	records  []json.RawMessage
+	current  json.RawMessage
+	prefetch chan pageResult
+	stream   chan streamResult
+	done     bool
+	err      error
+}
+
+func (c *Client) newIterator(
+	ctx context.Context,
+	request HorizonRequest,
+	options IteratorOptions,
+	ledgerOrdered, streamable bool,
+) iterator {
+	ctx, cancel := context.WithCancel(ctx)
+	it := iterator{
+		ctx:           ctx,
+		cancel:        cancel,
+		client:        c,
+		options:       options,
+		ledgerOrdered: ledgerOrdered,
+		streamable:    streamable,
+	}
+	req, err := request.HTTPRequest(c.fixHorizonURL())
+	if err != nil {
+		it.err = err
+		return it
+	}
+	it.nextURL = req.URL.String()
+	it.descending = req.URL.Query().Get("order") == string(OrderDesc)
+	return it
+}
+
+// Err returns the error which ended the iteration, if any.
+func (it *iterator) Err() error {
+	return it.err
+}
+
+// Close ends the iteration and releases its resources, in particular the
+// stream when the iterator is streaming.
+func (it *iterator) Close() {
+	it.done = true
+	it.cancel()
+}
+
+// next moves to the next record and decodes it.
+func (it *iterator) next(decode func(json.RawMessage) error) bool {
+	for len(it.records) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		if it.stream != nil {
+			return it.nextStreamed(decode)
+		}
+
+		var result pageResult
+		if it.prefetch != nil {
+			select {
+			case result = <-it.prefetch:
+			case <-it.ctx.Done():
+				result.err = it.ctx.Err()
+			}
+			it.prefetch = nil
+		} else {
+			result = it.fetch(it.nextURL)
+		}
+		if result.err != nil {
+			it.err = result.err
+			return false
+		}
+
+		it.records = result.page.Embedded.Records
+		if len(it.records) == 0 {
+			if it.options.Stream && it.streamable && !it.descending {
+				it.startStream()
+				continue
+			}
+			it.done = true
+			return false
+		}
+		it.nextURL = result.page.Links.Next.Href
+		if it.options.Prefetch {
+			it.prefetch = make(chan pageResult, 1)
+			go func(pageURL string, prefetch chan<- pageResult) {
+				prefetch <- it.fetch(pageURL)
+			}(it.nextURL, it.prefetch)
+		}
+	}
+
+	it.current, it.records = it.records[0], it.records[1:]
+	return it.accept(decode)
+}
+
+// nextStreamed moves to the next streamed record.
+func (it *iterator) nextStreamed(decode func(json.RawMessage) error) bool {
+	var result streamResult
+	select {
+	case result = <-it.stream:
+	case <-it.ctx.Done():
+		result.end = true
+	}
+	if result.end && result.err == nil {
+		result.err = it.ctx.Err()
+	}
+	if result.end {
+		it.err = result.err
+		it.Close()
+		return false
+	}
+	it.current = result.record
+	return it.accept(decode)
+}
+
+// accept decodes the current record unless it meets a stop condition.
+func (it *iterator) accept(decode func(json.RawMessage) error) bool {
+	if it.stopped() {
+		it.Close()
+		return false
+	}
+	if err := decode(it.current); err != nil {
+		it.err = errors.Wrap(err, "error decoding record")
+		it.Close()
+		return false
+	}
+	return true
+}
+
+func (it *iterator) fetch(pageURL string) pageResult {
+	var result pageResult
+	result.err = it.client.sendGetRequest(it.ctx, pageURL, &result.page)
+	return result
+}
+
+// startStream streams the records following the last page.
+func (it *iterator) startStream() {
+	it.stream = make(chan streamResult)
+	go func(streamURL string) {
+		err := it.client.stream(it.ctx, streamURL, func(data []byte) error {
+			record := make(json.RawMessage, len(data))
+			copy(record, data)
+			select {
+			case it.stream <- streamResult{record: record}:
+				return nil
+			case <-it.ctx.Done():
+				return it.ctx.Err()
+			}
+		})
+		if it.ctx.Err() != nil {
+			err = nil
+		}
+		select {
+		case it.stream <- streamResult{err: err, end: true}:
+		case <-it.ctx.Done():
+		}
+	}(it.nextURL)
+}
+
+// stopped returns true when the current record meets a stop condition.
+func (it *iterator) stopped() bool {
+	if !it.ledgerOrdered || (it.options.StopLedger == 0 && it.options.StopTime.IsZero()) {
+		return false
+	}
+	var position recordPosition
+	if err := json.Unmarshal(it.current, &position); err != nil {
+		return false
+	}
+
+	if ledger, ok := ledgerFromPagingToken(position.PT); ok && it.options.StopLedger != 0 {
+		if (it.descending && ledger < it.options.StopLedger) || (!it.descending && ledger > it.options.StopLedger) {
+			return true
+		}
+	}
+	closedAt := position.CreatedAt
+	if closedAt.IsZero() {
+		closedAt = position.LedgerCloseTime
+	}
+	if !closedAt.IsZero() && !it.options.StopTime.IsZero() {
+		if (it.descending && closedAt.Before(it.options.StopTime)) || (!it.descending && closedAt.After(it.options.StopTime)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ledgerFromPagingToken returns the ledger of a record whose paging token
+// starts with a TOID, i.e. transactions, operations, effects and trades.
+// The ledger sequence is stored in the 32 high bits of TOIDs.
+func ledgerFromPagingToken(pt string) (uint32, bool) {
+	if i := strings.IndexByte(pt, '-'); i >= 0 {
+		pt = pt[:i]
+	}
+	toid, err := strconv.ParseInt(pt, 10, 64)
+	if err != nil || toid < 0 {
+		return 0, false
+	}
+	return uint32(toid >> 32), true
+}
+
+// AccountsIterator iterates over the accounts matching an AccountsRequest.
+type AccountsIterator struct {
+	iterator
+	account hProtocol.Account
+}
+
+// AccountsIterator returns an iterator over the accounts matching the request.
+func (c *Client) AccountsIterator(ctx context.Context, request AccountsRequest, options IteratorOptions) *AccountsIterator {
+	return &AccountsIterator{iterator: c.newIterator(ctx, request, options, false, false)}
+}
+
+// Next moves to the next account, it returns false at the end of the
+// iteration.
+func (it *AccountsIterator) Next() bool {
+	it.account = hProtocol.Account{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.account)
+	})
+}
+
+// Account returns the current account.
+func (it *AccountsIterator) Account() hProtocol.Account {
+	return it.account
+}
+
+// AssetsIterator iterates over the assets matching an AssetRequest.
+type AssetsIterator struct {
+	iterator
+	asset hProtocol.AssetStat
+}
+
+// AssetsIterator returns an iterator over the assets matching the request.
+func (c *Client) AssetsIterator(ctx context.Context, request AssetRequest, options IteratorOptions) *AssetsIterator {
+	return &AssetsIterator{iterator: c.newIterator(ctx, request, options, false, false)}
+}
+
+// Next moves to the next asset, it returns false at the end of the iteration.
+func (it *AssetsIterator) Next() bool {
+	it.asset = hProtocol.AssetStat{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.asset)
+	})
+}
+
+// Asset returns the current asset.
+func (it *AssetsIterator) Asset() hProtocol.AssetStat {
+	return it.asset
+}
+
+// ClaimableBalancesIterator iterates over the claimable balances matching a
+// ClaimableBalanceRequest.
+type ClaimableBalancesIterator struct {
+	iterator
+	claimableBalance hProtocol.ClaimableBalance
+}
+
+// ClaimableBalancesIterator returns an iterator over the claimable balances
+// matching the request.
+func (c *Client) ClaimableBalancesIterator(ctx context.Context, request ClaimableBalanceRequest, options IteratorOptions) *ClaimableBalancesIterator {
+	return &ClaimableBalancesIterator{iterator: c.newIterator(ctx, request, options, false, false)}
+}
+
+// Next moves to the next claimable balance, it returns false at the end of
+// the iteration.
+func (it *ClaimableBalancesIterator) Next() bool {
+	it.claimableBalance = hProtocol.ClaimableBalance{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.claimableBalance)
+	})
+}
+
+// ClaimableBalance returns the current claimable balance.
+func (it *ClaimableBalancesIterator) ClaimableBalance() hProtocol.ClaimableBalance {
+	return it.claimableBalance
+}
+
+// EffectsIterator iterates over the effects matching an EffectRequest.
+type EffectsIterator struct {
+	iterator
+	effect effects.Effect
+}
+
+// EffectsIterator returns an iterator over the effects matching the request.
+func (c *Client) EffectsIterator(ctx context.Context, request EffectRequest, options IteratorOptions) *EffectsIterator {
+	return &EffectsIterator{iterator: c.newIterator(ctx, request, options, true, true)}
+}
+
+// Next moves to the next effect, it returns false at the end of the
+// iteration.
+func (it *EffectsIterator) Next() bool {
+	it.effect = nil
+	return it.next(func(data json.RawMessage) error {
+		var base effects.Base
+		if err := json.Unmarshal(data, &base); err != nil {
+			return err
+		}
+		effect, err := effects.UnmarshalEffect(base.Type, data)
+		it.effect = effect
+		return err
+	})
+}
+
+// Effect returns the current effect.
+func (it *EffectsIterator) Effect() effects.Effect {
+	return it.effect
+}
+
+// LiquidityPoolsIterator iterates over the liquidity pools matching a
+// LiquidityPoolsRequest.
+type LiquidityPoolsIterator struct {
+	iterator
+	liquidityPool hProtocol.LiquidityPool
+}
+
+// LiquidityPoolsIterator returns an iterator over the liquidity pools
+// matching the request.
+func (c *Client) LiquidityPoolsIterator(ctx context.Context, request LiquidityPoolsRequest, options IteratorOptions) *LiquidityPoolsIterator {
+	return &LiquidityPoolsIterator{iterator: c.newIterator(ctx, request, options, false, false)}
+}
+
+// Next moves to the next liquidity pool, it returns false at the end of the
+// iteration.
+func (it *LiquidityPoolsIterator) Next() bool {
+	it.liquidityPool = hProtocol.LiquidityPool{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.liquidityPool)
+	})
+}
+
+// LiquidityPool returns the current liquidity pool.
+func (it *LiquidityPoolsIterator) LiquidityPool() hProtocol.LiquidityPool {
+	return it.liquidityPool
+}
+
+// OffersIterator iterates over the offers matching an OfferRequest.
+type OffersIterator struct {
+	iterator
+	offer hProtocol.Offer
+}
+
+// OffersIterator returns an iterator over the offers matching the request.
+func (c *Client) OffersIterator(ctx context.Context, request OfferRequest, options IteratorOptions) *OffersIterator {
+	return &OffersIterator{iterator: c.newIterator(ctx, request, options, false, true)}
+}
+
+// Next moves to the next offer, it returns false at the end of the
+// iteration.
+func (it *OffersIterator) Next() bool {
+	it.offer = hProtocol.Offer{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.offer)
+	})
+}
+
+// Offer returns the current offer.
+func (it *OffersIterator) Offer() hProtocol.Offer {
+	return it.offer
+}
+
+// OperationsIterator iterates over the operations, or the payments, matching
+// an OperationRequest.
+type OperationsIterator struct {
+	iterator
+	operation operations.Operation
+}
+
+// OperationsIterator returns an iterator over the operations matching the
+// request.
+func (c *Client) OperationsIterator(ctx context.Context, request OperationRequest, options IteratorOptions) *OperationsIterator {
+	return &OperationsIterator{iterator: c.newIterator(ctx, request.SetOperationsEndpoint(), options, true, true)}
+}
+
+// PaymentsIterator returns an iterator over the payments matching the
+// request.
+func (c *Client) PaymentsIterator(ctx context.Context, request OperationRequest, options IteratorOptions) *OperationsIterator {
+	return &OperationsIterator{iterator: c.newIterator(ctx, request.SetPaymentsEndpoint(), options, true, true)}
+}
+
+// Next moves to the next operation, it returns false at the end of the
+// iteration.
+func (it *OperationsIterator) Next() bool {
+	it.operation = nil
+	return it.next(func(data json.RawMessage) error {
+		var base operations.Base
+		if err := json.Unmarshal(data, &base); err != nil {
+			return err
+		}
+		operation, err := operations.UnmarshalOperation(base.TypeI, data)
+		it.operation = operation
+		return err
+	})
+}
+
+// Operation returns the current operation.
+func (it *OperationsIterator) Operation() operations.Operation {
+	return it.operation
+}
+
+// TradesIterator iterates over the trades matching a TradeRequest.
+type TradesIterator struct {
+	iterator
+	trade hProtocol.Trade
+}
+
+// TradesIterator returns an iterator over the trades matching the request.
+func (c *Client) TradesIterator(ctx context.Context, request TradeRequest, options IteratorOptions) *TradesIterator {
+	return &TradesIterator{iterator: c.newIterator(ctx, request, options, true, true)}
+}
+
+// Next moves to the next trade, it returns false at the end of the
+// iteration.
+func (it *TradesIterator) Next() bool {
+	it.trade = hProtocol.Trade{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.trade)
+	})
+}
+
+// Trade returns the current trade.
+func (it *TradesIterator) Trade() hProtocol.Trade {
+	return it.trade
+}
+
+// TransactionsIterator iterates over the transactions matching a
+// TransactionRequest.
+type TransactionsIterator struct {
+	iterator
+	transaction hProtocol.Transaction
+}
+
+// TransactionsIterator returns an iterator over the transactions matching
+// the request.
+func (c *Client) TransactionsIterator(ctx context.Context, request TransactionRequest, options IteratorOptions) *TransactionsIterator {
+	return &TransactionsIterator{iterator: c.newIterator(ctx, request, options, true, true)}
+}
+
+// Next moves to the next transaction, it returns false at the end of the
+// iteration.
+func (it *TransactionsIterator) Next() bool {
+	it.transaction = hProtocol.Transaction{}
+	return it.next(func(data json.RawMessage) error {
+		return json.Unmarshal(data, &it.transaction)
+	})
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionsIterator) Transaction() hProtocol.Transaction {
+	return it.transaction
+}
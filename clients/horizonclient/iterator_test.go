@@ -0,0 +1,149 @@
+package horizonclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/support/http/httptest"
+)
+
+// iteratorTransaction returns a transaction of the given ledger.
+func iteratorTransaction(ledger int64, index int) string {
+	closedAt := time.Date(2021, 1, 1, 0, 0, int(ledger)*5, 0, time.UTC).Format(time.RFC3339)
+	return fmt.Sprintf(
+		`{"id": "%d-%d", "paging_token": "%d", "ledger": %d, "created_at": "%s"}`,
+		ledger, index, ledger<<32|int64(index)<<12, ledger, closedAt,
+	)
+}
+
+// stringResponder responds with a new response on every request.
+func stringResponder(status int, body string) httpmock.Responder {
+	return func(*http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(status, body), nil
+	}
+}
+
+// iteratorPage returns a page of records linking to the next page.
+func iteratorPage(next string, records ...string) string {
+	return fmt.Sprintf(
+		`{"_links": {"next": {"href": "%s"}}, "_embedded": {"records": [%s]}}`,
+		next, strings.Join(records, ","),
+	)
+}
+
+func TestTransactionsIterator(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{HorizonURL: "https://localhost/", HTTP: hmock}
+
+	page2 := "https://localhost/transactions?cursor=p1&limit=2&order=asc"
+	page3 := "https://localhost/transactions?cursor=p2&limit=2&order=asc"
+	hmock.On("GET", "https://localhost/transactions?limit=2").
+		Return(stringResponder(200, iteratorPage(page2, iteratorTransaction(10, 1), iteratorTransaction(10, 2))))
+	hmock.On("GET", page2).
+		Return(stringResponder(200, iteratorPage(page3, iteratorTransaction(11, 1), iteratorTransaction(12, 1))))
+	hmock.On("GET", page3).Return(stringResponder(200, iteratorPage(page3)))
+
+	for _, prefetch := range []bool{false, true} {
+		it := client.TransactionsIterator(context.Background(), TransactionRequest{Limit: 2}, IteratorOptions{Prefetch: prefetch})
+		var ids []string
+		for it.Next() {
+			ids = append(ids, it.Transaction().ID)
+		}
+		require.NoError(t, it.Err())
+		assert.Equal(t, []string{"10-1", "10-2", "11-1", "12-1"}, ids)
+		it.Close()
+	}
+
+	// The iteration stops after the given ledger.
+	it := client.TransactionsIterator(context.Background(), TransactionRequest{Limit: 2}, IteratorOptions{StopLedger: 10})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Transaction().ID)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"10-1", "10-2"}, ids)
+	assert.False(t, it.Next())
+
+	// Errors end the iteration.
+	hmock.On("GET", page2).Return(stringResponder(404, notFoundResponse))
+	it = client.TransactionsIterator(context.Background(), TransactionRequest{Limit: 2}, IteratorOptions{})
+	ids = nil
+	for it.Next() {
+		ids = append(ids, it.Transaction().ID)
+	}
+	assert.Equal(t, []string{"10-1", "10-2"}, ids)
+	assert.Equal(t, 404, GetError(it.Err()).Response.StatusCode)
+
+	it = client.TransactionsIterator(context.Background(), TransactionRequest{ForAccount: "G", ForLedger: 1}, IteratorOptions{})
+	assert.False(t, it.Next())
+	assert.EqualError(t, it.Err(), "invalid request: too many parameters")
+}
+
+func TestOperationsIteratorDescending(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{HorizonURL: "https://localhost/", HTTP: hmock}
+
+	next := "https://localhost/payments?cursor=p1&limit=10&order=desc"
+	hmock.On("GET", "https://localhost/payments?order=desc").Return(stringResponder(200, iteratorPage(next,
+		`{"id": "3", "paging_token": "12884905985", "type_i": 1, "type": "payment", "created_at": "2021-01-01T00:00:15Z"}`,
+		`{"id": "2", "paging_token": "8589938689", "type_i": 0, "type": "create_account", "created_at": "2021-01-01T00:00:10Z"}`,
+		`{"id": "1", "paging_token": "4294971393", "type_i": 1, "type": "payment", "created_at": "2021-01-01T00:00:05Z"}`,
+	)))
+
+	// The iteration stops before the given time in descending order.
+	it := client.PaymentsIterator(context.Background(), OperationRequest{Order: OrderDesc}, IteratorOptions{
+		StopTime: time.Date(2021, 1, 1, 0, 0, 10, 0, time.UTC),
+	})
+	defer it.Close()
+	require.True(t, it.Next())
+	assert.IsType(t, operations.Payment{}, it.Operation())
+	require.True(t, it.Next())
+	assert.IsType(t, operations.CreateAccount{}, it.Operation())
+	assert.Equal(t, "2", it.Operation().GetID())
+	assert.False(t, it.Next())
+	assert.NoError(t, it.Err())
+}
+
+func TestTransactionsIteratorStream(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{HorizonURL: "https://localhost/", HTTP: hmock}
+
+	head := "https://localhost/transactions?cursor=p1&limit=10&order=asc"
+	hmock.On("GET", "https://localhost/transactions").
+		Return(stringResponder(200, iteratorPage(head, iteratorTransaction(10, 1))))
+	hmock.On("GET", head).Return(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Accept") != "text/event-stream" {
+			return httpmock.NewStringResponse(200, iteratorPage(head)), nil
+		}
+		return httpmock.NewStringResponse(200,
+			"id: a\ndata: "+iteratorTransaction(11, 1)+"\n\n"+
+				"id: b\ndata: "+iteratorTransaction(12, 1)+"\n\n",
+		), nil
+	})
+
+	it := client.TransactionsIterator(context.Background(), TransactionRequest{}, IteratorOptions{Stream: true, StopLedger: 11})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Transaction().ID)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []string{"10-1", "11-1"}, ids)
+
+	// Closing the iterator ends the stream.
+	it = client.TransactionsIterator(context.Background(), TransactionRequest{}, IteratorOptions{Stream: true})
+	require.True(t, it.Next())
+	require.True(t, it.Next())
+	assert.Equal(t, "11-1", it.Transaction().ID)
+	it.Close()
+	assert.False(t, it.Next())
+	assert.NoError(t, it.Err())
+}
@@ -129,6 +129,14 @@ type HTTP interface {
 // transaction timebounds.
 type UniversalTimeHandler func() int64
 
+// StreamErrorHandler is a function that is called with errors encountered while streaming, such
+// as failed connections, bad HTTP status codes or events which cannot be decoded. Returning nil
+// resumes the stream from the last received paging token, returning an error stops the stream
+// with it. Events which cannot be decoded are skipped when the stream is resumed, or stop the
+// stream after 3 attempts if they have no paging token. The function is called synchronously,
+// so it can be used to back off before reconnecting.
+type StreamErrorHandler func(error) error
+
 // Client struct contains data for creating a horizon client that connects to the stellar network.
 type Client struct {
 	// URL of Horizon server to connect
@@ -145,6 +153,10 @@ type Client struct {
 	AppVersion     string
 	horizonTimeout time.Duration
 
+	// StreamErrorHandler is called with the errors of all Stream methods. When nil,
+	// streams stop on the first error.
+	StreamErrorHandler StreamErrorHandler
+
 	// clock is a Clock returning the current time.
 	clock *clock.Clock
 }
@@ -157,30 +169,67 @@ type SubmitTxOpts struct {
 // ClientInterface contains methods implemented by the horizon client
 type ClientInterface interface {
 	Accounts(request AccountsRequest) (hProtocol.AccountsPage, error)
+	AccountsWithContext(ctx context.Context, request AccountsRequest) (hProtocol.AccountsPage, error)
 	AccountDetail(request AccountRequest) (hProtocol.Account, error)
+	AccountDetailWithContext(ctx context.Context, request AccountRequest) (hProtocol.Account, error)
 	AccountData(request AccountRequest) (hProtocol.AccountData, error)
+	AccountDataWithContext(ctx context.Context, request AccountRequest) (hProtocol.AccountData, error)
 	Effects(request EffectRequest) (effects.EffectsPage, error)
+	EffectsWithContext(ctx context.Context, request EffectRequest) (effects.EffectsPage, error)
 	Assets(request AssetRequest) (hProtocol.AssetsPage, error)
+	AssetsWithContext(ctx context.Context, request AssetRequest) (hProtocol.AssetsPage, error)
 	Ledgers(request LedgerRequest) (hProtocol.LedgersPage, error)
+	LedgersWithContext(ctx context.Context, request LedgerRequest) (hProtocol.LedgersPage, error)
 	LedgerDetail(sequence uint32) (hProtocol.Ledger, error)
+	LedgerDetailWithContext(ctx context.Context, sequence uint32) (hProtocol.Ledger, error)
 	FeeStats() (hProtocol.FeeStats, error)
+	FeeStatsWithContext(ctx context.Context) (hProtocol.FeeStats, error)
 	Offers(request OfferRequest) (hProtocol.OffersPage, error)
+	OffersWithContext(ctx context.Context, request OfferRequest) (hProtocol.OffersPage, error)
 	OfferDetails(offerID string) (offer hProtocol.Offer, err error)
+	OfferDetailsWithContext(ctx context.Context, offerID string) (offer hProtocol.Offer, err error)
 	Operations(request OperationRequest) (operations.OperationsPage, error)
+	OperationsWithContext(ctx context.Context, request OperationRequest) (operations.OperationsPage, error)
 	OperationDetail(id string) (operations.Operation, error)
+	OperationDetailWithContext(ctx context.Context, id string) (operations.Operation, error)
 	SubmitTransactionXDR(transactionXdr string) (hProtocol.Transaction, error)
+	SubmitTransactionXDRWithContext(ctx context.Context, transactionXdr string) (hProtocol.Transaction, error)
 	SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (hProtocol.Transaction, error)
+	SubmitFeeBumpTransactionWithOptionsWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (hProtocol.Transaction, error)
 	SubmitTransactionWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.Transaction, error)
+	SubmitTransactionWithOptionsWithContext(ctx context.Context, transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.Transaction, error)
 	SubmitFeeBumpTransaction(transaction *txnbuild.FeeBumpTransaction) (hProtocol.Transaction, error)
+	SubmitFeeBumpTransactionWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction) (hProtocol.Transaction, error)
 	SubmitTransaction(transaction *txnbuild.Transaction) (hProtocol.Transaction, error)
+	SubmitTransactionWithContext(ctx context.Context, transaction *txnbuild.Transaction) (hProtocol.Transaction, error)
+	SubmitTransactionXDRAsync(transactionXdr string) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitTransactionXDRAsyncWithContext(ctx context.Context, transactionXdr string) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitTransactionAsync(transaction *txnbuild.Transaction) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitTransactionAsyncWithContext(ctx context.Context, transaction *txnbuild.Transaction) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitTransactionAsyncWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitTransactionAsyncWithOptionsWithContext(ctx context.Context, transaction *txnbuild.Transaction, opts SubmitTxOpts) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitFeeBumpTransactionAsync(transaction *txnbuild.FeeBumpTransaction) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitFeeBumpTransactionAsyncWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitFeeBumpTransactionAsyncWithOptions(transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	SubmitFeeBumpTransactionAsyncWithOptionsWithContext(ctx context.Context, transaction *txnbuild.FeeBumpTransaction, opts SubmitTxOpts) (hProtocol.AsyncTransactionSubmissionResponse, error)
+	TransactionStatus(txHash string) (hProtocol.AsyncTransactionStatus, error)
+	TransactionStatusWithContext(ctx context.Context, txHash string) (hProtocol.AsyncTransactionStatus, error)
 	Transactions(request TransactionRequest) (hProtocol.TransactionsPage, error)
+	TransactionsWithContext(ctx context.Context, request TransactionRequest) (hProtocol.TransactionsPage, error)
 	TransactionDetail(txHash string) (hProtocol.Transaction, error)
+	TransactionDetailWithContext(ctx context.Context, txHash string) (hProtocol.Transaction, error)
 	OrderBook(request OrderBookRequest) (hProtocol.OrderBookSummary, error)
+	OrderBookWithContext(ctx context.Context, request OrderBookRequest) (hProtocol.OrderBookSummary, error)
 	Paths(request PathsRequest) (hProtocol.PathsPage, error)
+	PathsWithContext(ctx context.Context, request PathsRequest) (hProtocol.PathsPage, error)
 	Payments(request OperationRequest) (operations.OperationsPage, error)
+	PaymentsWithContext(ctx context.Context, request OperationRequest) (operations.OperationsPage, error)
 	TradeAggregations(request TradeAggregationRequest) (hProtocol.TradeAggregationsPage, error)
+	TradeAggregationsWithContext(ctx context.Context, request TradeAggregationRequest) (hProtocol.TradeAggregationsPage, error)
 	Trades(request TradeRequest) (hProtocol.TradesPage, error)
+	TradesWithContext(ctx context.Context, request TradeRequest) (hProtocol.TradesPage, error)
 	Fund(addr string) (hProtocol.Transaction, error)
+	FundWithContext(ctx context.Context, addr string) (hProtocol.Transaction, error)
 	StreamTransactions(ctx context.Context, request TransactionRequest, handler TransactionHandler) error
 	StreamTrades(ctx context.Context, request TradeRequest, handler TradeHandler) error
 	StreamEffects(ctx context.Context, request EffectRequest, handler EffectHandler) error
@@ -189,31 +238,67 @@ type ClientInterface interface {
 	StreamOffers(ctx context.Context, request OfferRequest, handler OfferHandler) error
 	StreamLedgers(ctx context.Context, request LedgerRequest, handler LedgerHandler) error
 	StreamOrderBooks(ctx context.Context, request OrderBookRequest, handler OrderBookHandler) error
+	StreamAccount(ctx context.Context, request AccountRequest, handler AccountHandler) error
+	StreamAccountData(ctx context.Context, request AccountRequest, handler AccountDataHandler) error
+	StreamTransactionStatus(ctx context.Context, txHash string, handler TransactionStatusHandler) error
 	Root() (hProtocol.Root, error)
+	RootWithContext(ctx context.Context) (hProtocol.Root, error)
 	NextAccountsPage(hProtocol.AccountsPage) (hProtocol.AccountsPage, error)
+	NextAccountsPageWithContext(ctx context.Context, page hProtocol.AccountsPage) (hProtocol.AccountsPage, error)
 	NextAssetsPage(hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
+	NextAssetsPageWithContext(ctx context.Context, page hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
 	PrevAssetsPage(hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
+	PrevAssetsPageWithContext(ctx context.Context, page hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
 	NextLedgersPage(hProtocol.LedgersPage) (hProtocol.LedgersPage, error)
+	NextLedgersPageWithContext(ctx context.Context, page hProtocol.LedgersPage) (hProtocol.LedgersPage, error)
 	PrevLedgersPage(hProtocol.LedgersPage) (hProtocol.LedgersPage, error)
+	PrevLedgersPageWithContext(ctx context.Context, page hProtocol.LedgersPage) (hProtocol.LedgersPage, error)
 	NextEffectsPage(effects.EffectsPage) (effects.EffectsPage, error)
+	NextEffectsPageWithContext(ctx context.Context, page effects.EffectsPage) (effects.EffectsPage, error)
 	PrevEffectsPage(effects.EffectsPage) (effects.EffectsPage, error)
+	PrevEffectsPageWithContext(ctx context.Context, page effects.EffectsPage) (effects.EffectsPage, error)
 	NextTransactionsPage(hProtocol.TransactionsPage) (hProtocol.TransactionsPage, error)
+	NextTransactionsPageWithContext(ctx context.Context, page hProtocol.TransactionsPage) (hProtocol.TransactionsPage, error)
 	PrevTransactionsPage(hProtocol.TransactionsPage) (hProtocol.TransactionsPage, error)
+	PrevTransactionsPageWithContext(ctx context.Context, page hProtocol.TransactionsPage) (hProtocol.TransactionsPage, error)
 	NextOperationsPage(operations.OperationsPage) (operations.OperationsPage, error)
+	NextOperationsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error)
 	PrevOperationsPage(operations.OperationsPage) (operations.OperationsPage, error)
+	PrevOperationsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error)
 	NextPaymentsPage(operations.OperationsPage) (operations.OperationsPage, error)
+	NextPaymentsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error)
 	PrevPaymentsPage(operations.OperationsPage) (operations.OperationsPage, error)
+	PrevPaymentsPageWithContext(ctx context.Context, page operations.OperationsPage) (operations.OperationsPage, error)
 	NextOffersPage(hProtocol.OffersPage) (hProtocol.OffersPage, error)
+	NextOffersPageWithContext(ctx context.Context, page hProtocol.OffersPage) (hProtocol.OffersPage, error)
 	PrevOffersPage(hProtocol.OffersPage) (hProtocol.OffersPage, error)
+	PrevOffersPageWithContext(ctx context.Context, page hProtocol.OffersPage) (hProtocol.OffersPage, error)
 	NextTradesPage(hProtocol.TradesPage) (hProtocol.TradesPage, error)
+	NextTradesPageWithContext(ctx context.Context, page hProtocol.TradesPage) (hProtocol.TradesPage, error)
 	PrevTradesPage(hProtocol.TradesPage) (hProtocol.TradesPage, error)
+	PrevTradesPageWithContext(ctx context.Context, page hProtocol.TradesPage) (hProtocol.TradesPage, error)
 	HomeDomainForAccount(aid string) (string, error)
+	HomeDomainForAccountWithContext(ctx context.Context, aid string) (string, error)
 	NextTradeAggregationsPage(hProtocol.TradeAggregationsPage) (hProtocol.TradeAggregationsPage, error)
+	NextTradeAggregationsPageWithContext(ctx context.Context, page hProtocol.TradeAggregationsPage) (hProtocol.TradeAggregationsPage, error)
 	PrevTradeAggregationsPage(hProtocol.TradeAggregationsPage) (hProtocol.TradeAggregationsPage, error)
+	PrevTradeAggregationsPageWithContext(ctx context.Context, page hProtocol.TradeAggregationsPage) (hProtocol.TradeAggregationsPage, error)
 	LiquidityPoolDetail(request LiquidityPoolRequest) (hProtocol.LiquidityPool, error)
+	LiquidityPoolDetailWithContext(ctx context.Context, request LiquidityPoolRequest) (hProtocol.LiquidityPool, error)
 	LiquidityPools(request LiquidityPoolsRequest) (hProtocol.LiquidityPoolsPage, error)
+	LiquidityPoolsWithContext(ctx context.Context, request LiquidityPoolsRequest) (hProtocol.LiquidityPoolsPage, error)
+	ClaimableBalances(cbr ClaimableBalanceRequest) (hProtocol.ClaimableBalances, error)
+	ClaimableBalancesWithContext(ctx context.Context, cbr ClaimableBalanceRequest) (hProtocol.ClaimableBalances, error)
+	ClaimableBalance(id string) (hProtocol.ClaimableBalance, error)
+	ClaimableBalanceWithContext(ctx context.Context, id string) (hProtocol.ClaimableBalance, error)
+	NextClaimableBalancesPage(page hProtocol.ClaimableBalances) (hProtocol.ClaimableBalances, error)
+	NextClaimableBalancesPageWithContext(ctx context.Context, page hProtocol.ClaimableBalances) (hProtocol.ClaimableBalances, error)
+	PrevClaimableBalancesPage(page hProtocol.ClaimableBalances) (hProtocol.ClaimableBalances, error)
+	PrevClaimableBalancesPageWithContext(ctx context.Context, page hProtocol.ClaimableBalances) (hProtocol.ClaimableBalances, error)
 	NextLiquidityPoolsPage(hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
+	NextLiquidityPoolsPageWithContext(ctx context.Context, page hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
 	PrevLiquidityPoolsPage(hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
+	PrevLiquidityPoolsPageWithContext(ctx context.Context, page hProtocol.LiquidityPoolsPage) (hProtocol.LiquidityPoolsPage, error)
 }
 
 // DefaultTestNetClient is a default client to connect to test network.
@@ -429,11 +514,15 @@ type TradeAggregationRequest struct {
 
 // ClaimableBalanceRequest contains data about claimable balances.
 // The filters are optional (all added except Asset)
+// The query parameters (Order, Cursor and Limit) are optional. All or none can be set.
 type ClaimableBalanceRequest struct {
 	ID       string
 	Asset    string
 	Sponsor  string
 	Claimant string
+	Cursor   string
+	Limit    uint
+	Order    Order
 }
 
 // ServerTimeRecord contains data for the current unix time of a horizon server instance, and the local time when it was recorded.
@@ -1,6 +1,7 @@
 package horizonclient
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -21,6 +22,7 @@ import (
 	"github.com/stellar/go/xdr"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFixHTTP(t *testing.T) {
@@ -165,7 +167,7 @@ func TestCheckMemoRequired(t *testing.T) {
 				).ReturnString(404, notFoundResponse)
 			}
 
-			err = client.checkMemoRequired(tx)
+			err = client.checkMemoRequired(context.Background(), tx)
 
 			if len(tc.expected) > 0 {
 				tt.Error(err)
@@ -343,6 +345,43 @@ func TestAccountDetail(t *testing.T) {
 	}
 }
 
+type testContextKey struct{}
+
+func TestRequestWithContext(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+	accountRequest := AccountRequest{AccountID: "GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU"}
+
+	hmock.On(
+		"GET",
+		"https://localhost/accounts/GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU",
+	).Return(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		// The request uses the given context and the horizon timeout.
+		assert.Equal(t, "value", req.Context().Value(testContextKey{}))
+		_, ok := req.Context().Deadline()
+		assert.True(t, ok)
+		return httpmock.NewStringResponse(200, accountResponse), nil
+	})
+
+	ctx := context.WithValue(context.Background(), testContextKey{}, "value")
+	account, err := client.AccountDetailWithContext(ctx, accountRequest)
+	require.NoError(t, err)
+	assert.Equal(t, "GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU", account.AccountID)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = client.AccountDetailWithContext(ctx, accountRequest)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = client.HomeDomainForAccountWithContext(ctx, accountRequest.AccountID)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestAccountData(t *testing.T) {
 	hmock := httptest.NewClient()
 	client := &Client{
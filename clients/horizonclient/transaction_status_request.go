@@ -0,0 +1,60 @@
+package horizonclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+)
+
+// transactionStatusRequest is the request of the status of a transaction
+// submitted asynchronously.
+type transactionStatusRequest struct {
+	hash string
+}
+
+// BuildURL creates the endpoint to be queried based on the data in the transactionStatusRequest struct.
+func (tsr transactionStatusRequest) BuildURL() (endpoint string, err error) {
+	if tsr.hash == "" {
+		return endpoint, errors.New("invalid request: no transaction hash provided")
+	}
+	return fmt.Sprintf("transactions_async/%s", tsr.hash), nil
+}
+
+// HTTPRequest returns the http request for the transaction status endpoint
+func (tsr transactionStatusRequest) HTTPRequest(horizonURL string) (*http.Request, error) {
+	endpoint, err := tsr.BuildURL()
+	if err != nil {
+		return nil, err
+	}
+
+	return http.NewRequest("GET", horizonURL+endpoint, nil)
+}
+
+// TransactionStatusHandler is a function that is called when a new transaction status is received
+type TransactionStatusHandler func(hProtocol.AsyncTransactionStatus)
+
+// StreamTransactionStatus streams the status of a transaction. An event is received whenever
+// the status changes, which is once the transaction is included in a ledger. Use
+// context.WithCancel to stop streaming or context.Background() if you want to stream indefinitely.
+// TransactionStatusHandler is a user-supplied function that is executed for each streamed status received.
+func (tsr transactionStatusRequest) StreamTransactionStatus(ctx context.Context, client *Client, handler TransactionStatusHandler) error {
+	endpoint, err := tsr.BuildURL()
+	if err != nil {
+		return errors.Wrap(err, "unable to build endpoint for transaction status request")
+	}
+
+	url := fmt.Sprintf("%s%s", client.fixHorizonURL(), endpoint)
+	return client.stream(ctx, url, func(data []byte) error {
+		var status hProtocol.AsyncTransactionStatus
+		err = json.Unmarshal(data, &status)
+		if err != nil {
+			return errors.Wrap(err, "error unmarshaling data for transaction status request")
+		}
+		handler(status)
+		return nil
+	})
+}
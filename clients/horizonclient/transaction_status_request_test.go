@@ -0,0 +1,116 @@
+package horizonclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/http/httptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const asyncTxHash = "bcc7a97264dca0a51a63f7ea971b5e7458e334489673078bb2a34eb0cce910ca"
+
+func TestSubmitTransactionXDRAsync(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+
+	txXdr := `AAAAABB90WssODNIgi6BHveqzxTRmIpvAFRyVNM+Hm2GVuCcAAAAZAAABD0AAuV/AAAAAAAAAAAAAAABAAAAAAAAAAAAAAAAyTBGxOgfSApppsTnb/YRr6gOR8WT0LZNrhLh4y3FCgoAAAAXSHboAAAAAAAAAAABhlbgnAAAAEAivKe977CQCxMOKTuj+cWTFqc2OOJU8qGr9afrgu2zDmQaX5Q0cNshc3PiBwe0qw/+D/qJk5QqM5dYeSUGeDQP`
+
+	// accepted by stellar-core
+	hmock.On(
+		"POST",
+		"https://localhost/transactions_async",
+	).Return(func(request *http.Request) (*http.Response, error) {
+		assert.Equal(t, txXdr, request.FormValue("tx"))
+		return httpmock.NewStringResponse(http.StatusCreated, `{"tx_status": "PENDING", "hash": "`+asyncTxHash+`"}`), nil
+	})
+
+	resp, err := client.SubmitTransactionXDRAsync(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, hProtocol.AsyncTransactionSubmissionResponse{TxStatus: "PENDING", Hash: asyncTxHash}, resp)
+
+	// rejected by stellar-core
+	hmock.On(
+		"POST",
+		"https://localhost/transactions_async",
+	).ReturnString(http.StatusBadRequest, `{"error_result_xdr": "AAAAAAAAAGT/////AAAAAQAAAAAAAAAB////+wAAAAA=", "tx_status": "ERROR", "hash": "`+asyncTxHash+`"}`)
+
+	resp, err = client.SubmitTransactionXDRAsync(txXdr)
+	require.NoError(t, err)
+	assert.Equal(t, "ERROR", resp.TxStatus)
+	assert.Equal(t, "AAAAAAAAAGT/////AAAAAQAAAAAAAAAB////+wAAAAA=", resp.ErrorResultXDR)
+
+	// horizon error
+	hmock.On(
+		"POST",
+		"https://localhost/transactions_async",
+	).ReturnString(http.StatusBadRequest, `{"type": "https://stellar.org/horizon-errors/transaction_malformed", "title": "Transaction Malformed", "status": 400}`)
+
+	_, err = client.SubmitTransactionXDRAsync(txXdr)
+	if assert.Error(t, err) {
+		horizonError, ok := errors.Cause(err).(*Error)
+		assert.True(t, ok)
+		assert.Equal(t, "Transaction Malformed", horizonError.Problem.Title)
+	}
+}
+
+func TestTransactionStatus(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+
+	_, err := client.TransactionStatus("")
+	assert.EqualError(t, err, "no transaction hash provided")
+
+	hmock.On(
+		"GET",
+		"https://localhost/transactions_async/"+asyncTxHash,
+	).ReturnString(http.StatusOK, `{"hash": "`+asyncTxHash+`", "status": "SUCCESS", "ledger": 354811, "created_at": "2019-04-09T20:14:25Z", "result_xdr": "AAAAAAAAAGQAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAA="}`)
+
+	status, err := client.TransactionStatus(asyncTxHash)
+	require.NoError(t, err)
+	assert.Equal(t, hProtocol.TransactionStatusSuccess, status.Status)
+	assert.Equal(t, int32(354811), status.Ledger)
+	assert.Equal(t, "AAAAAAAAAGQAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAA=", status.ResultXdr)
+}
+
+func TestStreamTransactionStatus(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		HorizonURL: "https://localhost/",
+		HTTP:       hmock,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hmock.On(
+		"GET",
+		"https://localhost/transactions_async/"+asyncTxHash+"?cursor=now",
+	).ReturnString(http.StatusOK, `data: {"hash": "`+asyncTxHash+`", "status": "NOT_FOUND"}
+
+data: {"hash": "`+asyncTxHash+`", "status": "FAILED", "ledger": 354812}
+
+`)
+
+	var statuses []hProtocol.AsyncTransactionStatus
+	err := client.StreamTransactionStatus(ctx, asyncTxHash, func(status hProtocol.AsyncTransactionStatus) {
+		statuses = append(statuses, status)
+		if status.Status != hProtocol.TransactionStatusNotFound {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, hProtocol.TransactionStatusNotFound, statuses[0].Status)
+	assert.Equal(t, hProtocol.TransactionStatusFailed, statuses[1].Status)
+	assert.Equal(t, int32(354812), statuses[1].Ledger)
+}
@@ -0,0 +1,356 @@
+package horizonclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/effects"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/support/errors"
+)
+
+// WebSocket is a WebSocket connection to Horizon receiving the streams of
+// several requests. Each request is a Subscription with its own cursor. Create
+// it with Client.WebSocket.
+//
+// The handlers of all the subscriptions are called sequentially, from the
+// goroutine reading the connection.
+type WebSocket struct {
+	conn *websocket.Conn
+
+	writeLock sync.Mutex
+
+	lock          sync.Mutex
+	subscriptions map[string]*Subscription
+	nextID        int
+	err           error
+	closed        bool
+
+	done chan struct{}
+}
+
+// Subscription is the stream of a request on a WebSocket.
+type Subscription struct {
+	ws      *WebSocket
+	id      string
+	handler func(data []byte) error
+
+	// The fields below are guarded by the lock of the WebSocket.
+	cursor string
+	err    error
+
+	done chan struct{}
+}
+
+// WebSocket opens a WebSocket connection to Horizon on which several streams
+// can be subscribed to. The connection is closed when ctx is cancelled or
+// when Close is called.
+//
+// The connection is established with the default WebSocket dialer, the HTTP
+// client of the Client is not used.
+func (c *Client) WebSocket(ctx context.Context) (*WebSocket, error) {
+	u, err := url.Parse(c.fixHorizonURL() + "ws")
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing horizon url")
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return nil, errors.Errorf("unsupported horizon url scheme %q", u.Scheme)
+	}
+
+	// The client app headers are set on a dummy request to reuse
+	// setClientAppHeaders.
+	req := &http.Request{Header: http.Header{}}
+	c.setClientAppHeaders(req)
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), req.Header)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening websocket")
+	}
+
+	ws := &WebSocket{
+		conn:          conn,
+		subscriptions: map[string]*Subscription{},
+		done:          make(chan struct{}),
+	}
+	go ws.read()
+	go func() {
+		select {
+		case <-ctx.Done():
+			ws.Close()
+		case <-ws.done:
+		}
+	}()
+	return ws, nil
+}
+
+// Close closes the connection. Its subscriptions end without an error.
+func (ws *WebSocket) Close() error {
+	ws.lock.Lock()
+	ws.closed = true
+	ws.lock.Unlock()
+	return ws.conn.Close()
+}
+
+// Done returns a channel closed once the connection is closed and all its
+// subscriptions have ended.
+func (ws *WebSocket) Done() <-chan struct{} {
+	return ws.done
+}
+
+// Err returns the error which closed the connection, or nil if it was closed
+// by Close or by cancelling its context. It must be called after Done is
+// closed.
+func (ws *WebSocket) Err() error {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	return ws.err
+}
+
+// Subscribe subscribes to the stream of request. The stream starts from the
+// cursor of the request, or from now if it has none. handler is called with
+// the JSON resource of every event, the subscription ends with the error
+// returned by handler, if any.
+func (ws *WebSocket) Subscribe(request HorizonRequest, handler func(data []byte) error) (*Subscription, error) {
+	endpoint, err := request.BuildURL()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build endpoint for subscription")
+	}
+	path, err := url.Parse("/" + endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing subscription path")
+	}
+	query := path.Query()
+	if query.Get("cursor") == "" {
+		query.Set("cursor", "now")
+		path.RawQuery = query.Encode()
+	}
+
+	ws.lock.Lock()
+	if ws.closed {
+		ws.lock.Unlock()
+		return nil, errors.New("websocket is closed")
+	}
+	ws.nextID++
+	s := &Subscription{
+		ws:      ws,
+		id:      strconv.Itoa(ws.nextID),
+		handler: handler,
+		cursor:  query.Get("cursor"),
+		done:    make(chan struct{}),
+	}
+	ws.subscriptions[s.id] = s
+	ws.lock.Unlock()
+
+	err = ws.send(hProtocol.WebSocketRequest{
+		Type: hProtocol.WebSocketSubscribe,
+		ID:   s.id,
+		Path: path.String(),
+	})
+	if err != nil {
+		ws.end(s, nil)
+		return nil, err
+	}
+	return s, nil
+}
+
+// SubscribeTransactions subscribes to the transactions stream of request.
+func (ws *WebSocket) SubscribeTransactions(request TransactionRequest, handler TransactionHandler) (*Subscription, error) {
+	return ws.Subscribe(request, func(data []byte) error {
+		var transaction hProtocol.Transaction
+		if err := json.Unmarshal(data, &transaction); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for transaction subscription")
+		}
+		handler(transaction)
+		return nil
+	})
+}
+
+// SubscribeEffects subscribes to the effects stream of request.
+func (ws *WebSocket) SubscribeEffects(request EffectRequest, handler EffectHandler) (*Subscription, error) {
+	return ws.Subscribe(request, func(data []byte) error {
+		var baseEffect effects.Base
+		if err := json.Unmarshal(data, &baseEffect); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for effect subscription")
+		}
+		effect, err := effects.UnmarshalEffect(baseEffect.GetType(), data)
+		if err != nil {
+			return errors.Wrap(err, "unmarshaling to the correct effect type")
+		}
+		handler(effect)
+		return nil
+	})
+}
+
+// SubscribeOperations subscribes to the operations stream of request.
+func (ws *WebSocket) SubscribeOperations(request OperationRequest, handler OperationHandler) (*Subscription, error) {
+	return ws.Subscribe(request.SetOperationsEndpoint(), operationSubscriptionHandler(handler))
+}
+
+// SubscribePayments subscribes to the payments stream of request.
+func (ws *WebSocket) SubscribePayments(request OperationRequest, handler OperationHandler) (*Subscription, error) {
+	return ws.Subscribe(request.SetPaymentsEndpoint(), operationSubscriptionHandler(handler))
+}
+
+func operationSubscriptionHandler(handler OperationHandler) func(data []byte) error {
+	return func(data []byte) error {
+		var baseRecord operations.Base
+		if err := json.Unmarshal(data, &baseRecord); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for operation subscription")
+		}
+		operation, err := operations.UnmarshalOperation(baseRecord.GetTypeI(), data)
+		if err != nil {
+			return errors.Wrap(err, "unmarshaling to the correct operation type")
+		}
+		handler(operation)
+		return nil
+	}
+}
+
+// SubscribeLedgers subscribes to the ledgers stream of request.
+func (ws *WebSocket) SubscribeLedgers(request LedgerRequest, handler LedgerHandler) (*Subscription, error) {
+	return ws.Subscribe(request, func(data []byte) error {
+		var ledger hProtocol.Ledger
+		if err := json.Unmarshal(data, &ledger); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for ledger subscription")
+		}
+		handler(ledger)
+		return nil
+	})
+}
+
+// SubscribeOrderBooks subscribes to the order book stream of request.
+func (ws *WebSocket) SubscribeOrderBooks(request OrderBookRequest, handler OrderBookHandler) (*Subscription, error) {
+	return ws.Subscribe(request, func(data []byte) error {
+		var orderBook hProtocol.OrderBookSummary
+		if err := json.Unmarshal(data, &orderBook); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for order book subscription")
+		}
+		handler(orderBook)
+		return nil
+	})
+}
+
+// Unsubscribe ends the subscription. Events received before Horizon
+// acknowledges it may still be handled, Done is closed once it is
+// acknowledged.
+func (s *Subscription) Unsubscribe() error {
+	return s.ws.send(hProtocol.WebSocketRequest{Type: hProtocol.WebSocketUnsubscribe, ID: s.id})
+}
+
+// Done returns a channel closed once the subscription has ended.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error which ended the subscription: an Error when Horizon
+// ended it, the error of the handler or the error which closed the
+// connection. It is nil when the subscription was unsubscribed. It must be
+// called after Done is closed.
+func (s *Subscription) Err() error {
+	s.ws.lock.Lock()
+	defer s.ws.lock.Unlock()
+	return s.err
+}
+
+// Cursor returns the paging token of the last handled event, or the cursor of
+// the request if none was handled. Subscribing with it as the cursor resumes
+// the stream.
+func (s *Subscription) Cursor() string {
+	s.ws.lock.Lock()
+	defer s.ws.lock.Unlock()
+	return s.cursor
+}
+
+func (ws *WebSocket) send(request hProtocol.WebSocketRequest) error {
+	ws.writeLock.Lock()
+	defer ws.writeLock.Unlock()
+	return errors.Wrap(ws.conn.WriteJSON(request), "error sending websocket request")
+}
+
+// read dispatches the messages of the connection to the subscriptions until
+// the connection is closed.
+func (ws *WebSocket) read() {
+	var err error
+	for {
+		var message hProtocol.WebSocketMessage
+		if err = ws.conn.ReadJSON(&message); err != nil {
+			err = errors.Wrap(err, "error reading websocket message")
+			break
+		}
+
+		ws.lock.Lock()
+		s, ok := ws.subscriptions[message.ID]
+		ws.lock.Unlock()
+		if !ok {
+			// Errors of requests without a subscription are ignored, the
+			// requests of the client are always valid.
+			continue
+		}
+
+		switch message.Type {
+		case hProtocol.WebSocketEvent:
+			if handlerErr := s.handler(message.Data); handlerErr != nil {
+				ws.end(s, handlerErr)
+				s.Unsubscribe()
+				continue
+			}
+			if message.EventID != "" {
+				ws.lock.Lock()
+				s.cursor = message.EventID
+				ws.lock.Unlock()
+			}
+		case hProtocol.WebSocketError:
+			herr := &Error{}
+			if message.Problem != nil {
+				herr.Problem = *message.Problem
+			}
+			ws.end(s, herr)
+		case hProtocol.WebSocketUnsubscribed:
+			ws.end(s, nil)
+		}
+	}
+
+	ws.lock.Lock()
+	if ws.closed {
+		err = nil
+	}
+	ws.err = err
+	ws.closed = true
+	subscriptions := ws.subscriptions
+	ws.subscriptions = map[string]*Subscription{}
+	ws.lock.Unlock()
+
+	ws.conn.Close()
+	for _, s := range subscriptions {
+		ws.end(s, err)
+	}
+	close(ws.done)
+}
+
+// end ends the subscription with err, if it has not already ended.
+func (ws *WebSocket) end(s *Subscription, err error) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	if current, ok := ws.subscriptions[s.id]; ok && current == s {
+		delete(ws.subscriptions, s.id)
+	}
+	s.err = err
+	close(s.done)
+}
@@ -0,0 +1,156 @@
+package horizonclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/support/render/problem"
+)
+
+// webSocketTestServer streams two ledgers to the subscriptions of
+// "/ledgers?cursor=now", two payments to the subscriptions of
+// "/accounts/GABC/payments?cursor=10" and fails the other subscriptions.
+func webSocketTestServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ws", r.URL.Path)
+		assert.Equal(t, "go-stellar-sdk", r.Header.Get("X-Client-Name"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var request hProtocol.WebSocketRequest
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			var messages []hProtocol.WebSocketMessage
+			switch {
+			case request.Type == hProtocol.WebSocketUnsubscribe:
+				messages = append(messages, hProtocol.WebSocketMessage{Type: hProtocol.WebSocketUnsubscribed, ID: request.ID})
+			case request.Path == "/ledgers?cursor=now":
+				messages = append(messages, hProtocol.WebSocketMessage{Type: hProtocol.WebSocketSubscribed, ID: request.ID})
+				for _, seq := range []int{1, 2} {
+					messages = append(messages, hProtocol.WebSocketMessage{
+						Type:    hProtocol.WebSocketEvent,
+						ID:      request.ID,
+						EventID: strconv.Itoa(seq),
+						Data:    []byte(`{"paging_token":"` + strconv.Itoa(seq) + `","sequence":` + strconv.Itoa(seq) + `}`),
+					})
+				}
+			case request.Path == "/accounts/GABC/payments?cursor=10":
+				messages = append(messages, hProtocol.WebSocketMessage{Type: hProtocol.WebSocketSubscribed, ID: request.ID})
+				for _, id := range []string{"11", "12"} {
+					messages = append(messages, hProtocol.WebSocketMessage{
+						Type:    hProtocol.WebSocketEvent,
+						ID:      request.ID,
+						EventID: id,
+						Data:    []byte(`{"id":"` + id + `","paging_token":"` + id + `","type":"payment","type_i":1,"amount":"1.0000000"}`),
+					})
+				}
+			default:
+				messages = append(messages,
+					hProtocol.WebSocketMessage{Type: hProtocol.WebSocketSubscribed, ID: request.ID},
+					hProtocol.WebSocketMessage{
+						Type:    hProtocol.WebSocketError,
+						ID:      request.ID,
+						Problem: &problem.P{Title: "Resource Missing", Status: http.StatusNotFound},
+					},
+				)
+			}
+			for _, message := range messages {
+				if err := conn.WriteJSON(message); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func waitForDone(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	server := webSocketTestServer(t)
+	client := &Client{HorizonURL: server.URL}
+	ws, err := client.WebSocket(context.Background())
+	require.NoError(t, err)
+
+	ledgers := make(chan hProtocol.Ledger, 2)
+	ledgerSubscription, err := ws.SubscribeLedgers(LedgerRequest{}, func(ledger hProtocol.Ledger) {
+		ledgers <- ledger
+	})
+	require.NoError(t, err)
+	payments := make(chan operations.Operation, 2)
+	paymentSubscription, err := ws.SubscribePayments(OperationRequest{ForAccount: "GABC", Cursor: "10"}, func(op operations.Operation) {
+		payments <- op
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), (<-ledgers).Sequence)
+	assert.Equal(t, int32(2), (<-ledgers).Sequence)
+	payment := (<-payments).(operations.Payment)
+	assert.Equal(t, "1.0000000", payment.Amount)
+	assert.Equal(t, "12", (<-payments).PagingToken())
+
+	require.NoError(t, ledgerSubscription.Unsubscribe())
+	waitForDone(t, ledgerSubscription.Done())
+	assert.NoError(t, ledgerSubscription.Err())
+	assert.Equal(t, "2", ledgerSubscription.Cursor())
+	assert.Equal(t, "12", paymentSubscription.Cursor())
+
+	missing, err := ws.SubscribeTransactions(TransactionRequest{ForAccount: "GMISSING"}, func(hProtocol.Transaction) {})
+	require.NoError(t, err)
+	waitForDone(t, missing.Done())
+	herr, ok := missing.Err().(*Error)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, herr.Problem.Status)
+
+	require.NoError(t, ws.Close())
+	waitForDone(t, ws.Done())
+	assert.NoError(t, ws.Err())
+	waitForDone(t, paymentSubscription.Done())
+	assert.NoError(t, paymentSubscription.Err())
+
+	_, err = ws.SubscribeLedgers(LedgerRequest{}, func(hProtocol.Ledger) {})
+	assert.EqualError(t, err, "websocket is closed")
+}
+
+func TestWebSocketHandlerError(t *testing.T) {
+	server := webSocketTestServer(t)
+	client := &Client{HorizonURL: server.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	ws, err := client.WebSocket(ctx)
+	require.NoError(t, err)
+
+	subscription, err := ws.Subscribe(LedgerRequest{}, func(data []byte) error {
+		return assert.AnError
+	})
+	require.NoError(t, err)
+	waitForDone(t, subscription.Done())
+	assert.Equal(t, assert.AnError, subscription.Err())
+	assert.Equal(t, "now", subscription.Cursor())
+
+	cancel()
+	waitForDone(t, ws.Done())
+	assert.NoError(t, ws.Err())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/stellar/go/ingest/ledgerbackend"
 	"github.com/stellar/go/support/errors"
 	"github.com/stellar/go/support/log"
+	"github.com/stellar/go/xdr"
 )
 
 var (
@@ -17,6 +18,15 @@ var (
 	// ErrMissingPrepareRange is returned when attempting an operation before PrepareRange has finished
 	// running
 	ErrPrepareRangeNotReady = errors.New("PrepareRange operation is not yet complete")
+	// ErrTooManyReplaySessions is returned when a bounded range cannot be prepared
+	// because all replay sessions are used by other clients
+	ErrTooManyReplaySessions = errors.New("All replay sessions are busy, try again later")
+	// ErrRangeInUse is returned when a range cannot be prepared because the
+	// range prepared on the main captive core instance is used by another client
+	ErrRangeInUse = errors.New("The prepared range is used by another client, try again later")
+	// ErrLedgerNotInRange is returned when the requested ledger is not in a
+	// prepared range
+	ErrLedgerNotInRange = errors.New("Ledger is not in the prepared range")
 )
 
 type rangeRequest struct {
@@ -25,16 +35,117 @@ type rangeRequest struct {
 	readyDuration int
 	valid         bool
 	ready         bool
+	// next is the sequence of the next ledger emitted by the backend.
+	next     uint32
+	lastUsed time.Time
+	// generation is incremented every time the range is reset so that a
+	// read started on a previous range does not update the current one.
+	generation int
+	// reading is true while a client reads ledgers from the backend without
+	// holding the lock, the other clients of the range wait on readDone.
+	reading  bool
+	readDone *sync.Cond
 	sync.Mutex
 }
 
+// reset must be called with the request locked.
+func (r *rangeRequest) reset(ledgerRange ledgerbackend.Range) {
+	r.ledgerRange = ledgerRange
+	r.startTime = time.Now()
+	r.lastUsed = r.startTime
+	r.ready = false
+	r.readyDuration = 0
+	r.valid = true
+	r.next = ledgerRange.From()
+	r.generation++
+}
+
+// waitForReader waits until the client reading from the backend is done. It
+// must be called with the request locked.
+func (r *rangeRequest) waitForReader() {
+	if r.readDone == nil {
+		r.readDone = sync.NewCond(&r.Mutex)
+	}
+	r.readDone.Wait()
+}
+
+// readerDone wakes up the clients waiting for the reader. It must be called
+// with the request locked.
+func (r *rangeRequest) readerDone() {
+	r.reading = false
+	if r.readDone != nil {
+		r.readDone.Broadcast()
+	}
+}
+
+// idle returns true if the request was not used by any client for the given
+// duration. It must be called with the request locked.
+func (r *rangeRequest) idle(timeout time.Duration) bool {
+	return time.Since(r.lastUsed) >= timeout
+}
+
+// response must be called with the request locked.
+func (r *rangeRequest) response() ledgerbackend.PrepareRangeResponse {
+	return ledgerbackend.PrepareRangeResponse{
+		LedgerRange:   r.ledgerRange,
+		StartTime:     r.startTime,
+		Ready:         r.ready,
+		ReadyDuration: r.readyDuration,
+	}
+}
+
+// replaySession is an additional ledger backend used to serve a bounded range
+// which is not covered by the range prepared on the main captive core instance.
+type replaySession struct {
+	request *rangeRequest
+	core    ledgerbackend.LedgerBackend
+}
+
+type replayPool struct {
+	newBackend  func() (ledgerbackend.LedgerBackend, error)
+	maxSessions int
+	sessions    []*replaySession
+	sync.Mutex
+}
+
+// CaptiveCoreAPIConfig configures the optional features of CaptiveCoreAPI
+// which allow it to be shared by multiple clients.
+type CaptiveCoreAPIConfig struct {
+	// NewReplayBackend creates a LedgerBackend used to replay a bounded range
+	// which does not fit in the range prepared on the main captive core
+	// instance. If nil, bounded ranges are prepared on the main instance
+	// like unbounded ranges.
+	NewReplayBackend func() (ledgerbackend.LedgerBackend, error)
+	// MaxReplayBackends is the maximum number of replay backends running at
+	// the same time.
+	MaxReplayBackends int
+	// IdleTimeout is the duration after which a prepared range which was not
+	// used by any client can be replaced by the range of another client. It
+	// must be positive when NewReplayBackend is set. If zero, preparing a new
+	// range always replaces the range prepared on the main captive core
+	// instance.
+	IdleTimeout time.Duration
+	// Cache configures the cache of ledgers emitted by all backends.
+	Cache LedgerCacheConfig
+}
+
 // CaptiveCoreAPI manages a shared captive core subprocess and exposes an API for
 // executing commands remotely on the captive core instance.
+//
+// When configured with a replay backend factory, bounded ranges which are not
+// covered by the range prepared on the main captive core instance are
+// prepared on additional backends so multiple clients reading disjoint
+// ranges don't tear each other's ranges down. Ledgers returned by any backend
+// are kept in a shared cache so clients reading overlapping ranges can read
+// ledgers which were already emitted.
 type CaptiveCoreAPI struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	core          ledgerbackend.LedgerBackend
 	activeRequest *rangeRequest
+	idleTimeout   time.Duration
+	replays       *replayPool
+	cache         *ledgerCache
 	wg            *sync.WaitGroup
 	log           *log.Entry
 }
@@ -52,6 +163,39 @@ func NewCaptiveCoreAPI(core ledgerbackend.LedgerBackend, log *log.Entry) Captive
 	}
 }
 
+// NewCaptiveCoreAPIWithConfig constructs a new CaptiveCoreAPI instance which
+// can be shared by multiple clients.
+func NewCaptiveCoreAPIWithConfig(
+	core ledgerbackend.LedgerBackend,
+	config CaptiveCoreAPIConfig,
+	log *log.Entry,
+) (CaptiveCoreAPI, error) {
+	api := NewCaptiveCoreAPI(core, log)
+	api.idleTimeout = config.IdleTimeout
+
+	if config.NewReplayBackend != nil {
+		if config.MaxReplayBackends <= 0 {
+			return CaptiveCoreAPI{}, errors.New("MaxReplayBackends must be positive")
+		}
+		if config.IdleTimeout <= 0 {
+			return CaptiveCoreAPI{}, errors.New("IdleTimeout must be positive")
+		}
+		api.replays = &replayPool{
+			newBackend:  config.NewReplayBackend,
+			maxSessions: config.MaxReplayBackends,
+		}
+	}
+
+	cache, err := newLedgerCache(config.Cache, log)
+	if err != nil {
+		return CaptiveCoreAPI{}, err
+	}
+	if cache.enabled() {
+		api.cache = cache
+	}
+	return api, nil
+}
+
 // Shutdown disables the PrepareRange endpoint and closes
 // the captive core process.
 func (c *CaptiveCoreAPI) Shutdown() {
@@ -61,46 +205,60 @@ func (c *CaptiveCoreAPI) Shutdown() {
 
 	c.wg.Wait()
 	c.core.Close()
+
+	if c.replays != nil {
+		c.replays.Lock()
+		for _, session := range c.replays.sessions {
+			session.core.Close()
+		}
+		c.replays.sessions = nil
+		c.replays.Unlock()
+	}
 }
 
 func (c *CaptiveCoreAPI) isShutdown() bool {
 	return c.ctx.Err() != nil
 }
 
-func (c *CaptiveCoreAPI) startPrepareRange(ctx context.Context, ledgerRange ledgerbackend.Range) {
+func (c *CaptiveCoreAPI) startPrepareRange(
+	ctx context.Context,
+	request *rangeRequest,
+	core ledgerbackend.LedgerBackend,
+	ledgerRange ledgerbackend.Range,
+) {
 	defer c.wg.Done()
 
-	err := c.core.PrepareRange(ctx, ledgerRange)
+	err := core.PrepareRange(ctx, ledgerRange)
 
-	c.activeRequest.Lock()
-	defer c.activeRequest.Unlock()
+	request.Lock()
+	defer request.Unlock()
 	if c.isShutdown() {
 		return
 	}
 
-	if !c.activeRequest.valid || c.activeRequest.ledgerRange != ledgerRange {
+	if !request.valid || request.ledgerRange != ledgerRange {
 		c.log.WithFields(log.F{
-			"requestedRange": c.activeRequest.ledgerRange,
-			"valid":          c.activeRequest.valid,
+			"requestedRange": request.ledgerRange,
+			"valid":          request.valid,
 			"preparedRange":  ledgerRange,
 		}).Warn("Prepared range does not match requested range")
 		return
 	}
 
-	if c.activeRequest.ready {
+	if request.ready {
 		c.log.WithField("preparedRange", ledgerRange).Warn("Prepared range already completed")
 		return
 	}
 
 	if err != nil {
 		c.log.WithError(err).WithField("preparedRange", ledgerRange).Warn("Could not prepare range")
-		c.activeRequest.valid = false
-		c.activeRequest.ready = false
+		request.valid = false
+		request.ready = false
 		return
 	}
 
-	c.activeRequest.ready = true
-	c.activeRequest.readyDuration = int(time.Since(c.activeRequest.startTime).Seconds())
+	request.ready = true
+	request.readyDuration = int(time.Since(request.startTime).Seconds())
 }
 
 // PrepareRange executes the PrepareRange operation on the captive core instance.
@@ -111,36 +269,117 @@ func (c *CaptiveCoreAPI) PrepareRange(ctx context.Context, ledgerRange ledgerbac
 		return ledgerbackend.PrepareRangeResponse{}, errors.New("Cannot prepare range when shut down")
 	}
 
-	if !c.activeRequest.valid || !c.activeRequest.ledgerRange.Contains(ledgerRange) {
-		if c.activeRequest.valid {
-			c.log.WithFields(log.F{
-				"activeRange":    c.activeRequest.ledgerRange,
-				"requestedRange": ledgerRange,
-			}).Info("Requested range differs from previously requested range")
+	if c.activeRequest.valid && c.activeRequest.ledgerRange.Contains(ledgerRange) {
+		c.activeRequest.lastUsed = time.Now()
+		return c.activeRequest.response(), nil
+	}
+
+	if c.activeRequest.valid && !c.activeRequest.idle(c.idleTimeout) {
+		// Bounded ranges are replayed on a separate backend when the main
+		// captive core instance is used by another client, other ranges
+		// must wait until it is idle.
+		if c.replays != nil && ledgerRange.Bounded() {
+			return c.prepareReplayRange(ledgerRange)
+		}
+		return ledgerbackend.PrepareRangeResponse{}, ErrRangeInUse
+	}
+
+	if c.activeRequest.valid {
+		c.log.WithFields(log.F{
+			"activeRange":    c.activeRequest.ledgerRange,
+			"requestedRange": ledgerRange,
+		}).Info("Requested range differs from previously requested range")
+	}
+
+	c.activeRequest.reset(ledgerRange)
+
+	c.wg.Add(1)
+	go c.startPrepareRange(c.ctx, c.activeRequest, c.core, ledgerRange)
+
+	return c.activeRequest.response(), nil
+}
+
+// prepareReplayRange finds a replay session containing ledgerRange or starts a
+// new one. It must be called with c.activeRequest locked.
+func (c *CaptiveCoreAPI) prepareReplayRange(ledgerRange ledgerbackend.Range) (ledgerbackend.PrepareRangeResponse, error) {
+	c.replays.Lock()
+	defer c.replays.Unlock()
+
+	var idle *replaySession
+	var idleRange ledgerbackend.Range
+	var idleLastUsed time.Time
+	idleIndex := -1
+	for i, session := range c.replays.sessions {
+		session.request.Lock()
+		valid := session.request.valid
+		if valid && session.request.ledgerRange.Contains(ledgerRange) {
+			session.request.lastUsed = time.Now()
+			response := session.request.response()
+			session.request.Unlock()
+			return response, nil
+		}
+		// Sessions which failed or were not used by any client for the idle
+		// timeout can be replaced, least recently used first.
+		replaceable := !valid || session.request.idle(c.idleTimeout)
+		if replaceable && (idle == nil || session.request.lastUsed.Before(idleLastUsed)) {
+			idle, idleRange, idleLastUsed, idleIndex = session, session.request.ledgerRange, session.request.lastUsed, i
 		}
+		session.request.Unlock()
+	}
+
+	var session *replaySession
+	if len(c.replays.sessions) < c.replays.maxSessions {
+		core, err := c.replays.newBackend()
+		if err != nil {
+			return ledgerbackend.PrepareRangeResponse{}, errors.Wrap(err, "Could not create replay backend")
+		}
+		session = &replaySession{request: &rangeRequest{}, core: core}
+		c.replays.sessions = append(c.replays.sessions, session)
+	} else if idle != nil {
+		c.log.WithFields(log.F{
+			"replacedRange":  idleRange,
+			"requestedRange": ledgerRange,
+		}).Info("Replacing least recently used replay range")
+		session = idle
+		c.replays.sessions = append(c.replays.sessions[:idleIndex], c.replays.sessions[idleIndex+1:]...)
+		c.replays.sessions = append(c.replays.sessions, session)
+	} else {
+		return ledgerbackend.PrepareRangeResponse{}, ErrTooManyReplaySessions
+	}
 
-		c.activeRequest.ledgerRange = ledgerRange
-		c.activeRequest.startTime = time.Now()
-		c.activeRequest.ready = false
-		c.activeRequest.valid = true
+	session.request.Lock()
+	defer session.request.Unlock()
+	session.request.reset(ledgerRange)
 
-		c.wg.Add(1)
-		go c.startPrepareRange(c.ctx, ledgerRange)
+	c.wg.Add(1)
+	go c.startPrepareRange(c.ctx, session.request, session.core, ledgerRange)
 
-		return ledgerbackend.PrepareRangeResponse{
-			LedgerRange:   ledgerRange,
-			StartTime:     c.activeRequest.startTime,
-			Ready:         false,
-			ReadyDuration: 0,
-		}, nil
+	return session.request.response(), nil
+}
+
+// findReplaySession returns a replay session whose range contains the given
+// ledger, preferring sessions which are ready.
+func (c *CaptiveCoreAPI) findReplaySession(sequence uint32) *replaySession {
+	if c.replays == nil {
+		return nil
 	}
+	c.replays.Lock()
+	defer c.replays.Unlock()
 
-	return ledgerbackend.PrepareRangeResponse{
-		LedgerRange:   c.activeRequest.ledgerRange,
-		StartTime:     c.activeRequest.startTime,
-		Ready:         c.activeRequest.ready,
-		ReadyDuration: c.activeRequest.readyDuration,
-	}, nil
+	var found *replaySession
+	for _, session := range c.replays.sessions {
+		session.request.Lock()
+		valid, ready := session.request.valid, session.request.ready
+		contains := session.request.ledgerRange.Contains(ledgerbackend.SingleLedgerRange(sequence))
+		session.request.Unlock()
+		if valid && contains {
+			if ready {
+				return session
+			}
+			found = session
+		}
+	}
+	return found
 }
 
 // GetLatestLedgerSequence determines the latest ledger sequence available on the captive core instance.
@@ -155,6 +394,7 @@ func (c *CaptiveCoreAPI) GetLatestLedgerSequence(ctx context.Context) (ledgerbac
 		return ledgerbackend.LatestLedgerSequenceResponse{}, ErrPrepareRangeNotReady
 	}
 
+	c.activeRequest.lastUsed = time.Now()
 	seq, err := c.core.GetLatestLedgerSequence(ctx)
 	if err != nil {
 		c.activeRequest.valid = false
@@ -162,25 +402,119 @@ func (c *CaptiveCoreAPI) GetLatestLedgerSequence(ctx context.Context) (ledgerbac
 	return ledgerbackend.LatestLedgerSequenceResponse{Sequence: seq}, err
 }
 
-// GetLedger fetches the ledger with the given sequence number from the captive core instance.
+// GetLedger fetches the ledger with the given sequence number from the range
+// containing it: a replay session or the captive core instance. Ledgers which
+// were already emitted for other clients are read from the cache.
 func (c *CaptiveCoreAPI) GetLedger(ctx context.Context, sequence uint32) (ledgerbackend.LedgerResponse, error) {
-	c.activeRequest.Lock()
-	defer c.activeRequest.Unlock()
-
-	if !c.activeRequest.valid {
-		return ledgerbackend.LedgerResponse{}, ErrMissingPrepareRange
+	if session := c.findReplaySession(sequence); session != nil {
+		return c.getLedger(ctx, session.request, session.core, sequence)
 	}
-	if !c.activeRequest.ready {
-		return ledgerbackend.LedgerResponse{}, ErrPrepareRangeNotReady
+	return c.getLedger(ctx, c.activeRequest, c.core, sequence)
+}
+
+// getLedger returns the ledger from the cache or reads it from the backend.
+// The backend is read by one client at a time without holding the lock of the
+// request, so the clients reading cached ledgers and PrepareRange calls are
+// not blocked while a client waits for a ledger to close. The other clients
+// of the range wait for the reader, the ledgers it emits are cached.
+func (c *CaptiveCoreAPI) getLedger(
+	ctx context.Context,
+	request *rangeRequest,
+	core ledgerbackend.LedgerBackend,
+	sequence uint32,
+) (ledgerbackend.LedgerResponse, error) {
+	request.Lock()
+	defer request.Unlock()
+
+	for {
+		if !request.valid {
+			return ledgerbackend.LedgerResponse{}, ErrMissingPrepareRange
+		}
+		if !request.ready {
+			return ledgerbackend.LedgerResponse{}, ErrPrepareRangeNotReady
+		}
+		if !request.ledgerRange.Contains(ledgerbackend.SingleLedgerRange(sequence)) {
+			return ledgerbackend.LedgerResponse{}, ErrLedgerNotInRange
+		}
+		request.lastUsed = time.Now()
+
+		if c.cache != nil {
+			if ledger, ok := c.cache.get(sequence); ok {
+				return ledgerbackend.LedgerResponse{
+					Ledger: ledgerbackend.Base64Ledger(ledger),
+				}, nil
+			}
+		}
+		if !request.reading {
+			break
+		}
+		request.waitForReader()
 	}
 
-	ledger, err := c.core.GetLedger(ctx, sequence)
+	request.reading = true
+	generation, next := request.generation, request.next
+	request.Unlock()
+	ledger, next, err := c.readLedger(ctx, core, sequence, next)
+	request.Lock()
+	defer request.readerDone()
+
+	if request.generation != generation {
+		if err == nil {
+			err = ErrMissingPrepareRange
+		}
+		return ledgerbackend.LedgerResponse{}, err
+	}
+	request.next = next
 	if err != nil {
-		c.activeRequest.valid = false
+		// The range stays valid for the other clients when this client
+		// went away or read a ledger behind the stream.
+		if ctx.Err() == nil && sequence >= next {
+			request.valid = false
+		}
+		return ledgerbackend.LedgerResponse{}, err
 	}
 	// TODO: We are always true here now, so this changes the semantics of this
 	// call a bit. We need to change the client to long-poll this endpoint.
 	return ledgerbackend.LedgerResponse{
 		Ledger: ledgerbackend.Base64Ledger(ledger),
-	}, err
+	}, nil
+}
+
+// readLedger reads the ledger from the backend whose stream is at next and
+// returns the new position of the stream. It must be called by the only
+// reader of the backend, without holding the lock of the request.
+func (c *CaptiveCoreAPI) readLedger(
+	ctx context.Context,
+	core ledgerbackend.LedgerBackend,
+	sequence, next uint32,
+) (xdr.LedgerCloseMeta, uint32, error) {
+	// Ledgers behind the stream which are not cached may not be available
+	// anymore.
+	if sequence < next {
+		ledger, err := core.GetLedger(ctx, sequence)
+		return ledger, next, err
+	}
+
+	// When the cache is enabled the core is advanced one ledger at a time so
+	// the ledgers skipped by this client are cached for the clients behind it.
+	from := sequence
+	if c.cache != nil {
+		from = next
+	}
+	var ledger xdr.LedgerCloseMeta
+	for seq := from; seq <= sequence; seq++ {
+		if err := ctx.Err(); err != nil {
+			return xdr.LedgerCloseMeta{}, next, err
+		}
+		var err error
+		ledger, err = core.GetLedger(ctx, seq)
+		if err != nil {
+			return xdr.LedgerCloseMeta{}, next, err
+		}
+		next = seq + 1
+		if c.cache != nil {
+			c.cache.put(ledger)
+		}
+	}
+	return ledger, next, nil
 }
@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/stellar/go/ingest/ledgerbackend"
@@ -242,3 +244,211 @@ func (s *APITestSuite) TestNewPrepareRange() {
 	s.waitUntilReady(ledgerbackend.BoundedRange(45, 50))
 	s.waitUntilReady(ledgerbackend.UnboundedRange(46))
 }
+
+func TestReplaySessions(t *testing.T) {
+	ctx := context.Background()
+	primary := &ledgerbackend.MockDatabaseBackend{}
+	replay := &ledgerbackend.MockDatabaseBackend{}
+	created := 0
+	api, err := NewCaptiveCoreAPIWithConfig(primary, CaptiveCoreAPIConfig{
+		NewReplayBackend: func() (ledgerbackend.LedgerBackend, error) {
+			created++
+			return replay, nil
+		},
+		MaxReplayBackends: 1,
+		IdleTimeout:       time.Hour,
+		Cache:             LedgerCacheConfig{MemorySize: 10},
+	}, log.New())
+	require.NoError(t, err)
+
+	primary.On("PrepareRange", mock.Anything, ledgerbackend.UnboundedRange(63)).Return(nil).Once()
+	_, err = api.PrepareRange(ctx, ledgerbackend.UnboundedRange(63))
+	require.NoError(t, err)
+	api.wg.Wait()
+
+	// A disjoint bounded range does not replace the range prepared on the
+	// main instance.
+	waitChan := make(chan time.Time)
+	replay.On("PrepareRange", mock.Anything, ledgerbackend.BoundedRange(10, 20)).
+		WaitUntil(waitChan).Return(nil).Once()
+	response, err := api.PrepareRange(ctx, ledgerbackend.BoundedRange(10, 20))
+	require.NoError(t, err)
+	assert.False(t, response.Ready)
+	assert.Equal(t, ledgerbackend.BoundedRange(10, 20), response.LedgerRange)
+
+	// All replay sessions are busy.
+	_, err = api.PrepareRange(ctx, ledgerbackend.BoundedRange(30, 40))
+	assert.Equal(t, ErrTooManyReplaySessions, err)
+
+	close(waitChan)
+	api.wg.Wait()
+	response, err = api.PrepareRange(ctx, ledgerbackend.BoundedRange(12, 15))
+	require.NoError(t, err)
+	assert.True(t, response.Ready)
+	assert.Equal(t, ledgerbackend.BoundedRange(10, 20), response.LedgerRange)
+	assert.True(t, api.activeRequest.ready)
+	assert.Equal(t, ledgerbackend.UnboundedRange(63), api.activeRequest.ledgerRange)
+
+	// The skipped ledgers are read and cached too.
+	for seq := uint32(10); seq <= 15; seq++ {
+		replay.On("GetLedger", ctx, seq).Return(testLedger(seq), nil).Once()
+	}
+	primary.On("GetLedger", ctx, uint32(63)).Return(testLedger(63), nil).Once()
+	primary.On("GetLedger", ctx, uint32(64)).Return(testLedger(64), nil).Once()
+	replayLedger, primaryLedger := testLedger(15), testLedger(64)
+
+	// Second calls are served from the cache.
+	for i := 0; i < 2; i++ {
+		var ledger ledgerbackend.LedgerResponse
+		ledger, err = api.GetLedger(ctx, 15)
+		require.NoError(t, err)
+		assert.Equal(t, ledgerbackend.Base64Ledger(replayLedger), ledger.Ledger)
+
+		ledger, err = api.GetLedger(ctx, 64)
+		require.NoError(t, err)
+		assert.Equal(t, ledgerbackend.Base64Ledger(primaryLedger), ledger.Ledger)
+	}
+
+	// The replay session is still used.
+	_, err = api.PrepareRange(ctx, ledgerbackend.BoundedRange(30, 40))
+	assert.Equal(t, ErrTooManyReplaySessions, err)
+
+	// The replay session is idle so it can be replaced.
+	api.replays.sessions[0].request.lastUsed = time.Now().Add(-time.Hour)
+	replay.On("PrepareRange", mock.Anything, ledgerbackend.BoundedRange(30, 40)).Return(nil).Once()
+	_, err = api.PrepareRange(ctx, ledgerbackend.BoundedRange(30, 40))
+	require.NoError(t, err)
+	api.wg.Wait()
+	assert.Equal(t, 1, created)
+
+	primary.On("Close").Return(nil).Once()
+	replay.On("Close").Return(nil).Once()
+	api.Shutdown()
+
+	primary.AssertExpectations(t)
+	replay.AssertExpectations(t)
+}
+
+func TestClientsSharingRange(t *testing.T) {
+	ctx := context.Background()
+	core := &ledgerbackend.MockDatabaseBackend{}
+	api, err := NewCaptiveCoreAPIWithConfig(core, CaptiveCoreAPIConfig{
+		IdleTimeout: time.Hour,
+		Cache:       LedgerCacheConfig{MemorySize: 4},
+	}, log.New())
+	require.NoError(t, err)
+
+	core.On("PrepareRange", mock.Anything, ledgerbackend.UnboundedRange(63)).Return(nil).Once()
+	_, err = api.PrepareRange(ctx, ledgerbackend.UnboundedRange(63))
+	require.NoError(t, err)
+	api.wg.Wait()
+
+	getLedger := func(sequence uint32) error {
+		ledger, getErr := api.GetLedger(ctx, sequence)
+		if getErr == nil {
+			assert.Equal(t, ledgerbackend.Base64Ledger(testLedger(sequence)), ledger.Ledger)
+		}
+		return getErr
+	}
+
+	// The first client advances the core one ledger at a time.
+	for seq := uint32(63); seq <= 66; seq++ {
+		core.On("GetLedger", ctx, seq).Return(testLedger(seq), nil).Once()
+	}
+	require.NoError(t, getLedger(66))
+
+	// The second client reads the ledgers behind the first one from the cache.
+	require.NoError(t, getLedger(64))
+	require.NoError(t, getLedger(65))
+
+	core.On("GetLedger", ctx, uint32(67)).Return(testLedger(67), nil).Once()
+	require.NoError(t, getLedger(67))
+
+	// Ledger 63 was evicted from the cache and is behind the stream. The
+	// request fails but the range stays valid for the first client.
+	behindErr := fmt.Errorf("requested ledger 63 is behind the captive core stream")
+	core.On("GetLedger", ctx, uint32(63)).Return(xdr.LedgerCloseMeta{}, behindErr).Once()
+	assert.Equal(t, behindErr, getLedger(63))
+	assert.True(t, api.activeRequest.valid)
+
+	core.On("GetLedger", ctx, uint32(68)).Return(testLedger(68), nil).Once()
+	require.NoError(t, getLedger(68))
+
+	// Cached ledgers are only served within the prepared range.
+	api.cache.put(testLedger(10))
+	assert.Equal(t, ErrLedgerNotInRange, getLedger(10))
+
+	// The range is used so it is not replaced by the range of another client.
+	_, err = api.PrepareRange(ctx, ledgerbackend.UnboundedRange(50))
+	assert.Equal(t, ErrRangeInUse, err)
+	_, err = api.PrepareRange(ctx, ledgerbackend.BoundedRange(10, 20))
+	assert.Equal(t, ErrRangeInUse, err)
+	assert.Equal(t, ledgerbackend.UnboundedRange(63), api.activeRequest.ledgerRange)
+
+	api.activeRequest.lastUsed = time.Now().Add(-time.Hour)
+	core.On("PrepareRange", mock.Anything, ledgerbackend.UnboundedRange(50)).Return(nil).Once()
+	_, err = api.PrepareRange(ctx, ledgerbackend.UnboundedRange(50))
+	require.NoError(t, err)
+	api.wg.Wait()
+	assert.Equal(t, ledgerbackend.UnboundedRange(50), api.activeRequest.ledgerRange)
+
+	core.On("Close").Return(nil).Once()
+	api.Shutdown()
+	core.AssertExpectations(t)
+}
+
+func TestReaderDoesNotBlockOtherClients(t *testing.T) {
+	ctx := context.Background()
+	core := &ledgerbackend.MockDatabaseBackend{}
+	api, err := NewCaptiveCoreAPIWithConfig(core, CaptiveCoreAPIConfig{
+		IdleTimeout: time.Hour,
+		Cache:       LedgerCacheConfig{MemorySize: 4},
+	}, log.New())
+	require.NoError(t, err)
+
+	core.On("PrepareRange", mock.Anything, ledgerbackend.UnboundedRange(63)).Return(nil).Once()
+	_, err = api.PrepareRange(ctx, ledgerbackend.UnboundedRange(63))
+	require.NoError(t, err)
+	api.wg.Wait()
+
+	core.On("GetLedger", ctx, uint32(63)).Return(testLedger(63), nil).Once()
+	_, err = api.GetLedger(ctx, 63)
+	require.NoError(t, err)
+
+	// The first client waits for ledger 64 to close.
+	reading, closed := make(chan struct{}), make(chan struct{})
+	core.On("GetLedger", ctx, uint32(64)).Run(func(mock.Arguments) {
+		close(reading)
+		<-closed
+	}).Return(testLedger(64), nil).Once()
+	responses := make(chan ledgerbackend.LedgerResponse)
+	getLedger := func() {
+		ledger, getErr := api.GetLedger(ctx, 64)
+		assert.NoError(t, getErr)
+		responses <- ledger
+	}
+	go getLedger()
+	<-reading
+
+	// Cached ledgers and prepared ranges are served in the meantime.
+	ledger, err := api.GetLedger(ctx, 63)
+	require.NoError(t, err)
+	assert.Equal(t, ledgerbackend.Base64Ledger(testLedger(63)), ledger.Ledger)
+	response, err := api.PrepareRange(ctx, ledgerbackend.UnboundedRange(63))
+	require.NoError(t, err)
+	assert.True(t, response.Ready)
+	_, err = api.PrepareRange(ctx, ledgerbackend.UnboundedRange(50))
+	assert.Equal(t, ErrRangeInUse, err)
+
+	// The second client waits for the first one and reads the ledger from
+	// the cache.
+	go getLedger()
+	close(closed)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, ledgerbackend.Base64Ledger(testLedger(64)), (<-responses).Ledger)
+	}
+
+	core.On("Close").Return(nil).Once()
+	api.Shutdown()
+	core.AssertExpectations(t)
+}
@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/log"
+	"github.com/stellar/go/xdr"
+)
+
+// LedgerCacheConfig configures the cache of recently emitted ledgers shared by
+// all clients of CaptiveCoreAPI.
+type LedgerCacheConfig struct {
+	// MemorySize is the number of ledgers kept in memory. Zero disables the
+	// in-memory cache.
+	MemorySize int
+	// Path is the directory in which ledgers are stored on disk. Empty
+	// disables the on-disk cache.
+	Path string
+	// DiskSize is the number of ledgers kept on disk. Zero means ledgers
+	// are never removed from disk.
+	DiskSize int
+}
+
+// ledgerCache is a two tier LRU cache of ledgers. Ledgers evicted from memory
+// can still be read from disk until they are evicted from the disk tier.
+type ledgerCache struct {
+	config LedgerCacheConfig
+	log    *log.Entry
+
+	mutex      sync.Mutex
+	memory     *list.List
+	memoryKeys map[uint32]*list.Element
+	disk       *list.List
+	diskKeys   map[uint32]*list.Element
+}
+
+type cachedLedger struct {
+	sequence uint32
+	ledger   xdr.LedgerCloseMeta
+}
+
+func newLedgerCache(config LedgerCacheConfig, logger *log.Entry) (*ledgerCache, error) {
+	c := &ledgerCache{
+		config:     config,
+		log:        logger,
+		memory:     list.New(),
+		memoryKeys: map[uint32]*list.Element{},
+		disk:       list.New(),
+		diskKeys:   map[uint32]*list.Element{},
+	}
+	if config.Path == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, errors.Wrap(err, "cannot create ledger cache directory")
+	}
+	files, err := ioutil.ReadDir(config.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read ledger cache directory")
+	}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasPrefix(name, ".tmp-") {
+			// Leftover from an interrupted write.
+			os.Remove(filepath.Join(config.Path, name))
+			continue
+		}
+		if filepath.Ext(name) != ".xdr" {
+			continue
+		}
+		sequence, parseErr := strconv.ParseUint(strings.TrimSuffix(name, ".xdr"), 10, 32)
+		if parseErr != nil {
+			continue
+		}
+		c.diskKeys[uint32(sequence)] = c.disk.PushFront(uint32(sequence))
+	}
+	c.evictDisk()
+	return c, nil
+}
+
+func (c *ledgerCache) enabled() bool {
+	return c.config.MemorySize > 0 || c.config.Path != ""
+}
+
+func (c *ledgerCache) ledgerPath(sequence uint32) string {
+	return filepath.Join(c.config.Path, strconv.FormatUint(uint64(sequence), 10)+".xdr")
+}
+
+// get returns the ledger with the given sequence if it is cached.
+func (c *ledgerCache) get(sequence uint32) (xdr.LedgerCloseMeta, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.memoryKeys[sequence]; ok {
+		c.memory.MoveToFront(elem)
+		return elem.Value.(*cachedLedger).ledger, true
+	}
+
+	elem, ok := c.diskKeys[sequence]
+	if !ok {
+		return xdr.LedgerCloseMeta{}, false
+	}
+	c.disk.MoveToFront(elem)
+
+	var ledger xdr.LedgerCloseMeta
+	raw, err := ioutil.ReadFile(c.ledgerPath(sequence))
+	if err == nil {
+		err = ledger.UnmarshalBinary(raw)
+	}
+	if err != nil {
+		c.log.WithError(err).WithField("sequence", sequence).Warn("Could not read cached ledger")
+		c.disk.Remove(elem)
+		delete(c.diskKeys, sequence)
+		os.Remove(c.ledgerPath(sequence))
+		return xdr.LedgerCloseMeta{}, false
+	}
+	c.addToMemory(sequence, ledger)
+	return ledger, true
+}
+
+// put adds the ledger to the cache.
+func (c *ledgerCache) put(ledger xdr.LedgerCloseMeta) {
+	sequence := ledger.LedgerSequence()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.addToMemory(sequence, ledger)
+	if c.config.Path == "" {
+		return
+	}
+	if elem, ok := c.diskKeys[sequence]; ok {
+		c.disk.MoveToFront(elem)
+		return
+	}
+	if err := c.writeLedger(sequence, ledger); err != nil {
+		c.log.WithError(err).WithField("sequence", sequence).Warn("Could not write ledger to cache")
+		return
+	}
+	c.diskKeys[sequence] = c.disk.PushFront(sequence)
+	c.evictDisk()
+}
+
+func (c *ledgerCache) writeLedger(sequence uint32, ledger xdr.LedgerCloseMeta) error {
+	raw, err := ledger.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(c.config.Path, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.ledgerPath(sequence))
+}
+
+// addToMemory must be called with c.mutex held.
+func (c *ledgerCache) addToMemory(sequence uint32, ledger xdr.LedgerCloseMeta) {
+	if c.config.MemorySize <= 0 {
+		return
+	}
+	if elem, ok := c.memoryKeys[sequence]; ok {
+		c.memory.MoveToFront(elem)
+		return
+	}
+	c.memoryKeys[sequence] = c.memory.PushFront(&cachedLedger{sequence: sequence, ledger: ledger})
+	for c.memory.Len() > c.config.MemorySize {
+		oldest := c.memory.Remove(c.memory.Back()).(*cachedLedger)
+		delete(c.memoryKeys, oldest.sequence)
+	}
+}
+
+// evictDisk must be called with c.mutex held.
+func (c *ledgerCache) evictDisk() {
+	if c.config.DiskSize <= 0 {
+		return
+	}
+	for c.disk.Len() > c.config.DiskSize {
+		oldest := c.disk.Remove(c.disk.Back()).(uint32)
+		delete(c.diskKeys, oldest)
+		if err := os.Remove(c.ledgerPath(oldest)); err != nil && !os.IsNotExist(err) {
+			c.log.WithError(err).WithField("sequence", oldest).Warn("Could not remove cached ledger")
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/support/log"
+	"github.com/stellar/go/xdr"
+)
+
+func testLedger(sequence uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence)},
+			},
+		},
+	}
+}
+
+func TestLedgerCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "captivecore-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := LedgerCacheConfig{MemorySize: 1, Path: dir, DiskSize: 2}
+	cache, err := newLedgerCache(config, log.New())
+	require.NoError(t, err)
+
+	for sequence := uint32(1); sequence <= 3; sequence++ {
+		cache.put(testLedger(sequence))
+	}
+	assert.Equal(t, 1, cache.memory.Len())
+
+	_, ok := cache.get(1)
+	assert.False(t, ok)
+	for sequence := uint32(2); sequence <= 3; sequence++ {
+		ledger, found := cache.get(sequence)
+		require.True(t, found)
+		assert.Equal(t, sequence, ledger.LedgerSequence())
+	}
+
+	// Ledgers stored on disk are reused.
+	reopened, err := newLedgerCache(config, log.New())
+	require.NoError(t, err)
+	ledger, ok := reopened.get(2)
+	require.True(t, ok)
+	assert.Equal(t, uint32(2), ledger.LedgerSequence())
+	_, ok = reopened.get(1)
+	assert.False(t, ok)
+}
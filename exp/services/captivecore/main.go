@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/types"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -24,6 +25,8 @@ func main() {
 	var captiveCoreTomlParams ledgerbackend.CaptiveCoreTomlParams
 	var historyArchiveURLs []string
 	var checkpointFrequency uint32
+	var maxReplaySessions, rangeIdleTimeout, ledgerCacheSize, ledgerCacheDiskSize int
+	var ledgerCachePath string
 	var logLevel logrus.Level
 	logger := supportlog.New()
 
@@ -115,6 +118,46 @@ func main() {
 			Required:    false,
 			Usage:       "establishes how many ledgers exist between checkpoints, do NOT change this unless you really know what you are doing",
 		},
+		&config.ConfigOption{
+			Name:        "max-replay-sessions",
+			ConfigKey:   &maxReplaySessions,
+			OptType:     types.Int,
+			FlagDefault: 0,
+			Required:    false,
+			Usage:       "maximum number of additional captive core subprocesses replaying bounded ranges requested by other clients (0 disables replay sessions)",
+		},
+		&config.ConfigOption{
+			Name:        "range-idle-timeout",
+			ConfigKey:   &rangeIdleTimeout,
+			OptType:     types.Int,
+			FlagDefault: 60,
+			Required:    false,
+			Usage:       "seconds without requests after which a prepared range can be replaced by the range of another client (0 lets any client replace the range of the main subprocess, it must be positive with replay sessions)",
+		},
+		&config.ConfigOption{
+			Name:        "ledger-cache-size",
+			ConfigKey:   &ledgerCacheSize,
+			OptType:     types.Int,
+			FlagDefault: 100,
+			Required:    false,
+			Usage:       "number of recently emitted ledgers kept in memory and shared by all clients (0 disables the in-memory cache)",
+		},
+		&config.ConfigOption{
+			Name:        "ledger-cache-path",
+			ConfigKey:   &ledgerCachePath,
+			OptType:     types.String,
+			FlagDefault: "",
+			Required:    false,
+			Usage:       "directory in which recently emitted ledgers are stored on disk (empty disables the on-disk cache)",
+		},
+		&config.ConfigOption{
+			Name:        "ledger-cache-disk-size",
+			ConfigKey:   &ledgerCacheDiskSize,
+			OptType:     types.Int,
+			FlagDefault: 10000,
+			Required:    false,
+			Usage:       "number of recently emitted ledgers kept in the on-disk cache (0 means no limit)",
+		},
 	}
 	cmd := &cobra.Command{
 		Use:   "captivecore",
@@ -154,7 +197,24 @@ func main() {
 			if err != nil {
 				logger.WithError(err).Fatal("Could not create captive core instance")
 			}
-			api := internal.NewCaptiveCoreAPI(core, logger.WithField("subservice", "api"))
+			apiConfig := internal.CaptiveCoreAPIConfig{
+				IdleTimeout: time.Duration(rangeIdleTimeout) * time.Second,
+				Cache: internal.LedgerCacheConfig{
+					MemorySize: ledgerCacheSize,
+					Path:       ledgerCachePath,
+					DiskSize:   ledgerCacheDiskSize,
+				},
+			}
+			if maxReplaySessions > 0 {
+				apiConfig.MaxReplayBackends = maxReplaySessions
+				apiConfig.NewReplayBackend = func() (ledgerbackend.LedgerBackend, error) {
+					return ledgerbackend.NewCaptive(captiveConfig)
+				}
+			}
+			api, err := internal.NewCaptiveCoreAPIWithConfig(core, apiConfig, logger.WithField("subservice", "api"))
+			if err != nil {
+				logger.WithError(err).Fatal("Could not create captive core API")
+			}
 
 			supporthttp.Run(supporthttp.Config{
 				ListenAddr: fmt.Sprintf(":%d", port),
@@ -0,0 +1,499 @@
+// Package statexport exports the ledger state at a checkpoint ledger to
+// files with one table per ledger entry type and decoded, typed columns.
+//
+// Entries are streamed from the history archive buckets using
+// ingest.CheckpointChangeReader and written to one file per table and
+// bucket, in bucket order, so the output for a given checkpoint is
+// deterministic and can be diffed with exports of other checkpoints. Progress
+// is recorded in a manifest after every bucket which allows interrupted
+// exports to be resumed.
+package statexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stellar/go/historyarchive"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/log"
+	"github.com/stellar/go/xdr"
+)
+
+// ManifestFileName is the name of the manifest file in the output
+// directory.
+const ManifestFileName = "manifest.json"
+
+// Config configures Export.
+type Config struct {
+	Archive historyarchive.ArchiveInterface
+	// Checkpoint is the checkpoint ledger to export.
+	Checkpoint uint32
+	// OutputDir is the directory the tables and the manifest are written to.
+	OutputDir string
+	// Format is the format of the table files, CSVFormat by default.
+	Format Format
+	// Tables are the names of the tables to export (see Tables()). All
+	// tables are exported by default.
+	Tables []string
+	Log    *log.Entry
+}
+
+// TableSchema describes an exported table.
+type TableSchema struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+}
+
+// BucketManifest describes the files written for a single bucket.
+type BucketManifest struct {
+	// Index is the position of the bucket in the HAS bucket list, see
+	// ingest.CheckpointChangeReader.ReadWithBucketIndex.
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+	// Files maps table names to file paths relative to the output
+	// directory. Tables without rows in the bucket have no file.
+	Files map[string]string `json:"files"`
+	// Rows maps table names to the number of rows written.
+	Rows map[string]int64 `json:"rows"`
+}
+
+// Manifest describes the state of an export. It is written to
+// ManifestFileName in the output directory after every bucket.
+type Manifest struct {
+	Checkpoint uint32        `json:"checkpoint"`
+	Format     string        `json:"format"`
+	Tables     []TableSchema `json:"tables"`
+	// Buckets lists the buckets which were fully exported, ordered by index.
+	Buckets []BucketManifest `json:"buckets"`
+	// Complete is true when all buckets were exported.
+	Complete bool `json:"complete"`
+}
+
+type exporter struct {
+	config   Config
+	tables   []Table
+	manifest *Manifest
+	done     map[int]bool
+	buckets  map[int]historyarchive.Hash
+}
+
+// Export exports the ledger state at config.Checkpoint and returns the
+// manifest of the export. If config.OutputDir contains an unfinished export
+// of the same checkpoint, format and tables, the export is resumed: buckets
+// which were already exported are still read (to track entries shadowed by
+// newer buckets) but their files are not written again.
+func Export(ctx context.Context, config Config) (*Manifest, error) {
+	if config.Archive == nil {
+		return nil, errors.New("archive is required")
+	}
+	if config.OutputDir == "" {
+		return nil, errors.New("output directory is required")
+	}
+	if config.Format == nil {
+		config.Format = CSVFormat{}
+	}
+	if config.Log == nil {
+		config.Log = log.DefaultLogger
+	}
+
+	tables, err := selectTables(config.Tables)
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{config: config, tables: tables, done: map[int]bool{}}
+
+	has, err := config.Archive.GetCheckpointHAS(config.Checkpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting HAS of checkpoint %d", config.Checkpoint)
+	}
+	if e.buckets, err = bucketsByIndex(has); err != nil {
+		return nil, err
+	}
+
+	if err = e.loadManifest(); err != nil {
+		return nil, err
+	}
+	if e.manifest.Complete {
+		config.Log.Info("Export is already complete")
+		return e.manifest, nil
+	}
+	if err = e.prepareOutputDir(); err != nil {
+		return nil, err
+	}
+	if err = e.run(ctx); err != nil {
+		return nil, err
+	}
+	return e.manifest, nil
+}
+
+func selectTables(names []string) ([]Table, error) {
+	all := Tables()
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	selected := map[string]bool{}
+	for _, name := range names {
+		found := false
+		for _, table := range all {
+			if table.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unknown table %s", name)
+		}
+		selected[name] = true
+	}
+
+	var tables []Table
+	for _, table := range all {
+		if selected[table.Name] {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+func bucketsByIndex(has historyarchive.HistoryArchiveState) (map[int]historyarchive.Hash, error) {
+	buckets := map[int]historyarchive.Hash{}
+	for i, level := range has.CurrentBuckets {
+		for j, hashString := range []string{level.Curr, level.Snap} {
+			hash, err := historyarchive.DecodeHash(hashString)
+			if err != nil {
+				return nil, errors.Wrap(err, "error decoding bucket hash")
+			}
+			if !hash.IsZero() {
+				buckets[2*i+j] = hash
+			}
+		}
+	}
+	return buckets, nil
+}
+
+func (e *exporter) loadManifest() error {
+	manifest := &Manifest{
+		Checkpoint: e.config.Checkpoint,
+		Format:     e.config.Format.Name(),
+		Buckets:    []BucketManifest{},
+	}
+	for _, table := range e.tables {
+		manifest.Tables = append(manifest.Tables, TableSchema{Name: table.Name, Columns: table.Columns})
+	}
+	e.manifest = manifest
+
+	raw, err := ioutil.ReadFile(filepath.Join(e.config.OutputDir, ManifestFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "error reading manifest")
+	}
+
+	var existing Manifest
+	if err = json.Unmarshal(raw, &existing); err != nil {
+		return errors.Wrap(err, "error decoding manifest")
+	}
+	if existing.Checkpoint != manifest.Checkpoint || existing.Format != manifest.Format ||
+		!sameTables(existing.Tables, manifest.Tables) {
+		return errors.Errorf(
+			"output directory contains an export of checkpoint %d with a different configuration",
+			existing.Checkpoint,
+		)
+	}
+	for _, bucket := range existing.Buckets {
+		e.done[bucket.Index] = true
+	}
+	e.manifest = &existing
+	e.config.Log.WithField("buckets", len(existing.Buckets)).Info("Resuming export")
+	return nil
+}
+
+func sameTables(a, b []TableSchema) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || len(a[i].Columns) != len(b[i].Columns) {
+			return false
+		}
+		for j := range a[i].Columns {
+			if a[i].Columns[j] != b[i].Columns[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// prepareOutputDir creates the table directories and removes files left
+// behind by an interrupted export.
+func (e *exporter) prepareOutputDir() error {
+	for _, table := range e.tables {
+		dir := filepath.Join(e.config.OutputDir, table.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrapf(err, "error creating directory %s", dir)
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return errors.Wrapf(err, "error reading directory %s", dir)
+		}
+		for _, file := range files {
+			if strings.HasPrefix(file.Name(), ".tmp-") {
+				if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+					return errors.Wrap(err, "error removing temporary file")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) run(ctx context.Context) error {
+	var entryTypes []xdr.LedgerEntryType
+	seenTypes := map[xdr.LedgerEntryType]bool{}
+	for _, table := range e.tables {
+		if !seenTypes[table.EntryType] {
+			seenTypes[table.EntryType] = true
+			entryTypes = append(entryTypes, table.EntryType)
+		}
+	}
+
+	reader, err := ingest.NewFilteredCheckpointChangeReader(
+		ctx, e.config.Archive, e.config.Checkpoint,
+		ingest.CheckpointFilter{LedgerEntryTypes: entryTypes},
+	)
+	if err != nil {
+		return errors.Wrap(err, "error creating checkpoint change reader")
+	}
+	defer reader.Close()
+
+	var current *bucketWriter
+	defer func() {
+		if current != nil {
+			current.abort()
+		}
+	}()
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		var change ingest.Change
+		var index int
+		change, index, err = reader.ReadWithBucketIndex()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "error reading checkpoint changes")
+		}
+
+		if current != nil && current.index != index {
+			if err = e.finishBucket(current); err != nil {
+				return err
+			}
+			current = nil
+		}
+		if e.done[index] {
+			continue
+		}
+		if current == nil {
+			if err = e.finishBucketsBefore(index); err != nil {
+				return err
+			}
+			current = e.newBucketWriter(index)
+		}
+		if err = current.write(change.Post); err != nil {
+			return err
+		}
+	}
+
+	if current != nil {
+		if err = e.finishBucket(current); err != nil {
+			return err
+		}
+		current = nil
+	}
+	// Buckets without exported entries.
+	if err = e.finishBucketsBefore(math.MaxInt32); err != nil {
+		return err
+	}
+
+	e.manifest.Complete = true
+	if err = e.writeManifest(); err != nil {
+		return err
+	}
+	e.config.Log.WithField("buckets", len(e.manifest.Buckets)).Info("Export complete")
+	return nil
+}
+
+// finishBucketsBefore records buckets with indices lower than index, which
+// did not contain any exported entries, as done.
+func (e *exporter) finishBucketsBefore(index int) error {
+	indices := make([]int, 0, len(e.buckets))
+	for i := range e.buckets {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	for _, i := range indices {
+		if i >= index {
+			break
+		}
+		if !e.done[i] {
+			if err := e.finishBucket(e.newBucketWriter(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *exporter) finishBucket(w *bucketWriter) error {
+	bucket, err := w.finish()
+	if err != nil {
+		return err
+	}
+	e.done[w.index] = true
+	e.manifest.Buckets = append(e.manifest.Buckets, bucket)
+	sort.Slice(e.manifest.Buckets, func(i, j int) bool {
+		return e.manifest.Buckets[i].Index < e.manifest.Buckets[j].Index
+	})
+	if err = e.writeManifest(); err != nil {
+		return err
+	}
+	e.config.Log.WithField("bucket", bucket.Hash).WithField("index", bucket.Index).Info("Exported bucket")
+	return nil
+}
+
+func (e *exporter) writeManifest() error {
+	raw, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding manifest")
+	}
+	path := filepath.Join(e.config.OutputDir, ManifestFileName)
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, raw, 0644); err != nil {
+		return errors.Wrap(err, "error writing manifest")
+	}
+	return errors.Wrap(os.Rename(tmp, path), "error writing manifest")
+}
+
+func (e *exporter) newBucketWriter(index int) *bucketWriter {
+	return &bucketWriter{
+		exporter: e,
+		index:    index,
+		hash:     e.buckets[index],
+		files:    map[string]*os.File{},
+		writers:  map[string]TableWriter{},
+		rows:     map[string]int64{},
+	}
+}
+
+// bucketWriter writes the rows of a single bucket to temporary files which
+// are renamed when the bucket is finished.
+type bucketWriter struct {
+	exporter *exporter
+	index    int
+	hash     historyarchive.Hash
+	files    map[string]*os.File
+	writers  map[string]TableWriter
+	rows     map[string]int64
+}
+
+func (w *bucketWriter) write(entry *xdr.LedgerEntry) error {
+	for _, table := range w.exporter.tables {
+		if table.EntryType != entry.Data.Type {
+			continue
+		}
+		rows, err := table.Rows(entry)
+		if err != nil {
+			return errors.Wrapf(err, "error exporting entry to table %s", table.Name)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		writer, ok := w.writers[table.Name]
+		if !ok {
+			if writer, err = w.open(table); err != nil {
+				return err
+			}
+		}
+		for _, row := range rows {
+			if err = writer.WriteRow(row); err != nil {
+				return errors.Wrapf(err, "error writing row to table %s", table.Name)
+			}
+		}
+		w.rows[table.Name] += int64(len(rows))
+	}
+	return nil
+}
+
+func (w *bucketWriter) open(table Table) (TableWriter, error) {
+	file, err := ioutil.TempFile(filepath.Join(w.exporter.config.OutputDir, table.Name), ".tmp-")
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating table file")
+	}
+	w.files[table.Name] = file
+
+	writer, err := w.exporter.config.Format.NewTableWriter(file, table.Columns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating writer for table %s", table.Name)
+	}
+	w.writers[table.Name] = writer
+	return writer, nil
+}
+
+func (w *bucketWriter) fileName(table string) string {
+	return filepath.Join(table, fmt.Sprintf("%02d-%s%s", w.index, w.hash, w.exporter.config.Format.Extension()))
+}
+
+func (w *bucketWriter) finish() (BucketManifest, error) {
+	bucket := BucketManifest{
+		Index: w.index,
+		Hash:  w.hash.String(),
+		Files: map[string]string{},
+		Rows:  w.rows,
+	}
+	for _, table := range w.exporter.tables {
+		file, ok := w.files[table.Name]
+		if !ok {
+			continue
+		}
+		err := w.writers[table.Name].Close()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return bucket, errors.Wrapf(err, "error closing file of table %s", table.Name)
+		}
+
+		name := w.fileName(table.Name)
+		if err = os.Rename(file.Name(), filepath.Join(w.exporter.config.OutputDir, name)); err != nil {
+			return bucket, errors.Wrapf(err, "error renaming file of table %s", table.Name)
+		}
+		bucket.Files[table.Name] = name
+		delete(w.files, table.Name)
+	}
+	return bucket, nil
+}
+
+// abort removes the temporary files of an unfinished bucket.
+func (w *bucketWriter) abort() {
+	for _, file := range w.files {
+		file.Close()
+		os.Remove(file.Name())
+	}
+}
@@ -0,0 +1,238 @@
+package statexport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/historyarchive"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	accountA = "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"
+	accountB = "GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB"
+	issuer   = "GBXGQJWVLWOYHFLVTKWV5FGHA3LNYY2JQKM7OAJAUEQFU6LPCSEFVXON"
+)
+
+func liveEntry(lastModified uint32, data xdr.LedgerEntryData) xdr.BucketEntry {
+	return xdr.BucketEntry{
+		Type: xdr.BucketEntryTypeLiveentry,
+		LiveEntry: &xdr.LedgerEntry{
+			LastModifiedLedgerSeq: xdr.Uint32(lastModified),
+			Data:                  data,
+		},
+	}
+}
+
+func accountData(address string, balance int64) xdr.LedgerEntryData {
+	return xdr.LedgerEntryData{
+		Type: xdr.LedgerEntryTypeAccount,
+		Account: &xdr.AccountEntry{
+			AccountId:  xdr.MustAddress(address),
+			Balance:    xdr.Int64(balance),
+			SeqNum:     1,
+			HomeDomain: "example.com",
+			Thresholds: xdr.Thresholds{1, 2, 3, 4},
+			Signers: []xdr.Signer{
+				{Key: xdr.MustSigner(issuer), Weight: 5},
+			},
+		},
+	}
+}
+
+// writeBucket writes a bucket with the given entries to the archive in dir
+// and returns its hash.
+func writeBucket(t *testing.T, dir string, entries ...xdr.BucketEntry) string {
+	var raw bytes.Buffer
+	require.NoError(t, xdr.MarshalFramed(&raw, metaEntry()))
+	for _, entry := range entries {
+		require.NoError(t, xdr.MarshalFramed(&raw, entry))
+	}
+	hash := historyarchive.Hash(sha256.Sum256(raw.Bytes()))
+
+	path := filepath.Join(dir, historyarchive.BucketPath(hash))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write(raw.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, ioutil.WriteFile(path, compressed.Bytes(), 0644))
+	return hash.String()
+}
+
+func metaEntry() xdr.BucketEntry {
+	return xdr.BucketEntry{
+		Type:      xdr.BucketEntryTypeMetaentry,
+		MetaEntry: &xdr.BucketMetadata{LedgerVersion: 17},
+	}
+}
+
+func testArchive(t *testing.T) *historyarchive.Archive {
+	dir := t.TempDir()
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+
+	level0Curr := writeBucket(t, dir,
+		liveEntry(60, accountData(accountA, 100)),
+		liveEntry(61, xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: xdr.MustAddress(accountA),
+				Asset:     usd.ToTrustLineAsset(),
+				Balance:   10,
+				Limit:     1000,
+				Flags:     1,
+			},
+		}),
+		xdr.BucketEntry{
+			Type: xdr.BucketEntryTypeDeadentry,
+			DeadEntry: &xdr.LedgerKey{
+				Type:    xdr.LedgerEntryTypeAccount,
+				Account: &xdr.LedgerKeyAccount{AccountId: xdr.MustAddress(accountB)},
+			},
+		},
+	)
+	level0Snap := writeBucket(t, dir,
+		// Shadowed by the newer entry in level 0 curr.
+		liveEntry(50, accountData(accountA, 50)),
+		// Removed in level 0 curr.
+		liveEntry(50, accountData(accountB, 50)),
+		liveEntry(52, xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeOffer,
+			Offer: &xdr.OfferEntry{
+				SellerId: xdr.MustAddress(accountA),
+				OfferId:  7,
+				Selling:  xdr.MustNewNativeAsset(),
+				Buying:   usd,
+				Amount:   20,
+				Price:    xdr.Price{N: 1, D: 2},
+			},
+		}),
+	)
+	level1Curr := writeBucket(t, dir,
+		liveEntry(40, xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeData,
+			Data: &xdr.DataEntry{
+				AccountId: xdr.MustAddress(accountA),
+				DataName:  "name",
+				DataValue: []byte("value"),
+			},
+		}),
+	)
+
+	has := historyarchive.HistoryArchiveState{Version: 1, CurrentLedger: 63}
+	for i := range has.CurrentBuckets {
+		has.CurrentBuckets[i].Curr = historyarchive.Hash{}.String()
+		has.CurrentBuckets[i].Snap = historyarchive.Hash{}.String()
+	}
+	has.CurrentBuckets[0].Curr = level0Curr
+	has.CurrentBuckets[0].Snap = level0Snap
+	has.CurrentBuckets[1].Curr = level1Curr
+
+	archive, err := historyarchive.Connect("file://"+dir, historyarchive.ConnectOptions{})
+	require.NoError(t, err)
+	require.NoError(t, archive.PutCheckpointHAS(63, has, nil))
+	return archive
+}
+
+func readFile(t *testing.T, path string) string {
+	raw, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestExport(t *testing.T) {
+	archive := testArchive(t)
+	out := t.TempDir()
+
+	manifest, err := Export(context.Background(), Config{Archive: archive, Checkpoint: 63, OutputDir: out})
+	require.NoError(t, err)
+	assert.True(t, manifest.Complete)
+	require.Len(t, manifest.Buckets, 3)
+	assert.Equal(t, []int{0, 1, 2}, []int{
+		manifest.Buckets[0].Index, manifest.Buckets[1].Index, manifest.Buckets[2].Index,
+	})
+	assert.Equal(t, map[string]int64{"accounts": 1, "signers": 1, "trust_lines": 1}, manifest.Buckets[0].Rows)
+	assert.Equal(t, map[string]int64{"offers": 1}, manifest.Buckets[1].Rows)
+	assert.Equal(t, map[string]int64{"data": 1}, manifest.Buckets[2].Rows)
+
+	accounts := readFile(t, filepath.Join(out, manifest.Buckets[0].Files["accounts"]))
+	assert.Equal(t,
+		"account_id,balance,buying_liabilities,selling_liabilities,sequence,num_subentries,num_sponsored,"+
+			"num_sponsoring,inflation_destination,flags,home_domain,master_weight,threshold_low,"+
+			"threshold_medium,threshold_high,sponsor,last_modified_ledger\n"+
+			accountA+",100,0,0,1,0,0,0,,0,example.com,1,2,3,4,,60\n",
+		accounts,
+	)
+	trustLines := readFile(t, filepath.Join(out, manifest.Buckets[0].Files["trust_lines"]))
+	assert.Equal(t,
+		"account_id,asset_type,asset_code,asset_issuer,liquidity_pool_id,balance,limit,"+
+			"buying_liabilities,selling_liabilities,flags,sponsor,last_modified_ledger\n"+
+			accountA+",credit_alphanum4,USD,"+issuer+",,10,1000,0,0,1,,61\n",
+		trustLines,
+	)
+	offers := readFile(t, filepath.Join(out, manifest.Buckets[1].Files["offers"]))
+	assert.True(t, strings.HasSuffix(offers, accountA+",7,native,,,credit_alphanum4,USD,"+issuer+",20,1,2,0,,52\n"))
+
+	// The manifest on disk matches the returned one.
+	var written Manifest
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, filepath.Join(out, ManifestFileName))), &written))
+	assert.Equal(t, *manifest, written)
+
+	// Exports of the same checkpoint are identical.
+	other := t.TempDir()
+	_, err = Export(context.Background(), Config{Archive: archive, Checkpoint: 63, OutputDir: other})
+	require.NoError(t, err)
+	for _, bucket := range manifest.Buckets {
+		for _, file := range bucket.Files {
+			assert.Equal(t, readFile(t, filepath.Join(out, file)), readFile(t, filepath.Join(other, file)))
+		}
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	archive := testArchive(t)
+	out := t.TempDir()
+	config := Config{Archive: archive, Checkpoint: 63, OutputDir: out, Tables: []string{"accounts", "offers"}}
+
+	manifest, err := Export(context.Background(), config)
+	require.NoError(t, err)
+	offersFile := filepath.Join(out, manifest.Buckets[1].Files["offers"])
+	expectedOffers := readFile(t, offersFile)
+
+	// Simulate an export interrupted after the first bucket.
+	require.NoError(t, os.Remove(offersFile))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(out, "offers", ".tmp-123"), []byte("partial"), 0644))
+	accountsFile := filepath.Join(out, manifest.Buckets[0].Files["accounts"])
+	require.NoError(t, ioutil.WriteFile(accountsFile, []byte("not rewritten"), 0644))
+	partial := *manifest
+	partial.Buckets = partial.Buckets[:1]
+	partial.Complete = false
+	raw, err := json.Marshal(partial)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(out, ManifestFileName), raw, 0644))
+
+	resumed, err := Export(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, resumed)
+	assert.Equal(t, expectedOffers, readFile(t, offersFile))
+	assert.Equal(t, "not rewritten", readFile(t, accountsFile))
+	_, err = os.Stat(filepath.Join(out, "offers", ".tmp-123"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A different configuration cannot be resumed.
+	config.Tables = nil
+	_, err = Export(context.Background(), config)
+	assert.EqualError(t, err, "output directory contains an export of checkpoint 63 with a different configuration")
+}
@@ -0,0 +1,115 @@
+package statexport
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// Format creates writers of exported tables. CSVFormat and ParquetFormat are
+// provided, other output formats can be supported by implementing this
+// interface.
+type Format interface {
+	// Name identifies the format in the export manifest.
+	Name() string
+	// Extension is the file extension of the files written, including the
+	// leading dot.
+	Extension() string
+	// NewTableWriter returns a TableWriter writing rows with the given
+	// columns to w.
+	NewTableWriter(w io.Writer, columns []Column) (TableWriter, error)
+}
+
+// TableWriter writes rows of a table.
+type TableWriter interface {
+	// WriteRow writes a single row. Values match the types of the columns.
+	WriteRow(values []interface{}) error
+	// Close flushes the rows written. It does not close the underlying
+	// io.Writer.
+	Close() error
+}
+
+// CSVFormat writes tables as CSV files with a header row. Nil values are
+// written as empty fields and integers in decimal notation. Column types are
+// recorded in the export manifest.
+type CSVFormat struct{}
+
+// Name returns "csv".
+func (CSVFormat) Name() string {
+	return "csv"
+}
+
+// Extension returns ".csv".
+func (CSVFormat) Extension() string {
+	return ".csv"
+}
+
+// NewTableWriter returns a TableWriter writing CSV rows to w.
+func (CSVFormat) NewTableWriter(w io.Writer, columns []Column) (TableWriter, error) {
+	writer := &csvTableWriter{writer: csv.NewWriter(w), columns: columns}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := writer.writer.Write(header); err != nil {
+		return nil, errors.Wrap(err, "error writing csv header")
+	}
+	return writer, nil
+}
+
+type csvTableWriter struct {
+	writer  *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func (w *csvTableWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(w.columns) {
+		return errors.Errorf("expected %d values, got %d", len(w.columns), len(values))
+	}
+	w.record = w.record[:0]
+	for i, value := range values {
+		field, err := formatValue(w.columns[i], value)
+		if err != nil {
+			return err
+		}
+		w.record = append(w.record, field)
+	}
+	return w.writer.Write(w.record)
+}
+
+func (w *csvTableWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func formatValue(column Column, value interface{}) (string, error) {
+	if value == nil {
+		if !column.Nullable {
+			return "", errors.Errorf("column %s is not nullable", column.Name)
+		}
+		return "", nil
+	}
+
+	switch column.Type {
+	case ColumnTypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case ColumnTypeInt64:
+		if v, ok := value.(int64); ok {
+			return strconv.FormatInt(v, 10), nil
+		}
+	case ColumnTypeUint32:
+		if v, ok := value.(uint32); ok {
+			return strconv.FormatUint(uint64(v), 10), nil
+		}
+	case ColumnTypeBool:
+		if v, ok := value.(bool); ok {
+			return strconv.FormatBool(v), nil
+		}
+	}
+	return "", errors.Errorf("invalid value %v (%T) for %s column %s", value, value, column.Type, column.Name)
+}
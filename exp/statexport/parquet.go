@@ -0,0 +1,112 @@
+package statexport
+
+import (
+	"fmt"
+	"io"
+
+	parquetwriter "github.com/xitongsys/parquet-go/writer"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// ParquetFormat writes tables as snappy compressed Parquet files with one
+// column per table column. Strings are written as UTF8 byte arrays, int64
+// and bool values as INT64 and BOOLEAN and uint32 values as unsigned INT32.
+// Nullable columns are optional, other columns are required.
+type ParquetFormat struct{}
+
+// Name returns "parquet".
+func (ParquetFormat) Name() string {
+	return "parquet"
+}
+
+// Extension returns ".parquet".
+func (ParquetFormat) Extension() string {
+	return ".parquet"
+}
+
+// NewTableWriter returns a TableWriter writing a Parquet file to w.
+func (ParquetFormat) NewTableWriter(w io.Writer, columns []Column) (TableWriter, error) {
+	metadata := make([]string, len(columns))
+	for i, column := range columns {
+		var columnType string
+		switch column.Type {
+		case ColumnTypeString:
+			columnType = "type=BYTE_ARRAY, convertedtype=UTF8"
+		case ColumnTypeInt64:
+			columnType = "type=INT64"
+		case ColumnTypeUint32:
+			columnType = "type=INT32, convertedtype=UINT_32"
+		case ColumnTypeBool:
+			columnType = "type=BOOLEAN"
+		default:
+			return nil, errors.Errorf("unsupported type %s of column %s", column.Type, column.Name)
+		}
+		repetition := "REQUIRED"
+		if column.Nullable {
+			repetition = "OPTIONAL"
+		}
+		metadata[i] = fmt.Sprintf("name=%s, %s, repetitiontype=%s", column.Name, columnType, repetition)
+	}
+
+	writer, err := parquetwriter.NewCSVWriterFromWriter(metadata, w, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "error writing parquet header")
+	}
+	return &parquetTableWriter{writer: writer, columns: columns}, nil
+}
+
+type parquetTableWriter struct {
+	writer  *parquetwriter.CSVWriter
+	columns []Column
+}
+
+func (w *parquetTableWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(w.columns) {
+		return errors.Errorf("expected %d values, got %d", len(w.columns), len(values))
+	}
+	// The writer keeps the rows until they are flushed so every row needs
+	// its own slice.
+	row := make([]interface{}, len(values))
+	for i, value := range values {
+		field, err := parquetValue(w.columns[i], value)
+		if err != nil {
+			return err
+		}
+		row[i] = field
+	}
+	return w.writer.Write(row)
+}
+
+func (w *parquetTableWriter) Close() error {
+	return w.writer.WriteStop()
+}
+
+func parquetValue(column Column, value interface{}) (interface{}, error) {
+	if value == nil {
+		if !column.Nullable {
+			return nil, errors.Errorf("column %s is not nullable", column.Name)
+		}
+		return nil, nil
+	}
+
+	switch column.Type {
+	case ColumnTypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case ColumnTypeInt64:
+		if v, ok := value.(int64); ok {
+			return v, nil
+		}
+	case ColumnTypeUint32:
+		if v, ok := value.(uint32); ok {
+			return int32(v), nil
+		}
+	case ColumnTypeBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	}
+	return nil, errors.Errorf("invalid value %v (%T) for %s column %s", value, value, column.Type, column.Name)
+}
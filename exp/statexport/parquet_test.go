@@ -0,0 +1,68 @@
+package statexport
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	parquetreader "github.com/xitongsys/parquet-go/reader"
+)
+
+func TestExportParquet(t *testing.T) {
+	archive := testArchive(t)
+	out := t.TempDir()
+
+	manifest, err := Export(context.Background(), Config{
+		Archive:    archive,
+		Checkpoint: 63,
+		OutputDir:  out,
+		Tables:     []string{"accounts"},
+		Format:     ParquetFormat{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "parquet", manifest.Format)
+	require.Len(t, manifest.Buckets, 3)
+	path := manifest.Buckets[0].Files["accounts"]
+	assert.Equal(t, ".parquet", filepath.Ext(path))
+
+	file, err := local.NewLocalFileReader(filepath.Join(out, path))
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := parquetreader.NewParquetColumnReader(file, 1)
+	require.NoError(t, err)
+	defer reader.ReadStop()
+	require.Equal(t, int64(1), reader.GetNumRows())
+
+	columns := map[string]interface{}{}
+	for i, column := range Tables()[0].Columns {
+		var values []interface{}
+		values, _, _, err = reader.ReadColumnByIndex(int64(i), 1)
+		require.NoError(t, err)
+		require.Len(t, values, 1)
+		columns[column.Name] = values[0]
+	}
+	assert.Equal(t, accountA, columns["account_id"])
+	assert.Equal(t, int64(100), columns["balance"])
+	assert.Equal(t, int32(60), columns["last_modified_ledger"])
+	assert.Equal(t, "example.com", columns["home_domain"])
+	assert.Nil(t, columns["inflation_destination"])
+}
+
+func TestParquetWriteRowErrors(t *testing.T) {
+	columns := []Column{
+		{Name: "name", Type: ColumnTypeString},
+		{Name: "count", Type: ColumnTypeUint32, Nullable: true},
+	}
+	writer, err := ParquetFormat{}.NewTableWriter(&bytes.Buffer{}, columns)
+	require.NoError(t, err)
+
+	assert.EqualError(t, writer.WriteRow([]interface{}{"a"}), "expected 2 values, got 1")
+	assert.EqualError(t, writer.WriteRow([]interface{}{nil, nil}), "column name is not nullable")
+	assert.EqualError(t, writer.WriteRow([]interface{}{"a", 1}), "invalid value 1 (int) for uint32 column count")
+	require.NoError(t, writer.WriteRow([]interface{}{"a", nil}))
+	require.NoError(t, writer.Close())
+}
@@ -0,0 +1,390 @@
+package statexport
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// ColumnType is the type of values in a column.
+type ColumnType string
+
+const (
+	// ColumnTypeString columns contain Go string values.
+	ColumnTypeString ColumnType = "string"
+	// ColumnTypeInt64 columns contain Go int64 values.
+	ColumnTypeInt64 ColumnType = "int64"
+	// ColumnTypeUint32 columns contain Go uint32 values.
+	ColumnTypeUint32 ColumnType = "uint32"
+	// ColumnTypeBool columns contain Go bool values.
+	ColumnTypeBool ColumnType = "bool"
+)
+
+// Column describes a single column of a table.
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+	// Nullable columns can contain nil values.
+	Nullable bool `json:"nullable,omitempty"`
+}
+
+// Table describes the rows exported for a ledger entry type. A single ledger
+// entry can produce more than one row (ex. an account produces one row in
+// "accounts" and one row per signer in "signers").
+type Table struct {
+	Name      string
+	EntryType xdr.LedgerEntryType
+	Columns   []Column
+	rows      func(entry *xdr.LedgerEntry) ([][]interface{}, error)
+}
+
+// Rows returns the rows of the table for the given ledger entry. Values
+// match the types of the table columns. entry must be of the table's
+// EntryType.
+func (t Table) Rows(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+	if entry.Data.Type != t.EntryType {
+		return nil, errors.Errorf("cannot export %s entry to table %s", entry.Data.Type, t.Name)
+	}
+	return t.rows(entry)
+}
+
+var assetColumns = []Column{
+	{Name: "asset_type", Type: ColumnTypeString},
+	{Name: "asset_code", Type: ColumnTypeString, Nullable: true},
+	{Name: "asset_issuer", Type: ColumnTypeString, Nullable: true},
+}
+
+func prefixedAssetColumns(prefix string) []Column {
+	columns := make([]Column, len(assetColumns))
+	for i, column := range assetColumns {
+		column.Name = prefix + column.Name
+		columns[i] = column
+	}
+	return columns
+}
+
+func columns(groups ...[]Column) []Column {
+	var result []Column
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	return result
+}
+
+var entryColumns = []Column{
+	{Name: "sponsor", Type: ColumnTypeString, Nullable: true},
+	{Name: "last_modified_ledger", Type: ColumnTypeUint32},
+}
+
+func entryValues(entry *xdr.LedgerEntry) []interface{} {
+	var sponsor interface{}
+	if sponsoringID := entry.SponsoringID(); sponsoringID != nil {
+		sponsor = (*sponsoringID).Address()
+	}
+	return []interface{}{sponsor, uint32(entry.LastModifiedLedgerSeq)}
+}
+
+func assetValues(asset xdr.Asset) ([]interface{}, error) {
+	var assetType, code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return nil, errors.Wrap(err, "error extracting asset")
+	}
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return []interface{}{assetType, nil, nil}, nil
+	}
+	return []interface{}{assetType, code, issuer}, nil
+}
+
+func optionalAddress(account *xdr.AccountId) interface{} {
+	if account == nil {
+		return nil
+	}
+	return account.Address()
+}
+
+// Tables returns all tables in the order they are exported.
+func Tables() []Table {
+	return []Table{
+		accountsTable,
+		signersTable,
+		trustLinesTable,
+		offersTable,
+		dataTable,
+		claimableBalancesTable,
+		claimantsTable,
+		liquidityPoolsTable,
+	}
+}
+
+var accountsTable = Table{
+	Name:      "accounts",
+	EntryType: xdr.LedgerEntryTypeAccount,
+	Columns: columns([]Column{
+		{Name: "account_id", Type: ColumnTypeString},
+		{Name: "balance", Type: ColumnTypeInt64},
+		{Name: "buying_liabilities", Type: ColumnTypeInt64},
+		{Name: "selling_liabilities", Type: ColumnTypeInt64},
+		{Name: "sequence", Type: ColumnTypeInt64},
+		{Name: "num_subentries", Type: ColumnTypeUint32},
+		{Name: "num_sponsored", Type: ColumnTypeUint32},
+		{Name: "num_sponsoring", Type: ColumnTypeUint32},
+		{Name: "inflation_destination", Type: ColumnTypeString, Nullable: true},
+		{Name: "flags", Type: ColumnTypeUint32},
+		{Name: "home_domain", Type: ColumnTypeString},
+		{Name: "master_weight", Type: ColumnTypeUint32},
+		{Name: "threshold_low", Type: ColumnTypeUint32},
+		{Name: "threshold_medium", Type: ColumnTypeUint32},
+		{Name: "threshold_high", Type: ColumnTypeUint32},
+	}, entryColumns),
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		account := entry.Data.MustAccount()
+		liabilities := account.Liabilities()
+		row := []interface{}{
+			account.AccountId.Address(),
+			int64(account.Balance),
+			int64(liabilities.Buying),
+			int64(liabilities.Selling),
+			int64(account.SeqNum),
+			uint32(account.NumSubEntries),
+			uint32(account.NumSponsored()),
+			uint32(account.NumSponsoring()),
+			optionalAddress(account.InflationDest),
+			uint32(account.Flags),
+			string(account.HomeDomain),
+			uint32(account.MasterKeyWeight()),
+			uint32(account.ThresholdLow()),
+			uint32(account.ThresholdMedium()),
+			uint32(account.ThresholdHigh()),
+		}
+		return [][]interface{}{append(row, entryValues(entry)...)}, nil
+	},
+}
+
+var signersTable = Table{
+	Name:      "signers",
+	EntryType: xdr.LedgerEntryTypeAccount,
+	Columns: []Column{
+		{Name: "account_id", Type: ColumnTypeString},
+		{Name: "signer", Type: ColumnTypeString},
+		{Name: "weight", Type: ColumnTypeUint32},
+		{Name: "sponsor", Type: ColumnTypeString, Nullable: true},
+		{Name: "last_modified_ledger", Type: ColumnTypeUint32},
+	},
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		account := entry.Data.MustAccount()
+		sponsors := account.SignerSponsoringIDs()
+		rows := make([][]interface{}, 0, len(account.Signers))
+		for i, signer := range account.Signers {
+			var sponsor interface{}
+			if i < len(sponsors) && sponsors[i] != nil {
+				sponsor = (*sponsors[i]).Address()
+			}
+			rows = append(rows, []interface{}{
+				account.AccountId.Address(),
+				signer.Key.Address(),
+				uint32(signer.Weight),
+				sponsor,
+				uint32(entry.LastModifiedLedgerSeq),
+			})
+		}
+		return rows, nil
+	},
+}
+
+var trustLinesTable = Table{
+	Name:      "trust_lines",
+	EntryType: xdr.LedgerEntryTypeTrustline,
+	Columns: columns([]Column{
+		{Name: "account_id", Type: ColumnTypeString},
+	}, assetColumns, []Column{
+		{Name: "liquidity_pool_id", Type: ColumnTypeString, Nullable: true},
+		{Name: "balance", Type: ColumnTypeInt64},
+		{Name: "limit", Type: ColumnTypeInt64},
+		{Name: "buying_liabilities", Type: ColumnTypeInt64},
+		{Name: "selling_liabilities", Type: ColumnTypeInt64},
+		{Name: "flags", Type: ColumnTypeUint32},
+	}, entryColumns),
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		trustLine := entry.Data.MustTrustLine()
+		row := []interface{}{trustLine.AccountId.Address()}
+		if trustLine.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			row = append(row,
+				"liquidity_pool_shares", nil, nil,
+				xdr.Hash(*trustLine.Asset.LiquidityPoolId).HexString(),
+			)
+		} else {
+			asset, err := assetValues(trustLine.Asset.ToAsset())
+			if err != nil {
+				return nil, err
+			}
+			row = append(append(row, asset...), nil)
+		}
+		liabilities := trustLine.Liabilities()
+		row = append(row,
+			int64(trustLine.Balance),
+			int64(trustLine.Limit),
+			int64(liabilities.Buying),
+			int64(liabilities.Selling),
+			uint32(trustLine.Flags),
+		)
+		return [][]interface{}{append(row, entryValues(entry)...)}, nil
+	},
+}
+
+var offersTable = Table{
+	Name:      "offers",
+	EntryType: xdr.LedgerEntryTypeOffer,
+	Columns: columns([]Column{
+		{Name: "seller_id", Type: ColumnTypeString},
+		{Name: "offer_id", Type: ColumnTypeInt64},
+	}, prefixedAssetColumns("selling_"), prefixedAssetColumns("buying_"), []Column{
+		{Name: "amount", Type: ColumnTypeInt64},
+		{Name: "price_n", Type: ColumnTypeInt64},
+		{Name: "price_d", Type: ColumnTypeInt64},
+		{Name: "flags", Type: ColumnTypeUint32},
+	}, entryColumns),
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		offer := entry.Data.MustOffer()
+		selling, err := assetValues(offer.Selling)
+		if err != nil {
+			return nil, err
+		}
+		buying, err := assetValues(offer.Buying)
+		if err != nil {
+			return nil, err
+		}
+		row := []interface{}{offer.SellerId.Address(), int64(offer.OfferId)}
+		row = append(row, selling...)
+		row = append(row, buying...)
+		row = append(row,
+			int64(offer.Amount),
+			int64(offer.Price.N),
+			int64(offer.Price.D),
+			uint32(offer.Flags),
+		)
+		return [][]interface{}{append(row, entryValues(entry)...)}, nil
+	},
+}
+
+var dataTable = Table{
+	Name:      "data",
+	EntryType: xdr.LedgerEntryTypeData,
+	Columns: columns([]Column{
+		{Name: "account_id", Type: ColumnTypeString},
+		{Name: "name", Type: ColumnTypeString},
+		// Data values are arbitrary bytes so they are base64 encoded.
+		{Name: "value", Type: ColumnTypeString},
+	}, entryColumns),
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		data := entry.Data.MustData()
+		row := []interface{}{
+			data.AccountId.Address(),
+			string(data.DataName),
+			base64.StdEncoding.EncodeToString(data.DataValue),
+		}
+		return [][]interface{}{append(row, entryValues(entry)...)}, nil
+	},
+}
+
+var claimableBalancesTable = Table{
+	Name:      "claimable_balances",
+	EntryType: xdr.LedgerEntryTypeClaimableBalance,
+	Columns: columns([]Column{
+		{Name: "balance_id", Type: ColumnTypeString},
+	}, assetColumns, []Column{
+		{Name: "amount", Type: ColumnTypeInt64},
+		{Name: "flags", Type: ColumnTypeUint32},
+	}, entryColumns),
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		balance := entry.Data.MustClaimableBalance()
+		id, err := xdr.MarshalHex(balance.BalanceId)
+		if err != nil {
+			return nil, errors.Wrap(err, "error encoding claimable balance id")
+		}
+		asset, err := assetValues(balance.Asset)
+		if err != nil {
+			return nil, err
+		}
+		row := append([]interface{}{id}, asset...)
+		row = append(row, int64(balance.Amount), uint32(balance.Flags()))
+		return [][]interface{}{append(row, entryValues(entry)...)}, nil
+	},
+}
+
+var claimantsTable = Table{
+	Name:      "claimants",
+	EntryType: xdr.LedgerEntryTypeClaimableBalance,
+	Columns: []Column{
+		{Name: "balance_id", Type: ColumnTypeString},
+		{Name: "destination", Type: ColumnTypeString},
+		// Predicates are encoded as JSON, in the format used by Horizon.
+		{Name: "predicate", Type: ColumnTypeString},
+		{Name: "last_modified_ledger", Type: ColumnTypeUint32},
+	},
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		balance := entry.Data.MustClaimableBalance()
+		id, err := xdr.MarshalHex(balance.BalanceId)
+		if err != nil {
+			return nil, errors.Wrap(err, "error encoding claimable balance id")
+		}
+		rows := make([][]interface{}, 0, len(balance.Claimants))
+		for _, claimant := range balance.Claimants {
+			v0 := claimant.MustV0()
+			predicate, err := json.Marshal(v0.Predicate)
+			if err != nil {
+				return nil, errors.Wrap(err, "error encoding claimant predicate")
+			}
+			rows = append(rows, []interface{}{
+				id,
+				v0.Destination.Address(),
+				string(predicate),
+				uint32(entry.LastModifiedLedgerSeq),
+			})
+		}
+		return rows, nil
+	},
+}
+
+var liquidityPoolsTable = Table{
+	Name:      "liquidity_pools",
+	EntryType: xdr.LedgerEntryTypeLiquidityPool,
+	Columns: columns([]Column{
+		{Name: "liquidity_pool_id", Type: ColumnTypeString},
+		{Name: "type", Type: ColumnTypeString},
+		{Name: "fee", Type: ColumnTypeUint32},
+	}, prefixedAssetColumns("a_"), []Column{
+		{Name: "a_reserve", Type: ColumnTypeInt64},
+	}, prefixedAssetColumns("b_"), []Column{
+		{Name: "b_reserve", Type: ColumnTypeInt64},
+		{Name: "total_pool_shares", Type: ColumnTypeInt64},
+		{Name: "pool_shares_trust_line_count", Type: ColumnTypeInt64},
+		{Name: "last_modified_ledger", Type: ColumnTypeUint32},
+	}),
+	rows: func(entry *xdr.LedgerEntry) ([][]interface{}, error) {
+		pool := entry.Data.MustLiquidityPool()
+		cp := pool.Body.MustConstantProduct()
+		assetA, err := assetValues(cp.Params.AssetA)
+		if err != nil {
+			return nil, err
+		}
+		assetB, err := assetValues(cp.Params.AssetB)
+		if err != nil {
+			return nil, err
+		}
+		row := []interface{}{
+			xdr.Hash(pool.LiquidityPoolId).HexString(),
+			"constant_product",
+			uint32(cp.Params.Fee),
+		}
+		row = append(append(row, assetA...), int64(cp.ReserveA))
+		row = append(append(row, assetB...), int64(cp.ReserveB))
+		row = append(row,
+			int64(cp.TotalPoolShares),
+			int64(cp.PoolSharesTrustLineCount),
+			uint32(entry.LastModifiedLedgerSeq),
+		)
+		return [][]interface{}{row}, nil
+	},
+}
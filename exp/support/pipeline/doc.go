@@ -1,2 +1,7 @@
 // TODO explain here how to write wrappers to use without casting from `interface{}`.
+//
+// Deprecated: this package predates the current ingest readers and is no
+// longer maintained. Use ingest.ProcessorRunner in
+// github.com/stellar/go/ingest to run processors over ChangeReader and
+// LedgerTransactionReader output.
 package pipeline
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/stellar/go/exp/statexport"
+	"github.com/stellar/go/historyarchive"
+	"github.com/stellar/go/support/log"
+)
+
+// This program exports the ledger state at a history archive checkpoint to
+// CSV or Parquet files with one table per ledger entry type. See the statexport package
+// for details.
+func main() {
+	archiveURL := flag.String(
+		"archive-url",
+		"https://history.stellar.org/prd/core-live/core_live_001/",
+		"history archive to read buckets from",
+	)
+	checkpointLedger := flag.Int(
+		"checkpoint-ledger",
+		0,
+		"checkpoint ledger sequence to export, if omitted will use latest checkpoint ledger.",
+	)
+	output := flag.String("output", "state", "output directory, an unfinished export in it is resumed")
+	tables := flag.String("tables", "", "comma separated list of tables to export, exports all tables if omitted")
+	format := flag.String("format", "csv", "format of the exported files: csv or parquet")
+	flag.Parse()
+	log.SetLevel(log.InfoLevel)
+
+	var exportFormat statexport.Format
+	switch *format {
+	case "csv":
+		exportFormat = statexport.CSVFormat{}
+	case "parquet":
+		exportFormat = statexport.ParquetFormat{}
+	default:
+		log.WithField("format", *format).Fatal("unknown format")
+	}
+
+	archive, err := historyarchive.Connect(*archiveURL, historyarchive.ConnectOptions{})
+	if err != nil {
+		log.WithField("err", err).Fatal("could not connect to history archive")
+	}
+
+	sequence := uint32(*checkpointLedger)
+	if sequence == 0 {
+		var root historyarchive.HistoryArchiveState
+		root, err = archive.GetRootHAS()
+		if err != nil {
+			log.WithField("err", err).Fatal("could not fetch root has")
+		}
+		sequence = root.CurrentLedger
+	}
+
+	var tableNames []string
+	if *tables != "" {
+		tableNames = strings.Split(*tables, ",")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		log.Info("Interrupted, the export can be resumed by running the same command again")
+		cancel()
+	}()
+
+	log.WithField("ledger", sequence).Info("Exporting entries from History Archive Snapshot")
+	manifest, err := statexport.Export(ctx, statexport.Config{
+		Archive:    archive,
+		Checkpoint: sequence,
+		OutputDir:  *output,
+		Tables:     tableNames,
+		Format:     exportFormat,
+	})
+	if err != nil {
+		log.WithField("err", err).Fatal("could not export ledger state")
+	}
+
+	rows := map[string]int64{}
+	for _, bucket := range manifest.Buckets {
+		for table, count := range bucket.Rows {
+			rows[table] += count
+		}
+	}
+	fields := log.F{}
+	for table, count := range rows {
+		fields[table] = count
+	}
+	log.WithFields(fields).Info("Export finished")
+}
@@ -0,0 +1,292 @@
+package txsimulator
+
+import (
+	"math"
+
+	"github.com/stellar/go/xdr"
+)
+
+func accountKey(id xdr.AccountId) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:    xdr.LedgerEntryTypeAccount,
+		Account: &xdr.LedgerKeyAccount{AccountId: id},
+	}
+}
+
+func trustlineKey(id xdr.AccountId, asset xdr.TrustLineAsset) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:      xdr.LedgerEntryTypeTrustline,
+		TrustLine: &xdr.LedgerKeyTrustLine{AccountId: id, Asset: asset},
+	}
+}
+
+func dataKey(id xdr.AccountId, name xdr.String64) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeData,
+		Data: &xdr.LedgerKeyData{AccountId: id, DataName: name},
+	}
+}
+
+func offerKey(seller xdr.AccountId, id xdr.Int64) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:  xdr.LedgerEntryTypeOffer,
+		Offer: &xdr.LedgerKeyOffer{SellerId: seller, OfferId: id},
+	}
+}
+
+func claimableBalanceKey(id xdr.ClaimableBalanceId) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:             xdr.LedgerEntryTypeClaimableBalance,
+		ClaimableBalance: &xdr.LedgerKeyClaimableBalance{BalanceId: id},
+	}
+}
+
+func liquidityPoolKey(id xdr.PoolId) xdr.LedgerKey {
+	return xdr.LedgerKey{
+		Type:          xdr.LedgerEntryTypeLiquidityPool,
+		LiquidityPool: &xdr.LedgerKeyLiquidityPool{LiquidityPoolId: id},
+	}
+}
+
+func poolShareAsset(id xdr.PoolId) xdr.TrustLineAsset {
+	return xdr.TrustLineAsset{
+		Type:            xdr.AssetTypeAssetTypePoolShare,
+		LiquidityPoolId: &id,
+	}
+}
+
+// accountExtV1 returns the extension of the account holding its
+// liabilities, adding it if needed.
+func accountExtV1(account *xdr.AccountEntry) *xdr.AccountEntryExtensionV1 {
+	if account.Ext.V1 == nil {
+		account.Ext = xdr.AccountEntryExt{V: 1, V1: &xdr.AccountEntryExtensionV1{}}
+	}
+	return account.Ext.V1
+}
+
+// accountExtV2 returns the extension of the account holding the sponsorship
+// counters, adding it if needed.
+func accountExtV2(account *xdr.AccountEntry) *xdr.AccountEntryExtensionV2 {
+	accountExtV1(account)
+	if account.Ext.V1.Ext.V2 == nil {
+		account.Ext.V1.Ext = xdr.AccountEntryExtensionV1Ext{
+			V: 2,
+			V2: &xdr.AccountEntryExtensionV2{
+				SignerSponsoringIDs: make([]xdr.SponsorshipDescriptor, len(account.Signers)),
+			},
+		}
+	}
+	return account.Ext.V1.Ext.V2
+}
+
+// trustlineExtV1 returns the extension of the trustline holding its
+// liabilities, adding it if needed.
+func trustlineExtV1(trustline *xdr.TrustLineEntry) *xdr.TrustLineEntryV1 {
+	if trustline.Ext.V1 == nil {
+		trustline.Ext = xdr.TrustLineEntryExt{V: 1, V1: &xdr.TrustLineEntryV1{}}
+	}
+	return trustline.Ext.V1
+}
+
+// trustlineExtV2 returns the extension of the trustline holding its
+// liquidity pool use count, adding it if needed.
+func trustlineExtV2(trustline *xdr.TrustLineEntry) *xdr.TrustLineEntryExtensionV2 {
+	trustlineExtV1(trustline)
+	if trustline.Ext.V1.Ext.V2 == nil {
+		trustline.Ext.V1.Ext = xdr.TrustLineEntryV1Ext{V: 2, V2: &xdr.TrustLineEntryExtensionV2{}}
+	}
+	return trustline.Ext.V1.Ext.V2
+}
+
+// setSponsor sets the sponsor of the entry, nil removes its sponsor.
+func setSponsor(entry *xdr.LedgerEntry, sponsor *xdr.AccountId) {
+	if entry.Ext.V1 == nil {
+		entry.Ext = xdr.LedgerEntryExt{V: 1, V1: &xdr.LedgerEntryExtensionV1{}}
+	}
+	entry.Ext.V1.SponsoringId = sponsor
+}
+
+// minBalance returns the native balance the account has to maintain.
+func (a *applier) minBalance(account *xdr.AccountEntry) int64 {
+	reserves := 2 + int64(account.NumSubEntries) + int64(account.NumSponsoring()) - int64(account.NumSponsored())
+	return reserves * a.config.BaseReserve
+}
+
+// availableNative returns the native balance the account can spend.
+func (a *applier) availableNative(account *xdr.AccountEntry) int64 {
+	return int64(account.Balance) - a.minBalance(account) - int64(account.Liabilities().Selling)
+}
+
+// addNative adds delta to the native balance of the account. It returns
+// false when the account would not maintain its minimum balance and selling
+// liabilities, or exceed its buying liabilities.
+func (a *applier) addNative(account *xdr.AccountEntry, delta int64) bool {
+	if delta < 0 && a.availableNative(account) < -delta {
+		return false
+	}
+	if delta > 0 && int64(account.Balance) > math.MaxInt64-int64(account.Liabilities().Buying)-delta {
+		return false
+	}
+	account.Balance += xdr.Int64(delta)
+	return true
+}
+
+func authorized(trustline *xdr.TrustLineEntry) bool {
+	return xdr.TrustLineFlags(trustline.Flags)&xdr.TrustLineFlagsAuthorizedFlag != 0
+}
+
+func authorizedToMaintainLiabilities(trustline *xdr.TrustLineEntry) bool {
+	return xdr.TrustLineFlags(trustline.Flags)&(xdr.TrustLineFlagsAuthorizedFlag|xdr.TrustLineFlagsAuthorizedToMaintainLiabilitiesFlag) != 0
+}
+
+// availableTrustline returns the balance of the trustline which can be
+// spent.
+func availableTrustline(trustline *xdr.TrustLineEntry) int64 {
+	return int64(trustline.Balance) - int64(trustline.Liabilities().Selling)
+}
+
+// addTrustline adds delta to the balance of the trustline. It returns false
+// when the trustline is not authorized to maintain liabilities, or the
+// balance would not cover the liabilities of the trustline or exceed its
+// limit.
+func addTrustline(trustline *xdr.TrustLineEntry, delta int64) bool {
+	if delta == 0 {
+		return true
+	}
+	if !authorizedToMaintainLiabilities(trustline) {
+		return false
+	}
+	if delta < 0 && availableTrustline(trustline) < -delta {
+		return false
+	}
+	if delta > 0 && int64(trustline.Balance) > int64(trustline.Limit)-int64(trustline.Liabilities().Buying)-delta {
+		return false
+	}
+	trustline.Balance += xdr.Int64(delta)
+	return true
+}
+
+// issuer returns the issuer of a credit asset.
+func issuer(asset xdr.Asset) (xdr.AccountId, bool) {
+	switch asset.Type {
+	case xdr.AssetTypeAssetTypeCreditAlphanum4:
+		return asset.AlphaNum4.Issuer, true
+	case xdr.AssetTypeAssetTypeCreditAlphanum12:
+		return asset.AlphaNum12.Issuer, true
+	}
+	return xdr.AccountId{}, false
+}
+
+// isIssuer returns whether the account is the issuer of the asset.
+func isIssuer(id xdr.AccountId, asset xdr.Asset) bool {
+	assetIssuer, ok := issuer(asset)
+	return ok && assetIssuer.Equals(id)
+}
+
+// balanceResult is the result of a change of the balance of an asset.
+type balanceResult int
+
+const (
+	balanceSuccess balanceResult = iota
+	balanceNoTrust
+	balanceNotAuthorized
+	balanceFailed
+)
+
+// addBalance adds delta to the balance of the asset held by the account.
+// Issuers can send and receive any amount of their assets. Balances can
+// only change when the trustline is fully authorized.
+func (a *applier) addBalance(sb *sandbox, id xdr.AccountId, asset xdr.Asset, delta int64) balanceResult {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		entry := sb.load(accountKey(id))
+		if entry == nil {
+			return balanceNoTrust
+		}
+		if !a.addNative(entry.Data.Account, delta) {
+			return balanceFailed
+		}
+		sb.store(entry)
+		return balanceSuccess
+	}
+	if isIssuer(id, asset) {
+		return balanceSuccess
+	}
+
+	entry := sb.load(trustlineKey(id, asset.ToTrustLineAsset()))
+	switch {
+	case entry == nil:
+		return balanceNoTrust
+	case !authorized(entry.Data.TrustLine):
+		return balanceNotAuthorized
+	case !addTrustline(entry.Data.TrustLine, delta):
+		return balanceFailed
+	}
+	sb.store(entry)
+	return balanceSuccess
+}
+
+// updateAccount loads the account, updates it with fn and stores it.
+func updateAccount(sb *sandbox, id xdr.AccountId, fn func(account *xdr.AccountEntry)) {
+	entry := sb.load(accountKey(id))
+	fn(entry.Data.Account)
+	sb.store(entry)
+}
+
+// multiplier returns the number of base reserves of the entry.
+func multiplier(entry *xdr.LedgerEntry) int64 {
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeAccount:
+		return 2
+	case xdr.LedgerEntryTypeTrustline:
+		if entry.Data.TrustLine.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			return 2
+		}
+	case xdr.LedgerEntryTypeClaimableBalance:
+		return int64(len(entry.Data.ClaimableBalance.Claimants))
+	}
+	return 1
+}
+
+// createSubentry accounts for the reserves of a new subentry of the owner,
+// paid by the account sponsoring the future reserves of the owner if any.
+// It returns the sponsor of the subentry, or false when the account paying
+// the reserves does not have enough available balance.
+func (a *applier) createSubentry(sb *sandbox, owner xdr.AccountId, mult int64) (*xdr.AccountId, bool) {
+	sponsor, sponsored := a.sponsoring[owner.Address()]
+	payer := owner
+	if sponsored {
+		payer = sponsor
+	}
+	if payerEntry := sb.load(accountKey(payer)); a.availableNative(payerEntry.Data.Account) < mult*a.config.BaseReserve {
+		return nil, false
+	}
+
+	updateAccount(sb, owner, func(account *xdr.AccountEntry) {
+		account.NumSubEntries += xdr.Uint32(mult)
+		if sponsored {
+			accountExtV2(account).NumSponsored += xdr.Uint32(mult)
+		}
+	})
+	if !sponsored {
+		return nil, true
+	}
+	updateAccount(sb, sponsor, func(account *xdr.AccountEntry) {
+		accountExtV2(account).NumSponsoring += xdr.Uint32(mult)
+	})
+	return &sponsor, true
+}
+
+// removeSubentry releases the reserves of a removed subentry of the owner.
+func removeSubentry(sb *sandbox, owner xdr.AccountId, sponsor *xdr.AccountId, mult int64) {
+	updateAccount(sb, owner, func(account *xdr.AccountEntry) {
+		account.NumSubEntries -= xdr.Uint32(mult)
+		if sponsor != nil {
+			accountExtV2(account).NumSponsored -= xdr.Uint32(mult)
+		}
+	})
+	if sponsor != nil {
+		updateAccount(sb, *sponsor, func(account *xdr.AccountEntry) {
+			accountExtV2(account).NumSponsoring -= xdr.Uint32(mult)
+		})
+	}
+}
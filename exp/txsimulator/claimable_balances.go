@@ -0,0 +1,242 @@
+package txsimulator
+
+import (
+	"crypto/sha256"
+
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	// maxClaimants is the maximum number of claimants of a claimable balance.
+	maxClaimants = 10
+	// maxPredicateDepth is the maximum depth of a claim predicate.
+	maxPredicateDepth = 4
+)
+
+func (a *applier) createClaimableBalance(sb *sandbox, source xdr.AccountId, index int, body xdr.OperationBody) interface{} {
+	op := body.MustCreateClaimableBalanceOp()
+	if op.Amount <= 0 || len(op.Claimants) == 0 || len(op.Claimants) > maxClaimants {
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceMalformed
+	}
+	destinations := map[string]bool{}
+	for _, claimant := range op.Claimants {
+		destination := claimant.MustV0().Destination.Address()
+		if destinations[destination] || !validPredicate(claimant.MustV0().Predicate, 1) {
+			return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceMalformed
+		}
+		destinations[destination] = true
+	}
+
+	switch a.addBalance(sb, source, op.Asset, -int64(op.Amount)) {
+	case balanceNoTrust:
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceNoTrust
+	case balanceNotAuthorized:
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceNotAuthorized
+	case balanceFailed:
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceUnderfunded
+	}
+
+	// Claimable balances are not subentries, their reserves are always
+	// sponsored, by the source account if nobody sponsors its reserves.
+	sponsor, ok := a.sponsoring[source.Address()]
+	if !ok {
+		sponsor = source
+	}
+	mult := int64(len(op.Claimants))
+	sponsorEntry := sb.load(accountKey(sponsor))
+	if a.availableNative(sponsorEntry.Data.Account) < mult*a.config.BaseReserve {
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceLowReserve
+	}
+	accountExtV2(sponsorEntry.Data.Account).NumSponsoring += xdr.Uint32(mult)
+	sb.store(sponsorEntry)
+
+	id, err := a.claimableBalanceID(source, index)
+	if err != nil {
+		panic(err)
+	}
+	claimants := make([]xdr.Claimant, len(op.Claimants))
+	for i, claimant := range op.Claimants {
+		claimants[i] = xdr.Claimant{
+			Type: xdr.ClaimantTypeClaimantTypeV0,
+			V0: &xdr.ClaimantV0{
+				Destination: claimant.MustV0().Destination,
+				Predicate:   a.absolutePredicate(claimant.MustV0().Predicate),
+			},
+		}
+	}
+	balance := &xdr.ClaimableBalanceEntry{
+		BalanceId: id,
+		Claimants: claimants,
+		Asset:     op.Asset,
+		Amount:    op.Amount,
+	}
+	if a.clawbackEnabled(sb, source, op.Asset) {
+		balance.Ext = xdr.ClaimableBalanceEntryExt{
+			V: 1,
+			V1: &xdr.ClaimableBalanceEntryExtensionV1{
+				Flags: xdr.Uint32(xdr.ClaimableBalanceFlagsClaimableBalanceClawbackEnabledFlag),
+			},
+		}
+	}
+	entry := &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeClaimableBalance, ClaimableBalance: balance},
+	}
+	setSponsor(entry, &sponsor)
+	sb.store(entry)
+
+	return xdr.CreateClaimableBalanceResult{
+		Code:      xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceSuccess,
+		BalanceId: &id,
+	}
+}
+
+// claimableBalanceID returns the id of the claimable balance created by the
+// operation at the given index of the transaction.
+func (a *applier) claimableBalanceID(source xdr.AccountId, index int) (xdr.ClaimableBalanceId, error) {
+	opID := xdr.OperationId{
+		Type: xdr.EnvelopeTypeEnvelopeTypeOpId,
+		Id: &xdr.OperationIdId{
+			SourceAccount: a.envelope.SourceAccount().ToAccountId(),
+			SeqNum:        xdr.SequenceNumber(a.envelope.SeqNum()),
+			OpNum:         xdr.Uint32(index),
+		},
+	}
+	binary, err := opID.MarshalBinary()
+	if err != nil {
+		return xdr.ClaimableBalanceId{}, err
+	}
+	return xdr.NewClaimableBalanceId(xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0, xdr.Hash(sha256.Sum256(binary)))
+}
+
+// clawbackEnabled returns whether the balances of the asset sent by the
+// account can be clawed back.
+func (a *applier) clawbackEnabled(sb *sandbox, id xdr.AccountId, asset xdr.Asset) bool {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return false
+	}
+	if isIssuer(id, asset) {
+		entry := sb.load(accountKey(id))
+		return xdr.AccountFlags(entry.Data.Account.Flags)&xdr.AccountFlagsAuthClawbackEnabledFlag != 0
+	}
+	entry := sb.load(trustlineKey(id, asset.ToTrustLineAsset()))
+	return xdr.TrustLineFlags(entry.Data.TrustLine.Flags)&xdr.TrustLineFlagsTrustlineClawbackEnabledFlag != 0
+}
+
+// validPredicate returns whether the predicate is well formed.
+func validPredicate(predicate xdr.ClaimPredicate, depth int) bool {
+	if depth > maxPredicateDepth {
+		return false
+	}
+	switch predicate.Type {
+	case xdr.ClaimPredicateTypeClaimPredicateUnconditional:
+		return true
+	case xdr.ClaimPredicateTypeClaimPredicateAnd, xdr.ClaimPredicateTypeClaimPredicateOr:
+		predicates := predicate.AndPredicates
+		if predicate.Type == xdr.ClaimPredicateTypeClaimPredicateOr {
+			predicates = predicate.OrPredicates
+		}
+		return predicates != nil && len(*predicates) == 2 &&
+			validPredicate((*predicates)[0], depth+1) &&
+			validPredicate((*predicates)[1], depth+1)
+	case xdr.ClaimPredicateTypeClaimPredicateNot:
+		return predicate.NotPredicate != nil && *predicate.NotPredicate != nil &&
+			validPredicate(**predicate.NotPredicate, depth+1)
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime:
+		return predicate.AbsBefore != nil && *predicate.AbsBefore >= 0
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeRelativeTime:
+		return predicate.RelBefore != nil && *predicate.RelBefore >= 0
+	}
+	return false
+}
+
+// absolutePredicate returns the predicate with its relative times converted
+// to absolute times, as stored in claimable balances.
+func (a *applier) absolutePredicate(predicate xdr.ClaimPredicate) xdr.ClaimPredicate {
+	switch predicate.Type {
+	case xdr.ClaimPredicateTypeClaimPredicateAnd, xdr.ClaimPredicateTypeClaimPredicateOr:
+		source := predicate.AndPredicates
+		if predicate.Type == xdr.ClaimPredicateTypeClaimPredicateOr {
+			source = predicate.OrPredicates
+		}
+		predicates := []xdr.ClaimPredicate{
+			a.absolutePredicate((*source)[0]),
+			a.absolutePredicate((*source)[1]),
+		}
+		if predicate.Type == xdr.ClaimPredicateTypeClaimPredicateOr {
+			return xdr.ClaimPredicate{Type: predicate.Type, OrPredicates: &predicates}
+		}
+		return xdr.ClaimPredicate{Type: predicate.Type, AndPredicates: &predicates}
+	case xdr.ClaimPredicateTypeClaimPredicateNot:
+		not := a.absolutePredicate(**predicate.NotPredicate)
+		notPtr := &not
+		return xdr.ClaimPredicate{Type: predicate.Type, NotPredicate: &notPtr}
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeRelativeTime:
+		absBefore := xdr.Int64(a.closeTime) + *predicate.RelBefore
+		if absBefore < *predicate.RelBefore {
+			// Saturate on overflow like stellar-core.
+			absBefore = xdr.Int64(1<<63 - 1)
+		}
+		return xdr.ClaimPredicate{
+			Type:      xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime,
+			AbsBefore: &absBefore,
+		}
+	}
+	return predicate
+}
+
+// satisfied returns whether the absolute predicate is satisfied at the close
+// time of the ledger.
+func (a *applier) satisfied(predicate xdr.ClaimPredicate) bool {
+	switch predicate.Type {
+	case xdr.ClaimPredicateTypeClaimPredicateUnconditional:
+		return true
+	case xdr.ClaimPredicateTypeClaimPredicateAnd:
+		return a.satisfied((*predicate.AndPredicates)[0]) && a.satisfied((*predicate.AndPredicates)[1])
+	case xdr.ClaimPredicateTypeClaimPredicateOr:
+		return a.satisfied((*predicate.OrPredicates)[0]) || a.satisfied((*predicate.OrPredicates)[1])
+	case xdr.ClaimPredicateTypeClaimPredicateNot:
+		return !a.satisfied(**predicate.NotPredicate)
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime:
+		return a.closeTime < int64(*predicate.AbsBefore)
+	}
+	return false
+}
+
+func (a *applier) claimClaimableBalance(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustClaimClaimableBalanceOp()
+	key := claimableBalanceKey(op.BalanceId)
+	entry := sb.load(key)
+	if entry == nil {
+		return xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceDoesNotExist
+	}
+	balance := entry.Data.ClaimableBalance
+
+	canClaim := false
+	for _, claimant := range balance.Claimants {
+		v0 := claimant.MustV0()
+		if v0.Destination.Equals(source) {
+			canClaim = a.satisfied(v0.Predicate)
+			break
+		}
+	}
+	if !canClaim {
+		return xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceCannotClaim
+	}
+
+	switch a.addBalance(sb, source, balance.Asset, int64(balance.Amount)) {
+	case balanceNoTrust:
+		return xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceNoTrust
+	case balanceNotAuthorized:
+		return xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceNotAuthorized
+	case balanceFailed:
+		return xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceLineFull
+	}
+
+	sb.remove(key)
+	if sponsor := entry.SponsoringID(); sponsor != nil {
+		updateAccount(sb, *sponsor, func(account *xdr.AccountEntry) {
+			accountExtV2(account).NumSponsoring -= xdr.Uint32(multiplier(entry))
+		})
+	}
+	return xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceSuccess
+}
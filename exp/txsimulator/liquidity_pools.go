@@ -0,0 +1,139 @@
+package txsimulator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/stellar/go/xdr"
+)
+
+func (a *applier) liquidityPoolDeposit(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustLiquidityPoolDepositOp()
+	if op.MaxAmountA <= 0 || op.MaxAmountB <= 0 ||
+		op.MinPrice.N <= 0 || op.MinPrice.D <= 0 || op.MaxPrice.N <= 0 || op.MaxPrice.D <= 0 ||
+		big.NewRat(int64(op.MinPrice.N), int64(op.MinPrice.D)).Cmp(big.NewRat(int64(op.MaxPrice.N), int64(op.MaxPrice.D))) > 0 {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositMalformed
+	}
+
+	sharesEntry := sb.load(trustlineKey(source, poolShareAsset(op.LiquidityPoolId)))
+	if sharesEntry == nil {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositNoTrust
+	}
+	poolKey := liquidityPoolKey(op.LiquidityPoolId)
+	poolEntry := sb.load(poolKey)
+	pool := poolEntry.Data.LiquidityPool.Body.ConstantProduct
+
+	var available [2]int64
+	for i, asset := range []xdr.Asset{pool.Params.AssetA, pool.Params.AssetB} {
+		switch {
+		case asset.Type == xdr.AssetTypeAssetTypeNative:
+			available[i] = a.availableNative(sb.load(accountKey(source)).Data.Account)
+		case isIssuer(source, asset):
+			available[i] = math.MaxInt64
+		default:
+			entry := sb.load(trustlineKey(source, asset.ToTrustLineAsset()))
+			if entry == nil {
+				return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositNoTrust
+			}
+			if !authorized(entry.Data.TrustLine) {
+				return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositNotAuthorized
+			}
+			available[i] = availableTrustline(entry.Data.TrustLine)
+		}
+	}
+
+	maxA, maxB := big.NewInt(int64(op.MaxAmountA)), big.NewInt(int64(op.MaxAmountB))
+	var amountA, amountB, shares *big.Int
+	if pool.TotalPoolShares == 0 {
+		amountA, amountB = maxA, maxB
+		// The initial shares are the geometric mean of the deposited amounts.
+		shares = new(big.Int).Sqrt(new(big.Int).Mul(maxA, maxB))
+	} else {
+		total := big.NewInt(int64(pool.TotalPoolShares))
+		reserveA, reserveB := big.NewInt(int64(pool.ReserveA)), big.NewInt(int64(pool.ReserveB))
+		sharesA := new(big.Int).Quo(new(big.Int).Mul(total, maxA), reserveA)
+		sharesB := new(big.Int).Quo(new(big.Int).Mul(total, maxB), reserveB)
+		shares = sharesA
+		if sharesB.Cmp(sharesA) < 0 {
+			shares = sharesB
+		}
+		amountA = divCeil(new(big.Int).Mul(shares, reserveA), total)
+		amountB = divCeil(new(big.Int).Mul(shares, reserveB), total)
+	}
+
+	if amountA.Cmp(big.NewInt(available[0])) > 0 || amountB.Cmp(big.NewInt(available[1])) > 0 {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositUnderfunded
+	}
+	// The price of the deposit, amountA/amountB, must be within the bounds.
+	if amountB.Sign() == 0 ||
+		new(big.Int).Mul(amountA, big.NewInt(int64(op.MinPrice.D))).Cmp(new(big.Int).Mul(amountB, big.NewInt(int64(op.MinPrice.N)))) < 0 ||
+		new(big.Int).Mul(amountA, big.NewInt(int64(op.MaxPrice.D))).Cmp(new(big.Int).Mul(amountB, big.NewInt(int64(op.MaxPrice.N)))) > 0 {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositBadPrice
+	}
+	if shares.Cmp(big.NewInt(int64(sharesEntry.Data.TrustLine.Limit-sharesEntry.Data.TrustLine.Balance))) > 0 {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositLineFull
+	}
+	if shares.Sign() == 0 ||
+		!new(big.Int).Add(shares, big.NewInt(int64(pool.TotalPoolShares))).IsInt64() ||
+		!new(big.Int).Add(amountA, big.NewInt(int64(pool.ReserveA))).IsInt64() ||
+		!new(big.Int).Add(amountB, big.NewInt(int64(pool.ReserveB))).IsInt64() {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositPoolFull
+	}
+
+	if a.addBalance(sb, source, pool.Params.AssetA, -amountA.Int64()) != balanceSuccess ||
+		a.addBalance(sb, source, pool.Params.AssetB, -amountB.Int64()) != balanceSuccess {
+		return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositUnderfunded
+	}
+	sharesEntry.Data.TrustLine.Balance += xdr.Int64(shares.Int64())
+	sb.store(sharesEntry)
+	pool.ReserveA += xdr.Int64(amountA.Int64())
+	pool.ReserveB += xdr.Int64(amountB.Int64())
+	pool.TotalPoolShares += xdr.Int64(shares.Int64())
+	sb.store(poolEntry)
+	return xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositSuccess
+}
+
+func (a *applier) liquidityPoolWithdraw(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustLiquidityPoolWithdrawOp()
+	if op.Amount <= 0 || op.MinAmountA < 0 || op.MinAmountB < 0 {
+		return xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawMalformed
+	}
+
+	sharesEntry := sb.load(trustlineKey(source, poolShareAsset(op.LiquidityPoolId)))
+	if sharesEntry == nil {
+		return xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawNoTrust
+	}
+	if availableTrustline(sharesEntry.Data.TrustLine) < int64(op.Amount) {
+		return xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawUnderfunded
+	}
+	poolEntry := sb.load(liquidityPoolKey(op.LiquidityPoolId))
+	pool := poolEntry.Data.LiquidityPool.Body.ConstantProduct
+
+	amount, total := big.NewInt(int64(op.Amount)), big.NewInt(int64(pool.TotalPoolShares))
+	amountA := new(big.Int).Quo(new(big.Int).Mul(amount, big.NewInt(int64(pool.ReserveA))), total).Int64()
+	amountB := new(big.Int).Quo(new(big.Int).Mul(amount, big.NewInt(int64(pool.ReserveB))), total).Int64()
+	if amountA < int64(op.MinAmountA) || amountB < int64(op.MinAmountB) {
+		return xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawUnderMinimum
+	}
+
+	if a.addBalance(sb, source, pool.Params.AssetA, amountA) != balanceSuccess ||
+		a.addBalance(sb, source, pool.Params.AssetB, amountB) != balanceSuccess {
+		return xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawLineFull
+	}
+	sharesEntry.Data.TrustLine.Balance -= op.Amount
+	sb.store(sharesEntry)
+	pool.ReserveA -= xdr.Int64(amountA)
+	pool.ReserveB -= xdr.Int64(amountB)
+	pool.TotalPoolShares -= op.Amount
+	sb.store(poolEntry)
+	return xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawSuccess
+}
+
+// divCeil returns x/y rounded up, for positive numbers.
+func divCeil(x, y *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
@@ -0,0 +1,255 @@
+// Package txsimulator predicts the outcome of transactions built with
+// txnbuild without submitting them to the network. Transactions are applied
+// to an in-memory ledger state, seeded with XDR ledger entries, following the
+// rules of stellar-core for protocol 18: the result codes of the transaction
+// and of its operations are computed along with the changes of the ledger
+// entries, including the reserve and sponsorship accounting.
+//
+// The create_account, payment, change_trust, set_options, manage_data,
+// bump_sequence, manage_sell_offer, create_claimable_balance,
+// claim_claimable_balance, begin_sponsoring_future_reserves,
+// end_sponsoring_future_reserves, revoke_sponsorship, liquidity_pool_deposit
+// and liquidity_pool_withdraw operations are supported. Offers are not crossed
+// with the offers of the ledger, their amount is only reduced to what the
+// seller can sell and buy. Signatures are verified against the signers and
+// thresholds of the source accounts when Config.NetworkPassphrase is set, and
+// the base fee is charged as if the network was not in surge pricing.
+package txsimulator
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	// DefaultBaseReserve is the base reserve, in stroops, used when
+	// Config.BaseReserve is not set.
+	DefaultBaseReserve = 5000000
+)
+
+// ErrUnsupportedOperation is returned when a transaction contains an
+// operation the simulator cannot apply.
+var ErrUnsupportedOperation = errors.New("unsupported operation")
+
+// Config configures a Simulator.
+type Config struct {
+	// BaseFee is the minimum fee per operation, in stroops. It defaults to
+	// txnbuild.MinBaseFee.
+	BaseFee int64
+	// BaseReserve is the base reserve, in stroops. It defaults to
+	// DefaultBaseReserve.
+	BaseReserve int64
+	// LedgerSequence is the sequence of the ledger the transactions are
+	// applied in. It sets the last modified ledger of the changed entries and
+	// the sequence number of the created accounts.
+	LedgerSequence uint32
+	// CloseTime is the close time of the ledger the transactions are applied
+	// in, checked against timebounds and claim predicates. It defaults to the
+	// time of the simulation.
+	CloseTime time.Time
+	// NetworkPassphrase is the passphrase of the network the transactions
+	// are signed for. Signatures are not verified when it is empty, and fee
+	// bump transactions cannot be simulated.
+	NetworkPassphrase string
+}
+
+// Result is the predicted outcome of a transaction.
+type Result struct {
+	// Successful is true when the transaction would be applied successfully.
+	Successful bool
+	// Included is true when the transaction would be included in the
+	// ledger, successful or not. Rejected transactions are not included.
+	Included bool
+	// TransactionCode is the Horizon representation of the result code of
+	// the transaction, ex. tx_success or tx_bad_seq.
+	TransactionCode string
+	// OperationCodes are the Horizon representations of the result codes of
+	// the operations, ex. op_underfunded. It is empty when the transaction is
+	// rejected before its operations are checked, and for tx_bad_sponsorship.
+	OperationCodes []string
+	// XDR is the result of the transaction.
+	XDR xdr.TransactionResult
+	// Changes are the changes of the ledger entries, in the order the
+	// entries were first changed. The fee and sequence number of rejected
+	// transactions are not charged, they have no changes. Failed
+	// transactions only change their source account.
+	Changes []ingest.Change
+}
+
+// Simulator applies transactions to an in-memory ledger state. It is safe
+// for concurrent use.
+type Simulator struct {
+	config Config
+
+	mu      sync.Mutex
+	entries map[string]xdr.LedgerEntry
+	// idPool is the highest offer ID of the ledger state, including the
+	// offers removed since.
+	idPool int64
+}
+
+// New returns a simulator with an empty ledger state, seed it with Add.
+func New(config Config) *Simulator {
+	if config.BaseFee == 0 {
+		config.BaseFee = txnbuild.MinBaseFee
+	}
+	if config.BaseReserve == 0 {
+		config.BaseReserve = DefaultBaseReserve
+	}
+	return &Simulator{
+		config:  config,
+		entries: map[string]xdr.LedgerEntry{},
+	}
+}
+
+// Add adds the entries to the ledger state, replacing the entries with the
+// same keys.
+func (s *Simulator) Add(entries ...xdr.LedgerEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range entries {
+		key, err := keyString(entry.LedgerKey())
+		if err != nil {
+			return err
+		}
+		entry, err = copyEntry(entry)
+		if err != nil {
+			return err
+		}
+		s.entries[key] = entry
+		if offer, ok := entry.Data.GetOffer(); ok && int64(offer.OfferId) > s.idPool {
+			s.idPool = int64(offer.OfferId)
+		}
+	}
+	return nil
+}
+
+// SetLedger sets the sequence and the close time of the ledger the next
+// transactions are applied in.
+func (s *Simulator) SetLedger(sequence uint32, closeTime time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.LedgerSequence = sequence
+	s.config.CloseTime = closeTime
+}
+
+// LastOfferID returns the highest offer ID of the ledger state, including
+// the offers removed since. New offers get the next IDs.
+func (s *Simulator) LastOfferID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idPool
+}
+
+// Entry returns the entry of the ledger state with the given key.
+func (s *Simulator) Entry(key xdr.LedgerKey) (xdr.LedgerEntry, bool, error) {
+	k, err := keyString(key)
+	if err != nil {
+		return xdr.LedgerEntry{}, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[k]
+	if !ok {
+		return xdr.LedgerEntry{}, false, nil
+	}
+	entry, err = copyEntry(entry)
+	return entry, err == nil, err
+}
+
+// Entries returns the entries of the ledger state, ordered by key.
+func (s *Simulator) Entries() ([]xdr.LedgerEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]xdr.LedgerEntry, len(keys))
+	for i, key := range keys {
+		entry, err := copyEntry(s.entries[key])
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// Simulate predicts the outcome of the transaction without changing the
+// ledger state.
+func (s *Simulator) Simulate(tx *txnbuild.Transaction) (Result, error) {
+	return s.run(tx.ToXDR(), false)
+}
+
+// Apply predicts the outcome of the transaction and applies its changes to
+// the ledger state, so that the transactions depending on it can be
+// simulated next.
+func (s *Simulator) Apply(tx *txnbuild.Transaction) (Result, error) {
+	return s.run(tx.ToXDR(), true)
+}
+
+// SimulateFeeBump predicts the outcome of the fee bump transaction without
+// changing the ledger state.
+func (s *Simulator) SimulateFeeBump(tx *txnbuild.FeeBumpTransaction) (Result, error) {
+	return s.run(tx.ToXDR(), false)
+}
+
+// ApplyFeeBump predicts the outcome of the fee bump transaction and applies
+// its changes to the ledger state.
+func (s *Simulator) ApplyFeeBump(tx *txnbuild.FeeBumpTransaction) (Result, error) {
+	return s.run(tx.ToXDR(), true)
+}
+
+func (s *Simulator) run(envelope xdr.TransactionEnvelope, commit bool) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, op := range envelope.Operations() {
+		if _, ok := operationFuncs[op.Body.Type]; !ok {
+			return Result{}, errors.Wrapf(ErrUnsupportedOperation, "operation %d (%s)", i, op.Body.Type)
+		}
+	}
+	if envelope.IsFeeBump() && s.config.NetworkPassphrase == "" {
+		return Result{}, errors.New("fee bump transactions cannot be simulated without network passphrase")
+	}
+
+	closeTime := s.config.CloseTime
+	if closeTime.IsZero() {
+		closeTime = time.Now()
+	}
+	a := &applier{
+		config:     s.config,
+		closeTime:  closeTime.Unix(),
+		envelope:   envelope,
+		idPool:     s.idPool,
+		sponsoring: map[string]xdr.AccountId{},
+	}
+	sb := newSandbox(s.entries, s.config.LedgerSequence)
+	result, err := a.apply(sb)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Changes = sb.changes()
+
+	if commit {
+		for _, key := range sb.keys {
+			if entry := sb.updated[key]; entry != nil {
+				s.entries[key] = *entry
+			} else {
+				delete(s.entries, key)
+			}
+		}
+		s.idPool = a.idPool
+	}
+	return result, nil
+}
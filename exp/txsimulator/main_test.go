@@ -0,0 +1,473 @@
+package txsimulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/txnbuild"
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sequence = 100
+
+func accountEntry(kp *keypair.Full, balance string) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId:  xdr.MustAddress(kp.Address()),
+				Balance:    amount.MustParse(balance),
+				SeqNum:     sequence,
+				Thresholds: xdr.Thresholds{1, 0, 0, 0},
+			},
+		},
+	}
+}
+
+func newSimulator(t *testing.T, entries ...xdr.LedgerEntry) *Simulator {
+	sim := New(Config{LedgerSequence: 10, CloseTime: time.Unix(1600000000, 0)})
+	require.NoError(t, sim.Add(entries...))
+	return sim
+}
+
+func buildTx(t *testing.T, source *keypair.Full, seq int64, ops ...txnbuild.Operation) *txnbuild.Transaction {
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: source.Address(), Sequence: seq},
+		IncrementSequenceNum: true,
+		Operations:           ops,
+		BaseFee:              txnbuild.MinBaseFee,
+		Timebounds:           txnbuild.NewInfiniteTimeout(),
+	})
+	require.NoError(t, err)
+	return tx
+}
+
+func loadAccount(t *testing.T, sim *Simulator, kp *keypair.Full) *xdr.AccountEntry {
+	entry, ok, err := sim.Entry(accountKey(xdr.MustAddress(kp.Address())))
+	require.NoError(t, err)
+	require.True(t, ok)
+	return entry.Data.Account
+}
+
+func loadTrustline(t *testing.T, sim *Simulator, kp *keypair.Full, asset xdr.TrustLineAsset) *xdr.TrustLineEntry {
+	entry, ok, err := sim.Entry(trustlineKey(xdr.MustAddress(kp.Address()), asset))
+	require.NoError(t, err)
+	require.True(t, ok)
+	return entry.Data.TrustLine
+}
+
+func TestPayment(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "10"), accountEntry(bob, "10"))
+
+	tx := buildTx(t, alice, sequence, &txnbuild.Payment{
+		Destination: bob.Address(),
+		Amount:      "5",
+		Asset:       txnbuild.NativeAsset{},
+	})
+	result, err := sim.Simulate(tx)
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.Equal(t, "tx_success", result.TransactionCode)
+	assert.Equal(t, []string{"op_success"}, result.OperationCodes)
+	assert.Equal(t, xdr.Int64(100), result.XDR.FeeCharged)
+	require.Len(t, result.Changes, 2)
+	assert.Equal(t, xdr.Int64(49999900), result.Changes[0].Post.Data.Account.Balance)
+	assert.Equal(t, xdr.Uint32(10), result.Changes[0].Post.LastModifiedLedgerSeq)
+	assert.Equal(t, xdr.Int64(150000000), result.Changes[1].Post.Data.Account.Balance)
+
+	// Simulations do not change the ledger state.
+	assert.Equal(t, xdr.Int64(100000000), loadAccount(t, sim, alice).Balance)
+
+	result, err = sim.Apply(tx)
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.Equal(t, xdr.Int64(49999900), loadAccount(t, sim, alice).Balance)
+	assert.Equal(t, xdr.SequenceNumber(sequence+1), loadAccount(t, sim, alice).SeqNum)
+
+	// The sequence number was consumed.
+	result, err = sim.Simulate(tx)
+	require.NoError(t, err)
+	assert.False(t, result.Successful)
+	assert.Equal(t, "tx_bad_seq", result.TransactionCode)
+	assert.Empty(t, result.OperationCodes)
+	assert.Empty(t, result.Changes)
+}
+
+func TestPaymentUnderfunded(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "10"), accountEntry(bob, "10"))
+
+	// Alice has to keep 1 XLM for the reserves of her account.
+	result, err := sim.Apply(buildTx(t, alice, sequence,
+		&txnbuild.Payment{Destination: bob.Address(), Amount: "4", Asset: txnbuild.NativeAsset{}},
+		&txnbuild.Payment{Destination: bob.Address(), Amount: "5", Asset: txnbuild.NativeAsset{}},
+	))
+	require.NoError(t, err)
+	assert.False(t, result.Successful)
+	assert.Equal(t, "tx_failed", result.TransactionCode)
+	assert.Equal(t, []string{"op_success", "op_underfunded"}, result.OperationCodes)
+
+	// Only the fee and sequence number of failed transactions are charged.
+	require.Len(t, result.Changes, 1)
+	assert.Equal(t, xdr.Int64(99999800), loadAccount(t, sim, alice).Balance)
+	assert.Equal(t, xdr.Int64(100000000), loadAccount(t, sim, bob).Balance)
+}
+
+func TestCreateAccountLowReserve(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "10"))
+
+	result, err := sim.Simulate(buildTx(t, alice, sequence,
+		&txnbuild.CreateAccount{Destination: bob.Address(), Amount: "0.5"},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_low_reserve"}, result.OperationCodes)
+
+	result, err = sim.Apply(buildTx(t, alice, sequence,
+		&txnbuild.CreateAccount{Destination: bob.Address(), Amount: "1"},
+	))
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	account := loadAccount(t, sim, bob)
+	assert.Equal(t, xdr.Int64(10000000), account.Balance)
+	assert.Equal(t, xdr.SequenceNumber(10<<32), account.SeqNum)
+}
+
+func TestSponsoredAccount(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "10"))
+
+	ops := []txnbuild.Operation{
+		&txnbuild.BeginSponsoringFutureReserves{SponsoredID: bob.Address()},
+		&txnbuild.CreateAccount{Destination: bob.Address(), Amount: "0"},
+		&txnbuild.ManageData{Name: "name", Value: []byte("value"), SourceAccount: bob.Address()},
+	}
+	result, err := sim.Simulate(buildTx(t, alice, sequence, ops...))
+	require.NoError(t, err)
+	assert.Equal(t, "tx_bad_sponsorship", result.TransactionCode)
+	assert.Empty(t, result.OperationCodes)
+	assert.True(t, result.Included)
+
+	ops = append(ops, &txnbuild.EndSponsoringFutureReserves{SourceAccount: bob.Address()})
+	result, err = sim.Apply(buildTx(t, alice, sequence, ops...))
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+
+	sponsor := loadAccount(t, sim, alice)
+	assert.Equal(t, xdr.Uint32(3), sponsor.NumSponsoring())
+	sponsored := loadAccount(t, sim, bob)
+	assert.Equal(t, xdr.Uint32(3), sponsored.NumSponsored())
+	assert.Equal(t, xdr.Uint32(1), sponsored.NumSubEntries)
+
+	// Alice can revoke the sponsorship of the data entry, Bob cannot afford
+	// its reserve.
+	result, err = sim.Simulate(buildTx(t, alice, sequence+1, &txnbuild.RevokeSponsorship{
+		SponsorshipType: txnbuild.RevokeSponsorshipTypeData,
+		Data:            &txnbuild.DataID{Account: bob.Address(), DataName: "name"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_low_reserve"}, result.OperationCodes)
+
+	result, err = sim.Simulate(buildTx(t, bob, int64(sponsored.SeqNum), &txnbuild.RevokeSponsorship{
+		SponsorshipType: txnbuild.RevokeSponsorshipTypeData,
+		Data:            &txnbuild.DataID{Account: bob.Address(), DataName: "name"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "tx_insufficient_balance", result.TransactionCode)
+}
+
+func TestClaimableBalance(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "100"), accountEntry(bob, "10"))
+
+	notBefore := txnbuild.NotPredicate(txnbuild.BeforeRelativeTimePredicate(60))
+	tx := buildTx(t, alice, sequence, &txnbuild.CreateClaimableBalance{
+		Amount: "50",
+		Asset:  txnbuild.NativeAsset{},
+		Destinations: []txnbuild.Claimant{
+			txnbuild.NewClaimant(alice.Address(), nil),
+			txnbuild.NewClaimant(bob.Address(), &notBefore),
+		},
+	})
+	result, err := sim.Apply(tx)
+	require.NoError(t, err)
+	require.True(t, result.Successful)
+
+	expectedID, err := tx.ClaimableBalanceID(0)
+	require.NoError(t, err)
+	id := (*result.XDR.Result.Results)[0].Tr.MustCreateClaimableBalanceResult().BalanceId
+	idString, err := xdr.MarshalHex(id)
+	require.NoError(t, err)
+	assert.Equal(t, expectedID, idString)
+	assert.Equal(t, xdr.Uint32(2), loadAccount(t, sim, alice).NumSponsoring())
+
+	// Bob can only claim the balance after a minute.
+	claim := buildTx(t, bob, sequence, &txnbuild.ClaimClaimableBalance{BalanceID: expectedID})
+	result, err = sim.Simulate(claim)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_cannot_claim"}, result.OperationCodes)
+
+	later := New(Config{LedgerSequence: 10, CloseTime: time.Unix(1600000061, 0)})
+	for _, kp := range []*keypair.Full{alice, bob} {
+		entry, _, err := sim.Entry(accountKey(xdr.MustAddress(kp.Address())))
+		require.NoError(t, err)
+		require.NoError(t, later.Add(entry))
+	}
+	balance, _, err := sim.Entry(claimableBalanceKey(*id))
+	require.NoError(t, err)
+	require.NoError(t, later.Add(balance))
+
+	result, err = later.Apply(claim)
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.Equal(t, xdr.Int64(599999900), loadAccount(t, later, bob).Balance)
+	assert.Equal(t, xdr.Uint32(0), loadAccount(t, later, alice).NumSponsoring())
+	_, ok, err := later.Entry(claimableBalanceKey(*id))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLiquidityPool(t *testing.T) {
+	issuer, alice := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(issuer, "10"), accountEntry(alice, "1000"))
+
+	usd := txnbuild.CreditAsset{Code: "USD", Issuer: issuer.Address()}
+	poolID, err := txnbuild.NewLiquidityPoolId(txnbuild.NativeAsset{}, usd)
+	require.NoError(t, err)
+	result, err := sim.Apply(buildTx(t, alice, sequence,
+		&txnbuild.ChangeTrust{Line: usd.MustToChangeTrustAsset(), Limit: "1000"},
+		&txnbuild.Payment{Destination: alice.Address(), Amount: "500", Asset: usd, SourceAccount: issuer.Address()},
+		&txnbuild.ChangeTrust{
+			Line: txnbuild.LiquidityPoolShareChangeTrustAsset{
+				LiquidityPoolParameters: txnbuild.LiquidityPoolParameters{
+					AssetA: txnbuild.NativeAsset{},
+					AssetB: usd,
+					Fee:    txnbuild.LiquidityPoolFeeV18,
+				},
+			},
+			Limit: txnbuild.MaxTrustlineLimit,
+		},
+	))
+	require.NoError(t, err)
+	require.True(t, result.Successful, result.OperationCodes)
+	assert.Equal(t, xdr.Uint32(3), loadAccount(t, sim, alice).NumSubEntries)
+
+	result, err = sim.Simulate(buildTx(t, alice, sequence+1, &txnbuild.LiquidityPoolDeposit{
+		LiquidityPoolID: poolID,
+		MaxAmountA:      "100",
+		MaxAmountB:      "400",
+		MinPrice:        "1",
+		MaxPrice:        "2",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_bad_price"}, result.OperationCodes)
+
+	result, err = sim.Apply(buildTx(t, alice, sequence+1, &txnbuild.LiquidityPoolDeposit{
+		LiquidityPoolID: poolID,
+		MaxAmountA:      "100",
+		MaxAmountB:      "400",
+		MinPrice:        "0.2",
+		MaxPrice:        "0.3",
+	}))
+	require.NoError(t, err)
+	require.True(t, result.Successful, result.OperationCodes)
+	shares := loadTrustline(t, sim, alice, poolShareAsset(xdr.PoolId(poolID)))
+	assert.Equal(t, xdr.Int64(2000000000), shares.Balance)
+	usdTrustline := xdr.MustNewCreditAsset("USD", issuer.Address()).ToTrustLineAsset()
+	assert.Equal(t, xdr.Int64(1000000000), loadTrustline(t, sim, alice, usdTrustline).Balance)
+
+	result, err = sim.Simulate(buildTx(t, alice, sequence+2, &txnbuild.LiquidityPoolWithdraw{
+		LiquidityPoolID: poolID,
+		Amount:          "100",
+		MinAmountA:      "60",
+		MinAmountB:      "0",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_under_minimum"}, result.OperationCodes)
+
+	result, err = sim.Apply(buildTx(t, alice, sequence+2, &txnbuild.LiquidityPoolWithdraw{
+		LiquidityPoolID: poolID,
+		Amount:          "100",
+		MinAmountA:      "50",
+		MinAmountB:      "200",
+	}))
+	require.NoError(t, err)
+	require.True(t, result.Successful, result.OperationCodes)
+	assert.Equal(t, xdr.Int64(3000000000), loadTrustline(t, sim, alice, usdTrustline).Balance)
+	entry, ok, err := sim.Entry(liquidityPoolKey(xdr.PoolId(poolID)))
+	require.NoError(t, err)
+	require.True(t, ok)
+	pool := entry.Data.LiquidityPool.Body.ConstantProduct
+	assert.Equal(t, xdr.Int64(500000000), pool.ReserveA)
+	assert.Equal(t, xdr.Int64(2000000000), pool.ReserveB)
+	assert.Equal(t, xdr.Int64(1000000000), pool.TotalPoolShares)
+}
+
+func TestBumpSequence(t *testing.T) {
+	alice := keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "10"))
+
+	result, err := sim.Apply(buildTx(t, alice, sequence, &txnbuild.BumpSequence{BumpTo: 200}))
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.Equal(t, xdr.SequenceNumber(200), loadAccount(t, sim, alice).SeqNum)
+
+	result, err = sim.Simulate(buildTx(t, alice, 200, &txnbuild.BumpSequence{BumpTo: 100}))
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.Equal(t, xdr.SequenceNumber(201), result.Changes[0].Post.Data.Account.SeqNum)
+}
+
+func TestManageSellOffer(t *testing.T) {
+	alice, issuer := keypair.MustRandom(), keypair.MustRandom()
+	usd := txnbuild.CreditAsset{Code: "USD", Issuer: issuer.Address()}
+	usdAsset, err := usd.ToXDR()
+	require.NoError(t, err)
+	aliceEntry := accountEntry(alice, "10")
+	aliceEntry.Data.Account.NumSubEntries = 1
+	sim := newSimulator(t, aliceEntry, accountEntry(issuer, "10"), xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: xdr.MustAddress(alice.Address()),
+				Asset:     usdAsset.ToTrustLineAsset(),
+				Limit:     amount.MustParse("1000"),
+				Flags:     xdr.Uint32(xdr.TrustLineFlagsAuthorizedFlag),
+			},
+		},
+	})
+
+	result, err := sim.Simulate(buildTx(t, alice, sequence, &txnbuild.ManageSellOffer{
+		Selling: usd, Buying: txnbuild.NativeAsset{}, Amount: "1", Price: "1",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_underfunded"}, result.OperationCodes)
+
+	// The amount of the offer is reduced to what Alice can sell once the fee
+	// and the reserve of the offer are paid.
+	result, err = sim.Simulate(buildTx(t, alice, sequence, &txnbuild.ManageSellOffer{
+		Selling: txnbuild.NativeAsset{}, Buying: usd, Amount: "20", Price: "2",
+	}))
+	require.NoError(t, err)
+	require.True(t, result.Successful)
+	offer := result.XDR.Result.MustResults()[0].MustTr().MustManageSellOfferResult().MustSuccess().Offer
+	assert.Equal(t, xdr.ManageOfferEffectManageOfferCreated, offer.Effect)
+	assert.Equal(t, xdr.Int64(1), offer.Offer.OfferId)
+	assert.Equal(t, xdr.Int64(79999900), offer.Offer.Amount)
+
+	result, err = sim.Apply(buildTx(t, alice, sequence, &txnbuild.ManageSellOffer{
+		Selling: txnbuild.NativeAsset{}, Buying: usd, Amount: "5", Price: "2",
+	}))
+	require.NoError(t, err)
+	require.True(t, result.Successful)
+	assert.Equal(t, int64(1), sim.LastOfferID())
+	account := loadAccount(t, sim, alice)
+	assert.Equal(t, xdr.Uint32(2), account.NumSubEntries)
+	assert.Equal(t, xdr.Int64(50000000), account.Liabilities().Selling)
+	trustline := loadTrustline(t, sim, alice, usdAsset.ToTrustLineAsset())
+	assert.Equal(t, xdr.Int64(100000000), trustline.Liabilities().Buying)
+
+	result, err = sim.Apply(buildTx(t, alice, sequence+1, &txnbuild.ManageSellOffer{
+		Selling: txnbuild.NativeAsset{}, Buying: usd, Amount: "0", Price: "2", OfferID: 1,
+	}))
+	require.NoError(t, err)
+	require.True(t, result.Successful)
+	account = loadAccount(t, sim, alice)
+	assert.Equal(t, xdr.Uint32(1), account.NumSubEntries)
+	assert.Equal(t, xdr.Int64(0), account.Liabilities().Selling)
+	_, ok, err := sim.Entry(offerKey(xdr.MustAddress(alice.Address()), 1))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	result, err = sim.Simulate(buildTx(t, alice, sequence+2, &txnbuild.ManageSellOffer{
+		Selling: txnbuild.NativeAsset{}, Buying: usd, Amount: "1", Price: "2", OfferID: 1,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"op_offer_not_found"}, result.OperationCodes)
+}
+
+func newSigningSimulator(t *testing.T, entries ...xdr.LedgerEntry) *Simulator {
+	sim := New(Config{
+		LedgerSequence:    10,
+		CloseTime:         time.Unix(1600000000, 0),
+		NetworkPassphrase: network.TestNetworkPassphrase,
+	})
+	require.NoError(t, sim.Add(entries...))
+	return sim
+}
+
+func TestSignatures(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSigningSimulator(t, accountEntry(alice, "10"), accountEntry(bob, "10"))
+
+	tx := buildTx(t, alice, sequence, &txnbuild.BumpSequence{BumpTo: 200})
+	result, err := sim.Simulate(tx)
+	require.NoError(t, err)
+	assert.Equal(t, "tx_bad_auth", result.TransactionCode)
+	assert.False(t, result.Included)
+
+	tx = buildTx(t, alice, sequence, &txnbuild.ManageData{Name: "name", Value: []byte("value"), SourceAccount: bob.Address()})
+	tx, err = tx.Sign(network.TestNetworkPassphrase, alice)
+	require.NoError(t, err)
+	result, err = sim.Simulate(tx)
+	require.NoError(t, err)
+	assert.Equal(t, "tx_failed", result.TransactionCode)
+	assert.Equal(t, []string{"op_bad_auth"}, result.OperationCodes)
+	assert.False(t, result.Included)
+	assert.Empty(t, result.Changes)
+
+	tx, err = tx.Sign(network.TestNetworkPassphrase, bob)
+	require.NoError(t, err)
+	result, err = sim.Simulate(tx)
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.True(t, result.Included)
+}
+
+func TestFeeBump(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSigningSimulator(t, accountEntry(alice, "10"), accountEntry(bob, "10"))
+
+	tx := buildTx(t, alice, sequence, &txnbuild.BumpSequence{BumpTo: 200})
+	tx, err := tx.Sign(network.TestNetworkPassphrase, alice)
+	require.NoError(t, err)
+	feeBump, err := txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+		Inner:      tx,
+		FeeAccount: bob.Address(),
+		BaseFee:    txnbuild.MinBaseFee,
+	})
+	require.NoError(t, err)
+
+	result, err := sim.SimulateFeeBump(feeBump)
+	require.NoError(t, err)
+	assert.Equal(t, "tx_bad_auth", result.TransactionCode)
+
+	feeBump, err = feeBump.Sign(network.TestNetworkPassphrase, bob)
+	require.NoError(t, err)
+	result, err = sim.ApplyFeeBump(feeBump)
+	require.NoError(t, err)
+	assert.True(t, result.Successful)
+	assert.Equal(t, "tx_fee_bump_inner_success", result.TransactionCode)
+	assert.Equal(t, []string{"op_success"}, result.OperationCodes)
+	assert.Equal(t, xdr.Int64(200), result.XDR.FeeCharged)
+	assert.Equal(t, xdr.Int64(100000000), loadAccount(t, sim, alice).Balance)
+	assert.Equal(t, xdr.SequenceNumber(200), loadAccount(t, sim, alice).SeqNum)
+	assert.Equal(t, xdr.Int64(99999800), loadAccount(t, sim, bob).Balance)
+
+	_, err = New(Config{}).SimulateFeeBump(feeBump)
+	assert.EqualError(t, err, "fee bump transactions cannot be simulated without network passphrase")
+}
+
+func TestUnsupportedOperation(t *testing.T) {
+	alice, bob := keypair.MustRandom(), keypair.MustRandom()
+	sim := newSimulator(t, accountEntry(alice, "10"), accountEntry(bob, "10"))
+
+	_, err := sim.Simulate(buildTx(t, alice, sequence, &txnbuild.AccountMerge{Destination: bob.Address()}))
+	assert.EqualError(t, err, "operation 0 (OperationTypeAccountMerge): unsupported operation")
+}
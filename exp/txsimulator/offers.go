@@ -0,0 +1,218 @@
+package txsimulator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/stellar/go/xdr"
+)
+
+// manageSellOffer creates, updates or deletes an offer. Offers are not
+// crossed with the offers of the ledger, their amount is only reduced to
+// what the seller can sell and buy.
+func (a *applier) manageSellOffer(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustManageSellOfferOp()
+	if op.Selling.Equals(op.Buying) || op.Amount < 0 || op.Price.N <= 0 || op.Price.D <= 0 ||
+		op.OfferId < 0 || op.OfferId == 0 && op.Amount == 0 {
+		return xdr.ManageSellOfferResultCodeManageSellOfferMalformed
+	}
+	if op.Amount > 0 {
+		if code := checkOfferAssets(sb, source, op.Selling, op.Buying); code != xdr.ManageSellOfferResultCodeManageSellOfferSuccess {
+			return code
+		}
+	}
+
+	var (
+		entry   *xdr.LedgerEntry
+		sponsor *xdr.AccountId
+		effect  = xdr.ManageOfferEffectManageOfferUpdated
+	)
+	if op.OfferId != 0 {
+		entry = sb.load(offerKey(source, op.OfferId))
+		if entry == nil {
+			return xdr.ManageSellOfferResultCodeManageSellOfferNotFound
+		}
+		sponsor = entry.SponsoringID()
+		addOfferLiabilities(sb, *entry.Data.Offer, -1)
+	} else {
+		var ok bool
+		sponsor, ok = a.createSubentry(sb, source, 1)
+		if !ok {
+			return xdr.ManageSellOfferResultCodeManageSellOfferLowReserve
+		}
+		entry = &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type:  xdr.LedgerEntryTypeOffer,
+				Offer: &xdr.OfferEntry{SellerId: source},
+			},
+		}
+		if sponsor != nil {
+			setSponsor(entry, sponsor)
+		}
+		effect = xdr.ManageOfferEffectManageOfferCreated
+	}
+
+	offerAmount := int64(op.Amount)
+	if offerAmount > 0 {
+		maxSell := a.maxSellable(sb, source, op.Selling)
+		if maxSell <= 0 {
+			return xdr.ManageSellOfferResultCodeManageSellOfferUnderfunded
+		}
+		maxBuy := a.maxBuyable(sb, source, op.Buying)
+		if maxBuy <= 0 {
+			return xdr.ManageSellOfferResultCodeManageSellOfferLineFull
+		}
+		for _, max := range []int64{maxSell, sellingAmount(maxBuy, op.Price)} {
+			if offerAmount > max {
+				offerAmount = max
+			}
+		}
+	}
+
+	result := xdr.ManageSellOfferResult{
+		Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+		Success: &xdr.ManageOfferSuccessResult{
+			OffersClaimed: []xdr.ClaimAtom{},
+			Offer:         xdr.ManageOfferSuccessResultOffer{Effect: xdr.ManageOfferEffectManageOfferDeleted},
+		},
+	}
+	if offerAmount == 0 {
+		if op.OfferId != 0 {
+			sb.remove(offerKey(source, op.OfferId))
+		}
+		removeSubentry(sb, source, sponsor, 1)
+		return result
+	}
+
+	offer := entry.Data.Offer
+	if op.OfferId == 0 {
+		a.idPool++
+		offer.OfferId = xdr.Int64(a.idPool)
+	}
+	offer.Selling = op.Selling
+	offer.Buying = op.Buying
+	offer.Amount = xdr.Int64(offerAmount)
+	offer.Price = op.Price
+	sb.store(entry)
+	addOfferLiabilities(sb, *offer, 1)
+
+	stored := *offer
+	result.Success.Offer = xdr.ManageOfferSuccessResultOffer{Effect: effect, Offer: &stored}
+	return result
+}
+
+// checkOfferAssets checks that the seller holds authorized trustlines to
+// the assets of the offer.
+func checkOfferAssets(sb *sandbox, seller xdr.AccountId, selling, buying xdr.Asset) xdr.ManageSellOfferResultCode {
+	if selling.Type != xdr.AssetTypeAssetTypeNative && !isIssuer(seller, selling) {
+		entry := sb.load(trustlineKey(seller, selling.ToTrustLineAsset()))
+		if entry == nil {
+			return xdr.ManageSellOfferResultCodeManageSellOfferSellNoTrust
+		}
+		if !authorized(entry.Data.TrustLine) {
+			return xdr.ManageSellOfferResultCodeManageSellOfferSellNotAuthorized
+		}
+	}
+	if buying.Type != xdr.AssetTypeAssetTypeNative && !isIssuer(seller, buying) {
+		entry := sb.load(trustlineKey(seller, buying.ToTrustLineAsset()))
+		if entry == nil {
+			return xdr.ManageSellOfferResultCodeManageSellOfferBuyNoTrust
+		}
+		if !authorized(entry.Data.TrustLine) {
+			return xdr.ManageSellOfferResultCodeManageSellOfferBuyNotAuthorized
+		}
+	}
+	return xdr.ManageSellOfferResultCodeManageSellOfferSuccess
+}
+
+// maxSellable returns the amount of the asset the account can sell on top
+// of its selling liabilities.
+func (a *applier) maxSellable(sb *sandbox, id xdr.AccountId, asset xdr.Asset) int64 {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return a.availableNative(sb.load(accountKey(id)).Data.Account)
+	}
+	if isIssuer(id, asset) {
+		return math.MaxInt64
+	}
+	return availableTrustline(sb.load(trustlineKey(id, asset.ToTrustLineAsset())).Data.TrustLine)
+}
+
+// maxBuyable returns the amount of the asset the account can receive on
+// top of its buying liabilities.
+func (a *applier) maxBuyable(sb *sandbox, id xdr.AccountId, asset xdr.Asset) int64 {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		account := sb.load(accountKey(id)).Data.Account
+		return math.MaxInt64 - int64(account.Balance) - int64(account.Liabilities().Buying)
+	}
+	if isIssuer(id, asset) {
+		return math.MaxInt64
+	}
+	trustline := sb.load(trustlineKey(id, asset.ToTrustLineAsset())).Data.TrustLine
+	return int64(trustline.Limit) - int64(trustline.Balance) - int64(trustline.Liabilities().Buying)
+}
+
+// sellingAmount returns the amount which can be sold at the price to buy at
+// most the given amount, rounded down.
+func sellingAmount(buying int64, price xdr.Price) int64 {
+	n := new(big.Int).Mul(big.NewInt(buying), big.NewInt(int64(price.D)))
+	n.Quo(n, big.NewInt(int64(price.N)))
+	if !n.IsInt64() {
+		return math.MaxInt64
+	}
+	return n.Int64()
+}
+
+// buyingAmount returns the amount bought by selling the amount at the
+// price, rounded up.
+func buyingAmount(selling int64, price xdr.Price) int64 {
+	n := new(big.Int).Mul(big.NewInt(selling), big.NewInt(int64(price.N)))
+	n.Add(n, big.NewInt(int64(price.D)-1))
+	n.Quo(n, big.NewInt(int64(price.D)))
+	if !n.IsInt64() {
+		return math.MaxInt64
+	}
+	return n.Int64()
+}
+
+// OfferLiabilities returns the liabilities of the offer, which are added to
+// the selling liabilities of the selling asset and the buying liabilities of
+// the buying asset of the seller. Ledger entries seeded with Add must
+// account for the liabilities of the offers.
+func OfferLiabilities(offer xdr.OfferEntry) xdr.Liabilities {
+	return xdr.Liabilities{
+		Buying:  xdr.Int64(buyingAmount(int64(offer.Amount), offer.Price)),
+		Selling: offer.Amount,
+	}
+}
+
+// addOfferLiabilities adds the liabilities of the offer to its seller, sign
+// is -1 to release them.
+func addOfferLiabilities(sb *sandbox, offer xdr.OfferEntry, sign int64) {
+	liabilities := OfferLiabilities(offer)
+	addLiabilities(sb, offer.SellerId, offer.Selling, 0, sign*int64(liabilities.Selling))
+	addLiabilities(sb, offer.SellerId, offer.Buying, sign*int64(liabilities.Buying), 0)
+}
+
+// addLiabilities adds the liabilities to the balance of the asset held by
+// the account. Issuers have no liabilities in their assets.
+func addLiabilities(sb *sandbox, id xdr.AccountId, asset xdr.Asset, buying, selling int64) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		updateAccount(sb, id, func(account *xdr.AccountEntry) {
+			liabilities := &accountExtV1(account).Liabilities
+			liabilities.Buying += xdr.Int64(buying)
+			liabilities.Selling += xdr.Int64(selling)
+		})
+		return
+	}
+	if isIssuer(id, asset) {
+		return
+	}
+	entry := sb.load(trustlineKey(id, asset.ToTrustLineAsset()))
+	if entry == nil {
+		return
+	}
+	liabilities := &trustlineExtV1(entry.Data.TrustLine).Liabilities
+	liabilities.Buying += xdr.Int64(buying)
+	liabilities.Selling += xdr.Int64(selling)
+	sb.store(entry)
+}
@@ -0,0 +1,437 @@
+package txsimulator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/stellar/go/xdr"
+)
+
+func (a *applier) createAccount(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustCreateAccountOp()
+	if op.StartingBalance < 0 || op.Destination.Equals(source) {
+		return xdr.CreateAccountResultCodeCreateAccountMalformed
+	}
+	if sb.load(accountKey(op.Destination)) != nil {
+		return xdr.CreateAccountResultCodeCreateAccountAlreadyExist
+	}
+
+	account := &xdr.AccountEntry{
+		AccountId:  op.Destination,
+		Balance:    op.StartingBalance,
+		SeqNum:     xdr.SequenceNumber(int64(sb.ledger) << 32),
+		Thresholds: xdr.Thresholds{1, 0, 0, 0},
+	}
+	entry := &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: account},
+	}
+
+	// The reserves of the new account are paid by the account sponsoring
+	// its future reserves, or by its starting balance.
+	if sponsor, ok := a.sponsoring[op.Destination.Address()]; ok {
+		sponsorEntry := sb.load(accountKey(sponsor))
+		if a.availableNative(sponsorEntry.Data.Account) < 2*a.config.BaseReserve {
+			return xdr.CreateAccountResultCodeCreateAccountLowReserve
+		}
+		accountExtV2(sponsorEntry.Data.Account).NumSponsoring += 2
+		sb.store(sponsorEntry)
+		accountExtV2(account).NumSponsored = 2
+		setSponsor(entry, &sponsor)
+	} else if int64(account.Balance) < a.minBalance(account) {
+		return xdr.CreateAccountResultCodeCreateAccountLowReserve
+	}
+
+	sourceEntry := sb.load(accountKey(source))
+	if !a.addNative(sourceEntry.Data.Account, -int64(op.StartingBalance)) {
+		return xdr.CreateAccountResultCodeCreateAccountUnderfunded
+	}
+	sb.store(sourceEntry)
+	sb.store(entry)
+	return xdr.CreateAccountResultCodeCreateAccountSuccess
+}
+
+func (a *applier) payment(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustPaymentOp()
+	if op.Amount <= 0 {
+		return xdr.PaymentResultCodePaymentMalformed
+	}
+	destination := op.Destination.ToAccountId()
+	if sb.load(accountKey(destination)) == nil {
+		return xdr.PaymentResultCodePaymentNoDestination
+	}
+
+	// Like stellar-core, the destination is credited before the source is
+	// debited.
+	switch a.addBalance(sb, destination, op.Asset, int64(op.Amount)) {
+	case balanceNoTrust:
+		return xdr.PaymentResultCodePaymentNoTrust
+	case balanceNotAuthorized:
+		return xdr.PaymentResultCodePaymentNotAuthorized
+	case balanceFailed:
+		return xdr.PaymentResultCodePaymentLineFull
+	}
+	switch a.addBalance(sb, source, op.Asset, -int64(op.Amount)) {
+	case balanceNoTrust:
+		return xdr.PaymentResultCodePaymentSrcNoTrust
+	case balanceNotAuthorized:
+		return xdr.PaymentResultCodePaymentSrcNotAuthorized
+	case balanceFailed:
+		return xdr.PaymentResultCodePaymentUnderfunded
+	}
+	return xdr.PaymentResultCodePaymentSuccess
+}
+
+func (a *applier) changeTrust(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustChangeTrustOp()
+	if op.Limit < 0 {
+		return xdr.ChangeTrustResultCodeChangeTrustMalformed
+	}
+
+	var (
+		asset  xdr.TrustLineAsset
+		params *xdr.LiquidityPoolConstantProductParameters
+	)
+	switch op.Line.Type {
+	case xdr.AssetTypeAssetTypeCreditAlphanum4, xdr.AssetTypeAssetTypeCreditAlphanum12:
+		credit := op.Line.ToAsset()
+		if isIssuer(source, credit) {
+			return xdr.ChangeTrustResultCodeChangeTrustMalformed
+		}
+		asset = credit.ToTrustLineAsset()
+	case xdr.AssetTypeAssetTypePoolShare:
+		params = op.Line.LiquidityPool.ConstantProduct
+		if params.Fee != xdr.LiquidityPoolFeeV18 || !params.AssetA.LessThan(params.AssetB) {
+			return xdr.ChangeTrustResultCodeChangeTrustMalformed
+		}
+		id, err := xdr.NewPoolId(params.AssetA, params.AssetB, params.Fee)
+		if err != nil {
+			return xdr.ChangeTrustResultCodeChangeTrustMalformed
+		}
+		asset = poolShareAsset(id)
+	default:
+		return xdr.ChangeTrustResultCodeChangeTrustMalformed
+	}
+
+	key := trustlineKey(source, asset)
+	entry := sb.load(key)
+	if entry != nil {
+		trustline := entry.Data.TrustLine
+		if int64(op.Limit) < int64(trustline.Balance)+int64(trustline.Liabilities().Buying) {
+			return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit
+		}
+		if op.Limit > 0 {
+			trustline.Limit = op.Limit
+			sb.store(entry)
+			return xdr.ChangeTrustResultCodeChangeTrustSuccess
+		}
+		if trustline.Ext.V1 != nil && trustline.Ext.V1.Ext.V2 != nil && trustline.Ext.V1.Ext.V2.LiquidityPoolUseCount > 0 {
+			return xdr.ChangeTrustResultCodeChangeTrustCannotDelete
+		}
+		sb.remove(key)
+		removeSubentry(sb, source, entry.SponsoringID(), multiplier(entry))
+		if params != nil {
+			a.leavePool(sb, source, *asset.LiquidityPoolId, *params)
+		}
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess
+	}
+
+	if op.Limit == 0 {
+		return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit
+	}
+	trustline := &xdr.TrustLineEntry{
+		AccountId: source,
+		Asset:     asset,
+		Limit:     op.Limit,
+	}
+	if params == nil {
+		assetIssuer, _ := issuer(asset.ToAsset())
+		issuerEntry := sb.load(accountKey(assetIssuer))
+		if issuerEntry == nil {
+			return xdr.ChangeTrustResultCodeChangeTrustNoIssuer
+		}
+		flags := xdr.AccountFlags(issuerEntry.Data.Account.Flags)
+		if flags&xdr.AccountFlagsAuthRequiredFlag == 0 {
+			trustline.Flags |= xdr.Uint32(xdr.TrustLineFlagsAuthorizedFlag)
+		}
+		if flags&xdr.AccountFlagsAuthClawbackEnabledFlag != 0 {
+			trustline.Flags |= xdr.Uint32(xdr.TrustLineFlagsTrustlineClawbackEnabledFlag)
+		}
+	} else {
+		trustline.Flags = xdr.Uint32(xdr.TrustLineFlagsAuthorizedFlag)
+		for _, poolAsset := range []xdr.Asset{params.AssetA, params.AssetB} {
+			if poolAsset.Type == xdr.AssetTypeAssetTypeNative || isIssuer(source, poolAsset) {
+				continue
+			}
+			assetEntry := sb.load(trustlineKey(source, poolAsset.ToTrustLineAsset()))
+			if assetEntry == nil {
+				return xdr.ChangeTrustResultCodeChangeTrustTrustLineMissing
+			}
+			if !authorizedToMaintainLiabilities(assetEntry.Data.TrustLine) {
+				return xdr.ChangeTrustResultCodeChangeTrustNotAuthMaintainLiabilities
+			}
+		}
+	}
+
+	entry = &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeTrustline, TrustLine: trustline},
+	}
+	sponsor, ok := a.createSubentry(sb, source, multiplier(entry))
+	if !ok {
+		return xdr.ChangeTrustResultCodeChangeTrustLowReserve
+	}
+	if sponsor != nil {
+		setSponsor(entry, sponsor)
+	}
+	sb.store(entry)
+	if params != nil {
+		a.joinPool(sb, source, *asset.LiquidityPoolId, *params)
+	}
+	return xdr.ChangeTrustResultCodeChangeTrustSuccess
+}
+
+// joinPool records a new pool share trustline of the account, creating the
+// liquidity pool if needed.
+func (a *applier) joinPool(sb *sandbox, id xdr.AccountId, poolID xdr.PoolId, params xdr.LiquidityPoolConstantProductParameters) {
+	entry := sb.load(liquidityPoolKey(poolID))
+	if entry == nil {
+		entry = &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeLiquidityPool,
+				LiquidityPool: &xdr.LiquidityPoolEntry{
+					LiquidityPoolId: poolID,
+					Body: xdr.LiquidityPoolEntryBody{
+						Type:            xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+						ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{Params: params},
+					},
+				},
+			},
+		}
+	}
+	entry.Data.LiquidityPool.Body.ConstantProduct.PoolSharesTrustLineCount++
+	sb.store(entry)
+	a.updatePoolUseCount(sb, id, params, 1)
+}
+
+// leavePool records the removal of a pool share trustline of the account,
+// removing the liquidity pool when it was the last one.
+func (a *applier) leavePool(sb *sandbox, id xdr.AccountId, poolID xdr.PoolId, params xdr.LiquidityPoolConstantProductParameters) {
+	key := liquidityPoolKey(poolID)
+	entry := sb.load(key)
+	cp := entry.Data.LiquidityPool.Body.ConstantProduct
+	cp.PoolSharesTrustLineCount--
+	if cp.PoolSharesTrustLineCount == 0 {
+		sb.remove(key)
+	} else {
+		sb.store(entry)
+	}
+	a.updatePoolUseCount(sb, id, params, -1)
+}
+
+// updatePoolUseCount adds delta to the liquidity pool use count of the
+// trustlines of the account to the assets of the pool.
+func (a *applier) updatePoolUseCount(sb *sandbox, id xdr.AccountId, params xdr.LiquidityPoolConstantProductParameters, delta xdr.Int32) {
+	for _, asset := range []xdr.Asset{params.AssetA, params.AssetB} {
+		if asset.Type == xdr.AssetTypeAssetTypeNative || isIssuer(id, asset) {
+			continue
+		}
+		entry := sb.load(trustlineKey(id, asset.ToTrustLineAsset()))
+		trustlineExtV2(entry.Data.TrustLine).LiquidityPoolUseCount += delta
+		sb.store(entry)
+	}
+}
+
+func (a *applier) setOptions(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustSetOptionsOp()
+	if code := checkSetOptions(source, op); code != xdr.SetOptionsResultCodeSetOptionsSuccess {
+		return code
+	}
+
+	if op.InflationDest != nil && sb.load(accountKey(*op.InflationDest)) == nil {
+		return xdr.SetOptionsResultCodeSetOptionsInvalidInflation
+	}
+
+	entry := sb.load(accountKey(source))
+	account := entry.Data.Account
+	const authFlags = xdr.AccountFlagsAuthRequiredFlag | xdr.AccountFlagsAuthRevocableFlag |
+		xdr.AccountFlagsAuthImmutableFlag | xdr.AccountFlagsAuthClawbackEnabledFlag
+	flags := xdr.AccountFlags(account.Flags)
+	if op.ClearFlags != nil {
+		if flags&xdr.AccountFlagsAuthImmutableFlag != 0 && xdr.AccountFlags(*op.ClearFlags)&authFlags != 0 {
+			return xdr.SetOptionsResultCodeSetOptionsCantChange
+		}
+		flags &^= xdr.AccountFlags(*op.ClearFlags)
+	}
+	if op.SetFlags != nil {
+		if flags&xdr.AccountFlagsAuthImmutableFlag != 0 && xdr.AccountFlags(*op.SetFlags)&authFlags != 0 {
+			return xdr.SetOptionsResultCodeSetOptionsCantChange
+		}
+		flags |= xdr.AccountFlags(*op.SetFlags)
+	}
+	if flags&xdr.AccountFlagsAuthClawbackEnabledFlag != 0 && flags&xdr.AccountFlagsAuthRevocableFlag == 0 {
+		return xdr.SetOptionsResultCodeSetOptionsAuthRevocableRequired
+	}
+	account.Flags = xdr.Uint32(flags)
+
+	if op.InflationDest != nil {
+		account.InflationDest = op.InflationDest
+	}
+	if op.MasterWeight != nil {
+		account.Thresholds[0] = byte(*op.MasterWeight)
+	}
+	if op.LowThreshold != nil {
+		account.Thresholds[1] = byte(*op.LowThreshold)
+	}
+	if op.MedThreshold != nil {
+		account.Thresholds[2] = byte(*op.MedThreshold)
+	}
+	if op.HighThreshold != nil {
+		account.Thresholds[3] = byte(*op.HighThreshold)
+	}
+	if op.HomeDomain != nil {
+		account.HomeDomain = *op.HomeDomain
+	}
+	sb.store(entry)
+
+	if op.Signer != nil {
+		return a.setSigner(sb, source, *op.Signer)
+	}
+	return xdr.SetOptionsResultCodeSetOptionsSuccess
+}
+
+// checkSetOptions validates the set options operation.
+func checkSetOptions(source xdr.AccountId, op xdr.SetOptionsOp) xdr.SetOptionsResultCode {
+	if op.SetFlags != nil && *op.SetFlags&^xdr.MaskAccountFlagsV17 != 0 ||
+		op.ClearFlags != nil && *op.ClearFlags&^xdr.MaskAccountFlagsV17 != 0 {
+		return xdr.SetOptionsResultCodeSetOptionsUnknownFlag
+	}
+	if op.SetFlags != nil && op.ClearFlags != nil && *op.SetFlags&*op.ClearFlags != 0 {
+		return xdr.SetOptionsResultCodeSetOptionsBadFlags
+	}
+	for _, threshold := range []*xdr.Uint32{op.MasterWeight, op.LowThreshold, op.MedThreshold, op.HighThreshold} {
+		if threshold != nil && *threshold > 255 {
+			return xdr.SetOptionsResultCodeSetOptionsThresholdOutOfRange
+		}
+	}
+	if op.Signer != nil {
+		if op.Signer.Weight > 255 || op.Signer.Key.Address() == source.Address() {
+			return xdr.SetOptionsResultCodeSetOptionsBadSigner
+		}
+	}
+	if op.HomeDomain != nil {
+		for _, c := range string(*op.HomeDomain) {
+			if c < 0x20 || c > 0x7e {
+				return xdr.SetOptionsResultCodeSetOptionsInvalidHomeDomain
+			}
+		}
+	}
+	return xdr.SetOptionsResultCodeSetOptionsSuccess
+}
+
+// maxSigners is the maximum number of signers of an account.
+const maxSigners = 20
+
+// setSigner adds, updates or removes, when its weight is 0, a signer of the
+// account. Signers are kept sorted by key.
+func (a *applier) setSigner(sb *sandbox, id xdr.AccountId, signer xdr.Signer) xdr.SetOptionsResultCode {
+	entry := sb.load(accountKey(id))
+	account := entry.Data.Account
+	address := signer.Key.Address()
+	i := sort.Search(len(account.Signers), func(i int) bool {
+		return strings.Compare(account.Signers[i].Key.Address(), address) >= 0
+	})
+	exists := i < len(account.Signers) && account.Signers[i].Key.Address() == address
+
+	switch {
+	case exists && signer.Weight > 0:
+		account.Signers[i].Weight = signer.Weight
+		sb.store(entry)
+	case exists:
+		sponsor := account.SignerSponsoringIDs()[i]
+		account.Signers = append(account.Signers[:i], account.Signers[i+1:]...)
+		if account.Ext.V1 != nil && account.Ext.V1.Ext.V2 != nil {
+			ids := account.Ext.V1.Ext.V2.SignerSponsoringIDs
+			account.Ext.V1.Ext.V2.SignerSponsoringIDs = append(ids[:i], ids[i+1:]...)
+		}
+		sb.store(entry)
+		removeSubentry(sb, id, sponsor, 1)
+	case signer.Weight > 0:
+		if len(account.Signers) >= maxSigners {
+			return xdr.SetOptionsResultCodeSetOptionsTooManySigners
+		}
+		sponsor, ok := a.createSubentry(sb, id, 1)
+		if !ok {
+			return xdr.SetOptionsResultCodeSetOptionsLowReserve
+		}
+		entry = sb.load(accountKey(id))
+		account = entry.Data.Account
+		// The sponsors of the signers are only tracked once the account has
+		// the extension, add it before inserting the signer.
+		if sponsor != nil {
+			accountExtV2(account)
+		}
+		account.Signers = append(account.Signers, xdr.Signer{})
+		copy(account.Signers[i+1:], account.Signers[i:])
+		account.Signers[i] = signer
+		if account.Ext.V1 != nil && account.Ext.V1.Ext.V2 != nil {
+			ids := append(account.Ext.V1.Ext.V2.SignerSponsoringIDs, nil)
+			copy(ids[i+1:], ids[i:])
+			ids[i] = sponsor
+			account.Ext.V1.Ext.V2.SignerSponsoringIDs = ids
+		}
+		sb.store(entry)
+	}
+	return xdr.SetOptionsResultCodeSetOptionsSuccess
+}
+
+func (a *applier) manageData(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustManageDataOp()
+	if len(op.DataName) == 0 {
+		return xdr.ManageDataResultCodeManageDataInvalidName
+	}
+
+	key := dataKey(source, op.DataName)
+	entry := sb.load(key)
+	switch {
+	case op.DataValue == nil && entry == nil:
+		return xdr.ManageDataResultCodeManageDataNameNotFound
+	case op.DataValue == nil:
+		sb.remove(key)
+		removeSubentry(sb, source, entry.SponsoringID(), 1)
+	case entry != nil:
+		entry.Data.Data.DataValue = *op.DataValue
+		sb.store(entry)
+	default:
+		sponsor, ok := a.createSubentry(sb, source, 1)
+		if !ok {
+			return xdr.ManageDataResultCodeManageDataLowReserve
+		}
+		entry = &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeData,
+				Data: &xdr.DataEntry{
+					AccountId: source,
+					DataName:  op.DataName,
+					DataValue: *op.DataValue,
+				},
+			},
+		}
+		if sponsor != nil {
+			setSponsor(entry, sponsor)
+		}
+		sb.store(entry)
+	}
+	return xdr.ManageDataResultCodeManageDataSuccess
+}
+
+func (a *applier) bumpSequence(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustBumpSequenceOp()
+	if op.BumpTo < 0 {
+		return xdr.BumpSequenceResultCodeBumpSequenceBadSeq
+	}
+	updateAccount(sb, source, func(account *xdr.AccountEntry) {
+		if op.BumpTo > account.SeqNum {
+			account.SeqNum = op.BumpTo
+		}
+	})
+	return xdr.BumpSequenceResultCodeBumpSequenceSuccess
+}
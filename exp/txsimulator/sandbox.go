@@ -0,0 +1,144 @@
+package txsimulator
+
+import (
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+// sandbox records the changes of the ledger entries on top of the ledger
+// state, or of a parent sandbox. Operations are applied in a child sandbox of
+// the transaction which is only committed when they succeed.
+type sandbox struct {
+	base    map[string]xdr.LedgerEntry
+	parent  *sandbox
+	ledger  uint32
+	updated map[string]*xdr.LedgerEntry
+	// keys are the keys of the updated entries, in the order they were first
+	// updated.
+	keys []string
+}
+
+func newSandbox(base map[string]xdr.LedgerEntry, ledger uint32) *sandbox {
+	return &sandbox{
+		base:    base,
+		ledger:  ledger,
+		updated: map[string]*xdr.LedgerEntry{},
+	}
+}
+
+func (sb *sandbox) child() *sandbox {
+	return &sandbox{
+		parent:  sb,
+		ledger:  sb.ledger,
+		updated: map[string]*xdr.LedgerEntry{},
+	}
+}
+
+// get returns the current entry of the key, without copying it.
+func (sb *sandbox) get(key string) *xdr.LedgerEntry {
+	for s := sb; s != nil; s = s.parent {
+		if entry, ok := s.updated[key]; ok {
+			return entry
+		}
+		if s.parent == nil {
+			if entry, ok := s.base[key]; ok {
+				return &entry
+			}
+		}
+	}
+	return nil
+}
+
+// load returns a copy of the entry with the given key, or nil if it does
+// not exist. Changes to the copy are recorded with store.
+func (sb *sandbox) load(key xdr.LedgerKey) *xdr.LedgerEntry {
+	entry := sb.get(mustKeyString(key))
+	if entry == nil {
+		return nil
+	}
+	c := mustCopyEntry(*entry)
+	return &c
+}
+
+// store records the entry as created or updated in the ledger.
+func (sb *sandbox) store(entry *xdr.LedgerEntry) {
+	entry.LastModifiedLedgerSeq = xdr.Uint32(sb.ledger)
+	c := mustCopyEntry(*entry)
+	sb.set(mustKeyString(entry.LedgerKey()), &c)
+}
+
+// remove records the entry with the given key as removed from the ledger.
+func (sb *sandbox) remove(key xdr.LedgerKey) {
+	sb.set(mustKeyString(key), nil)
+}
+
+func (sb *sandbox) set(key string, entry *xdr.LedgerEntry) {
+	if _, ok := sb.updated[key]; !ok {
+		sb.keys = append(sb.keys, key)
+	}
+	sb.updated[key] = entry
+}
+
+// commit records the changes of the sandbox in its parent.
+func (sb *sandbox) commit() {
+	for _, key := range sb.keys {
+		sb.parent.set(key, sb.updated[key])
+	}
+}
+
+// changes returns the changes of the ledger entries recorded by the sandbox
+// compared to the ledger state.
+func (sb *sandbox) changes() []ingest.Change {
+	changes := []ingest.Change{}
+	for _, key := range sb.keys {
+		var change ingest.Change
+		if entry, ok := sb.base[key]; ok {
+			pre := mustCopyEntry(entry)
+			change.Pre = &pre
+			change.Type = pre.Data.Type
+		}
+		if entry := sb.updated[key]; entry != nil {
+			post := mustCopyEntry(*entry)
+			change.Post = &post
+			change.Type = post.Data.Type
+		}
+		if change.Pre == nil && change.Post == nil {
+			// The entry was created and removed by the transaction.
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func keyString(key xdr.LedgerKey) (string, error) {
+	b, err := key.MarshalBinary()
+	return string(b), err
+}
+
+func mustKeyString(key xdr.LedgerKey) string {
+	k, err := keyString(key)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// copyEntry returns a deep copy of the entry.
+func copyEntry(entry xdr.LedgerEntry) (xdr.LedgerEntry, error) {
+	var c xdr.LedgerEntry
+	b, err := entry.MarshalBinary()
+	if err != nil {
+		return c, err
+	}
+	err = c.UnmarshalBinary(b)
+	return c, err
+}
+
+func mustCopyEntry(entry xdr.LedgerEntry) xdr.LedgerEntry {
+	c, err := copyEntry(entry)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,94 @@
+package txsimulator
+
+import (
+	"crypto/sha256"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/xdr"
+)
+
+// thresholdLevel is the index of a threshold in xdr.Thresholds.
+type thresholdLevel int
+
+const (
+	thresholdLow    thresholdLevel = 1
+	thresholdMedium thresholdLevel = 2
+	thresholdHigh   thresholdLevel = 3
+)
+
+// threshold returns the threshold the signatures of the source account of
+// the operation must reach.
+func threshold(body xdr.OperationBody) thresholdLevel {
+	switch body.Type {
+	case xdr.OperationTypeAllowTrust, xdr.OperationTypeBumpSequence,
+		xdr.OperationTypeClaimClaimableBalance, xdr.OperationTypeSetTrustLineFlags,
+		xdr.OperationTypeInflation:
+		return thresholdLow
+	case xdr.OperationTypeAccountMerge:
+		return thresholdHigh
+	case xdr.OperationTypeSetOptions:
+		op := body.MustSetOptionsOp()
+		if op.MasterWeight != nil || op.LowThreshold != nil || op.MedThreshold != nil ||
+			op.HighThreshold != nil || op.Signer != nil {
+			return thresholdHigh
+		}
+	}
+	return thresholdMedium
+}
+
+// signed returns whether the signatures of the transaction hash by the
+// signers of the account reach the threshold. All signatures are accepted
+// when the network passphrase is not configured, as the hash is unknown.
+func (a *applier) signed(account *xdr.AccountEntry, hash [32]byte, signatures []xdr.DecoratedSignature, level thresholdLevel) bool {
+	if a.config.NetworkPassphrase == "" {
+		return true
+	}
+
+	var weight uint32
+	found := false
+	if master := account.Thresholds[0]; master > 0 && signedBy(account.AccountId.Address(), hash, signatures) {
+		weight += uint32(master)
+		found = true
+	}
+	for _, signer := range account.Signers {
+		if signer.Weight > 0 && signedBySigner(signer.Key, hash, signatures) {
+			weight += uint32(signer.Weight)
+			found = true
+		}
+	}
+	return found && weight >= uint32(account.Thresholds[level])
+}
+
+// signedBySigner returns whether the hash is signed by the signer, or is
+// the pre-authorized transaction of the signer.
+func signedBySigner(key xdr.SignerKey, hash [32]byte, signatures []xdr.DecoratedSignature) bool {
+	switch key.Type {
+	case xdr.SignerKeyTypeSignerKeyTypeEd25519:
+		return signedBy(key.Address(), hash, signatures)
+	case xdr.SignerKeyTypeSignerKeyTypePreAuthTx:
+		return *key.PreAuthTx == xdr.Uint256(hash)
+	case xdr.SignerKeyTypeSignerKeyTypeHashX:
+		for _, signature := range signatures {
+			if sha256.Sum256(signature.Signature) == [32]byte(*key.HashX) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// signedBy returns whether one of the signatures is a signature of the hash
+// by the key with the given address.
+func signedBy(address string, hash [32]byte, signatures []xdr.DecoratedSignature) bool {
+	kp, err := keypair.ParseAddress(address)
+	if err != nil {
+		return false
+	}
+	hint := xdr.SignatureHint(kp.Hint())
+	for _, signature := range signatures {
+		if signature.Hint == hint && kp.Verify(hash[:], signature.Signature) == nil {
+			return true
+		}
+	}
+	return false
+}
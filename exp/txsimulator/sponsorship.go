@@ -0,0 +1,168 @@
+package txsimulator
+
+import (
+	"github.com/stellar/go/xdr"
+)
+
+func (a *applier) beginSponsoringFutureReserves(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustBeginSponsoringFutureReservesOp()
+	if op.SponsoredId.Equals(source) {
+		return xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesMalformed
+	}
+	if _, ok := a.sponsoring[op.SponsoredId.Address()]; ok {
+		return xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesAlreadySponsored
+	}
+	if _, ok := a.sponsoring[source.Address()]; ok {
+		return xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesRecursive
+	}
+	for _, sponsor := range a.sponsoring {
+		if sponsor.Equals(op.SponsoredId) {
+			return xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesRecursive
+		}
+	}
+	a.sponsoring[op.SponsoredId.Address()] = source
+	return xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesSuccess
+}
+
+func (a *applier) endSponsoringFutureReserves(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	if _, ok := a.sponsoring[source.Address()]; !ok {
+		return xdr.EndSponsoringFutureReservesResultCodeEndSponsoringFutureReservesNotSponsored
+	}
+	delete(a.sponsoring, source.Address())
+	return xdr.EndSponsoringFutureReservesResultCodeEndSponsoringFutureReservesSuccess
+}
+
+func (a *applier) revokeSponsorship(sb *sandbox, source xdr.AccountId, _ int, body xdr.OperationBody) interface{} {
+	op := body.MustRevokeSponsorshipOp()
+	if op.Type == xdr.RevokeSponsorshipTypeRevokeSponsorshipSigner {
+		return a.revokeSignerSponsorship(sb, source, *op.Signer)
+	}
+
+	key := *op.LedgerKey
+	if key.Type == xdr.LedgerEntryTypeLiquidityPool {
+		return xdr.RevokeSponsorshipResultCodeRevokeSponsorshipMalformed
+	}
+	entry := sb.load(key)
+	if entry == nil {
+		return xdr.RevokeSponsorshipResultCodeRevokeSponsorshipDoesNotExist
+	}
+
+	// Claimable balances have no owner, they are always sponsored.
+	var owner *xdr.AccountId
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeAccount:
+		owner = &entry.Data.Account.AccountId
+	case xdr.LedgerEntryTypeTrustline:
+		owner = &entry.Data.TrustLine.AccountId
+	case xdr.LedgerEntryTypeOffer:
+		owner = &entry.Data.Offer.SellerId
+	case xdr.LedgerEntryTypeData:
+		owner = &entry.Data.Data.AccountId
+	}
+	oldSponsor := entry.SponsoringID()
+	newSponsor, code := a.transferSponsorship(sb, source, owner, oldSponsor, multiplier(entry))
+	if code != xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess {
+		return code
+	}
+	// Reload the entry, the owner account may have been updated.
+	entry = sb.load(key)
+	setSponsor(entry, newSponsor)
+	if entry.Data.Type == xdr.LedgerEntryTypeAccount {
+		updateSponsoredCount(entry.Data.Account, oldSponsor, newSponsor, multiplier(entry))
+	} else if owner != nil {
+		updateAccount(sb, *owner, func(account *xdr.AccountEntry) {
+			updateSponsoredCount(account, oldSponsor, newSponsor, multiplier(entry))
+		})
+	}
+	sb.store(entry)
+	return xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess
+}
+
+func (a *applier) revokeSignerSponsorship(sb *sandbox, source xdr.AccountId, op xdr.RevokeSponsorshipOpSigner) interface{} {
+	entry := sb.load(accountKey(op.AccountId))
+	if entry == nil {
+		return xdr.RevokeSponsorshipResultCodeRevokeSponsorshipDoesNotExist
+	}
+	index := -1
+	for i, signer := range entry.Data.Account.Signers {
+		if signer.Key.Address() == op.SignerKey.Address() {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return xdr.RevokeSponsorshipResultCodeRevokeSponsorshipDoesNotExist
+	}
+
+	oldSponsor := entry.Data.Account.SignerSponsoringIDs()[index]
+	newSponsor, code := a.transferSponsorship(sb, source, &op.AccountId, oldSponsor, 1)
+	if code != xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess {
+		return code
+	}
+	// Reload the account, it may have been updated as a sponsor.
+	entry = sb.load(accountKey(op.AccountId))
+	account := entry.Data.Account
+	if newSponsor != nil || account.Ext.V1 != nil && account.Ext.V1.Ext.V2 != nil {
+		accountExtV2(account).SignerSponsoringIDs[index] = newSponsor
+	}
+	updateSponsoredCount(account, oldSponsor, newSponsor, 1)
+	sb.store(entry)
+	return xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess
+}
+
+// transferSponsorship checks the source account can revoke the sponsorship
+// of an entry of the owner, nil for claimable balances, and moves the
+// reserves from the old sponsor to the new one: the account sponsoring the
+// future reserves of the source account, or the owner when there is none. It
+// returns the new sponsor, nil when the owner pays the reserves.
+func (a *applier) transferSponsorship(sb *sandbox, source xdr.AccountId, owner, oldSponsor *xdr.AccountId, mult int64) (*xdr.AccountId, xdr.RevokeSponsorshipResultCode) {
+	switch {
+	case oldSponsor != nil && !oldSponsor.Equals(source):
+		return nil, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipNotSponsor
+	case oldSponsor == nil && (owner == nil || !owner.Equals(source)):
+		return nil, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipNotSponsor
+	}
+
+	var newSponsor *xdr.AccountId
+	if sponsor, ok := a.sponsoring[source.Address()]; ok && (owner == nil || !owner.Equals(sponsor)) {
+		newSponsor = &sponsor
+	}
+	if oldSponsor == nil && newSponsor == nil {
+		return nil, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess
+	}
+
+	if newSponsor != nil {
+		entry := sb.load(accountKey(*newSponsor))
+		if a.availableNative(entry.Data.Account) < mult*a.config.BaseReserve {
+			return nil, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipLowReserve
+		}
+		accountExtV2(entry.Data.Account).NumSponsoring += xdr.Uint32(mult)
+		sb.store(entry)
+	} else {
+		if owner == nil {
+			return nil, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipOnlyTransferable
+		}
+		// The owner pays the reserves once they stop being sponsored.
+		entry := sb.load(accountKey(*owner))
+		if a.availableNative(entry.Data.Account) < mult*a.config.BaseReserve {
+			return nil, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipLowReserve
+		}
+	}
+	if oldSponsor != nil {
+		updateAccount(sb, *oldSponsor, func(account *xdr.AccountEntry) {
+			accountExtV2(account).NumSponsoring -= xdr.Uint32(mult)
+		})
+	}
+	return newSponsor, xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess
+}
+
+// updateSponsoredCount updates the sponsored counter of the account when
+// the sponsor of one of its entries changed.
+func updateSponsoredCount(account *xdr.AccountEntry, oldSponsor, newSponsor *xdr.AccountId, mult int64) {
+	switch {
+	case oldSponsor == nil && newSponsor != nil:
+		accountExtV2(account).NumSponsored += xdr.Uint32(mult)
+	case oldSponsor != nil && newSponsor == nil:
+		accountExtV2(account).NumSponsored -= xdr.Uint32(mult)
+	}
+}
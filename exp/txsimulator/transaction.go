@@ -0,0 +1,334 @@
+package txsimulator
+
+import (
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/protocols/horizon/codes"
+	"github.com/stellar/go/xdr"
+)
+
+// applier applies a transaction.
+type applier struct {
+	config    Config
+	closeTime int64
+	envelope  xdr.TransactionEnvelope
+	// idPool is the highest offer ID, incremented for every new offer.
+	idPool int64
+	// sponsoring maps the addresses of the accounts whose future reserves
+	// are sponsored to their sponsor.
+	sponsoring map[string]xdr.AccountId
+}
+
+// operationFunc applies an operation of the source account and returns
+// its result, which is either a result code or a result struct of the
+// operation type.
+type operationFunc func(a *applier, sb *sandbox, source xdr.AccountId, index int, op xdr.OperationBody) interface{}
+
+var operationFuncs = map[xdr.OperationType]operationFunc{
+	xdr.OperationTypeCreateAccount:                 (*applier).createAccount,
+	xdr.OperationTypePayment:                       (*applier).payment,
+	xdr.OperationTypeChangeTrust:                   (*applier).changeTrust,
+	xdr.OperationTypeSetOptions:                    (*applier).setOptions,
+	xdr.OperationTypeManageData:                    (*applier).manageData,
+	xdr.OperationTypeBumpSequence:                  (*applier).bumpSequence,
+	xdr.OperationTypeManageSellOffer:               (*applier).manageSellOffer,
+	xdr.OperationTypeCreateClaimableBalance:        (*applier).createClaimableBalance,
+	xdr.OperationTypeClaimClaimableBalance:         (*applier).claimClaimableBalance,
+	xdr.OperationTypeBeginSponsoringFutureReserves: (*applier).beginSponsoringFutureReserves,
+	xdr.OperationTypeEndSponsoringFutureReserves:   (*applier).endSponsoringFutureReserves,
+	xdr.OperationTypeRevokeSponsorship:             (*applier).revokeSponsorship,
+	xdr.OperationTypeLiquidityPoolDeposit:          (*applier).liquidityPoolDeposit,
+	xdr.OperationTypeLiquidityPoolWithdraw:         (*applier).liquidityPoolWithdraw,
+}
+
+// apply applies the transaction, or the inner transaction of a fee bump
+// transaction with its fee paid by the fee account.
+func (a *applier) apply(sb *sandbox) (Result, error) {
+	hash, err := a.hash(a.envelope)
+	if err != nil {
+		return Result{}, err
+	}
+	if !a.envelope.IsFeeBump() {
+		source := a.envelope.SourceAccount().ToAccountId()
+		fee := int64(len(a.envelope.Operations())) * a.config.BaseFee
+		result := xdr.TransactionResult{FeeCharged: xdr.Int64(fee)}
+		var included bool
+		result.Result.Code, result.Result.Results, included = a.applyTransaction(sb, a.envelope, hash, source, fee, true)
+		return newResult(result, included), nil
+	}
+
+	inner := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1:   a.envelope.FeeBump.Tx.InnerTx.V1,
+	}
+	innerHash, err := a.hash(inner)
+	if err != nil {
+		return Result{}, err
+	}
+	feeSource := a.envelope.FeeBumpAccount().ToAccountId()
+	fee := int64(len(inner.Operations())+1) * a.config.BaseFee
+	result := xdr.TransactionResult{FeeCharged: xdr.Int64(fee)}
+	if code := a.checkFeeBump(sb, hash, feeSource, fee); code != xdr.TransactionResultCodeTxSuccess {
+		result.Result.Code = code
+		return newResult(result, false), nil
+	}
+
+	innerCode, results, included := a.applyTransaction(sb, inner, innerHash, feeSource, fee, false)
+	result.Result.Code = xdr.TransactionResultCodeTxFeeBumpInnerFailed
+	if innerCode == xdr.TransactionResultCodeTxSuccess {
+		result.Result.Code = xdr.TransactionResultCodeTxFeeBumpInnerSuccess
+	}
+	result.Result.InnerResultPair = &xdr.InnerTransactionResultPair{
+		TransactionHash: xdr.Hash(innerHash),
+		Result: xdr.InnerTransactionResult{
+			Result: xdr.InnerTransactionResultResult{Code: innerCode, Results: results},
+		},
+	}
+	return newResult(result, included), nil
+}
+
+// hash returns the hash of the transaction signed by its signers. It is
+// only known when the network passphrase is configured.
+func (a *applier) hash(envelope xdr.TransactionEnvelope) ([32]byte, error) {
+	if a.config.NetworkPassphrase == "" {
+		return [32]byte{}, nil
+	}
+	return network.HashTransactionInEnvelope(envelope, a.config.NetworkPassphrase)
+}
+
+// applyTransaction validates the transaction and applies it with its fee
+// paid by the fee source. It returns the result code of the transaction,
+// the results of its operations if they were checked and whether the
+// transaction is included in the ledger. Rejected transactions do not
+// change the sandbox. The fee of the other transactions is charged and
+// their sequence number consumed, the changes of their operations are only
+// recorded when all of them succeed.
+func (a *applier) applyTransaction(sb *sandbox, envelope xdr.TransactionEnvelope, hash [32]byte, feeSource xdr.AccountId, fee int64, checkFee bool) (xdr.TransactionResultCode, *[]xdr.OperationResult, bool) {
+	if code := a.check(sb, envelope, hash, fee, checkFee); code != xdr.TransactionResultCodeTxSuccess {
+		return code, nil, false
+	}
+	if results, ok := a.checkOperations(sb, envelope, hash); !ok {
+		return xdr.TransactionResultCodeTxFailed, &results, false
+	}
+
+	source := envelope.SourceAccount().ToAccountId()
+	updateAccount(sb, feeSource, func(account *xdr.AccountEntry) {
+		account.Balance -= xdr.Int64(fee)
+	})
+	updateAccount(sb, source, func(account *xdr.AccountEntry) {
+		account.SeqNum = xdr.SequenceNumber(envelope.SeqNum())
+	})
+
+	ops := envelope.Operations()
+	opsSandbox := sb.child()
+	idPool := a.idPool
+	results := make([]xdr.OperationResult, len(ops))
+	code := xdr.TransactionResultCodeTxSuccess
+	for i, op := range ops {
+		opSource := source
+		if op.SourceAccount != nil {
+			opSource = op.SourceAccount.ToAccountId()
+		}
+		results[i] = a.applyOperation(opsSandbox, opSource, i, op.Body)
+		if operationCode(results[i]) != codes.OpSuccess {
+			code = xdr.TransactionResultCodeTxFailed
+		}
+	}
+	if code == xdr.TransactionResultCodeTxSuccess && len(a.sponsoring) > 0 {
+		// Like stellar-core, the results of the operations are not
+		// reported when the sponsorships are not ended.
+		a.idPool = idPool
+		return xdr.TransactionResultCodeTxBadSponsorship, nil, true
+	}
+	if code == xdr.TransactionResultCodeTxSuccess {
+		opsSandbox.commit()
+	} else {
+		a.idPool = idPool
+	}
+	return code, &results, true
+}
+
+// check validates the transaction against the ledger. The fee is not
+// checked for the inner transactions of fee bump transactions.
+func (a *applier) check(sb *sandbox, envelope xdr.TransactionEnvelope, hash [32]byte, fee int64, checkFee bool) xdr.TransactionResultCode {
+	if len(envelope.Operations()) == 0 {
+		return xdr.TransactionResultCodeTxMissingOperation
+	}
+	if tb := envelope.TimeBounds(); tb != nil {
+		if a.closeTime < int64(tb.MinTime) {
+			return xdr.TransactionResultCodeTxTooEarly
+		}
+		if tb.MaxTime != 0 && a.closeTime > int64(tb.MaxTime) {
+			return xdr.TransactionResultCodeTxTooLate
+		}
+	}
+	if checkFee && int64(envelope.Fee()) < fee {
+		return xdr.TransactionResultCodeTxInsufficientFee
+	}
+
+	entry := sb.load(accountKey(envelope.SourceAccount().ToAccountId()))
+	if entry == nil {
+		return xdr.TransactionResultCodeTxNoAccount
+	}
+	account := entry.Data.Account
+	if envelope.SeqNum() != int64(account.SeqNum)+1 {
+		return xdr.TransactionResultCodeTxBadSeq
+	}
+	if !a.signed(account, hash, envelope.Signatures(), thresholdLow) {
+		return xdr.TransactionResultCodeTxBadAuth
+	}
+	if checkFee && a.availableNative(account) < fee {
+		return xdr.TransactionResultCodeTxInsufficientBalance
+	}
+	return xdr.TransactionResultCodeTxSuccess
+}
+
+// checkFeeBump validates the fee bump transaction against the ledger.
+func (a *applier) checkFeeBump(sb *sandbox, hash [32]byte, feeSource xdr.AccountId, fee int64) xdr.TransactionResultCode {
+	if a.envelope.FeeBumpFee() < fee {
+		return xdr.TransactionResultCodeTxInsufficientFee
+	}
+	entry := sb.load(accountKey(feeSource))
+	if entry == nil {
+		return xdr.TransactionResultCodeTxNoAccount
+	}
+	if !a.signed(entry.Data.Account, hash, a.envelope.FeeBumpSignatures(), thresholdLow) {
+		return xdr.TransactionResultCodeTxBadAuth
+	}
+	if a.availableNative(entry.Data.Account) < fee {
+		return xdr.TransactionResultCodeTxInsufficientBalance
+	}
+	return xdr.TransactionResultCodeTxSuccess
+}
+
+// checkOperations verifies the signatures of the source accounts of the
+// operations. Like stellar-core, the transaction is rejected when one of
+// them is not authorized, with op_bad_auth as its result. The operations
+// of missing accounts fail when they are applied.
+func (a *applier) checkOperations(sb *sandbox, envelope xdr.TransactionEnvelope, hash [32]byte) ([]xdr.OperationResult, bool) {
+	ops := envelope.Operations()
+	results := make([]xdr.OperationResult, len(ops))
+	ok := true
+	for i, op := range ops {
+		source := envelope.SourceAccount().ToAccountId()
+		if op.SourceAccount != nil {
+			source = op.SourceAccount.ToAccountId()
+		}
+		entry := sb.load(accountKey(source))
+		if entry != nil && !a.signed(entry.Data.Account, hash, envelope.Signatures(), threshold(op.Body)) {
+			results[i] = xdr.OperationResult{Code: xdr.OperationResultCodeOpBadAuth}
+			ok = false
+			continue
+		}
+		tr, err := xdr.NewOperationResultTr(op.Body.Type, newOperationResult(op.Body.Type))
+		if err != nil {
+			panic(err)
+		}
+		results[i] = xdr.OperationResult{Code: xdr.OperationResultCodeOpInner, Tr: &tr}
+	}
+	return results, ok
+}
+
+// applyOperation applies the operation in a child sandbox committed when
+// the operation succeeds.
+func (a *applier) applyOperation(sb *sandbox, source xdr.AccountId, index int, body xdr.OperationBody) xdr.OperationResult {
+	opSandbox := sb.child()
+	if opSandbox.load(accountKey(source)) == nil {
+		return xdr.OperationResult{Code: xdr.OperationResultCodeOpNoAccount}
+	}
+	r := operationFuncs[body.Type](a, opSandbox, source, index, body)
+	tr, err := xdr.NewOperationResultTr(body.Type, newOperationResult(r))
+	if err != nil {
+		panic(err)
+	}
+	result := xdr.OperationResult{Code: xdr.OperationResultCodeOpInner, Tr: &tr}
+	if operationCode(result) == codes.OpSuccess {
+		opSandbox.commit()
+	}
+	return result
+}
+
+// newOperationResult returns the result struct of the result code returned
+// by an operationFunc. Result structs are returned as is. The result of an
+// operation type is the successful result of an operation which was
+// validated but not applied.
+func newOperationResult(r interface{}) interface{} {
+	switch code := r.(type) {
+	case xdr.OperationType:
+		switch code {
+		case xdr.OperationTypeManageSellOffer:
+			return xdr.ManageSellOfferResult{Success: &xdr.ManageOfferSuccessResult{}}
+		case xdr.OperationTypeCreateClaimableBalance:
+			return xdr.CreateClaimableBalanceResult{BalanceId: &xdr.ClaimableBalanceId{V0: &xdr.Hash{}}}
+		}
+		// The other success codes are 0.
+		return newOperationResult(successCodes[code])
+	case xdr.CreateAccountResultCode:
+		return xdr.CreateAccountResult{Code: code}
+	case xdr.PaymentResultCode:
+		return xdr.PaymentResult{Code: code}
+	case xdr.ChangeTrustResultCode:
+		return xdr.ChangeTrustResult{Code: code}
+	case xdr.SetOptionsResultCode:
+		return xdr.SetOptionsResult{Code: code}
+	case xdr.ManageDataResultCode:
+		return xdr.ManageDataResult{Code: code}
+	case xdr.BumpSequenceResultCode:
+		return xdr.BumpSequenceResult{Code: code}
+	case xdr.ManageSellOfferResultCode:
+		return xdr.ManageSellOfferResult{Code: code}
+	case xdr.CreateClaimableBalanceResultCode:
+		return xdr.CreateClaimableBalanceResult{Code: code}
+	case xdr.ClaimClaimableBalanceResultCode:
+		return xdr.ClaimClaimableBalanceResult{Code: code}
+	case xdr.BeginSponsoringFutureReservesResultCode:
+		return xdr.BeginSponsoringFutureReservesResult{Code: code}
+	case xdr.EndSponsoringFutureReservesResultCode:
+		return xdr.EndSponsoringFutureReservesResult{Code: code}
+	case xdr.RevokeSponsorshipResultCode:
+		return xdr.RevokeSponsorshipResult{Code: code}
+	case xdr.LiquidityPoolDepositResultCode:
+		return xdr.LiquidityPoolDepositResult{Code: code}
+	case xdr.LiquidityPoolWithdrawResultCode:
+		return xdr.LiquidityPoolWithdrawResult{Code: code}
+	}
+	return r
+}
+
+// successCodes are the success codes of the operations whose successful
+// result is only a code.
+var successCodes = map[xdr.OperationType]interface{}{
+	xdr.OperationTypeCreateAccount:                 xdr.CreateAccountResultCodeCreateAccountSuccess,
+	xdr.OperationTypePayment:                       xdr.PaymentResultCodePaymentSuccess,
+	xdr.OperationTypeChangeTrust:                   xdr.ChangeTrustResultCodeChangeTrustSuccess,
+	xdr.OperationTypeSetOptions:                    xdr.SetOptionsResultCodeSetOptionsSuccess,
+	xdr.OperationTypeManageData:                    xdr.ManageDataResultCodeManageDataSuccess,
+	xdr.OperationTypeBumpSequence:                  xdr.BumpSequenceResultCodeBumpSequenceSuccess,
+	xdr.OperationTypeClaimClaimableBalance:         xdr.ClaimClaimableBalanceResultCodeClaimClaimableBalanceSuccess,
+	xdr.OperationTypeBeginSponsoringFutureReserves: xdr.BeginSponsoringFutureReservesResultCodeBeginSponsoringFutureReservesSuccess,
+	xdr.OperationTypeEndSponsoringFutureReserves:   xdr.EndSponsoringFutureReservesResultCodeEndSponsoringFutureReservesSuccess,
+	xdr.OperationTypeRevokeSponsorship:             xdr.RevokeSponsorshipResultCodeRevokeSponsorshipSuccess,
+	xdr.OperationTypeLiquidityPoolDeposit:          xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositSuccess,
+	xdr.OperationTypeLiquidityPoolWithdraw:         xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawSuccess,
+}
+
+// operationCode returns the Horizon representation of the result code of
+// the operation.
+func operationCode(result xdr.OperationResult) string {
+	code, _ := codes.ForOperationResult(result)
+	return code
+}
+
+func newResult(xdrResult xdr.TransactionResult, included bool) Result {
+	result := Result{
+		Successful: xdrResult.Successful(),
+		Included:   included,
+		XDR:        xdrResult,
+	}
+	result.TransactionCode, _ = codes.String(xdrResult.Result.Code)
+	if results, ok := xdrResult.OperationResults(); ok {
+		for _, r := range results {
+			result.OperationCodes = append(result.OperationCodes, operationCode(r))
+		}
+	}
+	return result
+}
@@ -0,0 +1,409 @@
+// Package txsubmitter submits transactions built from operations through a
+// pool of channel accounts. Every transaction is sourced from a channel
+// account, whose sequence number is tracked locally, so that operations of the
+// same source accounts can be submitted concurrently. Transactions which are
+// not included in a ledger because their fee is too low are wrapped in a fee
+// bump transaction priced from the fee stats of Horizon.
+package txsubmitter
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/txnbuild"
+)
+
+const (
+	// DefaultTimeout is the default validity of the transactions whose
+	// requests do not set timebounds.
+	DefaultTimeout = 5 * time.Minute
+	// DefaultFeeBumpAfter is the default duration after which a pending
+	// transaction is fee bumped.
+	DefaultFeeBumpAfter = 30 * time.Second
+	// DefaultMaxFeeMultiplier is the default ratio between Config.MaxFee and
+	// Config.BaseFee.
+	DefaultMaxFeeMultiplier = 100
+
+	// maxBadSequenceRetries is the number of times a transaction is rebuilt
+	// after failing with tx_bad_seq.
+	maxBadSequenceRetries = 3
+)
+
+var (
+	// ErrExpired is returned when the transaction of a request was not
+	// included in a ledger before the end of its timebounds.
+	ErrExpired = errors.New("transaction expired")
+	// ErrMaxFee is returned when the transaction of a request was not
+	// included in a ledger and its fee cannot be increased without exceeding
+	// Config.MaxFee.
+	ErrMaxFee = errors.New("fee cannot be increased above the maximum fee")
+	// ErrTransactionFailed is returned when the transaction of a request was
+	// included in a ledger but failed.
+	ErrTransactionFailed = errors.New("transaction failed")
+
+	errBadSequence = errors.New("bad sequence number")
+)
+
+// Config configures a Submitter.
+type Config struct {
+	// Horizon is the client used to load the channel accounts, fetch fee
+	// stats and submit transactions.
+	Horizon horizonclient.ClientInterface
+	// NetworkPassphrase is the passphrase of the network transactions are
+	// submitted to.
+	NetworkPassphrase string
+	// Channels are the keypairs of the channel accounts. The number of
+	// channels is the maximum number of transactions pending at once.
+	Channels []*keypair.Full
+	// FeeAccount pays the fees of fee bump transactions. When nil the fee
+	// bumps are paid by the channel account of the transaction.
+	FeeAccount *keypair.Full
+	// BaseFee is the fee per operation of the transactions when they are
+	// first submitted. Defaults to txnbuild.MinBaseFee.
+	BaseFee int64
+	// MaxFee is the maximum fee per operation of fee bump transactions.
+	// Defaults to BaseFee * DefaultMaxFeeMultiplier.
+	MaxFee int64
+	// Timeout is the validity of the transactions whose requests do not set
+	// timebounds. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// FeeBumpAfter is the duration after which a transaction not included in
+	// a ledger yet is fee bumped. Defaults to DefaultFeeBumpAfter.
+	FeeBumpAfter time.Duration
+	// SkipMemoRequiredCheck disables the SEP-29 memo required check of the
+	// payment destinations.
+	SkipMemoRequiredCheck bool
+}
+
+// Request is a set of operations to submit in a single transaction.
+type Request struct {
+	// Operations are the operations of the transaction. Operations without a
+	// source account are sourced from the channel account.
+	Operations []txnbuild.Operation
+	// Signers sign the transaction in addition to the channel account,
+	// typically the source accounts of the operations.
+	Signers []*keypair.Full
+	// Memo is the memo of the transaction.
+	Memo txnbuild.Memo
+	// Timebounds are the timebounds of the transaction. When not set the
+	// transaction is valid for Config.Timeout.
+	Timebounds txnbuild.Timebounds
+}
+
+// Result is the outcome of a submitted request.
+type Result struct {
+	// Hash is the hash of the transaction built for the request. Fee bump
+	// transactions wrap this transaction, so the hash identifies the result
+	// of the request whether it was fee bumped or not.
+	Hash string
+	// FeeBumped is true when the transaction was wrapped in a fee bump
+	// transaction.
+	FeeBumped bool
+	// Transaction is the transaction resource returned by Horizon, the fee
+	// bump transaction when FeeBumped is true. It is only set when the
+	// transaction was included in a ledger.
+	Transaction hProtocol.Transaction
+}
+
+// channel is a channel account of the pool.
+type channel struct {
+	keypair *keypair.Full
+	account txnbuild.SimpleAccount
+	// synced is false when the sequence number of the account must be loaded
+	// from Horizon before building a transaction.
+	synced bool
+}
+
+// Submitter submits requests through a pool of channel accounts. It is safe
+// for concurrent use, Submit blocks until a channel account is available.
+type Submitter struct {
+	config   Config
+	channels chan *channel
+}
+
+// New returns a Submitter with the given config.
+func New(config Config) (*Submitter, error) {
+	if config.Horizon == nil {
+		return nil, errors.New("horizon client is required")
+	}
+	if config.NetworkPassphrase == "" {
+		return nil, errors.New("network passphrase is required")
+	}
+	if len(config.Channels) == 0 {
+		return nil, errors.New("at least one channel account is required")
+	}
+	if config.BaseFee == 0 {
+		config.BaseFee = txnbuild.MinBaseFee
+	}
+	if config.BaseFee < txnbuild.MinBaseFee {
+		return nil, errors.Errorf("base fee cannot be lower than %d", txnbuild.MinBaseFee)
+	}
+	if config.MaxFee == 0 {
+		config.MaxFee = config.BaseFee * DefaultMaxFeeMultiplier
+	}
+	if config.MaxFee < config.BaseFee {
+		return nil, errors.New("max fee cannot be lower than base fee")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.FeeBumpAfter == 0 {
+		config.FeeBumpAfter = DefaultFeeBumpAfter
+	}
+
+	s := &Submitter{
+		config:   config,
+		channels: make(chan *channel, len(config.Channels)),
+	}
+	for _, kp := range config.Channels {
+		s.channels <- &channel{
+			keypair: kp,
+			account: txnbuild.SimpleAccount{AccountID: kp.Address()},
+		}
+	}
+	return s, nil
+}
+
+// Submit builds a transaction with the operations of the request, sourced
+// from an available channel account, and submits it. It returns when the
+// transaction is included in a ledger or when it cannot be included anymore.
+//
+// Transactions rejected with tx_insufficient_fee, or still pending after
+// Config.FeeBumpAfter, are wrapped in fee bump transactions until they are
+// included, they expire (ErrExpired) or their fee reaches Config.MaxFee
+// (ErrMaxFee). Transactions rejected with tx_bad_seq are rebuilt after
+// reloading the sequence number of the channel account. Transactions included
+// in a ledger but failed return ErrTransactionFailed or, if the failure was
+// reported by the submission, the *horizonclient.Error with the result codes.
+func (s *Submitter) Submit(ctx context.Context, request Request) (Result, error) {
+	if len(request.Operations) == 0 {
+		return Result{}, errors.New("request has no operations")
+	}
+
+	var ch *channel
+	select {
+	case ch = <-s.channels:
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+	defer func() { s.channels <- ch }()
+
+	for attempt := 0; ; attempt++ {
+		if !ch.synced {
+			if err := s.syncChannel(ctx, ch); err != nil {
+				return Result{}, err
+			}
+		}
+		tx, err := s.buildTransaction(ch, request)
+		if err != nil {
+			return Result{}, err
+		}
+		result, err := s.submitTransaction(ctx, ch, tx)
+		if err == errBadSequence && attempt < maxBadSequenceRetries {
+			continue
+		}
+		return result, err
+	}
+}
+
+// syncChannel loads the sequence number of the channel account from Horizon.
+func (s *Submitter) syncChannel(ctx context.Context, ch *channel) error {
+	account, err := s.config.Horizon.AccountDetailWithContext(
+		ctx,
+		horizonclient.AccountRequest{AccountID: ch.account.AccountID},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "could not load channel account %s", ch.account.AccountID)
+	}
+	sequence, err := account.GetSequenceNumber()
+	if err != nil {
+		return errors.Wrapf(err, "invalid sequence number of channel account %s", ch.account.AccountID)
+	}
+	ch.account.Sequence = sequence
+	ch.synced = true
+	return nil
+}
+
+// buildTransaction builds and signs the transaction of the request using the
+// next sequence number of the channel account.
+func (s *Submitter) buildTransaction(ch *channel, request Request) (*txnbuild.Transaction, error) {
+	timebounds := request.Timebounds
+	if timebounds == (txnbuild.Timebounds{}) {
+		timebounds = txnbuild.NewTimeout(int64(s.config.Timeout / time.Second))
+	}
+	account := ch.account
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &account,
+		IncrementSequenceNum: true,
+		Operations:           request.Operations,
+		BaseFee:              s.config.BaseFee,
+		Memo:                 request.Memo,
+		Timebounds:           timebounds,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not build transaction")
+	}
+	signers := append([]*keypair.Full{ch.keypair}, request.Signers...)
+	tx, err = tx.Sign(s.config.NetworkPassphrase, signers...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not sign transaction")
+	}
+	// The sequence number is consumed unless the transaction is rejected, in
+	// which case the channel is synced again.
+	ch.account = account
+	return tx, nil
+}
+
+// submitTransaction submits the transaction, fee bumping it until it is
+// included in a ledger.
+func (s *Submitter) submitTransaction(ctx context.Context, ch *channel, tx *txnbuild.Transaction) (Result, error) {
+	hash, err := tx.HashHex(s.config.NetworkPassphrase)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "could not hash transaction")
+	}
+	result := Result{Hash: hash}
+	maxTime := tx.Timebounds().MaxTime
+	fee := tx.BaseFee()
+	var feeBump *txnbuild.FeeBumpTransaction
+
+	for {
+		if maxTime != 0 && time.Now().Unix() > maxTime {
+			return s.lookupTransaction(ctx, ch, result, ErrExpired)
+		}
+
+		submitCtx, cancel := context.WithTimeout(ctx, s.config.FeeBumpAfter)
+		opts := horizonclient.SubmitTxOpts{SkipMemoRequiredCheck: s.config.SkipMemoRequiredCheck}
+		var resp hProtocol.Transaction
+		if feeBump == nil {
+			resp, err = s.config.Horizon.SubmitTransactionWithOptionsWithContext(submitCtx, tx, opts)
+		} else {
+			resp, err = s.config.Horizon.SubmitFeeBumpTransactionWithOptionsWithContext(submitCtx, feeBump, opts)
+		}
+		timedOut := submitCtx.Err() == context.DeadlineExceeded
+		cancel()
+
+		if err == nil {
+			result.Transaction = resp
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			ch.synced = false
+			return result, ctx.Err()
+		}
+
+		replace := false
+		switch transactionCode(err) {
+		case "tx_insufficient_fee":
+		case "tx_bad_seq":
+			ch.synced = false
+			return result, errBadSequence
+		case "tx_too_late":
+			ch.synced = false
+			return result, ErrExpired
+		case "tx_failed", "tx_fee_bump_inner_failed":
+			return result, err
+		default:
+			horizonError := horizonclient.GetError(err)
+			pending := timedOut || (horizonError != nil && horizonError.Response.StatusCode == http.StatusGatewayTimeout)
+			if !pending {
+				ch.synced = false
+				return result, err
+			}
+			// The transaction is pending, it may have been included in the
+			// meantime.
+			transaction, lookupErr := s.config.Horizon.TransactionDetailWithContext(ctx, hash)
+			if lookupErr == nil {
+				return included(result, transaction)
+			}
+			// Stellar Core only replaces a pending transaction with a fee
+			// bump paying at least ten times its fee.
+			replace = true
+		}
+
+		fee, err = s.bumpedFee(ctx, fee, replace)
+		if err != nil {
+			return s.lookupTransaction(ctx, ch, result, err)
+		}
+		feeAccount := ch.keypair
+		if s.config.FeeAccount != nil {
+			feeAccount = s.config.FeeAccount
+		}
+		feeBump, err = txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+			Inner:      tx,
+			FeeAccount: feeAccount.Address(),
+			BaseFee:    fee,
+		})
+		if err != nil {
+			return s.lookupTransaction(ctx, ch, result, errors.Wrap(err, "could not build fee bump transaction"))
+		}
+		feeBump, err = feeBump.Sign(s.config.NetworkPassphrase, feeAccount)
+		if err != nil {
+			return s.lookupTransaction(ctx, ch, result, errors.Wrap(err, "could not sign fee bump transaction"))
+		}
+		result.FeeBumped = true
+	}
+}
+
+// bumpedFee returns the fee per operation of the next fee bump transaction,
+// priced from the fee stats of Horizon. When replace is true the fee is at
+// least ten times the previous fee.
+func (s *Submitter) bumpedFee(ctx context.Context, previous int64, replace bool) (int64, error) {
+	stats, err := s.config.Horizon.FeeStatsWithContext(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not fetch fee stats")
+	}
+	fee := stats.MaxFee.P95
+	if replace && fee < previous*10 {
+		fee = previous * 10
+	}
+	if fee <= previous {
+		fee = previous * 2
+	}
+	if fee > s.config.MaxFee {
+		fee = s.config.MaxFee
+	}
+	if fee <= previous {
+		return 0, ErrMaxFee
+	}
+	return fee, nil
+}
+
+// lookupTransaction returns the transaction if it was included in a ledger,
+// or the given error otherwise. The channel is synced again in the latter
+// case, its sequence number may not have been consumed.
+func (s *Submitter) lookupTransaction(ctx context.Context, ch *channel, result Result, err error) (Result, error) {
+	transaction, lookupErr := s.config.Horizon.TransactionDetailWithContext(ctx, result.Hash)
+	if lookupErr == nil {
+		return included(result, transaction)
+	}
+	ch.synced = false
+	return result, err
+}
+
+// included returns the result of a transaction included in a ledger.
+func included(result Result, transaction hProtocol.Transaction) (Result, error) {
+	result.Transaction = transaction
+	if !transaction.Successful {
+		return result, ErrTransactionFailed
+	}
+	return result, nil
+}
+
+// transactionCode returns the transaction result code of a submission error,
+// or an empty string if there is none.
+func transactionCode(err error) string {
+	horizonError := horizonclient.GetError(err)
+	if horizonError == nil {
+		return ""
+	}
+	codes, err := horizonError.ResultCodes()
+	if err != nil {
+		return ""
+	}
+	return codes.TransactionCode
+}
@@ -0,0 +1,179 @@
+package txsubmitter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/clients/horizonclient"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/support/render/problem"
+	"github.com/stellar/go/txnbuild"
+)
+
+var (
+	channelKP = keypair.MustRandom()
+	sourceKP  = keypair.MustRandom()
+	feeKP     = keypair.MustRandom()
+)
+
+func newTestSubmitter(t *testing.T, horizon *horizonclient.MockClient) *Submitter {
+	s, err := New(Config{
+		Horizon:               horizon,
+		NetworkPassphrase:     network.TestNetworkPassphrase,
+		Channels:              []*keypair.Full{channelKP},
+		FeeAccount:            feeKP,
+		FeeBumpAfter:          time.Second,
+		SkipMemoRequiredCheck: true,
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func paymentRequest() Request {
+	return Request{
+		Operations: []txnbuild.Operation{&txnbuild.Payment{
+			Destination:   "GAS4V4O2B7DW5T7IQRPEEVCRXMDZESKISR7DVIGKZQYYV3OSQ5SH5LVP",
+			Amount:        "10",
+			Asset:         txnbuild.NativeAsset{},
+			SourceAccount: sourceKP.Address(),
+		}},
+		Signers: []*keypair.Full{sourceKP},
+	}
+}
+
+func expectAccount(horizon *horizonclient.MockClient, sequence string) {
+	horizon.On("AccountDetailWithContext", mock.Anything, horizonclient.AccountRequest{AccountID: channelKP.Address()}).
+		Return(hProtocol.Account{Sequence: sequence}, nil).Once()
+}
+
+func withSequence(sequence int64) interface{} {
+	return mock.MatchedBy(func(tx *txnbuild.Transaction) bool {
+		return tx.SourceAccount().Sequence == sequence && len(tx.Signatures()) == 2
+	})
+}
+
+func horizonError(status int, transactionCode string) error {
+	p := problem.P{Status: status}
+	if transactionCode != "" {
+		p.Extras = map[string]interface{}{
+			"result_codes": hProtocol.TransactionResultCodes{TransactionCode: transactionCode},
+		}
+	}
+	return &horizonclient.Error{Response: &http.Response{StatusCode: status}, Problem: p}
+}
+
+func TestSubmit(t *testing.T) {
+	horizon := &horizonclient.MockClient{}
+	s := newTestSubmitter(t, horizon)
+	opts := horizonclient.SubmitTxOpts{SkipMemoRequiredCheck: true}
+
+	expectAccount(horizon, "100")
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(101), opts).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(102), opts).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	result, err := s.Submit(context.Background(), paymentRequest())
+	require.NoError(t, err)
+	assert.Len(t, result.Hash, 64)
+	assert.False(t, result.FeeBumped)
+	assert.True(t, result.Transaction.Successful)
+
+	// The sequence number is tracked locally.
+	_, err = s.Submit(context.Background(), paymentRequest())
+	require.NoError(t, err)
+
+	// Failed transactions consume their sequence number.
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(103), opts).
+		Return(hProtocol.Transaction{}, horizonError(400, "tx_failed")).Once()
+	_, err = s.Submit(context.Background(), paymentRequest())
+	assert.Equal(t, "tx_failed", transactionCode(err))
+	assert.True(t, (<-s.channels).synced)
+	horizon.AssertExpectations(t)
+
+	_, err = s.Submit(context.Background(), Request{})
+	assert.EqualError(t, err, "request has no operations")
+}
+
+func TestSubmitBadSequence(t *testing.T) {
+	horizon := &horizonclient.MockClient{}
+	s := newTestSubmitter(t, horizon)
+	opts := horizonclient.SubmitTxOpts{SkipMemoRequiredCheck: true}
+
+	expectAccount(horizon, "100")
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(101), opts).
+		Return(hProtocol.Transaction{}, horizonError(400, "tx_bad_seq")).Once()
+	expectAccount(horizon, "200")
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(201), opts).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	result, err := s.Submit(context.Background(), paymentRequest())
+	require.NoError(t, err)
+	assert.True(t, result.Transaction.Successful)
+	horizon.AssertExpectations(t)
+}
+
+func TestSubmitInsufficientFee(t *testing.T) {
+	horizon := &horizonclient.MockClient{}
+	s := newTestSubmitter(t, horizon)
+	opts := horizonclient.SubmitTxOpts{SkipMemoRequiredCheck: true}
+
+	expectAccount(horizon, "100")
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(101), opts).
+		Return(hProtocol.Transaction{}, horizonError(400, "tx_insufficient_fee")).Once()
+	horizon.On("FeeStatsWithContext", mock.Anything).
+		Return(hProtocol.FeeStats{MaxFee: hProtocol.FeeDistribution{P95: 500}}, nil).Once()
+	horizon.On("SubmitFeeBumpTransactionWithOptionsWithContext", mock.Anything, mock.MatchedBy(func(tx *txnbuild.FeeBumpTransaction) bool {
+		return tx.BaseFee() == 500 && tx.FeeAccount() == feeKP.Address() && tx.InnerTransaction().SourceAccount().Sequence == 101
+	}), opts).Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	result, err := s.Submit(context.Background(), paymentRequest())
+	require.NoError(t, err)
+	assert.True(t, result.FeeBumped)
+	horizon.AssertExpectations(t)
+}
+
+func TestSubmitPending(t *testing.T) {
+	horizon := &horizonclient.MockClient{}
+	s := newTestSubmitter(t, horizon)
+	opts := horizonclient.SubmitTxOpts{SkipMemoRequiredCheck: true}
+
+	expectAccount(horizon, "100")
+	horizon.On("SubmitTransactionWithOptionsWithContext", mock.Anything, withSequence(101), opts).
+		Return(hProtocol.Transaction{}, horizonError(504, "")).Once()
+	horizon.On("TransactionDetailWithContext", mock.Anything, mock.Anything).
+		Return(hProtocol.Transaction{}, horizonError(404, "")).Once()
+	horizon.On("FeeStatsWithContext", mock.Anything).
+		Return(hProtocol.FeeStats{MaxFee: hProtocol.FeeDistribution{P95: 150}}, nil).Once()
+	// A pending transaction is only replaced by a fee bump paying ten times
+	// its fee.
+	horizon.On("SubmitFeeBumpTransactionWithOptionsWithContext", mock.Anything, mock.MatchedBy(func(tx *txnbuild.FeeBumpTransaction) bool {
+		return tx.BaseFee() == 1000
+	}), opts).Return(hProtocol.Transaction{}, horizonError(504, "")).Once()
+	horizon.On("TransactionDetailWithContext", mock.Anything, mock.Anything).
+		Return(hProtocol.Transaction{Successful: true, Ledger: 10}, nil).Once()
+
+	result, err := s.Submit(context.Background(), paymentRequest())
+	require.NoError(t, err)
+	assert.True(t, result.FeeBumped)
+	assert.Equal(t, int32(10), result.Transaction.Ledger)
+	horizon.AssertExpectations(t)
+
+	// Transactions are not submitted after their timebounds.
+	request := paymentRequest()
+	request.Timebounds = txnbuild.NewTimebounds(0, time.Now().Unix()-1)
+	horizon.On("TransactionDetailWithContext", mock.Anything, mock.Anything).
+		Return(hProtocol.Transaction{}, horizonError(404, "")).Once()
+	_, err = s.Submit(context.Background(), request)
+	assert.Equal(t, ErrExpired, err)
+	assert.False(t, (<-s.channels).synced)
+	horizon.AssertExpectations(t)
+}
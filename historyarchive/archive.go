@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 	"net/url"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -48,6 +49,10 @@ type ConnectOptions struct {
 	// CheckpointFrequency is the number of ledgers between checkpoints
 	// if unset, DefaultCheckpointFrequency will be used
 	CheckpointFrequency uint32
+	// CacheConfig controls how the history archive is cached on local disk.
+	// Immutable files (buckets and checkpoint files) are cached, the root HAS
+	// is always fetched from the archive.
+	CacheConfig CacheOptions
 }
 
 type Ledger struct {
@@ -423,6 +428,11 @@ func Connect(u string, opts ConnectOptions) (*Archive, error) {
 	} else {
 		err = errors.New("unknown URL scheme: '" + parsed.Scheme + "'")
 	}
+
+	if err == nil && opts.CacheConfig.Cache {
+		cacheDir := filepath.Join(opts.CacheConfig.Path, cacheDirForURL(u))
+		arch.backend, err = MakeArchiveBackendCache(arch.backend, cacheDir, opts.CacheConfig.MaxBytes)
+	}
 	return &arch, err
 }
 
@@ -0,0 +1,341 @@
+// Copyright 2021 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// CacheOptions configures the local disk cache placed in front of an
+// ArchiveBackend. See ArchiveBackendCache.
+type CacheOptions struct {
+	// Cache enables the local disk cache.
+	Cache bool
+	// Path is the directory in which cached files are stored. Every archive
+	// URL is cached in its own subdirectory of Path.
+	Path string
+	// MaxBytes is the maximum total size of files kept in the cache of a
+	// single archive. Least recently used files are evicted first. Zero
+	// means the cache is unbounded.
+	MaxBytes int64
+}
+
+var bucketPathRegexp = regexp.MustCompile(`^/?bucket` + hexPrefixPat + `bucket-([0-9a-f]{64})\.xdr\.gz$`)
+
+// ArchiveBackendCache is an ArchiveBackend which stores immutable files
+// (buckets and checkpoint files) fetched from the wrapped backend on local
+// disk and serves subsequent requests for them from there. Mutable files (the
+// root `.well-known/stellar-history.json` HAS) are always fetched from the
+// wrapped backend.
+//
+// Files are downloaded in full before being returned by GetFile. Buckets are
+// hashed before being added to the cache so corrupted downloads are never
+// cached.
+type ArchiveBackendCache struct {
+	upstream ArchiveBackend
+	path     string
+	maxBytes int64
+
+	mutex   sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	size    int64
+}
+
+type cacheEntry struct {
+	path string
+	size int64
+}
+
+// MakeArchiveBackendCache wraps upstream with a local disk cache stored in
+// dir. Files already present in dir (ex. from a previous run) are reused.
+func MakeArchiveBackendCache(upstream ArchiveBackend, dir string, maxBytes int64) (*ArchiveBackendCache, error) {
+	if dir == "" {
+		return nil, errors.New("cache path is empty")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "cannot create cache directory")
+	}
+
+	cache := &ArchiveBackendCache{
+		upstream: upstream,
+		path:     dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+	}
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// load adds the files already present in the cache directory to the LRU
+// list, least recently modified first.
+func (c *ArchiveBackendCache) load() error {
+	type existingFile struct {
+		path string
+		info os.FileInfo
+	}
+	var files []existingFile
+	err := filepath.Walk(c.path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(c.path, p)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(filepath.Base(p), ".tmp-") {
+			// Leftover from an interrupted download.
+			return os.Remove(p)
+		}
+		files = append(files, existingFile{path: filepath.ToSlash(rel), info: info})
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot load cache directory")
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].info.ModTime().Before(files[j].info.ModTime())
+	})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, f := range files {
+		c.add(f.path, f.info.Size())
+	}
+	c.evict()
+	return nil
+}
+
+func isCacheable(pth string) bool {
+	return strings.TrimPrefix(pth, "/") != rootHASPath
+}
+
+func cacheKey(pth string) string {
+	return strings.TrimPrefix(path.Clean("/"+pth), "/")
+}
+
+func (c *ArchiveBackendCache) localPath(key string) string {
+	return filepath.Join(c.path, filepath.FromSlash(key))
+}
+
+// lookup returns true and marks the entry as recently used if key is cached.
+func (c *ArchiveBackendCache) lookup(key string) (int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).size, true
+}
+
+// add inserts key into the LRU list. It must be called with c.mutex held.
+func (c *ArchiveBackendCache) add(key string, size int64) {
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		elem.Value.(*cacheEntry).size = size
+		c.size += size
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{path: key, size: size})
+	c.size += size
+}
+
+// remove deletes key from the cache. It must be called with c.mutex held.
+func (c *ArchiveBackendCache) remove(key string) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(elem)
+	delete(c.entries, key)
+	c.size -= elem.Value.(*cacheEntry).size
+	if err := os.Remove(c.localPath(key)); err != nil && !os.IsNotExist(err) {
+		log.WithField("path", key).WithError(err).Warn("cache: remove file")
+	}
+}
+
+// evict removes least recently used files until the cache fits in maxBytes.
+// It must be called with c.mutex held.
+func (c *ArchiveBackendCache) evict() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	// The most recently used file is always kept, even if it's larger than
+	// maxBytes on its own.
+	for c.size > c.maxBytes && c.lru.Len() > 1 {
+		entry := c.lru.Back().Value.(*cacheEntry)
+		log.WithField("path", entry.path).Trace("cache: evict")
+		c.remove(entry.path)
+	}
+}
+
+func (c *ArchiveBackendCache) GetFile(pth string) (io.ReadCloser, error) {
+	if !isCacheable(pth) {
+		return c.upstream.GetFile(pth)
+	}
+
+	key := cacheKey(pth)
+	c.mutex.Lock()
+	if elem, ok := c.entries[key]; ok {
+		f, err := os.Open(c.localPath(key))
+		if err == nil {
+			c.lru.MoveToFront(elem)
+			c.mutex.Unlock()
+			log.WithField("path", key).Trace("cache: hit")
+			return f, nil
+		}
+		// The file was removed behind our back, fetch it again.
+		log.WithField("path", key).WithError(err).Warn("cache: open cached file")
+		c.remove(key)
+	}
+	c.mutex.Unlock()
+
+	log.WithField("path", key).Trace("cache: miss")
+	return c.download(key, pth)
+}
+
+// download fetches pth from upstream, stores it in the cache under key and
+// returns the cached file opened for reading.
+func (c *ArchiveBackendCache) download(key, pth string) (io.ReadCloser, error) {
+	local := c.localPath(key)
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return nil, errors.Wrap(err, "cannot create cache directory")
+	}
+
+	in, err := c.upstream.GetFile(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(local), ".tmp-")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create temporary cache file")
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, in)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error downloading %s", pth)
+	}
+
+	if matches := bucketPathRegexp.FindStringSubmatch(pth); matches != nil {
+		if err = verifyCachedBucket(tmp.Name(), matches[1]); err != nil {
+			return nil, errors.Wrapf(err, "error verifying %s", pth)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err = os.Rename(tmp.Name(), local); err != nil {
+		return nil, errors.Wrap(err, "cannot move file into cache")
+	}
+	// Open the file before evicting anything so it can be read even if it
+	// is evicted by a concurrent download.
+	f, err := os.Open(local)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open cached file")
+	}
+	c.add(key, size)
+	c.evict()
+	return f, nil
+}
+
+func verifyCachedBucket(localPath string, expectedHash string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, gz); err != nil {
+		return err
+	}
+	var actual Hash
+	copy(actual[:], hasher.Sum(nil))
+	if actual.String() != expectedHash {
+		return errors.Errorf("bucket hash mismatch: expected=%s actual=%s", expectedHash, actual)
+	}
+	return nil
+}
+
+func (c *ArchiveBackendCache) Exists(pth string) (bool, error) {
+	if isCacheable(pth) {
+		if _, ok := c.lookup(cacheKey(pth)); ok {
+			return true, nil
+		}
+	}
+	return c.upstream.Exists(pth)
+}
+
+func (c *ArchiveBackendCache) Size(pth string) (int64, error) {
+	if isCacheable(pth) {
+		if size, ok := c.lookup(cacheKey(pth)); ok {
+			return size, nil
+		}
+	}
+	return c.upstream.Size(pth)
+}
+
+// PutFile writes the file to the wrapped backend and drops any cached copy.
+func (c *ArchiveBackendCache) PutFile(pth string, in io.ReadCloser) error {
+	c.mutex.Lock()
+	c.remove(cacheKey(pth))
+	c.mutex.Unlock()
+	return c.upstream.PutFile(pth, in)
+}
+
+func (c *ArchiveBackendCache) ListFiles(pth string) (chan string, chan error) {
+	return c.upstream.ListFiles(pth)
+}
+
+func (c *ArchiveBackendCache) CanListFiles() bool {
+	return c.upstream.CanListFiles()
+}
+
+var cacheDirSanitizer = regexp.MustCompile(`[^a-zA-Z0-9.\-]+`)
+
+// cacheDirForURL returns the subdirectory of the cache path used for the
+// archive at the given URL.
+func cacheDirForURL(u string) string {
+	return strings.Trim(cacheDirSanitizer.ReplaceAllString(u, "_"), "_")
+}
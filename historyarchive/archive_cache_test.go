@@ -0,0 +1,156 @@
+// Copyright 2021 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingBackend struct {
+	ArchiveBackend
+	gets map[string]int
+}
+
+func (b *countingBackend) GetFile(pth string) (io.ReadCloser, error) {
+	b.gets[pth]++
+	return b.ArchiveBackend.GetFile(pth)
+}
+
+func makeTestCache(t *testing.T, maxBytes int64) (*ArchiveBackendCache, *countingBackend, string) {
+	dir, err := ioutil.TempDir("", "archive-cache")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	upstream := &countingBackend{
+		ArchiveBackend: makeMockBackend(ConnectOptions{}),
+		gets:           map[string]int{},
+	}
+	cache, err := MakeArchiveBackendCache(upstream, dir, maxBytes)
+	require.NoError(t, err)
+	return cache, upstream, dir
+}
+
+func putGzipBucket(t *testing.T, backend ArchiveBackend, content []byte) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	pth := BucketPath(sha256.Sum256(content))
+	require.NoError(t, backend.PutFile(pth, ioutil.NopCloser(&buf)))
+	return pth
+}
+
+func readAll(t *testing.T, backend ArchiveBackend, pth string) []byte {
+	rdr, err := backend.GetFile(pth)
+	require.NoError(t, err)
+	defer rdr.Close()
+	content, err := ioutil.ReadAll(rdr)
+	require.NoError(t, err)
+	return content
+}
+
+func TestArchiveBackendCacheServesImmutableFilesFromDisk(t *testing.T) {
+	cache, upstream, _ := makeTestCache(t, 0)
+	bucket := putGzipBucket(t, upstream, []byte("bucket content"))
+	checkpoint := CategoryCheckpointPath("ledger", 63)
+	require.NoError(t, upstream.PutFile(checkpoint, ioutil.NopCloser(bytes.NewReader([]byte("ledger")))))
+	require.NoError(t, upstream.PutFile(rootHASPath, ioutil.NopCloser(bytes.NewReader([]byte("{}")))))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, readAll(t, upstream.ArchiveBackend, bucket), readAll(t, cache, bucket))
+		assert.Equal(t, []byte("ledger"), readAll(t, cache, checkpoint))
+		assert.Equal(t, []byte("{}"), readAll(t, cache, rootHASPath))
+	}
+
+	assert.Equal(t, 1, upstream.gets[bucket])
+	assert.Equal(t, 1, upstream.gets[checkpoint])
+	assert.Equal(t, 3, upstream.gets[rootHASPath])
+
+	size, err := cache.Size(checkpoint)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), size)
+}
+
+func TestArchiveBackendCacheRejectsCorruptBucket(t *testing.T) {
+	cache, upstream, dir := makeTestCache(t, 0)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte("corrupt"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	pth := BucketPath(sha256.Sum256([]byte("expected")))
+	require.NoError(t, upstream.PutFile(pth, ioutil.NopCloser(&buf)))
+
+	_, err = cache.GetFile(pth)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "bucket hash mismatch")
+
+	_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(cacheKey(pth))))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestArchiveBackendCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, upstream, _ := makeTestCache(t, 10)
+	for _, chk := range []uint32{63, 127, 191} {
+		pth := CategoryCheckpointPath("ledger", chk)
+		require.NoError(t, upstream.PutFile(pth, ioutil.NopCloser(bytes.NewReader([]byte("12345")))))
+	}
+	first := CategoryCheckpointPath("ledger", 63)
+	second := CategoryCheckpointPath("ledger", 127)
+	third := CategoryCheckpointPath("ledger", 191)
+
+	readAll(t, cache, first)
+	readAll(t, cache, second)
+	// Touch first so that second becomes the least recently used file.
+	readAll(t, cache, first)
+	readAll(t, cache, third)
+
+	assert.Equal(t, int64(10), cache.size)
+	readAll(t, cache, first)
+	readAll(t, cache, second)
+	assert.Equal(t, 1, upstream.gets[first])
+	assert.Equal(t, 2, upstream.gets[second])
+}
+
+func TestArchiveBackendCacheReusesExistingFiles(t *testing.T) {
+	cache, upstream, dir := makeTestCache(t, 0)
+	pth := CategoryCheckpointPath("results", 63)
+	require.NoError(t, upstream.PutFile(pth, ioutil.NopCloser(bytes.NewReader([]byte("results")))))
+	readAll(t, cache, pth)
+
+	reopened, err := MakeArchiveBackendCache(upstream, dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("results"), readAll(t, reopened, pth))
+	assert.Equal(t, 1, upstream.gets[pth])
+}
+
+func TestConnectWithCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	arch := MustConnect("mock://test", ConnectOptions{
+		CheckpointFrequency: 64,
+		CacheConfig:         CacheOptions{Cache: true, Path: dir},
+	})
+	_, ok := arch.backend.(*ArchiveBackendCache)
+	assert.True(t, ok)
+
+	_, err = os.Stat(filepath.Join(dir, "mock_test"))
+	assert.NoError(t, err)
+}
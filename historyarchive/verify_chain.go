@@ -0,0 +1,232 @@
+// Copyright 2021 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// VerifyChainOptions configures Archive.VerifyChain.
+type VerifyChainOptions struct {
+	// Range is the range of checkpoints to verify. It is clamped to the range
+	// of checkpoints published in the archive.
+	Range Range
+	// TrustedLedger and TrustedHash anchor the chain of ledger headers to a
+	// ledger hash known to be correct (ex. fetched from a trusted
+	// stellar-core instance). Ledgers are only proven by the ledgers after
+	// them, so TrustedLedger must not be before the end of Range: the range
+	// is extended to end at TrustedLedger. If TrustedLedger is zero the
+	// archive is only checked for internal consistency.
+	TrustedLedger uint32
+	TrustedHash   Hash
+	// VerifyBuckets enables checking that every bucket referenced by the
+	// checkpoint HAS files hashes to its name.
+	VerifyBuckets bool
+}
+
+// ChainDivergenceError is returned by Archive.VerifyChain when the archive
+// contents do not match the chain of ledger hashes.
+type ChainDivergenceError struct {
+	// Ledger is the sequence of the ledger (or checkpoint for HAS files and
+	// buckets) at which the divergence was found.
+	Ledger uint32
+	// Object describes what does not match, ex. "previous ledger hash".
+	Object   string
+	Expected Hash
+	Actual   Hash
+}
+
+func (e *ChainDivergenceError) Error() string {
+	return fmt.Sprintf("chain diverges at ledger %d: mismatched %s, expected %s, got %s",
+		e.Ledger, e.Object, e.Expected, e.Actual)
+}
+
+// VerifyChain walks all ledgers in the checkpoints of opts.Range and checks
+// that:
+//
+//   - every ledger header hashes to the hash stored with it,
+//   - every header's PreviousLedgerHash is the hash of the previous header,
+//   - the hash of the header at opts.TrustedLedger is opts.TrustedHash,
+//   - transaction sets and transaction result sets hash to the TxSetHash and
+//     TxSetResultHash of their ledger header,
+//   - the BucketListHash of every checkpoint ledger header matches the
+//     buckets listed in the HAS file of the checkpoint.
+//
+// Because every ledger header is linked to the previous one, this proves the
+// integrity of the archive in the whole range ending at a single trusted
+// ledger hash. The first divergence found (in ledger order) is returned as a
+// *ChainDivergenceError. Missing files are returned as errors.
+func (a *Archive) VerifyChain(opts VerifyChainOptions) error {
+	state, err := a.GetRootHAS()
+	if err != nil {
+		return errors.Wrap(err, "error getting root HAS")
+	}
+	rng := opts.Range.clamp(state.Range(), a.checkpointManager)
+
+	if opts.TrustedLedger != 0 {
+		if opts.TrustedLedger < rng.High {
+			return errors.Errorf("trusted ledger %d is before the end of the verified range %d, the ledgers after it would not be proven",
+				opts.TrustedLedger, rng.High)
+		}
+		last := a.checkpointManager.GetCheckpoint(opts.TrustedLedger)
+		if last > state.CurrentLedger {
+			return errors.Errorf("trusted ledger %d is after the last checkpoint %d of the archive",
+				opts.TrustedLedger, state.CurrentLedger)
+		}
+		rng.High = last
+	}
+
+	log.Infof("Verifying chain of ledgers in checkpoints %s", rng)
+
+	verifiedBuckets := map[Hash]bool{}
+	var prevHash *Hash
+	var prevSeq uint32
+	count := 0
+	freq := uint64(a.checkpointManager.GetCheckpointFrequency())
+	for i := uint64(rng.Low); i <= uint64(rng.High); i += freq {
+		chk := uint32(i)
+		var ledgers map[uint32]*Ledger
+		ledgers, err = a.GetLedgers(chk, chk)
+		if err != nil {
+			return errors.Wrapf(err, "error getting ledgers of checkpoint %d", chk)
+		}
+
+		// The ledgers after the trusted ledger are not verified.
+		checkpointRange := a.checkpointManager.GetCheckpointRange(chk)
+		if opts.TrustedLedger != 0 && checkpointRange.High > opts.TrustedLedger {
+			checkpointRange.High = opts.TrustedLedger
+		}
+		for seq := checkpointRange.Low; seq <= checkpointRange.High; seq++ {
+			ledger, ok := ledgers[seq]
+			if !ok || uint32(ledger.Header.Header.LedgerSeq) != seq {
+				return errors.Errorf("ledger header %d is missing from checkpoint %d", seq, chk)
+			}
+
+			var hash Hash
+			hash, err = a.verifyChainLedger(ledger, prevHash, opts)
+			if err != nil {
+				return err
+			}
+			prevHash, prevSeq = &hash, seq
+			count++
+		}
+
+		if checkpointRange.High == chk {
+			if err = a.verifyChainCheckpoint(chk, ledgers[chk], opts, verifiedBuckets); err != nil {
+				return err
+			}
+		}
+		log.Debugf("Verified checkpoint %d", chk)
+	}
+
+	log.Infof("Verified chain of %d ledgers ending at ledger %d", count, prevSeq)
+	return nil
+}
+
+// verifyChainLedger verifies a single ledger and returns the hash of its
+// header.
+func (a *Archive) verifyChainLedger(ledger *Ledger, prevHash *Hash, opts VerifyChainOptions) (Hash, error) {
+	header := ledger.Header.Header
+	seq := uint32(header.LedgerSeq)
+
+	hash, err := HashXdr(&header)
+	if err != nil {
+		return Hash{}, errors.Wrapf(err, "error hashing ledger header %d", seq)
+	}
+	if hash != Hash(ledger.Header.Hash) {
+		return Hash{}, &ChainDivergenceError{
+			Ledger: seq, Object: "ledger header hash",
+			Expected: Hash(ledger.Header.Hash), Actual: hash,
+		}
+	}
+	if prevHash != nil && *prevHash != Hash(header.PreviousLedgerHash) {
+		return Hash{}, &ChainDivergenceError{
+			Ledger: seq, Object: "previous ledger hash",
+			Expected: Hash(header.PreviousLedgerHash), Actual: *prevHash,
+		}
+	}
+	if opts.TrustedLedger == seq && hash != opts.TrustedHash {
+		return Hash{}, &ChainDivergenceError{
+			Ledger: seq, Object: "trusted ledger hash",
+			Expected: opts.TrustedHash, Actual: hash,
+		}
+	}
+
+	// Ledgers with empty transaction sets are not present in the
+	// transactions and results files.
+	txSetHash := HashEmptyTxSet(Hash(header.PreviousLedgerHash))
+	if uint32(ledger.Transaction.LedgerSeq) == seq {
+		if txSetHash, err = HashTxSet(&ledger.Transaction.TxSet); err != nil {
+			return Hash{}, errors.Wrapf(err, "error hashing transaction set %d", seq)
+		}
+	}
+	if txSetHash != Hash(header.ScpValue.TxSetHash) {
+		return Hash{}, &ChainDivergenceError{
+			Ledger: seq, Object: "transaction set hash",
+			Expected: Hash(header.ScpValue.TxSetHash), Actual: txSetHash,
+		}
+	}
+
+	resultSetHash := EmptyXdrArrayHash()
+	if uint32(ledger.TransactionResult.LedgerSeq) == seq {
+		if resultSetHash, err = HashXdr(&ledger.TransactionResult.TxResultSet); err != nil {
+			return Hash{}, errors.Wrapf(err, "error hashing transaction result set %d", seq)
+		}
+	}
+	if resultSetHash != Hash(header.TxSetResultHash) {
+		return Hash{}, &ChainDivergenceError{
+			Ledger: seq, Object: "transaction result set hash",
+			Expected: Hash(header.TxSetResultHash), Actual: resultSetHash,
+		}
+	}
+
+	return hash, nil
+}
+
+// verifyChainCheckpoint checks the HAS file of the checkpoint against the
+// bucket list hash of the checkpoint ledger header.
+func (a *Archive) verifyChainCheckpoint(
+	chk uint32,
+	ledger *Ledger,
+	opts VerifyChainOptions,
+	verifiedBuckets map[Hash]bool,
+) error {
+	has, err := a.GetCheckpointHAS(chk)
+	if err != nil {
+		return errors.Wrapf(err, "error getting HAS of checkpoint %d", chk)
+	}
+	bucketListHash, err := has.BucketListHash()
+	if err != nil {
+		return errors.Wrapf(err, "error computing bucket list hash of checkpoint %d", chk)
+	}
+	if Hash(bucketListHash) != Hash(ledger.Header.Header.BucketListHash) {
+		return &ChainDivergenceError{
+			Ledger: chk, Object: "bucket list hash",
+			Expected: Hash(ledger.Header.Header.BucketListHash), Actual: Hash(bucketListHash),
+		}
+	}
+
+	if !opts.VerifyBuckets {
+		return nil
+	}
+	buckets, err := has.Buckets()
+	if err != nil {
+		return errors.Wrapf(err, "error getting buckets of checkpoint %d", chk)
+	}
+	for _, bucket := range buckets {
+		if verifiedBuckets[bucket] {
+			continue
+		}
+		if err = a.VerifyBucketHash(bucket); err != nil {
+			return errors.Wrapf(err, "error verifying bucket %s of checkpoint %d", bucket, chk)
+		}
+		verifiedBuckets[bucket] = true
+	}
+	return nil
+}
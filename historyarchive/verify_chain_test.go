@@ -0,0 +1,176 @@
+// Copyright 2021 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/xdr"
+)
+
+// populateChain writes a valid chain of ledgers 1-127 to the archive. tamper
+// is called with every header before it is hashed and written. It returns
+// the hashes of all ledgers.
+func populateChain(t *testing.T, arch *Archive, tamper func(header *xdr.LedgerHeader)) map[uint32]Hash {
+	has := HistoryArchiveState{CurrentLedger: 127}
+	for i := range has.CurrentBuckets {
+		has.CurrentBuckets[i].Curr = Hash{byte(i)}.String()
+		has.CurrentBuckets[i].Snap = Hash{}.String()
+	}
+	bucketListHash, err := has.BucketListHash()
+	require.NoError(t, err)
+
+	result := xdr.TransactionResult{Result: xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxBadSeq}}
+	resultSet := xdr.TransactionResultSet{
+		Results: []xdr.TransactionResultPair{{TransactionHash: xdr.Hash{1}, Result: result}},
+	}
+	resultSetHash, err := HashXdr(&resultSet)
+	require.NoError(t, err)
+
+	hashes := map[uint32]Hash{}
+	var prevHash Hash
+	for _, chk := range []uint32{63, 127} {
+		var headers, transactions, results []xdrEntry
+		for seq := chk - 63; seq <= chk; seq++ {
+			if seq == 0 {
+				continue
+			}
+			header := xdr.LedgerHeader{
+				LedgerSeq:          xdr.Uint32(seq),
+				PreviousLedgerHash: xdr.Hash(prevHash),
+				TxSetResultHash:    xdr.Hash(EmptyXdrArrayHash()),
+			}
+			header.ScpValue.TxSetHash = xdr.Hash(HashEmptyTxSet(prevHash))
+			if seq == chk {
+				header.BucketListHash = bucketListHash
+			}
+			if seq%10 == 0 {
+				transactions = append(transactions, xdr.TransactionHistoryEntry{
+					LedgerSeq: xdr.Uint32(seq),
+					TxSet:     xdr.TransactionSet{PreviousLedgerHash: xdr.Hash(prevHash)},
+				})
+				results = append(results, xdr.TransactionHistoryResultEntry{
+					LedgerSeq:   xdr.Uint32(seq),
+					TxResultSet: resultSet,
+				})
+				header.TxSetResultHash = xdr.Hash(resultSetHash)
+			}
+			if tamper != nil {
+				tamper(&header)
+			}
+
+			hash, err := HashXdr(&header)
+			require.NoError(t, err)
+			headers = append(headers, xdr.LedgerHeaderHistoryEntry{Hash: xdr.Hash(hash), Header: header})
+			hashes[seq] = hash
+			prevHash = hash
+		}
+
+		writeCategoryFile(t, arch.backend, CategoryCheckpointPath("ledger", chk), headers)
+		writeCategoryFile(t, arch.backend, CategoryCheckpointPath("transactions", chk), transactions)
+		writeCategoryFile(t, arch.backend, CategoryCheckpointPath("results", chk), results)
+		require.NoError(t, arch.PutCheckpointHAS(chk, has, &CommandOptions{Force: true}))
+	}
+	require.NoError(t, arch.PutRootHAS(has, &CommandOptions{Force: true}))
+	return hashes
+}
+
+func TestVerifyChain(t *testing.T) {
+	arch := GetTestMockArchive()
+	hashes := populateChain(t, arch, nil)
+
+	opts := VerifyChainOptions{
+		Range:         Range{Low: 63, High: 0xffffffff},
+		TrustedLedger: 127,
+		TrustedHash:   hashes[127],
+	}
+	assert.NoError(t, arch.VerifyChain(opts))
+
+	opts.TrustedHash = Hash{1}
+	err := arch.VerifyChain(opts)
+	assert.Equal(t, &ChainDivergenceError{
+		Ledger: 127, Object: "trusted ledger hash", Expected: Hash{1}, Actual: hashes[127],
+	}, err)
+
+	// The range is extended up to the trusted ledger, the ledgers after it
+	// are not verified.
+	opts = VerifyChainOptions{
+		Range:         Range{Low: 0, High: 63},
+		TrustedLedger: 100,
+		TrustedHash:   hashes[100],
+	}
+	assert.NoError(t, arch.VerifyChain(opts))
+
+	// Ledgers after the trusted ledger would not be proven.
+	opts.Range.High = 127
+	assert.EqualError(t, arch.VerifyChain(opts), "trusted ledger 100 is before the end of the verified range 127, the ledgers after it would not be proven")
+
+	opts.TrustedLedger = 200
+	assert.EqualError(t, arch.VerifyChain(opts), "trusted ledger 200 is after the last checkpoint 127 of the archive")
+}
+
+func TestVerifyChainDivergence(t *testing.T) {
+	for _, testCase := range []struct {
+		name   string
+		tamper func(header *xdr.LedgerHeader)
+		ledger uint32
+		object string
+	}{
+		{
+			name: "previous ledger hash",
+			tamper: func(header *xdr.LedgerHeader) {
+				if header.LedgerSeq == 100 {
+					header.PreviousLedgerHash = xdr.Hash{1}
+				}
+			},
+			ledger: 100,
+			object: "previous ledger hash",
+		},
+		{
+			name: "transaction result set",
+			tamper: func(header *xdr.LedgerHeader) {
+				if header.LedgerSeq == 70 {
+					header.TxSetResultHash = xdr.Hash{2}
+				}
+			},
+			ledger: 70,
+			object: "transaction result set hash",
+		},
+		{
+			name: "transaction set",
+			tamper: func(header *xdr.LedgerHeader) {
+				if header.LedgerSeq == 5 {
+					header.ScpValue.TxSetHash = xdr.Hash{3}
+				}
+			},
+			ledger: 5,
+			object: "transaction set hash",
+		},
+		{
+			name: "bucket list hash",
+			tamper: func(header *xdr.LedgerHeader) {
+				if header.LedgerSeq == 127 {
+					header.BucketListHash = xdr.Hash{4}
+				}
+			},
+			ledger: 127,
+			object: "bucket list hash",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			arch := GetTestMockArchive()
+			populateChain(t, arch, testCase.tamper)
+
+			err := arch.VerifyChain(VerifyChainOptions{Range: Range{Low: 63, High: 127}})
+			divergence, ok := err.(*ChainDivergenceError)
+			require.True(t, ok, "unexpected error: %v", err)
+			assert.Equal(t, testCase.ledger, divergence.Ledger)
+			assert.Equal(t, testCase.object, divergence.Object)
+		})
+	}
+}
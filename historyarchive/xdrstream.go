@@ -133,16 +133,13 @@ func (x *XdrStream) closeReaders() error {
 			err = err2
 		}
 	}
+	// gzipReader wraps rdr2 so closing rdr2 closes both. Closing the
+	// underlying reader twice fails for some readers (ex. *os.File).
 	if x.rdr2 != nil {
 		if err2 := x.rdr2.Close(); err2 != nil {
 			err = err2
 		}
 	}
-	if x.gzipReader != nil {
-		if err2 := x.gzipReader.Close(); err2 != nil {
-			err = err2
-		}
-	}
 
 	return err
 }
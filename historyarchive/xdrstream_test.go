@@ -6,6 +6,7 @@ package historyarchive
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/sha256"
 	"io"
 	"io/ioutil"
@@ -129,3 +130,42 @@ func createXdrStream(entries ...xdr.BucketEntry) *XdrStream {
 
 	return NewXdrStream(ioutil.NopCloser(b))
 }
+
+func TestXdrGzStreamFromFileArchive(t *testing.T) {
+	archive := MustConnect("file://"+t.TempDir(), ConnectOptions{CheckpointFrequency: 64})
+
+	bucketEntry := xdr.BucketEntry{
+		Type: xdr.BucketEntryTypeLiveentry,
+		LiveEntry: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeAccount,
+				Account: &xdr.AccountEntry{
+					AccountId: xdr.MustAddress("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+					Balance:   xdr.Int64(200000000),
+				},
+			},
+		},
+	}
+	raw := &bytes.Buffer{}
+	require.NoError(t, xdr.MarshalFramed(raw, bucketEntry))
+	hash := Hash(sha256.Sum256(raw.Bytes()))
+
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
+	_, err := writer.Write(raw.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, archive.backend.PutFile(archive.GetBucketPathForHash(hash), ioutil.NopCloser(compressed)))
+
+	stream, err := archive.GetXdrStreamForHash(hash)
+	require.NoError(t, err)
+	stream.SetExpectedHash(hash)
+
+	var readEntry xdr.BucketEntry
+	require.NoError(t, stream.ReadOne(&readEntry))
+	assert.Equal(t, bucketEntry, readEntry)
+	assert.Equal(t, io.EOF, stream.ReadOne(&readEntry))
+
+	// The bucket file must be closed only once.
+	assert.NoError(t, stream.Close())
+}
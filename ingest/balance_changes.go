@@ -0,0 +1,228 @@
+package ingest
+
+import (
+	"sort"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// BalanceHolderType describes the kind of ledger entry holding a balance.
+type BalanceHolderType int
+
+const (
+	// BalanceHolderAccount is an account holding native balance, trustline
+	// balances or liquidity pool shares. The holder is the account address.
+	BalanceHolderAccount BalanceHolderType = iota
+	// BalanceHolderClaimableBalance is a claimable balance. The holder is the
+	// hex encoded XDR of the claimable balance ID.
+	BalanceHolderClaimableBalance
+	// BalanceHolderLiquidityPool is a liquidity pool holding reserves. The
+	// holder is the hex encoded liquidity pool ID.
+	BalanceHolderLiquidityPool
+)
+
+// BalanceChange is the net change of a single balance caused by a
+// transaction.
+type BalanceChange struct {
+	HolderType BalanceHolderType
+	Holder     string
+	// Asset is the asset of the balance. It is a pool share asset for
+	// liquidity pool shares held by accounts.
+	Asset xdr.TrustLineAsset
+	// Amount is the signed net change of the balance, including fees.
+	Amount int64
+	// Fee is the part of Amount caused by the fee changes of the
+	// transaction (negative when a fee was charged). It is only non-zero
+	// for the native balance of the fee source account.
+	Fee int64
+}
+
+type balanceKey struct {
+	holderType BalanceHolderType
+	holder     string
+	asset      string
+}
+
+type balanceChanges struct {
+	changes map[balanceKey]*BalanceChange
+}
+
+func (b *balanceChanges) add(
+	holderType BalanceHolderType,
+	holder string,
+	asset xdr.TrustLineAsset,
+	amount int64,
+	fee bool,
+) error {
+	if amount == 0 {
+		return nil
+	}
+	assetKey, err := balanceAssetKey(asset)
+	if err != nil {
+		return err
+	}
+
+	key := balanceKey{holderType: holderType, holder: holder, asset: assetKey}
+	change, ok := b.changes[key]
+	if !ok {
+		change = &BalanceChange{HolderType: holderType, Holder: holder, Asset: asset}
+		b.changes[key] = change
+	}
+	change.Amount += amount
+	if fee {
+		change.Fee += amount
+	}
+	return nil
+}
+
+// addChange adds the balance differences between the pre and post state of
+// a ledger entry.
+func (b *balanceChanges) addChange(change Change, fee bool) error {
+	var pre, post xdr.LedgerEntryData
+	var entry *xdr.LedgerEntry
+	if change.Pre != nil {
+		pre = change.Pre.Data
+		entry = change.Pre
+	}
+	if change.Post != nil {
+		post = change.Post.Data
+		entry = change.Post
+	}
+
+	switch change.Type {
+	case xdr.LedgerEntryTypeAccount:
+		var before, after int64
+		if change.Pre != nil {
+			before = int64(pre.MustAccount().Balance)
+		}
+		if change.Post != nil {
+			after = int64(post.MustAccount().Balance)
+		}
+		account := entry.Data.MustAccount().AccountId
+		return b.add(
+			BalanceHolderAccount, account.Address(),
+			xdr.MustNewNativeAsset().ToTrustLineAsset(), after-before, fee,
+		)
+	case xdr.LedgerEntryTypeTrustline:
+		var before, after int64
+		if change.Pre != nil {
+			before = int64(pre.MustTrustLine().Balance)
+		}
+		if change.Post != nil {
+			after = int64(post.MustTrustLine().Balance)
+		}
+		trustLine := entry.Data.MustTrustLine()
+		return b.add(
+			BalanceHolderAccount, trustLine.AccountId.Address(),
+			trustLine.Asset, after-before, fee,
+		)
+	case xdr.LedgerEntryTypeClaimableBalance:
+		var before, after int64
+		if change.Pre != nil {
+			before = int64(pre.MustClaimableBalance().Amount)
+		}
+		if change.Post != nil {
+			after = int64(post.MustClaimableBalance().Amount)
+		}
+		balance := entry.Data.MustClaimableBalance()
+		id, err := xdr.MarshalHex(balance.BalanceId)
+		if err != nil {
+			return errors.Wrap(err, "error encoding claimable balance id")
+		}
+		return b.add(
+			BalanceHolderClaimableBalance, id,
+			balance.Asset.ToTrustLineAsset(), after-before, fee,
+		)
+	case xdr.LedgerEntryTypeLiquidityPool:
+		var beforeA, beforeB, afterA, afterB int64
+		if change.Pre != nil {
+			cp := pre.MustLiquidityPool().Body.MustConstantProduct()
+			beforeA, beforeB = int64(cp.ReserveA), int64(cp.ReserveB)
+		}
+		if change.Post != nil {
+			cp := post.MustLiquidityPool().Body.MustConstantProduct()
+			afterA, afterB = int64(cp.ReserveA), int64(cp.ReserveB)
+		}
+		pool := entry.Data.MustLiquidityPool()
+		params := pool.Body.MustConstantProduct().Params
+		id := xdr.Hash(pool.LiquidityPoolId).HexString()
+		if err := b.add(
+			BalanceHolderLiquidityPool, id,
+			params.AssetA.ToTrustLineAsset(), afterA-beforeA, fee,
+		); err != nil {
+			return err
+		}
+		return b.add(
+			BalanceHolderLiquidityPool, id,
+			params.AssetB.ToTrustLineAsset(), afterB-beforeB, fee,
+		)
+	}
+	return nil
+}
+
+func balanceAssetKey(asset xdr.TrustLineAsset) (string, error) {
+	switch asset.Type {
+	case xdr.AssetTypeAssetTypePoolShare:
+		return "pool_share:" + xdr.Hash(*asset.LiquidityPoolId).HexString(), nil
+	case xdr.AssetTypeAssetTypeNative, xdr.AssetTypeAssetTypeCreditAlphanum4, xdr.AssetTypeAssetTypeCreditAlphanum12:
+		return asset.ToAsset().StringCanonical(), nil
+	default:
+		return "", errors.Errorf("unknown asset type %d", asset.Type)
+	}
+}
+
+// GetBalanceChanges returns the net balance changes caused by the
+// transaction, including fees charged. The changes are computed from the
+// fee changes and the transaction meta and cover native balances, trustline
+// balances (including liquidity pool shares), claimable balances and
+// liquidity pool reserves. Balances which did not change (or which changed
+// and then returned to their previous value within the transaction) are
+// omitted.
+//
+// The sum of the returned changes for a given balance is exactly the
+// difference between the balance after the transaction and the balance
+// before fees were charged, so it can be used for accounting. Changes are
+// sorted by holder type, holder and canonical asset string.
+func (t *LedgerTransaction) GetBalanceChanges() ([]BalanceChange, error) {
+	b := balanceChanges{changes: map[balanceKey]*BalanceChange{}}
+
+	for _, change := range t.GetFeeChanges() {
+		if err := b.addChange(change, true); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := t.GetChanges()
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if err := b.addChange(change, false); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]balanceKey, 0, len(b.changes))
+	for key, change := range b.changes {
+		if change.Amount == 0 && change.Fee == 0 {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].holderType != keys[j].holderType {
+			return keys[i].holderType < keys[j].holderType
+		}
+		if keys[i].holder != keys[j].holder {
+			return keys[i].holder < keys[j].holder
+		}
+		return keys[i].asset < keys[j].asset
+	})
+
+	result := make([]BalanceChange, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *b.changes[key])
+	}
+	return result, nil
+}
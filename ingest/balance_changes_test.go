@@ -0,0 +1,267 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	balanceTestSource      = "GAHK7EEG2WWHVKDNT4CEQFZGKF2LGDSW2IVM4S5DP42RBW3K6BTODB4A"
+	balanceTestDestination = "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+)
+
+func balanceAccountEntry(address string, balance xdr.Int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId: xdr.MustAddress(address),
+				Balance:   balance,
+			},
+		},
+	}
+}
+
+func balanceTrustLineEntry(address string, asset xdr.TrustLineAsset, balance xdr.Int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: xdr.MustAddress(address),
+				Asset:     asset,
+				Balance:   balance,
+			},
+		},
+	}
+}
+
+func balancePoolEntry(id xdr.PoolId, a, b xdr.Asset, reserveA, reserveB xdr.Int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeLiquidityPool,
+			LiquidityPool: &xdr.LiquidityPoolEntry{
+				LiquidityPoolId: id,
+				Body: xdr.LiquidityPoolEntryBody{
+					Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+					ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+						Params: xdr.LiquidityPoolConstantProductParameters{
+							AssetA: a,
+							AssetB: b,
+							Fee:    xdr.LiquidityPoolFeeV18,
+						},
+						ReserveA: reserveA,
+						ReserveB: reserveB,
+					},
+				},
+			},
+		},
+	}
+}
+
+func balanceClaimableBalanceEntry(id xdr.ClaimableBalanceId, asset xdr.Asset, amount xdr.Int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeClaimableBalance,
+			ClaimableBalance: &xdr.ClaimableBalanceEntry{
+				BalanceId: id,
+				Claimants: []xdr.Claimant{},
+				Asset:     asset,
+				Amount:    amount,
+			},
+		},
+	}
+}
+
+func entryChangesUpdated(pre, post xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &pre},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &post},
+	}
+}
+
+func entryChangesCreated(post xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &post},
+	}
+}
+
+func entryChangesRemoved(pre xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	key := pre.LedgerKey()
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &pre},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+	}
+}
+
+func entryChangesConcat(changes ...xdr.LedgerEntryChanges) xdr.LedgerEntryChanges {
+	var result xdr.LedgerEntryChanges
+	for _, c := range changes {
+		result = append(result, c...)
+	}
+	return result
+}
+
+func TestGetBalanceChangesPathPaymentAndPoolDeposit(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", balanceTestDestination)
+	native := xdr.MustNewNativeAsset()
+	poolID := xdr.PoolId{1, 2, 3}
+	poolShare := xdr.TrustLineAsset{Type: xdr.AssetTypeAssetTypePoolShare, LiquidityPoolId: &poolID}
+
+	tx := LedgerTransaction{
+		FeeChanges: entryChangesUpdated(
+			balanceAccountEntry(balanceTestSource, 1000),
+			balanceAccountEntry(balanceTestSource, 900),
+		),
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 2,
+			V2: &xdr.TransactionMetaV2{
+				Operations: []xdr.OperationMeta{
+					{
+						// Path payment of 50 XLM through the pool, delivering 20 USD.
+						Changes: entryChangesConcat(
+							entryChangesUpdated(
+								balanceAccountEntry(balanceTestSource, 900),
+								balanceAccountEntry(balanceTestSource, 850),
+							),
+							entryChangesUpdated(
+								balancePoolEntry(poolID, native, usd, 500, 200),
+								balancePoolEntry(poolID, native, usd, 550, 180),
+							),
+							entryChangesUpdated(
+								balanceTrustLineEntry(balanceTestDestination, usd.ToTrustLineAsset(), 0),
+								balanceTrustLineEntry(balanceTestDestination, usd.ToTrustLineAsset(), 20),
+							),
+						),
+					},
+					{
+						// Deposit of 100 XLM and 40 USD into the pool.
+						Changes: entryChangesConcat(
+							entryChangesUpdated(
+								balanceAccountEntry(balanceTestSource, 850),
+								balanceAccountEntry(balanceTestSource, 750),
+							),
+							entryChangesUpdated(
+								balanceTrustLineEntry(balanceTestSource, usd.ToTrustLineAsset(), 100),
+								balanceTrustLineEntry(balanceTestSource, usd.ToTrustLineAsset(), 60),
+							),
+							entryChangesUpdated(
+								balancePoolEntry(poolID, native, usd, 550, 180),
+								balancePoolEntry(poolID, native, usd, 650, 220),
+							),
+							entryChangesCreated(balanceTrustLineEntry(balanceTestSource, poolShare, 30)),
+						),
+					},
+				},
+			},
+		},
+	}
+
+	changes, err := tx.GetBalanceChanges()
+	require.NoError(t, err)
+
+	poolHex := xdr.Hash(poolID).HexString()
+	assert.Equal(t, []BalanceChange{
+		{HolderType: BalanceHolderAccount, Holder: balanceTestSource, Asset: usd.ToTrustLineAsset(), Amount: -40},
+		{HolderType: BalanceHolderAccount, Holder: balanceTestSource, Asset: native.ToTrustLineAsset(), Amount: -250, Fee: -100},
+		{HolderType: BalanceHolderAccount, Holder: balanceTestSource, Asset: poolShare, Amount: 30},
+		{HolderType: BalanceHolderAccount, Holder: balanceTestDestination, Asset: usd.ToTrustLineAsset(), Amount: 20},
+		{HolderType: BalanceHolderLiquidityPool, Holder: poolHex, Asset: usd.ToTrustLineAsset(), Amount: 20},
+		{HolderType: BalanceHolderLiquidityPool, Holder: poolHex, Asset: native.ToTrustLineAsset(), Amount: 150},
+	}, changes)
+}
+
+func TestGetBalanceChangesClaimableBalances(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", balanceTestDestination)
+	native := xdr.MustNewNativeAsset()
+	created1 := xdr.ClaimableBalanceId{Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0, V0: &xdr.Hash{1}}
+	claimed := xdr.ClaimableBalanceId{Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0, V0: &xdr.Hash{2}}
+
+	tx := LedgerTransaction{
+		// Fee bump transaction: the fee is charged to the fee source which
+		// is also the destination of the claimed balance.
+		FeeChanges: entryChangesUpdated(
+			balanceAccountEntry(balanceTestDestination, 1000),
+			balanceAccountEntry(balanceTestDestination, 800),
+		),
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 2,
+			V2: &xdr.TransactionMetaV2{
+				Operations: []xdr.OperationMeta{
+					{
+						Changes: entryChangesConcat(
+							entryChangesUpdated(
+								balanceTrustLineEntry(balanceTestSource, usd.ToTrustLineAsset(), 100),
+								balanceTrustLineEntry(balanceTestSource, usd.ToTrustLineAsset(), 75),
+							),
+							entryChangesCreated(balanceClaimableBalanceEntry(created1, usd, 25)),
+						),
+					},
+					{
+						Changes: entryChangesConcat(
+							entryChangesRemoved(balanceClaimableBalanceEntry(claimed, native, 300)),
+							entryChangesUpdated(
+								balanceAccountEntry(balanceTestDestination, 800),
+								balanceAccountEntry(balanceTestDestination, 1100),
+							),
+						),
+					},
+				},
+			},
+		},
+	}
+
+	changes, err := tx.GetBalanceChanges()
+	require.NoError(t, err)
+
+	createdHex, err := xdr.MarshalHex(created1)
+	require.NoError(t, err)
+	claimedHex, err := xdr.MarshalHex(claimed)
+	require.NoError(t, err)
+
+	expected := []BalanceChange{
+		{HolderType: BalanceHolderAccount, Holder: balanceTestSource, Asset: usd.ToTrustLineAsset(), Amount: -25},
+		{HolderType: BalanceHolderAccount, Holder: balanceTestDestination, Asset: native.ToTrustLineAsset(), Amount: 100, Fee: -200},
+		{HolderType: BalanceHolderClaimableBalance, Holder: createdHex, Asset: usd.ToTrustLineAsset(), Amount: 25},
+		{HolderType: BalanceHolderClaimableBalance, Holder: claimedHex, Asset: native.ToTrustLineAsset(), Amount: -300},
+	}
+	if createdHex > claimedHex {
+		expected[2], expected[3] = expected[3], expected[2]
+	}
+	assert.Equal(t, expected, changes)
+}
+
+func TestGetBalanceChangesOmitsUnchangedBalances(t *testing.T) {
+	tx := LedgerTransaction{
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 2,
+			V2: &xdr.TransactionMetaV2{
+				Operations: []xdr.OperationMeta{
+					{
+						Changes: entryChangesUpdated(
+							balanceAccountEntry(balanceTestSource, 100),
+							balanceAccountEntry(balanceTestSource, 50),
+						),
+					},
+					{
+						Changes: entryChangesUpdated(
+							balanceAccountEntry(balanceTestSource, 50),
+							balanceAccountEntry(balanceTestSource, 100),
+						),
+					},
+				},
+			},
+		},
+	}
+
+	changes, err := tx.GetBalanceChanges()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	tx.UnsafeMeta = xdr.TransactionMeta{V: 0, Operations: &[]xdr.OperationMeta{}}
+	_, err = tx.GetBalanceChanges()
+	assert.Error(t, err)
+}
@@ -16,6 +16,9 @@ import (
 type readResult struct {
 	entryChange xdr.LedgerEntryChange
 	e           error
+	// bucketIndex is the index of the bucket containing the entry, see
+	// CheckpointChangeReader.ReadWithBucketIndex.
+	bucketIndex int
 }
 
 // CheckpointChangeReader is a ChangeReader which returns Changes from a history archive
@@ -26,6 +29,7 @@ type CheckpointChangeReader struct {
 	has        *historyarchive.HistoryArchiveState
 	archive    historyarchive.ArchiveInterface
 	tempStore  tempSet
+	filter     *checkpointFilter
 	sequence   uint32
 	readChan   chan readResult
 	streamOnce sync.Once
@@ -84,6 +88,18 @@ func NewCheckpointChangeReader(
 	ctx context.Context,
 	archive historyarchive.ArchiveInterface,
 	sequence uint32,
+) (*CheckpointChangeReader, error) {
+	return NewFilteredCheckpointChangeReader(ctx, archive, sequence, CheckpointFilter{})
+}
+
+// NewFilteredCheckpointChangeReader constructs a new CheckpointChangeReader
+// instance which only returns ledger entries matching the given filter. See
+// CheckpointFilter for details.
+func NewFilteredCheckpointChangeReader(
+	ctx context.Context,
+	archive historyarchive.ArchiveInterface,
+	sequence uint32,
+	filter CheckpointFilter,
 ) (*CheckpointChangeReader, error) {
 	manager := archive.GetCheckpointManager()
 
@@ -114,6 +130,7 @@ func NewCheckpointChangeReader(
 		has:        &has,
 		archive:    archive,
 		tempStore:  tempStore,
+		filter:     newCheckpointFilter(filter),
 		sequence:   sequence,
 		readChan:   make(chan readResult, msrBufferSize),
 		streamOnce: sync.Once{},
@@ -176,9 +193,10 @@ func (r *CheckpointChangeReader) streamBuckets() {
 	}()
 
 	var buckets []historyarchive.Hash
+	var bucketIndices []int
 	for i := 0; i < len(r.has.CurrentBuckets); i++ {
 		b := r.has.CurrentBuckets[i]
-		for _, hashString := range []string{b.Curr, b.Snap} {
+		for j, hashString := range []string{b.Curr, b.Snap} {
 			hash, err := historyarchive.DecodeHash(hashString)
 			if err != nil {
 				r.readChan <- r.error(errors.Wrap(err, "Error decoding bucket hash"))
@@ -190,6 +208,7 @@ func (r *CheckpointChangeReader) streamBuckets() {
 			}
 
 			buckets = append(buckets, hash)
+			bucketIndices = append(bucketIndices, 2*i+j)
 		}
 	}
 
@@ -224,7 +243,7 @@ func (r *CheckpointChangeReader) streamBuckets() {
 
 	for i, hash := range buckets {
 		oldestBucket := i == len(buckets)-1
-		if shouldContinue := r.streamBucketContents(hash, oldestBucket); !shouldContinue {
+		if shouldContinue := r.streamBucketContents(hash, bucketIndices[i], oldestBucket); !shouldContinue {
 			break
 		}
 	}
@@ -297,7 +316,7 @@ func (r *CheckpointChangeReader) newXDRStream(hash historyarchive.Hash) (
 }
 
 // streamBucketContents pushes value onto the read channel, returning false when the channel needs to be closed otherwise true
-func (r *CheckpointChangeReader) streamBucketContents(hash historyarchive.Hash, oldestBucket bool) bool {
+func (r *CheckpointChangeReader) streamBucketContents(hash historyarchive.Hash, bucketIndex int, oldestBucket bool) bool {
 	rdr, e := r.newXDRStream(hash)
 	if e != nil {
 		r.readChan <- r.error(
@@ -367,6 +386,11 @@ LoopBucketEntry:
 					continue
 				}
 
+				if r.filter.matchKey(key) == filterExclude {
+					// Entries excluded by the filter never reach the temp store.
+					continue
+				}
+
 				// We're using compressed keys here
 				keyBytes, e := key.MarshalBinaryCompress()
 				if e != nil {
@@ -421,6 +445,19 @@ LoopBucketEntry:
 			return false
 		}
 
+		// Filtering on the ledger key is safe with regard to shadowed and
+		// removed entries because all versions of an entry share the same key.
+		keyFilterResult := r.filter.matchKey(key)
+		if keyFilterResult == filterExclude {
+			select {
+			case <-r.done:
+				// Close() called: stop processing buckets.
+				return false
+			default:
+				continue LoopBucketEntry
+			}
+		}
+
 		// We're using compressed keys here
 		keyBytes, e := key.MarshalBinaryCompress()
 		if e != nil {
@@ -450,13 +487,17 @@ LoopBucketEntry:
 			}
 
 			if !seen {
-				// Return LEDGER_ENTRY_STATE changes only now.
+				// Return LEDGER_ENTRY_STATE changes only now. Entries which
+				// don't match the filter are still tracked in tempStore below
+				// so that they shadow older versions which could match it.
 				liveEntry := entry.MustLiveEntry()
-				entryChange := xdr.LedgerEntryChange{
-					Type:  xdr.LedgerEntryChangeTypeLedgerEntryState,
-					State: &liveEntry,
+				if keyFilterResult == filterInclude || r.filter.matchEntry(liveEntry) {
+					entryChange := xdr.LedgerEntryChange{
+						Type:  xdr.LedgerEntryChangeTypeLedgerEntryState,
+						State: &liveEntry,
+					}
+					r.readChan <- readResult{entryChange: entryChange, bucketIndex: bucketIndex}
 				}
-				r.readChan <- readResult{entryChange, nil}
 
 				// We don't update `tempStore` for INITENTRY because CAP-20 says:
 				// > a bucket entry marked INITENTRY implies that either no entry
@@ -505,6 +546,17 @@ LoopBucketEntry:
 
 // Read returns a new ledger entry change on each call, returning io.EOF when the stream ends.
 func (r *CheckpointChangeReader) Read() (Change, error) {
+	change, _, err := r.ReadWithBucketIndex()
+	return change, err
+}
+
+// ReadWithBucketIndex is like Read but it also returns the position in the
+// HAS bucket list of the bucket containing the entry of the returned Change.
+// The curr and snap buckets of level i have indices 2*i and 2*i+1
+// respectively. Buckets are streamed in increasing index order so once a
+// Change from bucket n is returned, all Changes from buckets with lower
+// indices were returned.
+func (r *CheckpointChangeReader) ReadWithBucketIndex() (Change, int, error) {
 	r.streamOnce.Do(func() {
 		go r.streamBuckets()
 	})
@@ -513,20 +565,20 @@ func (r *CheckpointChangeReader) Read() (Change, error) {
 	result, ok := <-r.readChan
 	if !ok {
 		// when channel is closed then return io.EOF
-		return Change{}, io.EOF
+		return Change{}, 0, io.EOF
 	}
 
 	if result.e != nil {
-		return Change{}, errors.Wrap(result.e, "Error while reading from buckets")
+		return Change{}, 0, errors.Wrap(result.e, "Error while reading from buckets")
 	}
 	return Change{
 		Type: result.entryChange.EntryType(),
 		Post: result.entryChange.State,
-	}, nil
+	}, result.bucketIndex, nil
 }
 
 func (r *CheckpointChangeReader) error(err error) readResult {
-	return readResult{xdr.LedgerEntryChange{}, err}
+	return readResult{e: err}
 }
 
 func (r *CheckpointChangeReader) close() {
@@ -220,6 +220,211 @@ func (s *SingleLedgerStateReaderTestSuite) TestEnsureLatestLiveEntry() {
 	s.Require().Equal(err, io.EOF)
 }
 
+// TestReadWithBucketIndex tests that ReadWithBucketIndex returns the position
+// of the bucket of the returned entry in the HAS bucket list.
+func (s *SingleLedgerStateReaderTestSuite) TestReadWithBucketIndex() {
+	nextBucket := s.getNextBucketChannel()
+
+	// Level 0 curr...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(
+			metaEntry(11),
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML", 1),
+		), nil).Once()
+	// ...level 0 snap...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(), nil).Once()
+	// ...level 1 curr...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(), nil).Once()
+	// ...level 1 snap...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(
+			metaEntry(11),
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB", 1),
+		), nil).Once()
+
+	// ...and empty streams for the rest of the buckets.
+	for hash := range nextBucket {
+		s.mockArchive.
+			On("GetXdrStreamForHash", hash).
+			Return(createXdrStream(), nil).Once()
+	}
+
+	_, index, err := s.reader.ReadWithBucketIndex()
+	s.Require().NoError(err)
+	s.Assert().Equal(0, index)
+
+	_, index, err = s.reader.ReadWithBucketIndex()
+	s.Require().NoError(err)
+	s.Assert().Equal(3, index)
+
+	_, _, err = s.reader.ReadWithBucketIndex()
+	s.Require().Equal(err, io.EOF)
+}
+
+// TestConcurrentReadWithBucketIndex tests that concurrent calls to
+// ReadWithBucketIndex return the bucket index of their own entry.
+func (s *SingleLedgerStateReaderTestSuite) TestConcurrentReadWithBucketIndex() {
+	expected := map[string]int{
+		"GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML": 0,
+		"GCMNSW2UZMSH3ZFRLWP6TW2TG4UX4HLSYO5HNIKUSFMLN2KFSF26JKWF": 0,
+		"GB6IPC7LIOSRY26MXHQ3QJ32MTELYAA6YFIRBXZVVGTU7AOI4KUFOQ54": 1,
+		"GCK45YKCFNIOICB4TWPCOPWLQYNUKCJVV7OMMHH55AB3DD67K4E54STO": 1,
+		"GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB": 3,
+	}
+
+	nextBucket := s.getNextBucketChannel()
+
+	// Level 0 curr...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML", 1),
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GCMNSW2UZMSH3ZFRLWP6TW2TG4UX4HLSYO5HNIKUSFMLN2KFSF26JKWF", 1),
+		), nil).Once()
+	// ...level 0 snap...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GB6IPC7LIOSRY26MXHQ3QJ32MTELYAA6YFIRBXZVVGTU7AOI4KUFOQ54", 1),
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GCK45YKCFNIOICB4TWPCOPWLQYNUKCJVV7OMMHH55AB3DD67K4E54STO", 1),
+		), nil).Once()
+	// ...level 1 curr...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(), nil).Once()
+	// ...level 1 snap...
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(createXdrStream(
+			entryAccount(xdr.BucketEntryTypeLiveentry, "GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB", 1),
+		), nil).Once()
+
+	// ...and empty streams for the rest of the buckets.
+	for hash := range nextBucket {
+		s.mockArchive.
+			On("GetXdrStreamForHash", hash).
+			Return(createXdrStream(), nil).Once()
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	actual := map[string]int{}
+
+	for i := 0; i < len(expected); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			change, index, err := s.reader.ReadWithBucketIndex()
+			if !s.Assert().NoError(err) {
+				return
+			}
+			mutex.Lock()
+			actual[change.Post.Data.MustAccount().AccountId.Address()] = index
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	s.Assert().Equal(expected, actual)
+
+	_, _, err := s.reader.ReadWithBucketIndex()
+	s.Require().Equal(err, io.EOF)
+}
+
+// TestFilterAccounts tests that only entries of the filtered accounts are returned
+func (s *SingleLedgerStateReaderTestSuite) TestFilterAccounts() {
+	s.reader.filter = newCheckpointFilter(CheckpointFilter{
+		AccountIDs: []string{"GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"},
+	})
+
+	curr1 := createXdrStream(
+		metaEntry(11),
+		entryAccount(xdr.BucketEntryTypeLiveentry, "GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB", 1),
+		entryAccount(xdr.BucketEntryTypeLiveentry, "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML", 2),
+	)
+	snap1 := createXdrStream(
+		metaEntry(11),
+		entryAccount(xdr.BucketEntryTypeLiveentry, "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML", 3),
+	)
+
+	nextBucket := s.getNextBucketChannel()
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(curr1, nil).Once()
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(snap1, nil).Once()
+	for hash := range nextBucket {
+		s.mockArchive.
+			On("GetXdrStreamForHash", hash).
+			Return(createXdrStream(), nil).Once()
+	}
+
+	entry, err := s.reader.Read()
+	s.Require().NoError(err)
+	s.Assert().Equal("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML", entry.Post.Data.MustAccount().AccountId.Address())
+	s.Assert().Equal(xdr.Int64(2), entry.Post.Data.MustAccount().Balance)
+
+	_, err = s.reader.Read()
+	s.Require().Equal(err, io.EOF)
+}
+
+// TestFilterShadowedEntries tests that a newer entry which doesn't match the
+// filter still shadows an older version of the entry which matches it.
+func (s *SingleLedgerStateReaderTestSuite) TestFilterShadowedEntries() {
+	usd := xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	eur := xdr.MustNewCreditAsset("EUR", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	s.reader.filter = newCheckpointFilter(CheckpointFilter{
+		LedgerEntryTypes: []xdr.LedgerEntryType{xdr.LedgerEntryTypeOffer},
+		Assets:           []xdr.Asset{usd},
+	})
+
+	seller := "GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB"
+	curr1 := createXdrStream(
+		metaEntry(11),
+		entryAccount(xdr.BucketEntryTypeLiveentry, seller, 1),
+		entryOffer(xdr.BucketEntryTypeLiveentry, seller, 1, eur),
+		entryOffer(xdr.BucketEntryTypeDeadentry, seller, 2, usd),
+		entryOffer(xdr.BucketEntryTypeLiveentry, seller, 3, usd),
+	)
+	snap1 := createXdrStream(
+		metaEntry(11),
+		entryOffer(xdr.BucketEntryTypeLiveentry, seller, 1, usd),
+		entryOffer(xdr.BucketEntryTypeLiveentry, seller, 2, usd),
+		entryOffer(xdr.BucketEntryTypeLiveentry, seller, 4, usd),
+	)
+
+	nextBucket := s.getNextBucketChannel()
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(curr1, nil).Once()
+	s.mockArchive.
+		On("GetXdrStreamForHash", <-nextBucket).
+		Return(snap1, nil).Once()
+	for hash := range nextBucket {
+		s.mockArchive.
+			On("GetXdrStreamForHash", hash).
+			Return(createXdrStream(), nil).Once()
+	}
+
+	var offerIDs []xdr.Int64
+	for {
+		entry, err := s.reader.Read()
+		if err == io.EOF {
+			break
+		}
+		s.Require().NoError(err)
+		offerIDs = append(offerIDs, entry.Post.Data.MustOffer().OfferId)
+	}
+	s.Assert().Equal([]xdr.Int64{3, 4}, offerIDs)
+}
+
 // TestMalformedProtocol11Bucket tests a buggy protocol 11 bucket (meta not the first entry)
 func (s *SingleLedgerStateReaderTestSuite) TestMalformedProtocol11Bucket() {
 	curr1 := createXdrStream(
@@ -765,6 +970,41 @@ func entryAccount(t xdr.BucketEntryType, id string, balance uint32) xdr.BucketEn
 	}
 }
 
+func entryOffer(t xdr.BucketEntryType, seller string, id int64, selling xdr.Asset) xdr.BucketEntry {
+	switch t {
+	case xdr.BucketEntryTypeLiveentry, xdr.BucketEntryTypeInitentry:
+		return xdr.BucketEntry{
+			Type: t,
+			LiveEntry: &xdr.LedgerEntry{
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeOffer,
+					Offer: &xdr.OfferEntry{
+						SellerId: xdr.MustAddress(seller),
+						OfferId:  xdr.Int64(id),
+						Selling:  selling,
+						Buying:   xdr.MustNewNativeAsset(),
+						Amount:   1,
+						Price:    xdr.Price{N: 1, D: 1},
+					},
+				},
+			},
+		}
+	case xdr.BucketEntryTypeDeadentry:
+		return xdr.BucketEntry{
+			Type: xdr.BucketEntryTypeDeadentry,
+			DeadEntry: &xdr.LedgerKey{
+				Type: xdr.LedgerEntryTypeOffer,
+				Offer: &xdr.LedgerKeyOffer{
+					SellerId: xdr.MustAddress(seller),
+					OfferId:  xdr.Int64(id),
+				},
+			},
+		}
+	default:
+		panic("Unkown entry type")
+	}
+}
+
 type errCloser struct {
 	io.Reader
 	err error
@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"github.com/stellar/go/xdr"
+)
+
+// CheckpointFilter restricts the ledger entries returned by a
+// CheckpointChangeReader. Every non-empty field must match for an entry to be
+// returned. Within a single field it is enough for any of the values to match.
+// An empty filter matches all entries.
+//
+// Filtering happens while buckets are streamed: entries which can be excluded
+// based on their ledger key alone (entry type, owning account, trustline asset,
+// liquidity pool ID) are never added to the temporary set used to track
+// shadowed and removed entries. Entries which can only be matched on their
+// body (ex. assets of an offer) are tracked as usual so that newer versions of
+// an entry still shadow older ones across bucket levels.
+type CheckpointFilter struct {
+	// LedgerEntryTypes restricts entries to the given types.
+	LedgerEntryTypes []xdr.LedgerEntryType
+	// AccountIDs restricts entries to ones related to the given accounts:
+	// the account itself, its trustlines, offers and data entries, and
+	// claimable balances claimable by the account.
+	AccountIDs []string
+	// Assets restricts entries to ones related to the given assets: accounts
+	// (native asset only), trustlines, offers selling or buying the asset,
+	// claimable balances and liquidity pools holding the asset.
+	Assets []xdr.Asset
+	// LiquidityPoolIDs restricts entries to the given liquidity pools and
+	// trustlines to their pool shares.
+	LiquidityPoolIDs []xdr.PoolId
+}
+
+type filterResult int
+
+const (
+	filterInclude filterResult = iota
+	filterExclude
+	// filterUndecided means the ledger key does not contain enough
+	// information and the ledger entry itself must be checked.
+	filterUndecided
+)
+
+// checkpointFilter is a compiled version of CheckpointFilter.
+type checkpointFilter struct {
+	types    map[xdr.LedgerEntryType]bool
+	accounts map[string]bool
+	assets   map[string]bool
+	pools    map[xdr.PoolId]bool
+}
+
+func newCheckpointFilter(filter CheckpointFilter) *checkpointFilter {
+	f := &checkpointFilter{}
+	if len(filter.LedgerEntryTypes) > 0 {
+		f.types = map[xdr.LedgerEntryType]bool{}
+		for _, t := range filter.LedgerEntryTypes {
+			f.types[t] = true
+		}
+	}
+	if len(filter.AccountIDs) > 0 {
+		f.accounts = map[string]bool{}
+		for _, account := range filter.AccountIDs {
+			f.accounts[account] = true
+		}
+	}
+	if len(filter.Assets) > 0 {
+		f.assets = map[string]bool{}
+		for _, asset := range filter.Assets {
+			f.assets[asset.StringCanonical()] = true
+		}
+	}
+	if len(filter.LiquidityPoolIDs) > 0 {
+		f.pools = map[xdr.PoolId]bool{}
+		for _, id := range filter.LiquidityPoolIDs {
+			f.pools[id] = true
+		}
+	}
+	return f
+}
+
+func (f *checkpointFilter) hasAsset(asset xdr.Asset) bool {
+	return f.assets[asset.StringCanonical()]
+}
+
+func combine(results ...filterResult) filterResult {
+	combined := filterInclude
+	for _, result := range results {
+		if result == filterExclude {
+			return filterExclude
+		}
+		if result == filterUndecided {
+			combined = filterUndecided
+		}
+	}
+	return combined
+}
+
+func boolResult(match bool) filterResult {
+	if match {
+		return filterInclude
+	}
+	return filterExclude
+}
+
+// matchKey checks the ledger key against the filter.
+func (f *checkpointFilter) matchKey(key xdr.LedgerKey) filterResult {
+	typeResult := filterInclude
+	if f.types != nil {
+		typeResult = boolResult(f.types[key.Type])
+	}
+	if typeResult == filterExclude {
+		return filterExclude
+	}
+
+	return combine(typeResult, f.matchKeyAccount(key), f.matchKeyAsset(key), f.matchKeyPool(key))
+}
+
+func (f *checkpointFilter) matchKeyAccount(key xdr.LedgerKey) filterResult {
+	if f.accounts == nil {
+		return filterInclude
+	}
+	switch key.Type {
+	case xdr.LedgerEntryTypeAccount:
+		return boolResult(f.accounts[key.Account.AccountId.Address()])
+	case xdr.LedgerEntryTypeTrustline:
+		return boolResult(f.accounts[key.TrustLine.AccountId.Address()])
+	case xdr.LedgerEntryTypeOffer:
+		return boolResult(f.accounts[key.Offer.SellerId.Address()])
+	case xdr.LedgerEntryTypeData:
+		return boolResult(f.accounts[key.Data.AccountId.Address()])
+	case xdr.LedgerEntryTypeClaimableBalance:
+		return filterUndecided
+	default:
+		return filterExclude
+	}
+}
+
+func (f *checkpointFilter) matchKeyAsset(key xdr.LedgerKey) filterResult {
+	if f.assets == nil {
+		return filterInclude
+	}
+	switch key.Type {
+	case xdr.LedgerEntryTypeAccount:
+		return boolResult(f.hasAsset(xdr.MustNewNativeAsset()))
+	case xdr.LedgerEntryTypeTrustline:
+		if key.TrustLine.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			return filterExclude
+		}
+		return boolResult(f.hasAsset(key.TrustLine.Asset.ToAsset()))
+	case xdr.LedgerEntryTypeOffer, xdr.LedgerEntryTypeClaimableBalance, xdr.LedgerEntryTypeLiquidityPool:
+		return filterUndecided
+	default:
+		return filterExclude
+	}
+}
+
+func (f *checkpointFilter) matchKeyPool(key xdr.LedgerKey) filterResult {
+	if f.pools == nil {
+		return filterInclude
+	}
+	switch key.Type {
+	case xdr.LedgerEntryTypeLiquidityPool:
+		return boolResult(f.pools[key.LiquidityPool.LiquidityPoolId])
+	case xdr.LedgerEntryTypeTrustline:
+		if key.TrustLine.Asset.Type != xdr.AssetTypeAssetTypePoolShare {
+			return filterExclude
+		}
+		return boolResult(f.pools[*key.TrustLine.Asset.LiquidityPoolId])
+	default:
+		return filterExclude
+	}
+}
+
+// matchEntry checks the ledger entry against the filter. It must only be
+// called for entries whose key returned filterUndecided.
+func (f *checkpointFilter) matchEntry(entry xdr.LedgerEntry) bool {
+	switch entry.Data.Type {
+	case xdr.LedgerEntryTypeOffer:
+		offer := entry.Data.MustOffer()
+		if f.assets != nil && !f.hasAsset(offer.Selling) && !f.hasAsset(offer.Buying) {
+			return false
+		}
+	case xdr.LedgerEntryTypeClaimableBalance:
+		balance := entry.Data.MustClaimableBalance()
+		if f.assets != nil && !f.hasAsset(balance.Asset) {
+			return false
+		}
+		if f.accounts != nil {
+			found := false
+			for _, claimant := range balance.Claimants {
+				if f.accounts[claimant.MustV0().Destination.Address()] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	case xdr.LedgerEntryTypeLiquidityPool:
+		params := entry.Data.MustLiquidityPool().Body.MustConstantProduct().Params
+		if f.assets != nil && !f.hasAsset(params.AssetA) && !f.hasAsset(params.AssetB) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go/xdr"
+)
+
+func TestCheckpointFilterMatchKey(t *testing.T) {
+	account := xdr.MustAddress("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	other := xdr.MustAddress("GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB")
+	usd := xdr.MustNewCreditAsset("USD", account.Address())
+	eur := xdr.MustNewCreditAsset("EUR", account.Address())
+	poolID := xdr.PoolId{1, 2, 3}
+
+	var accountKey, otherAccountKey, usdTrustline, eurTrustline, poolTrustline, poolKey, offerKey xdr.LedgerKey
+	assert.NoError(t, accountKey.SetAccount(account))
+	assert.NoError(t, otherAccountKey.SetAccount(other))
+	assert.NoError(t, usdTrustline.SetTrustline(account, usd.ToTrustLineAsset()))
+	assert.NoError(t, eurTrustline.SetTrustline(account, eur.ToTrustLineAsset()))
+	assert.NoError(t, poolTrustline.SetTrustline(account, xdr.TrustLineAsset{
+		Type:            xdr.AssetTypeAssetTypePoolShare,
+		LiquidityPoolId: &poolID,
+	}))
+	assert.NoError(t, poolKey.SetLiquidityPool(poolID))
+	assert.NoError(t, offerKey.SetOffer(account, 1))
+
+	empty := newCheckpointFilter(CheckpointFilter{})
+	for _, key := range []xdr.LedgerKey{accountKey, usdTrustline, poolKey, offerKey} {
+		assert.Equal(t, filterInclude, empty.matchKey(key))
+	}
+
+	byAccount := newCheckpointFilter(CheckpointFilter{AccountIDs: []string{account.Address()}})
+	assert.Equal(t, filterInclude, byAccount.matchKey(accountKey))
+	assert.Equal(t, filterExclude, byAccount.matchKey(otherAccountKey))
+	assert.Equal(t, filterInclude, byAccount.matchKey(usdTrustline))
+	assert.Equal(t, filterInclude, byAccount.matchKey(offerKey))
+	assert.Equal(t, filterExclude, byAccount.matchKey(poolKey))
+
+	byTypeAndAsset := newCheckpointFilter(CheckpointFilter{
+		LedgerEntryTypes: []xdr.LedgerEntryType{xdr.LedgerEntryTypeTrustline, xdr.LedgerEntryTypeOffer},
+		Assets:           []xdr.Asset{usd},
+	})
+	assert.Equal(t, filterExclude, byTypeAndAsset.matchKey(accountKey))
+	assert.Equal(t, filterInclude, byTypeAndAsset.matchKey(usdTrustline))
+	assert.Equal(t, filterExclude, byTypeAndAsset.matchKey(eurTrustline))
+	assert.Equal(t, filterExclude, byTypeAndAsset.matchKey(poolTrustline))
+	assert.Equal(t, filterUndecided, byTypeAndAsset.matchKey(offerKey))
+
+	byPool := newCheckpointFilter(CheckpointFilter{LiquidityPoolIDs: []xdr.PoolId{poolID}})
+	assert.Equal(t, filterInclude, byPool.matchKey(poolKey))
+	assert.Equal(t, filterInclude, byPool.matchKey(poolTrustline))
+	assert.Equal(t, filterExclude, byPool.matchKey(usdTrustline))
+	assert.Equal(t, filterExclude, byPool.matchKey(accountKey))
+}
+
+func TestCheckpointFilterMatchEntry(t *testing.T) {
+	account := xdr.MustAddress("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	other := xdr.MustAddress("GALPCCZN4YXA3YMJHKL6CVIECKPLJJCTVMSNYWBTKJW4K5HQLYLDMZTB")
+	usd := xdr.MustNewCreditAsset("USD", account.Address())
+
+	balance := xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeClaimableBalance,
+			ClaimableBalance: &xdr.ClaimableBalanceEntry{
+				Asset: usd,
+				Claimants: []xdr.Claimant{
+					{
+						Type: xdr.ClaimantTypeClaimantTypeV0,
+						V0:   &xdr.ClaimantV0{Destination: other},
+					},
+				},
+			},
+		},
+	}
+
+	assert.True(t, newCheckpointFilter(CheckpointFilter{Assets: []xdr.Asset{usd}}).matchEntry(balance))
+	assert.False(t, newCheckpointFilter(CheckpointFilter{Assets: []xdr.Asset{xdr.MustNewNativeAsset()}}).matchEntry(balance))
+	assert.True(t, newCheckpointFilter(CheckpointFilter{AccountIDs: []string{other.Address()}}).matchEntry(balance))
+	assert.False(t, newCheckpointFilter(CheckpointFilter{AccountIDs: []string{account.Address()}}).matchEntry(balance))
+}
@@ -0,0 +1,96 @@
+package ingest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// CursorStore persists the sequence of the last ledger committed by a
+// ProcessorRunner so an interrupted run can be resumed. Implementations
+// backed by the same database as the processors should update the cursor in
+// the same transaction as the processors' data (see
+// ProcessorRunnerConfig.BeforeCommit) to make commits atomic.
+type CursorStore interface {
+	// GetLastLedger returns the last committed ledger or 0 if no ledger was
+	// committed yet.
+	GetLastLedger(ctx context.Context) (uint32, error)
+	// SetLastLedger stores the last committed ledger.
+	SetLastLedger(ctx context.Context, sequence uint32) error
+}
+
+// MemoryCursorStore is a CursorStore keeping the cursor in memory. It is
+// useful in tests and for runs which do not need to be resumed after a
+// restart.
+type MemoryCursorStore struct {
+	mutex    sync.Mutex
+	sequence uint32
+}
+
+// GetLastLedger returns the last committed ledger.
+func (s *MemoryCursorStore) GetLastLedger(ctx context.Context) (uint32, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sequence, nil
+}
+
+// SetLastLedger stores the last committed ledger.
+func (s *MemoryCursorStore) SetLastLedger(ctx context.Context, sequence uint32) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sequence = sequence
+	return nil
+}
+
+// FileCursorStore is a CursorStore keeping the cursor in a file. The file is
+// replaced atomically on every update.
+type FileCursorStore struct {
+	path string
+}
+
+// NewFileCursorStore returns a FileCursorStore storing the cursor at path.
+// The file is created on the first SetLastLedger call.
+func NewFileCursorStore(path string) *FileCursorStore {
+	return &FileCursorStore{path: path}
+}
+
+// GetLastLedger returns the last committed ledger or 0 if the file does not
+// exist.
+func (s *FileCursorStore) GetLastLedger(ctx context.Context) (uint32, error) {
+	raw, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading cursor file")
+	}
+	sequence, err := strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid cursor in %s", s.path)
+	}
+	return uint32(sequence), nil
+}
+
+// SetLastLedger stores the last committed ledger.
+func (s *FileCursorStore) SetLastLedger(ctx context.Context, sequence uint32) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".cursor-")
+	if err != nil {
+		return errors.Wrap(err, "error creating temporary cursor file")
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(strconv.FormatUint(uint64(sequence), 10) + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "error writing cursor file")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), s.path), "error replacing cursor file")
+}
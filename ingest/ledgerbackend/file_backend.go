@@ -0,0 +1,435 @@
+package ledgerbackend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellar/go/historyarchive"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	ledgerFilePrefix     = "ledger-"
+	ledgerFileSuffix     = ".xdr"
+	ledgerFileGzipSuffix = ".xdr.gz"
+)
+
+// Ensure FileBackend implements LedgerBackend
+var _ LedgerBackend = (*FileBackend)(nil)
+
+// FileBackend is a LedgerBackend which replays xdr.LedgerCloseMeta previously
+// written to a local directory by ExportLedgers. Each ledger is stored in its
+// own (optionally gzipped) XDR file at:
+//
+//	<dir>/ledgers/ww/xx/yy/ledger-wwxxyyzz.xdr[.gz]
+//
+// where wwxxyyzz is the hex encoded ledger sequence. This mirrors the
+// directory layout used by history archives.
+//
+// When an UnboundedRange is prepared, GetLedger blocks until the requested
+// ledger file appears in the directory, so FileBackend can follow a directory
+// which is concurrently being written by ExportLedgers.
+type FileBackend struct {
+	dir          string
+	pollInterval time.Duration
+
+	mutex         sync.RWMutex
+	prepared      *Range
+	closed        bool
+	cancelPending context.CancelFunc
+	ctx           context.Context
+}
+
+// FileBackendOption values can be passed into NewFileBackend to customize a
+// FileBackend instance.
+type FileBackendOption func(b *FileBackend)
+
+// FileBackendPollInterval configures how often the directory is checked for
+// new ledger files when waiting on a ledger in an UnboundedRange.
+func FileBackendPollInterval(d time.Duration) FileBackendOption {
+	return func(b *FileBackend) {
+		b.pollInterval = d
+	}
+}
+
+// NewFileBackend returns a new FileBackend reading ledgers from dir.
+func NewFileBackend(dir string, options ...FileBackendOption) (*FileBackend, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot stat ledger directory")
+	}
+	if !info.IsDir() {
+		return nil, errors.Errorf("%s is not a directory", dir)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	backend := &FileBackend{
+		dir:           dir,
+		pollInterval:  time.Second,
+		ctx:           ctx,
+		cancelPending: cancel,
+	}
+	for _, option := range options {
+		option(backend)
+	}
+	return backend, nil
+}
+
+// GetLatestLedgerSequence returns the sequence of the latest ledger stored in
+// the directory. This method returns an error if not in a session (start with
+// PrepareRange).
+func (b *FileBackend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return 0, errors.New("file backend is closed")
+	}
+	if b.prepared == nil {
+		return 0, errors.New("session is not prepared, call PrepareRange first")
+	}
+
+	latest, ok, err := latestLedgerInDir(b.dir)
+	if err != nil {
+		return 0, err
+	}
+	if !ok || latest < b.prepared.from {
+		return 0, errors.New("no ledgers available in the prepared range")
+	}
+	if b.prepared.bounded && latest > b.prepared.to {
+		return b.prepared.to, nil
+	}
+	return latest, nil
+}
+
+// PrepareRange checks that the files for the given range are present. For a
+// BoundedRange every ledger file in the range must exist. For an
+// UnboundedRange PrepareRange blocks until the first ledger is available.
+func (b *FileBackend) PrepareRange(ctx context.Context, ledgerRange Range) error {
+	b.mutex.RLock()
+	closed := b.closed
+	b.mutex.RUnlock()
+
+	if closed {
+		return errors.New("file backend is closed")
+	}
+	if ledgerRange.bounded && ledgerRange.from > ledgerRange.to {
+		return errors.Errorf("invalid range: %s", ledgerRange)
+	}
+
+	if ledgerRange.bounded {
+		for seq := ledgerRange.from; seq <= ledgerRange.to; seq++ {
+			if _, ok, err := b.findLedgerFile(seq); err != nil {
+				return err
+			} else if !ok {
+				return errors.Errorf("ledger %d is missing from %s", seq, b.dir)
+			}
+		}
+	} else if err := b.waitForLedgerFile(ctx, ledgerRange.from); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return errors.New("file backend is closed")
+	}
+	b.prepared = &ledgerRange
+	return nil
+}
+
+// IsPrepared returns true if a given ledgerRange is prepared.
+func (b *FileBackend) IsPrepared(ctx context.Context, ledgerRange Range) (bool, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed || b.prepared == nil {
+		return false, nil
+	}
+	return b.prepared.Contains(ledgerRange), nil
+}
+
+// GetLedger returns the LedgerCloseMeta for the given sequence. The sequence
+// must be within the prepared range. For an UnboundedRange GetLedger blocks
+// until the ledger file is written to the directory.
+func (b *FileBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	b.mutex.RLock()
+	if b.closed {
+		b.mutex.RUnlock()
+		return xdr.LedgerCloseMeta{}, errors.New("file backend is closed")
+	}
+	if b.prepared == nil {
+		b.mutex.RUnlock()
+		return xdr.LedgerCloseMeta{}, errors.New("session is not prepared, call PrepareRange first")
+	}
+	prepared := *b.prepared
+	b.mutex.RUnlock()
+
+	if sequence < prepared.from {
+		return xdr.LedgerCloseMeta{}, errors.Errorf(
+			"requested ledger %d is behind the prepared range %s", sequence, prepared,
+		)
+	}
+	if prepared.bounded && sequence > prepared.to {
+		return xdr.LedgerCloseMeta{}, errors.Errorf(
+			"requested ledger %d is beyond the prepared range %s", sequence, prepared,
+		)
+	}
+
+	if !prepared.bounded {
+		if err := b.waitForLedgerFile(ctx, sequence); err != nil {
+			return xdr.LedgerCloseMeta{}, err
+		}
+	}
+
+	path, ok, err := b.findLedgerFile(sequence)
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	if !ok {
+		return xdr.LedgerCloseMeta{}, errors.Errorf("ledger %d is missing from %s", sequence, b.dir)
+	}
+
+	ledger, err := readLedgerFile(path)
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	if ledger.LedgerSequence() != sequence {
+		return xdr.LedgerCloseMeta{}, errors.Errorf(
+			"unexpected ledger sequence in %s (expected=%d actual=%d)",
+			path, sequence, ledger.LedgerSequence(),
+		)
+	}
+
+	return ledger, nil
+}
+
+// Close cancels any pending GetLedger or PrepareRange calls waiting on files.
+func (b *FileBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	b.prepared = nil
+	b.cancelPending()
+	return nil
+}
+
+func (b *FileBackend) waitForLedgerFile(ctx context.Context, sequence uint32) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "shutting down")
+		case <-b.ctx.Done():
+			return errors.New("file backend is closed")
+		case <-timer.C:
+		}
+
+		if _, ok, err := b.findLedgerFile(sequence); err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+		timer.Reset(b.pollInterval)
+	}
+}
+
+func (b *FileBackend) findLedgerFile(sequence uint32) (string, bool, error) {
+	base := ledgerFileBase(b.dir, sequence)
+	for _, suffix := range []string{ledgerFileGzipSuffix, ledgerFileSuffix} {
+		path := base + suffix
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, true, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", false, errors.Wrapf(err, "cannot stat %s", path)
+		}
+	}
+	return "", false, nil
+}
+
+// ExportLedgers reads every ledger in ledgerRange from backend and writes it
+// to dir using the layout read by FileBackend. Ledger files are gzipped when
+// compress is true. ExportLedgers calls PrepareRange on backend if the range
+// is not prepared yet.
+//
+// For an UnboundedRange ExportLedgers keeps following the backend until ctx
+// is cancelled.
+func ExportLedgers(
+	ctx context.Context,
+	backend LedgerBackend,
+	ledgerRange Range,
+	dir string,
+	compress bool,
+) error {
+	prepared, err := backend.IsPrepared(ctx, ledgerRange)
+	if err != nil {
+		return errors.Wrap(err, "error checking if range is prepared")
+	}
+	if !prepared {
+		if err = backend.PrepareRange(ctx, ledgerRange); err != nil {
+			return errors.Wrapf(err, "error preparing range %s", ledgerRange)
+		}
+	}
+
+	for seq := ledgerRange.from; !ledgerRange.bounded || seq <= ledgerRange.to; seq++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			return errors.Wrapf(err, "error getting ledger %d", seq)
+		}
+		if err = WriteLedgerFile(dir, ledger, compress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLedgerFile writes a single ledger to dir using the layout read by
+// FileBackend. The file is written to a temporary location first and then
+// renamed so that readers never observe a partially written ledger.
+func WriteLedgerFile(dir string, ledger xdr.LedgerCloseMeta, compress bool) error {
+	raw, err := ledger.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling ledger")
+	}
+
+	sequence := ledger.LedgerSequence()
+	path := ledgerFileBase(dir, sequence) + ledgerFileSuffix
+	if compress {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err = writer.Write(raw); err != nil {
+			return errors.Wrap(err, "error compressing ledger")
+		}
+		if err = writer.Close(); err != nil {
+			return errors.Wrap(err, "error compressing ledger")
+		}
+		raw = buf.Bytes()
+		path = ledgerFileBase(dir, sequence) + ledgerFileGzipSuffix
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "error creating ledger directory")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-"+ledgerFilePrefix)
+	if err != nil {
+		return errors.Wrap(err, "error creating temporary file")
+	}
+	if _, err = tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrapf(err, "error writing ledger %d", sequence)
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrapf(err, "error writing ledger %d", sequence)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrapf(err, "error moving ledger %d into place", sequence)
+	}
+	return nil
+}
+
+func readLedgerFile(path string) (xdr.LedgerCloseMeta, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, errors.Wrapf(err, "error reading %s", path)
+	}
+
+	if strings.HasSuffix(path, ledgerFileGzipSuffix) {
+		var reader *gzip.Reader
+		reader, err = gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return xdr.LedgerCloseMeta{}, errors.Wrapf(err, "error decompressing %s", path)
+		}
+		raw, err = ioutil.ReadAll(reader)
+		if err != nil {
+			reader.Close()
+			return xdr.LedgerCloseMeta{}, errors.Wrapf(err, "error decompressing %s", path)
+		}
+		if err = reader.Close(); err != nil {
+			return xdr.LedgerCloseMeta{}, errors.Wrapf(err, "error decompressing %s", path)
+		}
+	}
+
+	var ledger xdr.LedgerCloseMeta
+	if err = xdr.SafeUnmarshal(raw, &ledger); err != nil {
+		return xdr.LedgerCloseMeta{}, errors.Wrapf(err, "error unmarshaling %s", path)
+	}
+	return ledger, nil
+}
+
+func ledgerFileBase(dir string, sequence uint32) string {
+	return filepath.Join(
+		dir,
+		"ledgers",
+		filepath.FromSlash(historyarchive.CheckpointPrefix(sequence).Path()),
+		fmt.Sprintf("%s%08x", ledgerFilePrefix, sequence),
+	)
+}
+
+// latestLedgerInDir walks the prefix directories in descending order and
+// returns the highest ledger sequence stored in dir.
+func latestLedgerInDir(dir string) (uint32, bool, error) {
+	return latestLedgerInSubdir(filepath.Join(dir, "ledgers"), 0)
+}
+
+func latestLedgerInSubdir(dir string, depth int) (uint32, bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, errors.Wrapf(err, "error listing %s", dir)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() == (depth < len(historyarchive.DirPrefix{})) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	for _, name := range names {
+		if depth < len(historyarchive.DirPrefix{}) {
+			seq, ok, err := latestLedgerInSubdir(filepath.Join(dir, name), depth+1)
+			if err != nil || ok {
+				return seq, ok, err
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(name, ledgerFilePrefix) {
+			continue
+		}
+		hex := strings.TrimPrefix(name, ledgerFilePrefix)
+		hex = strings.TrimSuffix(strings.TrimSuffix(hex, ".gz"), ledgerFileSuffix)
+		seq, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			continue
+		}
+		return uint32(seq), true, nil
+	}
+	return 0, false, nil
+}
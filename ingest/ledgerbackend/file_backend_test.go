@@ -0,0 +1,139 @@
+package ledgerbackend
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/xdr"
+)
+
+func exportTestLedgers(t *testing.T, from, to uint32, compress bool) string {
+	dir, err := ioutil.TempDir("", "file-backend")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	ctx := context.Background()
+	ledgerRange := BoundedRange(from, to)
+	source := &MockDatabaseBackend{}
+	source.On("IsPrepared", ctx, ledgerRange).Return(false, nil).Once()
+	source.On("PrepareRange", ctx, ledgerRange).Return(nil).Once()
+	for seq := from; seq <= to; seq++ {
+		source.On("GetLedger", ctx, seq).
+			Return(buildLedgerCloseMeta(testLedgerHeader{sequence: seq}), nil).Once()
+	}
+
+	require.NoError(t, ExportLedgers(ctx, source, ledgerRange, dir, compress))
+	source.AssertExpectations(t)
+	return dir
+}
+
+func TestFileBackendRoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := exportTestLedgers(t, 255, 260, compress)
+		ctx := context.Background()
+
+		backend, err := NewFileBackend(dir)
+		require.NoError(t, err)
+
+		_, err = backend.GetLedger(ctx, 255)
+		assert.EqualError(t, err, "session is not prepared, call PrepareRange first")
+
+		require.NoError(t, backend.PrepareRange(ctx, BoundedRange(256, 260)))
+		prepared, err := backend.IsPrepared(ctx, BoundedRange(257, 259))
+		require.NoError(t, err)
+		assert.True(t, prepared)
+
+		latest, err := backend.GetLatestLedgerSequence(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(260), latest)
+
+		for seq := uint32(256); seq <= 260; seq++ {
+			var ledger xdr.LedgerCloseMeta
+			ledger, err = backend.GetLedger(ctx, seq)
+			require.NoError(t, err)
+			var expected, actual string
+			expected, err = xdr.MarshalBase64(buildLedgerCloseMeta(testLedgerHeader{sequence: seq}))
+			require.NoError(t, err)
+			actual, err = xdr.MarshalBase64(ledger)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		}
+
+		_, err = backend.GetLedger(ctx, 261)
+		assert.EqualError(t, err, "requested ledger 261 is beyond the prepared range [256,260]")
+		_, err = backend.GetLedger(ctx, 255)
+		assert.EqualError(t, err, "requested ledger 255 is behind the prepared range [256,260]")
+
+		require.NoError(t, backend.Close())
+		_, err = backend.GetLedger(ctx, 256)
+		assert.EqualError(t, err, "file backend is closed")
+	}
+}
+
+func TestFileBackendPrepareRangeMissingLedger(t *testing.T) {
+	dir := exportTestLedgers(t, 10, 12, false)
+	require.NoError(t, os.Remove(ledgerFileBase(dir, 11)+ledgerFileSuffix))
+
+	backend, err := NewFileBackend(dir)
+	require.NoError(t, err)
+
+	err = backend.PrepareRange(context.Background(), BoundedRange(10, 12))
+	assert.EqualError(t, err, "ledger 11 is missing from "+dir)
+}
+
+func TestFileBackendUnboundedRangeWaitsForLedger(t *testing.T) {
+	dir := exportTestLedgers(t, 10, 10, true)
+	ctx := context.Background()
+
+	backend, err := NewFileBackend(dir, FileBackendPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, backend.PrepareRange(ctx, UnboundedRange(10)))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ledger := buildLedgerCloseMeta(testLedgerHeader{sequence: 11})
+		assert.NoError(t, WriteLedgerFile(dir, ledger, true))
+	}()
+
+	ledger, err := backend.GetLedger(ctx, 11)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(11), ledger.LedgerSequence())
+
+	latest, err := backend.GetLatestLedgerSequence(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(11), latest)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = backend.GetLedger(cancelCtx, 12)
+	assert.EqualError(t, err, "shutting down: context canceled")
+}
+
+func TestExportLedgersErrGettingLedger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-backend")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	source := &MockDatabaseBackend{}
+	source.On("IsPrepared", ctx, BoundedRange(1, 2)).Return(true, nil).Once()
+	source.On("GetLedger", ctx, uint32(1)).
+		Return(buildLedgerCloseMeta(testLedgerHeader{sequence: 1}), nil).Once()
+	source.On("GetLedger", ctx, uint32(2)).
+		Return(xdr.LedgerCloseMeta{}, assert.AnError).Once()
+
+	err = ExportLedgers(ctx, source, BoundedRange(1, 2), dir, false)
+	assert.EqualError(t, err, "error getting ledger 2: "+assert.AnError.Error())
+	source.AssertNotCalled(t, "PrepareRange", mock.Anything, mock.Anything)
+
+	_, err = os.Stat(filepath.Join(dir, "ledgers", "00", "00", "00", "ledger-00000001.xdr"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,322 @@
+package ledgerbackend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// Ensure PrefetchingBackend implements LedgerBackend
+var _ LedgerBackend = (*PrefetchingBackend)(nil)
+
+// PrefetchingBackendConfig configures a PrefetchingBackend.
+type PrefetchingBackendConfig struct {
+	// BufferSize is the maximum number of ledgers fetched ahead of the ledger
+	// most recently returned by GetLedger.
+	BufferSize uint32
+	// NumWorkers is the number of concurrent GetLedger calls issued against
+	// the wrapped backend. Use 1 for backends which are not safe for
+	// concurrent use or which must be read sequentially (ex. CaptiveStellarCore).
+	NumWorkers uint32
+	// MetricsNamespace is the prometheus namespace used by RegisterMetrics.
+	MetricsNamespace string
+}
+
+// PrefetchingBackend is a LedgerBackend decorator which, after PrepareRange,
+// fetches ledgers ahead of the caller and serves GetLedger from an in-memory
+// buffer holding at most BufferSize ledgers. It is useful in front of
+// request/response backends like RemoteCaptiveStellarCore and
+// DatabaseBackend where throughput is dominated by round-trip latency.
+//
+// Errors returned by the wrapped backend are returned by GetLedger for the
+// sequence that failed, after which prefetching restarts from the next
+// requested sequence. Requesting a ledger outside of the buffered window
+// also restarts prefetching from the requested sequence.
+type PrefetchingBackend struct {
+	backend LedgerBackend
+	config  PrefetchingBackendConfig
+
+	mutex      sync.Mutex
+	prepared   *Range
+	session    *prefetchSession
+	cachedMeta *xdr.LedgerCloseMeta
+	closed     bool
+
+	bufferDepth  prometheus.GaugeFunc
+	fetchLatency prometheus.Summary
+}
+
+type prefetchResult struct {
+	done   chan struct{}
+	ledger xdr.LedgerCloseMeta
+	err    error
+}
+
+type prefetchSession struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// ledgerRange is the prepared range.
+	ledgerRange Range
+	// nextToServe is the lowest sequence which has not been returned yet.
+	nextToServe uint32
+	// nextToFetch is the lowest sequence which has not been scheduled yet.
+	nextToFetch uint32
+	results     map[uint32]*prefetchResult
+	queue       chan uint32
+}
+
+// NewPrefetchingBackend returns a PrefetchingBackend wrapping backend.
+func NewPrefetchingBackend(backend LedgerBackend, config PrefetchingBackendConfig) (*PrefetchingBackend, error) {
+	if config.BufferSize == 0 {
+		return nil, errors.New("BufferSize must be positive")
+	}
+	if config.NumWorkers == 0 {
+		return nil, errors.New("NumWorkers must be positive")
+	}
+	if config.NumWorkers > config.BufferSize {
+		return nil, errors.New("NumWorkers cannot be larger than BufferSize")
+	}
+
+	b := &PrefetchingBackend{
+		backend: backend,
+		config:  config,
+	}
+	b.bufferDepth = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: config.MetricsNamespace, Subsystem: "ledger_backend", Name: "prefetch_buffer_depth",
+			Help: "number of fetched ledgers waiting in the prefetch buffer",
+		},
+		b.bufferedLedgers,
+	)
+	b.fetchLatency = prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace: config.MetricsNamespace, Subsystem: "ledger_backend", Name: "prefetch_duration_seconds",
+		Help:       "durations of GetLedger calls issued against the wrapped backend, sliding window = 10m",
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+	})
+	return b, nil
+}
+
+// RegisterMetrics registers the prefetch buffer depth and fetch latency
+// metrics in the given registry.
+func (b *PrefetchingBackend) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(b.bufferDepth)
+	registry.MustRegister(b.fetchLatency)
+}
+
+func (b *PrefetchingBackend) bufferedLedgers() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.session == nil {
+		return 0
+	}
+	count := 0
+	for _, result := range b.session.results {
+		select {
+		case <-result.done:
+			count++
+		default:
+		}
+	}
+	return float64(count)
+}
+
+// GetLatestLedgerSequence returns the latest ledger sequence of the wrapped
+// backend.
+func (b *PrefetchingBackend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	return b.backend.GetLatestLedgerSequence(ctx)
+}
+
+// IsPrepared returns true if a given ledgerRange is prepared in the wrapped
+// backend.
+func (b *PrefetchingBackend) IsPrepared(ctx context.Context, ledgerRange Range) (bool, error) {
+	return b.backend.IsPrepared(ctx, ledgerRange)
+}
+
+// PrepareRange prepares the given range in the wrapped backend and starts
+// prefetching ledgers from the beginning of the range.
+func (b *PrefetchingBackend) PrepareRange(ctx context.Context, ledgerRange Range) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return errors.New("prefetching backend is closed")
+	}
+
+	b.stopSession()
+	b.prepared = nil
+	b.cachedMeta = nil
+	if err := b.backend.PrepareRange(ctx, ledgerRange); err != nil {
+		return err
+	}
+	b.prepared = &ledgerRange
+	b.startSession(ledgerRange.from)
+	return nil
+}
+
+// GetLedger returns the given ledger from the prefetch buffer, blocking until
+// it has been fetched from the wrapped backend.
+func (b *PrefetchingBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return xdr.LedgerCloseMeta{}, errors.New("prefetching backend is closed")
+	}
+	if b.cachedMeta != nil && b.cachedMeta.LedgerSequence() == sequence {
+		// GetLedger can be called multiple times using the same sequence, ex. to
+		// create change and transaction readers.
+		ledger := *b.cachedMeta
+		b.mutex.Unlock()
+		return ledger, nil
+	}
+	if b.prepared == nil {
+		b.mutex.Unlock()
+		return xdr.LedgerCloseMeta{}, errors.New("session is not prepared, call PrepareRange first")
+	}
+
+	inRange := sequence >= b.prepared.from && (!b.prepared.bounded || sequence <= b.prepared.to)
+	if inRange && (b.session == nil ||
+		sequence < b.session.nextToServe ||
+		sequence >= b.session.nextToServe+b.config.BufferSize) {
+		// The requested ledger is outside of the buffered window (or the
+		// previous fetch failed), restart prefetching from the requested
+		// sequence.
+		b.stopSession()
+		b.startSession(sequence)
+	}
+	session := b.session
+	var result *prefetchResult
+	if inRange {
+		result = session.results[sequence]
+	}
+	b.mutex.Unlock()
+
+	if result == nil {
+		// The ledger is outside of the prepared range, let the wrapped backend
+		// produce the appropriate response.
+		return b.backend.GetLedger(ctx, sequence)
+	}
+
+	select {
+	case <-ctx.Done():
+		return xdr.LedgerCloseMeta{}, ctx.Err()
+	case <-session.ctx.Done():
+		return xdr.LedgerCloseMeta{}, errors.New("session was reset while waiting for ledger")
+	case <-result.done:
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.session != session {
+		return xdr.LedgerCloseMeta{}, errors.New("session was reset while waiting for ledger")
+	}
+	if result.err != nil {
+		// Drop the session, the next GetLedger call will start prefetching
+		// again from the requested sequence.
+		b.stopSession()
+		return xdr.LedgerCloseMeta{}, result.err
+	}
+
+	for seq := session.nextToServe; seq <= sequence; seq++ {
+		delete(session.results, seq)
+	}
+	session.nextToServe = sequence + 1
+	b.schedule(session)
+
+	ledger := result.ledger
+	b.cachedMeta = &ledger
+	return ledger, nil
+}
+
+// Close stops prefetching and closes the wrapped backend.
+func (b *PrefetchingBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	b.stopSession()
+	b.prepared = nil
+	b.cachedMeta = nil
+	return b.backend.Close()
+}
+
+// startSession starts the workers prefetching ledgers in the
+// prepared range starting at from. It must be called with b.mutex held.
+func (b *PrefetchingBackend) startSession(from uint32) {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &prefetchSession{
+		ctx:         ctx,
+		cancel:      cancel,
+		ledgerRange: *b.prepared,
+		nextToServe: from,
+		nextToFetch: from,
+		results:     map[uint32]*prefetchResult{},
+		queue:       make(chan uint32, b.config.BufferSize),
+	}
+	b.schedule(session)
+
+	for i := uint32(0); i < b.config.NumWorkers; i++ {
+		go b.worker(session)
+	}
+	b.session = session
+}
+
+// stopSession cancels the current session. It must be called with b.mutex
+// held.
+func (b *PrefetchingBackend) stopSession() {
+	if b.session == nil {
+		return
+	}
+	b.session.cancel()
+	b.session = nil
+}
+
+// schedule creates result slots and queues fetches for all sequences which
+// fit in the buffer. It must be called with b.mutex held.
+func (b *PrefetchingBackend) schedule(session *prefetchSession) {
+	for session.nextToFetch < session.nextToServe+b.config.BufferSize {
+		if session.ledgerRange.bounded && session.nextToFetch > session.ledgerRange.to {
+			return
+		}
+		seq := session.nextToFetch
+		session.results[seq] = &prefetchResult{done: make(chan struct{})}
+		session.nextToFetch++
+		// queue has BufferSize capacity so this never blocks.
+		session.queue <- seq
+	}
+}
+
+func (b *PrefetchingBackend) worker(session *prefetchSession) {
+	for {
+		var seq uint32
+		select {
+		case <-session.ctx.Done():
+			return
+		case seq = <-session.queue:
+		}
+
+		b.mutex.Lock()
+		result, ok := session.results[seq]
+		b.mutex.Unlock()
+		if !ok {
+			// The ledger was skipped by the caller.
+			continue
+		}
+
+		start := time.Now()
+		ledger, err := b.backend.GetLedger(session.ctx, seq)
+		if session.ctx.Err() != nil {
+			return
+		}
+		b.fetchLatency.Observe(time.Since(start).Seconds())
+
+		result.ledger, result.err = ledger, err
+		close(result.done)
+	}
+}
@@ -0,0 +1,155 @@
+package ledgerbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/xdr"
+)
+
+func TestNewPrefetchingBackendInvalidConfig(t *testing.T) {
+	_, err := NewPrefetchingBackend(&MockDatabaseBackend{}, PrefetchingBackendConfig{NumWorkers: 1})
+	assert.EqualError(t, err, "BufferSize must be positive")
+	_, err = NewPrefetchingBackend(&MockDatabaseBackend{}, PrefetchingBackendConfig{BufferSize: 1})
+	assert.EqualError(t, err, "NumWorkers must be positive")
+	_, err = NewPrefetchingBackend(&MockDatabaseBackend{}, PrefetchingBackendConfig{BufferSize: 1, NumWorkers: 2})
+	assert.EqualError(t, err, "NumWorkers cannot be larger than BufferSize")
+}
+
+func TestPrefetchingBackendGetLedger(t *testing.T) {
+	ctx := context.Background()
+	ledgerRange := BoundedRange(2, 20)
+	wrapped := &MockDatabaseBackend{}
+	wrapped.On("PrepareRange", ctx, ledgerRange).Return(nil).Once()
+	for seq := uint32(2); seq <= 20; seq++ {
+		wrapped.On("GetLedger", mock.Anything, seq).
+			Return(buildLedgerCloseMeta(testLedgerHeader{sequence: seq}), nil).Once()
+	}
+	wrapped.On("Close").Return(nil).Once()
+
+	backend, err := NewPrefetchingBackend(wrapped, PrefetchingBackendConfig{
+		BufferSize: 5,
+		NumWorkers: 3,
+	})
+	require.NoError(t, err)
+	backend.RegisterMetrics(prometheus.NewRegistry())
+
+	_, err = backend.GetLedger(ctx, 2)
+	assert.EqualError(t, err, "session is not prepared, call PrepareRange first")
+
+	require.NoError(t, backend.PrepareRange(ctx, ledgerRange))
+	for seq := uint32(2); seq <= 20; seq++ {
+		var ledger xdr.LedgerCloseMeta
+		ledger, err = backend.GetLedger(ctx, seq)
+		require.NoError(t, err)
+		assert.Equal(t, seq, ledger.LedgerSequence())
+
+		// Requesting the same ledger again is served from cache.
+		ledger, err = backend.GetLedger(ctx, seq)
+		require.NoError(t, err)
+		assert.Equal(t, seq, ledger.LedgerSequence())
+	}
+
+	require.NoError(t, backend.Close())
+	wrapped.AssertExpectations(t)
+
+	_, err = backend.GetLedger(ctx, 20)
+	assert.EqualError(t, err, "prefetching backend is closed")
+}
+
+func TestPrefetchingBackendDoesNotFetchBeyondBuffer(t *testing.T) {
+	ctx := context.Background()
+	ledgerRange := UnboundedRange(2)
+	wrapped := &MockDatabaseBackend{}
+	wrapped.On("PrepareRange", ctx, ledgerRange).Return(nil).Once()
+	for seq := uint32(2); seq <= 4; seq++ {
+		wrapped.On("GetLedger", mock.Anything, seq).
+			Return(buildLedgerCloseMeta(testLedgerHeader{sequence: seq}), nil).Once()
+	}
+
+	backend, err := NewPrefetchingBackend(wrapped, PrefetchingBackendConfig{
+		BufferSize: 2,
+		NumWorkers: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, backend.PrepareRange(ctx, ledgerRange))
+
+	ledger, err := backend.GetLedger(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), ledger.LedgerSequence())
+
+	// Only ledgers 3 and 4 can be fetched after ledger 2 has been served.
+	assert.Eventually(t, func() bool { return backend.bufferedLedgers() == 2 }, time.Second, time.Millisecond)
+	wrapped.AssertExpectations(t)
+
+	backend.mutex.Lock()
+	backend.stopSession()
+	backend.mutex.Unlock()
+}
+
+func TestPrefetchingBackendErrorRestartsPrefetching(t *testing.T) {
+	ctx := context.Background()
+	ledgerRange := BoundedRange(2, 4)
+	wrapped := &MockDatabaseBackend{}
+	wrapped.On("PrepareRange", ctx, ledgerRange).Return(nil).Once()
+	wrapped.On("GetLedger", mock.Anything, uint32(2)).
+		Return(xdr.LedgerCloseMeta{}, assert.AnError).Once()
+	for seq := uint32(2); seq <= 4; seq++ {
+		wrapped.On("GetLedger", mock.Anything, seq).
+			Return(buildLedgerCloseMeta(testLedgerHeader{sequence: seq}), nil).Maybe()
+	}
+
+	backend, err := NewPrefetchingBackend(wrapped, PrefetchingBackendConfig{
+		BufferSize: 1,
+		NumWorkers: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, backend.PrepareRange(ctx, ledgerRange))
+
+	_, err = backend.GetLedger(ctx, 2)
+	assert.Equal(t, assert.AnError, err)
+
+	for seq := uint32(2); seq <= 4; seq++ {
+		ledger, err := backend.GetLedger(ctx, seq)
+		require.NoError(t, err)
+		assert.Equal(t, seq, ledger.LedgerSequence())
+	}
+}
+
+func TestPrefetchingBackendOutOfWindowRequest(t *testing.T) {
+	ctx := context.Background()
+	ledgerRange := BoundedRange(2, 100)
+	wrapped := &MockDatabaseBackend{}
+	wrapped.On("PrepareRange", ctx, ledgerRange).Return(nil).Once()
+	for seq := uint32(2); seq <= 100; seq++ {
+		wrapped.On("GetLedger", mock.Anything, seq).
+			Return(buildLedgerCloseMeta(testLedgerHeader{sequence: seq}), nil).Maybe()
+	}
+	wrapped.On("GetLedger", mock.Anything, uint32(101)).
+		Return(xdr.LedgerCloseMeta{}, assert.AnError).Once()
+
+	backend, err := NewPrefetchingBackend(wrapped, PrefetchingBackendConfig{
+		BufferSize: 3,
+		NumWorkers: 2,
+	})
+	require.NoError(t, err)
+	require.NoError(t, backend.PrepareRange(ctx, ledgerRange))
+
+	ledger, err := backend.GetLedger(ctx, 50)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(50), ledger.LedgerSequence())
+
+	ledger, err = backend.GetLedger(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), ledger.LedgerSequence())
+
+	// Ledgers outside of the prepared range are passed through.
+	_, err = backend.GetLedger(ctx, 101)
+	assert.Equal(t, assert.AnError, err)
+}
@@ -56,6 +56,21 @@ func (r Range) Contains(other Range) bool {
 	return r.from <= other.from
 }
 
+// From returns the first ledger of the range.
+func (r Range) From() uint32 {
+	return r.from
+}
+
+// To returns the last ledger of the range. It is zero for unbounded ranges.
+func (r Range) To() uint32 {
+	return r.to
+}
+
+// Bounded returns true if the range has a last ledger.
+func (r Range) Bounded() bool {
+	return r.bounded
+}
+
 // SingleLedgerRange constructs a bounded range containing a single ledger.
 func SingleLedgerRange(ledger uint32) Range {
 	return Range{from: ledger, to: ledger, bounded: true}
@@ -0,0 +1,356 @@
+package ingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stellar/go/historyarchive"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// ProcessorRunnerConfig configures a ProcessorRunner.
+type ProcessorRunnerConfig struct {
+	NetworkPassphrase string
+	// Archive is used to read the ledger state at a checkpoint. It is only
+	// required by RunHistoryArchiveIngestion.
+	Archive historyarchive.ArchiveInterface
+	// CheckpointFilter is applied to the history archive snapshot, see
+	// NewFilteredCheckpointChangeReader.
+	CheckpointFilter CheckpointFilter
+	// Backend is used to read ledgers. It is only required by
+	// RunLedgerRange.
+	Backend ledgerbackend.LedgerBackend
+
+	ChangeProcessors      []ChangeProcessor
+	TransactionProcessors []LedgerTransactionProcessor
+
+	// BatchSize is the number of ledgers processed between commits. Zero
+	// means every ledger is committed separately. When ingesting an
+	// unbounded range a partial batch is committed only when it is full so
+	// BatchSize should be 1 when following the network in real time.
+	BatchSize uint32
+	// Cursor stores the last committed ledger. When set, runs are resumed
+	// after the last committed ledger. Optional.
+	Cursor CursorStore
+	// BeforeCommit is called before the processors are committed with the
+	// sequence of the last ledger of the batch. Optional.
+	BeforeCommit func(ctx context.Context, sequence uint32) error
+	// AfterCommit is called after the processors are committed and the
+	// cursor is updated. Optional.
+	AfterCommit func(ctx context.Context, sequence uint32) error
+
+	// MetricsNamespace is the prometheus namespace used by RegisterMetrics.
+	MetricsNamespace string
+}
+
+// ProcessorRunner runs ChangeProcessors and LedgerTransactionProcessors over
+// the ledger state at a checkpoint (read from a history archive) and over
+// ranges of ledgers (read from any LedgerBackend).
+//
+// For every ledger changes are passed to the change processors first and
+// transactions to the transaction processors next. After a batch of ledgers
+// (or a history archive snapshot) is processed the runner calls BeforeCommit,
+// commits the change processors, commits the transaction processors, updates
+// the cursor and calls AfterCommit, stopping at the first error.
+type ProcessorRunner struct {
+	config            ProcessorRunnerConfig
+	changeGroup       *GroupChangeProcessors
+	transactionsGroup *GroupTransactionProcessors
+
+	ledgersCounter      prometheus.Counter
+	changesCounter      prometheus.Counter
+	transactionsCounter prometheus.Counter
+	lastLedgerGauge     prometheus.Gauge
+	ledgerDuration      prometheus.Summary
+	processorDuration   *prometheus.SummaryVec
+}
+
+// NewProcessorRunner returns a ProcessorRunner for the given config.
+func NewProcessorRunner(config ProcessorRunnerConfig) (*ProcessorRunner, error) {
+	if config.NetworkPassphrase == "" {
+		return nil, errors.New("NetworkPassphrase must be set")
+	}
+	if len(config.ChangeProcessors) == 0 && len(config.TransactionProcessors) == 0 {
+		return nil, errors.New("at least one processor is required")
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 1
+	}
+
+	subsystem := "processor_runner"
+	return &ProcessorRunner{
+		config:            config,
+		changeGroup:       NewGroupChangeProcessors(config.ChangeProcessors),
+		transactionsGroup: NewGroupTransactionProcessors(config.TransactionProcessors),
+		ledgersCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: config.MetricsNamespace, Subsystem: subsystem, Name: "ledgers_total",
+			Help: "number of ledgers processed",
+		}),
+		changesCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: config.MetricsNamespace, Subsystem: subsystem, Name: "changes_total",
+			Help: "number of ledger entry changes processed, including history archive snapshots",
+		}),
+		transactionsCounter: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: config.MetricsNamespace, Subsystem: subsystem, Name: "transactions_total",
+			Help: "number of transactions processed",
+		}),
+		lastLedgerGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: config.MetricsNamespace, Subsystem: subsystem, Name: "last_committed_ledger",
+			Help: "sequence of the last committed ledger",
+		}),
+		ledgerDuration: prometheus.NewSummary(prometheus.SummaryOpts{
+			Namespace: config.MetricsNamespace, Subsystem: subsystem, Name: "ledger_duration_seconds",
+			Help:       "ledger processing durations, excluding commits, sliding window = 10m",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}),
+		processorDuration: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace: config.MetricsNamespace, Subsystem: subsystem, Name: "processor_duration_seconds",
+			Help:       "time spent in each processor per batch, including commits, sliding window = 10m",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, []string{"processor"}),
+	}, nil
+}
+
+// RegisterMetrics registers the runner metrics in the given registry.
+func (r *ProcessorRunner) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(
+		r.ledgersCounter,
+		r.changesCounter,
+		r.transactionsCounter,
+		r.lastLedgerGauge,
+		r.ledgerDuration,
+		r.processorDuration,
+	)
+}
+
+// Run ingests the ledger state at the checkpoint and then all ledgers from
+// checkpoint+1 to to (inclusive, or unbounded if to is 0). If the cursor
+// shows the checkpoint was already committed, the history archive snapshot
+// is skipped and ledger ingestion resumes after the last committed ledger.
+func (r *ProcessorRunner) Run(ctx context.Context, checkpoint, to uint32) error {
+	lastLedger, err := r.lastCommittedLedger(ctx)
+	if err != nil {
+		return err
+	}
+	if lastLedger < checkpoint {
+		if err = r.RunHistoryArchiveIngestion(ctx, checkpoint); err != nil {
+			return err
+		}
+	}
+	if to != 0 && to <= checkpoint {
+		return nil
+	}
+	return r.RunLedgerRange(ctx, checkpoint+1, to)
+}
+
+// RunHistoryArchiveIngestion passes all ledger entries at the given
+// checkpoint to the change processors and commits them. Checkpoint 1 is the
+// genesis ledger which is not read from the archive.
+func (r *ProcessorRunner) RunHistoryArchiveIngestion(ctx context.Context, checkpoint uint32) error {
+	if len(r.config.ChangeProcessors) == 0 {
+		return r.commit(ctx, checkpoint)
+	}
+
+	processor := &countingChangeProcessor{ChangeProcessor: r.changeGroup, counter: r.changesCounter}
+	if checkpoint == 1 {
+		if err := processor.ProcessChange(ctx, GenesisChange(r.config.NetworkPassphrase)); err != nil {
+			return errors.Wrap(err, "error ingesting genesis ledger")
+		}
+		return r.commit(ctx, checkpoint)
+	}
+
+	if r.config.Archive == nil {
+		return errors.New("history archive is not configured")
+	}
+	reader, err := NewFilteredCheckpointChangeReader(ctx, r.config.Archive, checkpoint, r.config.CheckpointFilter)
+	if err != nil {
+		return errors.Wrap(err, "error creating checkpoint change reader")
+	}
+	defer reader.Close()
+
+	if err = StreamChanges(ctx, processor, reader); err != nil {
+		return errors.Wrapf(err, "error streaming changes from checkpoint %d", checkpoint)
+	}
+	return r.commit(ctx, checkpoint)
+}
+
+// RunLedgerRange processes all ledgers from from to to (inclusive, or
+// unbounded if to is 0) committing processors every BatchSize ledgers. If
+// the cursor shows ledgers in the range were already committed, processing
+// resumes after the last committed ledger. The range is prepared in the
+// backend if needed.
+func (r *ProcessorRunner) RunLedgerRange(ctx context.Context, from, to uint32) error {
+	if r.config.Backend == nil {
+		return errors.New("ledger backend is not configured")
+	}
+	if to != 0 && from > to {
+		return errors.Errorf("invalid range: from (%d) > to (%d)", from, to)
+	}
+
+	lastLedger, err := r.lastCommittedLedger(ctx)
+	if err != nil {
+		return err
+	}
+	if lastLedger >= from {
+		if to != 0 && lastLedger >= to {
+			return nil
+		}
+		from = lastLedger + 1
+	}
+
+	ledgerRange := ledgerbackend.UnboundedRange(from)
+	if to != 0 {
+		ledgerRange = ledgerbackend.BoundedRange(from, to)
+	}
+	prepared, err := r.config.Backend.IsPrepared(ctx, ledgerRange)
+	if err != nil {
+		return errors.Wrap(err, "error checking if range is prepared")
+	}
+	if !prepared {
+		if err = r.config.Backend.PrepareRange(ctx, ledgerRange); err != nil {
+			return errors.Wrapf(err, "error preparing range %v", ledgerRange)
+		}
+	}
+
+	batchEnd := from + r.config.BatchSize - 1
+	for sequence := from; to == 0 || sequence <= to; sequence++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		var ledger xdr.LedgerCloseMeta
+		ledger, err = r.config.Backend.GetLedger(ctx, sequence)
+		if err != nil {
+			return errors.Wrapf(err, "error getting ledger %d", sequence)
+		}
+		if err = r.RunProcessorsOnLedger(ctx, ledger); err != nil {
+			return err
+		}
+
+		if sequence == batchEnd || sequence == to {
+			if err = r.commit(ctx, sequence); err != nil {
+				return err
+			}
+			batchEnd = sequence + r.config.BatchSize
+		}
+	}
+	return nil
+}
+
+// RunProcessorsOnLedger passes the changes and transactions of a single
+// ledger to the processors. It does not commit the processors, this is done
+// by RunLedgerRange at the end of every batch.
+func (r *ProcessorRunner) RunProcessorsOnLedger(ctx context.Context, ledger xdr.LedgerCloseMeta) error {
+	startTime := time.Now()
+	sequence := ledger.LedgerSequence()
+
+	if len(r.config.ChangeProcessors) > 0 {
+		changeReader, err := NewLedgerChangeReaderFromLedgerCloseMeta(r.config.NetworkPassphrase, ledger)
+		if err != nil {
+			return errors.Wrapf(err, "error creating change reader for ledger %d", sequence)
+		}
+		processor := &countingChangeProcessor{ChangeProcessor: r.changeGroup, counter: r.changesCounter}
+		err = StreamChanges(ctx, processor, changeReader)
+		changeReader.Close()
+		if err != nil {
+			return errors.Wrapf(err, "error streaming changes from ledger %d", sequence)
+		}
+	}
+
+	if len(r.config.TransactionProcessors) > 0 {
+		txReader, err := NewLedgerTransactionReaderFromLedgerCloseMeta(r.config.NetworkPassphrase, ledger)
+		if err != nil {
+			return errors.Wrapf(err, "error creating transaction reader for ledger %d", sequence)
+		}
+		processor := &countingTransactionProcessor{
+			LedgerTransactionProcessor: r.transactionsGroup,
+			counter:                    r.transactionsCounter,
+		}
+		err = StreamLedgerTransactions(ctx, processor, txReader)
+		txReader.Close()
+		if err != nil {
+			return errors.Wrapf(err, "error streaming transactions from ledger %d", sequence)
+		}
+	}
+
+	r.ledgersCounter.Inc()
+	r.ledgerDuration.Observe(time.Since(startTime).Seconds())
+	return nil
+}
+
+func (r *ProcessorRunner) lastCommittedLedger(ctx context.Context) (uint32, error) {
+	if r.config.Cursor == nil {
+		return 0, nil
+	}
+	sequence, err := r.config.Cursor.GetLastLedger(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "error getting cursor")
+	}
+	return sequence, nil
+}
+
+func (r *ProcessorRunner) commit(ctx context.Context, sequence uint32) error {
+	if r.config.BeforeCommit != nil {
+		if err := r.config.BeforeCommit(ctx, sequence); err != nil {
+			return errors.Wrap(err, "error in BeforeCommit hook")
+		}
+	}
+	if err := r.changeGroup.Commit(ctx); err != nil {
+		return errors.Wrap(err, "error committing change processors")
+	}
+	if err := r.transactionsGroup.Commit(ctx); err != nil {
+		return errors.Wrap(err, "error committing transaction processors")
+	}
+	if r.config.Cursor != nil {
+		if err := r.config.Cursor.SetLastLedger(ctx, sequence); err != nil {
+			return errors.Wrap(err, "error updating cursor")
+		}
+	}
+	r.lastLedgerGauge.Set(float64(sequence))
+	r.observeProcessorDurations()
+
+	if r.config.AfterCommit != nil {
+		if err := r.config.AfterCommit(ctx, sequence); err != nil {
+			return errors.Wrap(err, "error in AfterCommit hook")
+		}
+	}
+	return nil
+}
+
+// observeProcessorDurations reports the time spent in every processor since
+// the previous commit and resets the durations.
+func (r *ProcessorRunner) observeProcessorDurations() {
+	for _, durations := range []ProcessorsRunDurations{
+		r.changeGroup.ProcessorsRunDurations,
+		r.transactionsGroup.ProcessorsRunDurations,
+	} {
+		for name, duration := range durations {
+			r.processorDuration.With(prometheus.Labels{"processor": name}).Observe(duration.Seconds())
+			delete(durations, name)
+		}
+	}
+}
+
+type countingChangeProcessor struct {
+	ChangeProcessor
+	counter prometheus.Counter
+}
+
+func (p *countingChangeProcessor) ProcessChange(ctx context.Context, change Change) error {
+	p.counter.Inc()
+	return p.ChangeProcessor.ProcessChange(ctx, change)
+}
+
+type countingTransactionProcessor struct {
+	LedgerTransactionProcessor
+	counter prometheus.Counter
+}
+
+func (p *countingTransactionProcessor) ProcessTransaction(ctx context.Context, tx LedgerTransaction) error {
+	p.counter.Inc()
+	return p.LedgerTransactionProcessor.ProcessTransaction(ctx, tx)
+}
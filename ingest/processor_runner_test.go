@@ -0,0 +1,210 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+)
+
+type recordingProcessor struct {
+	changes      []Change
+	transactions []LedgerTransaction
+	commits      int
+	err          error
+}
+
+func (p *recordingProcessor) ProcessChange(ctx context.Context, change Change) error {
+	p.changes = append(p.changes, change)
+	return p.err
+}
+
+func (p *recordingProcessor) ProcessTransaction(ctx context.Context, tx LedgerTransaction) error {
+	p.transactions = append(p.transactions, tx)
+	return p.err
+}
+
+func (p *recordingProcessor) Commit(ctx context.Context) error {
+	p.commits++
+	return nil
+}
+
+func runnerTestLedger(t *testing.T, sequence uint32) xdr.LedgerCloseMeta {
+	src := xdr.MustAddress("GBXGQJWVLWOYHFLVTKWV5FGHA3LNYY2JQKM7OAJAUEQFU6LPCSEFVXON")
+	tx := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				Fee:           100,
+				SourceAccount: src.ToMuxedAccount(),
+				SeqNum:        xdr.SequenceNumber(sequence),
+			},
+		},
+	}
+	hash, err := network.HashTransactionInEnvelope(tx, network.TestNetworkPassphrase)
+	require.NoError(t, err)
+
+	return xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence), LedgerVersion: 17},
+			},
+			TxSet: xdr.TransactionSet{Txs: []xdr.TransactionEnvelope{tx}},
+			TxProcessing: []xdr.TransactionResultMeta{
+				{
+					Result:        xdr.TransactionResultPair{TransactionHash: hash},
+					FeeProcessing: xdr.LedgerEntryChanges{buildChange(feeAddress, int64(sequence))},
+					TxApplyProcessing: xdr.TransactionMeta{
+						V:  2,
+						V2: &xdr.TransactionMetaV2{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestProcessorRunnerGenesis(t *testing.T) {
+	processor := &recordingProcessor{}
+	cursor := &MemoryCursorStore{}
+	runner, err := NewProcessorRunner(ProcessorRunnerConfig{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		ChangeProcessors:  []ChangeProcessor{processor},
+		Cursor:            cursor,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, runner.RunHistoryArchiveIngestion(context.Background(), 1))
+	require.Len(t, processor.changes, 1)
+	assert.Equal(t, GenesisChange(network.TestNetworkPassphrase), processor.changes[0])
+	assert.Equal(t, 1, processor.commits)
+
+	lastLedger, err := cursor.GetLastLedger(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+}
+
+func TestProcessorRunnerLedgerRange(t *testing.T) {
+	ctx := context.Background()
+	backend := &ledgerbackend.MockDatabaseBackend{}
+	backend.On("IsPrepared", ctx, ledgerbackend.BoundedRange(2, 6)).Return(false, nil).Once()
+	backend.On("PrepareRange", ctx, ledgerbackend.BoundedRange(2, 6)).Return(nil).Once()
+	for sequence := uint32(2); sequence <= 6; sequence++ {
+		backend.On("GetLedger", ctx, sequence).Return(runnerTestLedger(t, sequence), nil).Once()
+	}
+
+	changeProcessor := &recordingProcessor{}
+	txProcessor := &recordingProcessor{}
+	var committed []uint32
+	runner, err := NewProcessorRunner(ProcessorRunnerConfig{
+		NetworkPassphrase:     network.TestNetworkPassphrase,
+		Backend:               backend,
+		ChangeProcessors:      []ChangeProcessor{changeProcessor},
+		TransactionProcessors: []LedgerTransactionProcessor{txProcessor},
+		BatchSize:             2,
+		AfterCommit: func(ctx context.Context, sequence uint32) error {
+			committed = append(committed, sequence)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	runner.RegisterMetrics(registry)
+
+	require.NoError(t, runner.Run(ctx, 1, 6))
+	backend.AssertExpectations(t)
+
+	// Checkpoint 1 (genesis) and the ledger batches.
+	assert.Equal(t, []uint32{1, 3, 5, 6}, committed)
+	assert.Equal(t, 4, changeProcessor.commits)
+	assert.Equal(t, 4, txProcessor.commits)
+	assert.Len(t, changeProcessor.changes, 6)
+	require.Len(t, txProcessor.transactions, 5)
+	for i, tx := range txProcessor.transactions {
+		assert.Equal(t, int64(i+2), tx.Envelope.SeqNum())
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	values := map[string]float64{}
+	for _, family := range families {
+		if family.GetMetric()[0].Counter != nil {
+			values[family.GetName()] = family.GetMetric()[0].Counter.GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"processor_runner_ledgers_total":      5,
+		"processor_runner_changes_total":      6,
+		"processor_runner_transactions_total": 5,
+	}, values)
+}
+
+func TestProcessorRunnerResume(t *testing.T) {
+	ctx := context.Background()
+	cursor := NewFileCursorStore(filepath.Join(t.TempDir(), "cursor"))
+	require.NoError(t, cursor.SetLastLedger(ctx, 4))
+
+	backend := &ledgerbackend.MockDatabaseBackend{}
+	backend.On("IsPrepared", ctx, ledgerbackend.BoundedRange(5, 6)).Return(true, nil).Once()
+	backend.On("GetLedger", ctx, uint32(5)).Return(runnerTestLedger(t, 5), nil).Once()
+	backend.On("GetLedger", ctx, uint32(6)).Return(xdr.LedgerCloseMeta{}, fmt.Errorf("ledger error")).Once()
+
+	processor := &recordingProcessor{}
+	runner, err := NewProcessorRunner(ProcessorRunnerConfig{
+		NetworkPassphrase:     network.TestNetworkPassphrase,
+		Backend:               backend,
+		TransactionProcessors: []LedgerTransactionProcessor{processor},
+		Cursor:                cursor,
+	})
+	require.NoError(t, err)
+
+	// The checkpoint was already committed so only ledgers after the cursor
+	// are processed.
+	err = runner.Run(ctx, 1, 6)
+	assert.EqualError(t, err, "error getting ledger 6: ledger error")
+	backend.AssertExpectations(t)
+	assert.Len(t, processor.transactions, 1)
+	assert.Equal(t, 1, processor.commits)
+
+	lastLedger, err := cursor.GetLastLedger(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5), lastLedger)
+
+	// Already committed ranges are skipped.
+	require.NoError(t, runner.RunLedgerRange(ctx, 2, 5))
+	backend.AssertNotCalled(t, "GetLedger", ctx, uint32(2))
+}
+
+func TestProcessorRunnerProcessorError(t *testing.T) {
+	ctx := context.Background()
+	backend := &ledgerbackend.MockDatabaseBackend{}
+	backend.On("IsPrepared", ctx, mock.Anything).Return(true, nil).Once()
+	backend.On("GetLedger", ctx, uint32(2)).Return(runnerTestLedger(t, 2), nil).Once()
+
+	processor := &recordingProcessor{err: fmt.Errorf("processor error")}
+	cursor := &MemoryCursorStore{}
+	runner, err := NewProcessorRunner(ProcessorRunnerConfig{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		Backend:           backend,
+		ChangeProcessors:  []ChangeProcessor{processor},
+		Cursor:            cursor,
+	})
+	require.NoError(t, err)
+
+	err = runner.RunLedgerRange(ctx, 2, 0)
+	assert.EqualError(t, err, "error streaming changes from ledger 2: could not process change: "+
+		"error in *ingest.recordingProcessor.ProcessChange: processor error")
+	assert.Equal(t, 0, processor.commits)
+	lastLedger, err := cursor.GetLastLedger(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), lastLedger)
+}
@@ -0,0 +1,165 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// ChangeProcessor is a processor of ledger entry changes run by
+// ProcessorRunner. Commit is called after all changes of a history archive
+// snapshot or of a batch of ledgers were passed to ProcessChange.
+type ChangeProcessor interface {
+	ProcessChange(ctx context.Context, change Change) error
+	Commit(ctx context.Context) error
+}
+
+// LedgerTransactionProcessor is a processor of ledger transactions run by
+// ProcessorRunner. Commit is called after all transactions of a batch of
+// ledgers were passed to ProcessTransaction.
+type LedgerTransactionProcessor interface {
+	ProcessTransaction(ctx context.Context, transaction LedgerTransaction) error
+	Commit(ctx context.Context) error
+}
+
+// ProcessorsRunDurations contains the total time spent in each processor,
+// keyed by the processor type name.
+type ProcessorsRunDurations map[string]time.Duration
+
+// AddRunDuration adds the time elapsed since startTime to the duration of
+// the processor with the given name.
+func (d ProcessorsRunDurations) AddRunDuration(name string, startTime time.Time) {
+	d[name] += time.Since(startTime)
+}
+
+// GroupChangeProcessors is a ChangeProcessor which passes every change to
+// all of its processors, in order.
+type GroupChangeProcessors struct {
+	processors []ChangeProcessor
+	ProcessorsRunDurations
+}
+
+// NewGroupChangeProcessors returns a GroupChangeProcessors running the given
+// processors.
+func NewGroupChangeProcessors(processors []ChangeProcessor) *GroupChangeProcessors {
+	return &GroupChangeProcessors{
+		processors:             processors,
+		ProcessorsRunDurations: make(map[string]time.Duration),
+	}
+}
+
+// Processors returns the processors of the group.
+func (g *GroupChangeProcessors) Processors() []ChangeProcessor {
+	return g.processors
+}
+
+// ProcessChange passes the change to all processors in the group.
+func (g *GroupChangeProcessors) ProcessChange(ctx context.Context, change Change) error {
+	for _, p := range g.processors {
+		startTime := time.Now()
+		if err := p.ProcessChange(ctx, change); err != nil {
+			return errors.Wrapf(err, "error in %T.ProcessChange", p)
+		}
+		g.AddRunDuration(fmt.Sprintf("%T", p), startTime)
+	}
+	return nil
+}
+
+// Commit commits all processors in the group.
+func (g *GroupChangeProcessors) Commit(ctx context.Context) error {
+	for _, p := range g.processors {
+		startTime := time.Now()
+		if err := p.Commit(ctx); err != nil {
+			return errors.Wrapf(err, "error in %T.Commit", p)
+		}
+		g.AddRunDuration(fmt.Sprintf("%T", p), startTime)
+	}
+	return nil
+}
+
+// GroupTransactionProcessors is a LedgerTransactionProcessor which passes
+// every transaction to all of its processors, in order.
+type GroupTransactionProcessors struct {
+	processors []LedgerTransactionProcessor
+	ProcessorsRunDurations
+}
+
+// NewGroupTransactionProcessors returns a GroupTransactionProcessors running
+// the given processors.
+func NewGroupTransactionProcessors(processors []LedgerTransactionProcessor) *GroupTransactionProcessors {
+	return &GroupTransactionProcessors{
+		processors:             processors,
+		ProcessorsRunDurations: make(map[string]time.Duration),
+	}
+}
+
+// Processors returns the processors of the group.
+func (g *GroupTransactionProcessors) Processors() []LedgerTransactionProcessor {
+	return g.processors
+}
+
+// ProcessTransaction passes the transaction to all processors in the group.
+func (g *GroupTransactionProcessors) ProcessTransaction(ctx context.Context, tx LedgerTransaction) error {
+	for _, p := range g.processors {
+		startTime := time.Now()
+		if err := p.ProcessTransaction(ctx, tx); err != nil {
+			return errors.Wrapf(err, "error in %T.ProcessTransaction", p)
+		}
+		g.AddRunDuration(fmt.Sprintf("%T", p), startTime)
+	}
+	return nil
+}
+
+// Commit commits all processors in the group.
+func (g *GroupTransactionProcessors) Commit(ctx context.Context) error {
+	for _, p := range g.processors {
+		startTime := time.Now()
+		if err := p.Commit(ctx); err != nil {
+			return errors.Wrapf(err, "error in %T.Commit", p)
+		}
+		g.AddRunDuration(fmt.Sprintf("%T", p), startTime)
+	}
+	return nil
+}
+
+// StreamChanges reads all changes from reader and passes them to
+// changeProcessor. It does not commit the processor.
+func StreamChanges(ctx context.Context, changeProcessor ChangeProcessor, reader ChangeReader) error {
+	for {
+		change, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read change")
+		}
+
+		if err = changeProcessor.ProcessChange(ctx, change); err != nil {
+			return errors.Wrap(err, "could not process change")
+		}
+	}
+}
+
+// StreamLedgerTransactions reads all transactions from reader and passes them
+// to txProcessor. It does not commit the processor.
+func StreamLedgerTransactions(
+	ctx context.Context,
+	txProcessor LedgerTransactionProcessor,
+	reader *LedgerTransactionReader,
+) error {
+	for {
+		tx, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read transaction")
+		}
+		if err = txProcessor.ProcessTransaction(ctx, tx); err != nil {
+			return errors.Wrapf(err, "could not process transaction %v", tx.Index)
+		}
+	}
+}
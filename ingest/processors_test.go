@@ -7,40 +7,39 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
-
-	"github.com/stellar/go/ingest"
 )
 
-var _ horizonChangeProcessor = (*mockHorizonChangeProcessor)(nil)
+var _ ChangeProcessor = (*mockChangeProcessor)(nil)
 
-type mockHorizonChangeProcessor struct {
+type mockChangeProcessor struct {
 	mock.Mock
 }
 
-func (m *mockHorizonChangeProcessor) ProcessChange(ctx context.Context, change ingest.Change) error {
+func (m *mockChangeProcessor) ProcessChange(ctx context.Context, change Change) error {
 	args := m.Called(ctx, change)
 	return args.Error(0)
 }
 
-func (m *mockHorizonChangeProcessor) Commit(ctx context.Context) error {
+func (m *mockChangeProcessor) Commit(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
 
-var _ horizonTransactionProcessor = (*mockHorizonTransactionProcessor)(nil)
+var _ LedgerTransactionProcessor = (*mockLedgerTransactionProcessor)(nil)
 
-type mockHorizonTransactionProcessor struct {
+type mockLedgerTransactionProcessor struct {
 	mock.Mock
 }
 
-func (m *mockHorizonTransactionProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+func (m *mockLedgerTransactionProcessor) ProcessTransaction(ctx context.Context, transaction LedgerTransaction) error {
 	args := m.Called(ctx, transaction)
 	return args.Error(0)
 }
 
-func (m *mockHorizonTransactionProcessor) Commit(ctx context.Context) error {
+func (m *mockLedgerTransactionProcessor) Commit(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
@@ -48,9 +47,9 @@ func (m *mockHorizonTransactionProcessor) Commit(ctx context.Context) error {
 type GroupChangeProcessorsTestSuiteLedger struct {
 	suite.Suite
 	ctx        context.Context
-	processors *groupChangeProcessors
-	processorA *mockHorizonChangeProcessor
-	processorB *mockHorizonChangeProcessor
+	processors *GroupChangeProcessors
+	processorA *mockChangeProcessor
+	processorB *mockChangeProcessor
 }
 
 func TestGroupChangeProcessorsTestSuiteLedger(t *testing.T) {
@@ -59,9 +58,9 @@ func TestGroupChangeProcessorsTestSuiteLedger(t *testing.T) {
 
 func (s *GroupChangeProcessorsTestSuiteLedger) SetupTest() {
 	s.ctx = context.Background()
-	s.processorA = &mockHorizonChangeProcessor{}
-	s.processorB = &mockHorizonChangeProcessor{}
-	s.processors = newGroupChangeProcessors([]horizonChangeProcessor{
+	s.processorA = &mockChangeProcessor{}
+	s.processorB = &mockChangeProcessor{}
+	s.processors = NewGroupChangeProcessors([]ChangeProcessor{
 		s.processorA,
 		s.processorB,
 	})
@@ -73,18 +72,18 @@ func (s *GroupChangeProcessorsTestSuiteLedger) TearDownTest() {
 }
 
 func (s *GroupChangeProcessorsTestSuiteLedger) TestProcessChangeFails() {
-	change := ingest.Change{}
+	change := Change{}
 	s.processorA.
 		On("ProcessChange", s.ctx, change).
 		Return(errors.New("transient error")).Once()
 
 	err := s.processors.ProcessChange(s.ctx, change)
 	s.Assert().Error(err)
-	s.Assert().EqualError(err, "error in *ingest.mockHorizonChangeProcessor.ProcessChange: transient error")
+	s.Assert().EqualError(err, "error in *ingest.mockChangeProcessor.ProcessChange: transient error")
 }
 
 func (s *GroupChangeProcessorsTestSuiteLedger) TestProcessChangeSucceeds() {
-	change := ingest.Change{}
+	change := Change{}
 	s.processorA.
 		On("ProcessChange", s.ctx, change).
 		Return(nil).Once()
@@ -103,7 +102,7 @@ func (s *GroupChangeProcessorsTestSuiteLedger) TestCommitFails() {
 
 	err := s.processors.Commit(s.ctx)
 	s.Assert().Error(err)
-	s.Assert().EqualError(err, "error in *ingest.mockHorizonChangeProcessor.Commit: transient error")
+	s.Assert().EqualError(err, "error in *ingest.mockChangeProcessor.Commit: transient error")
 }
 
 func (s *GroupChangeProcessorsTestSuiteLedger) TestCommitSucceeds() {
@@ -121,9 +120,9 @@ func (s *GroupChangeProcessorsTestSuiteLedger) TestCommitSucceeds() {
 type GroupTransactionProcessorsTestSuiteLedger struct {
 	suite.Suite
 	ctx        context.Context
-	processors *groupTransactionProcessors
-	processorA *mockHorizonTransactionProcessor
-	processorB *mockHorizonTransactionProcessor
+	processors *GroupTransactionProcessors
+	processorA *mockLedgerTransactionProcessor
+	processorB *mockLedgerTransactionProcessor
 }
 
 func TestGroupTransactionProcessorsTestSuiteLedger(t *testing.T) {
@@ -132,9 +131,9 @@ func TestGroupTransactionProcessorsTestSuiteLedger(t *testing.T) {
 
 func (s *GroupTransactionProcessorsTestSuiteLedger) SetupTest() {
 	s.ctx = context.Background()
-	s.processorA = &mockHorizonTransactionProcessor{}
-	s.processorB = &mockHorizonTransactionProcessor{}
-	s.processors = newGroupTransactionProcessors([]horizonTransactionProcessor{
+	s.processorA = &mockLedgerTransactionProcessor{}
+	s.processorB = &mockLedgerTransactionProcessor{}
+	s.processors = NewGroupTransactionProcessors([]LedgerTransactionProcessor{
 		s.processorA,
 		s.processorB,
 	})
@@ -146,18 +145,18 @@ func (s *GroupTransactionProcessorsTestSuiteLedger) TearDownTest() {
 }
 
 func (s *GroupTransactionProcessorsTestSuiteLedger) TestProcessTransactionFails() {
-	transaction := ingest.LedgerTransaction{}
+	transaction := LedgerTransaction{}
 	s.processorA.
 		On("ProcessTransaction", s.ctx, transaction).
 		Return(errors.New("transient error")).Once()
 
 	err := s.processors.ProcessTransaction(s.ctx, transaction)
 	s.Assert().Error(err)
-	s.Assert().EqualError(err, "error in *ingest.mockHorizonTransactionProcessor.ProcessTransaction: transient error")
+	s.Assert().EqualError(err, "error in *ingest.mockLedgerTransactionProcessor.ProcessTransaction: transient error")
 }
 
 func (s *GroupTransactionProcessorsTestSuiteLedger) TestProcessTransactionSucceeds() {
-	transaction := ingest.LedgerTransaction{}
+	transaction := LedgerTransaction{}
 	s.processorA.
 		On("ProcessTransaction", s.ctx, transaction).
 		Return(nil).Once()
@@ -176,7 +175,7 @@ func (s *GroupTransactionProcessorsTestSuiteLedger) TestCommitFails() {
 
 	err := s.processors.Commit(s.ctx)
 	s.Assert().Error(err)
-	s.Assert().EqualError(err, "error in *ingest.mockHorizonTransactionProcessor.Commit: transient error")
+	s.Assert().EqualError(err, "error in *ingest.mockLedgerTransactionProcessor.Commit: transient error")
 }
 
 func (s *GroupTransactionProcessorsTestSuiteLedger) TestCommitSucceeds() {
@@ -190,3 +189,33 @@ func (s *GroupTransactionProcessorsTestSuiteLedger) TestCommitSucceeds() {
 	err := s.processors.Commit(s.ctx)
 	s.Assert().NoError(err)
 }
+
+func TestStreamChangesReaderError(t *testing.T) {
+	ctx := context.Background()
+
+	reader := &MockChangeReader{}
+	reader.
+		On("Read").
+		Return(Change{}, errors.New("transient error")).Once()
+
+	err := StreamChanges(ctx, &mockChangeProcessor{}, reader)
+	assert.EqualError(t, err, "could not read change: transient error")
+}
+
+func TestStreamChangesProcessorError(t *testing.T) {
+	ctx := context.Background()
+
+	change := Change{}
+	reader := &MockChangeReader{}
+	reader.
+		On("Read").
+		Return(change, nil).Once()
+
+	processor := &mockChangeProcessor{}
+	processor.
+		On("ProcessChange", ctx, change).
+		Return(errors.New("transient error")).Once()
+
+	err := StreamChanges(ctx, processor, reader)
+	assert.EqualError(t, err, "could not process change: transient error")
+}
@@ -0,0 +1,441 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// SpillingChangeCompactor squashes ledger entry changes like ChangeCompactor
+// but keeps at most a configured number of compacted changes in memory. When
+// the limit is reached all changes kept in memory are sorted by ledger key and
+// written to a run file on disk. GetChanges merges all run files and changes
+// still kept in memory and streams the final changes in ledger key order.
+//
+// This makes it possible to compact changes across a large number of ledgers
+// (ex. to build the state at an arbitrary ledger by compacting changes
+// forward from a checkpoint) without keeping all changed entries in memory.
+//
+// Integrity checks done by ChangeCompactor are done by AddChange for changes
+// kept in memory. Changes conflicting with a change that was already written
+// to disk are detected while merging and returned by the reader returned by
+// GetChanges, as a StateError.
+type SpillingChangeCompactor struct {
+	dir         string
+	maxInMemory int
+
+	mutex  sync.Mutex
+	memory *ChangeCompactor
+	runs   []string
+	closed bool
+}
+
+// NewSpillingChangeCompactor returns a new SpillingChangeCompactor which keeps
+// at most maxInMemory changes in memory. Run files are stored in a new
+// temporary directory created in dir (or in the default directory for
+// temporary files if dir is empty) which is removed by Close.
+func NewSpillingChangeCompactor(dir string, maxInMemory int) (*SpillingChangeCompactor, error) {
+	if maxInMemory <= 0 {
+		return nil, errors.New("maxInMemory must be positive")
+	}
+	tempDir, err := ioutil.TempDir(dir, "change-compactor")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create temporary directory")
+	}
+	return &SpillingChangeCompactor{
+		dir:         tempDir,
+		maxInMemory: maxInMemory,
+		memory:      NewChangeCompactor(),
+	}, nil
+}
+
+// AddChange adds a change to SpillingChangeCompactor. If the number of
+// changes kept in memory reaches the limit they are written to disk.
+func (c *SpillingChangeCompactor) AddChange(change Change) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return errors.New("change compactor is closed")
+	}
+	if err := c.memory.AddChange(change); err != nil {
+		return err
+	}
+	if c.memory.Size() >= c.maxInMemory {
+		return c.spill()
+	}
+	return nil
+}
+
+// spill writes all changes kept in memory to a new run file. It must be
+// called with c.mutex held.
+func (c *SpillingChangeCompactor) spill() error {
+	changes, err := sortedChanges(c.memory.GetChanges())
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(c.dir, "run-"+strconv.Itoa(len(c.runs))+".xdr")
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "cannot create run file")
+	}
+	w := bufio.NewWriter(f)
+	for _, change := range changes {
+		if err = writeKeyedChange(w, change); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name)
+		return errors.Wrap(err, "cannot write run file")
+	}
+
+	c.runs = append(c.runs, name)
+	c.memory = NewChangeCompactor()
+	return nil
+}
+
+// GetChanges returns a ChangeReader streaming the squashed changes in ledger
+// key order (the order of XDR encoded ledger keys). Each change is connected
+// to a separate entry. Changes added after GetChanges was called are not
+// returned by the reader. The reader must be closed before the compactor is
+// closed.
+func (c *SpillingChangeCompactor) GetChanges() (ChangeReader, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil, errors.New("change compactor is closed")
+	}
+
+	memory, err := sortedChanges(c.memory.GetChanges())
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &compactedChangeReader{}
+	for _, name := range c.runs {
+		f, err := os.Open(name)
+		if err != nil {
+			reader.Close()
+			return nil, errors.Wrap(err, "cannot open run file")
+		}
+		reader.sources = append(reader.sources, &runSource{file: f, reader: bufio.NewReader(f)})
+	}
+	// Changes in memory are the most recent ones so they must be the last
+	// source.
+	reader.sources = append(reader.sources, &memorySource{changes: memory})
+
+	for i := range reader.sources {
+		if err := reader.advance(i); err != nil {
+			reader.Close()
+			return nil, err
+		}
+	}
+	return reader, nil
+}
+
+// Close removes all run files. The compactor cannot be used after calling
+// Close.
+func (c *SpillingChangeCompactor) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	c.memory = NewChangeCompactor()
+	c.runs = nil
+	return os.RemoveAll(c.dir)
+}
+
+// keyedChange is a change with its XDR encoded ledger key.
+type keyedChange struct {
+	key    []byte
+	change Change
+}
+
+func changeLedgerKey(change Change) xdr.LedgerKey {
+	if change.Post != nil {
+		return change.Post.LedgerKey()
+	}
+	return change.Pre.LedgerKey()
+}
+
+func sortedChanges(changes []Change) ([]keyedChange, error) {
+	keyed := make([]keyedChange, 0, len(changes))
+	for _, change := range changes {
+		key, err := changeLedgerKey(change).MarshalBinary()
+		if err != nil {
+			return nil, errors.Wrap(err, "Error MarshalBinary")
+		}
+		keyed = append(keyed, keyedChange{key: key, change: change})
+	}
+	sort.Slice(keyed, func(i, j int) bool {
+		return bytes.Compare(keyed[i].key, keyed[j].key) < 0
+	})
+	return keyed, nil
+}
+
+// writeKeyedChange writes the framed ledger key followed by the change
+// encoded as framed xdr.LedgerEntryChanges.
+func writeKeyedChange(w io.Writer, keyed keyedChange) error {
+	var entryChanges xdr.LedgerEntryChanges
+	switch keyed.change.LedgerEntryChangeType() {
+	case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+		entryChanges = xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: keyed.change.Post},
+		}
+	case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+		entryChanges = xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: keyed.change.Pre},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: keyed.change.Post},
+		}
+	case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+		key := keyed.change.Pre.LedgerKey()
+		entryChanges = xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: keyed.change.Pre},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+		}
+	}
+
+	frameLen := uint32(len(keyed.key)) | 0x80000000
+	if _, err := xdr.Marshal(w, frameLen); err != nil {
+		return err
+	}
+	if _, err := w.Write(keyed.key); err != nil {
+		return err
+	}
+	return xdr.MarshalFramed(w, entryChanges)
+}
+
+// readKeyedChange reads a change written by writeKeyedChange. It returns
+// io.EOF when there are no more changes.
+func readKeyedChange(r *bufio.Reader) (keyedChange, error) {
+	if _, err := r.Peek(1); err == io.EOF {
+		return keyedChange{}, io.EOF
+	}
+
+	frameLen, err := xdr.ReadFrameLength(r)
+	if err != nil {
+		return keyedChange{}, err
+	}
+	key := make([]byte, frameLen)
+	if _, err = io.ReadFull(r, key); err != nil {
+		return keyedChange{}, errors.Wrap(err, "error reading ledger key")
+	}
+
+	var entryChanges xdr.LedgerEntryChanges
+	if _, err = xdr.UnmarshalFramed(r, &entryChanges); err != nil {
+		return keyedChange{}, err
+	}
+
+	var change Change
+	for _, entryChange := range entryChanges {
+		switch entryChange.Type {
+		case xdr.LedgerEntryChangeTypeLedgerEntryState:
+			change.Pre = entryChange.State
+		case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+			change.Post = entryChange.Created
+		case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+			change.Post = entryChange.Updated
+		}
+	}
+	if change.Pre == nil && change.Post == nil {
+		return keyedChange{}, errors.New("malformed change in run file")
+	}
+	change.Type = changeLedgerKey(change).Type
+	return keyedChange{key: key, change: change}, nil
+}
+
+// squashChanges returns a change equivalent to applying older and then newer
+// to the same ledger entry, following the rules described in
+// ChangeCompactor. It returns false if the changes cancel each other out
+// (the entry was created and then removed).
+func squashChanges(key []byte, older, newer Change) (Change, bool, error) {
+	ledgerKeyString := base64.StdEncoding.EncodeToString(key)
+	newerType := newer.LedgerEntryChangeType()
+
+	switch older.LedgerEntryChangeType() {
+	case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+		switch newerType {
+		case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+			return Change{Type: newer.Type, Pre: nil, Post: newer.Post}, true, nil
+		case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+			return Change{}, false, nil
+		}
+	case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+		switch newerType {
+		case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+			return Change{Type: newer.Type, Pre: older.Pre, Post: newer.Post}, true, nil
+		case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+			return Change{Type: newer.Type, Pre: older.Pre, Post: nil}, true, nil
+		}
+	case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+		switch newerType {
+		case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+			return Change{Type: newer.Type, Pre: older.Pre, Post: newer.Post}, true, nil
+		case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+			return Change{}, false, NewStateError(errors.Errorf(
+				"can't update an entry that was previously removed (ledger key = %s)",
+				ledgerKeyString,
+			))
+		case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+			return Change{}, false, NewStateError(errors.Errorf(
+				"can't remove an entry that was previously removed (ledger key = %s)",
+				ledgerKeyString,
+			))
+		}
+	}
+
+	// Created after created or updated.
+	return Change{}, false, NewStateError(errors.Errorf(
+		"can't create an entry that already exists (ledger key = %s)",
+		ledgerKeyString,
+	))
+}
+
+// changeSource is a stream of changes sorted by ledger key.
+type changeSource interface {
+	next() (keyedChange, error)
+	close() error
+}
+
+type runSource struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+func (s *runSource) next() (keyedChange, error) {
+	return readKeyedChange(s.reader)
+}
+
+func (s *runSource) close() error {
+	return s.file.Close()
+}
+
+type memorySource struct {
+	changes []keyedChange
+}
+
+func (s *memorySource) next() (keyedChange, error) {
+	if len(s.changes) == 0 {
+		return keyedChange{}, io.EOF
+	}
+	change := s.changes[0]
+	s.changes = s.changes[1:]
+	return change, nil
+}
+
+func (s *memorySource) close() error {
+	s.changes = nil
+	return nil
+}
+
+type sourceHead struct {
+	keyedChange
+	source int
+}
+
+// sourceHeap orders heads by ledger key and then by source, so for a given
+// key older changes come first.
+type sourceHeap []sourceHead
+
+func (h sourceHeap) Len() int { return len(h) }
+func (h sourceHeap) Less(i, j int) bool {
+	if cmp := bytes.Compare(h[i].key, h[j].key); cmp != 0 {
+		return cmp < 0
+	}
+	return h[i].source < h[j].source
+}
+func (h sourceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sourceHeap) Push(x interface{}) { *h = append(*h, x.(sourceHead)) }
+func (h *sourceHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// compactedChangeReader merges sorted change sources, squashing changes of
+// the same ledger entry.
+type compactedChangeReader struct {
+	sources []changeSource
+	heads   sourceHeap
+}
+
+// Ensure compactedChangeReader implements ChangeReader
+var _ ChangeReader = (*compactedChangeReader)(nil)
+
+func (r *compactedChangeReader) advance(source int) error {
+	next, err := r.sources[source].next()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "error reading run file")
+	}
+	heap.Push(&r.heads, sourceHead{keyedChange: next, source: source})
+	return nil
+}
+
+// Read returns the next squashed change or io.EOF if there are no more
+// changes.
+func (r *compactedChangeReader) Read() (Change, error) {
+	for r.heads.Len() > 0 {
+		head := heap.Pop(&r.heads).(sourceHead)
+		if err := r.advance(head.source); err != nil {
+			return Change{}, err
+		}
+
+		change, exists := head.change, true
+		for r.heads.Len() > 0 && bytes.Equal(r.heads[0].key, head.key) {
+			newer := heap.Pop(&r.heads).(sourceHead)
+			if err := r.advance(newer.source); err != nil {
+				return Change{}, err
+			}
+			if !exists {
+				change, exists = newer.change, true
+				continue
+			}
+			var err error
+			change, exists, err = squashChanges(head.key, change, newer.change)
+			if err != nil {
+				return Change{}, err
+			}
+		}
+
+		if exists {
+			return change, nil
+		}
+	}
+	return Change{}, io.EOF
+}
+
+// Close closes all run files opened by the reader.
+func (r *compactedChangeReader) Close() error {
+	var firstErr error
+	for _, source := range r.sources {
+		if err := source.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	r.heads = nil
+	return firstErr
+}
@@ -0,0 +1,127 @@
+package ingest
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/xdr"
+)
+
+func readAllChanges(t *testing.T, reader ChangeReader) []Change {
+	var changes []Change
+	for {
+		change, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		changes = append(changes, change)
+	}
+	require.NoError(t, reader.Close())
+	return changes
+}
+
+func encodeChanges(t *testing.T, changes []Change) map[string]string {
+	encoded := map[string]string{}
+	for _, change := range changes {
+		key, err := xdr.MarshalBase64(changeLedgerKey(change))
+		require.NoError(t, err)
+		var pre, post string
+		if change.Pre != nil {
+			pre, err = xdr.MarshalBase64(change.Pre)
+			require.NoError(t, err)
+		}
+		if change.Post != nil {
+			post, err = xdr.MarshalBase64(change.Post)
+			require.NoError(t, err)
+		}
+		encoded[key] = pre + "/" + post
+	}
+	return encoded
+}
+
+func TestSpillingChangeCompactorMatchesChangeCompactor(t *testing.T) {
+	compactor, err := NewSpillingChangeCompactor("", 3)
+	require.NoError(t, err)
+	defer compactor.Close()
+	expected := NewChangeCompactor()
+
+	accounts := make([]xdr.AccountId, 8)
+	for i := range accounts {
+		accounts[i] = xdr.MustAddress(keypair.MustRandom().Address())
+	}
+	current := map[int]*xdr.LedgerEntry{}
+	random := rand.New(rand.NewSource(1))
+
+	for ledger := uint32(1); ledger <= 200; ledger++ {
+		i := random.Intn(len(accounts))
+		change := Change{Type: xdr.LedgerEntryTypeAccount, Pre: current[i]}
+		if current[i] == nil || random.Intn(4) > 0 {
+			change.Post = &xdr.LedgerEntry{
+				LastModifiedLedgerSeq: xdr.Uint32(ledger),
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeAccount,
+					Account: &xdr.AccountEntry{
+						AccountId: accounts[i],
+						Balance:   xdr.Int64(random.Intn(1000)),
+					},
+				},
+			}
+		}
+		current[i] = change.Post
+
+		require.NoError(t, compactor.AddChange(change))
+		require.NoError(t, expected.AddChange(change))
+	}
+	assert.NotEmpty(t, compactor.runs)
+
+	reader, err := compactor.GetChanges()
+	require.NoError(t, err)
+	changes := readAllChanges(t, reader)
+
+	for i := 1; i < len(changes); i++ {
+		prev, err := changeLedgerKey(changes[i-1]).MarshalBinary()
+		require.NoError(t, err)
+		next, err := changeLedgerKey(changes[i]).MarshalBinary()
+		require.NoError(t, err)
+		assert.Less(t, string(prev), string(next))
+	}
+	assert.Equal(t, encodeChanges(t, expected.GetChanges()), encodeChanges(t, changes))
+}
+
+func TestSpillingChangeCompactorStateErrorAcrossRuns(t *testing.T) {
+	compactor, err := NewSpillingChangeCompactor("", 1)
+	require.NoError(t, err)
+	defer compactor.Close()
+
+	entry := &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId: xdr.MustAddress("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+			},
+		},
+	}
+	require.NoError(t, compactor.AddChange(Change{Type: xdr.LedgerEntryTypeAccount, Pre: entry}))
+	require.NoError(t, compactor.AddChange(Change{Type: xdr.LedgerEntryTypeAccount, Pre: entry, Post: entry}))
+	assert.Len(t, compactor.runs, 2)
+
+	reader, err := compactor.GetChanges()
+	require.NoError(t, err)
+	_, err = reader.Read()
+	assert.EqualError(
+		t, err,
+		"can't update an entry that was previously removed (ledger key = AAAAAAAAAAC2LgFRDBZ3J52nLm30kq2iMgrO7dYzYAN3hvjtf1IHWg==)",
+	)
+	_, ok := err.(StateError)
+	assert.True(t, ok)
+	require.NoError(t, reader.Close())
+
+	require.NoError(t, compactor.Close())
+	assert.EqualError(t, compactor.AddChange(Change{Type: xdr.LedgerEntryTypeAccount, Pre: entry}), "change compactor is closed")
+}
@@ -755,9 +755,7 @@ type ClaimableBalance struct {
 }
 
 type ClaimableBalances struct {
-	Links struct {
-		Self hal.Link `json:"self"`
-	} `json:"_links"`
+	Links hal.Links `json:"_links"`
 
 	Embedded struct {
 		Records []ClaimableBalance `json:"records"`
@@ -0,0 +1,46 @@
+package horizon
+
+import (
+	"time"
+)
+
+// The statuses of a transaction submitted asynchronously, once Horizon
+// looked it up in the ledgers it ingested.
+const (
+	// TransactionStatusNotFound is the status of a transaction which is not in
+	// the ingested ledgers. It may still be pending in stellar-core, or it was
+	// dropped or never submitted.
+	TransactionStatusNotFound = "NOT_FOUND"
+	// TransactionStatusSuccess is the status of a transaction which was
+	// included in a ledger and succeeded.
+	TransactionStatusSuccess = "SUCCESS"
+	// TransactionStatusFailed is the status of a transaction which was
+	// included in a ledger and failed.
+	TransactionStatusFailed = "FAILED"
+)
+
+// AsyncTransactionSubmissionResponse is the response of the
+// `/transactions_async` endpoint. It is returned as soon as stellar-core
+// handled the transaction, before it is included in a ledger.
+type AsyncTransactionSubmissionResponse struct {
+	// ErrorResultXDR is the TransactionResult XDR returned by stellar-core,
+	// only present if TxStatus is "ERROR".
+	ErrorResultXDR string `json:"error_result_xdr,omitempty"`
+	// TxStatus is the status returned by stellar-core: "PENDING",
+	// "DUPLICATE", "ERROR" or "TRY_AGAIN_LATER".
+	TxStatus string `json:"tx_status"`
+	// Hash is the hash of the transaction.
+	Hash string `json:"hash"`
+}
+
+// AsyncTransactionStatus is the response of the `/transactions_async/{hash}`
+// endpoint, reporting whether a transaction was included in a ledger.
+type AsyncTransactionStatus struct {
+	Hash   string `json:"hash"`
+	Status string `json:"status"`
+	// The fields below are only present once the transaction is included in
+	// a ledger.
+	Ledger          int32      `json:"ledger,omitempty"`
+	LedgerCloseTime *time.Time `json:"created_at,omitempty"`
+	ResultXdr       string     `json:"result_xdr,omitempty"`
+}
@@ -0,0 +1,47 @@
+package horizon
+
+import (
+	"encoding/json"
+
+	"github.com/stellar/go/support/render/problem"
+)
+
+// Types of the messages sent on a WebSocket connection to Horizon.
+const (
+	// WebSocketSubscribe subscribes to the stream of a path.
+	WebSocketSubscribe = "subscribe"
+	// WebSocketUnsubscribe ends a subscription.
+	WebSocketUnsubscribe = "unsubscribe"
+
+	// WebSocketSubscribed acknowledges a subscription.
+	WebSocketSubscribed = "subscribed"
+	// WebSocketUnsubscribed acknowledges the end of a subscription.
+	WebSocketUnsubscribed = "unsubscribed"
+	// WebSocketEvent carries a resource of the stream of a subscription.
+	WebSocketEvent = "event"
+	// WebSocketError ends a subscription, or reports an invalid request.
+	WebSocketError = "error"
+)
+
+// WebSocketRequest is a message sent by a client on a WebSocket connection to
+// Horizon.
+type WebSocketRequest struct {
+	Type string `json:"type"`
+	// ID identifies the subscription in the messages of the connection. It is
+	// chosen by the client and must be unique among its subscriptions.
+	ID string `json:"id"`
+	// Path is the path and query of a streamable end-point, for example
+	// "/accounts/{account_id}/payments?cursor=now", for subscribe requests.
+	Path string `json:"path,omitempty"`
+}
+
+// WebSocketMessage is a message sent by Horizon on a WebSocket connection.
+type WebSocketMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// EventID is the paging token of the resource of an event, when the
+	// stream has one. Subscribing with it as the cursor resumes the stream.
+	EventID string          `json:"event_id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Problem *problem.P      `json:"problem,omitempty"`
+}
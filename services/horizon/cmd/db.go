@@ -227,6 +227,7 @@ var (
 	parallelJobSize     uint32
 	retries             uint
 	retryBackoffSeconds uint
+	reingestLedgerDir   string
 )
 var reingestRangeCmdOpts = []*support.ConfigOption{
 	{
@@ -270,6 +271,14 @@ var reingestRangeCmdOpts = []*support.ConfigOption{
 		FlagDefault: uint(5),
 		Usage:       "[optional] backoff seconds between reingest retries",
 	},
+	{
+		Name:        "ledger-dir",
+		ConfigKey:   &reingestLedgerDir,
+		OptType:     types.String,
+		Required:    false,
+		FlagDefault: "",
+		Usage:       "[optional] directory of ledgers exported by horizon ingest export-ledgers to reingest from instead of stellar-core",
+	},
 }
 
 var dbReingestRangeCmd = &cobra.Command{
@@ -343,9 +352,10 @@ func runDBReingestRange(from, to uint32, reingestForce bool, parallelWorkers uin
 		CaptiveCoreStoragePath:      config.CaptiveCoreStoragePath,
 		StellarCoreCursor:           config.CursorName,
 		StellarCoreURL:              config.StellarCoreURL,
+		LedgerDir:                   reingestLedgerDir,
 	}
 
-	if !ingestConfig.EnableCaptiveCore {
+	if !ingestConfig.EnableCaptiveCore && ingestConfig.LedgerDir == "" {
 		if config.StellarCoreDatabaseURL == "" {
 			return fmt.Errorf("flag --%s cannot be empty", horizon.StellarCoreDBURLFlagName)
 		}
@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stellar/go/historyarchive"
+	"github.com/stellar/go/ingest/ledgerbackend"
 	horizon "github.com/stellar/go/services/horizon/internal"
 	"github.com/stellar/go/services/horizon/internal/db2/history"
 	"github.com/stellar/go/services/horizon/internal/ingest"
@@ -146,6 +147,109 @@ var ingestVerifyRangeCmd = &cobra.Command{
 	},
 }
 
+var ingestExportFrom, ingestExportTo uint32
+var ingestExportLedgerDir string
+var ingestExportCompress bool
+
+var ingestExportLedgersCmdOpts = []*support.ConfigOption{
+	{
+		Name:        "from",
+		ConfigKey:   &ingestExportFrom,
+		OptType:     types.Uint32,
+		Required:    true,
+		FlagDefault: uint32(0),
+		Usage:       "first ledger of the range to export",
+	},
+	{
+		Name:        "to",
+		ConfigKey:   &ingestExportTo,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(0),
+		Usage:       "[optional] last ledger of the range to export, ledgers are exported as they close if 0",
+	},
+	{
+		Name:        "ledger-dir",
+		ConfigKey:   &ingestExportLedgerDir,
+		OptType:     types.String,
+		Required:    true,
+		FlagDefault: "",
+		Usage:       "directory the ledgers are exported to",
+	},
+	{
+		Name:        "compress",
+		ConfigKey:   &ingestExportCompress,
+		OptType:     types.Bool,
+		Required:    false,
+		FlagDefault: false,
+		Usage:       "[optional] gzips the exported ledgers when true",
+	},
+}
+
+var ingestExportLedgersCmd = &cobra.Command{
+	Use:   "export-ledgers",
+	Short: "exports ledgers to a directory which can be reingested with `db reingest range --ledger-dir`",
+	Long:  "exports the ledgers between --from and --to (inclusive) from stellar-core to a directory",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, co := range ingestExportLedgersCmdOpts {
+			if err := co.RequireE(); err != nil {
+				return err
+			}
+			co.SetValue()
+		}
+
+		if err := horizon.ApplyFlags(config, flags, horizon.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true}); err != nil {
+			return err
+		}
+
+		ledgerRange := ledgerbackend.UnboundedRange(ingestExportFrom)
+		if ingestExportTo != 0 {
+			if ingestExportTo < ingestExportFrom {
+				return fmt.Errorf("`--to` must not be lower than `--from`")
+			}
+			ledgerRange = ledgerbackend.BoundedRange(ingestExportFrom, ingestExportTo)
+		}
+
+		ingestConfig := ingest.Config{
+			NetworkPassphrase:      config.NetworkPassphrase,
+			HistoryArchiveURL:      config.HistoryArchiveURLs[0],
+			EnableCaptiveCore:      config.EnableCaptiveCoreIngestion,
+			CaptiveCoreBinaryPath:  config.CaptiveCoreBinaryPath,
+			RemoteCaptiveCoreURL:   config.RemoteCaptiveCoreURL,
+			CheckpointFrequency:    config.CheckpointFrequency,
+			CaptiveCoreToml:        config.CaptiveCoreToml,
+			CaptiveCoreStoragePath: config.CaptiveCoreStoragePath,
+		}
+
+		if !ingestConfig.EnableCaptiveCore {
+			if config.StellarCoreDatabaseURL == "" {
+				return fmt.Errorf("flag --%s cannot be empty", horizon.StellarCoreDBURLFlagName)
+			}
+
+			coreSession, dbErr := db.Open("postgres", config.StellarCoreDatabaseURL)
+			if dbErr != nil {
+				return fmt.Errorf("cannot open Core DB: %v", dbErr)
+			}
+			ingestConfig.CoreSession = coreSession
+		}
+
+		ctx := context.Background()
+		backend, err := ingest.NewLedgerBackend(ctx, ingestConfig)
+		if err != nil {
+			return err
+		}
+		defer backend.Close()
+
+		err = ledgerbackend.ExportLedgers(ctx, backend, ledgerRange, ingestExportLedgerDir, ingestExportCompress)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Ledgers exported successfully!")
+		return nil
+	},
+}
+
 var stressTestNumTransactions, stressTestChangesPerTransaction int
 
 var stressTestCmdOpts = []*support.ConfigOption{
@@ -330,6 +434,13 @@ func init() {
 		}
 	}
 
+	for _, co := range ingestExportLedgersCmdOpts {
+		err := co.Init(ingestExportLedgersCmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
 	for _, co := range stressTestCmdOpts {
 		err := co.Init(ingestStressTestCmd)
 		if err != nil {
@@ -342,6 +453,7 @@ func init() {
 	RootCmd.AddCommand(ingestCmd)
 	ingestCmd.AddCommand(
 		ingestVerifyRangeCmd,
+		ingestExportLedgersCmd,
 		ingestStressTestCmd,
 		ingestTriggerStateRebuildCmd,
 		ingestInitGenesisStateCmd,
@@ -0,0 +1,223 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/db2"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/render/hal"
+	"github.com/stellar/go/xdr"
+)
+
+// LiveResourcesFunc returns the resources of a ledger matching a streaming
+// request, in order. The resources are built from the change set of the
+// ledger without querying the DB.
+type LiveResourcesFunc func(ctx context.Context, changes *feed.ChangeSet) ([]hal.Pageable, error)
+
+// ChangeFilterFunc returns true if the ledger of the change set may have
+// changed the resource of a streaming request.
+type ChangeFilterFunc func(changes *feed.ChangeSet) bool
+
+// GetLiveResources returns the function building the operations of a new
+// ledger for a streaming request. It returns false when the request has a
+// filter the feed cannot serve.
+func (handler GetOperationsHandler) GetLiveResources(r *http.Request) (LiveResourcesFunc, bool, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r)
+	if err != nil {
+		return nil, false, err
+	}
+	qp := OperationsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, false, err
+	}
+	if pq.Order != db2.OrderAscending || qp.ClaimableBalanceID != "" || qp.LiquidityPoolID != "" ||
+		qp.LedgerID > 0 || qp.TransactionHash != "" {
+		return nil, false, nil
+	}
+
+	return func(ctx context.Context, changes *feed.ChangeSet) ([]hal.Pageable, error) {
+		transactions := map[int64]*history.Transaction{}
+		for i := range changes.Transactions {
+			transactions[changes.Transactions[i].ID] = &changes.Transactions[i]
+		}
+
+		var response []hal.Pageable
+		for _, op := range changes.Operations {
+			if !op.TransactionSuccessful && !qp.IncludeFailedTransactions {
+				continue
+			}
+			if qp.AccountID != "" && !changes.OperationInvolves(op.ID, qp.AccountID) {
+				continue
+			}
+			if handler.OnlyPayments && !isPayment(op.Type) {
+				continue
+			}
+
+			var tx *history.Transaction
+			if qp.IncludeTransactions() {
+				var ok bool
+				if tx, ok = transactions[op.TransactionID]; !ok {
+					return nil, errors.Errorf("could not find transaction %d", op.TransactionID)
+				}
+			}
+			res, err := resourceadapter.NewOperation(ctx, op, op.TransactionHash, tx, changes.Ledger)
+			if err != nil {
+				return nil, err
+			}
+			response = append(response, res)
+		}
+		return response, nil
+	}, true, nil
+}
+
+func isPayment(operationType xdr.OperationType) bool {
+	for _, paymentType := range history.PaymentOperationTypes {
+		if operationType == paymentType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLiveResources returns the function building the transactions of a new
+// ledger for a streaming request. It returns false when the request has a
+// filter the feed cannot serve.
+func (handler GetTransactionsHandler) GetLiveResources(r *http.Request) (LiveResourcesFunc, bool, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r)
+	if err != nil {
+		return nil, false, err
+	}
+	qp := TransactionsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, false, err
+	}
+	if pq.Order != db2.OrderAscending || qp.ClaimableBalanceID != "" || qp.LiquidityPoolID != "" ||
+		qp.LedgerID > 0 {
+		return nil, false, nil
+	}
+
+	return func(ctx context.Context, changes *feed.ChangeSet) ([]hal.Pageable, error) {
+		var response []hal.Pageable
+		for _, record := range changes.Transactions {
+			if !record.Successful && !qp.IncludeFailedTransactions {
+				continue
+			}
+			if qp.AccountID != "" && !changes.TransactionInvolves(record.ID, qp.AccountID) {
+				continue
+			}
+
+			var res horizon.Transaction
+			if err := resourceadapter.PopulateTransaction(ctx, record.TransactionHash, &res, record); err != nil {
+				return nil, errors.Wrap(err, "could not populate transaction")
+			}
+			response = append(response, res)
+		}
+		return response, nil
+	}, true, nil
+}
+
+// GetLiveResources returns the function building the effects of a new ledger
+// for a streaming request. It returns false when the request has a filter the
+// feed cannot serve.
+func (handler GetEffectsHandler) GetLiveResources(r *http.Request) (LiveResourcesFunc, bool, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r)
+	if err != nil {
+		return nil, false, err
+	}
+	qp := EffectsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, false, err
+	}
+	if pq.Order != db2.OrderAscending || qp.OperationID > 0 || qp.LiquidityPoolID != "" ||
+		qp.TxHash != "" || qp.LedgerID > 0 {
+		return nil, false, nil
+	}
+
+	return func(ctx context.Context, changes *feed.ChangeSet) ([]hal.Pageable, error) {
+		var response []hal.Pageable
+		for _, record := range changes.Effects {
+			if qp.AccountID != "" && record.Account != qp.AccountID {
+				continue
+			}
+
+			effect, err := resourceadapter.NewEffect(ctx, record, changes.Ledger)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not create effect")
+			}
+			response = append(response, effect)
+		}
+		return response, nil
+	}, true, nil
+}
+
+// GetLiveResources returns the function building the resource of a new
+// ledger for a streaming request.
+func (handler GetLedgersHandler) GetLiveResources(r *http.Request) (LiveResourcesFunc, bool, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r)
+	if err != nil {
+		return nil, false, err
+	}
+	if pq.Order != db2.OrderAscending {
+		return nil, false, nil
+	}
+
+	return func(ctx context.Context, changes *feed.ChangeSet) ([]hal.Pageable, error) {
+		var ledger horizon.Ledger
+		resourceadapter.PopulateLedger(ctx, &ledger, changes.Ledger)
+		return []hal.Pageable{ledger}, nil
+	}, true, nil
+}
+
+// accountChangeFilter returns the filter matching the ledgers which changed
+// the account of the request.
+func accountChangeFilter(r *http.Request) (ChangeFilterFunc, error) {
+	accountID, err := getAccountID(r, "account_id")
+	if err != nil {
+		return nil, err
+	}
+	address := accountID.Address()
+	return func(changes *feed.ChangeSet) bool {
+		return changes.AccountChanged(address)
+	}, nil
+}
+
+// GetChangeFilter returns the filter matching the ledgers which changed the
+// account.
+func (handler GetAccountByIDHandler) GetChangeFilter(r *http.Request) (ChangeFilterFunc, error) {
+	return accountChangeFilter(r)
+}
+
+// GetChangeFilter returns the filter matching the ledgers which changed the
+// data entries of the account.
+func (handler GetAccountDataHandler) GetChangeFilter(r *http.Request) (ChangeFilterFunc, error) {
+	return accountChangeFilter(r)
+}
+
+// GetChangeFilter returns the filter matching the ledgers which changed the
+// offers of the account.
+func (handler GetAccountOffersHandler) GetChangeFilter(r *http.Request) (ChangeFilterFunc, error) {
+	return accountChangeFilter(r)
+}
+
+// GetChangeFilter returns the filter matching the ledgers which include the
+// transaction.
+func (handler GetAsyncTransactionStatusHandler) GetChangeFilter(r *http.Request) (ChangeFilterFunc, error) {
+	qp := TransactionQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	return func(changes *feed.ChangeSet) bool {
+		for _, tx := range changes.Transactions {
+			if tx.TransactionHash == qp.TransactionHash ||
+				(tx.InnerTransactionHash.Valid && tx.InnerTransactionHash.String == qp.TransactionHash) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -44,7 +44,7 @@ func extractEnvelopeInfo(raw string, passphrase string) (envelopeInfo, error) {
 	return result, nil
 }
 
-func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error {
+func validateBodyType(r *http.Request) error {
 	c := r.Header.Get("Content-Type")
 	if c == "" {
 		return nil
@@ -61,6 +61,37 @@ func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error
 	return nil
 }
 
+// getEnvelopeInfo returns the transaction envelope submitted in the body of
+// the request.
+func getEnvelopeInfo(r *http.Request, passphrase string) (envelopeInfo, error) {
+	if err := validateBodyType(r); err != nil {
+		return envelopeInfo{}, err
+	}
+
+	raw, err := getString(r, "tx")
+	if err != nil {
+		return envelopeInfo{}, err
+	}
+
+	info, err := extractEnvelopeInfo(raw, passphrase)
+	if err != nil {
+		return info, &problem.P{
+			Type:   "transaction_malformed",
+			Title:  "Transaction Malformed",
+			Status: http.StatusBadRequest,
+			Detail: "Horizon could not decode the transaction envelope in this " +
+				"request. A transaction should be an XDR TransactionEnvelope struct " +
+				"encoded using base64.  The envelope read from this request is " +
+				"echoed in the `extras.envelope_xdr` field of this response for your " +
+				"convenience.",
+			Extras: map[string]interface{}{
+				"envelope_xdr": raw,
+			},
+		}
+	}
+	return info, nil
+}
+
 func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeInfo, result txsub.Result) (hal.Pageable, error) {
 	if result.Err == nil {
 		var resource horizon.Transaction
@@ -111,32 +142,11 @@ func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeI
 }
 
 func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
-	if err := handler.validateBodyType(r); err != nil {
-		return nil, err
-	}
-
-	raw, err := getString(r, "tx")
+	info, err := getEnvelopeInfo(r, handler.NetworkPassphrase)
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
-	if err != nil {
-		return nil, &problem.P{
-			Type:   "transaction_malformed",
-			Title:  "Transaction Malformed",
-			Status: http.StatusBadRequest,
-			Detail: "Horizon could not decode the transaction envelope in this " +
-				"request. A transaction should be an XDR TransactionEnvelope struct " +
-				"encoded using base64.  The envelope read from this request is " +
-				"echoed in the `extras.envelope_xdr` field of this response for your " +
-				"convenience.",
-			Extras: map[string]interface{}{
-				"envelope_xdr": raw,
-			},
-		}
-	}
-
 	coreState := handler.GetCoreState()
 	if !coreState.Synced {
 		return nil, hProblem.StaleHistory
@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/stellar/go/protocols/horizon"
+	proto "github.com/stellar/go/protocols/stellarcore"
+	horizonContext "github.com/stellar/go/services/horizon/internal/context"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	hProblem "github.com/stellar/go/services/horizon/internal/render/problem"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+	"github.com/stellar/go/services/horizon/internal/txsub"
+	"github.com/stellar/go/support/errors"
+)
+
+// AsyncSubmitTransactionHandler is the action handler for the end-point
+// submitting transactions to stellar-core without waiting for them to be
+// included in a ledger.
+type AsyncSubmitTransactionHandler struct {
+	Submitter         *txsub.System
+	NetworkPassphrase string
+	CoreStateGetter
+}
+
+// AsyncTransactionSubmissionResponse is the response of the asynchronous
+// submission end-point. It is rendered with the HTTP status code matching the
+// status returned by stellar-core.
+type AsyncTransactionSubmissionResponse struct {
+	horizon.AsyncTransactionSubmissionResponse
+}
+
+// StatusCode returns the HTTP status code of the response.
+func (response AsyncTransactionSubmissionResponse) StatusCode() int {
+	switch response.TxStatus {
+	case proto.TXStatusPending:
+		return http.StatusCreated
+	case proto.TXStatusDuplicate:
+		return http.StatusConflict
+	case proto.TXStatusTryAgainLater:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetResource submits the transaction and returns the status returned by
+// stellar-core.
+func (handler AsyncSubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	info, err := getEnvelopeInfo(r, handler.NetworkPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	coreState := handler.GetCoreState()
+	if !coreState.Synced {
+		return nil, hProblem.StaleHistory
+	}
+
+	result := handler.Submitter.SubmitAsync(
+		r.Context(),
+		info.raw,
+		info.parsed,
+		info.hash,
+	)
+
+	response := AsyncTransactionSubmissionResponse{
+		horizon.AsyncTransactionSubmissionResponse{
+			TxStatus: result.Status,
+			Hash:     info.hash,
+		},
+	}
+	if failed, ok := result.Err.(*txsub.FailedTransactionError); ok {
+		response.ErrorResultXDR = failed.ResultXDR
+	} else if result.Err != nil {
+		return nil, result.Err
+	}
+	return response, nil
+}
+
+// GetAsyncTransactionStatusHandler is the action handler for the end-point
+// reporting whether a transaction was included in a ledger.
+type GetAsyncTransactionStatusHandler struct{}
+
+// AsyncTransactionStatus implements StreamableObjectResponse.
+type AsyncTransactionStatus horizon.AsyncTransactionStatus
+
+// Equals returns true if both statuses are the same status of the same
+// transaction.
+func (s AsyncTransactionStatus) Equals(other StreamableObjectResponse) bool {
+	otherStatus, ok := other.(AsyncTransactionStatus)
+	if !ok {
+		return false
+	}
+	return s.Hash == otherStatus.Hash && s.Status == otherStatus.Status
+}
+
+// GetResource returns the status of the transaction.
+func (handler GetAsyncTransactionStatusHandler) GetResource(
+	w HeaderWriter,
+	r *http.Request,
+) (StreamableObjectResponse, error) {
+	ctx := r.Context()
+	qp := TransactionQuery{}
+	err := getParams(&qp, r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := horizonContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	status := AsyncTransactionStatus{
+		Hash:   qp.TransactionHash,
+		Status: horizon.TransactionStatusNotFound,
+	}
+
+	var record history.Transaction
+	err = historyQ.TransactionByHash(ctx, &record, qp.TransactionHash)
+	if historyQ.NoRows(err) {
+		return status, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "loading transaction record")
+	}
+
+	var resource horizon.Transaction
+	if err = resourceadapter.PopulateTransaction(ctx, qp.TransactionHash, &resource, record); err != nil {
+		return nil, errors.Wrap(err, "could not populate transaction")
+	}
+	status.Status = horizon.TransactionStatusFailed
+	if resource.Successful {
+		status.Status = horizon.TransactionStatusSuccess
+	}
+	status.Ledger = resource.Ledger
+	status.LedgerCloseTime = &resource.LedgerCloseTime
+	status.ResultXdr = resource.ResultXdr
+	return status, nil
+}
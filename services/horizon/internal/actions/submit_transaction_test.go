@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/stellar/go/network"
 	"github.com/stellar/go/services/horizon/internal/corestate"
+	"github.com/stellar/go/services/horizon/internal/txsub"
+	"github.com/stellar/go/support/errors"
 	"github.com/stellar/go/support/render/problem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -64,3 +67,74 @@ func TestStellarCoreNotSynced(t *testing.T) {
 	assert.Equal(t, "stale_history", err.(problem.P).Type)
 	assert.Equal(t, "Historical DB Is Too Stale", err.(problem.P).Title)
 }
+
+type submitterMock struct {
+	mock.Mock
+}
+
+func (m *submitterMock) Submit(ctx context.Context, env string) txsub.SubmissionResult {
+	a := m.Called(ctx, env)
+	return a.Get(0).(txsub.SubmissionResult)
+}
+
+func TestAsyncSubmitTransaction(t *testing.T) {
+	const (
+		tx   = "AAAAAAGUcmKO5465JxTSLQOQljwk2SfqAJmZSG6JH6wtqpwhAAABLAAAAAAAAAABAAAAAAAAAAEAAAALaGVsbG8gd29ybGQAAAAAAwAAAAAAAAAAAAAAABbxCy3mLg3hiTqX4VUEEp60pFOrJNxYM1JtxXTwXhY2AAAAAAvrwgAAAAAAAAAAAQAAAAAW8Qst5i4N4Yk6l+FVBBKetKRTqyTcWDNSbcV08F4WNgAAAAAN4Lazj4x61AAAAAAAAAAFAAAAAAAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABLaqcIQAAAEBKwqWy3TaOxoGnfm9eUjfTRBvPf34dvDA0Nf+B8z4zBob90UXtuCqmQqwMCyH+okOI3c05br3khkH0yP4kCwcE"
+		hash = "3389e9f0f1a65f19736cacf544c2e825313e8447f569233bb8db39aa607c8889"
+	)
+
+	coreState := &coreStateGetterMock{}
+	coreState.On("GetCoreState").Return(corestate.State{Synced: true})
+
+	submit := func(result txsub.SubmissionResult) (interface{}, error) {
+		submitter := &submitterMock{}
+		submitter.On("Submit", mock.Anything, tx).Return(result).Once()
+		defer submitter.AssertExpectations(t)
+
+		handler := AsyncSubmitTransactionHandler{
+			Submitter:         &txsub.System{Submitter: submitter},
+			NetworkPassphrase: network.PublicNetworkPassphrase,
+			CoreStateGetter:   coreState,
+		}
+
+		form := url.Values{}
+		form.Set("tx", tx)
+		request, err := http.NewRequest(
+			"POST",
+			"https://horizon.stellar.org/transactions_async",
+			strings.NewReader(form.Encode()),
+		)
+		require.NoError(t, err)
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		return handler.GetResource(httptest.NewRecorder(), request)
+	}
+
+	resource, err := submit(txsub.SubmissionResult{Status: "PENDING"})
+	assert.NoError(t, err)
+	response := resource.(AsyncTransactionSubmissionResponse)
+	assert.Equal(t, "PENDING", response.TxStatus)
+	assert.Equal(t, hash, response.Hash)
+	assert.Empty(t, response.ErrorResultXDR)
+	assert.Equal(t, http.StatusCreated, response.StatusCode())
+
+	resource, err = submit(txsub.SubmissionResult{Status: "DUPLICATE"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resource.(AsyncTransactionSubmissionResponse).StatusCode())
+
+	resource, err = submit(txsub.SubmissionResult{Status: "TRY_AGAIN_LATER"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resource.(AsyncTransactionSubmissionResponse).StatusCode())
+
+	resource, err = submit(txsub.SubmissionResult{
+		Status: "ERROR",
+		Err:    &txsub.FailedTransactionError{ResultXDR: "AAAAAAAAAGT/////AAAAAQAAAAAAAAAB////+wAAAAA="},
+	})
+	assert.NoError(t, err)
+	response = resource.(AsyncTransactionSubmissionResponse)
+	assert.Equal(t, "ERROR", response.TxStatus)
+	assert.Equal(t, "AAAAAAAAAGT/////AAAAAQAAAAAAAAAB////+wAAAAA=", response.ErrorResultXDR)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode())
+
+	_, err = submit(txsub.SubmissionResult{Err: errors.New("stellar-core exception: Invalid XDR")})
+	assert.EqualError(t, err, "stellar-core exception: Invalid XDR")
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/stellar/go/clients/stellarcore"
 	"github.com/stellar/go/services/horizon/internal/corestate"
 	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
 	"github.com/stellar/go/services/horizon/internal/httpx"
 	"github.com/stellar/go/services/horizon/internal/ingest"
 	"github.com/stellar/go/services/horizon/internal/ledger"
@@ -43,6 +44,8 @@ type App struct {
 	horizonVersion  string
 	coreState       corestate.Store
 	orderBookStream *ingest.OrderBookStream
+	feed            *feed.Feed
+	feedStream      *ingest.FeedStream
 	submitter       *txsub.System
 	paths           paths.Finder
 	ingester        ingest.System
@@ -97,6 +100,7 @@ func (a *App) Serve() error {
 
 	go a.run()
 	go a.orderBookStream.Run(a.ctx)
+	go a.feedStream.Run(a.ctx)
 
 	// WaitGroup for all go routines. Makes sure that DB is closed when
 	// all services gracefully shutdown.
@@ -489,6 +493,9 @@ func (a *App) init() error {
 	}
 	initPathFinder(a)
 
+	// feed
+	initFeed(a)
+
 	// txsub
 	initSubmissionSystem(a)
 
@@ -514,13 +521,18 @@ func (a *App) init() error {
 		DBSession:             a.historyQ.SessionInterface,
 		TxSubmitter:           a.submitter,
 		RateQuota:             a.config.RateQuota,
+		RateLimitConfigPath:   a.config.RateLimitConfigPath,
+		RateLimitRedisURL:     a.config.RateLimitRedisURL,
 		BehindCloudflare:      a.config.BehindCloudflare,
 		BehindAWSLoadBalancer: a.config.BehindAWSLoadBalancer,
 		SSEUpdateFrequency:    a.config.SSEUpdateFrequency,
+		Feed:                  a.feed,
 		StaleThreshold:        a.config.StaleThreshold,
 		ConnectionTimeout:     a.config.ConnectionTimeout,
 		NetworkPassphrase:     a.config.NetworkPassphrase,
 		MaxPathLength:         a.config.MaxPathLength,
+		EnableGraphQL:         a.config.EnableGraphQL,
+		GraphQLMaxCost:        a.config.GraphQLMaxCost,
 		PathFinder:            a.paths,
 		PrometheusRegistry:    a.prometheusRegistry,
 		CoreGetter:            a,
@@ -7,7 +7,7 @@ import (
 	"github.com/stellar/go/ingest/ledgerbackend"
 
 	"github.com/sirupsen/logrus"
-	"github.com/stellar/throttled"
+	"github.com/throttled/throttled"
 )
 
 // Config is the configuration for horizon.  It gets populated by the
@@ -43,8 +43,19 @@ type Config struct {
 	FriendbotURL       *url.URL
 	LogLevel           logrus.Level
 	LogFile            string
+	// RateLimitConfigPath is the path of the TOML file configuring the rate
+	// limit tiers of the API keys and the costs of the endpoints.
+	RateLimitConfigPath string
+	// RateLimitRedisURL is the URL of the Redis server sharing the state of
+	// the quotas between Horizon instances.
+	RateLimitRedisURL string
 	// MaxPathLength is the maximum length of the path returned by `/paths` endpoint.
-	MaxPathLength     uint
+	MaxPathLength uint
+	// EnableGraphQL serves the GraphQL API at the `/graphql` endpoint.
+	EnableGraphQL bool
+	// GraphQLMaxCost is the maximum number of records a query of the
+	// `/graphql` endpoint may load.
+	GraphQLMaxCost    uint
 	NetworkPassphrase string
 	SentryDSN         string
 	LogglyToken       string
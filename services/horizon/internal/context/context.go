@@ -54,7 +54,12 @@ func BaseURL(ctx context.Context) *url.URL {
 }
 
 func HistoryQFromRequest(request *http.Request) (*history.Q, error) {
-	ctx := request.Context()
+	return HistoryQFromContext(request.Context())
+}
+
+// HistoryQFromContext returns a history.Q using the DB session of the request
+// of ctx.
+func HistoryQFromContext(ctx context.Context) (*history.Q, error) {
 	session, ok := ctx.Value(&SessionContextKey).(db.SessionInterface)
 	if !ok {
 		return nil, errors.New("missing session in request context")
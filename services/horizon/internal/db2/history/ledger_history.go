@@ -0,0 +1,82 @@
+package history
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/stellar/go/services/horizon/internal/toid"
+)
+
+// Participant is an account participating in a transaction or an operation.
+type Participant struct {
+	ID      int64  `db:"id"`
+	Address string `db:"address"`
+}
+
+// ledgerRange returns the range of the total order ids of the ledger.
+func ledgerRange(seq int32) (int64, int64) {
+	start := toid.ID{LedgerSequence: seq}
+	end := toid.ID{LedgerSequence: seq + 1}
+	return start.ToInt64(), end.ToInt64()
+}
+
+// TransactionsInLedger returns the transactions of the ledger, including the
+// failed ones, in application order.
+func (q *Q) TransactionsInLedger(ctx context.Context, seq int32) ([]Transaction, error) {
+	var dest []Transaction
+	sql := selectTransaction.
+		Where("ht.ledger_sequence = ?", seq).
+		OrderBy("ht.id asc")
+	err := q.Select(ctx, &dest, sql)
+	return dest, err
+}
+
+// OperationsInLedger returns the operations of the ledger, including the
+// operations of failed transactions, in application order.
+func (q *Q) OperationsInLedger(ctx context.Context, seq int32) ([]Operation, error) {
+	var dest []Operation
+	start, end := ledgerRange(seq)
+	sql := selectOperation.
+		Where("hop.id >= ? AND hop.id < ?", start, end).
+		OrderBy("hop.id asc")
+	err := q.Select(ctx, &dest, sql)
+	return dest, err
+}
+
+// EffectsInLedger returns the effects of the ledger in order.
+func (q *Q) EffectsInLedger(ctx context.Context, seq int32) ([]Effect, error) {
+	var dest []Effect
+	start, end := ledgerRange(seq)
+	sql := selectEffect.
+		Where("heff.history_operation_id >= ? AND heff.history_operation_id < ?", start, end).
+		OrderBy("heff.history_operation_id asc, heff.order asc")
+	err := q.Select(ctx, &dest, sql)
+	return dest, err
+}
+
+// TransactionParticipantsInLedger returns the accounts participating in the
+// transactions of the ledger, by transaction id.
+func (q *Q) TransactionParticipantsInLedger(ctx context.Context, seq int32) ([]Participant, error) {
+	var dest []Participant
+	start, end := ledgerRange(seq)
+	sql := sq.Select("htp.history_transaction_id AS id, hacc.address").
+		From("history_transaction_participants htp").
+		Join("history_accounts hacc ON hacc.id = htp.history_account_id").
+		Where("htp.history_transaction_id >= ? AND htp.history_transaction_id < ?", start, end)
+	err := q.Select(ctx, &dest, sql)
+	return dest, err
+}
+
+// OperationParticipantsInLedger returns the accounts participating in the
+// operations of the ledger, by operation id.
+func (q *Q) OperationParticipantsInLedger(ctx context.Context, seq int32) ([]Participant, error) {
+	var dest []Participant
+	start, end := ledgerRange(seq)
+	sql := sq.Select("hopp.history_operation_id AS id, hacc.address").
+		From("history_operation_participants hopp").
+		Join("history_accounts hacc ON hacc.id = hopp.history_account_id").
+		Where("hopp.history_operation_id >= ? AND hopp.history_operation_id < ?", start, end)
+	err := q.Select(ctx, &dest, sql)
+	return dest, err
+}
@@ -0,0 +1,134 @@
+package feed
+
+import (
+	"context"
+
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/support/errors"
+)
+
+// Q is the interface of the history queries loading the changes of a ledger.
+type Q interface {
+	LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error
+	TransactionsInLedger(ctx context.Context, seq int32) ([]history.Transaction, error)
+	OperationsInLedger(ctx context.Context, seq int32) ([]history.Operation, error)
+	EffectsInLedger(ctx context.Context, seq int32) ([]history.Effect, error)
+	TransactionParticipantsInLedger(ctx context.Context, seq int32) ([]history.Participant, error)
+	OperationParticipantsInLedger(ctx context.Context, seq int32) ([]history.Participant, error)
+}
+
+// ChangeSet is the history of a single ledger: its transactions, including
+// the failed ones, their operations and the effects of the operations, all
+// in application order. Change sets are shared between subscribers and must
+// not be modified.
+type ChangeSet struct {
+	Ledger       history.Ledger
+	Transactions []history.Transaction
+	Operations   []history.Operation
+	Effects      []history.Effect
+
+	transactionParticipants map[int64]map[string]bool
+	operationParticipants   map[int64]map[string]bool
+	accounts                map[string]bool
+}
+
+// NewChangeSet builds the change set of a ledger from its history.
+func NewChangeSet(
+	ledger history.Ledger,
+	transactions []history.Transaction,
+	operations []history.Operation,
+	effects []history.Effect,
+	transactionParticipants []history.Participant,
+	operationParticipants []history.Participant,
+) *ChangeSet {
+	cs := &ChangeSet{
+		Ledger:                  ledger,
+		Transactions:            transactions,
+		Operations:              operations,
+		Effects:                 effects,
+		transactionParticipants: map[int64]map[string]bool{},
+		operationParticipants:   map[int64]map[string]bool{},
+		accounts:                map[string]bool{},
+	}
+	for _, p := range transactionParticipants {
+		addParticipant(cs.transactionParticipants, p)
+		cs.accounts[p.Address] = true
+	}
+	for _, p := range operationParticipants {
+		addParticipant(cs.operationParticipants, p)
+		cs.accounts[p.Address] = true
+	}
+	for _, effect := range effects {
+		cs.accounts[effect.Account] = true
+	}
+	return cs
+}
+
+func addParticipant(participants map[int64]map[string]bool, p history.Participant) {
+	accounts, ok := participants[p.ID]
+	if !ok {
+		accounts = map[string]bool{}
+		participants[p.ID] = accounts
+	}
+	accounts[p.Address] = true
+}
+
+// Load loads the change set of the ledger with the given sequence.
+func Load(ctx context.Context, q Q, seq int32) (*ChangeSet, error) {
+	var ledger history.Ledger
+	if err := q.LedgerBySequence(ctx, &ledger, seq); err != nil {
+		return nil, errors.Wrap(err, "could not load ledger")
+	}
+	transactions, err := q.TransactionsInLedger(ctx, seq)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load transactions")
+	}
+	operations, err := q.OperationsInLedger(ctx, seq)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load operations")
+	}
+	effects, err := q.EffectsInLedger(ctx, seq)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load effects")
+	}
+	transactionParticipants, err := q.TransactionParticipantsInLedger(ctx, seq)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load transaction participants")
+	}
+	operationParticipants, err := q.OperationParticipantsInLedger(ctx, seq)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load operation participants")
+	}
+	return NewChangeSet(
+		ledger,
+		transactions,
+		operations,
+		effects,
+		transactionParticipants,
+		operationParticipants,
+	), nil
+}
+
+// Sequence returns the sequence of the ledger of the change set.
+func (cs *ChangeSet) Sequence() uint32 {
+	return uint32(cs.Ledger.Sequence)
+}
+
+// TransactionInvolves returns true if the account participates in the
+// transaction with the given id.
+func (cs *ChangeSet) TransactionInvolves(id int64, account string) bool {
+	return cs.transactionParticipants[id][account]
+}
+
+// OperationInvolves returns true if the account participates in the operation
+// with the given id.
+func (cs *ChangeSet) OperationInvolves(id int64, account string) bool {
+	return cs.operationParticipants[id][account]
+}
+
+// AccountChanged returns true if the ledger may have changed the state of the
+// account, its data entries or its offers: the account participates in a
+// transaction or an operation of the ledger, or has one of its effects.
+func (cs *ChangeSet) AccountChanged(account string) bool {
+	return cs.accounts[account]
+}
@@ -0,0 +1,94 @@
+// Package feed fans out the history of each new ledger to the streaming
+// requests, so that streams do not query the DB for every ledger.
+package feed
+
+import (
+	"sync"
+)
+
+// Feed publishes the change sets of the ingested ledgers, in order, to its
+// subscriptions. It is safe for concurrent use.
+type Feed struct {
+	bufferSize int
+
+	lock          sync.Mutex
+	subscriptions map[*Subscription]bool
+}
+
+// Subscription receives the change sets published after it was created. A
+// subscription which falls behind by more than the buffer size of the feed, or
+// misses ledgers, is closed: its subscriber must catch up from the DB and
+// subscribe again.
+type Subscription struct {
+	feed    *Feed
+	changes chan *ChangeSet
+}
+
+// New constructs a Feed. Each subscription buffers up to bufferSize change
+// sets.
+func New(bufferSize int) *Feed {
+	return &Feed{
+		bufferSize:    bufferSize,
+		subscriptions: map[*Subscription]bool{},
+	}
+}
+
+// Subscribe returns a new subscription to the feed.
+func (f *Feed) Subscribe() *Subscription {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	s := &Subscription{
+		feed:    f,
+		changes: make(chan *ChangeSet, f.bufferSize),
+	}
+	f.subscriptions[s] = true
+	return s
+}
+
+// Publish sends the change set to all the subscriptions. It never blocks:
+// the subscriptions whose buffer is full are closed.
+func (f *Feed) Publish(cs *ChangeSet) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for s := range f.subscriptions {
+		select {
+		case s.changes <- cs:
+		default:
+			f.remove(s)
+		}
+	}
+}
+
+// Reset closes all the subscriptions. It is called when ledgers were not
+// published, so that subscribers catch up from the DB.
+func (f *Feed) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for s := range f.subscriptions {
+		f.remove(s)
+	}
+}
+
+// remove must be called with the lock held.
+func (f *Feed) remove(s *Subscription) {
+	if f.subscriptions[s] {
+		delete(f.subscriptions, s)
+		close(s.changes)
+	}
+}
+
+// Changes returns the channel of the change sets. The channel is closed when
+// the subscription is closed.
+func (s *Subscription) Changes() <-chan *ChangeSet {
+	return s.changes
+}
+
+// Close closes the subscription. It can be called more than once.
+func (s *Subscription) Close() {
+	s.feed.lock.Lock()
+	defer s.feed.lock.Unlock()
+	s.feed.remove(s)
+}
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+)
+
+func changeSet(seq int32) *ChangeSet {
+	return NewChangeSet(history.Ledger{Sequence: seq}, nil, nil, nil, nil, nil)
+}
+
+func TestFeedPublish(t *testing.T) {
+	f := New(2)
+	first := f.Subscribe()
+	f.Publish(changeSet(1))
+	second := f.Subscribe()
+	f.Publish(changeSet(2))
+
+	assert.Equal(t, uint32(1), (<-first.Changes()).Sequence())
+	assert.Equal(t, uint32(2), (<-first.Changes()).Sequence())
+	assert.Equal(t, uint32(2), (<-second.Changes()).Sequence())
+
+	second.Close()
+	second.Close()
+	_, ok := <-second.Changes()
+	assert.False(t, ok)
+
+	f.Publish(changeSet(3))
+	assert.Equal(t, uint32(3), (<-first.Changes()).Sequence())
+	first.Close()
+}
+
+func TestFeedDropsSlowSubscriptions(t *testing.T) {
+	f := New(1)
+	slow := f.Subscribe()
+	fast := f.Subscribe()
+
+	f.Publish(changeSet(1))
+	assert.Equal(t, uint32(1), (<-fast.Changes()).Sequence())
+	f.Publish(changeSet(2))
+
+	assert.Equal(t, uint32(1), (<-slow.Changes()).Sequence())
+	_, ok := <-slow.Changes()
+	assert.False(t, ok)
+	assert.Equal(t, uint32(2), (<-fast.Changes()).Sequence())
+
+	// Closing a dropped subscription is a no-op.
+	slow.Close()
+	fast.Close()
+}
+
+func TestFeedReset(t *testing.T) {
+	f := New(1)
+	s := f.Subscribe()
+	f.Reset()
+	_, ok := <-s.Changes()
+	assert.False(t, ok)
+
+	f.Publish(changeSet(1))
+	s.Close()
+}
+
+func TestChangeSetParticipants(t *testing.T) {
+	cs := NewChangeSet(
+		history.Ledger{Sequence: 7},
+		nil,
+		nil,
+		[]history.Effect{{Account: "GEFFECT"}},
+		[]history.Participant{{ID: 1, Address: "GTX"}},
+		[]history.Participant{{ID: 2, Address: "GOP"}, {ID: 2, Address: "GTX"}},
+	)
+
+	assert.True(t, cs.TransactionInvolves(1, "GTX"))
+	assert.False(t, cs.TransactionInvolves(1, "GOP"))
+	assert.False(t, cs.TransactionInvolves(2, "GTX"))
+	assert.True(t, cs.OperationInvolves(2, "GOP"))
+	assert.True(t, cs.OperationInvolves(2, "GTX"))
+	assert.False(t, cs.OperationInvolves(1, "GTX"))
+
+	for _, account := range []string{"GEFFECT", "GTX", "GOP"} {
+		assert.True(t, cs.AccountChanged(account))
+	}
+	assert.False(t, cs.AccountChanged("GOTHER"))
+}
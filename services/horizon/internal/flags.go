@@ -19,7 +19,7 @@ import (
 	support "github.com/stellar/go/support/config"
 	"github.com/stellar/go/support/db"
 	"github.com/stellar/go/support/log"
-	"github.com/stellar/throttled"
+	"github.com/throttled/throttled"
 )
 
 const (
@@ -319,7 +319,19 @@ func Flags() (*Config, support.ConfigOptions) {
 				}
 				return nil
 			},
-			Usage: "max count of requests allowed in a one hour period, by remote ip address",
+			Usage: "max count of requests allowed in a one hour period, by remote ip address, for requests without API key",
+		},
+		&support.ConfigOption{
+			Name:      "rate-limit-config-path",
+			ConfigKey: &config.RateLimitConfigPath,
+			OptType:   types.String,
+			Usage:     "path of the TOML file configuring the rate limit tiers of the API keys (sent in the X-Api-Key header), their concurrent streams (limited per Horizon instance) and the cost of the endpoints",
+		},
+		&support.ConfigOption{
+			Name:      "rate-limit-redis-url",
+			ConfigKey: &config.RateLimitRedisURL,
+			OptType:   types.String,
+			Usage:     "URL of the Redis server (redis://[:password@]host:port[/db]) sharing the rate limit quotas between Horizon instances, quotas are kept in memory if not set, concurrent streams are always counted per instance",
 		},
 		&support.ConfigOption{
 			Name:           "friendbot-url",
@@ -364,6 +376,20 @@ func Flags() (*Config, support.ConfigOptions) {
 			FlagDefault: uint(3),
 			Usage:       "the maximum number of assets on the path in `/paths` endpoint, warning: increasing this value will increase /paths response time",
 		},
+		&support.ConfigOption{
+			Name:        "enable-graphql",
+			ConfigKey:   &config.EnableGraphQL,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "serves the GraphQL API at the /graphql endpoint",
+		},
+		&support.ConfigOption{
+			Name:        "graphql-max-cost",
+			ConfigKey:   &config.GraphQLMaxCost,
+			OptType:     types.Uint,
+			FlagDefault: uint(1000),
+			Usage:       "the maximum number of records a query of the /graphql endpoint may load, queries which may load more are rejected before they are executed",
+		},
 		&support.ConfigOption{
 			Name:      "network-passphrase",
 			ConfigKey: &config.NetworkPassphrase,
@@ -0,0 +1,96 @@
+package gql
+
+import (
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// loadingFields are the fields, other than connections, whose resolvers load
+// a record.
+var loadingFields = map[string]bool{
+	"Query.account":          true,
+	"Query.ledger":           true,
+	"Query.transaction":      true,
+	"Query.operation":        true,
+	"Query.offer":            true,
+	"Query.liquidityPool":    true,
+	"Query.claimableBalance": true,
+	"Operation.transaction":  true,
+}
+
+// queryCost returns the maximum number of records loaded by the operation of
+// the query: one for every field of loadingFields and the first argument of
+// every connection, for every record of the connections it is nested in. The
+// cost is not computed past max. Queries which cannot be parsed cost more
+// than max, operations which are not found cost nothing as Exec rejects them.
+func queryCost(schema *ast.Schema, query, operationName string, variables map[string]interface{}, max uint) uint {
+	doc, errs := gqlparser.LoadQuery(schema, query)
+	if len(errs) > 0 {
+		return max + 1
+	}
+	var operation *ast.OperationDefinition
+	if operationName == "" && len(doc.Operations) == 1 {
+		operation = doc.Operations[0]
+	} else {
+		operation = doc.Operations.ForName(operationName)
+	}
+	if operation == nil {
+		return 0
+	}
+	return selectionCost(operation.SelectionSet, variables, 1, max)
+}
+
+func selectionCost(selections ast.SelectionSet, variables map[string]interface{}, records, max uint) uint {
+	var cost uint
+	for _, selection := range selections {
+		if cost > max {
+			break
+		}
+		switch selection := selection.(type) {
+		case *ast.Field:
+			children := records
+			if selection.Definition != nil && selection.ObjectDefinition != nil {
+				if first, ok := selection.ArgumentMap(variables)["first"]; ok {
+					children = saturatingMul(records, firstArgument(first), max)
+					cost += children
+				} else if loadingFields[selection.ObjectDefinition.Name+"."+selection.Name] {
+					cost += records
+				}
+			}
+			cost += selectionCost(selection.SelectionSet, variables, children, max)
+		case *ast.InlineFragment:
+			cost += selectionCost(selection.SelectionSet, variables, records, max)
+		case *ast.FragmentSpread:
+			if selection.Definition != nil {
+				cost += selectionCost(selection.Definition.SelectionSet, variables, records, max)
+			}
+		}
+	}
+	return cost
+}
+
+// firstArgument returns the value of a first argument, which is decoded from
+// the query or from the JSON variables.
+func firstArgument(value interface{}) uint {
+	var first int64
+	switch value := value.(type) {
+	case int64:
+		first = value
+	case float64:
+		first = int64(value)
+	case int:
+		first = int64(value)
+	}
+	if first < 0 {
+		return 0
+	}
+	return uint(first)
+}
+
+// saturatingMul returns a*b, or max+1 if it exceeds max.
+func saturatingMul(a, b, max uint) uint {
+	if a != 0 && b > (max+1)/a {
+		return max + 1
+	}
+	return a * b
+}
@@ -0,0 +1,197 @@
+// Package gql implements the GraphQL end-point of Horizon. The resources are
+// loaded with the db2/history queries and populated by the resourceadapter
+// package, like the resources of the REST end-points.
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	horizonContext "github.com/stellar/go/services/horizon/internal/context"
+	"github.com/stellar/go/services/horizon/internal/db2"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/log"
+)
+
+// maxDepth is the maximum depth of the selections of a query.
+const maxDepth = 10
+
+var errServer = errors.New("internal server error")
+
+// handler serves GraphQL queries sent as JSON POST requests.
+type handler struct {
+	schema *graphql.Schema
+	// costSchema is the schema used to compute the cost of the queries.
+	// graphql-go does not expose the documents it parses, so the queries
+	// are parsed with gqlparser to walk their selections.
+	costSchema *ast.Schema
+	maxCost    uint
+}
+
+// NewHandler returns the handler of the GraphQL end-point. The requests must
+// go through a middleware setting the DB session of the request context.
+//
+// The cost of a query is the maximum number of records its resolvers load:
+// one for every resource requested by its ID and the first argument of every
+// connection, multiplied by the first argument of the connections it is
+// nested in. Queries whose cost exceeds maxCost are rejected before they are
+// executed.
+func NewHandler(maxCost uint) http.Handler {
+	s := graphql.MustParseSchema(
+		schema,
+		&resolver{},
+		graphql.UseFieldResolvers(),
+		graphql.MaxDepth(maxDepth),
+		// The resolvers share the DB transaction of the request, which
+		// cannot run concurrent queries.
+		graphql.MaxParallelism(1),
+	)
+	return handler{
+		schema:     s,
+		costSchema: gqlparser.MustLoadSchema(&ast.Source{Name: "schema", Input: schema}),
+		maxCost:    maxCost,
+	}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Queries within the cost limit are validated by Exec. The others are
+	// only validated to report their validation errors, which take
+	// precedence over the cost limit, so that queries are parsed twice at
+	// most in both cases.
+	var response *graphql.Response
+	if queryCost(h.costSchema, params.Query, params.OperationName, params.Variables, h.maxCost) <= h.maxCost {
+		response = h.schema.Exec(r.Context(), params.Query, params.OperationName, params.Variables)
+	} else if errs := h.schema.Validate(params.Query); len(errs) > 0 {
+		response = &graphql.Response{Errors: errs}
+	} else {
+		response = &graphql.Response{Errors: []*gqlerrors.QueryError{{
+			Message: fmt.Sprintf("query cost exceeds the limit of %d records", h.maxCost),
+		}}}
+	}
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+// historyQ returns the history.Q of the request.
+func historyQ(ctx context.Context) (*history.Q, error) {
+	q, err := horizonContext.HistoryQFromContext(ctx)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return q, nil
+}
+
+// serverError logs err and returns an error hiding it from the client, like
+// the REST end-points do for the errors which are not problems.
+func serverError(ctx context.Context, err error) error {
+	log.Ctx(ctx).WithStack(err).WithError(err).Error("could not resolve graphql query")
+	return errServer
+}
+
+// pageArgs are the arguments of the connections.
+type pageArgs struct {
+	First int32
+	After *string
+	Order string
+}
+
+// pageQuery validates the arguments. The page query loads one more record
+// than requested, to know whether there is a next page.
+func (args pageArgs) pageQuery(ctx context.Context) (db2.PageQuery, *history.Q, error) {
+	if args.First < 1 || args.First > db2.MaxPageSize {
+		return db2.PageQuery{}, nil, errors.Errorf("first must be between 1 and %d", db2.MaxPageSize)
+	}
+	q, err := historyQ(ctx)
+	if err != nil {
+		return db2.PageQuery{}, nil, err
+	}
+
+	pq := db2.PageQuery{
+		Order: strings.ToLower(args.Order),
+		Limit: uint64(args.First) + 1,
+	}
+	if args.After != nil {
+		pq.Cursor = *args.After
+	}
+	return pq, q, nil
+}
+
+// pageLength returns the number of records of the page among the count
+// loaded records, and whether there is a next page.
+func pageLength(count int, pq db2.PageQuery) (int, bool) {
+	if uint64(count) < pq.Limit {
+		return count, false
+	}
+	return int(pq.Limit) - 1, true
+}
+
+type pageInfo struct {
+	StartCursor *string
+	EndCursor   *string
+	HasNextPage bool
+}
+
+func newPageInfo(first, last string, hasNextPage bool) pageInfo {
+	info := pageInfo{HasNextPage: hasNextPage}
+	if first != "" {
+		info.StartCursor = &first
+		info.EndCursor = &last
+	}
+	return info
+}
+
+// optional returns nil for empty strings.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalTime converts t to a graphql.Time.
+func optionalTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+// loadLedgers loads the ledgers of the sequences.
+func loadLedgers(ctx context.Context, q *history.Q, sequences []int32) (map[int32]history.Ledger, error) {
+	ledgerCache := history.LedgerCache{}
+	for _, seq := range sequences {
+		ledgerCache.Queue(seq)
+	}
+	if err := ledgerCache.Load(ctx, q); err != nil {
+		return nil, errors.Wrap(err, "failed to load ledger batch")
+	}
+	return ledgerCache.Records, nil
+}
+
+// resolver is the resolver of the Query type.
+type resolver struct{}
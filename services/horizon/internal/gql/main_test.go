@@ -0,0 +1,133 @@
+package gql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/services/horizon/internal/db2"
+)
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, h http.Handler, query string) response {
+	return executeWithVariables(t, h, query, nil)
+}
+
+func executeWithVariables(t *testing.T, h http.Handler, query string, variables map[string]interface{}) response {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func messages(resp response) []string {
+	var result []string
+	for _, e := range resp.Errors {
+		result = append(result, e.Message)
+	}
+	return result
+}
+
+func TestQueryCost(t *testing.T) {
+	h := NewHandler(20)
+	exceeded := []string{"query cost exceeds the limit of 20 records"}
+
+	// Queries within the limit are executed and reach the DB, which is not
+	// set in the context of the test.
+	for _, query := range []string{
+		`{ ledgers(first: 20) { edges { cursor } } }`,
+		`{ ledgers(first: 4) { edges { node { transactions(first: 4) { edges { cursor } } } } } }`,
+		`{ operations(first: 10) { edges { node { transaction { id } } } } }`,
+	} {
+		resp := execute(t, h, query)
+		assert.Equal(t, "internal server error", resp.Errors[0].Message, query)
+	}
+
+	// Expensive queries are rejected before they are executed.
+	for _, query := range []string{
+		`{ ledgers(first: 21) { edges { cursor } } }`,
+		`{ ledgers { edges { cursor } } a: ledgers { edges { cursor } } b: ledgers { edges { cursor } } }`,
+		`{ ledgers(first: 4) { edges { node { transactions(first: 5) { edges { cursor } } } } } }`,
+		`{ operations(first: 11) { edges { node { transaction { id } } } } }`,
+		`{ ledgers(first: 200) { edges { node { transactions(first: 200) { edges { node { operations(first: 200) { edges { cursor } } } } } } } } }`,
+		`{ ...ledgers } fragment ledgers on Query { a: ledgers(first: 15) { edges { cursor } } b: ledgers(first: 15) { edges { cursor } } }`,
+	} {
+		assert.Equal(t, exceeded, messages(execute(t, h, query)), query)
+	}
+
+	// Validation errors are reported instead of the cost of the query.
+	resp := execute(t, h, `{ ledgers(first: 21) { edges { unknown } } }`)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, `Cannot query field "unknown"`)
+
+	query := `query($first: Int) { ledgers(first: $first) { edges { cursor } } }`
+	resp = executeWithVariables(t, h, query, map[string]interface{}{"first": 21})
+	assert.Equal(t, exceeded, messages(resp))
+	resp = executeWithVariables(t, h, query, map[string]interface{}{"first": 20})
+	assert.Equal(t, []string{"internal server error"}, messages(resp))
+}
+
+func TestPageArguments(t *testing.T) {
+	h := NewHandler(1000)
+
+	for _, first := range []string{"0", "-1", "201"} {
+		resp := execute(t, h, `{ transactions(first: `+first+`) { edges { cursor } } }`)
+		assert.Equal(t, []string{"first must be between 1 and 200"}, messages(resp))
+	}
+
+	resp := execute(t, h, `{ offers(selling: "USD") { edges { cursor } } }`)
+	assert.Equal(t, []string{"invalid selling, it should be an asset in canonical form"}, messages(resp))
+
+	resp = execute(t, h, `{ claimableBalances(claimant: "GABC") { edges { cursor } } }`)
+	assert.Equal(t, []string{"invalid claimant, it should be an account ID"}, messages(resp))
+}
+
+func TestQueryDepth(t *testing.T) {
+	h := NewHandler(1000)
+
+	resp := execute(t, h, `{
+		ledgers { edges { node { transactions { edges { node {
+			operations { edges { node { effects { edges { node { id } } } } } }
+		} } } } } }
+	}`)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "exceeds max depth 10")
+}
+
+func TestPageLength(t *testing.T) {
+	pq := db2.PageQuery{Limit: 11}
+
+	n, hasNextPage := pageLength(3, pq)
+	assert.Equal(t, 3, n)
+	assert.False(t, hasNextPage)
+
+	n, hasNextPage = pageLength(11, pq)
+	assert.Equal(t, 10, n)
+	assert.True(t, hasNextPage)
+
+	info := newPageInfo("", "", false)
+	assert.Nil(t, info.StartCursor)
+	assert.Nil(t, info.EndCursor)
+
+	info = newPageInfo("1", "2", true)
+	assert.Equal(t, "1", *info.StartCursor)
+	assert.Equal(t, "2", *info.EndCursor)
+	assert.True(t, info.HasNextPage)
+}
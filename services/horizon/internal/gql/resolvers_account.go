@@ -0,0 +1,179 @@
+package gql
+
+import (
+	"context"
+	"sort"
+
+	"github.com/graph-gophers/graphql-go"
+
+	protocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/actions"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/support/errors"
+)
+
+type account struct {
+	ID                   string
+	PagingToken          string
+	Sequence             string
+	SubentryCount        int32
+	InflationDestination *string
+	HomeDomain           *string
+	LastModifiedLedger   int32
+	LastModifiedTime     *graphql.Time
+	LowThreshold         int32
+	MedThreshold         int32
+	HighThreshold        int32
+	AuthRequired         bool
+	AuthRevocable        bool
+	AuthImmutable        bool
+	AuthClawbackEnabled  bool
+	Balances             []balance
+	Signers              []signer
+	Data                 []dataEntry
+	NumSponsoring        int32
+	NumSponsored         int32
+	Sponsor              *string
+}
+
+type balance struct {
+	Balance                           string
+	AssetType                         string
+	AssetCode                         *string
+	AssetIssuer                       *string
+	LiquidityPoolId                   *string
+	Limit                             *string
+	BuyingLiabilities                 *string
+	SellingLiabilities                *string
+	Sponsor                           *string
+	LastModifiedLedger                *int32
+	IsAuthorized                      *bool
+	IsAuthorizedToMaintainLiabilities *bool
+	IsClawbackEnabled                 *bool
+}
+
+type signer struct {
+	Key     string
+	Type    string
+	Weight  int32
+	Sponsor *string
+}
+
+type dataEntry struct {
+	Name  string
+	Value string
+}
+
+func newAccount(resource protocol.Account) *account {
+	result := &account{
+		ID:                   resource.ID,
+		PagingToken:          resource.PT,
+		Sequence:             resource.Sequence,
+		SubentryCount:        resource.SubentryCount,
+		InflationDestination: optional(resource.InflationDestination),
+		HomeDomain:           optional(resource.HomeDomain),
+		LastModifiedLedger:   int32(resource.LastModifiedLedger),
+		LastModifiedTime:     optionalTime(resource.LastModifiedTime),
+		LowThreshold:         int32(resource.Thresholds.LowThreshold),
+		MedThreshold:         int32(resource.Thresholds.MedThreshold),
+		HighThreshold:        int32(resource.Thresholds.HighThreshold),
+		AuthRequired:         resource.Flags.AuthRequired,
+		AuthRevocable:        resource.Flags.AuthRevocable,
+		AuthImmutable:        resource.Flags.AuthImmutable,
+		AuthClawbackEnabled:  resource.Flags.AuthClawbackEnabled,
+		NumSponsoring:        int32(resource.NumSponsoring),
+		NumSponsored:         int32(resource.NumSponsored),
+		Sponsor:              optional(resource.Sponsor),
+	}
+
+	for _, b := range resource.Balances {
+		var lastModifiedLedger *int32
+		if b.LastModifiedLedger != 0 {
+			seq := int32(b.LastModifiedLedger)
+			lastModifiedLedger = &seq
+		}
+		result.Balances = append(result.Balances, balance{
+			Balance:                           b.Balance,
+			AssetType:                         b.Type,
+			AssetCode:                         optional(b.Code),
+			AssetIssuer:                       optional(b.Issuer),
+			LiquidityPoolId:                   optional(b.LiquidityPoolId),
+			Limit:                             optional(b.Limit),
+			BuyingLiabilities:                 optional(b.BuyingLiabilities),
+			SellingLiabilities:                optional(b.SellingLiabilities),
+			Sponsor:                           optional(b.Sponsor),
+			LastModifiedLedger:                lastModifiedLedger,
+			IsAuthorized:                      b.IsAuthorized,
+			IsAuthorizedToMaintainLiabilities: b.IsAuthorizedToMaintainLiabilities,
+			IsClawbackEnabled:                 b.IsClawbackEnabled,
+		})
+	}
+
+	for _, s := range resource.Signers {
+		result.Signers = append(result.Signers, signer{
+			Key:     s.Key,
+			Type:    s.Type,
+			Weight:  s.Weight,
+			Sponsor: optional(s.Sponsor),
+		})
+	}
+
+	for name, value := range resource.Data {
+		result.Data = append(result.Data, dataEntry{Name: name, Value: value})
+	}
+	sort.Slice(result.Data, func(i, j int) bool {
+		return result.Data[i].Name < result.Data[j].Name
+	})
+
+	return result
+}
+
+// Account resolves the account with the given ID.
+func (r *resolver) Account(ctx context.Context, args struct{ ID string }) (*account, error) {
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := actions.AccountInfo(ctx, q, args.ID)
+	if q.NoRows(errors.Cause(err)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return newAccount(*resource), nil
+}
+
+// Transactions resolves the transactions of the account.
+func (a *account) Transactions(ctx context.Context, args transactionsArgs) (*transactionConnection, error) {
+	return loadTransactions(ctx, args, transactionFilter{account: a.ID})
+}
+
+// Operations resolves the operations of the account.
+func (a *account) Operations(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{account: a.ID})
+}
+
+// Payments resolves the payment operations of the account.
+func (a *account) Payments(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{account: a.ID, onlyPayments: true})
+}
+
+// Effects resolves the effects of the account.
+func (a *account) Effects(ctx context.Context, args pageArgs) (*effectConnection, error) {
+	return loadEffects(ctx, args, effectFilter{account: a.ID})
+}
+
+// Offers resolves the offers of the account.
+func (a *account) Offers(ctx context.Context, args pageArgs) (*offerConnection, error) {
+	return loadOffers(ctx, args, history.OffersQuery{SellerID: a.ID})
+}
+
+// Trades resolves the trades of the account.
+func (a *account) Trades(ctx context.Context, args pageArgs) (*tradeConnection, error) {
+	return loadTrades(ctx, args, tradeFilter{account: a.ID})
+}
+
+// ClaimableBalances resolves the claimable balances the account can claim.
+func (a *account) ClaimableBalances(ctx context.Context, args pageArgs) (*claimableBalanceConnection, error) {
+	return loadClaimableBalances(ctx, claimableBalancesArgs{pageArgs: args, Claimant: &a.ID})
+}
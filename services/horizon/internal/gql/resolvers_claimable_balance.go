@@ -0,0 +1,180 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/graph-gophers/graphql-go"
+
+	protocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+type claimableBalance struct {
+	ID                 string
+	PagingToken        string
+	Asset              string
+	Amount             string
+	Sponsor            *string
+	LastModifiedLedger int32
+	LastModifiedTime   *graphql.Time
+	Claimants          []claimant
+	ClawbackEnabled    bool
+}
+
+type claimant struct {
+	Destination string
+	Predicate   string
+}
+
+type claimableBalanceEdge struct {
+	Cursor string
+	Node   *claimableBalance
+}
+
+type claimableBalanceConnection struct {
+	Edges    []claimableBalanceEdge
+	PageInfo pageInfo
+}
+
+type claimableBalancesArgs struct {
+	pageArgs
+	Asset    *string
+	Sponsor  *string
+	Claimant *string
+}
+
+// parseAccountID parses the account ID of the argument name.
+func parseAccountID(name, s string) (*xdr.AccountId, error) {
+	aid, err := xdr.AddressToAccountId(s)
+	if err != nil {
+		return nil, errors.Errorf("invalid %s, it should be an account ID", name)
+	}
+	return &aid, nil
+}
+
+func newClaimableBalance(ctx context.Context, row history.ClaimableBalance, ledger *history.Ledger) (*claimableBalance, error) {
+	var resource protocol.ClaimableBalance
+	if err := resourceadapter.PopulateClaimableBalance(ctx, &resource, row, ledger); err != nil {
+		return nil, err
+	}
+	result := &claimableBalance{
+		ID:                 resource.BalanceID,
+		PagingToken:        resource.PT,
+		Asset:              resource.Asset,
+		Amount:             resource.Amount,
+		Sponsor:            optional(resource.Sponsor),
+		LastModifiedLedger: int32(resource.LastModifiedLedger),
+		LastModifiedTime:   optionalTime(resource.LastModifiedTime),
+		ClawbackEnabled:    resource.Flags.ClawbackEnabled,
+	}
+	for _, c := range resource.Claimants {
+		predicate, err := json.Marshal(c.Predicate)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal claim predicate")
+		}
+		result.Claimants = append(result.Claimants, claimant{
+			Destination: c.Destination,
+			Predicate:   string(predicate),
+		})
+	}
+	return result, nil
+}
+
+// ClaimableBalance resolves the claimable balance with the given ID.
+func (r *resolver) ClaimableBalance(ctx context.Context, args struct{ ID string }) (*claimableBalance, error) {
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.FindClaimableBalanceByID(ctx, args.ID)
+	if q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	ledgers, err := loadLedgers(ctx, q, []int32{int32(row.LastModifiedLedger)})
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	node, err := newClaimableBalance(ctx, row, lastModifiedLedger(ledgers, row.LastModifiedLedger))
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return node, nil
+}
+
+// ClaimableBalances resolves a page of claimable balances.
+func (r *resolver) ClaimableBalances(ctx context.Context, args claimableBalancesArgs) (*claimableBalanceConnection, error) {
+	return loadClaimableBalances(ctx, args)
+}
+
+func loadClaimableBalances(ctx context.Context, args claimableBalancesArgs) (*claimableBalanceConnection, error) {
+	query := history.ClaimableBalancesQuery{}
+	var err error
+	if args.Asset != nil {
+		if query.Asset, err = parseAsset("asset", *args.Asset); err != nil {
+			return nil, err
+		}
+	}
+	if args.Sponsor != nil {
+		if query.Sponsor, err = parseAccountID("sponsor", *args.Sponsor); err != nil {
+			return nil, err
+		}
+	}
+	if args.Claimant != nil {
+		if query.Claimant, err = parseAccountID("claimant", *args.Claimant); err != nil {
+			return nil, err
+		}
+	}
+
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query.PageQuery = pq
+	if _, _, err = query.Cursor(); err != nil {
+		return nil, errors.New("invalid after, it should be the paging token of a claimable balance")
+	}
+	rows, err := q.GetClaimableBalances(ctx, query)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	rows = rows[:n]
+	sequences := make([]int32, len(rows))
+	for i, row := range rows {
+		sequences[i] = int32(row.LastModifiedLedger)
+	}
+	ledgers, err := loadLedgers(ctx, q, sequences)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	connection := &claimableBalanceConnection{}
+	for _, row := range rows {
+		node, err := newClaimableBalance(ctx, row, lastModifiedLedger(ledgers, row.LastModifiedLedger))
+		if err != nil {
+			return nil, serverError(ctx, err)
+		}
+		connection.Edges = append(connection.Edges, claimableBalanceEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
+
+// Transactions resolves the transactions of the claimable balance.
+func (cb *claimableBalance) Transactions(ctx context.Context, args transactionsArgs) (*transactionConnection, error) {
+	return loadTransactions(ctx, args, transactionFilter{claimableBalance: cb.ID})
+}
+
+// Operations resolves the operations of the claimable balance.
+func (cb *claimableBalance) Operations(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{claimableBalance: cb.ID})
+}
@@ -0,0 +1,124 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+
+	protocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+)
+
+type ledger struct {
+	ID                         string
+	PagingToken                string
+	Hash                       string
+	PrevHash                   *string
+	Sequence                   int32
+	SuccessfulTransactionCount int32
+	FailedTransactionCount     *int32
+	OperationCount             int32
+	TxSetOperationCount        *int32
+	ClosedAt                   graphql.Time
+	TotalCoins                 string
+	FeePool                    string
+	BaseFeeInStroops           int32
+	BaseReserveInStroops       int32
+	MaxTxSetSize               int32
+	ProtocolVersion            int32
+	HeaderXdr                  string
+}
+
+type ledgerEdge struct {
+	Cursor string
+	Node   *ledger
+}
+
+type ledgerConnection struct {
+	Edges    []ledgerEdge
+	PageInfo pageInfo
+}
+
+func newLedger(ctx context.Context, row history.Ledger) *ledger {
+	var resource protocol.Ledger
+	resourceadapter.PopulateLedger(ctx, &resource, row)
+	return &ledger{
+		ID:                         resource.ID,
+		PagingToken:                resource.PT,
+		Hash:                       resource.Hash,
+		PrevHash:                   optional(resource.PrevHash),
+		Sequence:                   resource.Sequence,
+		SuccessfulTransactionCount: resource.SuccessfulTransactionCount,
+		FailedTransactionCount:     resource.FailedTransactionCount,
+		OperationCount:             resource.OperationCount,
+		TxSetOperationCount:        resource.TxSetOperationCount,
+		ClosedAt:                   graphql.Time{Time: resource.ClosedAt},
+		TotalCoins:                 resource.TotalCoins,
+		FeePool:                    resource.FeePool,
+		BaseFeeInStroops:           resource.BaseFee,
+		BaseReserveInStroops:       resource.BaseReserve,
+		MaxTxSetSize:               resource.MaxTxSetSize,
+		ProtocolVersion:            resource.ProtocolVersion,
+		HeaderXdr:                  resource.HeaderXDR,
+	}
+}
+
+// Ledger resolves the ledger with the given sequence.
+func (r *resolver) Ledger(ctx context.Context, args struct{ Sequence int32 }) (*ledger, error) {
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var row history.Ledger
+	err = q.LedgerBySequence(ctx, &row, args.Sequence)
+	if q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return newLedger(ctx, row), nil
+}
+
+// Ledgers resolves a page of ledgers.
+func (r *resolver) Ledgers(ctx context.Context, args pageArgs) (*ledgerConnection, error) {
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rows []history.Ledger
+	if err = q.Ledgers().Page(pq).Select(ctx, &rows); err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	connection := &ledgerConnection{}
+	for _, row := range rows[:n] {
+		node := newLedger(ctx, row)
+		connection.Edges = append(connection.Edges, ledgerEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
+
+// Transactions resolves the transactions of the ledger.
+func (l *ledger) Transactions(ctx context.Context, args transactionsArgs) (*transactionConnection, error) {
+	return loadTransactions(ctx, args, transactionFilter{ledger: l.Sequence})
+}
+
+// Operations resolves the operations of the ledger.
+func (l *ledger) Operations(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{ledger: l.Sequence})
+}
+
+// Payments resolves the payment operations of the ledger.
+func (l *ledger) Payments(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{ledger: l.Sequence, onlyPayments: true})
+}
+
+// Effects resolves the effects of the ledger.
+func (l *ledger) Effects(ctx context.Context, args pageArgs) (*effectConnection, error) {
+	return loadEffects(ctx, args, effectFilter{ledger: l.Sequence})
+}
@@ -0,0 +1,160 @@
+package gql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	protocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+)
+
+type liquidityPool struct {
+	ID                 string
+	PagingToken        string
+	FeeBp              int32
+	Type               string
+	TotalTrustlines    string
+	TotalShares        string
+	Reserves           []liquidityPoolReserve
+	LastModifiedLedger int32
+	LastModifiedTime   *graphql.Time
+}
+
+type liquidityPoolReserve struct {
+	Asset  string
+	Amount string
+}
+
+type liquidityPoolEdge struct {
+	Cursor string
+	Node   *liquidityPool
+}
+
+type liquidityPoolConnection struct {
+	Edges    []liquidityPoolEdge
+	PageInfo pageInfo
+}
+
+type liquidityPoolsArgs struct {
+	pageArgs
+	Reserves *[]string
+}
+
+func newLiquidityPool(ctx context.Context, row history.LiquidityPool, ledger *history.Ledger) (*liquidityPool, error) {
+	var resource protocol.LiquidityPool
+	if err := resourceadapter.PopulateLiquidityPool(ctx, &resource, row, ledger); err != nil {
+		return nil, err
+	}
+	result := &liquidityPool{
+		ID:                 resource.ID,
+		PagingToken:        resource.PT,
+		FeeBp:              int32(resource.FeeBP),
+		Type:               resource.Type,
+		TotalTrustlines:    strconv.FormatUint(resource.TotalTrustlines, 10),
+		TotalShares:        resource.TotalShares,
+		LastModifiedLedger: int32(resource.LastModifiedLedger),
+		LastModifiedTime:   optionalTime(resource.LastModifiedTime),
+	}
+	for _, reserve := range resource.Reserves {
+		result.Reserves = append(result.Reserves, liquidityPoolReserve{
+			Asset:  reserve.Asset,
+			Amount: reserve.Amount,
+		})
+	}
+	return result, nil
+}
+
+// LiquidityPool resolves the liquidity pool with the given ID.
+func (r *resolver) LiquidityPool(ctx context.Context, args struct{ ID string }) (*liquidityPool, error) {
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.FindLiquidityPoolByID(ctx, args.ID)
+	if q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	ledgers, err := loadLedgers(ctx, q, []int32{int32(row.LastModifiedLedger)})
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	node, err := newLiquidityPool(ctx, row, lastModifiedLedger(ledgers, row.LastModifiedLedger))
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return node, nil
+}
+
+// LiquidityPools resolves a page of liquidity pools, holding all the reserves
+// if any.
+func (r *resolver) LiquidityPools(ctx context.Context, args liquidityPoolsArgs) (*liquidityPoolConnection, error) {
+	query := history.LiquidityPoolsQuery{}
+	if args.Reserves != nil {
+		for _, reserve := range *args.Reserves {
+			a, err := parseAsset("reserves", reserve)
+			if err != nil {
+				return nil, err
+			}
+			query.Assets = append(query.Assets, *a)
+		}
+	}
+
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query.PageQuery = pq
+	rows, err := q.GetLiquidityPools(ctx, query)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	rows = rows[:n]
+	sequences := make([]int32, len(rows))
+	for i, row := range rows {
+		sequences[i] = int32(row.LastModifiedLedger)
+	}
+	ledgers, err := loadLedgers(ctx, q, sequences)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	connection := &liquidityPoolConnection{}
+	for _, row := range rows {
+		node, err := newLiquidityPool(ctx, row, lastModifiedLedger(ledgers, row.LastModifiedLedger))
+		if err != nil {
+			return nil, serverError(ctx, err)
+		}
+		connection.Edges = append(connection.Edges, liquidityPoolEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
+
+// Transactions resolves the transactions of the liquidity pool.
+func (lp *liquidityPool) Transactions(ctx context.Context, args transactionsArgs) (*transactionConnection, error) {
+	return loadTransactions(ctx, args, transactionFilter{liquidityPool: lp.ID})
+}
+
+// Operations resolves the operations of the liquidity pool.
+func (lp *liquidityPool) Operations(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{liquidityPool: lp.ID})
+}
+
+// Effects resolves the effects of the liquidity pool.
+func (lp *liquidityPool) Effects(ctx context.Context, args pageArgs) (*effectConnection, error) {
+	return loadEffects(ctx, args, effectFilter{liquidityPool: lp.ID})
+}
+
+// Trades resolves the trades of the liquidity pool.
+func (lp *liquidityPool) Trades(ctx context.Context, args pageArgs) (*tradeConnection, error) {
+	return loadTrades(ctx, args, tradeFilter{liquidityPool: lp.ID})
+}
@@ -0,0 +1,298 @@
+package gql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	protocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+type asset struct {
+	AssetType   string
+	AssetCode   *string
+	AssetIssuer *string
+}
+
+type price struct {
+	N string
+	D string
+}
+
+type offer struct {
+	ID                 string
+	PagingToken        string
+	Seller             string
+	Selling            asset
+	Buying             asset
+	Amount             string
+	Price              string
+	PriceR             price
+	LastModifiedLedger int32
+	LastModifiedTime   *graphql.Time
+	Sponsor            *string
+}
+
+type offerEdge struct {
+	Cursor string
+	Node   *offer
+}
+
+type offerConnection struct {
+	Edges    []offerEdge
+	PageInfo pageInfo
+}
+
+type offersArgs struct {
+	pageArgs
+	Seller  *string
+	Sponsor *string
+	Selling *string
+	Buying  *string
+}
+
+type trade struct {
+	ID                     string
+	PagingToken            string
+	LedgerCloseTime        graphql.Time
+	TradeType              string
+	LiquidityPoolFeeBp     *int32
+	BaseLiquidityPoolId    *string
+	BaseOfferId            *string
+	BaseAccount            *string
+	BaseAmount             string
+	BaseAsset              asset
+	CounterLiquidityPoolId *string
+	CounterOfferId         *string
+	CounterAccount         *string
+	CounterAmount          string
+	CounterAsset           asset
+	BaseIsSeller           bool
+	Price                  price
+}
+
+type tradeEdge struct {
+	Cursor string
+	Node   *trade
+}
+
+type tradeConnection struct {
+	Edges    []tradeEdge
+	PageInfo pageInfo
+}
+
+// tradeFilter selects the trades of a connection. At most one of its fields
+// is set.
+type tradeFilter struct {
+	account       string
+	offer         int64
+	liquidityPool string
+}
+
+func newAsset(assetType, code, issuer string) asset {
+	return asset{
+		AssetType:   assetType,
+		AssetCode:   optional(code),
+		AssetIssuer: optional(issuer),
+	}
+}
+
+// parseAsset parses an asset in the canonical form, "native" or
+// "code:issuer".
+func parseAsset(name, s string) (*xdr.Asset, error) {
+	assets, err := xdr.BuildAssets(s)
+	if err != nil || len(assets) != 1 {
+		return nil, errors.Errorf("invalid %s, it should be an asset in canonical form", name)
+	}
+	return &assets[0], nil
+}
+
+func newOffer(ctx context.Context, row history.Offer, ledger *history.Ledger) *offer {
+	var resource protocol.Offer
+	resourceadapter.PopulateOffer(ctx, &resource, row, ledger)
+	return &offer{
+		ID:          strconv.FormatInt(resource.ID, 10),
+		PagingToken: resource.PT,
+		Seller:      resource.Seller,
+		Selling:     newAsset(resource.Selling.Type, resource.Selling.Code, resource.Selling.Issuer),
+		Buying:      newAsset(resource.Buying.Type, resource.Buying.Code, resource.Buying.Issuer),
+		Amount:      resource.Amount,
+		Price:       resource.Price,
+		PriceR: price{
+			N: strconv.FormatInt(int64(resource.PriceR.N), 10),
+			D: strconv.FormatInt(int64(resource.PriceR.D), 10),
+		},
+		LastModifiedLedger: resource.LastModifiedLedger,
+		LastModifiedTime:   optionalTime(resource.LastModifiedTime),
+		Sponsor:            optional(resource.Sponsor),
+	}
+}
+
+func newTrade(ctx context.Context, row history.Trade) *trade {
+	var resource protocol.Trade
+	resourceadapter.PopulateTrade(ctx, &resource, row)
+	result := &trade{
+		ID:                     resource.ID,
+		PagingToken:            resource.PT,
+		LedgerCloseTime:        graphql.Time{Time: resource.LedgerCloseTime},
+		TradeType:              resource.TradeType,
+		BaseLiquidityPoolId:    optional(resource.BaseLiquidityPoolID),
+		BaseOfferId:            optional(resource.BaseOfferID),
+		BaseAccount:            optional(resource.BaseAccount),
+		BaseAmount:             resource.BaseAmount,
+		BaseAsset:              newAsset(resource.BaseAssetType, resource.BaseAssetCode, resource.BaseAssetIssuer),
+		CounterLiquidityPoolId: optional(resource.CounterLiquidityPoolID),
+		CounterOfferId:         optional(resource.CounterOfferID),
+		CounterAccount:         optional(resource.CounterAccount),
+		CounterAmount:          resource.CounterAmount,
+		CounterAsset:           newAsset(resource.CounterAssetType, resource.CounterAssetCode, resource.CounterAssetIssuer),
+		BaseIsSeller:           resource.BaseIsSeller,
+		Price: price{
+			N: strconv.FormatInt(resource.Price.N, 10),
+			D: strconv.FormatInt(resource.Price.D, 10),
+		},
+	}
+	if resource.LiquidityPoolFeeBP != 0 {
+		fee := int32(resource.LiquidityPoolFeeBP)
+		result.LiquidityPoolFeeBp = &fee
+	}
+	return result
+}
+
+// Offer resolves the offer with the given ID.
+func (r *resolver) Offer(ctx context.Context, args struct{ ID string }) (*offer, error) {
+	id, err := strconv.ParseInt(args.ID, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid offer id")
+	}
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetOfferByID(ctx, id)
+	if q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	ledgers, err := loadLedgers(ctx, q, []int32{int32(row.LastModifiedLedger)})
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return newOffer(ctx, row, lastModifiedLedger(ledgers, row.LastModifiedLedger)), nil
+}
+
+// Offers resolves a page of offers.
+func (r *resolver) Offers(ctx context.Context, args offersArgs) (*offerConnection, error) {
+	query := history.OffersQuery{}
+	if args.Seller != nil {
+		query.SellerID = *args.Seller
+	}
+	if args.Sponsor != nil {
+		query.Sponsor = *args.Sponsor
+	}
+	var err error
+	if args.Selling != nil {
+		if query.Selling, err = parseAsset("selling", *args.Selling); err != nil {
+			return nil, err
+		}
+	}
+	if args.Buying != nil {
+		if query.Buying, err = parseAsset("buying", *args.Buying); err != nil {
+			return nil, err
+		}
+	}
+	return loadOffers(ctx, args.pageArgs, query)
+}
+
+func loadOffers(ctx context.Context, args pageArgs, query history.OffersQuery) (*offerConnection, error) {
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query.PageQuery = pq
+	rows, err := q.GetOffers(ctx, query)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	rows = rows[:n]
+	sequences := make([]int32, len(rows))
+	for i, row := range rows {
+		sequences[i] = int32(row.LastModifiedLedger)
+	}
+	ledgers, err := loadLedgers(ctx, q, sequences)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	connection := &offerConnection{}
+	for _, row := range rows {
+		node := newOffer(ctx, row, lastModifiedLedger(ledgers, row.LastModifiedLedger))
+		connection.Edges = append(connection.Edges, offerEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
+
+// lastModifiedLedger returns the ledger of the sequence, or nil if it is not
+// in the history anymore.
+func lastModifiedLedger(ledgers map[int32]history.Ledger, seq uint32) *history.Ledger {
+	if l, ok := ledgers[int32(seq)]; ok {
+		return &l
+	}
+	return nil
+}
+
+// Trades resolves the trades of the offer.
+func (o *offer) Trades(ctx context.Context, args pageArgs) (*tradeConnection, error) {
+	id, err := strconv.ParseInt(o.ID, 10, 64)
+	if err != nil {
+		return nil, serverError(ctx, errors.Wrap(err, "invalid offer id"))
+	}
+	return loadTrades(ctx, args, tradeFilter{offer: id})
+}
+
+// Trades resolves a page of trades.
+func (r *resolver) Trades(ctx context.Context, args pageArgs) (*tradeConnection, error) {
+	return loadTrades(ctx, args, tradeFilter{})
+}
+
+func loadTrades(ctx context.Context, args pageArgs, filter tradeFilter) (*tradeConnection, error) {
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []history.Trade
+	switch {
+	case filter.offer > 0:
+		rows, err = q.GetTradesForOffer(ctx, pq, filter.offer)
+	case filter.liquidityPool != "":
+		rows, err = q.GetTradesForLiquidityPool(ctx, pq, filter.liquidityPool)
+	default:
+		rows, err = q.GetTrades(ctx, pq, filter.account, history.AllTrades)
+	}
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	connection := &tradeConnection{}
+	for _, row := range rows[:n] {
+		node := newTrade(ctx, row)
+		connection.Edges = append(connection.Edges, tradeEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
@@ -0,0 +1,311 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/stellar/go/protocols/horizon/effects"
+	"github.com/stellar/go/protocols/horizon/operations"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/render/hal"
+)
+
+type operation struct {
+	ID                    string
+	PagingToken           string
+	Type                  string
+	TypeI                 int32
+	SourceAccount         string
+	TransactionHash       string
+	TransactionSuccessful bool
+	CreatedAt             graphql.Time
+	Sponsor               *string
+	JSON                  string
+}
+
+type operationEdge struct {
+	Cursor string
+	Node   *operation
+}
+
+type operationConnection struct {
+	Edges    []operationEdge
+	PageInfo pageInfo
+}
+
+// operationFilter selects the operations of a connection. At most one of its
+// fields, besides onlyPayments, is set.
+type operationFilter struct {
+	account          string
+	ledger           int32
+	transaction      string
+	claimableBalance string
+	liquidityPool    string
+	onlyPayments     bool
+}
+
+type effect struct {
+	ID          string
+	PagingToken string
+	Account     string
+	Type        string
+	TypeI       int32
+	CreatedAt   graphql.Time
+	JSON        string
+}
+
+type effectEdge struct {
+	Cursor string
+	Node   *effect
+}
+
+type effectConnection struct {
+	Edges    []effectEdge
+	PageInfo pageInfo
+}
+
+// effectFilter selects the effects of a connection. At most one of its
+// fields is set.
+type effectFilter struct {
+	account       string
+	ledger        int32
+	transaction   string
+	operation     int64
+	liquidityPool string
+}
+
+func newOperation(ctx context.Context, row history.Operation, ledger history.Ledger) (*operation, error) {
+	resource, err := resourceadapter.NewOperation(ctx, row, row.TransactionHash, nil, ledger)
+	if err != nil {
+		return nil, err
+	}
+	data, base, err := decodeOperationBase(resource)
+	if err != nil {
+		return nil, err
+	}
+	return &operation{
+		ID:                    base.ID,
+		PagingToken:           base.PT,
+		Type:                  base.Type,
+		TypeI:                 base.TypeI,
+		SourceAccount:         base.SourceAccount,
+		TransactionHash:       base.TransactionHash,
+		TransactionSuccessful: base.TransactionSuccessful,
+		CreatedAt:             graphql.Time{Time: base.LedgerCloseTime},
+		Sponsor:               optional(base.Sponsor),
+		JSON:                  string(data),
+	}, nil
+}
+
+func newEffect(ctx context.Context, row history.Effect, ledger history.Ledger) (*effect, error) {
+	resource, err := resourceadapter.NewEffect(ctx, row, ledger)
+	if err != nil {
+		return nil, err
+	}
+	data, base, err := decodeEffectBase(resource)
+	if err != nil {
+		return nil, err
+	}
+	return &effect{
+		ID:          base.ID,
+		PagingToken: base.PT,
+		Account:     base.Account,
+		Type:        base.Type,
+		TypeI:       base.TypeI,
+		CreatedAt:   graphql.Time{Time: base.LedgerCloseTime},
+		JSON:        string(data),
+	}, nil
+}
+
+// decodeOperationBase returns the JSON representation of the operation
+// resource and its base fields.
+func decodeOperationBase(resource hal.Pageable) ([]byte, *operations.Base, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not marshal operation")
+	}
+	var base operations.Base
+	if err = json.Unmarshal(data, &base); err != nil {
+		return nil, nil, errors.Wrap(err, "could not unmarshal operation")
+	}
+	return data, &base, nil
+}
+
+// decodeEffectBase returns the JSON representation of the effect resource
+// and its base fields.
+func decodeEffectBase(resource hal.Pageable) ([]byte, *effects.Base, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not marshal effect")
+	}
+	var base effects.Base
+	if err = json.Unmarshal(data, &base); err != nil {
+		return nil, nil, errors.Wrap(err, "could not unmarshal effect")
+	}
+	return data, &base, nil
+}
+
+// Operation resolves the operation with the given ID.
+func (r *resolver) Operation(ctx context.Context, args struct{ ID string }) (*operation, error) {
+	id, err := strconv.ParseInt(args.ID, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid operation id")
+	}
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row, _, err := q.OperationByID(ctx, false, id)
+	if q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	ledgers, err := loadLedgers(ctx, q, []int32{row.LedgerSequence()})
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	node, err := newOperation(ctx, row, ledgers[row.LedgerSequence()])
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return node, nil
+}
+
+// Operations resolves a page of operations.
+func (r *resolver) Operations(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{})
+}
+
+// Payments resolves a page of payment operations.
+func (r *resolver) Payments(ctx context.Context, args transactionsArgs) (*operationConnection, error) {
+	return loadOperations(ctx, args, operationFilter{onlyPayments: true})
+}
+
+func loadOperations(ctx context.Context, args transactionsArgs, filter operationFilter) (*operationConnection, error) {
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := q.Operations()
+	switch {
+	case filter.account != "":
+		query.ForAccount(ctx, filter.account)
+	case filter.ledger > 0:
+		query.ForLedger(ctx, filter.ledger)
+	case filter.transaction != "":
+		query.ForTransaction(ctx, filter.transaction)
+	case filter.claimableBalance != "":
+		query.ForClaimableBalance(ctx, filter.claimableBalance)
+	case filter.liquidityPool != "":
+		query.ForLiquidityPool(ctx, filter.liquidityPool)
+	}
+	if args.IncludeFailed {
+		query.IncludeFailed()
+	}
+	if filter.onlyPayments {
+		query.OnlyPayments()
+	}
+	rows, _, err := query.Page(pq).Fetch(ctx)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	rows = rows[:n]
+	sequences := make([]int32, len(rows))
+	for i, row := range rows {
+		sequences[i] = row.LedgerSequence()
+	}
+	ledgers, err := loadLedgers(ctx, q, sequences)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	connection := &operationConnection{}
+	for _, row := range rows {
+		node, err := newOperation(ctx, row, ledgers[row.LedgerSequence()])
+		if err != nil {
+			return nil, serverError(ctx, err)
+		}
+		connection.Edges = append(connection.Edges, operationEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
+
+// Transaction resolves the transaction of the operation.
+func (o *operation) Transaction(ctx context.Context) (*transaction, error) {
+	return loadTransaction(ctx, o.TransactionHash)
+}
+
+// Effects resolves the effects of the operation.
+func (o *operation) Effects(ctx context.Context, args pageArgs) (*effectConnection, error) {
+	id, err := strconv.ParseInt(o.ID, 10, 64)
+	if err != nil {
+		return nil, serverError(ctx, errors.Wrap(err, "invalid operation id"))
+	}
+	return loadEffects(ctx, args, effectFilter{operation: id})
+}
+
+// Effects resolves a page of effects.
+func (r *resolver) Effects(ctx context.Context, args pageArgs) (*effectConnection, error) {
+	return loadEffects(ctx, args, effectFilter{})
+}
+
+func loadEffects(ctx context.Context, args pageArgs, filter effectFilter) (*effectConnection, error) {
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := q.Effects()
+	switch {
+	case filter.account != "":
+		query.ForAccount(ctx, filter.account)
+	case filter.ledger > 0:
+		query.ForLedger(ctx, filter.ledger)
+	case filter.transaction != "":
+		query.ForTransaction(ctx, filter.transaction)
+	case filter.operation > 0:
+		query.ForOperation(filter.operation)
+	case filter.liquidityPool != "":
+		query.ForLiquidityPool(filter.liquidityPool)
+	}
+	var rows []history.Effect
+	if err = query.Page(pq).Select(ctx, &rows); err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	rows = rows[:n]
+	sequences := make([]int32, len(rows))
+	for i, row := range rows {
+		sequences[i] = row.LedgerSequence()
+	}
+	ledgers, err := loadLedgers(ctx, q, sequences)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	connection := &effectConnection{}
+	for _, row := range rows {
+		node, err := newEffect(ctx, row, ledgers[row.LedgerSequence()])
+		if err != nil {
+			return nil, serverError(ctx, err)
+		}
+		connection.Edges = append(connection.Edges, effectEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
@@ -0,0 +1,178 @@
+package gql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	protocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/resourceadapter"
+)
+
+type transaction struct {
+	ID                    string
+	PagingToken           string
+	Successful            bool
+	Hash                  string
+	Ledger                int32
+	CreatedAt             graphql.Time
+	SourceAccount         string
+	SourceAccountSequence string
+	FeeAccount            string
+	FeeCharged            string
+	MaxFee                string
+	OperationCount        int32
+	EnvelopeXdr           string
+	ResultXdr             string
+	ResultMetaXdr         string
+	FeeMetaXdr            string
+	MemoType              string
+	Memo                  *string
+	Signatures            []string
+	ValidAfter            *string
+	ValidBefore           *string
+}
+
+type transactionEdge struct {
+	Cursor string
+	Node   *transaction
+}
+
+type transactionConnection struct {
+	Edges    []transactionEdge
+	PageInfo pageInfo
+}
+
+// transactionsArgs are the arguments of the connections of transactions and
+// of the operations of several transactions.
+type transactionsArgs struct {
+	pageArgs
+	IncludeFailed bool
+}
+
+// transactionFilter selects the transactions of a connection. At most one of
+// its fields is set.
+type transactionFilter struct {
+	account          string
+	ledger           int32
+	claimableBalance string
+	liquidityPool    string
+}
+
+func newTransaction(ctx context.Context, row history.Transaction) (*transaction, error) {
+	var resource protocol.Transaction
+	if err := resourceadapter.PopulateTransaction(ctx, row.TransactionHash, &resource, row); err != nil {
+		return nil, err
+	}
+	return &transaction{
+		ID:                    resource.ID,
+		PagingToken:           resource.PT,
+		Successful:            resource.Successful,
+		Hash:                  resource.Hash,
+		Ledger:                resource.Ledger,
+		CreatedAt:             graphql.Time{Time: resource.LedgerCloseTime},
+		SourceAccount:         resource.Account,
+		SourceAccountSequence: resource.AccountSequence,
+		FeeAccount:            resource.FeeAccount,
+		FeeCharged:            strconv.FormatInt(resource.FeeCharged, 10),
+		MaxFee:                strconv.FormatInt(resource.MaxFee, 10),
+		OperationCount:        resource.OperationCount,
+		EnvelopeXdr:           resource.EnvelopeXdr,
+		ResultXdr:             resource.ResultXdr,
+		ResultMetaXdr:         resource.ResultMetaXdr,
+		FeeMetaXdr:            resource.FeeMetaXdr,
+		MemoType:              resource.MemoType,
+		Memo:                  optional(resource.Memo),
+		Signatures:            resource.Signatures,
+		ValidAfter:            optional(resource.ValidAfter),
+		ValidBefore:           optional(resource.ValidBefore),
+	}, nil
+}
+
+// Transaction resolves the transaction with the given hash.
+func (r *resolver) Transaction(ctx context.Context, args struct{ Hash string }) (*transaction, error) {
+	return loadTransaction(ctx, args.Hash)
+}
+
+func loadTransaction(ctx context.Context, hash string) (*transaction, error) {
+	q, err := historyQ(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var row history.Transaction
+	err = q.TransactionByHash(ctx, &row, hash)
+	if q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	node, err := newTransaction(ctx, row)
+	if err != nil {
+		return nil, serverError(ctx, err)
+	}
+	return node, nil
+}
+
+// Transactions resolves a page of transactions.
+func (r *resolver) Transactions(ctx context.Context, args transactionsArgs) (*transactionConnection, error) {
+	return loadTransactions(ctx, args, transactionFilter{})
+}
+
+func loadTransactions(ctx context.Context, args transactionsArgs, filter transactionFilter) (*transactionConnection, error) {
+	pq, q, err := args.pageQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := q.Transactions()
+	switch {
+	case filter.account != "":
+		query.ForAccount(ctx, filter.account)
+	case filter.ledger > 0:
+		query.ForLedger(ctx, filter.ledger)
+	case filter.claimableBalance != "":
+		query.ForClaimableBalance(ctx, filter.claimableBalance)
+	case filter.liquidityPool != "":
+		query.ForLiquidityPool(ctx, filter.liquidityPool)
+	}
+	if args.IncludeFailed {
+		query.IncludeFailed()
+	}
+	var rows []history.Transaction
+	if err = query.Page(pq).Select(ctx, &rows); err != nil {
+		return nil, serverError(ctx, err)
+	}
+
+	n, hasNextPage := pageLength(len(rows), pq)
+	connection := &transactionConnection{}
+	for _, row := range rows[:n] {
+		node, err := newTransaction(ctx, row)
+		if err != nil {
+			return nil, serverError(ctx, err)
+		}
+		connection.Edges = append(connection.Edges, transactionEdge{Cursor: node.PagingToken, Node: node})
+	}
+	if n > 0 {
+		connection.PageInfo = newPageInfo(connection.Edges[0].Cursor, connection.Edges[n-1].Cursor, hasNextPage)
+	}
+	return connection, nil
+}
+
+// Operations resolves the operations of the transaction, whether it failed or
+// not.
+func (t *transaction) Operations(ctx context.Context, args pageArgs) (*operationConnection, error) {
+	return loadOperations(ctx, transactionsArgs{pageArgs: args, IncludeFailed: true}, operationFilter{transaction: t.Hash})
+}
+
+// Payments resolves the payment operations of the transaction, whether it
+// failed or not.
+func (t *transaction) Payments(ctx context.Context, args pageArgs) (*operationConnection, error) {
+	return loadOperations(ctx, transactionsArgs{pageArgs: args, IncludeFailed: true}, operationFilter{transaction: t.Hash, onlyPayments: true})
+}
+
+// Effects resolves the effects of the transaction.
+func (t *transaction) Effects(ctx context.Context, args pageArgs) (*effectConnection, error) {
+	return loadEffects(ctx, args, effectFilter{transaction: t.Hash})
+}
@@ -0,0 +1,349 @@
+package gql
+
+// schema is the GraphQL schema of Horizon. The resources have the fields of
+// their REST representation, in camel case. Paginated fields are connections
+// taking the number of records (first, 10 by default and 200 at most), the
+// paging token to start after (after) and the order of the records.
+const schema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+enum Order {
+	ASC
+	DESC
+}
+
+type PageInfo {
+	startCursor: String
+	endCursor: String
+	hasNextPage: Boolean!
+}
+
+type Query {
+	account(id: String!): Account
+	ledger(sequence: Int!): Ledger
+	ledgers(first: Int = 10, after: String, order: Order = ASC): LedgerConnection!
+	transaction(hash: String!): Transaction
+	transactions(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): TransactionConnection!
+	operation(id: String!): Operation
+	operations(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	payments(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	effects(first: Int = 10, after: String, order: Order = ASC): EffectConnection!
+	offer(id: String!): Offer
+	offers(first: Int = 10, after: String, order: Order = ASC, seller: String, sponsor: String, selling: String, buying: String): OfferConnection!
+	trades(first: Int = 10, after: String, order: Order = ASC): TradeConnection!
+	liquidityPool(id: String!): LiquidityPool
+	liquidityPools(first: Int = 10, after: String, order: Order = ASC, reserves: [String!]): LiquidityPoolConnection!
+	claimableBalance(id: String!): ClaimableBalance
+	claimableBalances(first: Int = 10, after: String, order: Order = ASC, asset: String, sponsor: String, claimant: String): ClaimableBalanceConnection!
+}
+
+type Asset {
+	assetType: String!
+	assetCode: String
+	assetIssuer: String
+}
+
+type Price {
+	n: String!
+	d: String!
+}
+
+type Balance {
+	balance: String!
+	assetType: String!
+	assetCode: String
+	assetIssuer: String
+	liquidityPoolId: String
+	limit: String
+	buyingLiabilities: String
+	sellingLiabilities: String
+	sponsor: String
+	lastModifiedLedger: Int
+	isAuthorized: Boolean
+	isAuthorizedToMaintainLiabilities: Boolean
+	isClawbackEnabled: Boolean
+}
+
+type Signer {
+	key: String!
+	type: String!
+	weight: Int!
+	sponsor: String
+}
+
+type DataEntry {
+	name: String!
+	value: String!
+}
+
+type Account {
+	id: String!
+	pagingToken: String!
+	sequence: String!
+	subentryCount: Int!
+	inflationDestination: String
+	homeDomain: String
+	lastModifiedLedger: Int!
+	lastModifiedTime: Time
+	lowThreshold: Int!
+	medThreshold: Int!
+	highThreshold: Int!
+	authRequired: Boolean!
+	authRevocable: Boolean!
+	authImmutable: Boolean!
+	authClawbackEnabled: Boolean!
+	balances: [Balance!]!
+	signers: [Signer!]!
+	data: [DataEntry!]!
+	numSponsoring: Int!
+	numSponsored: Int!
+	sponsor: String
+	transactions(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): TransactionConnection!
+	operations(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	payments(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	effects(first: Int = 10, after: String, order: Order = ASC): EffectConnection!
+	offers(first: Int = 10, after: String, order: Order = ASC): OfferConnection!
+	trades(first: Int = 10, after: String, order: Order = ASC): TradeConnection!
+	claimableBalances(first: Int = 10, after: String, order: Order = ASC): ClaimableBalanceConnection!
+}
+
+type Ledger {
+	id: String!
+	pagingToken: String!
+	hash: String!
+	prevHash: String
+	sequence: Int!
+	successfulTransactionCount: Int!
+	failedTransactionCount: Int
+	operationCount: Int!
+	txSetOperationCount: Int
+	closedAt: Time!
+	totalCoins: String!
+	feePool: String!
+	baseFeeInStroops: Int!
+	baseReserveInStroops: Int!
+	maxTxSetSize: Int!
+	protocolVersion: Int!
+	headerXdr: String!
+	transactions(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): TransactionConnection!
+	operations(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	payments(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	effects(first: Int = 10, after: String, order: Order = ASC): EffectConnection!
+}
+
+type Transaction {
+	id: String!
+	pagingToken: String!
+	successful: Boolean!
+	hash: String!
+	ledger: Int!
+	createdAt: Time!
+	sourceAccount: String!
+	sourceAccountSequence: String!
+	feeAccount: String!
+	feeCharged: String!
+	maxFee: String!
+	operationCount: Int!
+	envelopeXdr: String!
+	resultXdr: String!
+	resultMetaXdr: String!
+	feeMetaXdr: String!
+	memoType: String!
+	memo: String
+	signatures: [String!]!
+	validAfter: String
+	validBefore: String
+	operations(first: Int = 10, after: String, order: Order = ASC): OperationConnection!
+	payments(first: Int = 10, after: String, order: Order = ASC): OperationConnection!
+	effects(first: Int = 10, after: String, order: Order = ASC): EffectConnection!
+}
+
+type Operation {
+	id: String!
+	pagingToken: String!
+	type: String!
+	typeI: Int!
+	sourceAccount: String!
+	transactionHash: String!
+	transactionSuccessful: Boolean!
+	createdAt: Time!
+	sponsor: String
+	# json is the REST representation of the operation, with the fields
+	# specific to its type.
+	json: String!
+	transaction: Transaction
+	effects(first: Int = 10, after: String, order: Order = ASC): EffectConnection!
+}
+
+type Effect {
+	id: String!
+	pagingToken: String!
+	account: String!
+	type: String!
+	typeI: Int!
+	createdAt: Time!
+	# json is the REST representation of the effect, with the fields specific
+	# to its type.
+	json: String!
+}
+
+type Offer {
+	id: String!
+	pagingToken: String!
+	seller: String!
+	selling: Asset!
+	buying: Asset!
+	amount: String!
+	price: String!
+	priceR: Price!
+	lastModifiedLedger: Int!
+	lastModifiedTime: Time
+	sponsor: String
+	trades(first: Int = 10, after: String, order: Order = ASC): TradeConnection!
+}
+
+type Trade {
+	id: String!
+	pagingToken: String!
+	ledgerCloseTime: Time!
+	tradeType: String!
+	liquidityPoolFeeBp: Int
+	baseLiquidityPoolId: String
+	baseOfferId: String
+	baseAccount: String
+	baseAmount: String!
+	baseAsset: Asset!
+	counterLiquidityPoolId: String
+	counterOfferId: String
+	counterAccount: String
+	counterAmount: String!
+	counterAsset: Asset!
+	baseIsSeller: Boolean!
+	price: Price!
+}
+
+type LiquidityPoolReserve {
+	asset: String!
+	amount: String!
+}
+
+type LiquidityPool {
+	id: String!
+	pagingToken: String!
+	feeBp: Int!
+	type: String!
+	totalTrustlines: String!
+	totalShares: String!
+	reserves: [LiquidityPoolReserve!]!
+	lastModifiedLedger: Int!
+	lastModifiedTime: Time
+	transactions(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): TransactionConnection!
+	operations(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+	effects(first: Int = 10, after: String, order: Order = ASC): EffectConnection!
+	trades(first: Int = 10, after: String, order: Order = ASC): TradeConnection!
+}
+
+type Claimant {
+	destination: String!
+	# predicate is the JSON representation of the claim predicate.
+	predicate: String!
+}
+
+type ClaimableBalance {
+	id: String!
+	pagingToken: String!
+	asset: String!
+	amount: String!
+	sponsor: String
+	lastModifiedLedger: Int!
+	lastModifiedTime: Time
+	claimants: [Claimant!]!
+	clawbackEnabled: Boolean!
+	transactions(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): TransactionConnection!
+	operations(first: Int = 10, after: String, order: Order = ASC, includeFailed: Boolean = false): OperationConnection!
+}
+
+type LedgerEdge {
+	cursor: String!
+	node: Ledger!
+}
+
+type LedgerConnection {
+	edges: [LedgerEdge!]!
+	pageInfo: PageInfo!
+}
+
+type TransactionEdge {
+	cursor: String!
+	node: Transaction!
+}
+
+type TransactionConnection {
+	edges: [TransactionEdge!]!
+	pageInfo: PageInfo!
+}
+
+type OperationEdge {
+	cursor: String!
+	node: Operation!
+}
+
+type OperationConnection {
+	edges: [OperationEdge!]!
+	pageInfo: PageInfo!
+}
+
+type EffectEdge {
+	cursor: String!
+	node: Effect!
+}
+
+type EffectConnection {
+	edges: [EffectEdge!]!
+	pageInfo: PageInfo!
+}
+
+type OfferEdge {
+	cursor: String!
+	node: Offer!
+}
+
+type OfferConnection {
+	edges: [OfferEdge!]!
+	pageInfo: PageInfo!
+}
+
+type TradeEdge {
+	cursor: String!
+	node: Trade!
+}
+
+type TradeConnection {
+	edges: [TradeEdge!]!
+	pageInfo: PageInfo!
+}
+
+type LiquidityPoolEdge {
+	cursor: String!
+	node: LiquidityPool!
+}
+
+type LiquidityPoolConnection {
+	edges: [LiquidityPoolEdge!]!
+	pageInfo: PageInfo!
+}
+
+type ClaimableBalanceEdge {
+	cursor: String!
+	node: ClaimableBalance!
+}
+
+type ClaimableBalanceConnection {
+	edges: [ClaimableBalanceEdge!]!
+	pageInfo: PageInfo!
+}
+`
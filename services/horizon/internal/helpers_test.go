@@ -4,7 +4,7 @@ import (
 	"log"
 	"time"
 
-	"github.com/stellar/throttled"
+	"github.com/throttled/throttled"
 
 	"github.com/stellar/go/network"
 	"github.com/stellar/go/services/horizon/internal/test"
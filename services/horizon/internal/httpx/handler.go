@@ -4,9 +4,12 @@ import (
 	"database/sql"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/stellar/go/services/horizon/internal/actions"
 	horizonContext "github.com/stellar/go/services/horizon/internal/context"
+	"github.com/stellar/go/services/horizon/internal/feed"
 	"github.com/stellar/go/services/horizon/internal/ledger"
 	"github.com/stellar/go/services/horizon/internal/render"
 	hProblem "github.com/stellar/go/services/horizon/internal/render/problem"
@@ -29,6 +32,12 @@ type ObjectActionHandler struct {
 	Action objectAction
 }
 
+// statusCodeResponse is implemented by the resources which are not rendered
+// with the 200 OK status code.
+type statusCodeResponse interface {
+	StatusCode() int
+}
+
 func (handler ObjectActionHandler) ServeHTTP(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -41,6 +50,16 @@ func (handler ObjectActionHandler) ServeHTTP(
 			return
 		}
 
+		if resource, ok := response.(statusCodeResponse); ok {
+			httpjson.RenderStatus(
+				w,
+				resource.StatusCode(),
+				response,
+				httpjson.HALJSON,
+			)
+			return
+		}
+
 		httpjson.Render(
 			w,
 			response,
@@ -128,25 +147,54 @@ func (handler streamableObjectActionHandler) renderStream(
 		limit = defaultObjectStreamLimit
 	}
 
+	generateEvents := repeatableReadStream(r, func() ([]sse.Event, error) {
+		response, err := handler.action.GetResource(w, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastResponse == nil || !lastResponse.Equals(response) {
+			lastResponse = response
+			return []sse.Event{{Data: response}}, nil
+		}
+		return []sse.Event{}, nil
+	})
+
+	if action, ok := handler.action.(changeFilterAction); ok && handler.streamHandler.Feed != nil {
+		filter, err := action.GetChangeFilter(r)
+		if err != nil {
+			problem.Render(r.Context(), w, err)
+			return
+		}
+		handler.streamHandler.ServeFeedStream(w, r, limit, generateEvents, filteredEvents(filter, generateEvents))
+		return
+	}
+
 	handler.streamHandler.ServeStream(
 		w,
 		r,
 		limit,
-		repeatableReadStream(r, func() ([]sse.Event, error) {
-			response, err := handler.action.GetResource(w, r)
-			if err != nil {
-				return nil, err
-			}
-
-			if lastResponse == nil || !lastResponse.Equals(response) {
-				lastResponse = response
-				return []sse.Event{{Data: response}}, nil
-			}
-			return []sse.Event{}, nil
-		}),
+		generateEvents,
 	)
 }
 
+// changeFilterAction is implemented by the actions whose streams only need
+// to reload their resource after the ledgers matching the filter.
+type changeFilterAction interface {
+	GetChangeFilter(r *http.Request) (actions.ChangeFilterFunc, error)
+}
+
+// filteredEvents returns the live events calling generateEvents for the
+// ledgers matching the filter.
+func filteredEvents(filter actions.ChangeFilterFunc, generateEvents sse.GenerateEventsFunc) sse.LiveEventsFunc {
+	return func(changes *feed.ChangeSet) ([]sse.Event, error) {
+		if !filter(changes) {
+			return nil, nil
+		}
+		return generateEvents()
+	}
+}
+
 type pageAction interface {
 	GetResourcePage(w actions.HeaderWriter, r *http.Request) ([]hal.Pageable, error)
 }
@@ -256,6 +304,18 @@ func (handler pageActionHandler) renderStream(w http.ResponseWriter, r *http.Req
 		generateEvents = repeatableReadStream(r, generateEvents)
 	}
 
+	if handler.streamHandler.Feed != nil {
+		liveEvents, err := handler.liveEvents(r, generateEvents)
+		if err != nil {
+			problem.Render(r.Context(), w, err)
+			return
+		}
+		if liveEvents != nil {
+			handler.streamHandler.ServeFeedStream(w, r, int(pq.Limit), generateEvents, liveEvents)
+			return
+		}
+	}
+
 	handler.streamHandler.ServeStream(
 		w,
 		r,
@@ -264,6 +324,79 @@ func (handler pageActionHandler) renderStream(w http.ResponseWriter, r *http.Req
 	)
 }
 
+// livePageAction is implemented by the actions whose streams can be served
+// from the changes of the new ledgers.
+type livePageAction interface {
+	GetLiveResources(r *http.Request) (actions.LiveResourcesFunc, bool, error)
+}
+
+// liveEvents returns the live events of the stream, or nil when the stream
+// cannot use the feed.
+func (handler pageActionHandler) liveEvents(r *http.Request, generateEvents sse.GenerateEventsFunc) (sse.LiveEventsFunc, error) {
+	switch action := handler.action.(type) {
+	case livePageAction:
+		liveResources, ok, err := action.GetLiveResources(r)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return func(changes *feed.ChangeSet) ([]sse.Event, error) {
+			records, err := liveResources(r.Context(), changes)
+			if err != nil {
+				return nil, err
+			}
+
+			// Skip the records already sent when catching up from the DB.
+			cursor := r.Header.Get("Last-Event-ID")
+			events := make([]sse.Event, 0, len(records))
+			for _, record := range records {
+				if pagingTokenAfter(record.PagingToken(), cursor) {
+					events = append(events, sse.Event{ID: record.PagingToken(), Data: record})
+				}
+			}
+			if len(events) > 0 {
+				r.Header.Set("Last-Event-ID", events[len(events)-1].ID)
+			}
+			return events, nil
+		}, nil
+	case changeFilterAction:
+		filter, err := action.GetChangeFilter(r)
+		if err != nil {
+			return nil, err
+		}
+		return filteredEvents(filter, generateEvents), nil
+	}
+	return nil, nil
+}
+
+// pagingTokenAfter returns true if the paging token, made of integers
+// separated by dashes, follows the cursor. All the paging tokens follow an
+// empty cursor.
+func pagingTokenAfter(token, cursor string) bool {
+	if cursor == "" {
+		return true
+	}
+	tokenParts := strings.Split(token, "-")
+	cursorParts := strings.Split(cursor, "-")
+	for i := 0; i < len(tokenParts) || i < len(cursorParts); i++ {
+		var t, c int64
+		var err error
+		if i < len(tokenParts) {
+			if t, err = strconv.ParseInt(tokenParts[i], 10, 64); err != nil {
+				return true
+			}
+		}
+		if i < len(cursorParts) {
+			if c, err = strconv.ParseInt(cursorParts[i], 10, 64); err != nil {
+				return true
+			}
+		}
+		if t != c {
+			return t > c
+		}
+	}
+	return false
+}
+
 func (handler pageActionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch render.Negotiate(r) {
 	case render.MimeHal, render.MimeJSON:
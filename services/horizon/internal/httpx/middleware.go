@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/stellar/go/services/horizon/internal/actions"
@@ -104,8 +105,10 @@ func timeoutMiddleware(timeout time.Duration) func(next http.Handler) http.Handl
 				}
 			}()
 
-			// txsub has a custom timeout
-			if r.Method != http.MethodPost {
+			// txsub has a custom timeout and WebSocket connections are
+			// long-lived, their subscriptions have the timeout.
+			txsub := r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/transactions")
+			if !txsub && !websocket.IsWebSocketUpgrade(r) {
 				r = r.WithContext(ctx)
 			}
 			next.ServeHTTP(mw, r)
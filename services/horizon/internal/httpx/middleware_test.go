@@ -1,7 +1,10 @@
 package httpx
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -45,3 +48,25 @@ func TestMiddlewareSanitizesRoutesForPrometheus(t *testing.T) {
 	}
 
 }
+
+func TestTimeoutMiddlewareSkipsTransactionSubmission(t *testing.T) {
+	for _, setup := range []struct {
+		method   string
+		path     string
+		deadline bool
+	}{
+		{http.MethodGet, "/accounts", true},
+		{http.MethodPost, "/graphql", true},
+		{http.MethodPost, "/transactions", false},
+		{http.MethodPost, "/transactions_async", false},
+	} {
+		t.Run(setup.method+" "+setup.path, func(t *testing.T) {
+			var deadline bool
+			handler := timeoutMiddleware(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, deadline = r.Context().Deadline()
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(setup.method, setup.path, nil))
+			assert.Equal(t, setup.deadline, deadline)
+		})
+	}
+}
@@ -1,19 +1,29 @@
 package httpx
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/stellar/throttled"
+	"github.com/throttled/throttled"
+	"github.com/throttled/throttled/store/memstore"
 
 	"github.com/stellar/go/services/horizon/internal/ledger"
+	"github.com/stellar/go/services/horizon/internal/ratelimit"
+	"github.com/stellar/go/services/horizon/internal/render"
 	hProblem "github.com/stellar/go/services/horizon/internal/render/problem"
+	"github.com/stellar/go/support/errors"
 	"github.com/stellar/go/support/render/problem"
 )
 
 const lruCacheSize = 50000
 
+// APIKeyHeader is the header of the API key identifying the client of a
+// request. The requests without API key are rate limited by IP address.
+const APIKeyHeader = "X-Api-Key"
+
 type historyLedgerSourceFactory struct {
 	updateFrequency time.Duration
 	ledgerState     *ledger.State
@@ -33,24 +43,97 @@ func remoteAddrIP(r *http.Request) string {
 	}
 }
 
-type VaryByRemoteIP struct{}
+// newRateLimiter returns nil when the requests are not rate limited.
+func newRateLimiter(config *RouterConfig) (*ratelimit.Limiter, error) {
+	if config.RateQuota == nil && config.RateLimitConfigPath == "" {
+		return nil, nil
+	}
 
-func (v VaryByRemoteIP) Key(r *http.Request) string {
-	return remoteAddrIP(r)
-}
+	limiterConfig := ratelimit.Config{
+		Anonymous: ratelimit.Tier{
+			Name:  ratelimit.AnonymousTierName,
+			Quota: config.RateQuota,
+		},
+	}
+	// Only the deployments configuring tiers count the expensive endpoints
+	// as several requests, --per-hour-rate-limit alone counts every request
+	// once.
+	if config.RateLimitConfigPath != "" {
+		limiterConfig.Costs = ratelimit.DefaultCosts
+		if err := ratelimit.ReadConfig(config.RateLimitConfigPath, &limiterConfig); err != nil {
+			return nil, err
+		}
+	}
 
-func newRateLimiter(rateQuota *throttled.RateQuota) (*throttled.HTTPRateLimiter, error) {
-	rateLimiter, err := throttled.NewGCRARateLimiter(lruCacheSize, *rateQuota)
+	var store throttled.GCRAStore
+	var err error
+	if config.RateLimitRedisURL != "" {
+		store, err = ratelimit.NewRedisStore(config.RateLimitRedisURL)
+	} else {
+		store, err = memstore.New(lruCacheSize)
+	}
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "could not create rate limit store")
+	}
+	return ratelimit.NewLimiter(limiterConfig, store)
+}
+
+// rateLimitMiddleware identifies the client of the request by its API key or
+// IP address and counts the cost of the request against the quota of its
+// tier. Streaming requests are also counted against the concurrent streams
+// allowed for the tier.
+func rateLimitMiddleware(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			// The subscriptions of a WebSocket connection are routed with
+			// the client of the connection.
+			client, ok := ratelimit.FromContext(ctx)
+			if !ok {
+				var err error
+				client, err = limiter.Identify(r.Header.Get(APIKeyHeader), remoteAddrIP(r))
+				if err != nil {
+					problem.Render(ctx, w, hProblem.UnknownAPIKey)
+					return
+				}
+				ctx = ratelimit.NewContext(ctx, client)
+			}
+
+			limited, result, err := limiter.Limit(client, limiter.Cost(r.URL.Path))
+			if err != nil {
+				problem.Render(ctx, w, err)
+				return
+			}
+			setRateLimitHeaders(w, result)
+			if limited {
+				problem.Render(ctx, w, hProblem.RateLimitExceeded)
+				return
+			}
+
+			if render.Negotiate(r) == render.MimeEventStream {
+				closeStream, ok := limiter.OpenStream(client)
+				if !ok {
+					problem.Render(ctx, w, hProblem.StreamLimitExceeded)
+					return
+				}
+				defer closeStream()
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
 	}
+}
 
-	result := &throttled.HTTPRateLimiter{
-		RateLimiter: rateLimiter,
-		DeniedHandler: http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
-			problem.Render(request.Context(), w, hProblem.RateLimitExceeded)
-		}),
-		VaryBy: VaryByRemoteIP{},
+func setRateLimitHeaders(w http.ResponseWriter, result throttled.RateLimitResult) {
+	if v := result.Limit; v >= 0 {
+		w.Header().Add("X-RateLimit-Limit", strconv.Itoa(v))
+	}
+	if v := result.Remaining; v >= 0 {
+		w.Header().Add("X-RateLimit-Remaining", strconv.Itoa(v))
+	}
+	if v := result.ResetAfter; v >= 0 {
+		w.Header().Add("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(v.Seconds()))))
+	}
+	if v := result.RetryAfter; v >= 0 {
+		w.Header().Add("Retry-After", strconv.Itoa(int(math.Ceil(v.Seconds()))))
 	}
-	return result, nil
 }
@@ -0,0 +1,136 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/throttled/throttled"
+
+	"github.com/stellar/go/services/horizon/internal/render"
+)
+
+// newRateLimitTest returns a router whose streams signal opened when they are
+// open and end once release is received.
+func newRateLimitTest(t *testing.T, opened, release chan struct{}) http.Handler {
+	path := filepath.Join(t.TempDir(), "rate-limit.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[tiers.partner]
+per_hour = 3600
+max_burst = 99
+max_streams = 1
+
+[keys]
+"secret" = "partner"
+`), 0600))
+
+	limiter, err := newRateLimiter(&RouterConfig{
+		RateQuota:           &throttled.RateQuota{MaxRate: throttled.PerHour(10), MaxBurst: 9},
+		RateLimitConfigPath: path,
+	})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(rateLimitMiddleware(limiter))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if render.Negotiate(r) == render.MimeEventStream {
+			opened <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Get("/accounts", ok)
+	router.Get("/paths/strict-send", ok)
+	return router
+}
+
+func rateLimitRequest(h http.Handler, path, apiKey string, stream bool) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	if apiKey != "" {
+		r.Header.Set(APIKeyHeader, apiKey)
+	}
+	if stream {
+		r.Header.Set("Accept", render.MimeEventStream)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	h := newRateLimitTest(t, nil, nil)
+
+	w := rateLimitRequest(h, "/accounts", "", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "360", w.Header().Get("X-RateLimit-Reset"))
+
+	// Path finding requests cost 10 requests.
+	w = rateLimitRequest(h, "/paths/strict-send", "", false)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "360", w.Header().Get("Retry-After"))
+
+	// The headers reflect the tier of the API key.
+	w = rateLimitRequest(h, "/paths/strict-send", "secret", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "90", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Reset"))
+
+	w = rateLimitRequest(h, "/accounts", "wrong", false)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown_api_key")
+}
+
+func TestRateLimitMiddlewareWithoutConfigFile(t *testing.T) {
+	limiter, err := newRateLimiter(&RouterConfig{
+		RateQuota: &throttled.RateQuota{MaxRate: throttled.PerHour(10), MaxBurst: 9},
+	})
+	require.NoError(t, err)
+	router := chi.NewRouter()
+	router.Use(rateLimitMiddleware(limiter))
+	router.Get("/paths/strict-send", func(w http.ResponseWriter, r *http.Request) {})
+
+	// Without tiers every request counts once.
+	w := rateLimitRequest(router, "/paths/strict-send", "", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestRateLimitMiddlewareStreams(t *testing.T) {
+	opened, release := make(chan struct{}), make(chan struct{})
+	h := newRateLimitTest(t, opened, release)
+
+	done := make(chan int)
+	stream := func(apiKey string) {
+		done <- rateLimitRequest(h, "/accounts", apiKey, true).Code
+	}
+	go stream("secret")
+	<-opened
+
+	w := rateLimitRequest(h, "/accounts", "secret", true)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "stream_limit_exceeded")
+
+	// The anonymous tier has no stream limit.
+	go stream("")
+	<-opened
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done)
+
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done)
+
+	go stream("secret")
+	<-opened
+	release <- struct{}{}
+	assert.Equal(t, http.StatusOK, <-done)
+}
@@ -13,12 +13,15 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
-	"github.com/stellar/throttled"
+	"github.com/throttled/throttled"
 
 	"github.com/stellar/go/services/horizon/internal/actions"
 	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
+	"github.com/stellar/go/services/horizon/internal/gql"
 	"github.com/stellar/go/services/horizon/internal/ledger"
 	"github.com/stellar/go/services/horizon/internal/paths"
+	"github.com/stellar/go/services/horizon/internal/ratelimit"
 	"github.com/stellar/go/services/horizon/internal/render/sse"
 	"github.com/stellar/go/services/horizon/internal/txsub"
 	"github.com/stellar/go/support/db"
@@ -32,15 +35,26 @@ type RouterConfig struct {
 	DBSession        db.SessionInterface
 	PrimaryDBSession db.SessionInterface
 	TxSubmitter      *txsub.System
-	RateQuota        *throttled.RateQuota
+	// RateQuota is the quota of the requests without API key, they are not
+	// rate limited when it is nil.
+	RateQuota *throttled.RateQuota
+	// RateLimitConfigPath is the path of the TOML file configuring the rate
+	// limit tiers of the API keys.
+	RateLimitConfigPath string
+	// RateLimitRedisURL is the URL of the Redis server keeping the state of
+	// the quotas. The state is kept in memory when it is empty.
+	RateLimitRedisURL string
 
 	BehindCloudflare      bool
 	BehindAWSLoadBalancer bool
 	SSEUpdateFrequency    time.Duration
+	Feed                  *feed.Feed
 	StaleThreshold        uint
 	ConnectionTimeout     time.Duration
 	NetworkPassphrase     string
 	MaxPathLength         uint
+	EnableGraphQL         bool
+	GraphQLMaxCost        uint
 	PathFinder            paths.Finder
 	PrometheusRegistry    *prometheus.Registry
 	CoreGetter            actions.CoreStateGetter
@@ -59,13 +73,9 @@ func NewRouter(config *RouterConfig, serverMetrics *ServerMetrics, ledgerState *
 		Mux:      chi.NewMux(),
 		Internal: chi.NewMux(),
 	}
-	var rateLimiter *throttled.HTTPRateLimiter
-	if config.RateQuota != nil {
-		var err error
-		rateLimiter, err = newRateLimiter(config.RateQuota)
-		if err != nil {
-			return nil, fmt.Errorf("unable to create RateLimiter: %v", err)
-		}
+	rateLimiter, err := newRateLimiter(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create RateLimiter: %v", err)
 	}
 	result.addMiddleware(config, rateLimiter, serverMetrics)
 	result.addRoutes(config, rateLimiter, ledgerState)
@@ -73,7 +83,7 @@ func NewRouter(config *RouterConfig, serverMetrics *ServerMetrics, ledgerState *
 }
 
 func (r *Router) addMiddleware(config *RouterConfig,
-	rateLimitter *throttled.HTTPRateLimiter,
+	rateLimiter *ratelimit.Limiter,
 	serverMetrics *ServerMetrics) {
 
 	r.Use(chimiddleware.StripSlashes)
@@ -97,8 +107,8 @@ func (r *Router) addMiddleware(config *RouterConfig,
 	})
 	r.Use(c.Handler)
 
-	if rateLimitter != nil {
-		r.Use(rateLimitter.RateLimit)
+	if rateLimiter != nil {
+		r.Use(rateLimitMiddleware(rateLimiter))
 	}
 
 	if config.PrimaryDBSession != nil {
@@ -116,7 +126,7 @@ func (r *Router) addMiddleware(config *RouterConfig,
 	r.Internal.Use(loggerMiddleware(serverMetrics))
 }
 
-func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, ledgerState *ledger.State) {
+func (r *Router) addRoutes(config *RouterConfig, rateLimiter *ratelimit.Limiter, ledgerState *ledger.State) {
 	stateMiddleware := StateMiddleware{
 		HorizonSession: config.DBSession,
 	}
@@ -134,6 +144,15 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	streamHandler := sse.StreamHandler{
 		RateLimiter:         rateLimiter,
 		LedgerSourceFactory: historyLedgerSourceFactory{ledgerState: ledgerState, updateFrequency: config.SSEUpdateFrequency},
+		Feed:                config.Feed,
+	}
+
+	// Subscriptions of WebSocket connections are routed like Server Sent
+	// Events requests.
+	r.Method(http.MethodGet, "/ws", newWebSocketHandler(r))
+
+	if config.EnableGraphQL {
+		r.With(stateMiddleware.Wrap).Method(http.MethodPost, "/graphql", gql.NewHandler(config.GraphQLMaxCost))
 	}
 
 	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession)
@@ -308,6 +327,17 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		CoreStateGetter:   config.CoreGetter,
 	}})
 
+	// Asynchronous transaction submission API
+	r.Method(http.MethodPost, "/transactions_async", ObjectActionHandler{actions.AsyncSubmitTransactionHandler{
+		Submitter:         config.TxSubmitter,
+		NetworkPassphrase: config.NetworkPassphrase,
+		CoreStateGetter:   config.CoreGetter,
+	}})
+	r.With(historyMiddleware).Method(http.MethodGet, "/transactions_async/{tx_id}", streamableObjectActionHandler{
+		streamHandler: streamHandler,
+		action:        actions.GetAsyncTransactionStatusHandler{},
+	})
+
 	// Network state related endpoints
 	r.Method(http.MethodGet, "/fee_stats", ObjectActionHandler{actions.FeeStatsHandler{}})
 
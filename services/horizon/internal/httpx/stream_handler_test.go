@@ -16,6 +16,8 @@ import (
 
 	"github.com/stellar/go/services/horizon/internal/actions"
 	horizonContext "github.com/stellar/go/services/horizon/internal/context"
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
 	"github.com/stellar/go/services/horizon/internal/ledger"
 	"github.com/stellar/go/services/horizon/internal/render/sse"
 	"github.com/stellar/go/support/db"
@@ -547,3 +549,78 @@ func TestRepeatableReadStream(t *testing.T) {
 		session.AssertExpectations(t)
 	})
 }
+
+type testLivePageAction struct {
+	*testPageAction
+	live     map[uint32][]hal.Pageable
+	caughtUp chan struct{}
+}
+
+func (action *testLivePageAction) GetResourcePage(
+	w actions.HeaderWriter,
+	r *http.Request,
+) ([]hal.Pageable, error) {
+	defer close(action.caughtUp)
+	return action.testPageAction.GetResourcePage(w, r)
+}
+
+func (action *testLivePageAction) GetLiveResources(r *http.Request) (actions.LiveResourcesFunc, bool, error) {
+	return func(ctx context.Context, changes *feed.ChangeSet) ([]hal.Pageable, error) {
+		return action.live[changes.Sequence()], nil
+	}, true, nil
+}
+
+func TestLivePageStream(t *testing.T) {
+	ledgerSource := ledger.NewTestingSource(3)
+	action := &testLivePageAction{
+		testPageAction: &testPageAction{
+			objects:      map[uint32][]string{3: {"a", "b"}},
+			ledgerSource: ledgerSource,
+		},
+		live: map[uint32][]hal.Pageable{
+			// The ledger of the records returned when catching up.
+			3: {testPage{Value: "b", pagingToken: 2}},
+			4: {testPage{Value: "c", pagingToken: 3}, testPage{Value: "d", pagingToken: 4}},
+		},
+		caughtUp: make(chan struct{}),
+	}
+	f := feed.New(2)
+	streamHandler := sse.StreamHandler{LedgerSourceFactory: &testingFactory{ledgerSource}, Feed: f}
+	handler := streamableHistoryPageHandler(&ledger.State{}, action, streamHandler)
+
+	st := newStreamTest(
+		handler.renderStream,
+		ledgerSource,
+		streamRequest(t, "limit=4"),
+		expectResponse(t, unmarashalPage, []string{"a", "b", "c", "d"}),
+	)
+	go func() {
+		// The stream subscribes before catching up.
+		<-action.caughtUp
+		f.Publish(feed.NewChangeSet(history.Ledger{Sequence: 3}, nil, nil, nil, nil, nil))
+		f.Publish(feed.NewChangeSet(history.Ledger{Sequence: 4}, nil, nil, nil, nil, nil))
+	}()
+	st.Wait()
+}
+
+func TestPagingTokenAfter(t *testing.T) {
+	for _, testCase := range []struct {
+		token  string
+		cursor string
+		after  bool
+	}{
+		{"12", "", true},
+		{"12", "11", true},
+		{"12", "12", false},
+		{"12", "13", false},
+		{"12-1", "12-1", false},
+		{"12-2", "12-1", true},
+		{"13-1", "12-2", true},
+		{"12-1", "12", true},
+		{"12-1", "13", false},
+	} {
+		if got := pagingTokenAfter(testCase.token, testCase.cursor); got != testCase.after {
+			t.Errorf("pagingTokenAfter(%q, %q) = %v", testCase.token, testCase.cursor, got)
+		}
+	}
+}
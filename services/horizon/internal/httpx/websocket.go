@@ -0,0 +1,327 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+
+	"github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/render"
+	"github.com/stellar/go/services/horizon/internal/render/sse"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/support/log"
+	"github.com/stellar/go/support/render/problem"
+)
+
+const (
+	// maxWebSocketSubscriptions is the maximum number of concurrent
+	// subscriptions of a WebSocket connection.
+	maxWebSocketSubscriptions = 100
+	webSocketWriteTimeout     = 10 * time.Second
+	webSocketPingPeriod       = 30 * time.Second
+)
+
+// webSocketHandler serves the streams of several streamable end-points over
+// a single WebSocket connection. Each subscription is dispatched to handler,
+// the router, as a streaming request, so that it goes through the same
+// middlewares and actions as a Server Sent Events request. Streams are resumed
+// from their last event when the streaming request ends.
+type webSocketHandler struct {
+	handler  http.Handler
+	upgrader websocket.Upgrader
+}
+
+func newWebSocketHandler(handler http.Handler) webSocketHandler {
+	return webSocketHandler{
+		handler: handler,
+		upgrader: websocket.Upgrader{
+			// Like the other end-points, the WebSocket end-point can be used
+			// from any origin.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (h webSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied with an error.
+		return
+	}
+
+	c := &webSocketConn{
+		conn:          conn,
+		handler:       h.handler,
+		request:       r,
+		subscriptions: map[string]context.CancelFunc{},
+	}
+	c.serve(r.Context())
+}
+
+type webSocketConn struct {
+	conn    *websocket.Conn
+	handler http.Handler
+	// request is the upgrade request, the subscription requests copy its
+	// headers.
+	request *http.Request
+
+	writeLock sync.Mutex
+
+	lock          sync.Mutex
+	subscriptions map[string]context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+// serve reads the requests of the client until the connection is closed.
+func (c *webSocketConn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	go c.ping(ctx)
+
+	for {
+		var request horizon.WebSocketRequest
+		if err := c.conn.ReadJSON(&request); err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok && ctx.Err() == nil {
+				log.Ctx(ctx).WithError(err).Debug("could not read WebSocket request")
+			}
+			return
+		}
+
+		switch request.Type {
+		case horizon.WebSocketSubscribe:
+			c.subscribe(ctx, request)
+		case horizon.WebSocketUnsubscribe:
+			c.unsubscribe(request.ID)
+		default:
+			c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("type", errors.New("unknown message type")))
+		}
+	}
+}
+
+// ping keeps the idle connections open through proxies.
+func (c *webSocketConn) ping(ctx context.Context) {
+	ticker := time.NewTicker(webSocketPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(webSocketWriteTimeout)
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *webSocketConn) subscribe(ctx context.Context, request horizon.WebSocketRequest) {
+	if request.ID == "" {
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("id", errors.New("missing subscription id")))
+		return
+	}
+	path, err := url.Parse(request.Path)
+	if err != nil || !strings.HasPrefix(path.Path, "/") || path.Host != "" {
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("path", errors.New("invalid path")))
+		return
+	}
+
+	c.lock.Lock()
+	if _, ok := c.subscriptions[request.ID]; ok {
+		c.lock.Unlock()
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("id", errors.New("duplicate subscription id")))
+		return
+	}
+	if len(c.subscriptions) >= maxWebSocketSubscriptions {
+		c.lock.Unlock()
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("id", errors.New("too many subscriptions")))
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.subscriptions[request.ID] = cancel
+	c.wg.Add(1)
+	c.lock.Unlock()
+
+	c.send(horizon.WebSocketMessage{Type: horizon.WebSocketSubscribed, ID: request.ID})
+	go func() {
+		defer c.wg.Done()
+		defer c.remove(request.ID)
+		c.stream(ctx, request.ID, path)
+	}()
+}
+
+func (c *webSocketConn) unsubscribe(id string) {
+	c.lock.Lock()
+	cancel, ok := c.subscriptions[id]
+	c.lock.Unlock()
+	if !ok {
+		c.sendProblem(id, problem.MakeInvalidFieldProblem("id", errors.New("unknown subscription id")))
+		return
+	}
+	cancel()
+}
+
+// remove removes the subscription once its stream has ended.
+func (c *webSocketConn) remove(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.subscriptions[id]()
+	delete(c.subscriptions, id)
+}
+
+// stream dispatches streaming requests for the path until the subscription is
+// cancelled or the stream fails. Every request resumes the stream from the
+// last event of the previous one. The subscription ends if the path replies
+// without opening a stream.
+func (c *webSocketConn) stream(ctx context.Context, id string, path *url.URL) {
+	var last sentEvent
+	for {
+		w := &webSocketResponseWriter{conn: c, id: id, header: http.Header{}, last: last}
+		r := c.newRequest(sse.WithEventSink(ctx, w), path, last.id)
+		c.handler.ServeHTTP(w, r)
+
+		switch {
+		case w.failed:
+			return
+		case w.status != http.StatusOK:
+			var p problem.P
+			if err := json.Unmarshal(w.body.Bytes(), &p); err != nil || p.Status == 0 {
+				p = problem.P{Title: http.StatusText(w.status), Status: w.status}
+			}
+			c.sendProblem(id, &p)
+			return
+		case ctx.Err() != nil:
+			c.send(horizon.WebSocketMessage{Type: horizon.WebSocketUnsubscribed, ID: id})
+			return
+		case !w.opened:
+			// The end-point replied without streaming, dispatching the
+			// request again would reply the same.
+			c.sendProblem(id, problem.MakeInvalidFieldProblem("path", errors.New("path is not streamable")))
+			return
+		}
+		last = w.last
+	}
+}
+
+// newRequest returns a streaming request for the path with the headers of
+// the upgrade request.
+func (c *webSocketConn) newRequest(ctx context.Context, path *url.URL, lastEventID string) *http.Request {
+	// The subscription is routed from the root of the router.
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, (*chi.Context)(nil))
+	r := c.request.Clone(ctx)
+	r.URL = &url.URL{Path: path.Path, RawPath: path.RawPath, RawQuery: path.RawQuery}
+	r.RequestURI = r.URL.RequestURI()
+	for _, header := range []string{"Connection", "Upgrade", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol", "Accept-Encoding"} {
+		r.Header.Del(header)
+	}
+	r.Header.Set("Accept", render.MimeEventStream)
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+	return r
+}
+
+func (c *webSocketConn) send(message horizon.WebSocketMessage) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	if err := c.conn.WriteJSON(message); err != nil {
+		// Closing the connection ends the read loop and the subscriptions.
+		c.conn.Close()
+	}
+}
+
+func (c *webSocketConn) sendProblem(id string, p *problem.P) {
+	c.send(horizon.WebSocketMessage{Type: horizon.WebSocketError, ID: id, Problem: p})
+}
+
+// webSocketResponseWriter is the response writer of the streaming requests of
+// a subscription. The events of the streams are sent to the subscription,
+// the response is only used for the errors returned before the streams start.
+type webSocketResponseWriter struct {
+	conn   *webSocketConn
+	id     string
+	header http.Header
+	status int
+	body   bytes.Buffer
+	failed bool
+	// opened is true once the request opened a stream.
+	opened bool
+	last   sentEvent
+}
+
+// sentEvent is the last event sent to a subscription.
+type sentEvent struct {
+	id   string
+	data []byte
+}
+
+func (w *webSocketResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *webSocketResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *webSocketResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// SendEvent implements sse.EventSink.
+func (w *webSocketResponseWriter) SendEvent(e sse.Event) {
+	switch {
+	case e.Error != nil:
+		w.failed = true
+		p, ok := e.Error.(problem.P)
+		if !ok {
+			p = problem.ServerError
+			p.Detail = e.Error.Error()
+		}
+		w.conn.sendProblem(w.id, &p)
+	case e.IsOpen():
+		w.opened = true
+	case e.IsClose():
+		// The stream is resumed by the next request.
+	default:
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			w.failed = true
+			p := problem.ServerError
+			w.conn.sendProblem(w.id, &p)
+			return
+		}
+		if e.ID == "" && bytes.Equal(data, w.last.data) {
+			// Streams of objects start with the current object, which was
+			// already sent by the previous request.
+			return
+		}
+		w.last = sentEvent{id: w.last.id, data: data}
+		if e.ID != "" {
+			w.last.id = e.ID
+		}
+		w.conn.send(horizon.WebSocketMessage{
+			Type:    horizon.WebSocketEvent,
+			ID:      w.id,
+			EventID: e.ID,
+			Data:    data,
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/services/horizon/internal/ledger"
+	"github.com/stellar/go/services/horizon/internal/render/sse"
+)
+
+// newWebSocketTest returns a connection to the WebSocket end-point of a
+// router with the middlewares of Horizon and an /objects stream.
+func newWebSocketTest(t *testing.T) (*websocket.Conn, *ledger.TestingSource) {
+	ledgerSource := ledger.NewTestingSource(3)
+	action := &testPageAction{
+		objects:      map[uint32][]string{3: {"a", "b", "c"}, 4: {"a", "b", "c", "d"}},
+		ledgerSource: ledgerSource,
+	}
+	streamHandler := sse.StreamHandler{LedgerSourceFactory: &testingFactory{ledgerSource}}
+
+	router, err := NewRouter(&RouterConfig{
+		ConnectionTimeout: time.Minute,
+		HealthCheck: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"ok"}`))
+		}),
+	}, &ServerMetrics{
+		RequestDurationSummary: prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{Namespace: "horizon", Subsystem: "http", Name: "requests_duration_seconds"},
+			[]string{"status", "route", "streaming", "method"},
+		),
+	}, &ledger.State{})
+	require.NoError(t, err)
+	router.Method(http.MethodGet, "/objects", streamableHistoryPageHandler(&ledger.State{}, action, streamHandler))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, ledgerSource
+}
+
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) horizon.WebSocketMessage {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var message horizon.WebSocketMessage
+	require.NoError(t, conn.ReadJSON(&message))
+	return message
+}
+
+func TestWebSocketSubscription(t *testing.T) {
+	conn, ledgerSource := newWebSocketTest(t)
+
+	require.NoError(t, conn.WriteJSON(horizon.WebSocketRequest{
+		Type: horizon.WebSocketSubscribe,
+		ID:   "objects",
+		Path: "/objects?limit=2",
+	}))
+	assert.Equal(t, horizon.WebSocketMessage{Type: horizon.WebSocketSubscribed, ID: "objects"}, readWebSocketMessage(t, conn))
+
+	// The stream is resumed after its limit.
+	for i, expected := range []string{"a", "b", "c", "d"} {
+		if expected == "d" {
+			ledgerSource.AddLedger(4)
+		}
+		message := readWebSocketMessage(t, conn)
+		assert.Equal(t, horizon.WebSocketEvent, message.Type)
+		assert.Equal(t, "objects", message.ID)
+		assert.Equal(t, string(rune('1'+i)), message.EventID)
+		var page testPage
+		require.NoError(t, json.Unmarshal(message.Data, &page))
+		assert.Equal(t, expected, page.Value)
+	}
+
+	require.NoError(t, conn.WriteJSON(horizon.WebSocketRequest{Type: horizon.WebSocketUnsubscribe, ID: "objects"}))
+	assert.Equal(t, horizon.WebSocketMessage{Type: horizon.WebSocketUnsubscribed, ID: "objects"}, readWebSocketMessage(t, conn))
+
+	require.NoError(t, conn.WriteJSON(horizon.WebSocketRequest{Type: horizon.WebSocketUnsubscribe, ID: "objects"}))
+	message := readWebSocketMessage(t, conn)
+	assert.Equal(t, horizon.WebSocketError, message.Type)
+	assert.Equal(t, http.StatusBadRequest, message.Problem.Status)
+}
+
+func TestWebSocketSubscriptionErrors(t *testing.T) {
+	conn, _ := newWebSocketTest(t)
+
+	require.NoError(t, conn.WriteJSON(horizon.WebSocketRequest{
+		Type: horizon.WebSocketSubscribe,
+		ID:   "missing",
+		Path: "/missing",
+	}))
+	assert.Equal(t, horizon.WebSocketSubscribed, readWebSocketMessage(t, conn).Type)
+	message := readWebSocketMessage(t, conn)
+	assert.Equal(t, horizon.WebSocketError, message.Type)
+	assert.Equal(t, "missing", message.ID)
+	assert.Equal(t, http.StatusNotFound, message.Problem.Status)
+
+	// End-points which do not stream end the subscription instead of being
+	// requested again.
+	require.NoError(t, conn.WriteJSON(horizon.WebSocketRequest{
+		Type: horizon.WebSocketSubscribe,
+		ID:   "health",
+		Path: "/health",
+	}))
+	assert.Equal(t, horizon.WebSocketSubscribed, readWebSocketMessage(t, conn).Type)
+	message = readWebSocketMessage(t, conn)
+	assert.Equal(t, horizon.WebSocketError, message.Type)
+	assert.Equal(t, "health", message.ID)
+	assert.Equal(t, http.StatusBadRequest, message.Problem.Status)
+	assert.Equal(t, "path is not streamable", message.Problem.Extras["reason"])
+
+	for _, request := range []horizon.WebSocketRequest{
+		{Type: horizon.WebSocketSubscribe, Path: "/objects"},
+		{Type: horizon.WebSocketSubscribe, ID: "invalid", Path: "http://example.com/objects"},
+		{Type: "publish", ID: "objects"},
+	} {
+		require.NoError(t, conn.WriteJSON(request))
+		message := readWebSocketMessage(t, conn)
+		assert.Equal(t, horizon.WebSocketError, message.Type)
+		assert.Equal(t, request.ID, message.ID)
+		assert.Equal(t, http.StatusBadRequest, message.Problem.Status)
+	}
+}
@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stellar/go/services/horizon/internal/feed"
+	"github.com/stellar/go/support/errors"
+)
+
+// maxFeedGap is the maximum number of ledgers FeedStream publishes in a
+// single update. When more ledgers were ingested since the last update the
+// feed is reset instead, and the streams catch up from the DB.
+const maxFeedGap = 10
+
+// FeedQ is the interface of the history queries used by FeedStream.
+type FeedQ interface {
+	feed.Q
+	GetLatestHistoryLedger(ctx context.Context) (uint32, error)
+}
+
+// FeedStream publishes the history of the ledgers ingested into the Horizon
+// DB to an in memory feed, so that all the streaming requests of this
+// instance share a single DB read per ledger. Like OrderBookStream it follows
+// the DB rather than the ingestion system, so that it works on instances which
+// do not ingest.
+type FeedStream struct {
+	feed            *feed.Feed
+	historyQ        FeedQ
+	updateFrequency time.Duration
+	// LatestLedgerGauge exposes the latest ledger published to the feed
+	LatestLedgerGauge prometheus.Gauge
+	lastLedger        uint32
+}
+
+// NewFeedStream constructs a FeedStream checking the DB for new ledgers
+// every updateFrequency.
+func NewFeedStream(historyQ FeedQ, f *feed.Feed, updateFrequency time.Duration) *FeedStream {
+	return &FeedStream{
+		feed:            f,
+		historyQ:        historyQ,
+		updateFrequency: updateFrequency,
+		LatestLedgerGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "horizon", Subsystem: "feed_stream", Name: "latest_ledger",
+		}),
+	}
+}
+
+// Update publishes the ledgers ingested since the last call to Update. On
+// the first call, or when the ledgers cannot be published in order, the feed
+// is reset and starts over from the latest ledger.
+func (s *FeedStream) Update(ctx context.Context) error {
+	latest, err := s.historyQ.GetLatestHistoryLedger(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error from GetLatestHistoryLedger")
+	}
+	if latest == s.lastLedger {
+		return nil
+	}
+
+	if s.lastLedger == 0 || latest < s.lastLedger || latest-s.lastLedger > maxFeedGap {
+		if s.lastLedger != 0 {
+			log.WithField("last_ledger", s.lastLedger).
+				WithField("latest_ledger", latest).
+				Info("resetting feed")
+		}
+		s.feed.Reset()
+		s.setLastLedger(latest)
+		return nil
+	}
+
+	for seq := s.lastLedger + 1; seq <= latest; seq++ {
+		cs, err := feed.Load(ctx, s.historyQ, int32(seq))
+		if err != nil {
+			// The ledger must not be skipped, subscribers catch up from the DB.
+			s.feed.Reset()
+			s.setLastLedger(0)
+			return errors.Wrapf(err, "could not load ledger %d", seq)
+		}
+		s.feed.Publish(cs)
+		s.setLastLedger(seq)
+	}
+	return nil
+}
+
+func (s *FeedStream) setLastLedger(seq uint32) {
+	s.lastLedger = seq
+	s.LatestLedgerGauge.Set(float64(seq))
+}
+
+// Run calls Update() every update frequency until the given context is
+// terminated.
+func (s *FeedStream) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.updateFrequency)
+	defer ticker.Stop()
+	defer s.feed.Reset()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Update(ctx); err != nil && !isCancelledError(err) {
+				log.WithError(err).Error("could not publish ledgers to feed")
+			}
+		case <-ctx.Done():
+			log.Info("shutting down FeedStream")
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
+	"github.com/stellar/go/support/errors"
+)
+
+type fakeFeedQ struct {
+	latest uint32
+	loaded []int32
+	err    error
+}
+
+func (q *fakeFeedQ) GetLatestHistoryLedger(ctx context.Context) (uint32, error) {
+	return q.latest, nil
+}
+
+func (q *fakeFeedQ) LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.loaded = append(q.loaded, seq)
+	dest.(*history.Ledger).Sequence = seq
+	return nil
+}
+
+func (q *fakeFeedQ) TransactionsInLedger(ctx context.Context, seq int32) ([]history.Transaction, error) {
+	return nil, nil
+}
+
+func (q *fakeFeedQ) OperationsInLedger(ctx context.Context, seq int32) ([]history.Operation, error) {
+	return nil, nil
+}
+
+func (q *fakeFeedQ) EffectsInLedger(ctx context.Context, seq int32) ([]history.Effect, error) {
+	return nil, nil
+}
+
+func (q *fakeFeedQ) TransactionParticipantsInLedger(ctx context.Context, seq int32) ([]history.Participant, error) {
+	return nil, nil
+}
+
+func (q *fakeFeedQ) OperationParticipantsInLedger(ctx context.Context, seq int32) ([]history.Participant, error) {
+	return nil, nil
+}
+
+func TestFeedStreamUpdate(t *testing.T) {
+	ctx := context.Background()
+	q := &fakeFeedQ{latest: 10}
+	f := feed.New(maxFeedGap)
+	stream := NewFeedStream(q, f, time.Second)
+
+	// The first update starts from the latest ledger.
+	subscription := f.Subscribe()
+	require.NoError(t, stream.Update(ctx))
+	_, ok := <-subscription.Changes()
+	assert.False(t, ok)
+	assert.Empty(t, q.loaded)
+
+	subscription = f.Subscribe()
+	q.latest = 12
+	require.NoError(t, stream.Update(ctx))
+	assert.Equal(t, []int32{11, 12}, q.loaded)
+	assert.Equal(t, uint32(11), (<-subscription.Changes()).Sequence())
+	assert.Equal(t, uint32(12), (<-subscription.Changes()).Sequence())
+
+	// Nothing was ingested.
+	require.NoError(t, stream.Update(ctx))
+	assert.Equal(t, []int32{11, 12}, q.loaded)
+
+	// Too many ledgers were ingested.
+	q.latest = 13 + maxFeedGap
+	require.NoError(t, stream.Update(ctx))
+	_, ok = <-subscription.Changes()
+	assert.False(t, ok)
+	assert.Equal(t, []int32{11, 12}, q.loaded)
+
+	subscription = f.Subscribe()
+	q.latest++
+	q.err = errors.New("db error")
+	assert.EqualError(t, stream.Update(ctx), "could not load ledger 24: could not load ledger: db error")
+	_, ok = <-subscription.Changes()
+	assert.False(t, ok)
+
+	subscription = f.Subscribe()
+	q.latest++
+	q.err = nil
+	require.NoError(t, stream.Update(ctx))
+	_, ok = <-subscription.Changes()
+	assert.False(t, ok)
+	assert.Equal(t, []int32{11, 12}, q.loaded)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/stellar/go/ingest"
 	"github.com/stellar/go/ingest/ledgerbackend"
 	"github.com/stellar/go/services/horizon/internal/ingest/processors"
 	"github.com/stellar/go/services/horizon/internal/toid"
@@ -162,7 +163,7 @@ func (s *IngestHistoryRangeStateTestSuite) TestRunTransactionProcessorsOnLedgerR
 
 	s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		errors.New("my error"),
 	).Once()
 
@@ -191,7 +192,7 @@ func (s *IngestHistoryRangeStateTestSuite) TestSuccess() {
 
 		s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 	}
@@ -221,7 +222,7 @@ func (s *IngestHistoryRangeStateTestSuite) TestSuccessOneLedger() {
 
 	s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 
@@ -252,7 +253,7 @@ func (s *IngestHistoryRangeStateTestSuite) TestCommitsWorkOnLedgerBackendFailure
 
 	s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 
@@ -412,7 +413,7 @@ func (s *ReingestHistoryRangeStateTestSuite) TestRunTransactionProcessorsOnLedge
 	s.runner.On("RunTransactionProcessorsOnLedger", meta).
 		Return(
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			errors.New("my error"),
 		).Once()
 	s.historyQ.On("Rollback").Return(nil).Once()
@@ -447,7 +448,7 @@ func (s *ReingestHistoryRangeStateTestSuite) TestCommitFails() {
 
 	s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 
@@ -486,7 +487,7 @@ func (s *ReingestHistoryRangeStateTestSuite) TestSuccess() {
 
 		s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 
@@ -521,7 +522,7 @@ func (s *ReingestHistoryRangeStateTestSuite) TestSuccessOneLedger() {
 
 	s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 	s.historyQ.On("Commit").Return(nil).Once()
@@ -568,7 +569,7 @@ func (s *ReingestHistoryRangeStateTestSuite) TestReingestRangeForce() {
 
 		s.runner.On("RunTransactionProcessorsOnLedger", meta).Return(
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 	}
@@ -76,7 +76,11 @@ type Config struct {
 	CaptiveCoreReuseStoragePath bool
 	CaptiveCoreToml             *ledgerbackend.CaptiveCoreToml
 	RemoteCaptiveCoreURL        string
-	NetworkPassphrase           string
+	// LedgerDir is a directory of ledgers exported by
+	// ledgerbackend.ExportLedgers. When it is set ledgers are read from it
+	// instead of stellar-core.
+	LedgerDir         string
+	NetworkPassphrase string
 
 	HistorySession           db.SessionInterface
 	HistoryArchiveURL        string
@@ -184,6 +188,54 @@ type system struct {
 	checkpointManager historyarchive.CheckpointManager
 }
 
+// NewLedgerBackend returns the ledger backend configured by config: the
+// ledgers directory, captive core, remote captive core or the stellar-core DB.
+func NewLedgerBackend(ctx context.Context, config Config) (ledgerbackend.LedgerBackend, error) {
+	if config.LedgerDir != "" {
+		ledgerBackend, err := ledgerbackend.NewFileBackend(config.LedgerDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating file backend")
+		}
+		return ledgerBackend, nil
+	}
+
+	if !config.EnableCaptiveCore {
+		ledgerBackend, err := ledgerbackend.NewDatabaseBackendFromSession(config.CoreSession.Clone(), config.NetworkPassphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating ledger backend")
+		}
+		return ledgerBackend, nil
+	}
+
+	if len(config.RemoteCaptiveCoreURL) > 0 {
+		ledgerBackend, err := ledgerbackend.NewRemoteCaptive(config.RemoteCaptiveCoreURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating captive core backend")
+		}
+		return ledgerBackend, nil
+	}
+
+	captiveConfig := ledgerbackend.CaptiveCoreConfig{
+		BinaryPath:          config.CaptiveCoreBinaryPath,
+		StoragePath:         config.CaptiveCoreStoragePath,
+		ReuseStoragePath:    config.CaptiveCoreReuseStoragePath,
+		Toml:                config.CaptiveCoreToml,
+		NetworkPassphrase:   config.NetworkPassphrase,
+		HistoryArchiveURLs:  []string{config.HistoryArchiveURL},
+		CheckpointFrequency: config.CheckpointFrequency,
+		Log:                 log.WithField("subservice", "stellar-core"),
+		Context:             ctx,
+	}
+	if config.HistorySession != nil {
+		captiveConfig.LedgerHashStore = ledgerbackend.NewHorizonDBLedgerHashStore(config.HistorySession)
+	}
+	ledgerBackend, err := ledgerbackend.NewCaptive(captiveConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating captive core backend")
+	}
+	return ledgerBackend, nil
+}
+
 func NewSystem(config Config) (System, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -200,42 +252,10 @@ func NewSystem(config Config) (System, error) {
 		return nil, errors.Wrap(err, "error creating history archive")
 	}
 
-	var ledgerBackend ledgerbackend.LedgerBackend
-	if config.EnableCaptiveCore {
-		if len(config.RemoteCaptiveCoreURL) > 0 {
-			ledgerBackend, err = ledgerbackend.NewRemoteCaptive(config.RemoteCaptiveCoreURL)
-			if err != nil {
-				cancel()
-				return nil, errors.Wrap(err, "error creating captive core backend")
-			}
-		} else {
-			logger := log.WithField("subservice", "stellar-core")
-			ledgerBackend, err = ledgerbackend.NewCaptive(
-				ledgerbackend.CaptiveCoreConfig{
-					BinaryPath:          config.CaptiveCoreBinaryPath,
-					StoragePath:         config.CaptiveCoreStoragePath,
-					ReuseStoragePath:    config.CaptiveCoreReuseStoragePath,
-					Toml:                config.CaptiveCoreToml,
-					NetworkPassphrase:   config.NetworkPassphrase,
-					HistoryArchiveURLs:  []string{config.HistoryArchiveURL},
-					CheckpointFrequency: config.CheckpointFrequency,
-					LedgerHashStore:     ledgerbackend.NewHorizonDBLedgerHashStore(config.HistorySession),
-					Log:                 logger,
-					Context:             ctx,
-				},
-			)
-			if err != nil {
-				cancel()
-				return nil, errors.Wrap(err, "error creating captive core backend")
-			}
-		}
-	} else {
-		coreSession := config.CoreSession.Clone()
-		ledgerBackend, err = ledgerbackend.NewDatabaseBackendFromSession(coreSession, config.NetworkPassphrase)
-		if err != nil {
-			cancel()
-			return nil, errors.Wrap(err, "error creating ledger backend")
-		}
+	ledgerBackend, err := NewLedgerBackend(ctx, config)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
 	historyQ := &history.Q{config.HistorySession.Clone()}
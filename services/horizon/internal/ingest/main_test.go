@@ -435,27 +435,27 @@ func (m *mockProcessorsRunner) RunHistoryArchiveIngestion(
 
 func (m *mockProcessorsRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	ingest.StatsChangeProcessorResults,
-	processorsRunDurations,
+	ingest.ProcessorsRunDurations,
 	processors.StatsLedgerTransactionProcessorResults,
-	processorsRunDurations,
+	ingest.ProcessorsRunDurations,
 	error,
 ) {
 	args := m.Called(ledger)
 	return args.Get(0).(ingest.StatsChangeProcessorResults),
-		args.Get(1).(processorsRunDurations),
+		args.Get(1).(ingest.ProcessorsRunDurations),
 		args.Get(2).(processors.StatsLedgerTransactionProcessorResults),
-		args.Get(3).(processorsRunDurations),
+		args.Get(3).(ingest.ProcessorsRunDurations),
 		args.Error(4)
 }
 
 func (m *mockProcessorsRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	processors.StatsLedgerTransactionProcessorResults,
-	processorsRunDurations,
+	ingest.ProcessorsRunDurations,
 	error,
 ) {
 	args := m.Called(ledger)
 	return args.Get(0).(processors.StatsLedgerTransactionProcessorResults),
-		args.Get(1).(processorsRunDurations),
+		args.Get(1).(ingest.ProcessorsRunDurations),
 		args.Error(2)
 }
 
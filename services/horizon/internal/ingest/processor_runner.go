@@ -21,16 +21,6 @@ const (
 	logFrequency         = 50000
 )
 
-type horizonChangeProcessor interface {
-	processors.ChangeProcessor
-	Commit(context.Context) error
-}
-
-type horizonTransactionProcessor interface {
-	processors.LedgerTransactionProcessor
-	Commit(context.Context) error
-}
-
 type statsChangeProcessor struct {
 	*ingest.StatsChangeProcessor
 }
@@ -59,14 +49,14 @@ type ProcessorRunnerInterface interface {
 	) (ingest.StatsChangeProcessorResults, error)
 	RunTransactionProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 		transactionStats processors.StatsLedgerTransactionProcessorResults,
-		transactionDurations processorsRunDurations,
+		transactionDurations ingest.ProcessorsRunDurations,
 		err error,
 	)
 	RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 		changeStats ingest.StatsChangeProcessorResults,
-		changeDurations processorsRunDurations,
+		changeDurations ingest.ProcessorsRunDurations,
 		transactionStats processors.StatsLedgerTransactionProcessorResults,
-		transactionDurations processorsRunDurations,
+		transactionDurations ingest.ProcessorsRunDurations,
 		err error,
 	)
 }
@@ -99,13 +89,13 @@ func buildChangeProcessor(
 	changeStats *ingest.StatsChangeProcessor,
 	source ingestionSource,
 	ledgerSequence uint32,
-) *groupChangeProcessors {
+) *ingest.GroupChangeProcessors {
 	statsChangeProcessor := &statsChangeProcessor{
 		StatsChangeProcessor: changeStats,
 	}
 
 	useLedgerCache := source == ledgerSource
-	return newGroupChangeProcessors([]horizonChangeProcessor{
+	return ingest.NewGroupChangeProcessors([]ingest.ChangeProcessor{
 		statsChangeProcessor,
 		processors.NewAccountDataProcessor(historyQ),
 		processors.NewAccountsProcessor(historyQ),
@@ -121,13 +111,13 @@ func buildChangeProcessor(
 func (s *ProcessorRunner) buildTransactionProcessor(
 	ledgerTransactionStats *processors.StatsLedgerTransactionProcessor,
 	ledger xdr.LedgerHeaderHistoryEntry,
-) *groupTransactionProcessors {
+) *ingest.GroupTransactionProcessors {
 	statsLedgerTransactionProcessor := &statsLedgerTransactionProcessor{
 		StatsLedgerTransactionProcessor: ledgerTransactionStats,
 	}
 
 	sequence := uint32(ledger.Header.LedgerSeq)
-	return newGroupTransactionProcessors([]horizonTransactionProcessor{
+	return ingest.NewGroupTransactionProcessors([]ingest.LedgerTransactionProcessor{
 		statsLedgerTransactionProcessor,
 		processors.NewEffectProcessor(s.historyQ, sequence),
 		processors.NewLedgerProcessor(s.historyQ, ledger, CurrentVersion),
@@ -214,7 +204,7 @@ func (s *ProcessorRunner) RunHistoryArchiveIngestion(
 		log.WithField("sequence", checkpointLedger).
 			Info("Processing entries from History Archive Snapshot")
 
-		err = processors.StreamChanges(s.ctx, changeProcessor, newloggingChangeReader(
+		err = ingest.StreamChanges(s.ctx, changeProcessor, newloggingChangeReader(
 			changeReader,
 			"historyArchive",
 			checkpointLedger,
@@ -234,7 +224,7 @@ func (s *ProcessorRunner) RunHistoryArchiveIngestion(
 }
 
 func (s *ProcessorRunner) runChangeProcessorOnLedger(
-	changeProcessor horizonChangeProcessor, ledger xdr.LedgerCloseMeta,
+	changeProcessor ingest.ChangeProcessor, ledger xdr.LedgerCloseMeta,
 ) error {
 	var changeReader ingest.ChangeReader
 	var err error
@@ -249,7 +239,7 @@ func (s *ProcessorRunner) runChangeProcessorOnLedger(
 		logFrequency,
 		s.logMemoryStats,
 	)
-	if err = processors.StreamChanges(s.ctx, changeProcessor, changeReader); err != nil {
+	if err = ingest.StreamChanges(s.ctx, changeProcessor, changeReader); err != nil {
 		return errors.Wrap(err, "Error streaming changes from ledger")
 	}
 
@@ -263,7 +253,7 @@ func (s *ProcessorRunner) runChangeProcessorOnLedger(
 
 func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	transactionStats processors.StatsLedgerTransactionProcessorResults,
-	transactionDurations processorsRunDurations,
+	transactionDurations ingest.ProcessorsRunDurations,
 	err error,
 ) {
 	var (
@@ -283,7 +273,7 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 	}
 
 	groupTransactionProcessors := s.buildTransactionProcessor(&ledgerTransactionStats, transactionReader.GetHeader())
-	err = processors.StreamLedgerTransactions(s.ctx, groupTransactionProcessors, transactionReader)
+	err = ingest.StreamLedgerTransactions(s.ctx, groupTransactionProcessors, transactionReader)
 	if err != nil {
 		err = errors.Wrap(err, "Error streaming changes from ledger")
 		return
@@ -296,15 +286,15 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 	}
 
 	transactionStats = ledgerTransactionStats.GetResults()
-	transactionDurations = groupTransactionProcessors.processorsRunDurations
+	transactionDurations = groupTransactionProcessors.ProcessorsRunDurations
 	return
 }
 
 func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	changeStats ingest.StatsChangeProcessorResults,
-	changeDurations processorsRunDurations,
+	changeDurations ingest.ProcessorsRunDurations,
 	transactionStats processors.StatsLedgerTransactionProcessorResults,
-	transactionDurations processorsRunDurations,
+	transactionDurations ingest.ProcessorsRunDurations,
 	err error,
 ) {
 	changeStatsProcessor := ingest.StatsChangeProcessor{}
@@ -321,7 +311,7 @@ func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	}
 
 	changeStats = changeStatsProcessor.GetResults()
-	changeDurations = groupChangeProcessors.processorsRunDurations
+	changeDurations = groupChangeProcessors.ProcessorsRunDurations
 
 	transactionStats, transactionDurations, err =
 		s.RunTransactionProcessorsOnLedger(ledger)
@@ -174,19 +174,19 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 
 	stats := &ingest.StatsChangeProcessor{}
 	processor := buildChangeProcessor(runner.historyQ, stats, ledgerSource, 123)
-	assert.IsType(t, &groupChangeProcessors{}, processor)
-
-	assert.IsType(t, &statsChangeProcessor{}, processor.processors[0])
-	assert.IsType(t, &processors.AccountDataProcessor{}, processor.processors[1])
-	assert.IsType(t, &processors.AccountsProcessor{}, processor.processors[2])
-	assert.IsType(t, &processors.OffersProcessor{}, processor.processors[3])
-	assert.IsType(t, &processors.AssetStatsProcessor{}, processor.processors[4])
-	assert.True(t, reflect.ValueOf(processor.processors[4]).
+	assert.IsType(t, &ingest.GroupChangeProcessors{}, processor)
+
+	assert.IsType(t, &statsChangeProcessor{}, processor.Processors()[0])
+	assert.IsType(t, &processors.AccountDataProcessor{}, processor.Processors()[1])
+	assert.IsType(t, &processors.AccountsProcessor{}, processor.Processors()[2])
+	assert.IsType(t, &processors.OffersProcessor{}, processor.Processors()[3])
+	assert.IsType(t, &processors.AssetStatsProcessor{}, processor.Processors()[4])
+	assert.True(t, reflect.ValueOf(processor.Processors()[4]).
 		Elem().FieldByName("useLedgerEntryCache").Bool())
-	assert.IsType(t, &processors.SignersProcessor{}, processor.processors[5])
-	assert.True(t, reflect.ValueOf(processor.processors[5]).
+	assert.IsType(t, &processors.SignersProcessor{}, processor.Processors()[5])
+	assert.True(t, reflect.ValueOf(processor.Processors()[5]).
 		Elem().FieldByName("useLedgerEntryCache").Bool())
-	assert.IsType(t, &processors.TrustLinesProcessor{}, processor.processors[6])
+	assert.IsType(t, &processors.TrustLinesProcessor{}, processor.Processors()[6])
 
 	runner = ProcessorRunner{
 		ctx:      ctx,
@@ -194,19 +194,19 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 	}
 
 	processor = buildChangeProcessor(runner.historyQ, stats, historyArchiveSource, 456)
-	assert.IsType(t, &groupChangeProcessors{}, processor)
-
-	assert.IsType(t, &statsChangeProcessor{}, processor.processors[0])
-	assert.IsType(t, &processors.AccountDataProcessor{}, processor.processors[1])
-	assert.IsType(t, &processors.AccountsProcessor{}, processor.processors[2])
-	assert.IsType(t, &processors.OffersProcessor{}, processor.processors[3])
-	assert.IsType(t, &processors.AssetStatsProcessor{}, processor.processors[4])
-	assert.False(t, reflect.ValueOf(processor.processors[4]).
+	assert.IsType(t, &ingest.GroupChangeProcessors{}, processor)
+
+	assert.IsType(t, &statsChangeProcessor{}, processor.Processors()[0])
+	assert.IsType(t, &processors.AccountDataProcessor{}, processor.Processors()[1])
+	assert.IsType(t, &processors.AccountsProcessor{}, processor.Processors()[2])
+	assert.IsType(t, &processors.OffersProcessor{}, processor.Processors()[3])
+	assert.IsType(t, &processors.AssetStatsProcessor{}, processor.Processors()[4])
+	assert.False(t, reflect.ValueOf(processor.Processors()[4]).
 		Elem().FieldByName("useLedgerEntryCache").Bool())
-	assert.IsType(t, &processors.SignersProcessor{}, processor.processors[5])
-	assert.False(t, reflect.ValueOf(processor.processors[5]).
+	assert.IsType(t, &processors.SignersProcessor{}, processor.Processors()[5])
+	assert.False(t, reflect.ValueOf(processor.Processors()[5]).
 		Elem().FieldByName("useLedgerEntryCache").Bool())
-	assert.IsType(t, &processors.TrustLinesProcessor{}, processor.processors[6])
+	assert.IsType(t, &processors.TrustLinesProcessor{}, processor.Processors()[6])
 }
 
 func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
@@ -230,15 +230,15 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 	stats := &processors.StatsLedgerTransactionProcessor{}
 	ledger := xdr.LedgerHeaderHistoryEntry{}
 	processor := runner.buildTransactionProcessor(stats, ledger)
-	assert.IsType(t, &groupTransactionProcessors{}, processor)
-
-	assert.IsType(t, &statsLedgerTransactionProcessor{}, processor.processors[0])
-	assert.IsType(t, &processors.EffectProcessor{}, processor.processors[1])
-	assert.IsType(t, &processors.LedgersProcessor{}, processor.processors[2])
-	assert.IsType(t, &processors.OperationProcessor{}, processor.processors[3])
-	assert.IsType(t, &processors.TradeProcessor{}, processor.processors[4])
-	assert.IsType(t, &processors.ParticipantsProcessor{}, processor.processors[5])
-	assert.IsType(t, &processors.TransactionProcessor{}, processor.processors[6])
+	assert.IsType(t, &ingest.GroupTransactionProcessors{}, processor)
+
+	assert.IsType(t, &statsLedgerTransactionProcessor{}, processor.Processors()[0])
+	assert.IsType(t, &processors.EffectProcessor{}, processor.Processors()[1])
+	assert.IsType(t, &processors.LedgersProcessor{}, processor.Processors()[2])
+	assert.IsType(t, &processors.OperationProcessor{}, processor.Processors()[3])
+	assert.IsType(t, &processors.TradeProcessor{}, processor.Processors()[4])
+	assert.IsType(t, &processors.ParticipantsProcessor{}, processor.Processors()[5])
+	assert.IsType(t, &processors.TransactionProcessor{}, processor.Processors()[6])
 }
 
 func TestProcessorRunnerRunAllProcessorsOnLedger(t *testing.T) {
@@ -161,6 +161,3 @@ func (stats *StatsLedgerTransactionProcessorResults) Map() map[string]interface{
 		"stats_operations_liquidity_pool_withdraw":          stats.OperationsLiquidityPoolWithdraw,
 	}
 }
-
-// Ensure the StatsChangeProcessor conforms to the ChangeProcessor interface.
-var _ LedgerTransactionProcessor = (*StatsLedgerTransactionProcessor)(nil)
@@ -271,9 +271,9 @@ func (s *ResumeTestTestSuite) mockSuccessfulIngestion() {
 		}).
 		Return(
 			ingest.StatsChangeProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, uint32(101)).Return(nil).Once()
@@ -347,9 +347,9 @@ func (s *ResumeTestTestSuite) TestErrorSettingCursorIgnored() {
 		}).
 		Return(
 			ingest.StatsChangeProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, uint32(101)).Return(nil).Once()
@@ -12,7 +12,6 @@ import (
 
 	"github.com/stellar/go/historyarchive"
 	"github.com/stellar/go/ingest"
-	"github.com/stellar/go/services/horizon/internal/ingest/processors"
 	logpkg "github.com/stellar/go/support/log"
 	"github.com/stellar/go/xdr"
 )
@@ -204,7 +203,7 @@ func TestUpdateSampleChanges(t *testing.T) {
 	}
 
 	changeStats := &ingest.StatsChangeProcessor{}
-	err = processors.StreamChanges(changeStats, reader)
+	err = ingest.StreamChanges(context.Background(), statsChangeProcessor{changeStats}, reader)
 	if err != nil {
 		t.Fatalf("could not stream changes: %v", err)
 	}
@@ -97,9 +97,9 @@ func (s *StressTestStateTestSuite) TestRunAllProcessorsOnLedgerReturnsError() {
 
 	s.runner.On("RunAllProcessorsOnLedger", mock.AnythingOfType("xdr.LedgerCloseMeta")).Return(
 		ingest.StatsChangeProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		errors.New("my error"),
 	).Once()
 
@@ -112,9 +112,9 @@ func (s *StressTestStateTestSuite) TestUpdateLastLedgerIngestReturnsError() {
 	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(0), nil).Once()
 	s.runner.On("RunAllProcessorsOnLedger", mock.AnythingOfType("xdr.LedgerCloseMeta")).Return(
 		ingest.StatsChangeProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, uint32(1)).Return(errors.New("my error")).Once()
@@ -128,9 +128,9 @@ func (s *StressTestStateTestSuite) TestCommitReturnsError() {
 	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(0), nil).Once()
 	s.runner.On("RunAllProcessorsOnLedger", mock.AnythingOfType("xdr.LedgerCloseMeta")).Return(
 		ingest.StatsChangeProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, uint32(1)).Return(nil).Once()
@@ -145,9 +145,9 @@ func (s *StressTestStateTestSuite) TestSucceeds() {
 	s.historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(0), nil).Once()
 	s.runner.On("RunAllProcessorsOnLedger", mock.AnythingOfType("xdr.LedgerCloseMeta")).Return(
 		ingest.StatsChangeProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		processors.StatsLedgerTransactionProcessorResults{},
-		processorsRunDurations{},
+		ingest.ProcessorsRunDurations{},
 		nil,
 	).Once()
 	s.historyQ.On("UpdateLastLedgerIngest", s.ctx, uint32(1)).Return(nil).Once()
@@ -207,9 +207,9 @@ func (s *VerifyRangeStateTestSuite) TestSuccess() {
 
 		s.runner.On("RunAllProcessorsOnLedger", meta).Return(
 			ingest.StatsChangeProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 		s.historyQ.On("UpdateLastLedgerIngest", s.ctx, i).Return(nil).Once()
@@ -266,9 +266,9 @@ func (s *VerifyRangeStateTestSuite) TestSuccessWithVerify() {
 
 		s.runner.On("RunAllProcessorsOnLedger", meta).Return(
 			ingest.StatsChangeProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			processors.StatsLedgerTransactionProcessorResults{},
-			processorsRunDurations{},
+			ingest.ProcessorsRunDurations{},
 			nil,
 		).Once()
 		s.historyQ.On("UpdateLastLedgerIngest", s.ctx, i).Return(nil).Once()
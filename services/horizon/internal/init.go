@@ -10,6 +10,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stellar/go/exp/orderbook"
 	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
 	"github.com/stellar/go/services/horizon/internal/ingest"
 	"github.com/stellar/go/services/horizon/internal/simplepath"
 	"github.com/stellar/go/services/horizon/internal/txsub"
@@ -115,6 +116,19 @@ func initPathFinder(app *App) {
 	app.paths = simplepath.NewInMemoryFinder(orderBookGraph)
 }
 
+// feedBufferSize is the number of ledgers a streaming request can fall behind
+// the feed before it has to catch up from the DB.
+const feedBufferSize = 16
+
+func initFeed(app *App) {
+	app.feed = feed.New(feedBufferSize)
+	app.feedStream = ingest.NewFeedStream(
+		&history.Q{app.HorizonSession()},
+		app.feed,
+		app.config.SSEUpdateFrequency,
+	)
+}
+
 // initSentry initialized the default sentry client with the configured DSN
 func initSentry(app *App) {
 	if app.config.SentryDSN == "" {
@@ -230,6 +244,7 @@ func initDbMetrics(app *App) {
 	app.prometheusRegistry.MustRegister(app.coreSupportedProtocolVersion)
 
 	app.prometheusRegistry.MustRegister(app.orderBookStream.LatestLedgerGauge)
+	app.prometheusRegistry.MustRegister(app.feedStream.LatestLedgerGauge)
 }
 
 // initGoMetrics registers the Go collector provided by prometheus package which
@@ -10,9 +10,9 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi"
-	"github.com/stellar/throttled"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/throttled/throttled"
 
 	"github.com/stellar/go/services/horizon/internal/actions"
 	horizonContext "github.com/stellar/go/services/horizon/internal/context"
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/throttled/throttled"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// AnonymousTierName is the name of the tier of the clients without API key.
+const AnonymousTierName = "anonymous"
+
+type configFile struct {
+	Tiers map[string]tierFile `toml:"tiers"`
+	Keys  map[string]string   `toml:"keys"`
+	Costs map[string]int      `toml:"costs"`
+}
+
+type tierFile struct {
+	PerHour    int `toml:"per_hour"`
+	MaxBurst   int `toml:"max_burst"`
+	MaxStreams int `toml:"max_streams"`
+}
+
+// ReadConfig adds the tiers, the API keys and the costs of the TOML file at
+// path to the config:
+//
+//	[tiers.partner]
+//	per_hour = 100000   # requests per hour, unlimited if 0
+//	max_burst = 1000
+//	max_streams = 100   # concurrent streams per instance, unlimited if 0
+//
+//	[keys]
+//	"<API key>" = "partner"
+//
+//	[costs]
+//	"/paths" = 20
+//
+// A tier named anonymous replaces the anonymous tier of the config.
+func ReadConfig(path string, config *Config) error {
+	var file configFile
+	metadata, err := toml.DecodeFile(path, &file)
+	if err != nil {
+		return errors.Wrap(err, "could not decode rate limit config")
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		return errors.New("unknown fields in rate limit config: " + fmt.Sprintf("%+v", undecoded))
+	}
+
+	tiers := map[string]*Tier{}
+	for name, t := range file.Tiers {
+		tier := &Tier{Name: name, MaxStreams: t.MaxStreams}
+		if t.PerHour != 0 {
+			tier.Quota = &throttled.RateQuota{
+				MaxRate:  throttled.PerHour(t.PerHour),
+				MaxBurst: t.MaxBurst,
+			}
+		}
+		tiers[name] = tier
+	}
+	if anonymous, ok := tiers[AnonymousTierName]; ok {
+		config.Anonymous = *anonymous
+	}
+
+	keys := map[string]*Tier{}
+	for apiKey, tier := range config.Tiers {
+		keys[apiKey] = tier
+	}
+	for apiKey, name := range file.Keys {
+		tier, ok := tiers[name]
+		if !ok {
+			return errors.Errorf("unknown tier %s in rate limit config", name)
+		}
+		keys[apiKey] = tier
+	}
+	config.Tiers = keys
+
+	costs := map[string]int{}
+	for prefix, cost := range config.Costs {
+		costs[prefix] = cost
+	}
+	for prefix, cost := range file.Costs {
+		costs[prefix] = cost
+	}
+	config.Costs = costs
+	return nil
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rate-limit.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestReadConfig(t *testing.T) {
+	path := writeConfig(t, `
+[tiers.partner]
+per_hour = 100000
+max_burst = 1000
+max_streams = 100
+
+[tiers.internal]
+max_streams = 1000
+
+[tiers.anonymous]
+per_hour = 3600
+max_burst = 10
+max_streams = 5
+
+[keys]
+"key-a" = "partner"
+"key-b" = "partner"
+"key-c" = "internal"
+
+[costs]
+"/paths" = 20
+"/liquidity_pools" = 2
+`)
+
+	config := Config{
+		Anonymous: Tier{Name: AnonymousTierName, Quota: perHour(1000, 100)},
+		Costs:     DefaultCosts,
+	}
+	require.NoError(t, ReadConfig(path, &config))
+
+	assert.Equal(t, Tier{Name: AnonymousTierName, Quota: perHour(3600, 10), MaxStreams: 5}, config.Anonymous)
+	partner := &Tier{Name: "partner", Quota: perHour(100000, 1000), MaxStreams: 100}
+	assert.Equal(t, map[string]*Tier{
+		"key-a": partner,
+		"key-b": partner,
+		"key-c": {Name: "internal", MaxStreams: 1000},
+	}, config.Tiers)
+	assert.Equal(t, map[string]int{
+		"/paths":              20,
+		"/trade_aggregations": 5,
+		"/graphql":            20,
+		"/liquidity_pools":    2,
+	}, config.Costs)
+	// The default costs are not modified.
+	assert.Equal(t, 10, DefaultCosts["/paths"])
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	path := writeConfig(t, `
+[keys]
+"key-a" = "partner"
+`)
+	assert.EqualError(t, ReadConfig(path, &Config{}), "unknown tier partner in rate limit config")
+
+	path = writeConfig(t, `
+[tiers.partner]
+per_minute = 100
+`)
+	assert.EqualError(t, ReadConfig(path, &Config{}), "unknown fields in rate limit config: [tiers.partner.per_minute]")
+
+	err := ReadConfig(filepath.Join(t.TempDir(), "missing.toml"), &Config{})
+	assert.Error(t, err)
+}
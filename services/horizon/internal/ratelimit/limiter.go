@@ -0,0 +1,211 @@
+// Package ratelimit limits the rate of the requests of Horizon clients. A
+// client is identified by its API key, or by its IP address when it has none,
+// and is rate limited according to the quotas of its tier.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/throttled/throttled"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// ErrUnknownAPIKey is returned when identifying a client with an API key which
+// is not configured.
+var ErrUnknownAPIKey = errors.New("unknown API key")
+
+// DefaultCosts are the costs of the expensive endpoints, by path prefix. They
+// apply when a rate limit config file is used.
+var DefaultCosts = map[string]int{
+	"/paths":              10,
+	"/trade_aggregations": 5,
+	"/graphql":            20,
+}
+
+// Tier is the set of quotas applying to a kind of clients.
+type Tier struct {
+	Name string
+	// Quota is the quota of requests of each client, the requests are not
+	// rate limited when it is nil.
+	Quota *throttled.RateQuota
+	// MaxStreams is the number of concurrent streaming connections allowed
+	// for each client by each Horizon instance, they are not limited when it
+	// is 0.
+	MaxStreams int
+}
+
+// Client is a client of Horizon rate limited according to its tier.
+type Client struct {
+	// Key identifies the client in the store.
+	Key  string
+	Tier *Tier
+}
+
+// Config is the configuration of a Limiter.
+type Config struct {
+	// Anonymous is the tier of the clients without API key, identified by
+	// their IP address.
+	Anonymous Tier
+	// Tiers are the tiers of the clients with an API key, by API key.
+	Tiers map[string]*Tier
+	// Costs are the number of requests counted for the requests of a path,
+	// by path prefix. The requests of other paths count as one request.
+	Costs map[string]int
+}
+
+// Limiter rate limits the requests of the clients with a GCRA rate limiter
+// for each tier. The quotas of requests are shared by all the Limiters using
+// the same store, the concurrent streaming connections are counted by each
+// Limiter. It is safe for concurrent use.
+type Limiter struct {
+	anonymous *Tier
+	tiers     map[string]*Tier
+	// rateLimiters are the rate limiters of the tiers with a quota.
+	rateLimiters map[*Tier]*throttled.GCRARateLimiter
+	// costs are sorted by decreasing prefix length.
+	costs []pathCost
+
+	lock    sync.Mutex
+	streams map[string]int
+}
+
+type pathCost struct {
+	prefix string
+	cost   int
+}
+
+type contextKey struct{}
+
+// NewLimiter constructs a Limiter keeping the state of the quotas in the
+// store.
+func NewLimiter(config Config, store throttled.GCRAStore) (*Limiter, error) {
+	anonymous := config.Anonymous
+	l := &Limiter{
+		anonymous:    &anonymous,
+		tiers:        map[string]*Tier{},
+		rateLimiters: map[*Tier]*throttled.GCRARateLimiter{},
+		streams:      map[string]int{},
+	}
+
+	tiers := []*Tier{l.anonymous}
+	for apiKey, tier := range config.Tiers {
+		// The API keys are hashed so that they are not kept in the store.
+		l.tiers[hashAPIKey(apiKey)] = tier
+		tiers = append(tiers, tier)
+	}
+	for _, tier := range tiers {
+		if tier.MaxStreams < 0 {
+			return nil, errors.Errorf("invalid max streams of tier %s: %d", tier.Name, tier.MaxStreams)
+		}
+		if tier.Quota == nil || l.rateLimiters[tier] != nil {
+			continue
+		}
+		rateLimiter, err := throttled.NewGCRARateLimiter(store, *tier.Quota)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid quota of tier %s", tier.Name)
+		}
+		l.rateLimiters[tier] = rateLimiter
+	}
+
+	for prefix, cost := range config.Costs {
+		if !strings.HasPrefix(prefix, "/") || cost < 1 {
+			return nil, errors.Errorf("invalid cost of %s: %d", prefix, cost)
+		}
+		l.costs = append(l.costs, pathCost{prefix: strings.TrimSuffix(prefix, "/"), cost: cost})
+	}
+	sort.Slice(l.costs, func(i, j int) bool {
+		return len(l.costs[i].prefix) > len(l.costs[j].prefix)
+	})
+	return l, nil
+}
+
+func hashAPIKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// Identify returns the client with the API key or, if the API key is empty,
+// the anonymous client with the IP address. It returns ErrUnknownAPIKey if the
+// API key is not configured.
+func (l *Limiter) Identify(apiKey, ip string) (Client, error) {
+	if apiKey == "" {
+		return Client{Key: "ip:" + ip, Tier: l.anonymous}, nil
+	}
+	hash := hashAPIKey(apiKey)
+	tier, ok := l.tiers[hash]
+	if !ok {
+		return Client{}, ErrUnknownAPIKey
+	}
+	return Client{Key: "key:" + hash, Tier: tier}, nil
+}
+
+// Cost returns the number of requests counted for a request of the path.
+func (l *Limiter) Cost(path string) int {
+	for _, c := range l.costs {
+		if path == c.prefix || strings.HasPrefix(path, c.prefix+"/") {
+			return c.cost
+		}
+	}
+	return 1
+}
+
+// Limit counts cost requests of the client with the rate limiter of its tier,
+// unless they exceed its quota. It returns true if they are rate limited.
+func (l *Limiter) Limit(client Client, cost int) (bool, throttled.RateLimitResult, error) {
+	rateLimiter, ok := l.rateLimiters[client.Tier]
+	if !ok {
+		return false, throttled.RateLimitResult{Limit: -1, Remaining: -1, ResetAfter: -1, RetryAfter: -1}, nil
+	}
+	limited, result, err := rateLimiter.RateLimit(client.Key, cost)
+	if err != nil {
+		return false, result, errors.Wrap(err, "could not update rate limit state")
+	}
+	return limited, result, nil
+}
+
+// OpenStream counts a streaming connection of the client. It returns false if
+// the client has reached the number of concurrent streaming connections of its
+// tier, otherwise close must be called once the streaming connection is
+// closed. Streams are counted in memory and not in the store, so the limit
+// applies to every Horizon instance separately.
+func (l *Limiter) OpenStream(client Client) (close func(), ok bool) {
+	if client.Tier.MaxStreams == 0 {
+		return func() {}, true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.streams[client.Key] >= client.Tier.MaxStreams {
+		return nil, false
+	}
+	l.streams[client.Key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			l.streams[client.Key]--
+			if l.streams[client.Key] == 0 {
+				delete(l.streams, client.Key)
+			}
+		})
+	}, true
+}
+
+// NewContext returns a context carrying the client of a request.
+func NewContext(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// FromContext returns the client carried by the context, if any.
+func FromContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(contextKey{}).(Client)
+	return client, ok
+}
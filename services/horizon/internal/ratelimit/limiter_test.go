@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/throttled/throttled"
+	"github.com/throttled/throttled/store/memstore"
+)
+
+// fixedTimeStore is a MemStore whose clock only moves when the test moves it.
+type fixedTimeStore struct {
+	*memstore.MemStore
+	now time.Time
+}
+
+func (s *fixedTimeStore) GetWithTime(key string) (int64, time.Time, error) {
+	value, _, err := s.MemStore.GetWithTime(key)
+	return value, s.now, err
+}
+
+func newTestLimiter(t *testing.T, config Config) (*Limiter, *time.Time) {
+	store, err := memstore.New(100)
+	require.NoError(t, err)
+	fixed := &fixedTimeStore{MemStore: store, now: time.Unix(1600000000, 0)}
+
+	limiter, err := NewLimiter(config, fixed)
+	require.NoError(t, err)
+	return limiter, &fixed.now
+}
+
+func perHour(n, maxBurst int) *throttled.RateQuota {
+	return &throttled.RateQuota{MaxRate: throttled.PerHour(n), MaxBurst: maxBurst}
+}
+
+func TestLimit(t *testing.T) {
+	limiter, now := newTestLimiter(t, Config{
+		Anonymous: Tier{Name: AnonymousTierName, Quota: perHour(10, 9)},
+	})
+	client, err := limiter.Identify("", "127.0.0.1")
+	require.NoError(t, err)
+
+	limited, result, err := limiter.Limit(client, 1)
+	require.NoError(t, err)
+	assert.False(t, limited)
+	assert.Equal(t, throttled.RateLimitResult{Limit: 10, Remaining: 9, ResetAfter: 6 * time.Minute, RetryAfter: -1}, result)
+
+	limited, result, err = limiter.Limit(client, 8)
+	require.NoError(t, err)
+	assert.False(t, limited)
+	assert.Equal(t, 1, result.Remaining)
+
+	limited, result, err = limiter.Limit(client, 2)
+	require.NoError(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Equal(t, 6*time.Minute, result.RetryAfter)
+
+	// Other clients have their own quota.
+	other, err := limiter.Identify("", "127.0.0.2")
+	require.NoError(t, err)
+	limited, _, err = limiter.Limit(other, 10)
+	require.NoError(t, err)
+	assert.False(t, limited)
+
+	*now = now.Add(6 * time.Minute)
+	limited, result, err = limiter.Limit(client, 2)
+	require.NoError(t, err)
+	assert.False(t, limited)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Hour, result.ResetAfter)
+}
+
+func TestLimitUnlimitedTier(t *testing.T) {
+	limiter, _ := newTestLimiter(t, Config{
+		Anonymous: Tier{Name: AnonymousTierName},
+	})
+	client, err := limiter.Identify("", "127.0.0.1")
+	require.NoError(t, err)
+
+	limited, result, err := limiter.Limit(client, 1000)
+	require.NoError(t, err)
+	assert.False(t, limited)
+	assert.Equal(t, throttled.RateLimitResult{Limit: -1, Remaining: -1, ResetAfter: -1, RetryAfter: -1}, result)
+}
+
+func TestIdentify(t *testing.T) {
+	partner := &Tier{Name: "partner", Quota: perHour(1000, 100)}
+	limiter, _ := newTestLimiter(t, Config{
+		Anonymous: Tier{Name: AnonymousTierName, Quota: perHour(10, 0)},
+		Tiers:     map[string]*Tier{"secret": partner},
+	})
+
+	client, err := limiter.Identify("", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "ip:127.0.0.1", client.Key)
+	assert.Equal(t, AnonymousTierName, client.Tier.Name)
+
+	client, err = limiter.Identify("secret", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, partner, client.Tier)
+	assert.NotContains(t, client.Key, "secret")
+
+	_, err = limiter.Identify("wrong", "127.0.0.1")
+	assert.Equal(t, ErrUnknownAPIKey, err)
+}
+
+func TestNewLimiterInvalidConfig(t *testing.T) {
+	_, err := NewLimiter(Config{Anonymous: Tier{Name: AnonymousTierName, Quota: &throttled.RateQuota{}}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid quota of tier anonymous: ")
+
+	_, err = NewLimiter(Config{Costs: map[string]int{"paths": 10}}, nil)
+	assert.EqualError(t, err, "invalid cost of paths: 10")
+}
+
+func TestCost(t *testing.T) {
+	limiter, _ := newTestLimiter(t, Config{
+		Costs: map[string]int{
+			"/paths":             10,
+			"/paths/strict-send": 20,
+		},
+	})
+
+	assert.Equal(t, 10, limiter.Cost("/paths"))
+	assert.Equal(t, 10, limiter.Cost("/paths/strict-receive"))
+	assert.Equal(t, 20, limiter.Cost("/paths/strict-send"))
+	assert.Equal(t, 1, limiter.Cost("/pathsx"))
+	assert.Equal(t, 1, limiter.Cost("/accounts"))
+}
+
+func TestOpenStream(t *testing.T) {
+	limiter, _ := newTestLimiter(t, Config{
+		Anonymous: Tier{Name: AnonymousTierName, MaxStreams: 2},
+	})
+	client, err := limiter.Identify("", "127.0.0.1")
+	require.NoError(t, err)
+
+	close1, ok := limiter.OpenStream(client)
+	assert.True(t, ok)
+	close2, ok := limiter.OpenStream(client)
+	assert.True(t, ok)
+	_, ok = limiter.OpenStream(client)
+	assert.False(t, ok)
+
+	other, err := limiter.Identify("", "127.0.0.2")
+	require.NoError(t, err)
+	_, ok = limiter.OpenStream(other)
+	assert.True(t, ok)
+
+	close1()
+	// Closing twice has no effect.
+	close1()
+	_, ok = limiter.OpenStream(client)
+	assert.True(t, ok)
+	_, ok = limiter.OpenStream(client)
+	assert.False(t, ok)
+
+	close2()
+	assert.Equal(t, 1, limiter.streams["ip:127.0.0.1"])
+}
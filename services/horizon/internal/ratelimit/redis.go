@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/throttled/throttled/store/redigostore"
+
+	"github.com/stellar/go/support/errors"
+)
+
+const (
+	redisTimeout   = 5 * time.Second
+	redisIdleConns = 16
+)
+
+// NewRedisStore constructs a throttled store keeping the state of the quotas
+// in a server speaking the Redis protocol, so that the quotas are shared by
+// several Horizon instances. The URL is in the form
+// redis://[:password@]host:port[/db], the rediss scheme connects with TLS.
+// The server must support EVAL and its clock is used by all the instances.
+func NewRedisStore(redisURL string) (*redigostore.RedigoStore, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse redis URL")
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, errors.Errorf("invalid redis URL scheme %s", u.Scheme)
+	}
+	// The store selects the database on every connection.
+	db := 0
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		if db, err = strconv.Atoi(path); err != nil {
+			return nil, errors.Errorf("invalid redis database %s", path)
+		}
+	}
+
+	pool := &redis.Pool{
+		MaxIdle: redisIdleConns,
+		Dial: func() (redis.Conn, error) {
+			conn, err := redis.DialURL(redisURL,
+				redis.DialConnectTimeout(redisTimeout),
+				redis.DialReadTimeout(redisTimeout),
+				redis.DialWriteTimeout(redisTimeout),
+			)
+			return conn, errors.Wrap(err, "could not connect to redis")
+		},
+	}
+	return redigostore.New(pool, "", db)
+}
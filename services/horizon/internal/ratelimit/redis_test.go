@@ -0,0 +1,235 @@
+package ratelimit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/throttled/throttled/store/storetest"
+)
+
+// redisStandIn is a server speaking the Redis protocol, supporting the
+// commands sent by the store of NewRedisStore.
+type redisStandIn struct {
+	listener net.Listener
+	password string
+
+	lock     sync.Mutex
+	values   map[string]string
+	expiries map[string]time.Time
+	commands []string
+}
+
+func newRedisStandIn(t *testing.T, password string) *redisStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &redisStandIn{
+		listener: listener,
+		password: password,
+		values:   map[string]string{},
+		expiries: map[string]time.Time{},
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *redisStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		var reply string
+		if args[0] == "AUTH" {
+			authenticated = len(args) == 2 && args[1] == s.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		} else if !authenticated {
+			reply = "-NOAUTH Authentication required.\r\n"
+		} else {
+			reply = s.execute(args)
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var length int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &length); err != nil {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
+
+func (s *redisStandIn) execute(args []string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commands = append(s.commands, args[0])
+
+	now := time.Now()
+	for key, expiry := range s.expiries {
+		if !now.Before(expiry) {
+			delete(s.values, key)
+			delete(s.expiries, key)
+		}
+	}
+
+	switch {
+	case args[0] == "SELECT" && len(args) == 2:
+		return "+OK\r\n"
+	case args[0] == "TIME" && len(args) == 1:
+		seconds := strconv.FormatInt(now.Unix(), 10)
+		microseconds := strconv.Itoa(now.Nanosecond() / 1000)
+		return fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(seconds), seconds, len(microseconds), microseconds)
+	case args[0] == "GET" && len(args) == 2:
+		value, ok := s.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case args[0] == "SETNX" && len(args) == 3:
+		if _, ok := s.values[args[1]]; ok {
+			return ":0\r\n"
+		}
+		s.values[args[1]] = args[2]
+		return ":1\r\n"
+	case args[0] == "EXPIRE" && len(args) == 3:
+		if _, ok := s.values[args[1]]; !ok {
+			return ":0\r\n"
+		}
+		s.expire(args[1], args[2])
+		return ":1\r\n"
+	case args[0] == "EVAL" && len(args) == 7 && args[2] == "1":
+		// The compare and swap script of the store.
+		value, ok := s.values[args[3]]
+		if !ok {
+			return "-key does not exist\r\n"
+		}
+		if value != args[4] {
+			return ":0\r\n"
+		}
+		s.values[args[3]] = args[5]
+		s.expire(args[3], args[6])
+		return ":1\r\n"
+	default:
+		return "-ERR unsupported command\r\n"
+	}
+}
+
+func (s *redisStandIn) expire(key, seconds string) {
+	n, _ := strconv.Atoi(seconds)
+	s.expiries[key] = time.Now().Add(time.Duration(n) * time.Second)
+}
+
+func TestNewRedisStore(t *testing.T) {
+	_, err := NewRedisStore("redis://:secret@localhost/2")
+	require.NoError(t, err)
+	_, err = NewRedisStore("rediss://redis.example.com:6380")
+	require.NoError(t, err)
+
+	_, err = NewRedisStore("http://localhost:6379")
+	assert.EqualError(t, err, "invalid redis URL scheme http")
+	_, err = NewRedisStore("redis://localhost:6379/db")
+	assert.EqualError(t, err, "invalid redis database db")
+}
+
+func TestRedisStore(t *testing.T) {
+	standIn := newRedisStandIn(t, "secret")
+	store, err := NewRedisStore("redis://:secret@" + standIn.listener.Addr().String() + "/1")
+	require.NoError(t, err)
+
+	storetest.TestGCRAStore(t, store)
+
+	// The keys expire after their time to live.
+	standIn.lock.Lock()
+	assert.Contains(t, standIn.expiries, "foo")
+	assert.Contains(t, standIn.commands, "EXPIRE")
+	standIn.lock.Unlock()
+
+	store, err = NewRedisStore("redis://:wrong@" + standIn.listener.Addr().String())
+	require.NoError(t, err)
+	_, _, err = store.GetWithTime("a")
+	assert.EqualError(t, err, "could not connect to redis: WRONGPASS invalid password")
+}
+
+func TestRedisStoreSharedQuota(t *testing.T) {
+	standIn := newRedisStandIn(t, "")
+	config := Config{
+		Anonymous: Tier{Name: AnonymousTierName},
+		Tiers: map[string]*Tier{
+			"secret": {Name: "partner", Quota: perHour(10, 4)},
+		},
+	}
+
+	// Two Horizon instances using the same server share the quotas.
+	var limiters []*Limiter
+	for i := 0; i < 2; i++ {
+		store, err := NewRedisStore("redis://" + standIn.listener.Addr().String())
+		require.NoError(t, err)
+		limiter, err := NewLimiter(config, store)
+		require.NoError(t, err)
+		limiters = append(limiters, limiter)
+	}
+
+	for i := 0; i < 5; i++ {
+		limiter := limiters[i%2]
+		client, err := limiter.Identify("secret", "127.0.0.1")
+		require.NoError(t, err)
+		limited, result, err := limiter.Limit(client, 1)
+		require.NoError(t, err)
+		assert.False(t, limited)
+		assert.Equal(t, 5, result.Limit)
+		assert.Equal(t, 4-i, result.Remaining)
+	}
+
+	client, err := limiters[1].Identify("secret", "127.0.0.2")
+	require.NoError(t, err)
+	limited, _, err := limiters[1].Limit(client, 1)
+	require.NoError(t, err)
+	assert.True(t, limited)
+}
+
+func TestRedisStoreAuthRequired(t *testing.T) {
+	standIn := newRedisStandIn(t, "secret")
+	store, err := NewRedisStore("redis://" + standIn.listener.Addr().String())
+	require.NoError(t, err)
+
+	_, _, err = store.GetWithTime("a")
+	assert.EqualError(t, err, "NOAUTH Authentication required.")
+}
@@ -23,10 +23,31 @@ var (
 		Type:   "rate_limit_exceeded",
 		Title:  "Rate Limit Exceeded",
 		Status: 429,
-		Detail: "The rate limit for the requesting IP address is over its alloted " +
-			"limit.  The allowed limit and requests left per time period are " +
-			"communicated to clients via the http response headers 'X-RateLimit-*' " +
-			"headers.",
+		Detail: "The rate limit for the requesting API key or IP address is over " +
+			"its alloted limit.  The allowed limit and requests left per time " +
+			"period are communicated to clients via the http response headers " +
+			"'X-RateLimit-*' headers.",
+	}
+
+	// StreamLimitExceeded is a well-known problem type.  Use it as a shortcut
+	// in your actions.
+	StreamLimitExceeded = problem.P{
+		Type:   "stream_limit_exceeded",
+		Title:  "Stream Limit Exceeded",
+		Status: http.StatusTooManyRequests,
+		Detail: "The requesting API key or IP address has reached the number of " +
+			"streaming connections it can open concurrently. Please close one of " +
+			"its streams before opening a new one.",
+	}
+
+	// UnknownAPIKey is a well-known problem type.  Use it as a shortcut
+	// in your actions.
+	UnknownAPIKey = problem.P{
+		Type:   "unknown_api_key",
+		Title:  "Unknown API Key",
+		Status: http.StatusUnauthorized,
+		Detail: "The API key sent in the X-Api-Key header is not valid. Send a " +
+			"valid API key, or no API key to be rate limited by IP address.",
 	}
 
 	// NotImplemented is a well-known problem type.  Use it as a shortcut
@@ -25,6 +25,8 @@ func TestCommonProblems(t *testing.T) {
 	}{
 		{"NotFound", problem.NotFound, 404},
 		{"RateLimitExceeded", RateLimitExceeded, 429},
+		{"StreamLimitExceeded", StreamLimitExceeded, 429},
+		{"UnknownAPIKey", UnknownAPIKey, 401},
 	}
 
 	for _, tc := range testCases {
@@ -17,12 +17,43 @@ type Event struct {
 	Retry int
 }
 
+// EventSink receives the events of the streams whose request context was
+// created by WithEventSink, instead of the response writer. It is used to send
+// streams over other transports than Server Sent Events.
+type EventSink interface {
+	SendEvent(e Event)
+}
+
+type eventSinkKey struct{}
+
+// WithEventSink returns a context sending the events of the streams to the
+// sink.
+func WithEventSink(ctx context.Context, sink EventSink) context.Context {
+	return context.WithValue(ctx, eventSinkKey{}, sink)
+}
+
+func eventSinkFromContext(ctx context.Context) EventSink {
+	sink, _ := ctx.Value(eventSinkKey{}).(EventSink)
+	return sink
+}
+
+// IsOpen returns true if the event is the first event of a stream.
+func (e Event) IsOpen() bool {
+	return e.Event == helloEvent.Event
+}
+
+// IsClose returns true if the event is the last event of a stream which
+// completed successfully.
+func (e Event) IsClose() bool {
+	return e.Event == goodbyeEvent.Event
+}
+
 // WritePreamble prepares this http connection for streaming using Server Sent
 // Events. It sends the initial http response with the appropriate headers to
 // do so.
 func WritePreamble(ctx context.Context, w http.ResponseWriter) bool {
 	_, flushable := w.(http.Flusher)
-	if !flushable {
+	if !flushable && eventSinkFromContext(ctx) == nil {
 		//TODO: render a problem struct instead of simple string
 		http.Error(w, "Streaming Not Supported", http.StatusBadRequest)
 		return false
@@ -42,6 +73,11 @@ func WritePreamble(ctx context.Context, w http.ResponseWriter) bool {
 // WriteEvent does the actual work of formatting an SSE compliant message
 // sending it over the provided ResponseWriter and flushing.
 func WriteEvent(ctx context.Context, w http.ResponseWriter, e Event) {
+	if sink := eventSinkFromContext(ctx); sink != nil {
+		sink.SendEvent(e)
+		return
+	}
+
 	if e.Error != nil {
 		fmt.Fprint(w, "event: error\n")
 		fmt.Fprintf(w, "data: %s\n\n", e.Error.Error())
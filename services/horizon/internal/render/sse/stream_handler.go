@@ -3,9 +3,10 @@ package sse
 import (
 	"net/http"
 
+	"github.com/stellar/go/services/horizon/internal/feed"
 	"github.com/stellar/go/services/horizon/internal/ledger"
+	"github.com/stellar/go/services/horizon/internal/ratelimit"
 	"github.com/stellar/go/support/errors"
-	"github.com/stellar/throttled"
 )
 
 type LedgerSourceFactory interface {
@@ -14,14 +15,21 @@ type LedgerSourceFactory interface {
 
 // StreamHandler represents a stream handling action
 type StreamHandler struct {
-	RateLimiter         *throttled.HTTPRateLimiter
+	RateLimiter         *ratelimit.Limiter
 	LedgerSourceFactory LedgerSourceFactory
+	// Feed publishes the changes of the new ledgers. When it is nil streams
+	// query the DB for every new ledger.
+	Feed *feed.Feed
 }
 
 // GenerateEventsFunc generates a slice of sse.Event which are sent via
 // streaming.
 type GenerateEventsFunc func() ([]Event, error)
 
+// LiveEventsFunc generates the slice of sse.Event for the changes of a new
+// ledger.
+type LiveEventsFunc func(changes *feed.ChangeSet) ([]Event, error)
+
 // ServeStream handles a SSE requests, sending data every time there is a new
 // ledger.
 func (handler StreamHandler) ServeStream(
@@ -39,44 +47,14 @@ func (handler StreamHandler) ServeStream(
 
 	currentLedgerSequence := ledgerSource.CurrentLedger()
 	for {
-		// Rate limit the request if it's a call to stream since it queries the DB every second. See
-		// https://github.com/stellar/go/issues/715 for more details.
-		rateLimiter := handler.RateLimiter
-		if rateLimiter != nil {
-			limited, _, err := rateLimiter.RateLimiter.RateLimit(rateLimiter.VaryBy.Key(r), 1)
-			if err != nil {
-				stream.Err(errors.Wrap(err, "RateLimiter error"))
-				return
-			}
-			if limited {
-				stream.Err(ErrRateLimited)
-				return
-			}
-		}
-
-		events, err := generateEvents()
-		if err != nil {
-			stream.Err(err)
+		if !handler.rateLimit(stream, r) {
 			return
 		}
-		for _, event := range events {
-			if limit <= 0 {
-				break
-			}
-			stream.Send(event)
-			limit--
-		}
 
-		if limit <= 0 {
-			stream.Done()
+		if !send(stream, &limit, generateEvents) {
 			return
 		}
 
-		// Manually send the preamble in case there are no data events in SSE to trigger a stream.Send call.
-		// This method is called every iteration of the loop, but is protected by a sync.Once variable so it's
-		// only executed once.
-		stream.Init()
-
 		select {
 		case currentLedgerSequence = <-ledgerSource.NextLedger(currentLedgerSequence):
 			continue
@@ -86,3 +64,108 @@ func (handler StreamHandler) ServeStream(
 		}
 	}
 }
+
+// ServeFeedStream handles a SSE request like ServeStream but, instead of
+// calling generateEvents for every new ledger, it catches up with
+// generateEvents once and then sends the events of the ledgers published to
+// the feed, generated by liveEvents. When the stream falls behind the feed it
+// catches up with generateEvents again. generateEvents and liveEvents must
+// not return the events which were already sent.
+//
+// ServeFeedStream falls back to ServeStream when the handler has no feed.
+func (handler StreamHandler) ServeFeedStream(
+	w http.ResponseWriter,
+	r *http.Request,
+	limit int,
+	generateEvents GenerateEventsFunc,
+	liveEvents LiveEventsFunc,
+) {
+	if handler.Feed == nil {
+		handler.ServeStream(w, r, limit, generateEvents)
+		return
+	}
+
+	ctx := r.Context()
+	stream := NewStream(ctx, w)
+	stream.SetLimit(limit)
+
+	for {
+		// Subscribe before catching up so that no ledger is missed between
+		// the DB query and the first change set.
+		subscription := handler.Feed.Subscribe()
+		if !handler.rateLimit(stream, r) || !send(stream, &limit, generateEvents) {
+			subscription.Close()
+			return
+		}
+
+		for caughtUp := true; caughtUp; {
+			select {
+			case changes, ok := <-subscription.Changes():
+				if !ok {
+					// The subscription fell behind, catch up from the DB.
+					caughtUp = false
+					break
+				}
+				if !handler.rateLimit(stream, r) || !send(stream, &limit, func() ([]Event, error) {
+					return liveEvents(changes)
+				}) {
+					subscription.Close()
+					return
+				}
+			case <-ctx.Done():
+				subscription.Close()
+				stream.Done()
+				return
+			}
+		}
+	}
+}
+
+// rateLimit returns false, after sending the error, when the request is rate
+// limited.
+func (handler StreamHandler) rateLimit(stream *Stream, r *http.Request) bool {
+	// Rate limit the request if it's a call to stream since it queries the DB every second. See
+	// https://github.com/stellar/go/issues/715 for more details.
+	// The client is identified by the rate limit middleware.
+	client, ok := ratelimit.FromContext(r.Context())
+	if handler.RateLimiter != nil && ok {
+		limited, _, err := handler.RateLimiter.Limit(client, 1)
+		if err != nil {
+			stream.Err(errors.Wrap(err, "RateLimiter error"))
+			return false
+		}
+		if limited {
+			stream.Err(ErrRateLimited)
+			return false
+		}
+	}
+	return true
+}
+
+// send sends the generated events up to the limit. It returns false when the
+// stream is done.
+func send(stream *Stream, limit *int, generateEvents GenerateEventsFunc) bool {
+	events, err := generateEvents()
+	if err != nil {
+		stream.Err(err)
+		return false
+	}
+	for _, event := range events {
+		if *limit <= 0 {
+			break
+		}
+		stream.Send(event)
+		*limit--
+	}
+
+	if *limit <= 0 {
+		stream.Done()
+		return false
+	}
+
+	// Manually send the preamble in case there are no data events in SSE to trigger a stream.Send call.
+	// This method is called every iteration of the loop, but is protected by a sync.Once variable so it's
+	// only executed once.
+	stream.Init()
+	return true
+}
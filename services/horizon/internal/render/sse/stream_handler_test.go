@@ -4,8 +4,11 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
+	"github.com/stellar/go/services/horizon/internal/db2/history"
+	"github.com/stellar/go/services/horizon/internal/feed"
 	"github.com/stellar/go/services/horizon/internal/ledger"
 )
 
@@ -42,3 +45,50 @@ func TestSendByeByeOnContextDone(t *testing.T) {
 		t.Fatalf("expected '%v' but got '%v'", expected, got)
 	}
 }
+
+func TestServeFeedStream(t *testing.T) {
+	f := feed.New(1)
+	handler := StreamHandler{Feed: f}
+
+	r, err := http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	w := httptest.NewRecorder()
+
+	catchUps := 0
+	generateEvents := func() ([]Event, error) {
+		catchUps++
+		switch catchUps {
+		case 1:
+			// Ledger 2 is ingested while the stream catches up.
+			go f.Publish(feed.NewChangeSet(history.Ledger{Sequence: 2}, nil, nil, nil, nil, nil))
+			return []Event{{ID: "1", Data: "db 1"}}, nil
+		default:
+			// The stream catches up after the feed is reset.
+			go f.Publish(feed.NewChangeSet(history.Ledger{Sequence: 4}, nil, nil, nil, nil, nil))
+			return []Event{{ID: "3", Data: "db 3"}}, nil
+		}
+	}
+	liveEvents := func(changes *feed.ChangeSet) ([]Event, error) {
+		if changes.Sequence() == 2 {
+			go f.Reset()
+		}
+		return []Event{{ID: strconv.FormatUint(uint64(changes.Sequence()), 10), Data: "live"}}, nil
+	}
+
+	handler.ServeFeedStream(w, r, 4, generateEvents, liveEvents)
+
+	if catchUps != 2 {
+		t.Fatalf("expected 2 catch ups but got %d", catchUps)
+	}
+	expected := "retry: 1000\nevent: open\ndata: \"hello\"\n\n" +
+		"id: 1\ndata: \"db 1\"\n\n" +
+		"id: 2\ndata: \"live\"\n\n" +
+		"id: 3\ndata: \"db 3\"\n\n" +
+		"id: 4\ndata: \"live\"\n\n" +
+		"retry: 10\nevent: close\ndata: \"byebye\"\n\n"
+	if got := w.Body.String(); got != expected {
+		t.Fatalf("expected '%v' but got '%v'", expected, got)
+	}
+}
@@ -65,6 +65,10 @@ type SubmissionResult struct {
 	// inclusion in the ledger (i.e. A successful submission).
 	Err error
 
+	// Status is the status returned by stellar-core, if it handled the
+	// submission.
+	Status string
+
 	// Duration records the time it took to submit a transaction
 	// to stellar-core
 	Duration time.Duration
@@ -56,6 +56,7 @@ func (sub *submitter) Submit(ctx context.Context, env string) (result Submission
 		return
 	}
 
+	result.Status = cresp.Status
 	switch cresp.Status {
 	case proto.TXStatusError:
 		result.Err = &FailedTransactionError{cresp.Error}
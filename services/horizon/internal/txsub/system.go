@@ -183,6 +183,28 @@ func (sys *System) Submit(
 	return
 }
 
+// SubmitAsync submits the provided base64 encoded transaction envelope to
+// stellar-core and returns its response, without waiting for the transaction
+// to be included in a ledger nor queueing it behind the submissions of the
+// same source account.
+func (sys *System) SubmitAsync(
+	ctx context.Context,
+	rawTx string,
+	envelope xdr.TransactionEnvelope,
+	hash string,
+) SubmissionResult {
+	sys.Init()
+	sys.Log.Ctx(ctx).WithFields(log.F{
+		"hash":    hash,
+		"tx_type": envelope.Type.String(),
+		"tx":      rawTx,
+	}).Info("Processing asynchronous transaction")
+
+	sr := sys.submitOnce(ctx, rawTx)
+	sys.updateTransactionTypeMetrics(envelope)
+	return sr
+}
+
 // waitUntilAccountSequence blocks until either the context times out or the sequence number of the
 // given source account is greater than or equal to `seq`
 func (sys *System) waitUntilAccountSequence(ctx context.Context, db HorizonDB, sourceAddress string, seq uint64) bool {
@@ -283,6 +283,36 @@ func (suite *SystemTestSuite) TestSubmit_OpenTransactionList() {
 	assert.Equal(suite.T(), uint64(1), getMetricValue(suite.system.Metrics.SubmissionDuration).GetSummary().GetSampleCount())
 }
 
+// Asynchronous submissions return the result of stellar-core without looking
+// for the transaction in the DB nor adding it to the open transaction list.
+func (suite *SystemTestSuite) TestSubmitAsync() {
+	suite.submitter.R = SubmissionResult{Status: "PENDING"}
+
+	sr := suite.system.SubmitAsync(
+		suite.ctx,
+		suite.successTx.Transaction.TxEnvelope,
+		suite.successXDR,
+		suite.successTx.Transaction.TransactionHash,
+	)
+	assert.NoError(suite.T(), sr.Err)
+	assert.Equal(suite.T(), "PENDING", sr.Status)
+	assert.True(suite.T(), suite.submitter.WasSubmittedTo)
+	assert.Empty(suite.T(), suite.system.Pending.Pending(suite.ctx))
+	assert.Equal(suite.T(), float64(1), getMetricValue(suite.system.Metrics.SuccessfulSubmissionsCounter).GetCounter().GetValue())
+	assert.Equal(suite.T(), float64(1), getMetricValue(suite.system.Metrics.V1TransactionsCounter).GetCounter().GetValue())
+
+	suite.submitter.R = SubmissionResult{Status: "ERROR", Err: &FailedTransactionError{"1234"}}
+	sr = suite.system.SubmitAsync(
+		suite.ctx,
+		suite.successTx.Transaction.TxEnvelope,
+		suite.successXDR,
+		suite.successTx.Transaction.TransactionHash,
+	)
+	assert.Equal(suite.T(), "ERROR", sr.Status)
+	assert.IsType(suite.T(), &FailedTransactionError{}, sr.Err)
+	assert.Equal(suite.T(), float64(1), getMetricValue(suite.system.Metrics.FailedSubmissionsCounter).GetCounter().GetValue())
+}
+
 // Tick should be a no-op if there are no open submissions.
 func (suite *SystemTestSuite) TestTick_Noop() {
 	suite.db.On("BeginTx", &sql.TxOptions{
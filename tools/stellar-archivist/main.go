@@ -101,6 +101,10 @@ func (opts *Options) MaybeProfile() {
 	}
 }
 
+func (opts *Options) SetupCache() {
+	opts.ConnectOpts.CacheConfig.Cache = opts.ConnectOpts.CacheConfig.Path != ""
+}
+
 func (opts *Options) SetupLogging() {
 	if opts.Debug {
 		log.SetLevel(log.DebugLevel)
@@ -163,6 +167,30 @@ func repair(src string, dst string, opts *Options) {
 	}
 }
 
+func verifyChain(a string, trustedLedger uint32, trustedHash string, verifyBuckets bool, opts *Options) {
+	arch := historyarchive.MustConnect(a, opts.ConnectOpts)
+	opts.SetRange(arch, nil)
+
+	chainOpts := historyarchive.VerifyChainOptions{
+		Range:         opts.CommandOpts.Range,
+		TrustedLedger: trustedLedger,
+		VerifyBuckets: verifyBuckets,
+	}
+	if trustedLedger != 0 {
+		hash, err := historyarchive.DecodeHash(trustedHash)
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "Error decoding --trusted-hash"))
+		}
+		chainOpts.TrustedHash = hash
+	} else {
+		log.Warn("No --trusted-ledger given, only checking the archive for internal consistency")
+	}
+
+	if err := arch.VerifyChain(chainOpts); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func main() {
 
 	var opts Options
@@ -228,6 +256,20 @@ func main() {
 		"S3 endpoint to use",
 	)
 
+	rootCmd.PersistentFlags().StringVar(
+		&opts.ConnectOpts.CacheConfig.Path,
+		"cache-path",
+		"",
+		"cache immutable archive files (buckets, checkpoint files) in this directory",
+	)
+
+	rootCmd.PersistentFlags().Int64Var(
+		&opts.ConnectOpts.CacheConfig.MaxBytes,
+		"cache-size",
+		0,
+		"maximum size of the archive file cache in bytes (0 means unbounded)",
+	)
+
 	rootCmd.PersistentFlags().BoolVarP(
 		&opts.CommandOpts.DryRun,
 		"dryrun",
@@ -283,6 +325,7 @@ func main() {
 		Use: "status",
 		Run: func(cmd *cobra.Command, args []string) {
 			opts.SetupLogging()
+			opts.SetupCache()
 			status(firstArg(args), &opts)
 		},
 	})
@@ -291,6 +334,7 @@ func main() {
 		Use: "log",
 		Run: func(cmd *cobra.Command, args []string) {
 			opts.SetupLogging()
+			opts.SetupCache()
 			opts.MaybeProfile()
 			logArchive(firstArg(args), &opts)
 		},
@@ -300,6 +344,7 @@ func main() {
 		Use: "scan",
 		Run: func(cmd *cobra.Command, args []string) {
 			opts.SetupLogging()
+			opts.SetupCache()
 			opts.MaybeProfile()
 			scan(firstArg(args), &opts)
 		},
@@ -309,6 +354,7 @@ func main() {
 		Use: "mirror",
 		Run: func(cmd *cobra.Command, args []string) {
 			opts.SetupLogging()
+			opts.SetupCache()
 			opts.MaybeProfile()
 			src, dst := srcDst(args)
 			mirror(src, dst, &opts)
@@ -319,12 +365,46 @@ func main() {
 		Use: "repair",
 		Run: func(cmd *cobra.Command, args []string) {
 			opts.SetupLogging()
+			opts.SetupCache()
 			opts.MaybeProfile()
 			src, dst := srcDst(args)
 			repair(src, dst, &opts)
 		},
 	})
 
+	var trustedLedger uint32
+	var trustedHash string
+	var verifyBuckets bool
+	verifyChainCmd := &cobra.Command{
+		Use:   "verify-chain",
+		Short: "verify the chain of ledger hashes in an archive starting from a trusted ledger hash",
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.SetupLogging()
+			opts.SetupCache()
+			opts.MaybeProfile()
+			verifyChain(firstArg(args), trustedLedger, trustedHash, verifyBuckets, &opts)
+		},
+	}
+	verifyChainCmd.Flags().Uint32Var(
+		&trustedLedger,
+		"trusted-ledger",
+		0,
+		"sequence of a ledger whose hash is known to be correct, at or after the end of the range",
+	)
+	verifyChainCmd.Flags().StringVar(
+		&trustedHash,
+		"trusted-hash",
+		"",
+		"hex encoded hash of --trusted-ledger",
+	)
+	verifyChainCmd.Flags().BoolVar(
+		&verifyBuckets,
+		"verify-buckets",
+		false,
+		"also verify that every bucket referenced by checkpoints hashes to its name",
+	)
+	rootCmd.AddCommand(verifyChainCmd)
+
 	rootCmd.AddCommand(&cobra.Command{
 		Use: "dumpxdr",
 		Run: func(cmd *cobra.Command, args []string) {
@@ -0,0 +1,245 @@
+package txnbuild
+
+import (
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+const (
+	// MaxOperationsPerTransaction is the maximum number of operations of a
+	// transaction.
+	MaxOperationsPerTransaction = 100
+	// MaxSignaturesPerTransaction is the maximum number of signatures of a
+	// transaction envelope.
+	MaxSignaturesPerTransaction = 20
+
+	// decoratedSignatureSize is the size of an ed25519 decorated signature
+	// in an envelope: its hint, the length of the signature and the
+	// signature.
+	decoratedSignatureSize = 4 + 4 + 64
+)
+
+// BatchParams is a container for parameters which are used to split a list
+// of operations into transactions with BuildBatches.
+type BatchParams struct {
+	// SourceAccounts are the source accounts of the transactions, used in
+	// turn. The sequence number of the source account of each transaction
+	// is incremented once all the transactions were built and signed.
+	SourceAccounts []Account
+	// Operations are the operations to split, in order.
+	Operations []Operation
+	BaseFee    int64
+	Memo       Memo
+	Timebounds Timebounds
+	// EnableMuxedAccounts allows muxed source accounts.
+	EnableMuxedAccounts bool
+	// MaxOperations is the maximum number of operations per transaction. It
+	// defaults to, and cannot exceed, MaxOperationsPerTransaction.
+	MaxOperations int
+	// MaxEnvelopeSize is the maximum size, in bytes, of the XDR encoding of
+	// the signed transaction envelopes. 0 means no limit.
+	MaxEnvelopeSize int
+	// Network is the passphrase of the network the transactions are signed
+	// for.
+	Network string
+	// Signers sign the transactions for the accounts with the same address.
+	// Each transaction is signed for its source account and the source
+	// accounts of its operations.
+	Signers []*keypair.Full
+	// AccountSigners are the keypairs signing for the accounts, by address,
+	// whose master key is not in Signers.
+	AccountSigners map[string][]*keypair.Full
+}
+
+// Batch is a signed transaction built by BuildBatches.
+type Batch struct {
+	Transaction *Transaction
+	// OperationIndices are the indices, in BatchParams.Operations, of the
+	// operations of the transaction.
+	OperationIndices []int
+}
+
+// BuildBatches splits the operations into as few signed transactions as
+// possible, keeping their order. Transactions are limited in their number of
+// operations, their number of signatures and the size of their envelope.
+func BuildBatches(params BatchParams) ([]Batch, error) {
+	if len(params.SourceAccounts) == 0 {
+		return nil, errors.New("batch has no source accounts")
+	}
+	if len(params.Operations) == 0 {
+		return nil, errors.New("batch has no operations")
+	}
+	maxOperations := params.MaxOperations
+	if maxOperations == 0 {
+		maxOperations = MaxOperationsPerTransaction
+	}
+	if maxOperations < 0 || maxOperations > MaxOperationsPerTransaction {
+		return nil, errors.Errorf("max operations must be between 1 and %d", MaxOperationsPerTransaction)
+	}
+
+	sizes := make([]int, len(params.Operations))
+	signers := make([][]*keypair.Full, len(params.Operations))
+	for i, op := range params.Operations {
+		if err := op.Validate(params.EnableMuxedAccounts); err != nil {
+			return nil, errors.Wrapf(err, "validation failed for operation %d", i)
+		}
+		xdrOp, err := op.BuildXDR(params.EnableMuxedAccounts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build operation %d", i)
+		}
+		b, err := xdrOp.MarshalBinary()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode operation %d", i)
+		}
+		sizes[i] = len(b)
+		if op.GetSourceAccount() != "" {
+			if signers[i], err = params.signersOf(op.GetSourceAccount()); err != nil {
+				return nil, errors.Wrapf(err, "operation %d", i)
+			}
+		}
+	}
+
+	// The transactions are built on copies of the source accounts so that
+	// their sequence numbers are left unchanged if a transaction fails.
+	sources := make([]Account, len(params.SourceAccounts))
+	copies := map[string]*SimpleAccount{}
+	for i, account := range params.SourceAccounts {
+		address := account.GetAccountID()
+		if _, ok := copies[address]; !ok {
+			sequence, err := account.GetSequenceNumber()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get sequence number of source account %d", i)
+			}
+			copies[address] = &SimpleAccount{AccountID: address, Sequence: sequence}
+		}
+		sources[i] = copies[address]
+	}
+	transactions := make([]int, len(params.SourceAccounts))
+
+	var batches []Batch
+	for start := 0; start < len(params.Operations); {
+		sourceIndex := len(batches) % len(sources)
+		source := sources[sourceIndex]
+		keys, err := params.signersOf(source.GetAccountID())
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction %d", len(batches))
+		}
+		size, err := params.envelopeOverhead(source, start, sizes[start])
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction %d", len(batches))
+		}
+		keys = addKeypairs(nil, keys)
+		size += len(keys) * decoratedSignatureSize
+
+		end := start
+		for ; end < len(params.Operations) && end-start < maxOperations; end++ {
+			opKeys := addKeypairs(keys, signers[end])
+			opSize := size + sizes[end] + (len(opKeys)-len(keys))*decoratedSignatureSize
+			if len(opKeys) > MaxSignaturesPerTransaction ||
+				params.MaxEnvelopeSize > 0 && opSize > params.MaxEnvelopeSize {
+				break
+			}
+			keys, size = opKeys, opSize
+		}
+		if end == start {
+			return nil, errors.Errorf("operation %d does not fit in a transaction", start)
+		}
+
+		tx, err := NewTransaction(TransactionParams{
+			SourceAccount:        source,
+			IncrementSequenceNum: true,
+			Operations:           params.Operations[start:end],
+			BaseFee:              params.BaseFee,
+			Memo:                 params.Memo,
+			Timebounds:           params.Timebounds,
+			EnableMuxedAccounts:  params.EnableMuxedAccounts,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build transaction %d", len(batches))
+		}
+		tx, err = tx.Sign(params.Network, keys...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to sign transaction %d", len(batches))
+		}
+
+		indices := make([]int, end-start)
+		for i := range indices {
+			indices[i] = start + i
+		}
+		batches = append(batches, Batch{Transaction: tx, OperationIndices: indices})
+		transactions[sourceIndex]++
+		start = end
+	}
+
+	for i, account := range params.SourceAccounts {
+		for j := 0; j < transactions[i]; j++ {
+			if _, err := account.IncrementSequenceNumber(); err != nil {
+				return nil, errors.Wrapf(err, "failed to increment sequence number of source account %d", i)
+			}
+		}
+	}
+	return batches, nil
+}
+
+// signersOf returns the keypairs signing for the account with the given
+// address.
+func (params BatchParams) signersOf(address string) ([]*keypair.Full, error) {
+	var muxed xdr.MuxedAccount
+	if err := muxed.SetAddress(address); err != nil {
+		return nil, errors.Wrapf(err, "%s is not a valid account", address)
+	}
+	accountID := muxed.ToAccountId()
+	address = accountID.Address()
+
+	if keys, ok := params.AccountSigners[address]; ok {
+		return keys, nil
+	}
+	for _, kp := range params.Signers {
+		if kp.Address() == address {
+			return []*keypair.Full{kp}, nil
+		}
+	}
+	return nil, errors.Errorf("no signer for account %s", address)
+}
+
+// envelopeOverhead returns the size of the unsigned envelope of a transaction
+// of the source account, without its operations. It is computed from the
+// envelope of the transaction with only the operation at the given index.
+func (params BatchParams) envelopeOverhead(source Account, index, opSize int) (int, error) {
+	tx, err := NewTransaction(TransactionParams{
+		SourceAccount:       &SimpleAccount{AccountID: source.GetAccountID()},
+		Operations:          params.Operations[index : index+1],
+		BaseFee:             params.BaseFee,
+		Memo:                params.Memo,
+		Timebounds:          params.Timebounds,
+		EnableMuxedAccounts: params.EnableMuxedAccounts,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to build transaction")
+	}
+	b, err := tx.MarshalBinary()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to encode transaction")
+	}
+	return len(b) - opSize, nil
+}
+
+// addKeypairs returns the keypairs with the new ones, skipping duplicates.
+func addKeypairs(kps []*keypair.Full, newKps []*keypair.Full) []*keypair.Full {
+	result := kps
+	for _, kp := range newKps {
+		found := false
+		for _, existing := range result {
+			if existing.Address() == kp.Address() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			// Copy before appending, kps is shared with the caller.
+			result = append(result[:len(result):len(result)], kp)
+		}
+	}
+	return result
+}
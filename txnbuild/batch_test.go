@@ -0,0 +1,178 @@
+package txnbuild
+
+import (
+	"testing"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPayments(n int, source string) []Operation {
+	ops := make([]Operation, n)
+	for i := range ops {
+		ops[i] = &Payment{
+			Destination:   newKeypair2().Address(),
+			Amount:        "1",
+			Asset:         NativeAsset{},
+			SourceAccount: source,
+		}
+	}
+	return ops
+}
+
+func TestBuildBatches(t *testing.T) {
+	kp0, kp1 := newKeypair0(), newKeypair1()
+	source0 := &SimpleAccount{AccountID: kp0.Address(), Sequence: 10}
+	source1 := &SimpleAccount{AccountID: kp1.Address(), Sequence: 20}
+
+	batches, err := BuildBatches(BatchParams{
+		SourceAccounts: []Account{source0, source1},
+		Operations:     newPayments(250, ""),
+		BaseFee:        MinBaseFee,
+		Timebounds:     NewInfiniteTimeout(),
+		Network:        network.TestNetworkPassphrase,
+		Signers:        []*keypair.Full{kp0, kp1},
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 3)
+
+	expected := []struct {
+		source   string
+		sequence int64
+		ops      int
+	}{
+		{kp0.Address(), 11, 100},
+		{kp1.Address(), 21, 100},
+		{kp0.Address(), 12, 50},
+	}
+	next := 0
+	for i, batch := range batches {
+		tx := batch.Transaction
+		assert.Equal(t, expected[i].source, tx.SourceAccount().AccountID)
+		assert.Equal(t, expected[i].sequence, tx.SequenceNumber())
+		assert.Len(t, tx.Operations(), expected[i].ops)
+		assert.Equal(t, int64(expected[i].ops*MinBaseFee), tx.MaxFee())
+		require.Len(t, batch.OperationIndices, expected[i].ops)
+		for _, index := range batch.OperationIndices {
+			assert.Equal(t, next, index)
+			next++
+		}
+		require.Len(t, tx.Signatures(), 1)
+		assert.Equal(t, kp0.Address() == expected[i].source, kp0.Hint() == tx.Signatures()[0].Hint)
+	}
+	assert.Equal(t, int64(12), source0.Sequence)
+	assert.Equal(t, int64(21), source1.Sequence)
+}
+
+func TestBuildBatchesErrorKeepsSequenceNumbers(t *testing.T) {
+	kp0, kp1 := newKeypair0(), newKeypair1()
+	source0 := &SimpleAccount{AccountID: kp0.Address(), Sequence: 10}
+	source1 := &SimpleAccount{AccountID: kp1.Address(), Sequence: 20}
+
+	// The second transaction cannot be signed for source1.
+	_, err := BuildBatches(BatchParams{
+		SourceAccounts: []Account{source0, source1},
+		Operations:     newPayments(150, ""),
+		BaseFee:        MinBaseFee,
+		Timebounds:     NewInfiniteTimeout(),
+		Network:        network.TestNetworkPassphrase,
+		Signers:        []*keypair.Full{kp0},
+	})
+	assert.EqualError(t, err, "transaction 1: no signer for account "+kp1.Address())
+	assert.Equal(t, int64(10), source0.Sequence)
+	assert.Equal(t, int64(20), source1.Sequence)
+}
+
+func TestBuildBatchesSigners(t *testing.T) {
+	source := newKeypair0()
+	var ops []Operation
+	var signers []*keypair.Full
+	for i := 0; i < 30; i++ {
+		kp := keypair.MustRandom()
+		signers = append(signers, kp)
+		ops = append(ops, newPayments(2, kp.Address())...)
+	}
+	// The operations of the transaction source account do not need another
+	// signature.
+	ops = append(ops, newPayments(1, "")...)
+	signers = append(signers, source)
+
+	batches, err := BuildBatches(BatchParams{
+		SourceAccounts: []Account{&SimpleAccount{AccountID: source.Address()}},
+		Operations:     ops,
+		BaseFee:        MinBaseFee,
+		Timebounds:     NewInfiniteTimeout(),
+		Network:        network.TestNetworkPassphrase,
+		Signers:        signers,
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0].OperationIndices, 38)
+	assert.Len(t, batches[0].Transaction.Signatures(), MaxSignaturesPerTransaction)
+	assert.Len(t, batches[1].OperationIndices, 23)
+	assert.Len(t, batches[1].Transaction.Signatures(), 12)
+
+	_, err = BuildBatches(BatchParams{
+		SourceAccounts: []Account{&SimpleAccount{AccountID: source.Address()}},
+		Operations:     ops,
+		BaseFee:        MinBaseFee,
+		Timebounds:     NewInfiniteTimeout(),
+		Network:        network.TestNetworkPassphrase,
+		Signers:        signers[1:],
+	})
+	assert.EqualError(t, err, "operation 0: no signer for account "+signers[0].Address())
+}
+
+func TestBuildBatchesAccountSigners(t *testing.T) {
+	source, signer := newKeypair0(), newKeypair1()
+	batches, err := BuildBatches(BatchParams{
+		SourceAccounts: []Account{&SimpleAccount{AccountID: source.Address()}},
+		Operations:     newPayments(1, ""),
+		BaseFee:        MinBaseFee,
+		Timebounds:     NewInfiniteTimeout(),
+		Network:        network.TestNetworkPassphrase,
+		Signers:        []*keypair.Full{source},
+		AccountSigners: map[string][]*keypair.Full{source.Address(): {signer}},
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0].Transaction.Signatures(), 1)
+	assert.Equal(t, xdr.SignatureHint(signer.Hint()), batches[0].Transaction.Signatures()[0].Hint)
+}
+
+func TestBuildBatchesMaxEnvelopeSize(t *testing.T) {
+	kp0 := newKeypair0()
+	params := BatchParams{
+		SourceAccounts:  []Account{&SimpleAccount{AccountID: kp0.Address()}},
+		Operations:      newPayments(100, ""),
+		BaseFee:         MinBaseFee,
+		Timebounds:      NewInfiniteTimeout(),
+		Network:         network.TestNetworkPassphrase,
+		Signers:         []*keypair.Full{kp0},
+		MaxEnvelopeSize: 1000,
+	}
+	batches, err := BuildBatches(params)
+	require.NoError(t, err)
+	require.True(t, len(batches) > 1)
+
+	var sizes []int
+	for _, batch := range batches {
+		b, err := batch.Transaction.MarshalBinary()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(b), params.MaxEnvelopeSize)
+		sizes = append(sizes, len(b))
+	}
+	// Full transactions cannot fit another payment.
+	op, err := params.Operations[0].BuildXDR(false)
+	require.NoError(t, err)
+	opBytes, err := op.MarshalBinary()
+	require.NoError(t, err)
+	assert.Greater(t, sizes[0]+len(opBytes), params.MaxEnvelopeSize)
+
+	params.MaxEnvelopeSize = 100
+	_, err = BuildBatches(params)
+	assert.EqualError(t, err, "operation 0 does not fit in a transaction")
+}
@@ -0,0 +1,38 @@
+package sep7
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// HTTP represents the http client used to post signed transactions to
+// callbacks.
+type HTTP interface {
+	PostForm(url string, data url.Values) (*http.Response, error)
+}
+
+// PostCallback posts the signed transaction envelope (base64 XDR) to the
+// callback of the request, as a form with a single `xdr` field. Requests
+// without a callback return an error, their transactions should be submitted
+// to the network instead.
+func (p *CommonParams) PostCallback(client HTTP, txeB64 string) error {
+	if p.Callback == "" {
+		return errors.New("request has no callback")
+	}
+	resp, err := client.PostForm(p.Callback, url.Values{"xdr": {txeB64}})
+	if err != nil {
+		return errors.Wrap(err, "http request errored")
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return errors.Errorf("callback failed with status code %d", resp.StatusCode)
+	}
+	return nil
+}
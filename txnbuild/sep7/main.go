@@ -0,0 +1,308 @@
+// Package sep7 builds, parses, signs and verifies SEP-7 URI requests
+// (https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0007.md).
+// Both the `web+stellar:tx` and `web+stellar:pay` operations are supported.
+package sep7
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/txnbuild"
+)
+
+// Scheme is the URI scheme of SEP-7 requests.
+const Scheme = "web+stellar"
+
+// Operations supported by SEP-7 requests.
+const (
+	OperationTx  = "tx"
+	OperationPay = "pay"
+)
+
+// Memo types allowed in pay requests.
+const (
+	MemoTypeText   = "MEMO_TEXT"
+	MemoTypeID     = "MEMO_ID"
+	MemoTypeHash   = "MEMO_HASH"
+	MemoTypeReturn = "MEMO_RETURN"
+)
+
+// MaxMessageLength is the maximum length of the msg parameter.
+const MaxMessageLength = 300
+
+// callbackPrefix is the prefix of the callback parameter of URL callbacks,
+// the only kind of callback defined by SEP-7.
+const callbackPrefix = "url:"
+
+// Request is a SEP-7 URI request, either a *TxRequest or a *PayRequest.
+type Request interface {
+	// Operation returns the operation of the request (OperationTx or
+	// OperationPay).
+	Operation() string
+	// Params returns the parameters common to all operations.
+	Params() *CommonParams
+	// String returns the URI of the request.
+	String() string
+}
+
+// CommonParams are the parameters shared by all SEP-7 operations.
+type CommonParams struct {
+	// Callback is the URL to which the signed transaction should be posted
+	// instead of submitting it to the network. It does not include the `url:`
+	// prefix used in the URI.
+	Callback string
+	// Message is a message displayed to the user, at most 300 characters.
+	Message string
+	// NetworkPassphrase is the passphrase of the network the transaction is
+	// intended for. An empty value means the public network.
+	NetworkPassphrase string
+	// OriginDomain is the fully qualified domain name of the service that
+	// created the request. When set the request must be signed by the
+	// URI_REQUEST_SIGNING_KEY in the domain's stellar.toml.
+	OriginDomain string
+	// Signature is the base64 encoded signature of the request.
+	Signature string
+
+	// unsigned is the URI received by Parse without the signature parameter.
+	// Signatures of parsed requests are verified against it rather than the
+	// URI generated by String which may encode the parameters differently.
+	unsigned string
+}
+
+// TxRequest is a `web+stellar:tx` request asking the user to sign a
+// transaction.
+type TxRequest struct {
+	// XDR is the base64 encoded transaction envelope.
+	XDR string
+	// Replace lists the fields of the transaction that should be replaced by
+	// the user before signing.
+	Replace []Replacement
+	// Pubkey is the public key that should sign the transaction.
+	Pubkey string
+	// Chain is a SEP-7 URI which this request forwards.
+	Chain string
+	CommonParams
+}
+
+// PayRequest is a `web+stellar:pay` request asking the user to pay a
+// destination.
+type PayRequest struct {
+	// Destination is the account ID or payment address receiving the payment.
+	Destination string
+	// Amount is the amount to pay. When empty the user chooses the amount.
+	Amount string
+	// AssetCode and AssetIssuer identify the asset to pay. When both are
+	// empty the payment is in lumens.
+	AssetCode   string
+	AssetIssuer string
+	// Memo and MemoType are the memo of the payment transaction. Memos of
+	// type MEMO_HASH and MEMO_RETURN are base64 encoded.
+	Memo     string
+	MemoType string
+	CommonParams
+}
+
+// Operation returns OperationTx.
+func (r *TxRequest) Operation() string {
+	return OperationTx
+}
+
+// Params returns the parameters common to all operations.
+func (r *TxRequest) Params() *CommonParams {
+	return &r.CommonParams
+}
+
+// Transaction parses the transaction of the request.
+func (r *TxRequest) Transaction() (*txnbuild.GenericTransaction, error) {
+	return txnbuild.TransactionFromXDR(r.XDR, txnbuild.TransactionFromXDROptionEnableMuxedAccounts)
+}
+
+// String returns the URI of the request.
+func (r *TxRequest) String() string {
+	var params uriParams
+	params.add("xdr", r.XDR)
+	params.add("replace", encodeReplacements(r.Replace))
+	params.add("pubkey", r.Pubkey)
+	params.add("chain", r.Chain)
+	r.CommonParams.addTo(&params)
+	return params.uri(OperationTx)
+}
+
+// Operation returns OperationPay.
+func (r *PayRequest) Operation() string {
+	return OperationPay
+}
+
+// Params returns the parameters common to all operations.
+func (r *PayRequest) Params() *CommonParams {
+	return &r.CommonParams
+}
+
+// String returns the URI of the request.
+func (r *PayRequest) String() string {
+	var params uriParams
+	params.add("destination", r.Destination)
+	params.add("amount", r.Amount)
+	params.add("asset_code", r.AssetCode)
+	params.add("asset_issuer", r.AssetIssuer)
+	params.add("memo", r.Memo)
+	params.add("memo_type", r.MemoType)
+	r.CommonParams.addTo(&params)
+	return params.uri(OperationPay)
+}
+
+func (p *CommonParams) addTo(params *uriParams) {
+	if p.Callback != "" {
+		params.add("callback", callbackPrefix+p.Callback)
+	}
+	params.add("msg", p.Message)
+	params.add("network_passphrase", p.NetworkPassphrase)
+	params.add("origin_domain", p.OriginDomain)
+	// The signature must be the last parameter.
+	params.add("signature", p.Signature)
+}
+
+// uriParams builds the query of a URI keeping the order of the parameters.
+type uriParams struct {
+	query []string
+}
+
+func (p *uriParams) add(key, value string) {
+	if value == "" {
+		return
+	}
+	// Spaces are encoded as %20 like in the examples of SEP-7.
+	escaped := strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	p.query = append(p.query, key+"="+escaped)
+}
+
+func (p *uriParams) uri(operation string) string {
+	return Scheme + ":" + operation + "?" + strings.Join(p.query, "&")
+}
+
+// Parse parses and validates a SEP-7 URI. It returns a *TxRequest or a
+// *PayRequest depending on the operation of the URI.
+func Parse(uri string) (Request, error) {
+	rest := strings.TrimPrefix(uri, Scheme+":")
+	if rest == uri {
+		return nil, errors.Errorf("uri does not use the %s scheme", Scheme)
+	}
+	operation, rawQuery := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		operation, rawQuery = rest[:i], rest[i+1:]
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid query")
+	}
+	for key, values := range query {
+		if len(values) > 1 {
+			return nil, errors.Errorf("duplicate parameter %s", key)
+		}
+	}
+
+	common, err := parseCommonParams(uri, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var request Request
+	switch operation {
+	case OperationTx:
+		request, err = parseTxRequest(query, common)
+	case OperationPay:
+		request, err = parsePayRequest(query, common)
+	default:
+		return nil, errors.Errorf("unsupported operation %q", operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func parseCommonParams(uri string, query url.Values) (CommonParams, error) {
+	params := CommonParams{
+		Message:           query.Get("msg"),
+		NetworkPassphrase: query.Get("network_passphrase"),
+		OriginDomain:      query.Get("origin_domain"),
+		Signature:         query.Get("signature"),
+		unsigned:          uri,
+	}
+	if callback := query.Get("callback"); callback != "" {
+		if !strings.HasPrefix(callback, callbackPrefix) {
+			return params, errors.Errorf("unsupported callback %q", callback)
+		}
+		params.Callback = strings.TrimPrefix(callback, callbackPrefix)
+		if _, err := url.ParseRequestURI(params.Callback); err != nil {
+			return params, errors.Wrap(err, "invalid callback")
+		}
+	}
+	if len(params.Message) > MaxMessageLength {
+		return params, errors.Errorf("msg is longer than %d characters", MaxMessageLength)
+	}
+	if params.Signature != "" {
+		i := strings.LastIndex(uri, "&signature=")
+		if i < 0 || strings.Contains(uri[i+1:], "&") {
+			return params, errors.New("signature must be the last parameter")
+		}
+		params.unsigned = uri[:i]
+		if _, err := base64.StdEncoding.DecodeString(params.Signature); err != nil {
+			return params, errors.Wrap(err, "invalid signature")
+		}
+	}
+	return params, nil
+}
+
+func parseTxRequest(query url.Values, common CommonParams) (*TxRequest, error) {
+	request := &TxRequest{
+		XDR:          query.Get("xdr"),
+		Pubkey:       query.Get("pubkey"),
+		Chain:        query.Get("chain"),
+		CommonParams: common,
+	}
+	if request.XDR == "" {
+		return nil, errors.New("missing xdr parameter")
+	}
+	if _, err := request.Transaction(); err != nil {
+		return nil, errors.Wrap(err, "invalid xdr")
+	}
+	replace, err := parseReplacements(query.Get("replace"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid replace")
+	}
+	request.Replace = replace
+	return request, nil
+}
+
+func parsePayRequest(query url.Values, common CommonParams) (*PayRequest, error) {
+	request := &PayRequest{
+		Destination:  query.Get("destination"),
+		Amount:       query.Get("amount"),
+		AssetCode:    query.Get("asset_code"),
+		AssetIssuer:  query.Get("asset_issuer"),
+		Memo:         query.Get("memo"),
+		MemoType:     query.Get("memo_type"),
+		CommonParams: common,
+	}
+	if request.Destination == "" {
+		return nil, errors.New("missing destination parameter")
+	}
+	if request.Amount != "" {
+		if _, err := amount.Parse(request.Amount); err != nil {
+			return nil, errors.Wrap(err, "invalid amount")
+		}
+	}
+	if request.AssetIssuer != "" && request.AssetCode == "" {
+		return nil, errors.New("asset_issuer requires asset_code")
+	}
+	switch request.MemoType {
+	case "", MemoTypeText, MemoTypeID, MemoTypeHash, MemoTypeReturn:
+	default:
+		return nil, errors.Errorf("invalid memo_type %q", request.MemoType)
+	}
+	return request, nil
+}
@@ -0,0 +1,169 @@
+package sep7
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/clients/stellartoml"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/txnbuild"
+)
+
+// Example from SEP-7 signed by
+// SBPOVRVKTTV7W3IOX2FJPSMPCJ5L2WU2YKTP3HCLYPXNI5MDIGREVNYC.
+const signedPayURI = "web+stellar:pay?destination=GCALNQQBXAPZ2WIRSDDBMSTAKCUH5SG6U76YBFLQLIXJTF7FE5AX7AOO" +
+	"&amount=120.1234567&memo=skdjfasf&memo_type=MEMO_TEXT&msg=pay%20me%20with%20lumens" +
+	"&origin_domain=someDomain.com" +
+	"&signature=tbsLtlK%2FfouvRWk2UWFP47yHYeI1g1NEC%2FfEQvuXG6V8P%2BbeLxplYbOVtTk1g94Wp97cHZ3pVJy%2FtZNYobl3Cw%3D%3D"
+
+func TestParsePayRequest(t *testing.T) {
+	request, err := Parse(signedPayURI)
+	require.NoError(t, err)
+	pay, ok := request.(*PayRequest)
+	require.True(t, ok)
+	assert.Equal(t, OperationPay, pay.Operation())
+	assert.Equal(t, "GCALNQQBXAPZ2WIRSDDBMSTAKCUH5SG6U76YBFLQLIXJTF7FE5AX7AOO", pay.Destination)
+	assert.Equal(t, "120.1234567", pay.Amount)
+	assert.Equal(t, "skdjfasf", pay.Memo)
+	assert.Equal(t, MemoTypeText, pay.MemoType)
+	assert.Equal(t, "pay me with lumens", pay.Message)
+	assert.Equal(t, "someDomain.com", pay.OriginDomain)
+	assert.Equal(t, signedPayURI, pay.String())
+
+	signingKey := keypair.MustParseFull("SBPOVRVKTTV7W3IOX2FJPSMPCJ5L2WU2YKTP3HCLYPXNI5MDIGREVNYC")
+	assert.NoError(t, VerifySignature(pay, signingKey.Address()))
+	assert.EqualError(t, VerifySignature(pay, keypair.MustRandom().Address()), "signature does not match the signing key")
+
+	client := &stellartoml.MockClient{}
+	client.On("GetStellarToml", "someDomain.com").
+		Return(&stellartoml.Response{UriRequestSigningKey: signingKey.Address()}, nil).Once()
+	assert.NoError(t, VerifyOriginDomain(pay, client))
+	client.AssertExpectations(t)
+
+	// Modifying a parameter invalidates the signature.
+	tampered, err := Parse(strings.Replace(signedPayURI, "amount=120", "amount=121", 1))
+	require.NoError(t, err)
+	assert.Error(t, VerifySignature(tampered, signingKey.Address()))
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, testCase := range []struct {
+		uri      string
+		expected string
+	}{
+		{"https://example.com", "uri does not use the web+stellar scheme"},
+		{"web+stellar:sign?xdr=abc", `unsupported operation "sign"`},
+		{"web+stellar:tx", "missing xdr parameter"},
+		{"web+stellar:tx?xdr=abc", "invalid xdr: unable to unmarshal transaction envelope: xdr:DecodeInt: unexpected EOF while decoding 4 bytes - read: '[]'"},
+		{"web+stellar:pay?amount=1", "missing destination parameter"},
+		{"web+stellar:pay?destination=GCALNQQBXAPZ2WIRSDDBMSTAKCUH5SG6U76YBFLQLIXJTF7FE5AX7AOO&amount=x", "invalid amount: invalid amount format: x"},
+		{"web+stellar:pay?destination=G&memo_type=MEMO_X", `invalid memo_type "MEMO_X"`},
+		{"web+stellar:pay?destination=G&asset_issuer=G", "asset_issuer requires asset_code"},
+		{"web+stellar:pay?destination=G&callback=https://example.com", `unsupported callback "https://example.com"`},
+		{"web+stellar:pay?destination=G&destination=G", "duplicate parameter destination"},
+		{"web+stellar:pay?signature=abcd&destination=G", "signature must be the last parameter"},
+	} {
+		_, err := Parse(testCase.uri)
+		assert.EqualError(t, err, testCase.expected, testCase.uri)
+	}
+}
+
+func newTxRequest(t *testing.T) *TxRequest {
+	source := txnbuild.NewSimpleAccount("GAS4V4O2B7DW5T7IQRPEEVCRXMDZESKISR7DVIGKZQYYV3OSQ5SH5LVP", 1)
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount: &source,
+		BaseFee:       txnbuild.MinBaseFee,
+		Timebounds:    txnbuild.NewInfiniteTimeout(),
+		Operations: []txnbuild.Operation{
+			&txnbuild.ChangeTrust{
+				Line: txnbuild.CreditAsset{
+					Code:   "USD",
+					Issuer: "GB7BDSZU2Y27LYNLALKKALB52WS2IZWYBDGY6EQBLEED3TJOCVMZRH7H",
+				}.MustToChangeTrustAsset(),
+			},
+		},
+	})
+	require.NoError(t, err)
+	xdr, err := tx.Base64()
+	require.NoError(t, err)
+
+	return &TxRequest{
+		XDR: xdr,
+		Replace: []Replacement{
+			{Path: "sourceAccount", ID: "X", Hint: "account on which to create the trustline"},
+			{Path: "operations[0].sourceAccount", ID: "X", Hint: "account on which to create the trustline"},
+		},
+		CommonParams: CommonParams{
+			Message:           "create a trustline",
+			NetworkPassphrase: network.TestNetworkPassphrase,
+			OriginDomain:      "example.com",
+		},
+	}
+}
+
+func TestTxRequestRoundTrip(t *testing.T) {
+	request := newTxRequest(t)
+	signingKey := keypair.MustRandom()
+	require.NoError(t, Sign(request, signingKey))
+	uri := request.String()
+	assert.Contains(t, uri, "&replace=sourceAccount%3AX%2Coperations%5B0%5D.sourceAccount%3AX%3BX%3Aaccount%20on%20which")
+	assert.Contains(t, uri, "&msg=create%20a%20trustline&network_passphrase=Test%20SDF%20Network%20%3B%20September%202015&origin_domain=example.com&signature=")
+
+	parsed, err := Parse(uri)
+	require.NoError(t, err)
+	tx, ok := parsed.(*TxRequest)
+	require.True(t, ok)
+	assert.Equal(t, request.XDR, tx.XDR)
+	assert.Equal(t, request.Replace, tx.Replace)
+	assert.Equal(t, request.Message, tx.Message)
+	assert.Equal(t, request.NetworkPassphrase, tx.NetworkPassphrase)
+	assert.NoError(t, VerifySignature(tx, signingKey.Address()))
+
+	assert.EqualError(t, Sign(&PayRequest{Destination: "G"}, signingKey), "origin_domain is required to sign a request")
+	assert.EqualError(t, VerifyOriginDomain(&PayRequest{Destination: "G"}, &stellartoml.MockClient{}), "request has no origin_domain")
+}
+
+func TestReplaceFields(t *testing.T) {
+	request := newTxRequest(t)
+	account := "GDQNY3PBOJOKYZSRMK2S7LHHGWZIUISD4QORETLMXEWXBI7KFZZMKTL3"
+	replaced, err := request.ReplaceFields(map[string]string{"X": account})
+	require.NoError(t, err)
+	tx, ok := replaced.Transaction()
+	require.True(t, ok)
+	assert.Equal(t, account, tx.SourceAccount().AccountID)
+	assert.Equal(t, account, tx.Operations()[0].GetSourceAccount())
+
+	_, err = request.ReplaceFields(map[string]string{})
+	assert.EqualError(t, err, "missing value for X")
+
+	request.Replace = []Replacement{{Path: "operations[1].sourceAccount", ID: "X"}}
+	_, err = request.ReplaceFields(map[string]string{"X": account})
+	assert.EqualError(t, err, "transaction has no field operations[1].sourceAccount")
+}
+
+func TestPostCallback(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.PostFormValue("xdr")
+		if received == "fail" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	request := &PayRequest{Destination: "G", CommonParams: CommonParams{Callback: server.URL + "/callback"}}
+	parsed, err := Parse(request.String())
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/callback", parsed.Params().Callback)
+
+	require.NoError(t, parsed.Params().PostCallback(server.Client(), "AAAA"))
+	assert.Equal(t, "AAAA", received)
+	assert.EqualError(t, parsed.Params().PostCallback(server.Client(), "fail"), "callback failed with status code 400")
+	assert.EqualError(t, (&CommonParams{}).PostCallback(server.Client(), "AAAA"), "request has no callback")
+}
@@ -0,0 +1,135 @@
+package sep7
+
+import (
+	"strings"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/txnbuild"
+)
+
+// Replacement is a field of a tx request that should be replaced by the user
+// before signing the transaction.
+type Replacement struct {
+	// Path is the SEP-11 txrep path of the field, ex. `sourceAccount` or
+	// `operations[0].sourceAccount`.
+	Path string
+	// ID references the value used for the field. Fields with the same ID
+	// are replaced with the same value.
+	ID string
+	// Hint describes the value expected for the field.
+	Hint string
+}
+
+// parseReplacements parses the replace parameter which has the form
+// `path1:X,path2:Y;X:hint for X,Y:hint for Y`.
+func parseReplacements(value string) ([]Replacement, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(value, ";", 2)
+	hints := map[string]string{}
+	if len(parts) == 2 && parts[1] != "" {
+		for _, hint := range strings.Split(parts[1], ",") {
+			id, text, ok := splitPair(hint)
+			if !ok {
+				return nil, errors.Errorf("invalid hint %q", hint)
+			}
+			hints[id] = text
+		}
+	}
+
+	var replacements []Replacement
+	for _, field := range strings.Split(parts[0], ",") {
+		path, id, ok := splitPair(field)
+		if !ok || path == "" || id == "" {
+			return nil, errors.Errorf("invalid field %q", field)
+		}
+		replacements = append(replacements, Replacement{Path: path, ID: id, Hint: hints[id]})
+	}
+	return replacements, nil
+}
+
+func splitPair(value string) (string, string, bool) {
+	i := strings.IndexByte(value, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	return value[:i], value[i+1:], true
+}
+
+func encodeReplacements(replacements []Replacement) string {
+	if len(replacements) == 0 {
+		return ""
+	}
+	fields := make([]string, 0, len(replacements))
+	var hints []string
+	seen := map[string]bool{}
+	for _, replacement := range replacements {
+		fields = append(fields, replacement.Path+":"+replacement.ID)
+		if replacement.Hint != "" && !seen[replacement.ID] {
+			seen[replacement.ID] = true
+			hints = append(hints, replacement.ID+":"+replacement.Hint)
+		}
+	}
+	return strings.Join(fields, ",") + ";" + strings.Join(hints, ",")
+}
+
+// ReplaceFields returns the transaction of the request with the fields listed
+// in its replace parameter set to the given values, keyed by replacement ID.
+// Values are in SEP-11 txrep format, ex. account IDs are strkeys and strings
+// are quoted.
+func (r *TxRequest) ReplaceFields(values map[string]string) (*txnbuild.GenericTransaction, error) {
+	tx, err := r.Transaction()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid xdr")
+	}
+	txrep, err := tx.ToTxRep()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(txrep, "\n"), "\n")
+	index := make(map[string]int, len(lines))
+	for i, line := range lines {
+		key, _, _ := splitPair(line)
+		index[key] = i
+	}
+
+	for _, replacement := range r.Replace {
+		value, ok := values[replacement.ID]
+		if !ok {
+			return nil, errors.Errorf("missing value for %s", replacement.ID)
+		}
+		if !replaceTxRepField(&lines, index, replacement.Path, value) {
+			return nil, errors.Errorf("transaction has no field %s", replacement.Path)
+		}
+	}
+
+	replaced, err := txnbuild.TransactionFromTxRep(
+		strings.Join(lines, "\n"),
+		txnbuild.TransactionFromXDROptionEnableMuxedAccounts,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid replacement")
+	}
+	return replaced, nil
+}
+
+// replaceTxRepField sets the value of the field with the given path. Paths
+// may omit the `tx.` prefix of the transaction fields. Absent optional fields
+// are marked present.
+func replaceTxRepField(lines *[]string, index map[string]int, path, value string) bool {
+	for _, key := range []string{path, "tx." + path} {
+		if i, ok := index[key]; ok {
+			(*lines)[i] = key + ": " + value
+			return true
+		}
+		if i, ok := index[key+"._present"]; ok {
+			(*lines)[i] = key + "._present: true"
+			index[key] = len(*lines)
+			*lines = append(*lines, key+": "+value)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package sep7
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/stellar/go/clients/stellartoml"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/support/errors"
+)
+
+// signaturePayloadPrefix is prepended to the URI when signing. It consists of
+// 35 zero bytes followed by 4, the SEP-7 payload type, and a fixed string.
+var signaturePayloadPrefix = append(
+	append(make([]byte, 35), 4),
+	[]byte("stellar.sep.7 - URI Scheme")...,
+)
+
+// signaturePayload returns the payload signed by the origin domain.
+func signaturePayload(request Request) []byte {
+	params := request.Params()
+	unsigned := params.unsigned
+	if unsigned == "" {
+		// The request was not parsed, sign the URI without its signature.
+		signature := params.Signature
+		params.Signature = ""
+		unsigned = request.String()
+		params.Signature = signature
+	}
+	payload := make([]byte, 0, len(signaturePayloadPrefix)+len(unsigned))
+	payload = append(payload, signaturePayloadPrefix...)
+	return append(payload, unsigned...)
+}
+
+// Sign signs the request with the URI_REQUEST_SIGNING_KEY of its origin
+// domain and sets the signature parameter. The signature covers the URI
+// returned by String so the request must not be modified afterwards. The
+// origin domain must be set before signing.
+func Sign(request Request, signer *keypair.Full) error {
+	params := request.Params()
+	if params.OriginDomain == "" {
+		return errors.New("origin_domain is required to sign a request")
+	}
+	// The signed URI is the one returned by String.
+	params.Signature = ""
+	params.unsigned = ""
+	signature, err := signer.Sign(signaturePayload(request))
+	if err != nil {
+		return errors.Wrap(err, "error signing request")
+	}
+	params.Signature = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// VerifySignature verifies that the request was signed by the given signing
+// key.
+func VerifySignature(request Request, signingKey string) error {
+	params := request.Params()
+	if params.Signature == "" {
+		return errors.New("request is not signed")
+	}
+	kp, err := keypair.ParseAddress(signingKey)
+	if err != nil {
+		return errors.Wrap(err, "invalid signing key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(params.Signature)
+	if err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+	if err := kp.Verify(signaturePayload(request), signature); err != nil {
+		return errors.New("signature does not match the signing key")
+	}
+	return nil
+}
+
+// VerifyOriginDomain verifies that the request was signed by the
+// URI_REQUEST_SIGNING_KEY published in the stellar.toml of its origin domain.
+// Requests without an origin domain cannot be verified and return an error.
+func VerifyOriginDomain(request Request, client stellartoml.ClientInterface) error {
+	params := request.Params()
+	if params.OriginDomain == "" {
+		return errors.New("request has no origin_domain")
+	}
+	if strings.ContainsAny(params.OriginDomain, "/:@") {
+		return errors.Errorf("invalid origin_domain %q", params.OriginDomain)
+	}
+	toml, err := client.GetStellarToml(params.OriginDomain)
+	if err != nil {
+		return errors.Wrapf(err, "error fetching stellar.toml of %s", params.OriginDomain)
+	}
+	if toml.UriRequestSigningKey == "" {
+		return errors.Errorf("stellar.toml of %s has no URI_REQUEST_SIGNING_KEY", params.OriginDomain)
+	}
+	return VerifySignature(request, toml.UriRequestSigningKey)
+}
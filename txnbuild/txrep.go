@@ -0,0 +1,723 @@
+package txnbuild
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// This file implements the txrep format defined in SEP-11
+// (https://github.com/stellar/stellar-protocol/blob/master/ecosystem/sep-0011.md).
+// Every line of a txrep document has the form `key: value` where the key is
+// the path of a field in the XDR transaction envelope. The encoder walks the
+// generated XDR types using reflection so that every operation type is
+// covered. Compact representations (account strkeys, `CODE:ISSUER` assets)
+// are only used when decoding them yields exactly the same XDR value, which
+// guarantees that decoding a txrep document reproduces the original envelope
+// and its hash.
+
+// ToTxRep returns the SEP-11 txrep representation of the transaction envelope.
+func (t *Transaction) ToTxRep() (string, error) {
+	return envelopeToTxRep(t.envelope)
+}
+
+// ToTxRep returns the SEP-11 txrep representation of the transaction envelope.
+func (t *FeeBumpTransaction) ToTxRep() (string, error) {
+	return envelopeToTxRep(t.envelope)
+}
+
+// ToTxRep returns the SEP-11 txrep representation of the transaction envelope.
+func (t *GenericTransaction) ToTxRep() (string, error) {
+	if tx, ok := t.Transaction(); ok {
+		return tx.ToTxRep()
+	}
+	if fbtx, ok := t.FeeBump(); ok {
+		return fbtx.ToTxRep()
+	}
+	return "", errors.New("unable to convert empty GenericTransaction to txrep")
+}
+
+// TransactionFromTxRep parses the supplied transaction envelope in SEP-11
+// txrep format and returns a GenericTransaction instance.
+func TransactionFromTxRep(txrep string, options ...TransactionFromXDROption) (*GenericTransaction, error) {
+	xdrEnv, err := envelopeFromTxRep(txrep)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse txrep")
+	}
+	return transactionFromParsedXDR(xdrEnv, areMuxedAccountsEnabled(options))
+}
+
+type xdrUnion interface {
+	SwitchFieldName() string
+	ArmForSwitch(sw int32) (string, bool)
+}
+
+type xdrEnum interface {
+	ValidEnum(v int32) bool
+	String() string
+}
+
+var (
+	xdrUnionType       = reflect.TypeOf((*xdrUnion)(nil)).Elem()
+	xdrEnumType        = reflect.TypeOf((*xdrEnum)(nil)).Elem()
+	transactionV0Type  = reflect.TypeOf(xdr.TransactionV0{})
+	feeBumpInnerTxType = reflect.TypeOf(xdr.FeeBumpTransactionInnerTx{})
+)
+
+// Pointers to XDR types also implement the interfaces above so the kind is
+// checked as well.
+func isXDREnum(t reflect.Type) bool {
+	return t.Kind() == reflect.Int32 && t.Implements(xdrEnumType)
+}
+
+func isXDRUnion(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(xdrUnionType)
+}
+
+// txRepCodec converts values of a single XDR type to and from their compact
+// txrep representation.
+type txRepCodec struct {
+	encode func(v reflect.Value) (string, error)
+	decode func(s string, v reflect.Value) error
+}
+
+var txRepCompactTypes = map[reflect.Type]txRepCodec{
+	reflect.TypeOf(xdr.AccountId{}): {
+		encode: func(v reflect.Value) (string, error) {
+			accountID := v.Interface().(xdr.AccountId)
+			return accountID.GetAddress()
+		},
+		decode: func(s string, v reflect.Value) error {
+			return v.Addr().Interface().(*xdr.AccountId).SetAddress(s)
+		},
+	},
+	reflect.TypeOf(xdr.MuxedAccount{}): {
+		encode: func(v reflect.Value) (string, error) {
+			account := v.Interface().(xdr.MuxedAccount)
+			return account.GetAddress()
+		},
+		decode: func(s string, v reflect.Value) error {
+			return v.Addr().Interface().(*xdr.MuxedAccount).SetAddress(s)
+		},
+	},
+	reflect.TypeOf(xdr.SignerKey{}): {
+		encode: func(v reflect.Value) (string, error) {
+			signerKey := v.Interface().(xdr.SignerKey)
+			return signerKey.GetAddress()
+		},
+		decode: func(s string, v reflect.Value) error {
+			return v.Addr().Interface().(*xdr.SignerKey).SetAddress(s)
+		},
+	},
+	reflect.TypeOf(xdr.Asset{}): {
+		encode: func(v reflect.Value) (string, error) {
+			return txRepAssetString(v.Interface().(xdr.Asset))
+		},
+		decode: func(s string, v reflect.Value) error {
+			asset, err := txRepParseAsset(s)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(asset))
+			return nil
+		},
+	},
+	reflect.TypeOf(xdr.ChangeTrustAsset{}): {
+		encode: func(v reflect.Value) (string, error) {
+			asset := v.Interface().(xdr.ChangeTrustAsset)
+			if asset.Type == xdr.AssetTypeAssetTypePoolShare {
+				return "", errors.New("pool share assets have no compact representation")
+			}
+			return txRepAssetString(xdr.Asset{Type: asset.Type, AlphaNum4: asset.AlphaNum4, AlphaNum12: asset.AlphaNum12})
+		},
+		decode: func(s string, v reflect.Value) error {
+			asset, err := txRepParseAsset(s)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(asset.ToChangeTrustAsset()))
+			return nil
+		},
+	},
+	reflect.TypeOf(xdr.TrustLineAsset{}): {
+		encode: func(v reflect.Value) (string, error) {
+			asset := v.Interface().(xdr.TrustLineAsset)
+			if asset.Type == xdr.AssetTypeAssetTypePoolShare {
+				return "", errors.New("pool share assets have no compact representation")
+			}
+			return txRepAssetString(xdr.Asset{Type: asset.Type, AlphaNum4: asset.AlphaNum4, AlphaNum12: asset.AlphaNum12})
+		},
+		decode: func(s string, v reflect.Value) error {
+			asset, err := txRepParseAsset(s)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(asset.ToTrustLineAsset()))
+			return nil
+		},
+	},
+	reflect.TypeOf(xdr.AssetCode{}): {
+		encode: func(v reflect.Value) (string, error) {
+			code := v.Interface().(xdr.AssetCode)
+			switch code.Type {
+			case xdr.AssetTypeAssetTypeCreditAlphanum4:
+				return strings.TrimRight(string(code.AssetCode4[:]), "\x00"), nil
+			case xdr.AssetTypeAssetTypeCreditAlphanum12:
+				return strings.TrimRight(string(code.AssetCode12[:]), "\x00"), nil
+			}
+			return "", errors.Errorf("invalid asset code type %d", code.Type)
+		},
+		decode: func(s string, v reflect.Value) error {
+			if !xdr.ValidAssetCode.MatchString(s) {
+				return errors.Errorf("invalid asset code %q", s)
+			}
+			var code xdr.AssetCode
+			if len(s) <= 4 {
+				var code4 xdr.AssetCode4
+				copy(code4[:], s)
+				code = xdr.AssetCode{Type: xdr.AssetTypeAssetTypeCreditAlphanum4, AssetCode4: &code4}
+			} else {
+				var code12 xdr.AssetCode12
+				copy(code12[:], s)
+				code = xdr.AssetCode{Type: xdr.AssetTypeAssetTypeCreditAlphanum12, AssetCode12: &code12}
+			}
+			v.Set(reflect.ValueOf(code))
+			return nil
+		},
+	},
+}
+
+// txRepEd25519Codec is used for the source account of v0 transactions which is
+// stored as a raw ed25519 key.
+var txRepEd25519Codec = txRepCodec{
+	encode: func(v reflect.Value) (string, error) {
+		key := v.Interface().(xdr.Uint256)
+		return strkey.Encode(strkey.VersionByteAccountID, key[:])
+	},
+	decode: func(s string, v reflect.Value) error {
+		raw, err := strkey.Decode(strkey.VersionByteAccountID, s)
+		if err != nil {
+			return err
+		}
+		var key xdr.Uint256
+		if len(raw) != len(key) {
+			return errors.Errorf("invalid ed25519 key length %d", len(raw))
+		}
+		copy(key[:], raw)
+		v.Set(reflect.ValueOf(key))
+		return nil
+	},
+}
+
+func txRepAssetString(asset xdr.Asset) (string, error) {
+	var assetType, code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return "", err
+	}
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return "XLM", nil
+	}
+	return code + ":" + issuer, nil
+}
+
+func txRepParseAsset(s string) (xdr.Asset, error) {
+	if s == "XLM" || s == "native" {
+		return xdr.MustNewNativeAsset(), nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return xdr.Asset{}, errors.Errorf("invalid asset %q", s)
+	}
+	return xdr.NewCreditAsset(parts[0], parts[1])
+}
+
+// txRepFieldName returns the name of the XDR field corresponding to the given
+// Go field of a generated XDR type.
+func txRepFieldName(goName string) string {
+	name := strings.ToLower(goName[:1]) + goName[1:]
+	if len(name) > 2 && strings.HasSuffix(name, "Id") {
+		name = name[:len(name)-2] + "ID"
+	}
+	return name
+}
+
+// txRepEnumName returns the XDR name of an enum value, for example
+// OperationTypePathPaymentStrictSend is converted to
+// PATH_PAYMENT_STRICT_SEND.
+func txRepEnumName(v reflect.Value) string {
+	goName := strings.TrimPrefix(v.Interface().(xdrEnum).String(), v.Type().Name())
+	var name strings.Builder
+	for i, r := range goName {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rune(goName[i-1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				name.WriteByte('_')
+			}
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+var txRepEnumValues sync.Map
+
+// txRepEnumValue returns the value of the enum of type t with the given XDR
+// name.
+func txRepEnumValue(t reflect.Type, name string) (int64, bool) {
+	values, ok := txRepEnumValues.Load(t)
+	if !ok {
+		byName := map[string]int64{}
+		enum := reflect.New(t).Elem()
+		validator := enum.Interface().(xdrEnum)
+		// All enums used in transactions have small non-negative values.
+		for i := int32(0); i < 1024; i++ {
+			if validator.ValidEnum(i) {
+				enum.SetInt(int64(i))
+				byName[txRepEnumName(enum)] = int64(i)
+			}
+		}
+		values, _ = txRepEnumValues.LoadOrStore(t, byName)
+	}
+	value, ok := values.(map[string]int64)[name]
+	return value, ok
+}
+
+func txRepKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func txRepQuote(s string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&quoted, "\\x%02x", c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+type txRepEncoder struct {
+	lines []string
+}
+
+func envelopeToTxRep(env xdr.TransactionEnvelope) (string, error) {
+	e := &txRepEncoder{}
+	if err := e.encode("type", reflect.ValueOf(env.Type)); err != nil {
+		return "", err
+	}
+	var err error
+	switch env.Type {
+	case xdr.EnvelopeTypeEnvelopeTypeTxV0:
+		err = e.encodeFields("", reflect.ValueOf(*env.V0))
+	case xdr.EnvelopeTypeEnvelopeTypeTx:
+		err = e.encodeFields("", reflect.ValueOf(*env.V1))
+	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
+		err = e.encode("feeBump", reflect.ValueOf(*env.FeeBump))
+	default:
+		err = errors.Errorf("invalid envelope type %d", env.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(e.lines, "\n") + "\n", nil
+}
+
+func (e *txRepEncoder) add(key, value string) {
+	e.lines = append(e.lines, key+": "+value)
+}
+
+func (e *txRepEncoder) encodeCompact(key string, v reflect.Value, codec txRepCodec) bool {
+	s, err := codec.encode(v)
+	if err != nil {
+		return false
+	}
+	// Only use the compact representation if it decodes to the same value.
+	decoded := reflect.New(v.Type()).Elem()
+	if err := codec.decode(s, decoded); err != nil || !reflect.DeepEqual(decoded.Interface(), v.Interface()) {
+		return false
+	}
+	e.add(key, s)
+	return true
+}
+
+func (e *txRepEncoder) encodeFields(prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := txRepKey(prefix, txRepFieldName(field.Name))
+		if t == transactionV0Type && field.Name == "SourceAccountEd25519" &&
+			e.encodeCompact(key, v.Field(i), txRepEd25519Codec) {
+			continue
+		}
+		if err := e.encode(key, v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *txRepEncoder) encode(key string, v reflect.Value) error {
+	t := v.Type()
+	if codec, ok := txRepCompactTypes[t]; ok && e.encodeCompact(key, v, codec) {
+		return nil
+	}
+	if isXDREnum(t) {
+		e.add(key, txRepEnumName(v))
+		return nil
+	}
+	if isXDRUnion(t) {
+		return e.encodeUnion(key, v)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		e.add(key, strconv.FormatBool(v.Bool()))
+	case reflect.Int32, reflect.Int64:
+		e.add(key, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint32, reflect.Uint64:
+		e.add(key, strconv.FormatUint(v.Uint(), 10))
+	case reflect.String:
+		e.add(key, txRepQuote(v.String()))
+	case reflect.Array:
+		raw := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(raw), v)
+		e.add(key, hex.EncodeToString(raw))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.add(key, hex.EncodeToString(v.Bytes()))
+			return nil
+		}
+		e.add(key+".len", strconv.Itoa(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(fmt.Sprintf("%s[%d]", key, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		e.add(key+"._present", strconv.FormatBool(!v.IsNil()))
+		if !v.IsNil() {
+			return e.encode(key, v.Elem())
+		}
+	case reflect.Struct:
+		return e.encodeFields(key, v)
+	default:
+		return errors.Errorf("unsupported type %s for field %s", t, key)
+	}
+	return nil
+}
+
+func (e *txRepEncoder) encodeUnion(key string, v reflect.Value) error {
+	union := v.Interface().(xdrUnion)
+	switchName := union.SwitchFieldName()
+	discriminant := v.FieldByName(switchName)
+	if err := e.encode(txRepKey(key, txRepFieldName(switchName)), discriminant); err != nil {
+		return err
+	}
+	arm, ok := union.ArmForSwitch(int32(discriminant.Int()))
+	if !ok {
+		return errors.Errorf("invalid discriminant %d for field %s", discriminant.Int(), key)
+	}
+	if arm == "" {
+		return nil
+	}
+	armValue := v.FieldByName(arm)
+	if armValue.IsNil() {
+		return errors.Errorf("missing value for field %s", txRepKey(key, arm))
+	}
+	return e.encode(txRepKey(key, txRepArmName(v.Type(), arm)), armValue.Elem())
+}
+
+func txRepArmName(t reflect.Type, arm string) string {
+	// SEP-11 names the transaction of a fee bump inner envelope "tx".
+	if t == feeBumpInnerTxType {
+		return "tx"
+	}
+	return txRepFieldName(arm)
+}
+
+type txRepDecoder struct {
+	values map[string]string
+	used   map[string]bool
+}
+
+func envelopeFromTxRep(txrep string) (xdr.TransactionEnvelope, error) {
+	var env xdr.TransactionEnvelope
+	d := &txRepDecoder{values: map[string]string{}, used: map[string]bool{}}
+	scanner := bufio.NewScanner(strings.NewReader(txrep))
+	scanner.Buffer(nil, 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return env, errors.Errorf("invalid line %d: missing separator", lineNumber)
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, ok := d.values[key]; ok {
+			return env, errors.Errorf("invalid line %d: duplicate field %s", lineNumber, key)
+		}
+		d.values[key] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return env, err
+	}
+
+	if err := d.decode("type", reflect.ValueOf(&env.Type).Elem()); err != nil {
+		return env, err
+	}
+	var err error
+	switch env.Type {
+	case xdr.EnvelopeTypeEnvelopeTypeTxV0:
+		env.V0 = &xdr.TransactionV0Envelope{}
+		err = d.decodeFields("", reflect.ValueOf(env.V0).Elem())
+	case xdr.EnvelopeTypeEnvelopeTypeTx:
+		env.V1 = &xdr.TransactionV1Envelope{}
+		err = d.decodeFields("", reflect.ValueOf(env.V1).Elem())
+	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
+		env.FeeBump = &xdr.FeeBumpTransactionEnvelope{}
+		err = d.decode("feeBump", reflect.ValueOf(env.FeeBump).Elem())
+	default:
+		err = errors.Errorf("invalid envelope type %d", env.Type)
+	}
+	if err != nil {
+		return env, err
+	}
+
+	for key := range d.values {
+		if !d.used[key] {
+			return env, errors.Errorf("unexpected field %s", key)
+		}
+	}
+	return env, nil
+}
+
+// value returns the value of the given key without any trailing comment.
+func (d *txRepDecoder) value(key string) (string, error) {
+	value, ok := d.values[key]
+	if !ok {
+		return "", errors.Errorf("missing field %s", key)
+	}
+	d.used[key] = true
+	if strings.HasPrefix(value, `"`) {
+		for i := 1; i < len(value); i++ {
+			switch value[i] {
+			case '\\':
+				i++
+			case '"':
+				return value[:i+1], nil
+			}
+		}
+		return "", errors.Errorf("unterminated string in field %s", key)
+	}
+	if i := strings.IndexAny(value, " \t"); i >= 0 {
+		value = value[:i]
+	}
+	return value, nil
+}
+
+func (d *txRepDecoder) decodeCompact(key string, v reflect.Value, codec txRepCodec) (bool, error) {
+	if _, ok := d.values[key]; !ok {
+		return false, nil
+	}
+	s, err := d.value(key)
+	if err != nil {
+		return true, err
+	}
+	if err := codec.decode(s, v); err != nil {
+		return true, errors.Wrapf(err, "invalid value for field %s", key)
+	}
+	return true, nil
+}
+
+func (d *txRepDecoder) decodeFields(prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := txRepKey(prefix, txRepFieldName(field.Name))
+		if t == transactionV0Type && field.Name == "SourceAccountEd25519" {
+			if ok, err := d.decodeCompact(key, v.Field(i), txRepEd25519Codec); ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := d.decode(key, v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *txRepDecoder) decode(key string, v reflect.Value) error {
+	t := v.Type()
+	if codec, ok := txRepCompactTypes[t]; ok {
+		if ok, err := d.decodeCompact(key, v, codec); ok {
+			return err
+		}
+	}
+	if isXDRUnion(t) {
+		return d.decodeUnion(key, v)
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		return d.decodeSlice(key, v)
+	}
+	if t.Kind() == reflect.Ptr {
+		return d.decodeOptional(key, v)
+	}
+	if t.Kind() == reflect.Struct {
+		return d.decodeFields(key, v)
+	}
+
+	s, err := d.value(key)
+	if err != nil {
+		return err
+	}
+	if err := decodeTxRepScalar(s, v); err != nil {
+		return errors.Wrapf(err, "invalid value for field %s", key)
+	}
+	return nil
+}
+
+func decodeTxRepScalar(s string, v reflect.Value) error {
+	t := v.Type()
+	if isXDREnum(t) {
+		value, ok := txRepEnumValue(t, s)
+		if !ok {
+			return errors.Errorf("unknown %s %q", t.Name(), s)
+		}
+		v.SetInt(value)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.String:
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return errors.Errorf("invalid string %s", s)
+		}
+		v.SetString(unquoted)
+	case reflect.Array:
+		raw, err := hex.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		if len(raw) != v.Len() {
+			return errors.Errorf("expected %d bytes, got %d", v.Len(), len(raw))
+		}
+		reflect.Copy(v, reflect.ValueOf(raw))
+	case reflect.Slice:
+		raw, err := hex.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(raw)
+	default:
+		return errors.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+func (d *txRepDecoder) decodeSlice(key string, v reflect.Value) error {
+	s, err := d.value(key + ".len")
+	if err != nil {
+		return err
+	}
+	length, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value for field %s.len", key)
+	}
+	// Every element has at least one line so longer lengths are invalid.
+	if length > uint64(len(d.values)) {
+		return errors.Errorf("invalid value for field %s.len: too many elements", key)
+	}
+	if length == 0 {
+		// Match the XDR decoder which decodes empty arrays as nil.
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	v.Set(reflect.MakeSlice(v.Type(), int(length), int(length)))
+	for i := 0; i < int(length); i++ {
+		if err := d.decode(fmt.Sprintf("%s[%d]", key, i), v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *txRepDecoder) decodeOptional(key string, v reflect.Value) error {
+	s, err := d.value(key + "._present")
+	if err != nil {
+		return err
+	}
+	present, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value for field %s._present", key)
+	}
+	if !present {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	v.Set(reflect.New(v.Type().Elem()))
+	return d.decode(key, v.Elem())
+}
+
+func (d *txRepDecoder) decodeUnion(key string, v reflect.Value) error {
+	union := v.Interface().(xdrUnion)
+	switchName := union.SwitchFieldName()
+	discriminant := v.FieldByName(switchName)
+	if err := d.decode(txRepKey(key, txRepFieldName(switchName)), discriminant); err != nil {
+		return err
+	}
+	arm, ok := union.ArmForSwitch(int32(discriminant.Int()))
+	if !ok {
+		return errors.Errorf("invalid discriminant %d for field %s", discriminant.Int(), key)
+	}
+	if arm == "" {
+		return nil
+	}
+	armValue := v.FieldByName(arm)
+	armValue.Set(reflect.New(armValue.Type().Elem()))
+	return d.decode(txRepKey(key, txRepArmName(v.Type(), arm)), armValue.Elem())
+}
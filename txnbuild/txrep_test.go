@@ -0,0 +1,298 @@
+package txnbuild
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+)
+
+func TestTxRepPayment(t *testing.T) {
+	kp0 := newKeypair0()
+	kp1 := newKeypair1()
+	sourceAccount := NewSimpleAccount(kp0.Address(), 46489056724385792)
+	tx, err := NewTransaction(TransactionParams{
+		SourceAccount:        &sourceAccount,
+		IncrementSequenceNum: true,
+		BaseFee:              MinBaseFee,
+		Timebounds:           NewTimebounds(1535756672, 1567292672),
+		Memo:                 MemoText("Enjoy \"this\" transaction"),
+		Operations: []Operation{&Payment{
+			Destination: kp1.Address(),
+			Amount:      "40.0004",
+			Asset:       CreditAsset{Code: "USD", Issuer: kp1.Address()},
+		}},
+	})
+	require.NoError(t, err)
+	tx, err = tx.Sign(network.TestNetworkPassphrase, kp0)
+	require.NoError(t, err)
+
+	txrep, err := tx.ToTxRep()
+	require.NoError(t, err)
+	signature := tx.Signatures()[0]
+	assert.Equal(t, `type: ENVELOPE_TYPE_TX
+tx.sourceAccount: GDQNY3PBOJOKYZSRMK2S7LHHGWZIUISD4QORETLMXEWXBI7KFZZMKTL3
+tx.fee: 100
+tx.seqNum: 46489056724385793
+tx.timeBounds._present: true
+tx.timeBounds.minTime: 1535756672
+tx.timeBounds.maxTime: 1567292672
+tx.memo.type: MEMO_TEXT
+tx.memo.text: "Enjoy \"this\" transaction"
+tx.operations.len: 1
+tx.operations[0].sourceAccount._present: false
+tx.operations[0].body.type: PAYMENT
+tx.operations[0].body.paymentOp.destination: GAS4V4O2B7DW5T7IQRPEEVCRXMDZESKISR7DVIGKZQYYV3OSQ5SH5LVP
+tx.operations[0].body.paymentOp.asset: USD:GAS4V4O2B7DW5T7IQRPEEVCRXMDZESKISR7DVIGKZQYYV3OSQ5SH5LVP
+tx.operations[0].body.paymentOp.amount: 400004000
+tx.ext.v: 0
+signatures.len: 1
+signatures[0].hint: `+hex.EncodeToString(signature.Hint[:])+`
+signatures[0].signature: `+hex.EncodeToString(signature.Signature)+"\n", txrep)
+
+	// Comments after values are ignored.
+	commented := strings.Replace(txrep, "amount: 400004000", "amount: 400004000 (40.0004e7)", 1)
+	parsed, err := TransactionFromTxRep(commented)
+	require.NoError(t, err)
+	parsedTx, ok := parsed.Transaction()
+	require.True(t, ok)
+	assertSameEnvelope(t, tx.ToXDR(), parsedTx.ToXDR())
+}
+
+func assertSameEnvelope(t *testing.T, expected, actual xdr.TransactionEnvelope) {
+	expectedB64, err := xdr.MarshalBase64(expected)
+	require.NoError(t, err)
+	actualB64, err := xdr.MarshalBase64(actual)
+	require.NoError(t, err)
+	assert.Equal(t, expectedB64, actualB64)
+}
+
+func TestTxRepAllOperations(t *testing.T) {
+	kp0 := newKeypair0()
+	kp1 := newKeypair1()
+	kp2 := newKeypair2()
+	usd := CreditAsset{Code: "USD", Issuer: kp1.Address()}
+	long := CreditAsset{Code: "LONGASSET", Issuer: kp2.Address()}
+	poolParams := LiquidityPoolParameters{AssetA: NativeAsset{}, AssetB: usd, Fee: LiquidityPoolFeeV18}
+	poolID, err := NewLiquidityPoolId(NativeAsset{}, usd)
+	require.NoError(t, err)
+	balanceID := "00000000da0d57da7d4850e7fc10d2a9d0ebc731f7afb40574c03395b17d49149b91f5be"
+	merged := kp1.Address()
+	muxed := "MA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVAAAAAAAAAAAAAJLK"
+
+	operations := []Operation{
+		&CreateAccount{Destination: kp1.Address(), Amount: "10"},
+		&Payment{Destination: muxed, Amount: "1", Asset: NativeAsset{}, SourceAccount: kp2.Address()},
+		&PathPaymentStrictReceive{
+			SendAsset: NativeAsset{}, SendMax: "10", Destination: kp1.Address(),
+			DestAsset: usd, DestAmount: "1", Path: []Asset{long},
+		},
+		&PathPaymentStrictSend{
+			SendAsset: usd, SendAmount: "1", Destination: kp1.Address(),
+			DestAsset: NativeAsset{}, DestMin: "0.5", Path: []Asset{},
+		},
+		&ManageSellOffer{Selling: NativeAsset{}, Buying: usd, Amount: "100", Price: "0.5", OfferID: 3},
+		&ManageBuyOffer{Selling: usd, Buying: long, Amount: "1", Price: "3.25"},
+		&CreatePassiveSellOffer{Selling: long, Buying: NativeAsset{}, Amount: "2", Price: "1"},
+		&SetOptions{
+			InflationDestination: NewInflationDestination(kp1.Address()),
+			SetFlags:             []AccountFlag{AuthRequired},
+			MasterWeight:         NewThreshold(10),
+			HomeDomain:           NewHomeDomain("example.com"),
+			Signer:               &Signer{Address: kp2.Address(), Weight: 1},
+		},
+		&ChangeTrust{Line: usd.MustToChangeTrustAsset(), Limit: "1000"},
+		&ChangeTrust{Line: LiquidityPoolShareChangeTrustAsset{LiquidityPoolParameters: poolParams}, Limit: "5"},
+		&AllowTrust{Trustor: kp1.Address(), Type: long, Authorize: true},
+		&AccountMerge{Destination: kp1.Address()},
+		&Inflation{},
+		&ManageData{Name: "name", Value: []byte{0, 1, 0xff}},
+		&ManageData{Name: "deleted"},
+		&BumpSequence{BumpTo: 9},
+		&CreateClaimableBalance{
+			Amount: "1",
+			Asset:  usd,
+			Destinations: []Claimant{
+				NewClaimant(kp1.Address(), &UnconditionalPredicate),
+				NewClaimant(kp2.Address(), func() *xdr.ClaimPredicate {
+					p := AndPredicate(NotPredicate(BeforeAbsoluteTimePredicate(100)), BeforeRelativeTimePredicate(10))
+					return &p
+				}()),
+			},
+		},
+		&ClaimClaimableBalance{BalanceID: balanceID},
+		&BeginSponsoringFutureReserves{SponsoredID: kp1.Address()},
+		&EndSponsoringFutureReserves{SourceAccount: kp1.Address()},
+		&RevokeSponsorship{SponsorshipType: RevokeSponsorshipTypeAccount, Account: &merged},
+		&RevokeSponsorship{
+			SponsorshipType: RevokeSponsorshipTypeTrustLine,
+			TrustLine:       &TrustLineID{Account: kp1.Address(), Asset: usd.MustToTrustLineAsset()},
+		},
+		&RevokeSponsorship{
+			SponsorshipType: RevokeSponsorshipTypeOffer,
+			Offer:           &OfferID{SellerAccountAddress: kp1.Address(), OfferID: 3},
+		},
+		&RevokeSponsorship{
+			SponsorshipType: RevokeSponsorshipTypeData,
+			Data:            &DataID{Account: kp1.Address(), DataName: "name"},
+		},
+		&RevokeSponsorship{SponsorshipType: RevokeSponsorshipTypeClaimableBalance, ClaimableBalance: &balanceID},
+		&RevokeSponsorship{
+			SponsorshipType: RevokeSponsorshipTypeSigner,
+			Signer:          &SignerID{AccountID: kp1.Address(), SignerAddress: kp2.Address()},
+		},
+		&Clawback{From: kp1.Address(), Amount: "1", Asset: usd},
+		&ClawbackClaimableBalance{BalanceID: balanceID},
+		&SetTrustLineFlags{Trustor: kp1.Address(), Asset: usd, SetFlags: []TrustLineFlag{TrustLineClawbackEnabled}},
+		&LiquidityPoolDeposit{
+			LiquidityPoolID: poolID, MaxAmountA: "1", MaxAmountB: "2", MinPrice: "0.5", MaxPrice: "2",
+		},
+		&LiquidityPoolWithdraw{LiquidityPoolID: poolID, Amount: "1", MinAmountA: "0.1", MinAmountB: "0.2"},
+	}
+
+	sourceAccount := NewSimpleAccount(kp0.Address(), 1)
+	tx, err := NewTransaction(TransactionParams{
+		SourceAccount:        &sourceAccount,
+		IncrementSequenceNum: true,
+		BaseFee:              MinBaseFee,
+		Timebounds:           NewInfiniteTimeout(),
+		Memo:                 MemoHash{1, 2, 3},
+		Operations:           operations,
+		EnableMuxedAccounts:  true,
+	})
+	require.NoError(t, err)
+	tx, err = tx.Sign(network.TestNetworkPassphrase, kp0, kp1)
+	require.NoError(t, err)
+
+	txrep, err := tx.ToTxRep()
+	require.NoError(t, err)
+	assert.Contains(t, txrep, "tx.operations[9].body.changeTrustOp.line.type: ASSET_TYPE_POOL_SHARE\n")
+	assert.Contains(t, txrep, "tx.operations[10].body.allowTrustOp.asset: LONGASSET\n")
+	assert.Contains(t, txrep, "tx.operations[29].body.type: LIQUIDITY_POOL_DEPOSIT\n")
+
+	parsed, err := TransactionFromTxRep(txrep, TransactionFromXDROptionEnableMuxedAccounts)
+	require.NoError(t, err)
+	parsedTx, ok := parsed.Transaction()
+	require.True(t, ok)
+	assertSameEnvelope(t, tx.ToXDR(), parsedTx.ToXDR())
+	b64, err := tx.Base64()
+	require.NoError(t, err)
+	fromXDR, err := TransactionFromXDR(b64, TransactionFromXDROptionEnableMuxedAccounts)
+	require.NoError(t, err)
+	assert.Equal(t, fromXDR, parsed)
+
+	expectedHash, err := tx.HashHex(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	hash, err := parsedTx.HashHex(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, hash)
+}
+
+func TestTxRepFeeBump(t *testing.T) {
+	kp0 := newKeypair0()
+	kp1 := newKeypair1()
+	sourceAccount := NewSimpleAccount(kp0.Address(), 1)
+	inner, err := NewTransaction(TransactionParams{
+		SourceAccount: &sourceAccount,
+		BaseFee:       MinBaseFee,
+		Timebounds:    NewInfiniteTimeout(),
+		Operations:    []Operation{&BumpSequence{BumpTo: 10}},
+	})
+	require.NoError(t, err)
+	inner, err = inner.Sign(network.TestNetworkPassphrase, kp0)
+	require.NoError(t, err)
+	feeBump, err := NewFeeBumpTransaction(FeeBumpTransactionParams{
+		Inner:      inner,
+		FeeAccount: kp1.Address(),
+		BaseFee:    2 * MinBaseFee,
+	})
+	require.NoError(t, err)
+	feeBump, err = feeBump.Sign(network.TestNetworkPassphrase, kp1)
+	require.NoError(t, err)
+
+	txrep, err := feeBump.ToGenericTransaction().ToTxRep()
+	require.NoError(t, err)
+	assert.Contains(t, txrep, "type: ENVELOPE_TYPE_TX_FEE_BUMP\n"+
+		"feeBump.tx.feeSource: "+kp1.Address()+"\n"+
+		"feeBump.tx.fee: 400\n"+
+		"feeBump.tx.innerTx.type: ENVELOPE_TYPE_TX\n"+
+		"feeBump.tx.innerTx.tx.tx.sourceAccount: "+kp0.Address()+"\n")
+	assert.Contains(t, txrep, "feeBump.tx.innerTx.tx.signatures.len: 1\n"+
+		"feeBump.tx.innerTx.tx.signatures[0].hint:")
+	assert.Contains(t, txrep, "feeBump.tx.ext.v: 0\nfeeBump.signatures.len: 1\n")
+
+	parsed, err := TransactionFromTxRep(txrep)
+	require.NoError(t, err)
+	parsedFeeBump, ok := parsed.FeeBump()
+	require.True(t, ok)
+	assertSameEnvelope(t, feeBump.ToXDR(), parsedFeeBump.ToXDR())
+	assert.Equal(t, kp1.Address(), parsedFeeBump.FeeAccount())
+}
+
+func TestTxRepV0Envelope(t *testing.T) {
+	kp0 := newKeypair0()
+	env := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTxV0,
+		V0: &xdr.TransactionV0Envelope{
+			Tx: xdr.TransactionV0{
+				SourceAccountEd25519: *xdr.MustAddress(kp0.Address()).Ed25519,
+				Fee:                  100,
+				SeqNum:               2,
+				Memo:                 xdr.MemoID(7),
+				Operations: []xdr.Operation{{
+					Body: xdr.OperationBody{Type: xdr.OperationTypeInflation},
+				}},
+			},
+		},
+	}
+	b64, err := xdr.MarshalBase64(env)
+	require.NoError(t, err)
+	gtx, err := TransactionFromXDR(b64)
+	require.NoError(t, err)
+
+	txrep, err := gtx.ToTxRep()
+	require.NoError(t, err)
+	assert.Contains(t, txrep, "type: ENVELOPE_TYPE_TX_V0\ntx.sourceAccountEd25519: "+kp0.Address()+"\n")
+	assert.Contains(t, txrep, "tx.memo.type: MEMO_ID\ntx.memo.id: 7\n")
+
+	parsed, err := TransactionFromTxRep(txrep)
+	require.NoError(t, err)
+	parsedTx, ok := parsed.Transaction()
+	require.True(t, ok)
+	assertSameEnvelope(t, env, parsedTx.ToXDR())
+}
+
+func TestTxRepInvalid(t *testing.T) {
+	valid := "type: ENVELOPE_TYPE_TX_V0\n" +
+		"tx.sourceAccountEd25519: GDQNY3PBOJOKYZSRMK2S7LHHGWZIUISD4QORETLMXEWXBI7KFZZMKTL3\n" +
+		"tx.fee: 100\n" +
+		"tx.seqNum: 2\n" +
+		"tx.timeBounds._present: false\n" +
+		"tx.memo.type: MEMO_NONE\n" +
+		"tx.operations.len: 0\n" +
+		"tx.ext.v: 0\n" +
+		"signatures.len: 0\n"
+	_, err := TransactionFromTxRep(valid)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		txrep    string
+		expected string
+	}{
+		{"type: ENVELOPE_TYPE_SCP\n", "unable to parse txrep: invalid envelope type 1"},
+		{"type: UNKNOWN\n", `unable to parse txrep: invalid value for field type: unknown EnvelopeType "UNKNOWN"`},
+		{strings.Replace(valid, "tx.fee: 100\n", "", 1), "unable to parse txrep: missing field tx.fee"},
+		{strings.Replace(valid, "tx.fee: 100", "tx.fee: -1", 1), `unable to parse txrep: invalid value for field tx.fee: strconv.ParseUint: parsing "-1": invalid syntax`},
+		{valid + "tx.fee: 100\n", "unable to parse txrep: invalid line 10: duplicate field tx.fee"},
+		{valid + "tx.extra: 1\n", "unable to parse txrep: unexpected field tx.extra"},
+		{valid + "garbage\n", "unable to parse txrep: invalid line 10: missing separator"},
+	} {
+		_, err := TransactionFromTxRep(testCase.txrep)
+		assert.EqualError(t, err, testCase.expected)
+	}
+}